@@ -18,10 +18,14 @@ import (
 	"github.com/neo/trainer-plus/internal/config"
 	"github.com/neo/trainer-plus/internal/handler"
 	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/notify"
 	"github.com/neo/trainer-plus/internal/repository"
 	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/telegram"
 	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/checkin"
 	"github.com/neo/trainer-plus/pkg/jwt"
+	"github.com/neo/trainer-plus/pkg/storage"
 )
 
 func main() {
@@ -51,6 +55,13 @@ func main() {
 	// JWT Manager
 	jwtManager := jwt.NewManager(cfg.JWT.Secret, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL)
 
+	// QR check-in codes have their own key so either can be rotated alone
+	if cfg.CheckIn.QRSecret == cfg.JWT.Secret {
+		logger.Error("CHECKIN_QR_SECRET must differ from JWT_SECRET")
+		os.Exit(1)
+	}
+	checkInSigner := checkin.NewSigner(cfg.CheckIn.QRSecret)
+
 	// Validator
 	validate := validator.New()
 
@@ -64,22 +75,77 @@ func main() {
 	paymentRepo := repository.NewPaymentRepository(db)
 	attendanceRepo := repository.NewAttendanceRepository(db)
 	reportRepo := repository.NewReportRepository(db)
+	seriesRepo := repository.NewSeriesRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
+	makeupRepo := repository.NewMakeupRepository(db)
+	kioskRepo := repository.NewKioskRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	telegramRepo := repository.NewTelegramRepository(db)
+	importRepo := repository.NewImportRepository(db)
+	mergeRepo := repository.NewStudentMergeRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+	purgeRepo := repository.NewPurgeRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	customFieldRepo := repository.NewCustomFieldRepository(db)
+	progressRepo := repository.NewProgressRepository(db)
+	payrollRepo := repository.NewPayrollRepository(db)
+
+	// Uploaded student documents
+	documents := documentStorage(cfg)
+
+	// Notifications are queued by the notifier and sent by the dispatcher
+	notifier := notify.NewNotifier(notificationRepo, telegramRepo)
+	dispatcher := notify.NewDispatcher(notificationRepo, notificationDrivers(cfg, logger), logger)
+	reminders := notify.NewReminders(reminderRepo, clubRepo, notifier, logger)
 
 	// Services
 	authService := service.NewAuthService(userRepo, jwtManager)
+	attendanceService := service.NewAttendanceService(attendanceRepo, subscriptionRepo, makeupRepo)
+	checkInService := service.NewCheckInService(sessionRepo, studentRepo, clubRepo, attendanceRepo, attendanceService, checkInSigner)
+	scheduleService := service.NewScheduleService(seriesRepo, sessionRepo, groupRepo, clubRepo, studentRepo, subscriptionRepo, makeupRepo, notifier, logger)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, studentRepo, groupRepo, clubRepo, notifier)
+	importService := service.NewImportService(importRepo, studentRepo, subscriptionRepo, groupRepo, customFieldRepo, validate, logger)
+	mergeService := service.NewMergeService(mergeRepo, studentRepo, attendanceRepo, attendanceService)
+	purgeService := service.NewPurgeService(purgeRepo, documents, logger)
+	privacyService := service.NewPrivacyService(privacyRepo, studentRepo, profileRepo, documents, logger)
+	payrollService := service.NewPayrollService(payrollRepo)
+
+	// Coach bot; updates come from the webhook or from long polling
+	telegramClient := telegram.NewClient(cfg.Telegram.APIURL, cfg.Telegram.BotToken)
+	var bot *telegram.Bot
+	if cfg.Telegram.BotToken != "" && cfg.Telegram.BotMode != "" {
+		bot = telegram.NewBot(telegramClient, telegramRepo, groupRepo, sessionRepo, clubRepo, attendanceRepo, attendanceService, logger)
+	}
 
 	// Handlers
 	healthHandler := handler.NewHealthHandler()
 	authHandler := handler.NewAuthHandler(authService)
 	clubHandler := handler.NewClubHandler(clubRepo, validate)
-	groupHandler := handler.NewGroupHandler(groupRepo, clubRepo, validate)
-	sessionHandler := handler.NewSessionHandler(sessionRepo, groupRepo, clubRepo, validate)
-	studentHandler := handler.NewStudentHandler(studentRepo, clubRepo, validate)
-	publicHandler := handler.NewPublicHandler(clubRepo, groupRepo, sessionRepo)
+	groupHandler := handler.NewGroupHandler(groupRepo, clubRepo, locationRepo, customFieldRepo, validate)
+	sessionHandler := handler.NewSessionHandler(sessionRepo, groupRepo, clubRepo, locationRepo, scheduleService, validate)
+	seriesHandler := handler.NewSeriesHandler(seriesRepo, groupRepo, clubRepo, locationRepo, scheduleService, validate)
+	studentHandler := handler.NewStudentHandler(studentRepo, clubRepo, customFieldRepo, validate)
+	publicHandler := handler.NewPublicHandler(clubRepo, groupRepo, sessionRepo, locationRepo)
 	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionRepo, studentRepo, groupRepo, clubRepo, validate)
-	attendanceHandler := handler.NewAttendanceHandler(attendanceRepo, subscriptionRepo, sessionRepo, groupRepo, clubRepo, validate)
-	paymentHandler := handler.NewPaymentHandler(paymentRepo, subscriptionRepo, studentRepo, groupRepo, clubRepo, validate, logger)
+	attendanceHandler := handler.NewAttendanceHandler(attendanceRepo, sessionRepo, groupRepo, clubRepo, idempotencyRepo, attendanceService, validate)
+	paymentHandler := handler.NewPaymentHandler(paymentRepo, subscriptionRepo, studentRepo, groupRepo, clubRepo, notifier, validate, logger)
 	reportHandler := handler.NewReportHandler(reportRepo, clubRepo)
+	locationHandler := handler.NewLocationHandler(locationRepo, clubRepo, validate)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldRepo, clubRepo, validate)
+	kioskHandler := handler.NewKioskHandler(kioskRepo, clubRepo, groupRepo, studentRepo, locationRepo, checkInService, validate)
+	makeupHandler := handler.NewMakeupHandler(makeupRepo, studentRepo, groupRepo, clubRepo, validate)
+	notificationHandler := handler.NewNotificationHandler(notificationRepo, clubRepo)
+	reminderHandler := handler.NewReminderHandler(reminderRepo, clubRepo, validate)
+	importHandler := handler.NewImportHandler(importRepo, clubRepo, customFieldRepo, validate)
+	duplicateHandler := handler.NewDuplicateHandler(mergeService, mergeRepo, studentRepo, clubRepo, validate)
+	profileHandler := handler.NewProfileHandler(profileRepo, studentRepo, clubRepo, documents, validate, logger)
+	progressHandler := handler.NewProgressHandler(progressRepo, studentRepo, groupRepo, clubRepo, profileRepo, validate)
+	payrollHandler := handler.NewPayrollHandler(payrollService, payrollRepo, groupRepo, clubRepo, validate)
+	adminHandler := handler.NewAdminHandler(purgeService, purgeRepo)
+	privacyHandler := handler.NewPrivacyHandler(privacyService, privacyRepo, clubRepo, documents, validate, logger)
+	telegramHandler := handler.NewTelegramHandler(telegramRepo, bot, cfg.Telegram.WebhookSecret, cfg.Telegram.BotUsername, logger)
 
 	// Router
 	r := chi.NewRouter()
@@ -126,45 +192,161 @@ func main() {
 				r.Get("/{id}", clubHandler.GetByID)
 				r.Put("/{id}", clubHandler.Update)
 				r.Delete("/{id}", clubHandler.Delete)
+				r.Post("/{id}/restore", clubHandler.Restore)
 
 				// Nested: groups by club
 				r.Get("/{club_id}/groups", groupHandler.ListByClub)
 
+				// Nested: locations by club
+				r.Get("/{club_id}/locations", locationHandler.ListByClub)
+
+				// Nested: custom fields of students and groups by club
+				r.Get("/{club_id}/custom-fields", customFieldHandler.ListByClub)
+
+				// Nested: check-in kiosks by club
+				r.Get("/{club_id}/kiosk-devices", kioskHandler.ListDevices)
+
+				// Nested: notification log by club
+				r.Get("/{club_id}/notifications", notificationHandler.ListByClub)
+
+				// Nested: reminder rules by club
+				r.Get("/{club_id}/reminders", reminderHandler.Get)
+				r.Put("/{club_id}/reminders", reminderHandler.Update)
+
 				// Nested: students by club
 				r.Get("/{club_id}/students", studentHandler.ListByClub)
 				r.Get("/{club_id}/students/search", studentHandler.Search)
+				r.Get("/{club_id}/students/duplicates", duplicateHandler.ListByClub)
+				r.Get("/{club_id}/student-merges", duplicateHandler.ListMerges)
+				r.Get("/{club_id}/medical-certificates/alerts", profileHandler.CertificateAlerts)
+
+				// Nested: guardians' personal data requests by club
+				r.Post("/{club_id}/guardian-data/export", privacyHandler.ExportGuardian)
+				r.Post("/{club_id}/guardian-data/erase", privacyHandler.EraseGuardian)
+				r.Get("/{club_id}/data-requests", privacyHandler.ListRequests)
+
+				// Nested: student imports by club
+				r.Post("/{club_id}/imports", importHandler.Upload)
+				r.Get("/{club_id}/imports", importHandler.ListByClub)
 
 				// Nested: subscriptions by club
 				r.Get("/{club_id}/subscriptions", subscriptionHandler.ListByClub)
 
+				// Nested: payments by club
+				r.Get("/{club_id}/payments", paymentHandler.ListByClub)
+
+				// Nested: schedule conflicts by club
+				r.Get("/{club_id}/conflicts", sessionHandler.ClubConflicts)
+
+				// Nested: coach payroll by club
+				r.Get("/{club_id}/payroll-rules", payrollHandler.ListRules)
+				r.Post("/{club_id}/payroll-rules", payrollHandler.CreateRule)
+				r.Get("/{club_id}/payroll-runs", payrollHandler.ListRuns)
+				r.Post("/{club_id}/payroll-runs", payrollHandler.CreateRun)
+
 				// Nested: dashboard & reports by club
 				r.Get("/{club_id}/dashboard", reportHandler.Dashboard)
 				r.Route("/{club_id}/reports", func(r chi.Router) {
 					r.Get("/finance", reportHandler.Finance)
 					r.Get("/occupancy", reportHandler.Occupancy)
+					r.Get("/utilisation", reportHandler.Utilisation)
 					r.Get("/mrr", reportHandler.MRR)
 					r.Get("/students", reportHandler.Students)
 					r.Get("/debt", reportHandler.Debt)
+					r.Get("/trials", reportHandler.Trials)
+					r.Get("/levels", reportHandler.Levels)
+					r.Get("/retention", reportHandler.Retention)
+					r.Get("/revenue", reportHandler.Revenue)
 				})
 			})
 
+			// Locations
+			r.Route("/locations", func(r chi.Router) {
+				r.Post("/", locationHandler.Create)
+				r.Get("/{id}", locationHandler.GetByID)
+				r.Put("/{id}", locationHandler.Update)
+				r.Delete("/{id}", locationHandler.Delete)
+			})
+
+			// Custom fields
+			r.Route("/custom-fields", func(r chi.Router) {
+				r.Post("/", customFieldHandler.Create)
+				r.Put("/{id}", customFieldHandler.Update)
+				r.Delete("/{id}", customFieldHandler.Delete)
+			})
+
+			// Progress
+			r.Put("/levels/{id}", progressHandler.UpdateLevel)
+			r.Delete("/levels/{id}", progressHandler.DeleteLevel)
+			r.Put("/skills/{id}", progressHandler.UpdateSkill)
+			r.Delete("/skills/{id}", progressHandler.DeleteSkill)
+			r.Delete("/assessments/{id}", progressHandler.DeleteAssessment)
+			r.Delete("/level-awards/{id}", progressHandler.DeleteAward)
+			r.Route("/gradings", func(r chi.Router) {
+				r.Get("/{id}", progressHandler.GetGrading)
+				r.Put("/{id}/results", progressHandler.SaveResults)
+				r.Delete("/{id}", progressHandler.DeleteGrading)
+			})
+
+			// Coach payroll
+			r.Put("/payroll-rules/{id}", payrollHandler.UpdateRule)
+			r.Delete("/payroll-rules/{id}", payrollHandler.DeleteRule)
+			r.Route("/payroll-runs", func(r chi.Router) {
+				r.Get("/{id}", payrollHandler.GetRun)
+				r.Post("/{id}/recalculate", payrollHandler.RecalculateRun)
+				r.Post("/{id}/approve", payrollHandler.ApproveRun)
+				r.Delete("/{id}", payrollHandler.DeleteRun)
+			})
+
+			// Check-in kiosks
+			r.Post("/kiosk-devices", kioskHandler.RegisterDevice)
+			r.Delete("/kiosk-devices/{id}", kioskHandler.RevokeDevice)
+
+			// Notifications
+			r.Post("/notifications/{id}/retry", notificationHandler.Retry)
+
+			// Student imports
+			r.Route("/imports", func(r chi.Router) {
+				r.Get("/{id}", importHandler.GetByID)
+				r.Get("/{id}/rows", importHandler.ListRows)
+				r.Post("/{id}/validate", importHandler.Validate)
+				r.Post("/{id}/commit", importHandler.Commit)
+			})
+
 			// Groups
 			r.Route("/groups", func(r chi.Router) {
 				r.Post("/", groupHandler.Create)
 				r.Get("/{id}", groupHandler.GetByID)
 				r.Put("/{id}", groupHandler.Update)
 				r.Delete("/{id}", groupHandler.Delete)
+				r.Post("/{id}/restore", groupHandler.Restore)
 
 				// Nested: sessions by group
 				r.Post("/{group_id}/sessions", sessionHandler.Create)
 				r.Post("/{group_id}/sessions/recurring", sessionHandler.CreateRecurring)
 				r.Get("/{group_id}/sessions", sessionHandler.ListByGroup)
 
+				// Nested: schedule series by group
+				r.Post("/{group_id}/series", seriesHandler.Create)
+				r.Get("/{group_id}/series", seriesHandler.ListByGroup)
+
 				// Nested: subscriptions by group
 				r.Get("/{group_id}/subscriptions", subscriptionHandler.ListByGroup)
 
+				// Nested: groups where absences can be made up
+				r.Get("/{group_id}/makeup-groups", makeupHandler.GetAllowedGroups)
+				r.Put("/{group_id}/makeup-groups", makeupHandler.SetAllowedGroups)
+
 				// Nested: attendance stats
 				r.Get("/{group_id}/attendance/stats", attendanceHandler.GetStats)
+
+				// Nested: levels, skills and gradings by group
+				r.Get("/{group_id}/levels", progressHandler.ListLevels)
+				r.Post("/{group_id}/levels", progressHandler.CreateLevel)
+				r.Get("/{group_id}/skills", progressHandler.ListSkills)
+				r.Post("/{group_id}/skills", progressHandler.CreateSkill)
+				r.Get("/{group_id}/gradings", progressHandler.ListGradings)
+				r.Post("/{group_id}/gradings", progressHandler.CreateGrading)
 			})
 
 			// Sessions
@@ -172,9 +354,20 @@ func main() {
 				r.Get("/{id}", sessionHandler.GetByID)
 				r.Put("/{id}", sessionHandler.Update)
 				r.Delete("/{id}", sessionHandler.Delete)
+				r.Post("/{id}/cancel", sessionHandler.Cancel)
+				r.Post("/{id}/reschedule", sessionHandler.Reschedule)
 
 				// Nested: attendance by session
 				r.Get("/{session_id}/attendance", attendanceHandler.GetBySession)
+				r.Put("/{session_id}/attendance", attendanceHandler.UpsertSheet)
+				r.Get("/{session_id}/roster", attendanceHandler.Roster)
+			})
+
+			// Schedule series
+			r.Route("/series", func(r chi.Router) {
+				r.Get("/{id}", seriesHandler.GetByID)
+				r.Put("/{id}", seriesHandler.Update)
+				r.Delete("/{id}", seriesHandler.Delete)
 			})
 
 			// Students
@@ -183,14 +376,48 @@ func main() {
 				r.Get("/{id}", studentHandler.GetByID)
 				r.Put("/{id}", studentHandler.Update)
 				r.Delete("/{id}", studentHandler.Delete)
+				r.Post("/{id}/restore", studentHandler.Restore)
+				r.Post("/{id}/merge", duplicateHandler.Merge)
+
+				// Profile: medical, emergency contacts, documents, consents
+				r.Get("/{id}/medical", profileHandler.GetMedical)
+				r.Put("/{id}/medical", profileHandler.UpdateMedical)
+				r.Post("/{id}/medical-certificates", profileHandler.CreateCertificate)
+				r.Get("/{id}/emergency-contacts", profileHandler.GetEmergencyContacts)
+				r.Put("/{id}/emergency-contacts", profileHandler.UpdateEmergencyContacts)
+				r.Get("/{id}/documents", profileHandler.ListDocuments)
+				r.Post("/{id}/documents", profileHandler.UploadDocument)
+				r.Get("/{id}/consents", profileHandler.ListConsents)
+				r.Post("/{id}/consents", profileHandler.CreateConsent)
+
+				// Progress: levels, skill assessments and gradings
+				r.Get("/{id}/progress", progressHandler.GetStudentProgress)
+				r.Post("/{id}/assessments", progressHandler.CreateAssessment)
+				r.Post("/{id}/levels", progressHandler.AwardLevel)
+
+				// Personal data export and erasure
+				r.Get("/{id}/personal-data", privacyHandler.ExportStudent)
+				r.Post("/{id}/erase", privacyHandler.EraseStudent)
 
 				// Nested: subscriptions by student
 				r.Get("/{student_id}/subscriptions", subscriptionHandler.ListByStudent)
 
 				// Nested: attendance by student
 				r.Get("/{student_id}/attendance", attendanceHandler.GetByStudent)
+
+				// Nested: makeup credits by student
+				r.Get("/{student_id}/makeup-credits", makeupHandler.ListByStudent)
+
+				// Nested: personal QR check-in code
+				r.Get("/{student_id}/checkin-token", kioskHandler.StudentToken)
 			})
 
+			// Student profile records
+			r.Delete("/medical-certificates/{id}", profileHandler.DeleteCertificate)
+			r.Get("/documents/{id}", profileHandler.DownloadDocument)
+			r.Delete("/documents/{id}", profileHandler.DeleteDocument)
+			r.Post("/consents/{id}/revoke", profileHandler.RevokeConsent)
+
 			// Subscriptions
 			r.Route("/subscriptions", func(r chi.Router) {
 				r.Post("/", subscriptionHandler.Create)
@@ -214,9 +441,32 @@ func main() {
 				r.Post("/manual", paymentHandler.CreateManual)
 				r.Get("/{id}", paymentHandler.GetByID)
 			})
+
+			// Telegram coach bot account link
+			r.Route("/telegram", func(r chi.Router) {
+				r.Post("/link-code", telegramHandler.CreateLinkCode)
+				r.Get("/link", telegramHandler.GetLink)
+				r.Delete("/link", telegramHandler.Unlink)
+			})
+
+			// Admin: purging archived records
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(middleware.RequireRole("admin"))
+				r.Delete("/students/{id}", adminHandler.PurgeStudent)
+				r.Delete("/groups/{id}", adminHandler.PurgeGroup)
+				r.Delete("/clubs/{id}", adminHandler.PurgeClub)
+				r.Get("/purges", adminHandler.ListPurges)
+			})
 		})
 	})
 
+	// Kiosk self check-in (device token instead of a user session)
+	r.Route("/api/v1/kiosk", func(r chi.Router) {
+		r.Use(middleware.KioskAuth(kioskHandler.LookupDevice))
+		r.Post("/check-in", kioskHandler.CheckIn)
+		r.Post("/check-in/batch", kioskHandler.CheckInBatch)
+	})
+
 	// Public routes (no auth)
 	r.Route("/public", func(r chi.Router) {
 		r.Get("/club/{id}/schedule", publicHandler.Schedule)
@@ -226,6 +476,7 @@ func main() {
 	// Webhooks (special handling - no CSRF, raw body needed)
 	r.Route("/api/v1/webhooks", func(r chi.Router) {
 		r.Post("/stripe", paymentHandler.StripeWebhook)
+		r.Post("/telegram", telegramHandler.Webhook)
 	})
 
 	// Server
@@ -237,6 +488,39 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Background jobs
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Keep schedule series materialised on a rolling horizon
+	go scheduleService.RunMaterializer(bgCtx, time.Hour)
+
+	// Expire subscriptions, queue reminders and deliver queued notifications
+	go subscriptionService.RunExpirer(bgCtx, 15*time.Minute, logger)
+	go reminders.Run(bgCtx, 5*time.Minute)
+
+	if bot != nil {
+		switch cfg.Telegram.BotMode {
+		case "poll":
+			go bot.Poll(bgCtx)
+		case "webhook":
+			if cfg.Telegram.WebhookSecret == "" {
+				// The webhook handler rejects every update without a secret
+				logger.Error("TELEGRAM_WEBHOOK_SECRET is required in webhook mode, bot receives no updates")
+			} else if cfg.Telegram.WebhookURL != "" {
+				if err := telegramClient.SetWebhook(bgCtx, cfg.Telegram.WebhookURL, cfg.Telegram.WebhookSecret); err != nil {
+					logger.Error("failed to set telegram webhook", slog.String("error", err.Error()))
+				}
+			}
+		default:
+			logger.Warn("unknown TELEGRAM_BOT_MODE, bot receives no updates", slog.String("mode", cfg.Telegram.BotMode))
+		}
+	}
+	go dispatcher.Run(bgCtx, 30*time.Second)
+
+	// Validate and commit uploaded student imports
+	go importService.Run(bgCtx, 5*time.Second)
+
 	// Graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -251,6 +535,7 @@ func main() {
 
 	<-done
 	logger.Info("shutting down server...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -261,3 +546,36 @@ func main() {
 
 	logger.Info("server stopped")
 }
+
+// notificationDrivers uses a real driver for every channel with
+// credentials and a logging fake for the rest
+func notificationDrivers(cfg *config.Config, logger *slog.Logger) map[notify.Channel]notify.Driver {
+	drivers := map[notify.Channel]notify.Driver{
+		notify.ChannelEmail:    notify.NewFakeDriver(notify.ChannelEmail, logger),
+		notify.ChannelSMS:      notify.NewFakeDriver(notify.ChannelSMS, logger),
+		notify.ChannelTelegram: notify.NewFakeDriver(notify.ChannelTelegram, logger),
+	}
+	if cfg.Notify.FakeDrivers {
+		return drivers
+	}
+
+	if cfg.SMTP.Host != "" {
+		drivers[notify.ChannelEmail] = notify.NewSMTPDriver(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.User, cfg.SMTP.Password, cfg.SMTP.From)
+	}
+	if cfg.SMS.GatewayURL != "" {
+		drivers[notify.ChannelSMS] = notify.NewSMSGatewayDriver(cfg.SMS.GatewayURL, cfg.SMS.Token, cfg.SMS.Sender)
+	}
+	if cfg.Telegram.BotToken != "" {
+		drivers[notify.ChannelTelegram] = notify.NewTelegramDriver(cfg.Telegram.APIURL, cfg.Telegram.BotToken)
+	}
+	return drivers
+}
+
+// documentStorage keeps uploaded documents in S3 when it is configured and
+// on the local disk otherwise
+func documentStorage(cfg *config.Config) storage.Storage {
+	if cfg.S3.Endpoint != "" && cfg.S3.Bucket != "" {
+		return storage.NewS3(cfg.S3.Endpoint, cfg.S3.Bucket, cfg.S3.Region, cfg.S3.AccessKey, cfg.S3.SecretKey)
+	}
+	return storage.NewLocal(cfg.Storage.Dir)
+}
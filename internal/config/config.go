@@ -10,9 +10,14 @@ type Config struct {
 	Database DatabaseConfig
 	Redis    RedisConfig
 	JWT      JWTConfig
+	CheckIn  CheckInConfig
 	Stripe   StripeConfig
 	SMTP     SMTPConfig
+	SMS      SMSConfig
+	Telegram TelegramConfig
+	Notify   NotifyConfig
 	S3       S3Config
+	Storage  StorageConfig
 }
 
 type ServerConfig struct {
@@ -35,6 +40,12 @@ type JWTConfig struct {
 	RefreshTTL    time.Duration
 }
 
+// CheckInConfig holds the key that signs students' QR check-in tokens.
+// Changing it invalidates every printed code.
+type CheckInConfig struct {
+	QRSecret string
+}
+
 type StripeConfig struct {
 	SecretKey     string
 	WebhookSecret string
@@ -49,6 +60,32 @@ type SMTPConfig struct {
 	From     string
 }
 
+// SMSConfig points at an HTTP SMS gateway
+type SMSConfig struct {
+	GatewayURL string
+	Token      string
+	Sender     string
+}
+
+// TelegramConfig is shared by the notification driver and the coach bot.
+// BotMode is "webhook", "poll" for local development, or empty to run
+// without the bot.
+type TelegramConfig struct {
+	BotToken      string
+	APIURL        string
+	BotMode       string
+	BotUsername   string
+	WebhookURL    string
+	WebhookSecret string
+}
+
+// NotifyConfig controls notification delivery. With FakeDrivers set every
+// channel only logs messages, which is also what happens to a channel
+// without credentials.
+type NotifyConfig struct {
+	FakeDrivers bool
+}
+
 type S3Config struct {
 	Endpoint  string
 	AccessKey string
@@ -57,6 +94,12 @@ type S3Config struct {
 	Region    string
 }
 
+// StorageConfig is where uploaded documents are kept when S3 is not
+// configured (no S3_ENDPOINT or S3_BUCKET)
+type StorageConfig struct {
+	Dir string
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -75,6 +118,9 @@ func Load() *Config {
 			AccessTTL:  parseDuration(getEnv("JWT_ACCESS_TTL", "15m")),
 			RefreshTTL: parseDuration(getEnv("JWT_REFRESH_TTL", "168h")),
 		},
+		CheckIn: CheckInConfig{
+			QRSecret: getEnv("CHECKIN_QR_SECRET", "checkin-secret-change-me"),
+		},
 		Stripe: StripeConfig{
 			SecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
 			WebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
@@ -87,6 +133,22 @@ func Load() *Config {
 			Password: getEnv("SMTP_PASS", ""),
 			From:     getEnv("SMTP_FROM", ""),
 		},
+		SMS: SMSConfig{
+			GatewayURL: getEnv("SMS_GATEWAY_URL", ""),
+			Token:      getEnv("SMS_GATEWAY_TOKEN", ""),
+			Sender:     getEnv("SMS_SENDER", ""),
+		},
+		Telegram: TelegramConfig{
+			BotToken:      getEnv("TELEGRAM_BOT_TOKEN", ""),
+			APIURL:        getEnv("TELEGRAM_API_URL", "https://api.telegram.org"),
+			BotMode:       getEnv("TELEGRAM_BOT_MODE", ""),
+			BotUsername:   getEnv("TELEGRAM_BOT_USERNAME", ""),
+			WebhookURL:    getEnv("TELEGRAM_WEBHOOK_URL", ""),
+			WebhookSecret: getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
+		},
+		Notify: NotifyConfig{
+			FakeDrivers: getEnv("NOTIFY_FAKE_DRIVERS", "false") == "true",
+		},
 		S3: S3Config{
 			Endpoint:  getEnv("S3_ENDPOINT", ""),
 			AccessKey: getEnv("S3_ACCESS_KEY", ""),
@@ -94,6 +156,9 @@ func Load() *Config {
 			Bucket:    getEnv("S3_BUCKET", ""),
 			Region:    getEnv("S3_REGION", ""),
 		},
+		Storage: StorageConfig{
+			Dir: getEnv("STORAGE_DIR", "uploads"),
+		},
 	}
 }
 
@@ -0,0 +1,230 @@
+// Package dedupe finds students entered more than once: by staff, by an
+// import or by every purchase on the public checkout.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/importer"
+)
+
+// MinNameSimilarity is how alike two names must be, from 0 to 1, for the
+// students to be taken for the same person
+const MinNameSimilarity = 0.8
+
+// Reasons a pair was matched
+const (
+	ReasonSameName    = "same_name"
+	ReasonSimilarName = "similar_name"
+	ReasonSameBirth   = "same_birth_date"
+	ReasonSamePhone   = "same_phone"
+	ReasonSameEmail   = "same_email"
+)
+
+const (
+	// Shorter numbers are too likely to be typed in by mistake
+	minPhoneDigits = 10
+	// Score of a name whose words are all in the other one
+	subsetNameSimilarity = 0.9
+)
+
+// Student is what matching looks at
+type Student struct {
+	ID        uuid.UUID
+	Name      string
+	BirthDate *time.Time
+	Phone     string
+	Email     string
+	CreatedAt time.Time
+}
+
+// Pair is a likely duplicate. StudentID is the one created first, the
+// suggested survivor of a merge.
+type Pair struct {
+	StudentID   uuid.UUID `json:"student_id"`
+	DuplicateID uuid.UUID `json:"duplicate_id"`
+	// Score is the similarity of the names
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// Find returns the pairs of students with similar names that also share
+// a birth date, a parent phone or a parent email. Students whose birth
+// dates are both known and differ are never paired: siblings share their
+// parent's contacts. Pairs with more in common come first.
+func Find(students []Student) []Pair {
+	// Only students sharing a key can match, so names are compared within
+	// these buckets rather than across the whole club
+	buckets := make(map[string][]int)
+	for i, s := range students {
+		for _, key := range keys(s) {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	var pairs []Pair
+	for _, bucket := range buckets {
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				i, j := bucket[x], bucket[y]
+				if i > j {
+					i, j = j, i
+				}
+				if seen[[2]int{i, j}] {
+					continue
+				}
+				seen[[2]int{i, j}] = true
+
+				if pair, ok := match(students[i], students[j]); ok {
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if len(pairs[i].Reasons) != len(pairs[j].Reasons) {
+			return len(pairs[i].Reasons) > len(pairs[j].Reasons)
+		}
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		return pairs[i].StudentID.String() < pairs[j].StudentID.String()
+	})
+	return pairs
+}
+
+func keys(s Student) []string {
+	var keys []string
+	if s.BirthDate != nil {
+		keys = append(keys, "b:"+s.BirthDate.Format("2006-01-02"))
+	}
+	if phone := importer.NormalizePhone(s.Phone); len(phone) >= minPhoneDigits {
+		keys = append(keys, "p:"+phone)
+	}
+	if email := normalizeEmail(s.Email); email != "" {
+		keys = append(keys, "e:"+email)
+	}
+	return keys
+}
+
+func match(a, b Student) (Pair, bool) {
+	if a.BirthDate != nil && b.BirthDate != nil && !sameDay(*a.BirthDate, *b.BirthDate) {
+		return Pair{}, false
+	}
+
+	score := NameSimilarity(a.Name, b.Name)
+	if score < MinNameSimilarity {
+		return Pair{}, false
+	}
+
+	reasons := []string{ReasonSimilarName}
+	if score == 1 {
+		reasons[0] = ReasonSameName
+	}
+	if a.BirthDate != nil && b.BirthDate != nil {
+		reasons = append(reasons, ReasonSameBirth)
+	}
+	if phone := importer.NormalizePhone(a.Phone); len(phone) >= minPhoneDigits && phone == importer.NormalizePhone(b.Phone) {
+		reasons = append(reasons, ReasonSamePhone)
+	}
+	if email := normalizeEmail(a.Email); email != "" && email == normalizeEmail(b.Email) {
+		reasons = append(reasons, ReasonSameEmail)
+	}
+
+	if b.CreatedAt.Before(a.CreatedAt) {
+		a, b = b, a
+	}
+	return Pair{
+		StudentID:   a.ID,
+		DuplicateID: b.ID,
+		Score:       float64(int(score*100+0.5)) / 100,
+		Reasons:     reasons,
+	}, true
+}
+
+// NameSimilarity compares two names from 0 (nothing alike) to 1 (the same
+// once case, spacing and ё are ignored). Word order does not matter, small
+// typos cost little and a name contained in the other, such as "Иван
+// Петров" in "Петров Иван Сергеевич", scores 0.9.
+func NameSimilarity(a, b string) float64 {
+	a, b = importer.NormalizeName(a), importer.NormalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	sort.Strings(wordsA)
+	sort.Strings(wordsB)
+
+	score := max(ratio(a, b), ratio(strings.Join(wordsA, " "), strings.Join(wordsB, " ")))
+	if contains(wordsA, wordsB) || contains(wordsB, wordsA) {
+		score = max(score, subsetNameSimilarity)
+	}
+	return score
+}
+
+// contains reports whether every word of part is in words
+func contains(words, part []string) bool {
+	if len(part) >= len(words) {
+		return false
+	}
+	for _, p := range part {
+		found := false
+		for _, w := range words {
+			if w == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ratio is 1 minus the edit distance relative to the longer string
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func normalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
@@ -0,0 +1,73 @@
+package dedupe_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/dedupe"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Иванов Иван", "иванов  иван", 1, 1},
+		{"Алёна Ким", "Алена Ким", 1, 1},
+		{"Иванов Иван", "Иван Иванов", 0.99, 1},
+		{"Иванов Иван", "Иванов Иван Сергеевич", 0.9, 0.99},
+		{"Сейітова Айгерім", "Сейитова Айгерим", 0.8, 0.99},
+		{"Петров Пётр", "Петрова Петра", 0.8, 0.99},
+		{"Иванов Иван", "Петров Пётр", 0, 0.5},
+		{"Иванов Иван", "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := dedupe.NameSimilarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("NameSimilarity(%q, %q) = %.2f, expected %.2f..%.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return &d
+	}
+	created := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	first := dedupe.Student{ID: uuid.New(), Name: "Иванов Иван", BirthDate: day("2015-03-04"), Phone: "8 (701) 111-22-33", CreatedAt: created}
+	// Bought online later: no birth date, same parent phone, a typo in the name
+	checkout := dedupe.Student{ID: uuid.New(), Name: "Иванов Ивн", Phone: "+77011112233", CreatedAt: created.Add(time.Hour)}
+	// A sibling: same phone, different birth date
+	sibling := dedupe.Student{ID: uuid.New(), Name: "Иванова Инна", BirthDate: day("2017-06-01"), Phone: "+77011112233", CreatedAt: created}
+	// A namesake in another family
+	namesake := dedupe.Student{ID: uuid.New(), Name: "Иван Иванов", BirthDate: day("2014-01-01"), Email: "x@example.com", CreatedAt: created}
+	// Same email and birth date, entered earlier by staff
+	staff := dedupe.Student{ID: uuid.New(), Name: "Kim Alina", BirthDate: day("2016-02-02"), Email: "Kim@Example.com", CreatedAt: created.Add(time.Hour)}
+	online := dedupe.Student{ID: uuid.New(), Name: "Alina Kim", BirthDate: day("2016-02-02"), Email: " kim@example.com", CreatedAt: created}
+
+	pairs := dedupe.Find([]dedupe.Student{checkout, first, sibling, namesake, staff, online})
+
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %+v", pairs)
+	}
+
+	// More in common comes first
+	if pairs[0].StudentID != online.ID || pairs[0].DuplicateID != staff.ID {
+		t.Errorf("expected the earlier record to survive, got %+v", pairs[0])
+	}
+	if got := strings.Join(pairs[0].Reasons, ","); got != "same_name,same_birth_date,same_email" {
+		t.Errorf("unexpected reasons %q", got)
+	}
+
+	if pairs[1].StudentID != first.ID || pairs[1].DuplicateID != checkout.ID {
+		t.Errorf("unexpected pair %+v", pairs[1])
+	}
+	if got := strings.Join(pairs[1].Reasons, ","); got != "similar_name,same_phone" {
+		t.Errorf("unexpected reasons %q", got)
+	}
+}
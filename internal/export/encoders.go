@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/notify"
+	"github.com/neo/trainer-plus/pkg/pdf"
+	"github.com/neo/trainer-plus/pkg/xlsx"
+)
+
+// csvFlushEvery is how often, in rows, CSV output is pushed to the client
+const csvFlushEvery = 500
+
+// csvEncoder writes values as formatted for the locale. Russian and Kazakh
+// files use ';' like spreadsheets set up for those languages expect, since
+// ',' is their decimal mark.
+type csvEncoder struct {
+	w      *csv.Writer
+	tables int
+	rows   int
+}
+
+func newCSVEncoder(w io.Writer, locale Locale) *csvEncoder {
+	// The BOM makes Excel read the file as UTF-8
+	io.WriteString(w, "\ufeff")
+
+	cw := csv.NewWriter(w)
+	if locale.Lang != notify.LangEN {
+		cw.Comma = ';'
+	}
+	return &csvEncoder{w: cw}
+}
+
+func (e *csvEncoder) table(title string, headers []string, _ []Kind) error {
+	if e.tables > 0 {
+		e.w.Write(nil)
+	}
+	e.tables++
+	if title != "" {
+		e.w.Write([]string{title})
+	}
+	return e.w.Write(headers)
+}
+
+func (e *csvEncoder) row(cells []cell) error {
+	record := make([]string, len(cells))
+	for i, c := range cells {
+		record[i] = c.Text
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%csvFlushEvery == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// xlsxEncoder writes numbers and dates as such, formatted by Excel
+type xlsxEncoder struct {
+	w     *xlsx.Writer
+	title string
+}
+
+func newXLSXEncoder(w io.Writer, locale Locale, title string) *xlsxEncoder {
+	opts := xlsx.Options{MoneyFormat: locale.moneyFormat()}
+	if locale.Lang == notify.LangEN {
+		opts.DateFormat = "yyyy-mm-dd"
+		opts.DateTimeFormat = "yyyy-mm-dd hh:mm"
+	}
+	return &xlsxEncoder{w: xlsx.NewWriter(w, opts), title: title}
+}
+
+func (e *xlsxEncoder) table(title string, headers []string, kinds []Kind) error {
+	if title == "" {
+		title = e.title
+	}
+
+	widths := make([]float64, len(kinds))
+	for i, k := range kinds {
+		switch {
+		case k == Text || k == Label:
+			widths[i] = 28
+		case k == DateTime:
+			widths[i] = 17
+		default:
+			widths[i] = 14
+		}
+	}
+	if err := e.w.AddSheet(title, widths...); err != nil {
+		return err
+	}
+
+	cells := make([]xlsx.Cell, len(headers))
+	for i, h := range headers {
+		cells[i] = xlsx.Cell{Value: h, Style: xlsx.StyleBold}
+	}
+	return e.w.WriteRow(cells...)
+}
+
+var xlsxStyles = map[Kind]xlsx.Style{
+	Int:      xlsx.StyleInteger,
+	Number:   xlsx.StyleDecimal,
+	Money:    xlsx.StyleMoney,
+	Percent:  xlsx.StylePercent,
+	Day:      xlsx.StyleDate,
+	DateTime: xlsx.StyleDateTime,
+}
+
+func (e *xlsxEncoder) row(cells []cell) error {
+	row := make([]xlsx.Cell, len(cells))
+	for i, c := range cells {
+		row[i] = xlsx.Cell{Value: c.Value, Style: xlsxStyles[c.Kind]}
+	}
+	return e.w.WriteRow(row...)
+}
+
+func (e *xlsxEncoder) close() error {
+	return e.w.Close()
+}
+
+// pdfEncoder prints a landscape A4 document, numbers aligned right
+type pdfEncoder struct {
+	doc *pdf.Document
+}
+
+func newPDFEncoder(w io.Writer, locale Locale, info Info) (*pdfEncoder, error) {
+	doc := pdf.New(w, pdf.Options{
+		Landscape: true,
+		Footer:    locale.T("generated") + " " + locale.DateTime(time.Now()),
+	})
+	if err := doc.Title(locale.T(info.Title)); err != nil {
+		return nil, err
+	}
+	if info.Subtitle != "" {
+		if err := doc.Text(info.Subtitle); err != nil {
+			return nil, err
+		}
+	}
+	return &pdfEncoder{doc: doc}, nil
+}
+
+func (e *pdfEncoder) table(title string, headers []string, kinds []Kind) error {
+	if title != "" {
+		if err := e.doc.Text(title); err != nil {
+			return err
+		}
+	}
+
+	columns := make([]pdf.Column, len(headers))
+	for i, h := range headers {
+		columns[i] = pdf.Column{Header: h, Width: 1.5}
+		switch kinds[i] {
+		case Text, Label:
+			columns[i].Width = 3
+		case DateTime:
+			columns[i].Width = 2
+		}
+		if kinds[i].numeric() {
+			columns[i].Align = pdf.AlignRight
+		}
+	}
+	return e.doc.Table(columns)
+}
+
+func (e *pdfEncoder) row(cells []cell) error {
+	texts := make([]string, len(cells))
+	for i, c := range cells {
+		texts[i] = c.Text
+	}
+	return e.doc.Row(texts...)
+}
+
+func (e *pdfEncoder) close() error {
+	return e.doc.Close()
+}
@@ -0,0 +1,272 @@
+// Package export writes reports and lists as CSV, XLSX or PDF downloads.
+// Documents are streamed: rows go out as they are written, so a list can be
+// exported straight from a database cursor.
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatPDF  Format = "pdf"
+)
+
+var contentTypes = map[Format]string{
+	FormatJSON: "application/json",
+	FormatCSV:  "text/csv",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatPDF:  "application/pdf",
+}
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format, use json, csv, xlsx or pdf")
+	// ErrInterrupted wraps errors that happened after the download had
+	// started, when an error response can no longer be sent
+	ErrInterrupted = errors.New("export interrupted")
+)
+
+// writeTimeout is how long each chunk of a download may take to send. The
+// server's WriteTimeout covers the whole response, which a large export
+// can outlast, so the deadline is pushed back as long as data flows.
+const writeTimeout = 30 * time.Second
+
+// FormatFromRequest reads ?format=, falling back to the first supported
+// type in the Accept header and then to JSON
+func FormatFromRequest(r *http.Request) (Format, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		format := Format(strings.ToLower(f))
+		if _, ok := contentTypes[format]; !ok {
+			return "", ErrUnsupportedFormat
+		}
+		return format, nil
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		for format, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format, nil
+			}
+		}
+	}
+	return FormatJSON, nil
+}
+
+// Kind says how the values of a column are formatted
+type Kind int
+
+const (
+	Text Kind = iota
+	// Label values are translated with the column key as prefix, e.g.
+	// "payment_status.succeeded"; unknown ones are written as they are
+	Label
+	Int
+	Number
+	Money
+	// Percent values are already percentages: 12.5 is 12.5%
+	Percent
+	// Date is a timestamp shown as its day in the club's time zone
+	Date
+	DateTime
+	// Day is a calendar date such as a birth date, or a "2006-01-02"
+	// string; it is not moved to another time zone
+	Day
+)
+
+func (k Kind) numeric() bool {
+	return k == Int || k == Number || k == Money || k == Percent
+}
+
+// Column of a table. Key is the message key of its header; Title, when
+// set, is the header as is, e.g. the label of a club's custom field.
+type Column struct {
+	Key   string
+	Title string
+	Kind  Kind
+}
+
+// Metric is a line of a report summary
+type Metric struct {
+	Key   string
+	Kind  Kind
+	Value interface{}
+}
+
+// Info describes a document
+type Info struct {
+	// Name is the file name without date and extension, e.g. "finance"
+	Name string
+	// Title is the message key of the title
+	Title string
+	// Subtitle is printed under the title, e.g. the period of a report
+	Subtitle string
+}
+
+// cell is a value ready for an encoder: Value is a string, float64 or
+// time.Time, or nil for an empty cell, and Text its localized form
+type cell struct {
+	Kind  Kind
+	Value interface{}
+	Text  string
+}
+
+type encoder interface {
+	table(title string, headers []string, kinds []Kind) error
+	row(cells []cell) error
+	close() error
+}
+
+// Document is a download being written: one or more tables
+type Document struct {
+	locale  Locale
+	enc     encoder
+	columns []Column
+}
+
+// Table starts a table. In XLSX each table is a sheet, in CSV and PDF the
+// tables follow each other under their titles. An empty title leaves the
+// title out; in XLSX the sheet is then named after the document.
+func (d *Document) Table(title string, columns ...Column) error {
+	d.columns = columns
+	headers := make([]string, len(columns))
+	kinds := make([]Kind, len(columns))
+	for i, c := range columns {
+		headers[i] = c.Title
+		if headers[i] == "" {
+			headers[i] = d.locale.T(c.Key)
+		}
+		kinds[i] = c.Kind
+	}
+	if title != "" {
+		title = d.locale.T(title)
+	}
+	return d.enc.table(title, headers, kinds)
+}
+
+// Row writes a row of the current table, one value per column. Values may
+// be strings, numbers, times, pointers to them or nil.
+func (d *Document) Row(values ...interface{}) error {
+	if d.columns == nil {
+		return errors.New("export: no table started")
+	}
+	cells := make([]cell, len(d.columns))
+	for i, c := range d.columns {
+		var v interface{}
+		if i < len(values) {
+			v = values[i]
+		}
+		cells[i] = d.locale.cell(c, v)
+	}
+	return d.enc.row(cells)
+}
+
+// Summary writes the metrics of a report as a two column table
+func (d *Document) Summary(metrics ...Metric) error {
+	if err := d.Table("summary", Column{Key: "metric"}, Column{Key: "value", Kind: Number}); err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		cells := []cell{
+			{Kind: Text, Value: d.locale.T(m.Key), Text: d.locale.T(m.Key)},
+			d.locale.cell(Column{Key: m.Key, Kind: m.Kind}, m.Value),
+		}
+		if err := d.enc.row(cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write sends the document built by fn as a download. Output is buffered,
+// so an error before much was written is returned while an error response
+// can still be sent; later errors are wrapped in ErrInterrupted.
+func Write(w http.ResponseWriter, format Format, locale Locale, info Info, fn func(d *Document) error) error {
+	rw := &responseWriter{
+		w:        w,
+		rc:       http.NewResponseController(w),
+		format:   format,
+		filename: filename(info.Name, format, locale),
+	}
+	// Queries before the first row may already have used up the deadline
+	rw.extendDeadline()
+	bw := bufio.NewWriterSize(rw, 32<<10)
+
+	enc, err := newEncoder(bw, format, locale, info)
+	if err == nil {
+		err = fn(&Document{locale: locale, enc: enc})
+	}
+	if err == nil {
+		err = enc.close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+
+	if err != nil && rw.sent {
+		return fmt.Errorf("%w: %v", ErrInterrupted, err)
+	}
+	return err
+}
+
+func newEncoder(w io.Writer, format Format, locale Locale, info Info) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w, locale), nil
+	case FormatXLSX:
+		return newXLSXEncoder(w, locale, locale.T(info.Title)), nil
+	case FormatPDF:
+		return newPDFEncoder(w, locale, info)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func filename(name string, format Format, locale Locale) string {
+	return fmt.Sprintf("%s_%s.%s", name, time.Now().In(locale.location()).Format("2006-01-02"), format)
+}
+
+// responseWriter sets the download headers on the first write, so nothing
+// is committed while an error response is still possible
+type responseWriter struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	format   Format
+	filename string
+	sent     bool
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.sent {
+		rw.sent = true
+		contentType := contentTypes[rw.format]
+		if rw.format == FormatCSV {
+			contentType += "; charset=utf-8"
+		}
+		rw.w.Header().Set("Content-Type", contentType)
+		rw.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": rw.filename}))
+		rw.w.WriteHeader(http.StatusOK)
+	}
+	rw.extendDeadline()
+	return rw.w.Write(p)
+}
+
+// extendDeadline gives the next write writeTimeout to complete. Writers
+// that do not support deadlines keep the server's timeout.
+func (rw *responseWriter) extendDeadline() {
+	rw.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+}
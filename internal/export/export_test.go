@@ -0,0 +1,297 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/export"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/notify"
+	"github.com/neo/trainer-plus/pkg/xlsx"
+)
+
+func TestFormatFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   export.Format
+	}{
+		{"default", "/", "", export.FormatJSON},
+		{"query", "/?format=csv", "", export.FormatCSV},
+		{"query case", "/?format=XLSX", "", export.FormatXLSX},
+		{"query wins", "/?format=pdf", "text/csv", export.FormatPDF},
+		{"accept", "/", "application/pdf", export.FormatPDF},
+		{"accept list", "/", "text/html, text/csv;q=0.9", export.FormatCSV},
+		{"accept json", "/", "application/json", export.FormatJSON},
+		{"accept anything", "/", "*/*", export.FormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := export.FormatFromRequest(r)
+			if err != nil {
+				t.Fatalf("FormatFromRequest: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?format=docx", nil)
+	if _, err := export.FormatFromRequest(r); !errors.Is(err, export.ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestLocale_Money(t *testing.T) {
+	tests := []struct {
+		lang     notify.Language
+		currency string
+		amount   float64
+		want     string
+	}{
+		{notify.LangRU, "KZT", 12500, "12\u00a0500,00\u00a0₸"},
+		{notify.LangKK, "KZT", 1234567.5, "1\u00a0234\u00a0567,50\u00a0₸"},
+		{notify.LangRU, "RUB", -990, "-990,00\u00a0₽"},
+		{notify.LangEN, "USD", 1234.5, "$1,234.50"},
+		{notify.LangEN, "KZT", -12500, "-₸12,500.00"},
+		{notify.LangEN, "UZS", 100, "100.00\u00a0UZS"},
+		{notify.LangRU, "", 0.004, "0,00"},
+	}
+
+	for _, tt := range tests {
+		l := export.Locale{Lang: tt.lang, Currency: tt.currency}
+		if got := l.Money(tt.amount); got != tt.want {
+			t.Errorf("Money(%v) in %s %s: expected %q, got %q", tt.amount, tt.lang, tt.currency, tt.want, got)
+		}
+	}
+}
+
+func TestLocale_T(t *testing.T) {
+	if got := (export.Locale{Lang: notify.LangKK}).T("list.students"); got != "Оқушылар" {
+		t.Errorf("expected Kazakh title, got %q", got)
+	}
+	if got := (export.Locale{Lang: notify.LangEN}).T("no.such.key"); got != "no.such.key" {
+		t.Errorf("expected the key back, got %q", got)
+	}
+}
+
+func TestLocaleFor(t *testing.T) {
+	tests := []struct {
+		url, acceptLanguage string
+		want                notify.Language
+	}{
+		{"/", "", notify.LangRU},
+		{"/?lang=en", "kk", notify.LangEN},
+		{"/", "de-DE, kk-KZ;q=0.8, en;q=0.5", notify.LangKK},
+		{"/", "en-US", notify.LangEN},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+		club := testClub()
+		if got := export.LocaleFor(r, &club).Lang; got != tt.want {
+			t.Errorf("%s with %q: expected %s, got %s", tt.url, tt.acceptLanguage, tt.want, got)
+		}
+	}
+}
+
+func testClub() model.Club {
+	return model.Club{Currency: "KZT", Timezone: "Asia/Almaty"}
+}
+
+var almaty = time.FixedZone("Asia/Almaty", 5*60*60)
+
+// writeSample exports a summary and a list in the given format
+func writeSample(t *testing.T, format export.Format, lang notify.Language) *httptest.ResponseRecorder {
+	t.Helper()
+
+	locale := export.Locale{Lang: lang, Currency: "KZT", Location: almaty}
+	info := export.Info{Name: "finance", Title: "report.finance", Subtitle: "01.10.2026 – 31.10.2026"}
+	paidAt := time.Date(2026, 10, 5, 20, 30, 0, 0, time.UTC) // 6 October in Almaty
+
+	w := httptest.NewRecorder()
+	err := export.Write(w, format, locale, info, func(d *export.Document) error {
+		err := d.Summary(
+			export.Metric{Key: "total_paid", Kind: export.Money, Value: 25000.0},
+			export.Metric{Key: "payment_count", Kind: export.Int, Value: 2},
+		)
+		if err != nil {
+			return err
+		}
+
+		err = d.Table("",
+			export.Column{Key: "created_at", Kind: export.Date},
+			export.Column{Key: "student"},
+			export.Column{Key: "amount", Kind: export.Money},
+			export.Column{Key: "payment_method", Kind: export.Label},
+			export.Column{Key: "paid_at", Kind: export.DateTime},
+		)
+		if err != nil {
+			return err
+		}
+		if err := d.Row(paidAt, "Айгерім Сейітова", 12500.0, "cash", &paidAt); err != nil {
+			return err
+		}
+		var unpaid *time.Time
+		return d.Row(paidAt, "Иван; \"Ваня\"", 12500.0, "bitcoin", unpaid)
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return w
+}
+
+func TestWrite_CSV(t *testing.T) {
+	w := writeSample(t, export.FormatCSV, notify.LangRU)
+
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename=finance_`) || !strings.HasSuffix(got, `.csv`) {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "\ufeff") {
+		t.Fatal("expected a UTF-8 BOM")
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(body, "\ufeff")))
+	r.Comma = ';'
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	want := [][]string{
+		{"Итоги"},
+		{"Показатель", "Значение"},
+		{"Оплачено", "25\u00a0000,00\u00a0₸"},
+		{"Платежей", "2"},
+		{"Создан", "Ученик", "Сумма", "Способ оплаты", "Оплачен"},
+		{"06.10.2026", "Айгерім Сейітова", "12\u00a0500,00\u00a0₸", "Наличные", "06.10.2026 01:30"},
+		{"06.10.2026", "Иван; \"Ваня\"", "12\u00a0500,00\u00a0₸", "bitcoin", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %d: %q", len(want), len(records), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("record %d: expected %q, got %q", i, want[i], records[i])
+		}
+	}
+}
+
+func TestWrite_CSVEnglishUsesCommas(t *testing.T) {
+	w := writeSample(t, export.FormatCSV, notify.LangEN)
+	if !strings.Contains(w.Body.String(), "Total paid,\"₸25,000.00\"") {
+		t.Errorf("unexpected body:\n%s", w.Body.String())
+	}
+}
+
+func TestWrite_XLSX(t *testing.T) {
+	w := writeSample(t, export.FormatXLSX, notify.LangRU)
+
+	body := w.Body.Bytes()
+	rows, err := xlsx.ReadRows(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("ReadRows: %v", err)
+	}
+
+	// The reader sees the first sheet, the summary; numbers stay numbers
+	want := [][]string{
+		{"Показатель", "Значение"},
+		{"Оплачено", "25000"},
+		{"Платежей", "2"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %q", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestWrite_PDF(t *testing.T) {
+	w := writeSample(t, export.FormatPDF, notify.LangKK)
+
+	body := w.Body.String()
+	if w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+	if !strings.HasPrefix(body, "%PDF-1.4") || !strings.HasSuffix(body, "%%EOF\n") {
+		t.Fatal("expected a complete PDF")
+	}
+	for _, s := range []string{"/Type /Catalog", "/Count 1", "/BaseFont /Helvetica-Bold", "/uni04D9", "startxref"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("expected %q in the document", s)
+		}
+	}
+}
+
+func TestWrite_ErrorBeforeOutput(t *testing.T) {
+	w := httptest.NewRecorder()
+	boom := errors.New("boom")
+
+	err := export.Write(w, export.FormatXLSX, export.Locale{}, export.Info{Name: "students"}, func(d *export.Document) error {
+		return boom
+	})
+	if !errors.Is(err, boom) || errors.Is(err, export.ErrInterrupted) {
+		t.Fatalf("expected the error itself, got %v", err)
+	}
+	if w.Body.Len() != 0 || w.Header().Get("Content-Disposition") != "" {
+		t.Error("expected nothing written, so an error response can still be sent")
+	}
+}
+
+func TestWrite_OutlastsServerWriteTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		export.Write(w, export.FormatCSV, export.Locale{}, export.Info{Name: "students"}, func(d *export.Document) error {
+			if err := d.Table("", export.Column{Key: "name"}); err != nil {
+				return err
+			}
+			// A slow query, well past the server's WriteTimeout
+			time.Sleep(300 * time.Millisecond)
+			return d.Row("Aruzhan")
+		})
+	}
+
+	srv := httptest.NewUnstartedServer(middleware.Logger(logger)(http.HandlerFunc(handler)))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if !strings.Contains(string(body), "Aruzhan") {
+		t.Errorf("expected the whole export, got %q", body)
+	}
+}
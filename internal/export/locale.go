@@ -0,0 +1,264 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/notify"
+)
+
+// nbsp keeps amounts and their currency on one line
+const nbsp = "\u00a0"
+
+// Locale is the language, currency and time zone a document is written in
+type Locale struct {
+	Lang     notify.Language
+	Currency string // ISO 4217 code, e.g. KZT
+	Location *time.Location
+}
+
+// LocaleFor takes the language from ?lang= or Accept-Language and the
+// currency and time zone from the club
+func LocaleFor(r *http.Request, club *model.Club) Locale {
+	return Locale{
+		Lang:     requestLanguage(r),
+		Currency: club.Currency,
+		Location: club.Location(),
+	}
+}
+
+func requestLanguage(r *http.Request) notify.Language {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return notify.ParseLanguage(lang)
+	}
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		primary := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		switch primary {
+		case "ru", "kk", "kz", "en":
+			return notify.ParseLanguage(primary)
+		}
+	}
+	return notify.DefaultLanguage
+}
+
+func (l Locale) location() *time.Location {
+	if l.Location == nil {
+		return time.UTC
+	}
+	return l.Location
+}
+
+// T translates a message key, returning the key itself when unknown
+func (l Locale) T(key string) string {
+	m, ok := messages[key]
+	if !ok {
+		return key
+	}
+	switch l.Lang {
+	case notify.LangKK:
+		return m.kk
+	case notify.LangEN:
+		return m.en
+	default:
+		return m.ru
+	}
+}
+
+// Number formats f with the language's digit grouping and decimal mark
+func (l Locale) Number(f float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	group, point := nbsp, ","
+	if l.Lang == notify.LangEN {
+		group, point = ",", "."
+	}
+
+	var b strings.Builder
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(point + fraction)
+	}
+	return b.String()
+}
+
+// currencySymbols of the currencies clubs use; others are written as
+// their code
+var currencySymbols = map[string]string{
+	"KZT": "₸",
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+}
+
+// Money formats an amount in the club's currency: "12 500,00 ₸" in
+// Russian and Kazakh, "₸12,500.00" in English
+func (l Locale) Money(amount float64) string {
+	symbol, known := currencySymbols[l.Currency]
+	if !known {
+		symbol = l.Currency
+	}
+
+	s := l.Number(amount, 2)
+	switch {
+	case symbol == "":
+		return s
+	case l.Lang == notify.LangEN && known:
+		if strings.HasPrefix(s, "-") {
+			return "-" + symbol + s[1:]
+		}
+		return symbol + s
+	default:
+		return s + nbsp + symbol
+	}
+}
+
+// moneyFormat is the Excel number format of Money
+func (l Locale) moneyFormat() string {
+	symbol, known := currencySymbols[l.Currency]
+	if !known {
+		symbol = l.Currency
+	}
+	switch {
+	case symbol == "":
+		return "#,##0.00"
+	case l.Lang == notify.LangEN && known:
+		return fmt.Sprintf(`"%s"#,##0.00`, symbol)
+	default:
+		return fmt.Sprintf(`#,##0.00 "%s"`, symbol)
+	}
+}
+
+func (l Locale) dateLayout() string {
+	if l.Lang == notify.LangEN {
+		return "2006-01-02"
+	}
+	return "02.01.2006"
+}
+
+// Date formats the day of t in the locale's time zone
+func (l Locale) Date(t time.Time) string {
+	return t.In(l.location()).Format(l.dateLayout())
+}
+
+// DateTime formats t in the locale's time zone
+func (l Locale) DateTime(t time.Time) string {
+	return t.In(l.location()).Format(l.dateLayout() + " 15:04")
+}
+
+// Period formats the days from and to, e.g. "01.10.2026 – 31.10.2026"
+func (l Locale) Period(from, to time.Time) string {
+	return l.Date(from) + " – " + l.Date(to)
+}
+
+// cell normalizes v for a column and formats it
+func (l Locale) cell(c Column, v interface{}) cell {
+	v = deref(v)
+	if v == nil {
+		return cell{Kind: c.Kind}
+	}
+
+	switch c.Kind {
+	case Int, Number, Money, Percent:
+		f, ok := toFloat(v)
+		if !ok {
+			break
+		}
+		out := cell{Kind: c.Kind, Value: f}
+		switch c.Kind {
+		case Int:
+			out.Text = l.Number(f, 0)
+		case Number:
+			out.Text = l.Number(f, 2)
+		case Money:
+			out.Text = l.Money(f)
+		case Percent:
+			out.Text = l.Number(f, 1) + "%"
+		}
+		return out
+
+	case Date, DateTime, Day:
+		t, ok := v.(time.Time)
+		if s, isString := v.(string); isString {
+			parsed, err := time.Parse("2006-01-02", s)
+			t, ok = parsed, err == nil
+		}
+		if !ok {
+			break
+		}
+		switch c.Kind {
+		case Date:
+			t = t.In(l.location())
+			return cell{Kind: Day, Value: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Text: t.Format(l.dateLayout())}
+		case DateTime:
+			t = t.In(l.location())
+			return cell{Kind: DateTime, Value: t, Text: t.Format(l.dateLayout() + " 15:04")}
+		default:
+			return cell{Kind: Day, Value: t, Text: t.Format(l.dateLayout())}
+		}
+
+	case Label:
+		s := fmt.Sprint(v)
+		text := l.T(c.Key + "." + s)
+		if text == c.Key+"."+s {
+			text = s
+		}
+		return cell{Kind: Text, Value: text, Text: text}
+	}
+
+	s := fmt.Sprint(v)
+	return cell{Kind: Text, Value: s, Text: s}
+}
+
+// deref turns nil pointers into nil and other pointers into their values
+func deref(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *string:
+		if p == nil {
+			return nil
+		}
+		return *p
+	case *int:
+		if p == nil {
+			return nil
+		}
+		return *p
+	case *float64:
+		if p == nil {
+			return nil
+		}
+		return *p
+	case *time.Time:
+		if p == nil {
+			return nil
+		}
+		return *p
+	}
+	return v
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
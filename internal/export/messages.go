@@ -0,0 +1,167 @@
+package export
+
+// message is a text in Russian, Kazakh and English
+type message struct {
+	ru, kk, en string
+}
+
+// messages are the titles, column headers and labels of exports
+var messages = map[string]message{
+	// Documents
+	"report.finance":     {"Финансовый отчёт", "Қаржы есебі", "Finance report"},
+	"report.occupancy":   {"Заполняемость групп", "Топтардың толымдылығы", "Group occupancy"},
+	"report.utilisation": {"Загрузка залов", "Залдардың жүктемесі", "Location utilisation"},
+	"report.mrr":         {"Ежемесячная выручка", "Ай сайынғы түсім", "Monthly recurring revenue"},
+	"report.students":    {"Активность учеников", "Оқушылардың белсенділігі", "Student activity"},
+	"report.debt":        {"Задолженности", "Берешектер", "Outstanding payments"},
+	"report.trials":      {"Пробные занятия", "Сынақ сабақтары", "Trial lessons"},
+	"report.levels":      {"Уровни учеников", "Оқушылардың деңгейлері", "Student levels"},
+	"report.retention":   {"Удержание учеников", "Оқушыларды ұстап қалу", "Student retention"},
+	"report.revenue":     {"Признание выручки", "Түсімді тану", "Revenue recognition"},
+	"report.payroll":     {"Зарплата тренеров", "Жаттықтырушылардың жалақысы", "Coach payroll"},
+	"report.dashboard":   {"Сводка", "Жиынтық", "Dashboard"},
+	"list.students":      {"Ученики", "Оқушылар", "Students"},
+	"list.subscriptions": {"Абонементы", "Абонементтер", "Subscriptions"},
+	"list.payments":      {"Платежи", "Төлемдер", "Payments"},
+	"generated":          {"Сформировано", "Жасалған уақыты", "Generated"},
+
+	// Tables
+	"summary":                {"Итоги", "Қорытынды", "Summary"},
+	"payments_by_method":     {"По способам оплаты", "Төлем тәсілдері бойынша", "By payment method"},
+	"payments_by_group":      {"По группам", "Топтар бойынша", "By group"},
+	"daily_revenue":          {"По дням", "Күндер бойынша", "By day"},
+	"group_stats":            {"Группы", "Топтар", "Groups"},
+	"locations":              {"Залы", "Залдар", "Locations"},
+	"top_students":           {"Самые активные", "Ең белсенділер", "Most active"},
+	"debtors":                {"Должники", "Қарыздарлар", "Debtors"},
+	"leads":                  {"Пробные ученики", "Сынақ оқушылары", "Trial students"},
+	"levels":                 {"По уровням", "Деңгейлер бойынша", "By level"},
+	"cohorts":                {"Когорты", "Когорттар", "Cohorts"},
+	"churn_by_group":         {"Отток по группам", "Топтар бойынша кету", "Churn by group"},
+	"churn_by_coach":         {"Отток по тренерам", "Жаттықтырушылар бойынша кету", "Churn by coach"},
+	"at_risk":                {"Ходят реже", "Сирек келетіндер", "At risk"},
+	"revenue_by_month":       {"По месяцам", "Айлар бойынша", "By month"},
+	"revenue_by_group":       {"По группам", "Топтар бойынша", "By group"},
+	"revenue_by_group_month": {"По группам и месяцам", "Топтар мен айлар бойынша", "By group and month"},
+	"coaches":                {"Тренеры", "Жаттықтырушылар", "Coaches"},
+	"payroll_items":          {"Начисления", "Есептеулер", "Line items"},
+
+	// Columns
+	"metric":                {"Показатель", "Көрсеткіш", "Metric"},
+	"value":                 {"Значение", "Мәні", "Value"},
+	"amount":                {"Сумма", "Сомасы", "Amount"},
+	"count":                 {"Количество", "Саны", "Count"},
+	"group":                 {"Группа", "Топ", "Group"},
+	"date":                  {"Дата", "Күні", "Date"},
+	"capacity":              {"Вместимость", "Сыйымдылығы", "Capacity"},
+	"session_count":         {"Занятий", "Сабақтар", "Sessions"},
+	"total_present":         {"Посещений", "Қатысулар", "Visits"},
+	"avg_fill_rate":         {"Средняя заполняемость", "Орташа толымдылық", "Average fill rate"},
+	"location":              {"Зал", "Зал", "Location"},
+	"booked_hours":          {"Занято часов", "Бос емес сағаттар", "Booked hours"},
+	"booked_hours_per_week": {"Занято часов в неделю", "Аптасына бос емес сағаттар", "Booked hours per week"},
+	"open_hours_per_week":   {"Часов работы в неделю", "Аптасына жұмыс сағаттары", "Open hours per week"},
+	"utilisation_rate":      {"Загрузка", "Жүктеме", "Utilisation"},
+	"student":               {"Ученик", "Оқушы", "Student"},
+	"present_count":         {"Присутствовал", "Қатысты", "Present"},
+	"attendance_rate":       {"Посещаемость", "Қатысым", "Attendance rate"},
+	"name":                  {"Имя", "Аты", "Name"},
+	"birth_date":            {"Дата рождения", "Туған күні", "Birth date"},
+	"notes":                 {"Заметки", "Жазбалар", "Notes"},
+	"tags":                  {"Теги", "Тегтер", "Tags"},
+	"parent_name":           {"Родитель", "Ата-ана", "Parent"},
+	"parent_phone":          {"Телефон родителя", "Ата-ананың телефоны", "Parent phone"},
+	"parent_email":          {"Email родителя", "Ата-ананың email-і", "Parent email"},
+	"created_at":            {"Создан", "Құрылған", "Created"},
+	"days_overdue":          {"Дней просрочки", "Мерзімі өткен күндер", "Days overdue"},
+	"first_trial_at":        {"Первое пробное", "Алғашқы сынақ", "First trial"},
+	"trial_count":           {"Пробных", "Сынақтар", "Trials"},
+	"converted_at":          {"Купил абонемент", "Абонемент алды", "Converted"},
+	"total_sessions":        {"Всего занятий", "Барлық сабақтар", "Total sessions"},
+	"remaining_sessions":    {"Осталось занятий", "Қалған сабақтар", "Remaining sessions"},
+	"price":                 {"Цена", "Бағасы", "Price"},
+	"starts_at":             {"Начало", "Басталуы", "Starts"},
+	"expires_at":            {"Окончание", "Аяқталуы", "Expires"},
+	"paid_at":               {"Оплачен", "Төленген", "Paid"},
+	"level":                 {"Уровень", "Деңгей", "Level"},
+	"students":              {"Учеников", "Оқушылар", "Students"},
+	"without_level":         {"Без уровня", "Деңгейсіз", "Without level"},
+	"share":                 {"Доля", "Үлесі", "Share"},
+	"cohort":                {"Когорта", "Когорта", "Cohort"},
+	"coach":                 {"Тренер", "Жаттықтырушы", "Coach"},
+	"previous_visits":       {"Посещений раньше", "Бұрынғы қатысулар", "Visits before"},
+	"recent_visits":         {"Посещений недавно", "Соңғы қатысулар", "Recent visits"},
+	"last_visit_at":         {"Последнее посещение", "Соңғы қатысу", "Last visit"},
+	"deferred":              {"Доходы будущих периодов", "Болашақ кезеңдердің кірістері", "Deferred revenue"},
+	"attendees":             {"Учеников на занятиях", "Сабақтағы оқушылар", "Attendees"},
+	"rate":                  {"Ставка", "Мөлшерлеме", "Rate"},
+	"quantity":              {"Количество", "Саны", "Quantity"},
+
+	// Metrics
+	"total_paid":               {"Оплачено", "Төленді", "Total paid"},
+	"total_refunded":           {"Возвращено", "Қайтарылды", "Total refunded"},
+	"net_revenue":              {"Чистая выручка", "Таза түсім", "Net revenue"},
+	"payment_count":            {"Платежей", "Төлемдер саны", "Payments"},
+	"avg_payment":              {"Средний платёж", "Орташа төлем", "Average payment"},
+	"total_attendees":          {"Всего посещений", "Барлық қатысулар", "Total visits"},
+	"weeks":                    {"Недель", "Апталар", "Weeks"},
+	"unassigned_hours":         {"Часов без зала", "Залсыз сағаттар", "Hours without location"},
+	"month":                    {"Месяц", "Ай", "Month"},
+	"revenue":                  {"Выручка", "Түсім", "Revenue"},
+	"new_subscriptions":        {"Новых абонементов", "Жаңа абонементтер", "New subscriptions"},
+	"churned_subscriptions":    {"Непродлённых абонементов", "Ұзартылмаған абонементтер", "Churned subscriptions"},
+	"active_subscriptions":     {"Активных абонементов", "Белсенді абонементтер", "Active subscriptions"},
+	"total_students":           {"Всего учеников", "Барлық оқушылар", "Total students"},
+	"active_students":          {"Активных учеников", "Белсенді оқушылар", "Active students"},
+	"avg_sessions_per_student": {"Занятий на ученика", "Бір оқушыға сабақтар", "Sessions per student"},
+	"total_pending_amount":     {"Сумма задолженности", "Берешек сомасы", "Total outstanding"},
+	"pending_count":            {"Неоплаченных абонементов", "Төленбеген абонементтер", "Unpaid subscriptions"},
+	"trial_students":           {"Пришли на пробное", "Сынаққа келгендер", "Trial students"},
+	"converted_students":       {"Купили абонемент", "Абонемент алғандар", "Converted students"},
+	"conversion_rate":          {"Конверсия", "Конверсия", "Conversion rate"},
+	"drop_in_visits":           {"Разовых посещений", "Бір реттік қатысулар", "Drop-in visits"},
+	"drop_in_paid":             {"Оплачено за разовые", "Бір реттік үшін төленді", "Drop-in paid"},
+	"drop_in_outstanding":      {"Долг за разовые", "Бір реттік үшін берешек", "Drop-in outstanding"},
+	"upcoming_sessions":        {"Предстоящих занятий", "Алдағы сабақтар", "Upcoming sessions"},
+	"today_sessions":           {"Занятий сегодня", "Бүгінгі сабақтар", "Sessions today"},
+	"month_revenue":            {"Выручка за месяц", "Айлық түсім", "Revenue this month"},
+	"pending_payments":         {"Ожидают оплаты", "Төлемді күтуде", "Pending payments"},
+	"renewal_days":             {"Дней на продление", "Ұзартуға күндер", "Days to renew"},
+	"ended_students":           {"Закончился абонемент", "Абонементі аяқталды", "Subscription ended"},
+	"churned_students":         {"Не продлили", "Ұзартпады", "Did not renew"},
+	"churn_rate":               {"Отток", "Кету", "Churn rate"},
+	"opening_deferred":         {"Доходы будущих периодов на начало", "Кезең басындағы болашақ кірістер", "Opening deferred revenue"},
+	"cash_received":            {"Поступило денег", "Түскен ақша", "Cash received"},
+	"recognised":               {"Признано выручки", "Танылған түсім", "Revenue recognised"},
+	"closing_deferred":         {"Доходы будущих периодов на конец", "Кезең соңындағы болашақ кірістер", "Closing deferred revenue"},
+	"payroll_total":            {"К выплате", "Төлеуге", "Total payable"},
+	"calculated_at":            {"Рассчитано", "Есептелді", "Calculated"},
+	"approved_at":              {"Утверждено", "Бекітілді", "Approved"},
+
+	// Labels
+	"payment_method":                {"Способ оплаты", "Төлем тәсілі", "Payment method"},
+	"payment_method.stripe":         {"Онлайн", "Онлайн", "Online"},
+	"payment_method.cash":           {"Наличные", "Қолма-қол", "Cash"},
+	"payment_method.manual":         {"Вручную", "Қолмен", "Manual"},
+	"payment_status":                {"Статус", "Күйі", "Status"},
+	"payment_status.pending":        {"Ожидает", "Күтуде", "Pending"},
+	"payment_status.succeeded":      {"Оплачен", "Төленді", "Succeeded"},
+	"payment_status.failed":         {"Ошибка", "Сәтсіз", "Failed"},
+	"payment_status.refunded":       {"Возвращён", "Қайтарылды", "Refunded"},
+	"subscription_status":           {"Статус", "Күйі", "Status"},
+	"subscription_status.pending":   {"Ожидает оплаты", "Төлемді күтуде", "Pending"},
+	"subscription_status.active":    {"Активен", "Белсенді", "Active"},
+	"subscription_status.used":      {"Использован", "Пайдаланылды", "Used"},
+	"subscription_status.expired":   {"Истёк", "Мерзімі өтті", "Expired"},
+	"subscription_status.cancelled": {"Отменён", "Бас тартылды", "Cancelled"},
+	"subscription_kind":             {"Тип", "Түрі", "Kind"},
+	"subscription_kind.package":     {"Абонемент", "Абонемент", "Package"},
+	"subscription_kind.drop_in":     {"Разовое", "Бір реттік", "Drop-in"},
+	"payroll_status":                {"Статус", "Күйі", "Status"},
+	"payroll_status.draft":          {"Черновик", "Жоба", "Draft"},
+	"payroll_status.approved":       {"Утверждена", "Бекітілді", "Approved"},
+	"payroll_kind":                  {"Оплата", "Төлем түрі", "Paid"},
+	"payroll_kind.per_session":      {"За занятие", "Сабақ үшін", "Per session"},
+	"payroll_kind.per_attendee":     {"За ученика", "Оқушы үшін", "Per attendee"},
+	"payroll_kind.revenue_share":    {"Процент с оплат", "Төлемдерден пайыз", "Revenue share"},
+}
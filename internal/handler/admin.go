@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// AdminHandler serves operations reserved for admins; the routes check the
+// role
+type AdminHandler struct {
+	purgeService *service.PurgeService
+	purgeRepo    *repository.PurgeRepository
+}
+
+func NewAdminHandler(purgeService *service.PurgeService, purgeRepo *repository.PurgeRepository) *AdminHandler {
+	return &AdminHandler{
+		purgeService: purgeService,
+		purgeRepo:    purgeRepo,
+	}
+}
+
+// DELETE /api/v1/admin/students/:id
+func (h *AdminHandler) PurgeStudent(w http.ResponseWriter, r *http.Request) {
+	h.purge(w, r, model.PurgeStudent)
+}
+
+// DELETE /api/v1/admin/groups/:id
+func (h *AdminHandler) PurgeGroup(w http.ResponseWriter, r *http.Request) {
+	h.purge(w, r, model.PurgeGroup)
+}
+
+// DELETE /api/v1/admin/clubs/:id
+func (h *AdminHandler) PurgeClub(w http.ResponseWriter, r *http.Request) {
+	h.purge(w, r, model.PurgeClub)
+}
+
+func (h *AdminHandler) purge(w http.ResponseWriter, r *http.Request, entity model.PurgeEntity) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid "+string(entity)+" id")
+		return
+	}
+
+	purge, err := h.purgeService.Purge(r.Context(), entity, id, middleware.GetUserID(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, string(entity)+" not found")
+		case errors.Is(err, service.ErrNotArchived), errors.Is(err, service.ErrFinancialHistory):
+			response.Conflict(w, err.Error())
+		default:
+			response.InternalError(w, "failed to purge "+string(entity))
+		}
+		return
+	}
+
+	response.OK(w, purge)
+}
+
+// GET /api/v1/admin/purges?limit=100
+func (h *AdminHandler) ListPurges(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	purges, err := h.purgeRepo.GetRecent(r.Context(), limit)
+	if err != nil {
+		response.InternalError(w, "failed to get purges")
+		return
+	}
+
+	response.OK(w, purges)
+}
@@ -1,43 +1,50 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/neo/trainer-plus/internal/middleware"
 	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
 	"github.com/neo/trainer-plus/internal/validator"
 	"github.com/neo/trainer-plus/pkg/response"
 )
 
 type AttendanceHandler struct {
-	attendanceRepo *repository.AttendanceRepository
-	subRepo        *repository.SubscriptionRepository
-	sessionRepo    *repository.SessionRepository
-	groupRepo      *repository.GroupRepository
-	clubRepo       *repository.ClubRepository
-	validator      *validator.Validator
+	attendanceRepo    *repository.AttendanceRepository
+	sessionRepo       *repository.SessionRepository
+	groupRepo         *repository.GroupRepository
+	clubRepo          *repository.ClubRepository
+	idempotencyRepo   *repository.IdempotencyRepository
+	attendanceService *service.AttendanceService
+	validator         *validator.Validator
 }
 
 func NewAttendanceHandler(
 	attendanceRepo *repository.AttendanceRepository,
-	subRepo *repository.SubscriptionRepository,
 	sessionRepo *repository.SessionRepository,
 	groupRepo *repository.GroupRepository,
 	clubRepo *repository.ClubRepository,
+	idempotencyRepo *repository.IdempotencyRepository,
+	attendanceService *service.AttendanceService,
 	validator *validator.Validator,
 ) *AttendanceHandler {
 	return &AttendanceHandler{
-		attendanceRepo: attendanceRepo,
-		subRepo:        subRepo,
-		sessionRepo:    sessionRepo,
-		groupRepo:      groupRepo,
-		clubRepo:       clubRepo,
-		validator:      validator,
+		attendanceRepo:    attendanceRepo,
+		sessionRepo:       sessionRepo,
+		groupRepo:         groupRepo,
+		clubRepo:          clubRepo,
+		idempotencyRepo:   idempotencyRepo,
+		attendanceService: attendanceService,
+		validator:         validator,
 	}
 }
 
@@ -66,121 +73,241 @@ func (h *AttendanceHandler) Mark(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get session to find group
-	session, err := h.sessionRepo.GetByID(r.Context(), sessionID)
-	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			response.NotFound(w, "session not found")
-			return
-		}
-		response.InternalError(w, "failed to get session")
+	session, club, ok := h.loadForMarking(w, r, sessionID)
+	if !ok {
+		return
+	}
+
+	attendance := &model.Attendance{
+		SessionID: sessionID,
+		StudentID: studentID,
+		Status:    req.Status,
+		Kind:      req.Kind,
+		NotedBy:   middleware.GetUserID(r.Context()),
+	}
+
+	// A concurrent mark for the same student fails inside the transaction
+	// with ErrAlreadyMarked
+	if err := h.attendanceService.Mark(r.Context(), session, club, attendance); err != nil {
+		writeMarkError(w, err, "failed to mark attendance")
 		return
 	}
 
-	// Check if attendance already exists
-	exists, err := h.attendanceRepo.Exists(r.Context(), sessionID, studentID)
+	response.Created(w, attendance)
+}
+
+// writeMarkError maps attendance service errors to responses
+func writeMarkError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNoCredit),
+		errors.Is(err, service.ErrTrialLimit),
+		errors.Is(err, service.ErrNoDropIn):
+		response.UnprocessableEntity(w, err.Error())
+	case errors.Is(err, service.ErrAlreadyMarked),
+		errors.Is(err, service.ErrMakeupUsed):
+		response.Conflict(w, err.Error())
+	default:
+		response.InternalError(w, fallback)
+	}
+}
+
+// POST /api/v1/attendance/bulk
+// Marks new attendance only; each student gets a result. Send an
+// Idempotency-Key header to make retries safe.
+func (h *AttendanceHandler) BulkMark(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		response.InternalError(w, "failed to check attendance")
+		response.BadRequest(w, "invalid request body")
 		return
 	}
-	if exists {
-		response.Conflict(w, "attendance already marked for this student and session")
+
+	var req BulkAttendanceRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "invalid request body")
 		return
 	}
 
-	// Check permission
-	group, err := h.groupRepo.GetByID(r.Context(), session.GroupID)
-	if err != nil {
-		response.InternalError(w, "failed to verify group")
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
 		return
 	}
 
-	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	sessionID, err := uuid.Parse(req.SessionID)
 	if err != nil {
-		response.InternalError(w, "failed to verify club")
+		response.BadRequest(w, "invalid session_id")
 		return
 	}
 
-	userID := middleware.GetUserID(r.Context())
-	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
-		response.Forbidden(w, "you don't have permission to mark attendance")
+	h.applyItems(w, r, sessionID, body, req.Attendances, h.attendanceService.BulkMarkInTx)
+}
+
+// PUT /api/v1/sessions/:session_id/attendance
+// Upserts the whole attendance sheet of a session: students not listed
+// lose their attendance and get their credits back. Send an
+// Idempotency-Key header to make retries safe.
+func (h *AttendanceHandler) UpsertSheet(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(chi.URLParam(r, "session_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid session_id")
 		return
 	}
 
-	// Start transaction
-	tx, err := h.attendanceRepo.BeginTx(r.Context())
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		response.InternalError(w, "failed to start transaction")
+		response.BadRequest(w, "invalid request body")
 		return
 	}
-	defer tx.Rollback()
 
-	// Only decrement subscription for 'present' status
-	var subscriptionID *uuid.UUID
-	if req.Status == string(model.AttendancePresent) {
-		// Find active subscription
-		sub, err := h.subRepo.FindActiveForAttendance(r.Context(), tx, studentID, session.GroupID, session.StartAt)
-		if err != nil {
-			if errors.Is(err, repository.ErrNotFound) {
-				response.UnprocessableEntity(w, "no active subscription found for this student and group")
-				return
-			}
-			response.InternalError(w, "failed to find subscription")
+	var req AttendanceSheetRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	h.applyItems(w, r, sessionID, body, req.Attendances, h.attendanceService.ApplySheetInTx)
+}
+
+type applyItemsFunc func(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, notedBy uuid.UUID, items []service.AttendanceItem) ([]service.AttendanceItemResult, error)
+
+// applyItems runs a bulk attendance operation in one transaction,
+// honouring the Idempotency-Key header
+func (h *AttendanceHandler) applyItems(w http.ResponseWriter, r *http.Request, sessionID uuid.UUID, body []byte, reqItems []BulkAttendanceItemRequest, apply applyItemsFunc) {
+	idem := newIdempotentRequest(h.idempotencyRepo, r, body)
+	if idem != nil {
+		if !idem.valid() {
+			response.BadRequest(w, "Idempotency-Key is too long")
+			return
+		}
+		if idem.replay(r.Context(), w) {
 			return
 		}
+	}
+
+	session, club, ok := h.loadForMarking(w, r, sessionID)
+	if !ok {
+		return
+	}
 
-		// Decrement remaining sessions
-		if err := h.subRepo.DecrementRemainingSessions(r.Context(), tx, sub.ID); err != nil {
-			response.InternalError(w, "failed to update subscription")
+	items := make([]service.AttendanceItem, len(reqItems))
+	for i, item := range reqItems {
+		studentID, err := uuid.Parse(item.StudentID)
+		if err != nil {
+			response.BadRequest(w, "invalid student_id")
 			return
 		}
+		items[i] = service.AttendanceItem{StudentID: studentID, Status: item.Status, Kind: item.Kind}
+	}
 
-		subscriptionID = &sub.ID
+	tx, err := h.attendanceRepo.BeginTx(r.Context())
+	if err != nil {
+		response.InternalError(w, "failed to start transaction")
+		return
 	}
+	defer tx.Rollback()
 
-	// Create attendance record
-	attendance := &model.Attendance{
-		SessionID:      sessionID,
-		StudentID:      studentID,
-		SubscriptionID: subscriptionID,
-		Status:         req.Status,
-		NotedBy:        userID,
+	if idem != nil && !idem.reserve(r.Context(), w, tx) {
+		return
 	}
 
-	if err := h.attendanceRepo.CreateInTx(r.Context(), tx, attendance); err != nil {
-		response.InternalError(w, "failed to create attendance")
+	results, err := apply(r.Context(), tx, session, club, middleware.GetUserID(r.Context()), items)
+	if err != nil {
+		response.InternalError(w, "failed to mark attendance")
 		return
 	}
 
-	// Commit transaction
+	data := map[string]interface{}{
+		"session_id": session.ID,
+		"results":    results,
+	}
+
+	if idem != nil {
+		if err := idem.complete(r.Context(), tx, http.StatusOK, data); err != nil {
+			response.InternalError(w, "failed to store idempotent response")
+			return
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		response.InternalError(w, "failed to commit transaction")
 		return
 	}
 
-	response.Created(w, attendance)
+	response.OK(w, data)
 }
 
-// POST /api/v1/attendance/bulk
-func (h *AttendanceHandler) BulkMark(w http.ResponseWriter, r *http.Request) {
-	var req BulkAttendanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.BadRequest(w, "invalid request body")
+// loadForMarking fetches a session that attendance can be marked for and
+// checks the caller owns the club or coaches the group.
+// On failure the response has already been written.
+func (h *AttendanceHandler) loadForMarking(w http.ResponseWriter, r *http.Request, sessionID uuid.UUID) (*model.Session, *model.Club, bool) {
+	session, err := h.sessionRepo.GetByID(r.Context(), sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "session not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get session")
+		return nil, nil, false
+	}
+
+	if session.IsCancelled() {
+		response.UnprocessableEntity(w, "session is cancelled")
+		return nil, nil, false
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), session.GroupID)
+	if err != nil {
+		response.InternalError(w, "failed to verify group")
+		return nil, nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return nil, nil, false
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
+		response.Forbidden(w, "you don't have permission to mark attendance")
+		return nil, nil, false
+	}
+
+	return session, club, true
+}
+
+// GET /api/v1/sessions/:session_id/attendance
+func (h *AttendanceHandler) GetBySession(w http.ResponseWriter, r *http.Request) {
+	sessionIDStr := chi.URLParam(r, "session_id")
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		response.BadRequest(w, "invalid session_id")
 		return
 	}
 
-	if err := h.validator.Struct(req); err != nil {
-		response.UnprocessableEntity(w, err.Error())
+	attendances, err := h.attendanceRepo.GetBySessionWithDetails(r.Context(), sessionID)
+	if err != nil {
+		response.InternalError(w, "failed to get attendance")
 		return
 	}
 
-	sessionID, err := uuid.Parse(req.SessionID)
+	response.OK(w, attendances)
+}
+
+// GET /api/v1/sessions/:session_id/roster
+// Lists everyone expected in the session plus walk-ins already marked,
+// with their current mark, remaining sessions and warnings.
+func (h *AttendanceHandler) Roster(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(chi.URLParam(r, "session_id"))
 	if err != nil {
 		response.BadRequest(w, "invalid session_id")
 		return
 	}
 
-	// Get session
 	session, err := h.sessionRepo.GetByID(r.Context(), sessionID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
@@ -191,7 +318,6 @@ func (h *AttendanceHandler) BulkMark(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permission
 	group, err := h.groupRepo.GetByID(r.Context(), session.GroupID)
 	if err != nil {
 		response.InternalError(w, "failed to verify group")
@@ -206,117 +332,44 @@ func (h *AttendanceHandler) BulkMark(w http.ResponseWriter, r *http.Request) {
 
 	userID := middleware.GetUserID(r.Context())
 	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
-		response.Forbidden(w, "you don't have permission to mark attendance")
+		response.Forbidden(w, "you don't have permission to view this roster")
 		return
 	}
 
-	// Start transaction
-	tx, err := h.attendanceRepo.BeginTx(r.Context())
+	entries, err := h.attendanceRepo.GetRoster(r.Context(), session)
 	if err != nil {
-		response.InternalError(w, "failed to start transaction")
+		response.InternalError(w, "failed to get roster")
 		return
 	}
-	defer tx.Rollback()
-
-	var results []map[string]interface{}
-
-	for _, item := range req.Attendances {
-		studentID, err := uuid.Parse(item.StudentID)
-		if err != nil {
-			results = append(results, map[string]interface{}{
-				"student_id": item.StudentID,
-				"success":    false,
-				"error":      "invalid student_id",
-			})
-			continue
-		}
 
-		// Check if already exists
-		exists, _ := h.attendanceRepo.Exists(r.Context(), sessionID, studentID)
-		if exists {
-			results = append(results, map[string]interface{}{
-				"student_id": item.StudentID,
-				"success":    false,
-				"error":      "already marked",
-			})
-			continue
+	summary := map[string]int{"expected": 0, "marked": 0, "present": 0, "walk_ins": 0}
+	for _, e := range entries {
+		if e.Expected {
+			summary["expected"]++
 		}
-
-		var subscriptionID *uuid.UUID
-		if item.Status == string(model.AttendancePresent) {
-			sub, err := h.subRepo.FindActiveForAttendance(r.Context(), tx, studentID, session.GroupID, session.StartAt)
-			if err != nil {
-				results = append(results, map[string]interface{}{
-					"student_id": item.StudentID,
-					"success":    false,
-					"error":      "no active subscription",
-				})
-				continue
-			}
-
-			if err := h.subRepo.DecrementRemainingSessions(r.Context(), tx, sub.ID); err != nil {
-				results = append(results, map[string]interface{}{
-					"student_id": item.StudentID,
-					"success":    false,
-					"error":      "failed to update subscription",
-				})
-				continue
+		if e.Mark != nil {
+			summary["marked"]++
+			if *e.Mark == string(model.AttendancePresent) {
+				summary["present"]++
 			}
-			subscriptionID = &sub.ID
-		}
-
-		attendance := &model.Attendance{
-			SessionID:      sessionID,
-			StudentID:      studentID,
-			SubscriptionID: subscriptionID,
-			Status:         item.Status,
-			NotedBy:        userID,
 		}
-
-		if err := h.attendanceRepo.CreateInTx(r.Context(), tx, attendance); err != nil {
-			results = append(results, map[string]interface{}{
-				"student_id": item.StudentID,
-				"success":    false,
-				"error":      "failed to create",
-			})
-			continue
+		if e.Source == repository.RosterSourceWalkIn {
+			summary["walk_ins"]++
 		}
-
-		results = append(results, map[string]interface{}{
-			"student_id":    item.StudentID,
-			"success":       true,
-			"attendance_id": attendance.ID,
-		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		response.InternalError(w, "failed to commit transaction")
-		return
+	if entries == nil {
+		entries = []repository.RosterEntry{}
 	}
 
 	response.OK(w, map[string]interface{}{
-		"results": results,
+		"session":  session,
+		"group":    group,
+		"students": entries,
+		"summary":  summary,
 	})
 }
 
-// GET /api/v1/sessions/:session_id/attendance
-func (h *AttendanceHandler) GetBySession(w http.ResponseWriter, r *http.Request) {
-	sessionIDStr := chi.URLParam(r, "session_id")
-	sessionID, err := uuid.Parse(sessionIDStr)
-	if err != nil {
-		response.BadRequest(w, "invalid session_id")
-		return
-	}
-
-	attendances, err := h.attendanceRepo.GetBySessionWithDetails(r.Context(), sessionID)
-	if err != nil {
-		response.InternalError(w, "failed to get attendance")
-		return
-	}
-
-	response.OK(w, attendances)
-}
-
 // GET /api/v1/students/:student_id/attendance
 func (h *AttendanceHandler) GetByStudent(w http.ResponseWriter, r *http.Request) {
 	studentIDStr := chi.URLParam(r, "student_id")
@@ -345,14 +398,17 @@ func (h *AttendanceHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Status string `json:"status" validate:"required,oneof=present absent excused"`
-	}
+	var req UpdateAttendanceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response.BadRequest(w, "invalid request body")
 		return
 	}
 
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
 	attendance, err := h.attendanceRepo.GetByID(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
@@ -363,12 +419,18 @@ func (h *AttendanceHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := middleware.GetUserID(r.Context())
-	attendance.Status = req.Status
-	attendance.NotedBy = userID
+	session, club, ok := h.loadForMarking(w, r, attendance.SessionID)
+	if !ok {
+		return
+	}
 
-	if err := h.attendanceRepo.Update(r.Context(), attendance); err != nil {
-		response.InternalError(w, "failed to update attendance")
+	attendance.NotedBy = middleware.GetUserID(r.Context())
+	if err := h.attendanceService.UpdateStatus(r.Context(), session, club, attendance, req.Status); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "attendance not found")
+			return
+		}
+		writeMarkError(w, err, "failed to update attendance")
 		return
 	}
 
@@ -384,10 +446,23 @@ func (h *AttendanceHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Consider restoring subscription session if was 'present'
-	// For MVP, just delete without restoring
+	attendance, err := h.attendanceRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "attendance not found")
+			return
+		}
+		response.InternalError(w, "failed to get attendance")
+		return
+	}
+
+	if _, _, ok := h.loadForMarking(w, r, attendance.SessionID); !ok {
+		return
+	}
 
-	if err := h.attendanceRepo.Delete(r.Context(), id); err != nil {
+	// Gives back the subscription session, makeup credit or drop-in
+	// charge the attendance consumed
+	if err := h.attendanceService.Remove(r.Context(), attendance.ID); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			response.NotFound(w, "attendance not found")
 			return
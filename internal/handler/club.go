@@ -15,11 +15,11 @@ import (
 )
 
 type ClubHandler struct {
-	clubRepo  *repository.ClubRepository
+	clubRepo  repository.ClubRepositoryInterface
 	validator *validator.Validator
 }
 
-func NewClubHandler(clubRepo *repository.ClubRepository, validator *validator.Validator) *ClubHandler {
+func NewClubHandler(clubRepo repository.ClubRepositoryInterface, validator *validator.Validator) *ClubHandler {
 	return &ClubHandler{
 		clubRepo:  clubRepo,
 		validator: validator,
@@ -51,6 +51,18 @@ func (h *ClubHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Address:     req.Address,
 		Phone:       req.Phone,
 		Currency:    req.Currency,
+		Timezone:    req.Timezone,
+	}
+	if club.Timezone == "" {
+		club.Timezone = model.DefaultTimezone
+	}
+	club.MakeupValidDays = model.DefaultMakeupValidDays
+	if req.MakeupValidDays != nil {
+		club.MakeupValidDays = *req.MakeupValidDays
+	}
+	club.TrialVisitsPerGroup = model.DefaultTrialVisitsPerGroup
+	if req.TrialVisitsPerGroup != nil {
+		club.TrialVisitsPerGroup = *req.TrialVisitsPerGroup
 	}
 
 	if err := h.clubRepo.Create(r.Context(), club); err != nil {
@@ -91,7 +103,12 @@ func (h *ClubHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clubs, err := h.clubRepo.GetByOwner(r.Context(), userID)
+	archived, ok := parseArchived(w, r)
+	if !ok {
+		return
+	}
+
+	clubs, err := h.clubRepo.GetByOwner(r.Context(), userID, archived)
 	if err != nil {
 		response.InternalError(w, "failed to get clubs")
 		return
@@ -151,6 +168,15 @@ func (h *ClubHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Currency != nil {
 		club.Currency = *req.Currency
 	}
+	if req.Timezone != nil {
+		club.Timezone = *req.Timezone
+	}
+	if req.MakeupValidDays != nil {
+		club.MakeupValidDays = *req.MakeupValidDays
+	}
+	if req.TrialVisitsPerGroup != nil {
+		club.TrialVisitsPerGroup = *req.TrialVisitsPerGroup
+	}
 
 	if err := h.clubRepo.Update(r.Context(), club); err != nil {
 		response.InternalError(w, "failed to update club")
@@ -161,6 +187,7 @@ func (h *ClubHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE /api/v1/clubs/:id
+// Archives the club; only an admin can purge it
 func (h *ClubHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -186,10 +213,44 @@ func (h *ClubHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.clubRepo.Delete(r.Context(), id); err != nil {
+	if err := h.clubRepo.Archive(r.Context(), id); err != nil {
 		response.InternalError(w, "failed to delete club")
 		return
 	}
 
 	response.NoContent(w)
 }
+
+// POST /api/v1/clubs/:id/restore
+func (h *ClubHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid club id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to get club")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID {
+		response.Forbidden(w, "you don't have permission to restore this club")
+		return
+	}
+
+	if err := h.clubRepo.Restore(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to restore club")
+		return
+	}
+	club.ArchivedAt = nil
+
+	response.OK(w, club)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/neo/trainer-plus/internal/handler"
 	"github.com/neo/trainer-plus/internal/middleware"
 	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
 	"github.com/neo/trainer-plus/internal/validator"
 	"github.com/neo/trainer-plus/pkg/response"
 )
@@ -48,12 +49,17 @@ func (m *MockClubRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.
 	return club, nil
 }
 
-func (m *MockClubRepository) GetByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Club, error) {
+func (m *MockClubRepository) GetByOwner(ctx context.Context, ownerID uuid.UUID, archived repository.ArchiveFilter) ([]model.Club, error) {
 	var result []model.Club
 	for _, club := range m.clubs {
-		if club.OwnerUserID == ownerID {
-			result = append(result, *club)
+		if club.OwnerUserID != ownerID {
+			continue
 		}
+		if (archived == repository.ActiveOnly && club.ArchivedAt != nil) ||
+			(archived == repository.ArchivedOnly && club.ArchivedAt == nil) {
+			continue
+		}
+		result = append(result, *club)
 	}
 	return result, nil
 }
@@ -66,11 +72,24 @@ func (m *MockClubRepository) Update(ctx context.Context, club *model.Club) error
 	return nil
 }
 
-func (m *MockClubRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	if _, ok := m.clubs[id]; !ok {
+func (m *MockClubRepository) Archive(ctx context.Context, id uuid.UUID) error {
+	club, ok := m.clubs[id]
+	if !ok {
 		return &notFoundError{}
 	}
-	delete(m.clubs, id)
+	if club.ArchivedAt == nil {
+		now := time.Now()
+		club.ArchivedAt = &now
+	}
+	return nil
+}
+
+func (m *MockClubRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	club, ok := m.clubs[id]
+	if !ok {
+		return &notFoundError{}
+	}
+	club.ArchivedAt = nil
 	return nil
 }
 
@@ -86,6 +105,9 @@ type notFoundError struct{}
 
 func (e *notFoundError) Error() string { return "not found" }
 
+// Is lets handlers match the mock error against repository.ErrNotFound
+func (e *notFoundError) Is(target error) bool { return target == repository.ErrNotFound }
+
 // Helper to create request with user context
 func requestWithUser(method, path string, body []byte, userID uuid.UUID) *http.Request {
 	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
@@ -321,7 +343,89 @@ func TestClubHandler_Delete_Success(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
 	}
 
-	if len(mockRepo.clubs) != 0 {
-		t.Errorf("expected 0 clubs, got %d", len(mockRepo.clubs))
+	if mockRepo.clubs[clubID].ArchivedAt == nil {
+		t.Error("expected club to be archived")
+	}
+}
+
+func TestClubHandler_Restore_Success(t *testing.T) {
+	mockRepo := NewMockClubRepository()
+	validate := validator.New()
+	h := handler.NewClubHandler(mockRepo, validate)
+
+	clubID := uuid.New()
+	userID := uuid.New()
+	archivedAt := time.Now()
+	mockRepo.clubs[clubID] = &model.Club{
+		ID:          clubID,
+		OwnerUserID: userID,
+		Name:        "Archived Club",
+		Currency:    "KZT",
+		CreatedAt:   time.Now(),
+		ArchivedAt:  &archivedAt,
+	}
+
+	req := requestWithUser(http.MethodPost, "/api/v1/clubs/"+clubID.String()+"/restore", nil, userID)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clubID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	h.Restore(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if mockRepo.clubs[clubID].ArchivedAt != nil {
+		t.Error("expected club to be restored")
+	}
+}
+
+func TestClubHandler_List_Archived(t *testing.T) {
+	mockRepo := NewMockClubRepository()
+	validate := validator.New()
+	h := handler.NewClubHandler(mockRepo, validate)
+
+	userID := uuid.New()
+	archivedAt := time.Now()
+	for _, club := range []*model.Club{
+		{ID: uuid.New(), OwnerUserID: userID, Name: "Active"},
+		{ID: uuid.New(), OwnerUserID: userID, Name: "Archived", ArchivedAt: &archivedAt},
+	} {
+		mockRepo.clubs[club.ID] = club
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"", http.StatusOK, 1},
+		{"?archived=false", http.StatusOK, 1},
+		{"?archived=true", http.StatusOK, 1},
+		{"?archived=all", http.StatusOK, 2},
+		{"?archived=yes", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := requestWithUser(http.MethodGet, "/api/v1/clubs"+tt.query, nil, userID)
+			rr := httptest.NewRecorder()
+			h.List(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			resp := parseResponse(t, rr)
+			clubs, _ := resp.Data.([]interface{})
+			if len(clubs) != tt.wantCount {
+				t.Errorf("expected %d clubs, got %d", tt.wantCount, len(clubs))
+			}
+		})
 	}
 }
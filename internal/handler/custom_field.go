@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// fieldFilterPrefix starts the query parameters filtering lists by custom
+// field, e.g. ?field.belt=yellow
+const fieldFilterPrefix = "field."
+
+// CustomFieldHandler manages the fields a club defines for its students
+// and groups
+type CustomFieldHandler struct {
+	fieldRepo *repository.CustomFieldRepository
+	clubRepo  *repository.ClubRepository
+	validator *validator.Validator
+}
+
+func NewCustomFieldHandler(
+	fieldRepo *repository.CustomFieldRepository,
+	clubRepo *repository.ClubRepository,
+	validator *validator.Validator,
+) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		fieldRepo: fieldRepo,
+		clubRepo:  clubRepo,
+		validator: validator,
+	}
+}
+
+// POST /api/v1/custom-fields
+func (h *CustomFieldHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+	if err := checkFieldOptions(req.Type, req.Options); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	clubID, err := uuid.Parse(req.ClubID)
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.BadRequest(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage custom fields of this club")
+		return
+	}
+
+	field := &model.CustomField{
+		ClubID:  clubID,
+		Entity:  req.Entity,
+		Key:     req.Key,
+		Label:   strings.TrimSpace(req.Label),
+		Type:    req.Type,
+		Options: req.Options,
+	}
+
+	if err := h.fieldRepo.Create(r.Context(), field); err != nil {
+		if repository.IsDuplicateFieldKey(err) {
+			response.Conflict(w, "a field with this key already exists")
+			return
+		}
+		response.InternalError(w, "failed to create custom field")
+		return
+	}
+
+	response.Created(w, field)
+}
+
+// GET /api/v1/clubs/:club_id/custom-fields?entity=student
+func (h *CustomFieldHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	entity := model.CustomFieldEntity(r.URL.Query().Get("entity"))
+	switch entity {
+	case "", model.CustomFieldStudent, model.CustomFieldGroup:
+	default:
+		response.BadRequest(w, "entity must be student or group")
+		return
+	}
+
+	if _, err := h.clubRepo.GetByID(r.Context(), clubID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	fields, err := h.fieldRepo.GetByClub(r.Context(), clubID, entity)
+	if err != nil {
+		response.InternalError(w, "failed to get custom fields")
+		return
+	}
+
+	response.OK(w, fields)
+}
+
+// PUT /api/v1/custom-fields/:id
+func (h *CustomFieldHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	field, ok := h.loadForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Label != nil {
+		field.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Options != nil {
+		if err := checkFieldOptions(field.Type, req.Options); err != nil {
+			response.UnprocessableEntity(w, err.Error())
+			return
+		}
+		field.Options = req.Options
+	}
+
+	if err := h.fieldRepo.Update(r.Context(), field); err != nil {
+		response.InternalError(w, "failed to update custom field")
+		return
+	}
+
+	response.OK(w, field)
+}
+
+// DELETE /api/v1/custom-fields/:id
+// Removes the field and its values from every student or group
+func (h *CustomFieldHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	field, ok := h.loadForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.fieldRepo.Delete(r.Context(), field); err != nil {
+		response.InternalError(w, "failed to delete custom field")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// loadForWrite fetches the field from the URL and checks the caller owns
+// its club. On failure the response has already been written.
+func (h *CustomFieldHandler) loadForWrite(w http.ResponseWriter, r *http.Request) (*model.CustomField, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid custom field id")
+		return nil, false
+	}
+
+	field, err := h.fieldRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "custom field not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get custom field")
+		return nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), field.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return nil, false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage custom fields of this club")
+		return nil, false
+	}
+
+	return field, true
+}
+
+// checkFieldOptions allows options on enum fields only, each once
+func checkFieldOptions(fieldType string, options []string) error {
+	if fieldType != string(model.CustomFieldEnum) {
+		if len(options) > 0 {
+			return errors.New("options are only allowed for enum fields")
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		key := strings.ToLower(strings.TrimSpace(option))
+		if key == "" {
+			return errors.New("options must not be blank")
+		}
+		if seen[key] {
+			return errors.New("options must be unique")
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ==================== Tags and custom fields of students and groups ====================
+
+// errCustomFieldsUnavailable means the club's field definitions could not
+// be loaded
+var errCustomFieldsUnavailable = errors.New("failed to get custom fields")
+
+// attributeReader reads the tags and custom fields of students or groups
+// from requests
+type attributeReader struct {
+	fieldRepo *repository.CustomFieldRepository
+	validator *validator.Validator
+}
+
+// read validates the tags and custom field values of a create or update
+// request. Tags are nil when not given; values are changes, nil removing a
+// field. On failure the response has already been written.
+func (a attributeReader) read(w http.ResponseWriter, r *http.Request, clubID uuid.UUID, entity model.CustomFieldEntity,
+	tags []string, values map[string]interface{}) ([]string, model.CustomValues, bool) {
+	if tags != nil {
+		normalized, err := a.validator.Tags(tags)
+		if err != nil {
+			response.UnprocessableEntity(w, err.Error())
+			return nil, nil, false
+		}
+		tags = normalized
+	}
+
+	if len(values) == 0 {
+		return tags, nil, true
+	}
+	changes, err := a.customValues(r.Context(), clubID, entity, values)
+	if err != nil {
+		if errors.Is(err, errCustomFieldsUnavailable) {
+			response.InternalError(w, err.Error())
+			return nil, nil, false
+		}
+		response.UnprocessableEntity(w, err.Error())
+		return nil, nil, false
+	}
+	return tags, changes, true
+}
+
+// filter reads the tag and field.<key> filters of a list: ?tag=a&tag=b
+// keeps records with both tags, ?field.belt=yellow those with the value.
+// On failure the response has already been written.
+func (a attributeReader) filter(w http.ResponseWriter, r *http.Request, clubID uuid.UUID, entity model.CustomFieldEntity) (repository.AttributeFilter, bool) {
+	var filter repository.AttributeFilter
+	query := r.URL.Query()
+
+	if tags := query["tag"]; len(tags) > 0 {
+		normalized, err := a.validator.Tags(tags)
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return filter, false
+		}
+		filter.Tags = normalized
+	}
+
+	values := map[string]interface{}{}
+	for param, value := range query {
+		if key, ok := strings.CutPrefix(param, fieldFilterPrefix); ok && len(value) > 0 {
+			values[key] = value[0]
+		}
+	}
+	if len(values) == 0 {
+		return filter, true
+	}
+
+	fields, err := a.customValues(r.Context(), clubID, entity, values)
+	if err != nil {
+		if errors.Is(err, errCustomFieldsUnavailable) {
+			response.InternalError(w, err.Error())
+			return filter, false
+		}
+		response.BadRequest(w, err.Error())
+		return filter, false
+	}
+	// An empty value would read as removing the field
+	for key, value := range fields {
+		if value == nil {
+			response.BadRequest(w, fieldFilterPrefix+key+" must not be empty")
+			return filter, false
+		}
+	}
+	filter.Fields = fields
+	return filter, true
+}
+
+// customValues validates values against the club's fields of the entity
+func (a attributeReader) customValues(ctx context.Context, clubID uuid.UUID, entity model.CustomFieldEntity, values map[string]interface{}) (model.CustomValues, error) {
+	fields, err := a.fieldRepo.GetByClub(ctx, clubID, entity)
+	if err != nil {
+		return nil, errCustomFieldsUnavailable
+	}
+	return a.validator.CustomFields(fields, values)
+}
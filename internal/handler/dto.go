@@ -1,31 +1,47 @@
 package handler
 
+import (
+	"time"
+
+	"github.com/neo/trainer-plus/internal/model"
+)
+
 // ==================== Club DTOs ====================
 
 type CreateClubRequest struct {
-	Name     string `json:"name" validate:"required,min=3,max=100"`
-	Address  string `json:"address" validate:"omitempty,max=255"`
-	Phone    string `json:"phone" validate:"omitempty,max=20"`
-	Currency string `json:"currency" validate:"required,currency"`
+	Name                string `json:"name" validate:"required,min=3,max=100"`
+	Address             string `json:"address" validate:"omitempty,max=255"`
+	Phone               string `json:"phone" validate:"omitempty,max=20"`
+	Currency            string `json:"currency" validate:"required,currency"`
+	Timezone            string `json:"timezone" validate:"omitempty,timezone"` // IANA name, e.g. "Asia/Almaty"
+	MakeupValidDays     *int   `json:"makeup_valid_days" validate:"omitempty,gte=0,lte=365"`
+	TrialVisitsPerGroup *int   `json:"trial_visits_per_group" validate:"omitempty,gte=0,lte=10"`
 }
 
 type UpdateClubRequest struct {
-	Name     *string `json:"name" validate:"omitempty,min=3,max=100"`
-	Address  *string `json:"address" validate:"omitempty,max=255"`
-	Phone    *string `json:"phone" validate:"omitempty,max=20"`
-	Currency *string `json:"currency" validate:"omitempty,currency"`
+	Name                *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Address             *string `json:"address" validate:"omitempty,max=255"`
+	Phone               *string `json:"phone" validate:"omitempty,max=20"`
+	Currency            *string `json:"currency" validate:"omitempty,currency"`
+	Timezone            *string `json:"timezone" validate:"omitempty,timezone"`
+	MakeupValidDays     *int    `json:"makeup_valid_days" validate:"omitempty,gte=0,lte=365"`
+	TrialVisitsPerGroup *int    `json:"trial_visits_per_group" validate:"omitempty,gte=0,lte=10"`
 }
 
 // ==================== Group DTOs ====================
 
 type CreateGroupRequest struct {
-	ClubID      string  `json:"club_id" validate:"required,uuid4"`
-	Title       string  `json:"title" validate:"required,min=2,max=100"`
-	Sport       string  `json:"sport" validate:"omitempty,max=50"`
-	Capacity    int     `json:"capacity" validate:"omitempty,gte=1,lte=1000"`
-	Price       float64 `json:"price" validate:"omitempty,gte=0"`
-	Description string  `json:"description" validate:"omitempty,max=500"`
-	CoachUserID string  `json:"coach_user_id" validate:"omitempty,uuid4"`
+	ClubID       string                 `json:"club_id" validate:"required,uuid4"`
+	Title        string                 `json:"title" validate:"required,min=2,max=100"`
+	Sport        string                 `json:"sport" validate:"omitempty,max=50"`
+	Capacity     int                    `json:"capacity" validate:"omitempty,gte=1,lte=1000"`
+	Price        float64                `json:"price" validate:"omitempty,gte=0"`
+	Description  string                 `json:"description" validate:"omitempty,max=500"`
+	CoachUserID  string                 `json:"coach_user_id" validate:"omitempty,uuid4"`
+	LocationID   string                 `json:"location_id" validate:"omitempty,uuid4"`
+	DropInPrice  *float64               `json:"drop_in_price" validate:"omitempty,gte=0"`
+	Tags         []string               `json:"tags"`
+	CustomFields map[string]interface{} `json:"custom_fields"` // key -> value
 }
 
 type UpdateGroupRequest struct {
@@ -35,6 +51,33 @@ type UpdateGroupRequest struct {
 	Price       *float64 `json:"price" validate:"omitempty,gte=0"`
 	Description *string  `json:"description" validate:"omitempty,max=500"`
 	CoachUserID *string  `json:"coach_user_id" validate:"omitempty,uuid4"`
+	LocationID  *string  `json:"location_id" validate:"omitempty,uuid4"`
+	DropInPrice *float64 `json:"drop_in_price" validate:"omitempty,gte=0"`
+	// ClearDropInPrice stops drop-ins in the group
+	ClearDropInPrice bool `json:"clear_drop_in_price"`
+	// Tags replace the group's tags; custom fields are changed one by one,
+	// a null value removes a field
+	Tags         []string               `json:"tags"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// ==================== Location DTOs ====================
+
+type CreateLocationRequest struct {
+	ClubID       string             `json:"club_id" validate:"required,uuid4"`
+	Name         string             `json:"name" validate:"required,min=1,max=100"`
+	Address      string             `json:"address" validate:"omitempty,max=255"`
+	Capacity     int                `json:"capacity" validate:"omitempty,gte=1,lte=10000"`
+	OpeningHours model.OpeningHours `json:"opening_hours"` // {"mon": {"open": "08:00", "close": "22:00"}}
+	Resources    []string           `json:"resources" validate:"omitempty,max=50,dive,required,max=50"`
+}
+
+type UpdateLocationRequest struct {
+	Name         *string            `json:"name" validate:"omitempty,min=1,max=100"`
+	Address      *string            `json:"address" validate:"omitempty,max=255"`
+	Capacity     *int               `json:"capacity" validate:"omitempty,gte=1,lte=10000"`
+	OpeningHours model.OpeningHours `json:"opening_hours"`
+	Resources    []string           `json:"resources" validate:"omitempty,max=50,dive,required,max=50"`
 }
 
 // ==================== Session DTOs ====================
@@ -43,6 +86,7 @@ type CreateSessionRequest struct {
 	StartAt         string `json:"start_at" validate:"required"`
 	DurationMinutes int    `json:"duration_minutes" validate:"required,gte=15,lte=480"`
 	Location        string `json:"location" validate:"omitempty,max=255"`
+	LocationID      string `json:"location_id" validate:"omitempty,uuid4"`
 }
 
 type CreateRecurringSessionsRequest struct {
@@ -52,16 +96,53 @@ type CreateRecurringSessionsRequest struct {
 	ToDate          string `json:"to_date" validate:"required"`            // "2026-02-28"
 	DurationMinutes int    `json:"duration_minutes" validate:"required,gte=15,lte=480"`
 	Location        string `json:"location" validate:"omitempty,max=255"`
+	LocationID      string `json:"location_id" validate:"omitempty,uuid4"`
+}
+
+type CancelSessionRequest struct {
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
+
+type RescheduleSessionRequest struct {
+	StartAt         string `json:"start_at" validate:"required"`
+	DurationMinutes int    `json:"duration_minutes" validate:"omitempty,gte=15,lte=480"`
+	Location        string `json:"location" validate:"omitempty,max=255"`
+	LocationID      string `json:"location_id" validate:"omitempty,uuid4"`
+}
+
+// ==================== Schedule Series DTOs ====================
+
+type CreateSeriesRequest struct {
+	RRule           string   `json:"rrule" validate:"required,max=255"` // "FREQ=WEEKLY;BYDAY=MO,WE"
+	StartsOn        string   `json:"starts_on" validate:"required"`     // "2025-12-01"
+	EndsOn          string   `json:"ends_on" validate:"omitempty"`      // "2026-05-31"
+	StartTime       string   `json:"start_time" validate:"required"`    // "18:00"
+	DurationMinutes int      `json:"duration_minutes" validate:"required,gte=15,lte=480"`
+	Location        string   `json:"location" validate:"omitempty,max=255"`
+	LocationID      string   `json:"location_id" validate:"omitempty,uuid4"`
+	ExDates         []string `json:"exdates" validate:"omitempty,dive,required"`
+}
+
+type UpdateSeriesRequest struct {
+	RRule           *string  `json:"rrule" validate:"omitempty,min=1,max=255"`
+	EndsOn          *string  `json:"ends_on"` // "" clears the end date
+	StartTime       *string  `json:"start_time" validate:"omitempty"`
+	DurationMinutes *int     `json:"duration_minutes" validate:"omitempty,gte=15,lte=480"`
+	Location        *string  `json:"location" validate:"omitempty,max=255"`
+	LocationID      *string  `json:"location_id" validate:"omitempty,uuid4"`
+	ExDates         []string `json:"exdates" validate:"omitempty,dive,required"`
 }
 
 // ==================== Student DTOs ====================
 
 type CreateStudentRequest struct {
-	ClubID        string                `json:"club_id" validate:"required,uuid4"`
-	Name          string                `json:"name" validate:"required,min=2,max=100"`
-	BirthDate     string                `json:"birth_date" validate:"omitempty"`
-	ParentContact *ParentContactRequest `json:"parent_contact" validate:"omitempty"`
-	Notes         string                `json:"notes" validate:"omitempty,max=1000"`
+	ClubID        string                 `json:"club_id" validate:"required,uuid4"`
+	Name          string                 `json:"name" validate:"required,min=2,max=100"`
+	BirthDate     string                 `json:"birth_date" validate:"omitempty"`
+	ParentContact *ParentContactRequest  `json:"parent_contact" validate:"omitempty"`
+	Notes         string                 `json:"notes" validate:"omitempty,max=1000"`
+	Tags          []string               `json:"tags"`
+	CustomFields  map[string]interface{} `json:"custom_fields"` // key -> value
 }
 
 type UpdateStudentRequest struct {
@@ -69,12 +150,21 @@ type UpdateStudentRequest struct {
 	BirthDate     *string               `json:"birth_date" validate:"omitempty"`
 	ParentContact *ParentContactRequest `json:"parent_contact" validate:"omitempty"`
 	Notes         *string               `json:"notes" validate:"omitempty,max=1000"`
+	// Tags replace the student's tags; custom fields are changed one by
+	// one, a null value removes a field
+	Tags         []string               `json:"tags"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
 }
 
 type ParentContactRequest struct {
 	Name  string `json:"name" validate:"omitempty,max=100"`
 	Phone string `json:"phone" validate:"omitempty,max=20"`
 	Email string `json:"email" validate:"omitempty,email"`
+
+	// Notification preferences
+	Language       string   `json:"language" validate:"omitempty,oneof=ru kk kz en"`
+	Channels       []string `json:"channels" validate:"omitempty,max=3,dive,oneof=email sms telegram"`
+	TelegramChatID string   `json:"telegram_chat_id" validate:"omitempty,max=32"`
 }
 
 // ==================== Subscription DTOs ====================
@@ -94,16 +184,230 @@ type MarkAttendanceRequest struct {
 	SessionID string `json:"session_id" validate:"required,uuid4"`
 	StudentID string `json:"student_id" validate:"required,uuid4"`
 	Status    string `json:"status" validate:"required,oneof=present absent excused"`
+	Kind      string `json:"kind" validate:"omitempty,oneof=regular trial drop_in"` // default regular
+}
+
+type UpdateAttendanceRequest struct {
+	Status string `json:"status" validate:"required,oneof=present absent excused"`
 }
 
 type BulkAttendanceRequest struct {
 	SessionID   string                     `json:"session_id" validate:"required,uuid4"`
-	Attendances []BulkAttendanceItemRequest `json:"attendances" validate:"required,min=1"`
+	Attendances []BulkAttendanceItemRequest `json:"attendances" validate:"required,min=1,max=500,dive"`
+}
+
+// AttendanceSheetRequest is the full roster of a session; an empty list
+// clears it
+type AttendanceSheetRequest struct {
+	Attendances []BulkAttendanceItemRequest `json:"attendances" validate:"max=500,dive"`
 }
 
 type BulkAttendanceItemRequest struct {
 	StudentID string `json:"student_id" validate:"required,uuid4"`
 	Status    string `json:"status" validate:"required,oneof=present absent excused"`
+	Kind      string `json:"kind" validate:"omitempty,oneof=regular trial drop_in"` // ignored for students already marked
+}
+
+// ==================== Makeup DTOs ====================
+
+type SetMakeupGroupsRequest struct {
+	GroupIDs []string `json:"group_ids" validate:"max=50,dive,uuid4"`
+}
+
+// ==================== Kiosk DTOs ====================
+
+type RegisterKioskDeviceRequest struct {
+	ClubID     string `json:"club_id" validate:"required,uuid4"`
+	LocationID string `json:"location_id" validate:"omitempty,uuid4"`
+	Name       string `json:"name" validate:"required,min=1,max=100"`
+}
+
+type CheckInRequest struct {
+	Token string `json:"token" validate:"required,max=200"`
+}
+
+type CheckInBatchRequest struct {
+	Scans []CheckInScanRequest `json:"scans" validate:"required,min=1,max=500,dive"`
+}
+
+type CheckInScanRequest struct {
+	ID        string    `json:"id" validate:"omitempty,max=100"`
+	Token     string    `json:"token" validate:"required,max=200"`
+	ScannedAt time.Time `json:"scanned_at" validate:"required"`
+}
+
+// ==================== Reminder DTOs ====================
+
+// UpdateRemindersRequest replaces a club's reminder configuration
+type UpdateRemindersRequest struct {
+	QuietHoursStart *string               `json:"quiet_hours_start" validate:"omitempty,datetime=15:04"` // "22:00"
+	QuietHoursEnd   *string               `json:"quiet_hours_end" validate:"omitempty,datetime=15:04"`   // "08:00"
+	Channels        []string              `json:"channels" validate:"omitempty,max=3,dive,oneof=email sms telegram"`
+	Rules           []ReminderRuleRequest `json:"rules" validate:"max=4,dive"`
+}
+
+type ReminderRuleRequest struct {
+	Kind      string   `json:"kind" validate:"required,oneof=low_balance expiring upcoming_session unpaid_debt medical_certificate"`
+	Enabled   bool     `json:"enabled"`
+	Threshold *int     `json:"threshold" validate:"omitempty,gte=1,lte=365"` // sessions, days or hours by kind
+	Channels  []string `json:"channels" validate:"omitempty,max=3,dive,oneof=email sms telegram"`
+}
+
+// ==================== Import DTOs ====================
+
+// ValidateImportRequest sets how an uploaded file is read and starts the
+// dry run
+type ValidateImportRequest struct {
+	// Field name -> column header of the file
+	Mapping             map[string]string `json:"mapping" validate:"required"`
+	CreateSubscriptions bool              `json:"create_subscriptions"`
+	OnDuplicate         string            `json:"on_duplicate" validate:"omitempty,oneof=skip create existing"` // default skip
+}
+
+// ==================== Student Merge DTOs ====================
+
+// MergeStudentRequest names the duplicate merged into the student in the URL
+type MergeStudentRequest struct {
+	DuplicateID string `json:"duplicate_id" validate:"required,uuid4"`
+}
+
+// ==================== Student Profile DTOs ====================
+
+// UpdateMedicalRequest replaces a student's medical details
+type UpdateMedicalRequest struct {
+	BloodType   string `json:"blood_type" validate:"omitempty,max=10"`
+	Allergies   string `json:"allergies" validate:"max=2000"`
+	Conditions  string `json:"conditions" validate:"max=2000"`
+	Medications string `json:"medications" validate:"max=2000"`
+	Notes       string `json:"notes" validate:"max=2000"`
+}
+
+type CreateMedicalCertificateRequest struct {
+	IssuedOn   string `json:"issued_on" validate:"omitempty,datetime=2006-01-02"`
+	ExpiresOn  string `json:"expires_on" validate:"required,datetime=2006-01-02"`
+	Issuer     string `json:"issuer" validate:"max=200"`
+	Notes      string `json:"notes" validate:"max=2000"`
+	DocumentID string `json:"document_id" validate:"omitempty,uuid4"` // scan uploaded to the student's documents
+}
+
+// UpdateEmergencyContactsRequest replaces a student's emergency contacts;
+// the first is called first
+type UpdateEmergencyContactsRequest struct {
+	Contacts []EmergencyContactRequest `json:"contacts" validate:"max=10,dive"`
+}
+
+type EmergencyContactRequest struct {
+	Name     string `json:"name" validate:"required,max=200"`
+	Relation string `json:"relation" validate:"max=100"`
+	Phone    string `json:"phone" validate:"required,max=30"`
+}
+
+type CreateConsentRequest struct {
+	Kind       string     `json:"kind" validate:"required,oneof=waiver photo data_processing"`
+	SignedBy   string     `json:"signed_by" validate:"required,max=200"` // guardian's name
+	SignedAt   *time.Time `json:"signed_at"`                             // default now
+	DocumentID string     `json:"document_id" validate:"omitempty,uuid4"`
+}
+
+// ==================== Personal Data DTOs ====================
+
+// GuardianDataRequest names a guardian by the email or phone they gave
+// the club
+type GuardianDataRequest struct {
+	Email string `json:"email" validate:"required_without=Phone,omitempty,email,max=255"`
+	Phone string `json:"phone" validate:"required_without=Email,omitempty,max=30"`
+}
+
+// ==================== Custom Field DTOs ====================
+
+type CreateCustomFieldRequest struct {
+	ClubID  string   `json:"club_id" validate:"required,uuid4"`
+	Entity  string   `json:"entity" validate:"required,oneof=student group"`
+	Key     string   `json:"key" validate:"required,field_key"` // "belt_colour"
+	Label   string   `json:"label" validate:"required,max=100"`
+	Type    string   `json:"type" validate:"required,oneof=text number date enum"`
+	Options []string `json:"options" validate:"required_if=Type enum,max=50,dive,required,max=100"` // enum only
+}
+
+// UpdateCustomFieldRequest renames a field or changes its options; values
+// no longer among the options are removed
+type UpdateCustomFieldRequest struct {
+	Label   *string  `json:"label" validate:"omitempty,min=1,max=100"`
+	Options []string `json:"options" validate:"omitempty,min=1,max=50,dive,required,max=100"`
+}
+
+// ==================== Progress DTOs ====================
+
+// ProgressLevelRequest creates or replaces a level of a group; without a
+// position a new level goes above the highest
+type ProgressLevelRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Position    int    `json:"position" validate:"gte=0"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+// SkillRequest creates or replaces a skill of a group
+type SkillRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=1000"`
+	MaxScore    int    `json:"max_score" validate:"omitempty,gte=1,lte=100"` // default 10
+}
+
+type CreateAssessmentRequest struct {
+	SkillID    string `json:"skill_id" validate:"required,uuid4"`
+	Score      int    `json:"score" validate:"gte=0"`
+	Notes      string `json:"notes" validate:"max=2000"`
+	AssessedOn string `json:"assessed_on" validate:"omitempty,datetime=2006-01-02"` // default today
+}
+
+type CreateGradingRequest struct {
+	Title  string `json:"title" validate:"required,max=200"`
+	HeldOn string `json:"held_on" validate:"required,datetime=2006-01-02"`
+	Notes  string `json:"notes" validate:"max=2000"`
+}
+
+// GradingResultsRequest is every result of a grading; an empty list
+// clears them
+type GradingResultsRequest struct {
+	Results []GradingResultItemRequest `json:"results" validate:"max=500,dive"`
+}
+
+type GradingResultItemRequest struct {
+	StudentID string `json:"student_id" validate:"required,uuid4"`
+	LevelID   string `json:"level_id" validate:"required,uuid4"`
+	Passed    bool   `json:"passed"`
+	Notes     string `json:"notes" validate:"max=2000"`
+}
+
+// AwardLevelRequest gives a student a level without a grading
+type AwardLevelRequest struct {
+	LevelID   string `json:"level_id" validate:"required,uuid4"`
+	AwardedOn string `json:"awarded_on" validate:"omitempty,datetime=2006-01-02"` // default today
+	Notes     string `json:"notes" validate:"max=2000"`
+}
+
+// ==================== Payroll DTOs ====================
+
+// CreatePayrollRuleRequest sets how a coach is paid for a group, or for
+// all their groups without one. Rate is money per session or attendee, or
+// a percentage for a revenue share.
+type CreatePayrollRuleRequest struct {
+	CoachUserID string  `json:"coach_user_id" validate:"required,uuid4"`
+	GroupID     string  `json:"group_id" validate:"omitempty,uuid4"`
+	Kind        string  `json:"kind" validate:"required,oneof=per_session per_attendee revenue_share"`
+	Rate        float64 `json:"rate" validate:"gte=0,lte=10000000"`
+}
+
+type UpdatePayrollRuleRequest struct {
+	Kind string  `json:"kind" validate:"required,oneof=per_session per_attendee revenue_share"`
+	Rate float64 `json:"rate" validate:"gte=0,lte=10000000"`
+}
+
+// CreatePayrollRunRequest calculates the payroll for the days from
+// period_start to period_end
+type CreatePayrollRunRequest struct {
+	PeriodStart string `json:"period_start" validate:"required,datetime=2006-01-02"`
+	PeriodEnd   string `json:"period_end" validate:"required,datetime=2006-01-02"`
 }
 
 // ==================== Pagination ====================
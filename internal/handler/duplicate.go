@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/dedupe"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type DuplicateHandler struct {
+	mergeService *service.MergeService
+	mergeRepo    *repository.StudentMergeRepository
+	studentRepo  *repository.StudentRepository
+	clubRepo     *repository.ClubRepository
+	validator    *validator.Validator
+}
+
+func NewDuplicateHandler(
+	mergeService *service.MergeService,
+	mergeRepo *repository.StudentMergeRepository,
+	studentRepo *repository.StudentRepository,
+	clubRepo *repository.ClubRepository,
+	validator *validator.Validator,
+) *DuplicateHandler {
+	return &DuplicateHandler{
+		mergeService: mergeService,
+		mergeRepo:    mergeRepo,
+		studentRepo:  studentRepo,
+		clubRepo:     clubRepo,
+		validator:    validator,
+	}
+}
+
+// DuplicatePair is a likely duplicate with both students
+type DuplicatePair struct {
+	dedupe.Pair
+	Student   model.Student `json:"student"`
+	Duplicate model.Student `json:"duplicate"`
+}
+
+// GET /api/v1/clubs/:club_id/students/duplicates
+// Pairs come with the suggested survivor, the one created first, as student.
+func (h *DuplicateHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	pairs, students, err := h.mergeService.FindDuplicates(r.Context(), clubID)
+	if err != nil {
+		response.InternalError(w, "failed to find duplicates")
+		return
+	}
+
+	result := make([]DuplicatePair, len(pairs))
+	for i, p := range pairs {
+		result[i] = DuplicatePair{Pair: p, Student: students[p.StudentID], Duplicate: students[p.DuplicateID]}
+	}
+
+	response.OK(w, result)
+}
+
+// POST /api/v1/students/:id/merge
+// Merges the duplicate in the body into the student in the URL
+func (h *DuplicateHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid student id")
+		return
+	}
+
+	var req MergeStudentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	duplicateID, _ := uuid.Parse(req.DuplicateID)
+
+	student, err := h.studentRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return
+		}
+		response.InternalError(w, "failed to get student")
+		return
+	}
+
+	if !h.verifyOwner(w, r, student.ClubID) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	merge, err := h.mergeService.Merge(r.Context(), id, duplicateID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, "student not found")
+		case errors.Is(err, service.ErrSameStudent), errors.Is(err, service.ErrDifferentClubs):
+			response.UnprocessableEntity(w, err.Error())
+		default:
+			response.InternalError(w, "failed to merge students")
+		}
+		return
+	}
+
+	response.OK(w, merge)
+}
+
+// GET /api/v1/clubs/:club_id/student-merges
+func (h *DuplicateHandler) ListMerges(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	merges, err := h.mergeRepo.GetByClub(r.Context(), clubID, 100)
+	if err != nil {
+		response.InternalError(w, "failed to get merges")
+		return
+	}
+
+	response.OK(w, merges)
+}
+
+// verifyOwner writes the error response and returns false unless the
+// caller owns the club
+func (h *DuplicateHandler) verifyOwner(w http.ResponseWriter, r *http.Request, clubID uuid.UUID) bool {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return false
+		}
+		response.InternalError(w, "failed to verify club")
+		return false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to merge students of this club")
+		return false
+	}
+	return true
+}
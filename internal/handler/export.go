@@ -0,0 +1,534 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// exportFormat reads the requested format, writing the error response and
+// returning false when it is not supported
+func exportFormat(w http.ResponseWriter, r *http.Request) (export.Format, bool) {
+	format, err := export.FormatFromRequest(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return "", false
+	}
+	return format, true
+}
+
+// sendExport streams the document built by fn. Once the download has
+// started an error can only cut it short.
+func sendExport(w http.ResponseWriter, format export.Format, locale export.Locale, info export.Info, fn func(d *export.Document) error) {
+	err := export.Write(w, format, locale, info, fn)
+	if err != nil && !errors.Is(err, export.ErrInterrupted) {
+		response.InternalError(w, "failed to export "+info.Name)
+	}
+}
+
+// exportClub fetches the club of an export, writing the error response
+// and returning nil unless the caller owns it
+func exportClub(w http.ResponseWriter, r *http.Request, clubRepo *repository.ClubRepository, clubID uuid.UUID) *model.Club {
+	club, err := clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return nil
+		}
+		response.InternalError(w, "failed to get club")
+		return nil
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to export this club's data")
+		return nil
+	}
+	return club
+}
+
+// ==================== Report exports ====================
+// Each report is its summary followed by a table per list in it
+
+func exportFinance(d *export.Document, report *repository.FinanceReport) error {
+	err := d.Summary(
+		export.Metric{Key: "total_paid", Kind: export.Money, Value: report.TotalPaid},
+		export.Metric{Key: "total_refunded", Kind: export.Money, Value: report.TotalRefunded},
+		export.Metric{Key: "net_revenue", Kind: export.Money, Value: report.NetRevenue},
+		export.Metric{Key: "payment_count", Kind: export.Int, Value: report.PaymentCount},
+		export.Metric{Key: "avg_payment", Kind: export.Money, Value: report.AvgPayment},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("payments_by_method",
+		export.Column{Key: "payment_method", Kind: export.Label},
+		export.Column{Key: "amount", Kind: export.Money},
+		export.Column{Key: "count", Kind: export.Int},
+	)
+	if err != nil {
+		return err
+	}
+	for _, p := range report.PaymentsByMethod {
+		if err := d.Row(p.Method, p.Amount, p.Count); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("payments_by_group",
+		export.Column{Key: "group"},
+		export.Column{Key: "amount", Kind: export.Money},
+		export.Column{Key: "count", Kind: export.Int},
+	)
+	if err != nil {
+		return err
+	}
+	for _, p := range report.PaymentsByGroup {
+		if err := d.Row(p.GroupTitle, p.Amount, p.Count); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("daily_revenue",
+		export.Column{Key: "date", Kind: export.Day},
+		export.Column{Key: "amount", Kind: export.Money},
+		export.Column{Key: "count", Kind: export.Int},
+	)
+	if err != nil {
+		return err
+	}
+	for _, day := range report.DailyRevenue {
+		if err := d.Row(day.Date, day.Amount, day.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportOccupancy(d *export.Document, report *repository.OccupancyReport) error {
+	err := d.Summary(
+		export.Metric{Key: "avg_fill_rate", Kind: export.Percent, Value: report.AvgFillRate},
+		export.Metric{Key: "total_sessions", Kind: export.Int, Value: report.TotalSessions},
+		export.Metric{Key: "total_attendees", Kind: export.Int, Value: report.TotalAttendees},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("group_stats",
+		export.Column{Key: "group"},
+		export.Column{Key: "capacity", Kind: export.Int},
+		export.Column{Key: "session_count", Kind: export.Int},
+		export.Column{Key: "total_present", Kind: export.Int},
+		export.Column{Key: "avg_fill_rate", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.GroupStats {
+		if err := d.Row(g.GroupTitle, g.Capacity, g.SessionCount, g.TotalPresent, g.AvgFillRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportUtilisation(d *export.Document, report *repository.UtilisationReport) error {
+	err := d.Summary(
+		export.Metric{Key: "weeks", Kind: export.Number, Value: report.Weeks},
+		export.Metric{Key: "unassigned_hours", Kind: export.Number, Value: report.UnassignedHours},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("locations",
+		export.Column{Key: "location"},
+		export.Column{Key: "capacity", Kind: export.Int},
+		export.Column{Key: "session_count", Kind: export.Int},
+		export.Column{Key: "booked_hours", Kind: export.Number},
+		export.Column{Key: "booked_hours_per_week", Kind: export.Number},
+		export.Column{Key: "open_hours_per_week", Kind: export.Number},
+		export.Column{Key: "utilisation_rate", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, l := range report.Locations {
+		err := d.Row(l.Name, l.Capacity, l.SessionCount, l.BookedHours, l.BookedHoursPerWeek, l.OpenHoursPerWeek, l.UtilisationRate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportMRR(d *export.Document, report *repository.MRRReport) error {
+	return d.Summary(
+		export.Metric{Key: "month", Value: report.Month},
+		export.Metric{Key: "revenue", Kind: export.Money, Value: report.Revenue},
+		export.Metric{Key: "new_subscriptions", Kind: export.Int, Value: report.NewSubscriptions},
+		export.Metric{Key: "churned_subscriptions", Kind: export.Int, Value: report.ChurnedSubscriptions},
+		export.Metric{Key: "churned_students", Kind: export.Int, Value: report.ChurnedStudents},
+		export.Metric{Key: "active_subscriptions", Kind: export.Int, Value: report.ActiveSubscriptions},
+	)
+}
+
+func exportStudentsReport(d *export.Document, report *repository.StudentsReport) error {
+	err := d.Summary(
+		export.Metric{Key: "total_students", Kind: export.Int, Value: report.TotalStudents},
+		export.Metric{Key: "active_students", Kind: export.Int, Value: report.ActiveStudents},
+		export.Metric{Key: "avg_sessions_per_student", Kind: export.Number, Value: report.AvgSessionsPerStudent},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("top_students",
+		export.Column{Key: "student"},
+		export.Column{Key: "session_count", Kind: export.Int},
+		export.Column{Key: "present_count", Kind: export.Int},
+		export.Column{Key: "attendance_rate", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, s := range report.TopStudents {
+		if err := d.Row(s.StudentName, s.SessionCount, s.PresentCount, s.AttendanceRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportDebt(d *export.Document, report *repository.DebtReport) error {
+	err := d.Summary(
+		export.Metric{Key: "total_pending_amount", Kind: export.Money, Value: report.TotalPendingAmount},
+		export.Metric{Key: "pending_count", Kind: export.Int, Value: report.PendingCount},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("debtors",
+		export.Column{Key: "student"},
+		export.Column{Key: "parent_phone"},
+		export.Column{Key: "parent_email"},
+		export.Column{Key: "group"},
+		export.Column{Key: "subscription_kind", Kind: export.Label},
+		export.Column{Key: "amount", Kind: export.Money},
+		export.Column{Key: "created_at", Kind: export.Date},
+		export.Column{Key: "days_overdue", Kind: export.Int},
+	)
+	if err != nil {
+		return err
+	}
+	for _, debtor := range report.Debtors {
+		err := d.Row(debtor.StudentName, debtor.ParentPhone, debtor.ParentEmail, debtor.GroupTitle,
+			debtor.Kind, debtor.Amount, debtor.CreatedAt, debtor.DaysOverdue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportTrials(d *export.Document, report *repository.TrialReport) error {
+	err := d.Summary(
+		export.Metric{Key: "trial_students", Kind: export.Int, Value: report.TrialStudents},
+		export.Metric{Key: "converted_students", Kind: export.Int, Value: report.ConvertedStudents},
+		export.Metric{Key: "conversion_rate", Kind: export.Percent, Value: report.ConversionRate},
+		export.Metric{Key: "drop_in_visits", Kind: export.Int, Value: report.DropInVisits},
+		export.Metric{Key: "drop_in_paid", Kind: export.Money, Value: report.DropInPaid},
+		export.Metric{Key: "drop_in_outstanding", Kind: export.Money, Value: report.DropInOutstanding},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("leads",
+		export.Column{Key: "student"},
+		export.Column{Key: "parent_phone"},
+		export.Column{Key: "parent_email"},
+		export.Column{Key: "group"},
+		export.Column{Key: "first_trial_at", Kind: export.Date},
+		export.Column{Key: "trial_count", Kind: export.Int},
+		export.Column{Key: "converted_at", Kind: export.Date},
+	)
+	if err != nil {
+		return err
+	}
+	for _, l := range report.Leads {
+		err := d.Row(l.StudentName, l.ParentPhone, l.ParentEmail, l.GroupTitle, l.FirstTrialAt, l.TrialCount, l.ConvertedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportLevels(d *export.Document, report *repository.LevelReport) error {
+	err := d.Table("group_stats",
+		export.Column{Key: "group"},
+		export.Column{Key: "students", Kind: export.Int},
+		export.Column{Key: "without_level", Kind: export.Int},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		if err := d.Row(g.GroupTitle, g.Students, g.WithoutLevel); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("levels",
+		export.Column{Key: "group"},
+		export.Column{Key: "level"},
+		export.Column{Key: "students", Kind: export.Int},
+		export.Column{Key: "share", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		for _, l := range g.Levels {
+			if err := d.Row(g.GroupTitle, l.Name, l.Students, l.Share); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportRetention(d *export.Document, report *repository.RetentionReport) error {
+	err := d.Summary(
+		export.Metric{Key: "renewal_days", Kind: export.Int, Value: report.RenewalDays},
+		export.Metric{Key: "ended_students", Kind: export.Int, Value: report.EndedStudents},
+		export.Metric{Key: "churned_students", Kind: export.Int, Value: report.ChurnedStudents},
+		export.Metric{Key: "churn_rate", Kind: export.Percent, Value: report.ChurnRate},
+	)
+	if err != nil {
+		return err
+	}
+
+	// A column per month since the cohort started, as many as the oldest
+	// cohort has
+	columns := []export.Column{{Key: "cohort"}, {Key: "students", Kind: export.Int}}
+	months := 0
+	for _, c := range report.Cohorts {
+		months = max(months, len(c.Retention))
+	}
+	for i := 0; i < months; i++ {
+		columns = append(columns, export.Column{Key: "month_offset", Title: strconv.Itoa(i), Kind: export.Percent})
+	}
+	if err := d.Table("cohorts", columns...); err != nil {
+		return err
+	}
+	for _, c := range report.Cohorts {
+		row := make([]interface{}, len(columns))
+		row[0], row[1] = c.Month, c.Students
+		for i, rate := range c.Retention {
+			row[2+i] = rate
+		}
+		if err := d.Row(row...); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("churn_by_group",
+		export.Column{Key: "group"},
+		export.Column{Key: "ended_students", Kind: export.Int},
+		export.Column{Key: "churned_students", Kind: export.Int},
+		export.Column{Key: "churn_rate", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		if err := d.Row(g.GroupTitle, g.Ended, g.Churned, g.ChurnRate); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("churn_by_coach",
+		export.Column{Key: "coach"},
+		export.Column{Key: "ended_students", Kind: export.Int},
+		export.Column{Key: "churned_students", Kind: export.Int},
+		export.Column{Key: "churn_rate", Kind: export.Percent},
+	)
+	if err != nil {
+		return err
+	}
+	for _, c := range report.Coaches {
+		if err := d.Row(c.CoachName, c.Ended, c.Churned, c.ChurnRate); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("at_risk",
+		export.Column{Key: "student"},
+		export.Column{Key: "parent_phone"},
+		export.Column{Key: "parent_email"},
+		export.Column{Key: "group"},
+		export.Column{Key: "previous_visits", Kind: export.Int},
+		export.Column{Key: "recent_visits", Kind: export.Int},
+		export.Column{Key: "last_visit_at", Kind: export.Date},
+	)
+	if err != nil {
+		return err
+	}
+	for _, s := range report.AtRisk {
+		err := d.Row(s.StudentName, s.ParentPhone, s.ParentEmail, s.GroupTitle, s.PreviousVisits, s.RecentVisits, s.LastVisitAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportRevenue(d *export.Document, report *repository.RevenueReport) error {
+	err := d.Summary(
+		export.Metric{Key: "opening_deferred", Kind: export.Money, Value: report.OpeningDeferred},
+		export.Metric{Key: "cash_received", Kind: export.Money, Value: report.CashReceived},
+		export.Metric{Key: "recognised", Kind: export.Money, Value: report.Recognised},
+		export.Metric{Key: "closing_deferred", Kind: export.Money, Value: report.ClosingDeferred},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("revenue_by_month",
+		export.Column{Key: "month"},
+		export.Column{Key: "cash_received", Kind: export.Money},
+		export.Column{Key: "recognised", Kind: export.Money},
+		export.Column{Key: "deferred", Kind: export.Money},
+	)
+	if err != nil {
+		return err
+	}
+	for _, m := range report.Months {
+		if err := d.Row(m.Month, m.CashReceived, m.Recognised, m.Deferred); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("revenue_by_group",
+		export.Column{Key: "group"},
+		export.Column{Key: "opening_deferred", Kind: export.Money},
+		export.Column{Key: "cash_received", Kind: export.Money},
+		export.Column{Key: "recognised", Kind: export.Money},
+		export.Column{Key: "closing_deferred", Kind: export.Money},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		if err := d.Row(g.GroupTitle, g.OpeningDeferred, g.CashReceived, g.Recognised, g.ClosingDeferred); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("revenue_by_group_month",
+		export.Column{Key: "group"},
+		export.Column{Key: "month"},
+		export.Column{Key: "cash_received", Kind: export.Money},
+		export.Column{Key: "recognised", Kind: export.Money},
+		export.Column{Key: "deferred", Kind: export.Money},
+	)
+	if err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		for _, m := range g.Months {
+			if err := d.Row(g.GroupTitle, m.Month, m.CashReceived, m.Recognised, m.Deferred); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportDashboard(d *export.Document, stats *repository.DashboardStats) error {
+	return d.Summary(
+		export.Metric{Key: "total_students", Kind: export.Int, Value: stats.TotalStudents},
+		export.Metric{Key: "active_subscriptions", Kind: export.Int, Value: stats.ActiveSubscriptions},
+		export.Metric{Key: "upcoming_sessions", Kind: export.Int, Value: stats.UpcomingSessions},
+		export.Metric{Key: "today_sessions", Kind: export.Int, Value: stats.TodaySessions},
+		export.Metric{Key: "month_revenue", Kind: export.Money, Value: stats.MonthRevenue},
+		export.Metric{Key: "pending_payments", Kind: export.Int, Value: stats.PendingPayments},
+	)
+}
+
+// ==================== Payroll export ====================
+
+func exportPayroll(d *export.Document, run *model.PayrollRun) error {
+	err := d.Summary(
+		export.Metric{Key: "payroll_status", Kind: export.Label, Value: run.Status},
+		export.Metric{Key: "payroll_total", Kind: export.Money, Value: run.Total},
+		export.Metric{Key: "calculated_at", Kind: export.DateTime, Value: run.CalculatedAt},
+		export.Metric{Key: "approved_at", Kind: export.DateTime, Value: run.ApprovedAt},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = d.Table("coaches",
+		export.Column{Key: "coach"},
+		export.Column{Key: "session_count", Kind: export.Int},
+		export.Column{Key: "attendees", Kind: export.Int},
+		export.Column{Key: "revenue", Kind: export.Money},
+		export.Column{Key: "amount", Kind: export.Money},
+	)
+	if err != nil {
+		return err
+	}
+	for _, c := range run.Coaches {
+		if err := d.Row(c.CoachName, c.Sessions, c.Attendees, c.Revenue, c.Amount); err != nil {
+			return err
+		}
+	}
+
+	err = d.Table("payroll_items",
+		export.Column{Key: "coach"},
+		export.Column{Key: "group"},
+		export.Column{Key: "date", Kind: export.DateTime},
+		export.Column{Key: "payroll_kind", Kind: export.Label},
+		export.Column{Key: "rate", Kind: export.Number},
+		export.Column{Key: "quantity", Kind: export.Number},
+		export.Column{Key: "amount", Kind: export.Money},
+	)
+	if err != nil {
+		return err
+	}
+	for _, item := range run.Items {
+		err := d.Row(item.CoachName, item.GroupTitle, item.OccurredAt, item.Kind, item.Rate, item.Quantity, item.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// customFieldColumns returns a column per custom field, titled with its
+// label
+func customFieldColumns(fields []model.CustomField) []export.Column {
+	columns := make([]export.Column, len(fields))
+	for i, f := range fields {
+		columns[i] = export.Column{Key: f.Key, Title: f.Label}
+		switch model.CustomFieldType(f.Type) {
+		case model.CustomFieldNumber:
+			columns[i].Kind = export.Number
+		case model.CustomFieldDate:
+			columns[i].Kind = export.Day
+		}
+	}
+	return columns
+}
@@ -16,20 +16,26 @@ import (
 )
 
 type GroupHandler struct {
-	groupRepo *repository.GroupRepository
-	clubRepo  *repository.ClubRepository
-	validator *validator.Validator
+	groupRepo    *repository.GroupRepository
+	clubRepo     *repository.ClubRepository
+	locationRepo *repository.LocationRepository
+	validator    *validator.Validator
+	attributes   attributeReader
 }
 
 func NewGroupHandler(
 	groupRepo *repository.GroupRepository,
 	clubRepo *repository.ClubRepository,
+	locationRepo *repository.LocationRepository,
+	fieldRepo *repository.CustomFieldRepository,
 	validator *validator.Validator,
 ) *GroupHandler {
 	return &GroupHandler{
-		groupRepo: groupRepo,
-		clubRepo:  clubRepo,
-		validator: validator,
+		groupRepo:    groupRepo,
+		clubRepo:     clubRepo,
+		locationRepo: locationRepo,
+		validator:    validator,
+		attributes:   attributeReader{fieldRepo: fieldRepo, validator: validator},
 	}
 }
 
@@ -80,14 +86,32 @@ func (h *GroupHandler) Create(w http.ResponseWriter, r *http.Request) {
 		coachUserID = &parsed
 	}
 
+	// Default location for the group's sessions (optional)
+	location, err := resolveLocation(r.Context(), h.locationRepo, clubID, req.LocationID)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+
+	tags, customFields, ok := h.attributes.read(w, r, clubID, model.CustomFieldGroup, req.Tags, req.CustomFields)
+	if !ok {
+		return
+	}
+
 	group := &model.Group{
-		ClubID:      clubID,
-		Title:       req.Title,
-		Sport:       req.Sport,
-		Capacity:    req.Capacity,
-		Price:       req.Price,
-		Description: req.Description,
-		CoachUserID: coachUserID,
+		ClubID:       clubID,
+		Title:        req.Title,
+		Sport:        req.Sport,
+		Capacity:     req.Capacity,
+		Price:        req.Price,
+		Description:  req.Description,
+		CoachUserID:  coachUserID,
+		DropInPrice:  req.DropInPrice,
+		Tags:         tags,
+		CustomFields: model.CustomValues{}.Apply(customFields),
+	}
+	if location != nil {
+		group.LocationID = &location.ID
 	}
 
 	if err := h.groupRepo.Create(r.Context(), group); err != nil {
@@ -140,11 +164,21 @@ func (h *GroupHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	archived, ok := parseArchived(w, r)
+	if !ok {
+		return
+	}
+
+	attrs, ok := h.attributes.filter(w, r, clubID, model.CustomFieldGroup)
+	if !ok {
+		return
+	}
+
 	// Check if we want stats (query param)
 	withStats := r.URL.Query().Get("with_stats") == "true"
 
 	if withStats {
-		groups, err := h.groupRepo.GetByClubWithStats(r.Context(), clubID)
+		groups, err := h.groupRepo.GetByClubWithStats(r.Context(), clubID, archived, attrs)
 		if err != nil {
 			response.InternalError(w, "failed to get groups")
 			return
@@ -153,7 +187,7 @@ func (h *GroupHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groups, err := h.groupRepo.GetByClub(r.Context(), clubID)
+	groups, err := h.groupRepo.GetByClub(r.Context(), clubID, archived, attrs)
 	if err != nil {
 		response.InternalError(w, "failed to get groups")
 		return
@@ -218,6 +252,12 @@ func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Price != nil {
 		group.Price = *req.Price
 	}
+	if req.DropInPrice != nil {
+		group.DropInPrice = req.DropInPrice
+	}
+	if req.ClearDropInPrice {
+		group.DropInPrice = nil
+	}
 	if req.Description != nil {
 		group.Description = *req.Description
 	}
@@ -229,6 +269,26 @@ func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 		group.CoachUserID = &coachID
 	}
+	if req.LocationID != nil {
+		location, err := resolveLocation(r.Context(), h.locationRepo, group.ClubID, *req.LocationID)
+		if err != nil {
+			writeLocationError(w, err)
+			return
+		}
+		group.LocationID = nil
+		if location != nil {
+			group.LocationID = &location.ID
+		}
+	}
+
+	tags, customFields, ok := h.attributes.read(w, r, group.ClubID, model.CustomFieldGroup, req.Tags, req.CustomFields)
+	if !ok {
+		return
+	}
+	if tags != nil {
+		group.Tags = tags
+	}
+	group.CustomFields = group.CustomFields.Apply(customFields)
 
 	if err := h.groupRepo.Update(r.Context(), group); err != nil {
 		response.InternalError(w, "failed to update group")
@@ -239,6 +299,7 @@ func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE /api/v1/groups/:id
+// Archives the group; only an admin can purge it
 func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -271,7 +332,7 @@ func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.groupRepo.Delete(r.Context(), id); err != nil {
+	if err := h.groupRepo.Archive(r.Context(), id); err != nil {
 		response.InternalError(w, "failed to delete group")
 		return
 	}
@@ -279,6 +340,46 @@ func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	response.NoContent(w)
 }
 
+// POST /api/v1/groups/:id/restore
+func (h *GroupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid group id")
+		return
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to get group")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID {
+		response.Forbidden(w, "only club owner can restore groups")
+		return
+	}
+
+	if err := h.groupRepo.Restore(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to restore group")
+		return
+	}
+	group.ArchivedAt = nil
+
+	response.OK(w, group)
+}
+
 // Helper to check if user can modify group
 func (h *GroupHandler) checkGroupPermission(r *http.Request, clubID uuid.UUID, coachID *uuid.UUID, userID uuid.UUID) (bool, error) {
 	// Check if user is club owner
@@ -308,3 +409,19 @@ func parsePagination(r *http.Request) PaginationParams {
 		PerPage: perPage,
 	}
 }
+
+// parseArchived reads the archived filter of a list: false or absent for
+// active records only, true for archived ones only, all for both
+func parseArchived(w http.ResponseWriter, r *http.Request) (repository.ArchiveFilter, bool) {
+	switch r.URL.Query().Get("archived") {
+	case "", "false":
+		return repository.ActiveOnly, true
+	case "true":
+		return repository.ArchivedOnly, true
+	case "all":
+		return repository.AnyArchived, true
+	default:
+		response.BadRequest(w, "archived must be true, false or all")
+		return "", false
+	}
+}
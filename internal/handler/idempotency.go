@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	replayedHeader    = "Idempotent-Replayed"
+	maxIdempotencyKey = 255
+)
+
+// idempotentRequest identifies a request sent with an Idempotency-Key.
+// Retrying with the same key and body replays the stored response; reusing
+// the key for anything else is rejected.
+type idempotentRequest struct {
+	repo     *repository.IdempotencyRepository
+	userID   uuid.UUID
+	key      string
+	endpoint string
+	hash     string
+}
+
+// newIdempotentRequest returns nil when the request carries no key
+func newIdempotentRequest(repo *repository.IdempotencyRepository, r *http.Request, body []byte) *idempotentRequest {
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(body)
+	return &idempotentRequest{
+		repo:     repo,
+		userID:   middleware.GetUserID(r.Context()),
+		key:      key,
+		endpoint: r.Method + " " + r.URL.Path,
+		hash:     hex.EncodeToString(sum[:]),
+	}
+}
+
+func (req *idempotentRequest) valid() bool {
+	return len(req.key) <= maxIdempotencyKey
+}
+
+// replay writes the stored response for the key, if any, and reports
+// whether the request has been answered
+func (req *idempotentRequest) replay(ctx context.Context, w http.ResponseWriter) bool {
+	rec, err := req.repo.Get(ctx, req.userID, req.key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		response.InternalError(w, "failed to check idempotency key")
+		return true
+	}
+
+	if rec.Endpoint != req.endpoint || rec.RequestHash != req.hash {
+		response.UnprocessableEntity(w, "Idempotency-Key was already used for a different request")
+		return true
+	}
+
+	w.Header().Set(replayedHeader, "true")
+	response.JSON(w, rec.StatusCode, json.RawMessage(rec.Response))
+	return true
+}
+
+// reserve claims the key inside tx. If another request already holds it,
+// that request's response is replayed (or a conflict reported) and false
+// is returned; the caller must stop.
+func (req *idempotentRequest) reserve(ctx context.Context, w http.ResponseWriter, tx *sqlx.Tx) bool {
+	reserved, err := req.repo.ReserveInTx(ctx, tx, req.userID, req.key, req.endpoint, req.hash)
+	if err != nil {
+		response.InternalError(w, "failed to reserve idempotency key")
+		return false
+	}
+	if reserved {
+		return true
+	}
+
+	tx.Rollback()
+	if !req.replay(ctx, w) {
+		response.Conflict(w, "a request with this Idempotency-Key is still in progress")
+	}
+	return false
+}
+
+// complete stores the response to be replayed for the key
+func (req *idempotentRequest) complete(ctx context.Context, tx *sqlx.Tx, status int, data interface{}) error {
+	return req.repo.CompleteInTx(ctx, tx, req.userID, req.key, status, data)
+}
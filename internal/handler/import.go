@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/importer"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// importPreviewRows is how many rows an upload shows for column mapping
+const importPreviewRows = 5
+
+type ImportHandler struct {
+	importRepo *repository.ImportRepository
+	clubRepo   *repository.ClubRepository
+	fieldRepo  *repository.CustomFieldRepository
+	validator  *validator.Validator
+}
+
+func NewImportHandler(importRepo *repository.ImportRepository, clubRepo *repository.ClubRepository, fieldRepo *repository.CustomFieldRepository, v *validator.Validator) *ImportHandler {
+	return &ImportHandler{
+		importRepo: importRepo,
+		clubRepo:   clubRepo,
+		fieldRepo:  fieldRepo,
+		validator:  v,
+	}
+}
+
+// ImportUploadResponse is an uploaded file ready for column mapping
+type ImportUploadResponse struct {
+	*model.Import
+	// Fields the columns can be mapped to, custom.<key> for the club's
+	// custom fields of students; Mapping holds the suggestion
+	Fields  []string          `json:"fields"`
+	Preview []model.ImportRow `json:"preview"`
+}
+
+// POST /api/v1/clubs/:club_id/imports
+// Takes a multipart "file" in CSV or XLSX format. Nothing is validated
+// until the mapping is confirmed.
+func (h *ImportHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	// Room for the multipart envelope around the file
+	r.Body = http.MaxBytesReader(w, r.Body, importer.MaxFileSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.BadRequest(w, "file is larger than 10 MB")
+			return
+		}
+		response.BadRequest(w, "file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, importer.MaxFileSize+1))
+	if err != nil {
+		response.BadRequest(w, "failed to read file")
+		return
+	}
+	if len(data) > importer.MaxFileSize {
+		response.BadRequest(w, "file is larger than 10 MB")
+		return
+	}
+
+	table, err := importer.Parse(header.Filename, data)
+	if err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	customFields, err := h.fieldRepo.GetByClub(r.Context(), clubID, model.CustomFieldStudent)
+	if err != nil {
+		response.InternalError(w, "failed to get custom fields")
+		return
+	}
+
+	fields := append([]string{}, importer.Fields...)
+	labels := make(map[string]string, len(customFields))
+	for _, f := range customFields {
+		fields = append(fields, importer.CustomFieldPrefix+f.Key)
+		labels[f.Key] = f.Label
+	}
+
+	mapping := importer.SuggestMapping(table.Headers)
+	mapping.SuggestCustomFields(table.Headers, labels)
+
+	userID := middleware.GetUserID(r.Context())
+	imp := &model.Import{
+		ClubID:    clubID,
+		CreatedBy: &userID,
+		Filename:  header.Filename,
+		Headers:   table.Headers,
+		Mapping:   mapping,
+		Options:   model.ImportOptions{OnDuplicate: string(model.DuplicateSkip)},
+	}
+
+	rows := make([]model.ImportRow, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i] = model.ImportRow{
+			RowNumber: row.Number,
+			Data:      row.Cells,
+			Status:    string(model.ImportRowPending),
+			Errors:    []string{},
+		}
+	}
+
+	if err := h.importRepo.Create(r.Context(), imp, rows); err != nil {
+		response.InternalError(w, "failed to save import")
+		return
+	}
+
+	response.Created(w, ImportUploadResponse{
+		Import:  imp,
+		Fields:  fields,
+		Preview: rows[:min(importPreviewRows, len(rows))],
+	})
+}
+
+// GET /api/v1/clubs/:club_id/imports
+func (h *ImportHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	imports, err := h.importRepo.GetByClub(r.Context(), clubID, 50)
+	if err != nil {
+		response.InternalError(w, "failed to get imports")
+		return
+	}
+
+	response.OK(w, imports)
+}
+
+// GET /api/v1/imports/:id
+// Clients poll this for the progress of validation and commit.
+func (h *ImportHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	imp, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, imp)
+}
+
+// GET /api/v1/imports/:id/rows?status=invalid
+func (h *ImportHandler) ListRows(w http.ResponseWriter, r *http.Request) {
+	imp, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	pagination := parsePagination(r)
+
+	rows, err := h.importRepo.GetRows(r.Context(), imp.ID, status, pagination.GetLimit(), pagination.GetOffset())
+	if err != nil {
+		response.InternalError(w, "failed to get import rows")
+		return
+	}
+
+	total, err := h.importRepo.CountRows(r.Context(), imp.ID, status)
+	if err != nil {
+		response.InternalError(w, "failed to count import rows")
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, rows, &response.Meta{
+		Page:       pagination.Page,
+		PerPage:    pagination.GetLimit(),
+		Total:      total,
+		TotalPages: (total + pagination.GetLimit() - 1) / pagination.GetLimit(),
+	})
+}
+
+// POST /api/v1/imports/:id/validate
+// Queues the dry run with the given mapping. It can be repeated with
+// another mapping until the import is committed.
+func (h *ImportHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	imp, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	var req ValidateImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	mapping := importer.Mapping(req.Mapping)
+	if err := mapping.Validate(imp.Headers, req.CreateSubscriptions); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	if keys := mapping.CustomFields(); len(keys) > 0 {
+		customFields, err := h.fieldRepo.GetByClub(r.Context(), imp.ClubID, model.CustomFieldStudent)
+		if err != nil {
+			response.InternalError(w, "failed to get custom fields")
+			return
+		}
+		known := make(map[string]bool, len(customFields))
+		for _, f := range customFields {
+			known[f.Key] = true
+		}
+		for _, key := range keys {
+			if !known[key] {
+				response.UnprocessableEntity(w, fmt.Sprintf("unknown field %q", importer.CustomFieldPrefix+key))
+				return
+			}
+		}
+	}
+
+	imp.Mapping = mapping
+	imp.Options = model.ImportOptions{
+		CreateSubscriptions: req.CreateSubscriptions,
+		OnDuplicate:         req.OnDuplicate,
+	}
+	if imp.Options.OnDuplicate == "" {
+		imp.Options.OnDuplicate = string(model.DuplicateSkip)
+	}
+
+	err := h.importRepo.Queue(r.Context(), imp, model.ImportValidating,
+		model.ImportUploaded, model.ImportValidated, model.ImportFailed)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Conflict(w, "import is being processed or already committed")
+			return
+		}
+		response.InternalError(w, "failed to queue import")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, imp)
+}
+
+// POST /api/v1/imports/:id/commit
+// Queues the creation of students and subscriptions from a validated
+// import, all in one transaction.
+func (h *ImportHandler) Commit(w http.ResponseWriter, r *http.Request) {
+	imp, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.importRepo.Queue(r.Context(), imp, model.ImportCommitting, model.ImportValidated); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Conflict(w, "only a validated import can be committed")
+			return
+		}
+		response.InternalError(w, "failed to queue import")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, imp)
+}
+
+// load fetches the import in the URL, writing the error response and
+// returning false unless the caller owns its club
+func (h *ImportHandler) load(w http.ResponseWriter, r *http.Request) (*model.Import, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid import id")
+		return nil, false
+	}
+
+	imp, err := h.importRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "import not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get import")
+		return nil, false
+	}
+
+	if !h.verifyOwner(w, r, imp.ClubID) {
+		return nil, false
+	}
+	return imp, true
+}
+
+// verifyOwner writes the error response and returns false unless the
+// caller owns the club
+func (h *ImportHandler) verifyOwner(w http.ResponseWriter, r *http.Request, clubID uuid.UUID) bool {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return false
+		}
+		response.InternalError(w, "failed to verify club")
+		return false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to import into this club")
+		return false
+	}
+	return true
+}
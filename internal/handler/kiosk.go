@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/checkin"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type KioskHandler struct {
+	kioskRepo      *repository.KioskRepository
+	clubRepo       *repository.ClubRepository
+	groupRepo      *repository.GroupRepository
+	studentRepo    *repository.StudentRepository
+	locationRepo   *repository.LocationRepository
+	checkInService *service.CheckInService
+	validator      *validator.Validator
+}
+
+func NewKioskHandler(
+	kioskRepo *repository.KioskRepository,
+	clubRepo *repository.ClubRepository,
+	groupRepo *repository.GroupRepository,
+	studentRepo *repository.StudentRepository,
+	locationRepo *repository.LocationRepository,
+	checkInService *service.CheckInService,
+	validator *validator.Validator,
+) *KioskHandler {
+	return &KioskHandler{
+		kioskRepo:      kioskRepo,
+		clubRepo:       clubRepo,
+		groupRepo:      groupRepo,
+		studentRepo:    studentRepo,
+		locationRepo:   locationRepo,
+		checkInService: checkInService,
+		validator:      validator,
+	}
+}
+
+// LookupDevice resolves a device token for middleware.KioskAuth
+func (h *KioskHandler) LookupDevice(ctx context.Context, token string) (*model.KioskDevice, error) {
+	return h.kioskRepo.Authenticate(ctx, checkin.HashDeviceToken(token))
+}
+
+// POST /api/v1/kiosk-devices
+// The device token is only returned here; store it on the kiosk.
+func (h *KioskHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	var req RegisterKioskDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	clubID, err := uuid.Parse(req.ClubID)
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.BadRequest(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID {
+		response.Forbidden(w, "you don't have permission to manage kiosks of this club")
+		return
+	}
+
+	location, err := resolveLocation(r.Context(), h.locationRepo, clubID, req.LocationID)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+
+	token, err := checkin.NewDeviceToken()
+	if err != nil {
+		response.InternalError(w, "failed to generate device token")
+		return
+	}
+
+	device := &model.KioskDevice{
+		ClubID:    clubID,
+		Name:      req.Name,
+		TokenHash: checkin.HashDeviceToken(token),
+		CreatedBy: userID,
+	}
+	if location != nil {
+		device.LocationID = &location.ID
+	}
+
+	if err := h.kioskRepo.Create(r.Context(), device); err != nil {
+		response.InternalError(w, "failed to register device")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{
+		"device": device,
+		"token":  token,
+	})
+}
+
+// GET /api/v1/clubs/:club_id/kiosk-devices
+func (h *KioskHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage kiosks of this club")
+		return
+	}
+
+	devices, err := h.kioskRepo.GetByClub(r.Context(), clubID)
+	if err != nil {
+		response.InternalError(w, "failed to get devices")
+		return
+	}
+
+	response.OK(w, devices)
+}
+
+// DELETE /api/v1/kiosk-devices/:id
+func (h *KioskHandler) RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid device id")
+		return
+	}
+
+	device, err := h.kioskRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "device not found")
+			return
+		}
+		response.InternalError(w, "failed to get device")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), device.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage kiosks of this club")
+		return
+	}
+
+	if err := h.kioskRepo.Revoke(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "device already revoked")
+			return
+		}
+		response.InternalError(w, "failed to revoke device")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// GET /api/v1/students/:student_id/checkin-token
+// Returns the student's personal QR code payload.
+func (h *KioskHandler) StudentToken(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(chi.URLParam(r, "student_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid student_id")
+		return
+	}
+
+	student, err := h.studentRepo.GetByID(r.Context(), studentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return
+		}
+		response.InternalError(w, "failed to get student")
+		return
+	}
+
+	allowed, err := h.worksAtClub(r, student.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify permissions")
+		return
+	}
+	if !allowed {
+		response.Forbidden(w, "you don't have permission to view this student's check-in code")
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"student_id": student.ID,
+		"token":      h.checkInService.StudentToken(student.ID),
+	})
+}
+
+// POST /api/v1/kiosk/check-in
+// Authenticated with the X-Device-Token header.
+func (h *KioskHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	var req CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	device := middleware.GetKioskDevice(r.Context())
+	result, err := h.checkInService.CheckIn(r.Context(), device, req.Token, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, checkin.ErrInvalidToken), errors.Is(err, service.ErrUnknownStudent):
+			response.BadRequest(w, err.Error())
+		case errors.Is(err, service.ErrNoSessionNow):
+			response.NotFound(w, err.Error())
+		case errors.Is(err, service.ErrNoCredit):
+			response.UnprocessableEntity(w, err.Error())
+		default:
+			response.InternalError(w, "check-in failed")
+		}
+		return
+	}
+
+	if result.AlreadyCheckedIn {
+		response.OK(w, result)
+		return
+	}
+	response.Created(w, result)
+}
+
+// POST /api/v1/kiosk/check-in/batch
+// Uploads scans collected while the kiosk was offline. Results are
+// returned in request order; scans are idempotent so a batch can be
+// retried after a lost response.
+func (h *KioskHandler) CheckInBatch(w http.ResponseWriter, r *http.Request) {
+	var req CheckInBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	scans := make([]service.Scan, len(req.Scans))
+	for i, s := range req.Scans {
+		scans[i] = service.Scan{
+			ID:        s.ID,
+			Token:     s.Token,
+			ScannedAt: s.ScannedAt,
+		}
+	}
+
+	device := middleware.GetKioskDevice(r.Context())
+	response.OK(w, map[string]interface{}{
+		"results": h.checkInService.CheckInBatch(r.Context(), device, scans),
+	})
+}
+
+// worksAtClub reports whether the caller owns the club or coaches one of
+// its groups
+func (h *KioskHandler) worksAtClub(r *http.Request, clubID uuid.UUID) (bool, error) {
+	userID := middleware.GetUserID(r.Context())
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		return false, err
+	}
+	if club.OwnerUserID == userID {
+		return true, nil
+	}
+
+	groups, err := h.groupRepo.GetByCoach(r.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups {
+		if g.ClubID == clubID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// errInvalidLocation is returned when a location_id is unknown or belongs
+// to another club
+var errInvalidLocation = errors.New("location not found in this club")
+
+type LocationHandler struct {
+	locationRepo *repository.LocationRepository
+	clubRepo     *repository.ClubRepository
+	validator    *validator.Validator
+}
+
+func NewLocationHandler(
+	locationRepo *repository.LocationRepository,
+	clubRepo *repository.ClubRepository,
+	validator *validator.Validator,
+) *LocationHandler {
+	return &LocationHandler{
+		locationRepo: locationRepo,
+		clubRepo:     clubRepo,
+		validator:    validator,
+	}
+}
+
+// POST /api/v1/locations
+func (h *LocationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+	if err := req.OpeningHours.Validate(); err != nil {
+		response.UnprocessableEntity(w, "opening_hours: "+err.Error())
+		return
+	}
+
+	clubID, err := uuid.Parse(req.ClubID)
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.BadRequest(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage locations of this club")
+		return
+	}
+
+	location := &model.Location{
+		ClubID:       clubID,
+		Name:         req.Name,
+		OpeningHours: req.OpeningHours,
+		Resources:    req.Resources,
+	}
+	if req.Address != "" {
+		location.Address = &req.Address
+	}
+	if req.Capacity > 0 {
+		location.Capacity = &req.Capacity
+	}
+
+	if err := h.locationRepo.Create(r.Context(), location); err != nil {
+		if repository.IsDuplicateName(err) {
+			response.Conflict(w, "a location with this name already exists")
+			return
+		}
+		response.InternalError(w, "failed to create location")
+		return
+	}
+
+	response.Created(w, location)
+}
+
+// GET /api/v1/clubs/:club_id/locations
+func (h *LocationHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if _, err := h.clubRepo.GetByID(r.Context(), clubID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	locations, err := h.locationRepo.GetByClub(r.Context(), clubID)
+	if err != nil {
+		response.InternalError(w, "failed to get locations")
+		return
+	}
+
+	response.OK(w, locations)
+}
+
+// GET /api/v1/locations/:id
+func (h *LocationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid location id")
+		return
+	}
+
+	location, err := h.locationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "location not found")
+			return
+		}
+		response.InternalError(w, "failed to get location")
+		return
+	}
+
+	response.OK(w, location)
+}
+
+// PUT /api/v1/locations/:id
+func (h *LocationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+	if err := req.OpeningHours.Validate(); err != nil {
+		response.UnprocessableEntity(w, "opening_hours: "+err.Error())
+		return
+	}
+
+	location, ok := h.loadForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		location.Name = *req.Name
+	}
+	if req.Address != nil {
+		location.Address = req.Address
+	}
+	if req.Capacity != nil {
+		location.Capacity = req.Capacity
+	}
+	if req.OpeningHours != nil {
+		location.OpeningHours = req.OpeningHours
+	}
+	if req.Resources != nil {
+		location.Resources = req.Resources
+	}
+
+	if err := h.locationRepo.Update(r.Context(), location); err != nil {
+		if repository.IsDuplicateName(err) {
+			response.Conflict(w, "a location with this name already exists")
+			return
+		}
+		response.InternalError(w, "failed to update location")
+		return
+	}
+
+	response.OK(w, location)
+}
+
+// DELETE /api/v1/locations/:id
+func (h *LocationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	location, ok := h.loadForWrite(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.locationRepo.Delete(r.Context(), location.ID); err != nil {
+		response.InternalError(w, "failed to delete location")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// loadForWrite fetches the location from the URL and checks the caller owns
+// its club. On failure the response has already been written.
+func (h *LocationHandler) loadForWrite(w http.ResponseWriter, r *http.Request) (*model.Location, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid location id")
+		return nil, false
+	}
+
+	location, err := h.locationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "location not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get location")
+		return nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), location.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return nil, false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage locations of this club")
+		return nil, false
+	}
+
+	return location, true
+}
+
+// resolveLocation looks up a location_id from a request and checks it
+// belongs to the club. An empty value resolves to no location.
+func resolveLocation(ctx context.Context, repo *repository.LocationRepository, clubID uuid.UUID, value string) (*model.Location, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, errInvalidLocation
+	}
+
+	location, err := repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && location.ClubID != clubID) {
+		return nil, errInvalidLocation
+	}
+	return location, err
+}
+
+// locationFor picks the location of new sessions: the requested one, else
+// the group's default unless a free-text location was given
+func locationFor(ctx context.Context, repo *repository.LocationRepository, group *model.Group, locationID, freeText string) (*model.Location, error) {
+	if locationID != "" {
+		return resolveLocation(ctx, repo, group.ClubID, locationID)
+	}
+	if freeText != "" || group.LocationID == nil {
+		return nil, nil
+	}
+
+	location, err := repo.GetByID(ctx, *group.LocationID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return location, err
+}
+
+// locationFields returns the label and reference stored on a session or
+// series: the free text if given, else the location's name
+func locationFields(location *model.Location, freeText string) (string, *uuid.UUID) {
+	if location == nil {
+		return freeText, nil
+	}
+	if freeText == "" {
+		freeText = location.Name
+	}
+	return freeText, &location.ID
+}
+
+func writeLocationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidLocation) {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	response.InternalError(w, "failed to verify location")
+}
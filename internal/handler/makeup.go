@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type MakeupHandler struct {
+	makeupRepo  *repository.MakeupRepository
+	studentRepo *repository.StudentRepository
+	groupRepo   *repository.GroupRepository
+	clubRepo    *repository.ClubRepository
+	validator   *validator.Validator
+}
+
+func NewMakeupHandler(
+	makeupRepo *repository.MakeupRepository,
+	studentRepo *repository.StudentRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	validator *validator.Validator,
+) *MakeupHandler {
+	return &MakeupHandler{
+		makeupRepo:  makeupRepo,
+		studentRepo: studentRepo,
+		groupRepo:   groupRepo,
+		clubRepo:    clubRepo,
+		validator:   validator,
+	}
+}
+
+// GET /api/v1/students/:student_id/makeup-credits
+func (h *MakeupHandler) ListByStudent(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(chi.URLParam(r, "student_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid student_id")
+		return
+	}
+
+	if _, err := h.studentRepo.GetByID(r.Context(), studentID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return
+		}
+		response.InternalError(w, "failed to get student")
+		return
+	}
+
+	credits, err := h.makeupRepo.GetByStudent(r.Context(), studentID)
+	if err != nil {
+		response.InternalError(w, "failed to get makeup credits")
+		return
+	}
+
+	response.OK(w, credits)
+}
+
+// GET /api/v1/groups/:group_id/makeup-groups
+func (h *MakeupHandler) GetAllowedGroups(w http.ResponseWriter, r *http.Request) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "group_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid group_id")
+		return
+	}
+
+	if _, err := h.groupRepo.GetByID(r.Context(), groupID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to get group")
+		return
+	}
+
+	ids, err := h.makeupRepo.GetAllowedGroups(r.Context(), groupID)
+	if err != nil {
+		response.InternalError(w, "failed to get makeup groups")
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"group_ids": ids,
+	})
+}
+
+// PUT /api/v1/groups/:group_id/makeup-groups
+// Replaces the other groups where absences of this group can be made up.
+// Groups of other clubs are ignored.
+func (h *MakeupHandler) SetAllowedGroups(w http.ResponseWriter, r *http.Request) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "group_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid group_id")
+		return
+	}
+
+	var req SetMakeupGroupsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), groupID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to get group")
+		return
+	}
+
+	if !h.ownsClub(r, group) {
+		response.Forbidden(w, "you don't have permission to change this group's makeup policy")
+		return
+	}
+
+	allowed := make([]uuid.UUID, 0, len(req.GroupIDs))
+	for _, v := range req.GroupIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			response.BadRequest(w, "invalid group id in group_ids")
+			return
+		}
+		allowed = append(allowed, id)
+	}
+
+	if err := h.makeupRepo.SetAllowedGroups(r.Context(), groupID, allowed); err != nil {
+		response.InternalError(w, "failed to update makeup groups")
+		return
+	}
+
+	ids, err := h.makeupRepo.GetAllowedGroups(r.Context(), groupID)
+	if err != nil {
+		response.InternalError(w, "failed to get makeup groups")
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"group_ids": ids,
+	})
+}
+
+func (h *MakeupHandler) ownsClub(r *http.Request, group *model.Group) bool {
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		return false
+	}
+	return club.OwnerUserID == middleware.GetUserID(r.Context())
+}
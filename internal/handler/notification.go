@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type NotificationHandler struct {
+	notificationRepo *repository.NotificationRepository
+	clubRepo         *repository.ClubRepository
+}
+
+func NewNotificationHandler(notificationRepo *repository.NotificationRepository, clubRepo *repository.ClubRepository) *NotificationHandler {
+	return &NotificationHandler{
+		notificationRepo: notificationRepo,
+		clubRepo:         clubRepo,
+	}
+}
+
+// GET /api/v1/clubs/:club_id/notifications?status=failed&limit=50
+func (h *NotificationHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch model.NotificationStatus(status) {
+	case "", model.NotificationPending, model.NotificationSent, model.NotificationFailed:
+	default:
+		response.BadRequest(w, "status must be pending, sent or failed")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 200 {
+			limit = l
+		}
+	}
+
+	notifications, err := h.notificationRepo.GetByClub(r.Context(), clubID, status, limit)
+	if err != nil {
+		response.InternalError(w, "failed to get notifications")
+		return
+	}
+
+	response.OK(w, notifications)
+}
+
+// POST /api/v1/notifications/:id/retry
+// Queues a failed notification again.
+func (h *NotificationHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid notification id")
+		return
+	}
+
+	notification, err := h.notificationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "notification not found")
+			return
+		}
+		response.InternalError(w, "failed to get notification")
+		return
+	}
+
+	if !h.verifyOwner(w, r, notification.ClubID) {
+		return
+	}
+
+	if err := h.notificationRepo.Retry(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.UnprocessableEntity(w, "only failed notifications can be retried")
+			return
+		}
+		response.InternalError(w, "failed to retry notification")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// verifyOwner writes the error response and returns false unless the
+// caller owns the club
+func (h *NotificationHandler) verifyOwner(w http.ResponseWriter, r *http.Request, clubID uuid.UUID) bool {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return false
+		}
+		response.InternalError(w, "failed to verify club")
+		return false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to view this club's notifications")
+		return false
+	}
+	return true
+}
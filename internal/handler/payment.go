@@ -13,8 +13,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
 	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
 	"github.com/neo/trainer-plus/internal/validator"
 	"github.com/neo/trainer-plus/pkg/response"
 	"github.com/stripe/stripe-go/v76"
@@ -28,6 +30,7 @@ type PaymentHandler struct {
 	studentRepo *repository.StudentRepository
 	groupRepo   *repository.GroupRepository
 	clubRepo    *repository.ClubRepository
+	notifier    service.PaymentNotifier
 	validator   *validator.Validator
 	logger      *slog.Logger
 }
@@ -38,6 +41,7 @@ func NewPaymentHandler(
 	studentRepo *repository.StudentRepository,
 	groupRepo *repository.GroupRepository,
 	clubRepo *repository.ClubRepository,
+	notifier service.PaymentNotifier,
 	validator *validator.Validator,
 	logger *slog.Logger,
 ) *PaymentHandler {
@@ -47,6 +51,7 @@ func NewPaymentHandler(
 		studentRepo: studentRepo,
 		groupRepo:   groupRepo,
 		clubRepo:    clubRepo,
+		notifier:    notifier,
 		validator:   validator,
 		logger:      logger,
 	}
@@ -165,6 +170,7 @@ func (h *PaymentHandler) CreateCheckoutSession(w http.ResponseWriter, r *http.Re
 		RemainingSessions: req.Subscription.TotalSessions,
 		Price:             req.Subscription.Price,
 		Status:            string(model.SubscriptionPending),
+		Kind:              string(model.SubscriptionPackage),
 	}
 
 	if err := h.subRepo.Create(r.Context(), sub); err != nil {
@@ -351,8 +357,37 @@ func (h *PaymentHandler) handleCheckoutCompleted(ctx context.Context, sess *stri
 		slog.String("payment_id", payment.ID.String()),
 		slog.String("subscription_id", payment.SubscriptionID.String()))
 
-	// TODO: Send email receipt
-	// TODO: Notify coach/owner
+	payment.Status = string(model.PaymentSucceeded)
+	h.notifyPaid(ctx, payment)
+}
+
+// notifyPaid sends the guardian a receipt. Failures are logged; the
+// payment itself is already recorded.
+func (h *PaymentHandler) notifyPaid(ctx context.Context, payment *model.Payment) {
+	if h.notifier == nil {
+		return
+	}
+
+	notice := service.PaymentNotice{Payment: payment}
+	var err error
+	notice.Subscription, err = h.subRepo.GetByID(ctx, payment.SubscriptionID)
+	if err == nil {
+		notice.Student, err = h.studentRepo.GetByID(ctx, notice.Subscription.StudentID)
+	}
+	if err == nil {
+		notice.Group, err = h.groupRepo.GetByID(ctx, notice.Subscription.GroupID)
+	}
+	if err == nil {
+		notice.Club, err = h.clubRepo.GetByID(ctx, notice.Group.ClubID)
+	}
+	if err == nil {
+		err = h.notifier.PaymentSucceeded(ctx, notice)
+	}
+	if err != nil {
+		h.logger.Error("failed to send payment receipt",
+			slog.String("payment_id", payment.ID.String()),
+			slog.String("error", err.Error()))
+	}
 }
 
 func (h *PaymentHandler) handleCheckoutExpired(ctx context.Context, sess *stripe.CheckoutSession) {
@@ -438,6 +473,64 @@ func (h *PaymentHandler) GetBySubscription(w http.ResponseWriter, r *http.Reques
 	response.OK(w, payments)
 }
 
+// GET /api/v1/clubs/:club_id/payments?from=2026-01-01&to=2026-01-31&status=succeeded
+func (h *PaymentHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	club := exportClub(w, r, h.clubRepo, clubID)
+	if club == nil {
+		return
+	}
+
+	from, to := parseDateRange(r, club.Location())
+	status := r.URL.Query().Get("status")
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "payments", Title: "list.payments", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			err := d.Table("",
+				export.Column{Key: "created_at", Kind: export.DateTime},
+				export.Column{Key: "student"},
+				export.Column{Key: "group"},
+				export.Column{Key: "amount", Kind: export.Money},
+				export.Column{Key: "payment_method", Kind: export.Label},
+				export.Column{Key: "payment_status", Kind: export.Label},
+				export.Column{Key: "paid_at", Kind: export.DateTime},
+			)
+			if err != nil {
+				return err
+			}
+
+			return h.paymentRepo.EachByClubWithDetails(r.Context(), clubID, from, to, status, func(p *repository.PaymentWithDetails) error {
+				return d.Row(p.CreatedAt, p.StudentName, p.GroupTitle, p.Amount, p.Method, p.Status, p.PaidAt)
+			})
+		})
+		return
+	}
+
+	payments := []repository.PaymentWithDetails{}
+	err = h.paymentRepo.EachByClubWithDetails(r.Context(), clubID, from, to, status, func(p *repository.PaymentWithDetails) error {
+		payments = append(payments, *p)
+		return nil
+	})
+	if err != nil {
+		response.InternalError(w, "failed to get payments")
+		return
+	}
+
+	response.OK(w, payments)
+}
+
 // POST /api/v1/payments/manual - Create manual/cash payment
 func (h *PaymentHandler) CreateManual(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -508,6 +601,8 @@ func (h *PaymentHandler) CreateManual(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.notifyPaid(r.Context(), payment)
+
 	response.Created(w, payment)
 }
 
@@ -0,0 +1,427 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/payroll"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// maxPayrollDays is the longest period a payroll run covers
+const maxPayrollDays = 366
+
+// PayrollHandler manages the rules coaches are paid by and the payroll
+// runs of a club. Only the club owner sees them.
+type PayrollHandler struct {
+	payrollService *service.PayrollService
+	payrollRepo    *repository.PayrollRepository
+	groupRepo      *repository.GroupRepository
+	clubRepo       *repository.ClubRepository
+	validator      *validator.Validator
+}
+
+func NewPayrollHandler(
+	payrollService *service.PayrollService,
+	payrollRepo *repository.PayrollRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	validator *validator.Validator,
+) *PayrollHandler {
+	return &PayrollHandler{
+		payrollService: payrollService,
+		payrollRepo:    payrollRepo,
+		groupRepo:      groupRepo,
+		clubRepo:       clubRepo,
+		validator:      validator,
+	}
+}
+
+// ==================== Rules ====================
+
+// GET /api/v1/clubs/:club_id/payroll-rules
+func (h *PayrollHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	club, ok := h.loadClub(w, r, chi.URLParam(r, "club_id"))
+	if !ok {
+		return
+	}
+
+	rules, err := h.payrollRepo.GetRules(r.Context(), club.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get payroll rules")
+		return
+	}
+
+	response.OK(w, rules)
+}
+
+// POST /api/v1/clubs/:club_id/payroll-rules
+func (h *PayrollHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req CreatePayrollRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+	if err := checkPayrollRate(req.Kind, req.Rate); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	club, ok := h.loadClub(w, r, chi.URLParam(r, "club_id"))
+	if !ok {
+		return
+	}
+
+	rule := &model.PayrollRule{ClubID: club.ID, Kind: req.Kind, Rate: req.Rate}
+	rule.CoachUserID, _ = uuid.Parse(req.CoachUserID)
+
+	// The coach must teach in the club
+	groups, err := h.groupRepo.GetByCoach(r.Context(), rule.CoachUserID)
+	if err != nil {
+		response.InternalError(w, "failed to verify coach")
+		return
+	}
+	coaches := false
+	for _, g := range groups {
+		coaches = coaches || g.ClubID == club.ID
+	}
+	if !coaches {
+		response.UnprocessableEntity(w, "the coach has no group in this club")
+		return
+	}
+
+	if req.GroupID != "" {
+		groupID, _ := uuid.Parse(req.GroupID)
+		group, err := h.groupRepo.GetByID(r.Context(), groupID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				response.BadRequest(w, "group not found")
+				return
+			}
+			response.InternalError(w, "failed to verify group")
+			return
+		}
+		if group.ClubID != club.ID {
+			response.BadRequest(w, "group belongs to another club")
+			return
+		}
+		rule.GroupID = &group.ID
+	}
+
+	if err := h.payrollRepo.CreateRule(r.Context(), rule); err != nil {
+		if repository.IsDuplicatePayrollRule(err) {
+			response.Conflict(w, "the coach already has a rule for this group")
+			return
+		}
+		response.InternalError(w, "failed to create payroll rule")
+		return
+	}
+
+	response.Created(w, rule)
+}
+
+// PUT /api/v1/payroll-rules/:id
+// Runs already calculated keep their rates until recalculated
+func (h *PayrollHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePayrollRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+	if err := checkPayrollRate(req.Kind, req.Rate); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	rule, ok := h.loadRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule.Kind = req.Kind
+	rule.Rate = req.Rate
+	if err := h.payrollRepo.UpdateRule(r.Context(), rule); err != nil {
+		response.InternalError(w, "failed to update payroll rule")
+		return
+	}
+
+	response.OK(w, rule)
+}
+
+// DELETE /api/v1/payroll-rules/:id
+func (h *PayrollHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.loadRule(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.payrollRepo.DeleteRule(r.Context(), rule.ID); err != nil {
+		response.InternalError(w, "failed to delete payroll rule")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Runs ====================
+
+// GET /api/v1/clubs/:club_id/payroll-runs
+func (h *PayrollHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	club, ok := h.loadClub(w, r, chi.URLParam(r, "club_id"))
+	if !ok {
+		return
+	}
+
+	runs, err := h.payrollRepo.GetRuns(r.Context(), club.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get payroll runs")
+		return
+	}
+
+	response.OK(w, runs)
+}
+
+// POST /api/v1/clubs/:club_id/payroll-runs
+// Calculates a draft run with the club's current rules
+func (h *PayrollHandler) CreateRun(w http.ResponseWriter, r *http.Request) {
+	var req CreatePayrollRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	start, _ := time.Parse("2006-01-02", req.PeriodStart)
+	end, _ := time.Parse("2006-01-02", req.PeriodEnd)
+	if end.Before(start) {
+		response.UnprocessableEntity(w, "period_end must not be before period_start")
+		return
+	}
+	if end.Sub(start) >= maxPayrollDays*24*time.Hour {
+		response.UnprocessableEntity(w, "a payroll run covers at most a year")
+		return
+	}
+
+	club, ok := h.loadClub(w, r, chi.URLParam(r, "club_id"))
+	if !ok {
+		return
+	}
+
+	run, err := h.payrollService.Create(r.Context(), club, start, end, middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.InternalError(w, "failed to calculate payroll")
+		return
+	}
+
+	response.Created(w, run)
+}
+
+// GET /api/v1/payroll-runs/:id
+// The run with its lines and totals per coach; ?format= exports it
+func (h *PayrollHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	run, club, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.payrollRepo.GetItems(r.Context(), run.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get payroll items")
+		return
+	}
+	run.Items = items
+	run.Coaches, _ = payroll.Totals(items)
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		from, to := service.PayrollPeriod(run, club.Location())
+		info := export.Info{Name: "payroll", Title: "report.payroll", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportPayroll(d, run)
+		})
+		return
+	}
+
+	response.OK(w, run)
+}
+
+// POST /api/v1/payroll-runs/:id/recalculate
+func (h *PayrollHandler) RecalculateRun(w http.ResponseWriter, r *http.Request) {
+	run, club, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.payrollService.Recalculate(r.Context(), club, run.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, "payroll run not found")
+		case errors.Is(err, service.ErrPayrollApproved):
+			response.Conflict(w, err.Error())
+		default:
+			response.InternalError(w, "failed to calculate payroll")
+		}
+		return
+	}
+
+	response.OK(w, run)
+}
+
+// POST /api/v1/payroll-runs/:id/approve
+// Locks the run as it was last calculated
+func (h *PayrollHandler) ApproveRun(w http.ResponseWriter, r *http.Request) {
+	run, _, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.payrollService.Approve(r.Context(), run.ID, middleware.GetUserID(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, "payroll run not found")
+		case errors.Is(err, service.ErrPayrollApproved), errors.Is(err, service.ErrPayrollOverlaps):
+			response.Conflict(w, err.Error())
+		default:
+			response.InternalError(w, "failed to approve payroll run")
+		}
+		return
+	}
+
+	response.OK(w, run)
+}
+
+// DELETE /api/v1/payroll-runs/:id
+// Only drafts can be deleted
+func (h *PayrollHandler) DeleteRun(w http.ResponseWriter, r *http.Request) {
+	run, _, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.payrollRepo.DeleteRun(r.Context(), run.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Conflict(w, service.ErrPayrollApproved.Error())
+			return
+		}
+		response.InternalError(w, "failed to delete payroll run")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Helpers ====================
+
+// loadClub fetches a club and checks the caller owns it. On failure the
+// response has already been written.
+func (h *PayrollHandler) loadClub(w http.ResponseWriter, r *http.Request, rawID string) (*model.Club, bool) {
+	clubID, err := uuid.Parse(rawID)
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get club")
+		return nil, false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage this club's payroll")
+		return nil, false
+	}
+	return club, true
+}
+
+// loadRule fetches the rule from the URL for the owner of its club
+func (h *PayrollHandler) loadRule(w http.ResponseWriter, r *http.Request) (*model.PayrollRule, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid payroll rule id")
+		return nil, false
+	}
+
+	rule, err := h.payrollRepo.GetRule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "payroll rule not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get payroll rule")
+		return nil, false
+	}
+
+	if _, ok := h.loadClub(w, r, rule.ClubID.String()); !ok {
+		return nil, false
+	}
+	return rule, true
+}
+
+// loadRun fetches the run from the URL, and its club, for the club owner
+func (h *PayrollHandler) loadRun(w http.ResponseWriter, r *http.Request) (*model.PayrollRun, *model.Club, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid payroll run id")
+		return nil, nil, false
+	}
+
+	run, err := h.payrollRepo.GetRun(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "payroll run not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get payroll run")
+		return nil, nil, false
+	}
+
+	club, ok := h.loadClub(w, r, run.ClubID.String())
+	if !ok {
+		return nil, nil, false
+	}
+	return run, club, true
+}
+
+// checkPayrollRate keeps a revenue share within 100%
+func checkPayrollRate(kind string, rate float64) error {
+	if model.PayrollRuleKind(kind) == model.PayrollRevenueShare && rate > 100 {
+		return errors.New("a revenue share rate is a percentage up to 100")
+	}
+	return nil
+}
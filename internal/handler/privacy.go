@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/privacy"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+// PrivacyHandler serves guardians' requests to export or erase the
+// personal data of their children. Only the club owner can make them.
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+	privacyRepo    *repository.PrivacyRepository
+	clubRepo       *repository.ClubRepository
+	storage        storage.Storage
+	validator      *validator.Validator
+	logger         *slog.Logger
+}
+
+func NewPrivacyHandler(
+	privacyService *service.PrivacyService,
+	privacyRepo *repository.PrivacyRepository,
+	clubRepo *repository.ClubRepository,
+	storage storage.Storage,
+	validator *validator.Validator,
+	logger *slog.Logger,
+) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+		privacyRepo:    privacyRepo,
+		clubRepo:       clubRepo,
+		storage:        storage,
+		validator:      validator,
+		logger:         logger,
+	}
+}
+
+// GET /api/v1/students/:id/personal-data
+// Downloads a ZIP of everything held about the student.
+func (h *PrivacyHandler) ExportStudent(w http.ResponseWriter, r *http.Request) {
+	subject, club, ok := h.studentSubject(w, r)
+	if !ok {
+		return
+	}
+	h.export(w, r, club, subject)
+}
+
+// POST /api/v1/students/:id/erase
+func (h *PrivacyHandler) EraseStudent(w http.ResponseWriter, r *http.Request) {
+	subject, _, ok := h.studentSubject(w, r)
+	if !ok {
+		return
+	}
+	h.erase(w, r, subject)
+}
+
+// POST /api/v1/clubs/:club_id/guardian-data/export
+// Downloads a ZIP of everything held about the students of the guardian
+// with the email or phone in the body. The contacts go in the body rather
+// than the URL to keep them out of access logs.
+func (h *PrivacyHandler) ExportGuardian(w http.ResponseWriter, r *http.Request) {
+	subject, club, ok := h.guardianSubject(w, r)
+	if !ok {
+		return
+	}
+	h.export(w, r, club, subject)
+}
+
+// POST /api/v1/clubs/:club_id/guardian-data/erase
+func (h *PrivacyHandler) EraseGuardian(w http.ResponseWriter, r *http.Request) {
+	subject, _, ok := h.guardianSubject(w, r)
+	if !ok {
+		return
+	}
+	h.erase(w, r, subject)
+}
+
+// GET /api/v1/clubs/:club_id/data-requests?limit=100
+func (h *PrivacyHandler) ListRequests(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if _, ok := h.ownedClub(w, r, clubID); !ok {
+		return
+	}
+
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	requests, err := h.privacyRepo.GetRequestsByClub(r.Context(), clubID, limit)
+	if err != nil {
+		response.InternalError(w, "failed to get data requests")
+		return
+	}
+
+	response.OK(w, requests)
+}
+
+func (h *PrivacyHandler) export(w http.ResponseWriter, r *http.Request, club *model.Club, subject *service.DataSubject) {
+	dossier, err := h.privacyService.Export(r.Context(), club, subject, middleware.GetUserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, service.ErrNoPersonalData) {
+			response.NotFound(w, err.Error())
+			return
+		}
+		response.InternalError(w, "failed to export personal data")
+		return
+	}
+
+	filename := "personal-data-" + dossier.GeneratedAt.Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// Once the download has started an error can only cut it short
+	if err := privacy.WriteArchive(r.Context(), w, dossier, h.storage); err != nil {
+		h.logger.Error("personal data export interrupted", slog.String("request_id", dossier.Request.ID.String()), slog.String("error", err.Error()))
+	}
+}
+
+func (h *PrivacyHandler) erase(w http.ResponseWriter, r *http.Request, subject *service.DataSubject) {
+	request, err := h.privacyService.Erase(r.Context(), subject, middleware.GetUserID(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNoPersonalData), errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, service.ErrNoPersonalData.Error())
+		default:
+			response.InternalError(w, "failed to erase personal data")
+		}
+		return
+	}
+
+	response.OK(w, request)
+}
+
+// studentSubject loads the student in the URL, writing the error response
+// and returning false unless the caller owns their club
+func (h *PrivacyHandler) studentSubject(w http.ResponseWriter, r *http.Request) (*service.DataSubject, *model.Club, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid student id")
+		return nil, nil, false
+	}
+
+	subject, err := h.privacyService.StudentSubject(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get student")
+		return nil, nil, false
+	}
+
+	club, ok := h.ownedClub(w, r, subject.ClubID)
+	return subject, club, ok
+}
+
+// guardianSubject matches the guardian in the body against the students
+// of the club in the URL, writing the error response and returning false
+// unless the caller owns the club
+func (h *PrivacyHandler) guardianSubject(w http.ResponseWriter, r *http.Request) (*service.DataSubject, *model.Club, bool) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return nil, nil, false
+	}
+
+	var req GuardianDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return nil, nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return nil, nil, false
+	}
+
+	guardian := privacy.Guardian{Email: req.Email, Phone: req.Phone}
+	if !guardian.Valid() {
+		response.UnprocessableEntity(w, "email or a full phone number is required")
+		return nil, nil, false
+	}
+
+	club, ok := h.ownedClub(w, r, clubID)
+	if !ok {
+		return nil, nil, false
+	}
+
+	subject, err := h.privacyService.GuardianSubject(r.Context(), clubID, guardian)
+	if err != nil {
+		response.InternalError(w, "failed to find the guardian's students")
+		return nil, nil, false
+	}
+	return subject, club, true
+}
+
+// ownedClub fetches a club, writing the error response and returning false
+// unless the caller owns it
+func (h *PrivacyHandler) ownedClub(w http.ResponseWriter, r *http.Request, clubID uuid.UUID) (*model.Club, bool) {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to verify club")
+		return nil, false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to handle personal data of this club")
+		return nil, false
+	}
+	return club, true
+}
@@ -0,0 +1,671 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+// maxDocumentSize is the largest document that can be uploaded
+const maxDocumentSize = 10 << 20
+
+// documentTypes are the accepted document formats, as sniffed from the
+// content: scans and photos
+var documentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+// profileAccess is how much of a student's profile a user may see
+type profileAccess int
+
+const (
+	// Coaches of the student's groups see what matters at training:
+	// medical details, certificates, emergency contacts and consents
+	profileRead profileAccess = iota + 1
+	// The club owner also manages them and sees the documents
+	profileManage
+)
+
+type ProfileHandler struct {
+	profileRepo *repository.ProfileRepository
+	studentRepo *repository.StudentRepository
+	clubRepo    *repository.ClubRepository
+	storage     storage.Storage
+	validator   *validator.Validator
+	logger      *slog.Logger
+}
+
+func NewProfileHandler(
+	profileRepo *repository.ProfileRepository,
+	studentRepo *repository.StudentRepository,
+	clubRepo *repository.ClubRepository,
+	storage storage.Storage,
+	validator *validator.Validator,
+	logger *slog.Logger,
+) *ProfileHandler {
+	return &ProfileHandler{
+		profileRepo: profileRepo,
+		studentRepo: studentRepo,
+		clubRepo:    clubRepo,
+		storage:     storage,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// MedicalResponse is a student's medical details with their certificates
+type MedicalResponse struct {
+	*model.StudentMedical
+	Certificates []model.MedicalCertificate `json:"certificates"`
+}
+
+// GET /api/v1/students/:id/medical
+func (h *ProfileHandler) GetMedical(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileRead)
+	if !ok {
+		return
+	}
+
+	medical, err := h.profileRepo.GetMedical(r.Context(), student.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		medical, err = &model.StudentMedical{StudentID: student.ID}, nil
+	}
+	if err != nil {
+		response.InternalError(w, "failed to get medical details")
+		return
+	}
+
+	certificates, err := h.profileRepo.GetCertificates(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get medical certificates")
+		return
+	}
+
+	response.OK(w, MedicalResponse{StudentMedical: medical, Certificates: certificates})
+}
+
+// PUT /api/v1/students/:id/medical
+func (h *ProfileHandler) UpdateMedical(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	var req UpdateMedicalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	medical := &model.StudentMedical{
+		StudentID:   student.ID,
+		BloodType:   strings.TrimSpace(req.BloodType),
+		Allergies:   strings.TrimSpace(req.Allergies),
+		Conditions:  strings.TrimSpace(req.Conditions),
+		Medications: strings.TrimSpace(req.Medications),
+		Notes:       strings.TrimSpace(req.Notes),
+		UpdatedBy:   &userID,
+	}
+
+	if err := h.profileRepo.SaveMedical(r.Context(), medical); err != nil {
+		response.InternalError(w, "failed to save medical details")
+		return
+	}
+
+	response.OK(w, medical)
+}
+
+// POST /api/v1/students/:id/medical-certificates
+func (h *ProfileHandler) CreateCertificate(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	var req CreateMedicalCertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	certificate := &model.MedicalCertificate{
+		StudentID: student.ID,
+		Issuer:    strings.TrimSpace(req.Issuer),
+		Notes:     strings.TrimSpace(req.Notes),
+	}
+	// Validated as dates above
+	certificate.ExpiresOn, _ = time.Parse("2006-01-02", req.ExpiresOn)
+	if req.IssuedOn != "" {
+		issuedOn, _ := time.Parse("2006-01-02", req.IssuedOn)
+		if issuedOn.After(certificate.ExpiresOn) {
+			response.UnprocessableEntity(w, "issued_on must not be after expires_on")
+			return
+		}
+		certificate.IssuedOn = &issuedOn
+	}
+
+	if certificate.DocumentID, ok = h.studentDocument(w, r, student.ID, req.DocumentID); !ok {
+		return
+	}
+
+	if err := h.profileRepo.CreateCertificate(r.Context(), certificate); err != nil {
+		response.InternalError(w, "failed to save medical certificate")
+		return
+	}
+
+	response.Created(w, certificate)
+}
+
+// DELETE /api/v1/medical-certificates/:id
+func (h *ProfileHandler) DeleteCertificate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid certificate id")
+		return
+	}
+
+	certificate, err := h.profileRepo.GetCertificate(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "medical certificate not found")
+			return
+		}
+		response.InternalError(w, "failed to get medical certificate")
+		return
+	}
+
+	if _, ok := h.loadStudent(w, r, certificate.StudentID.String(), profileManage); !ok {
+		return
+	}
+
+	if err := h.profileRepo.DeleteCertificate(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to delete medical certificate")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// GET /api/v1/clubs/:club_id/medical-certificates/alerts?days=30
+// Students whose latest certificate has expired or expires within days
+func (h *ProfileHandler) CertificateAlerts(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to view this club's students")
+		return
+	}
+
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d >= 0 && d <= 365 {
+			days = d
+		}
+	}
+
+	now := time.Now().In(club.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	alerts, err := h.profileRepo.GetCertificateAlerts(r.Context(), clubID, today, today.AddDate(0, 0, days))
+	if err != nil {
+		response.InternalError(w, "failed to get medical certificate alerts")
+		return
+	}
+
+	response.OK(w, alerts)
+}
+
+// GET /api/v1/students/:id/emergency-contacts
+func (h *ProfileHandler) GetEmergencyContacts(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileRead)
+	if !ok {
+		return
+	}
+
+	contacts, err := h.profileRepo.GetEmergencyContacts(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get emergency contacts")
+		return
+	}
+
+	response.OK(w, contacts)
+}
+
+// PUT /api/v1/students/:id/emergency-contacts
+func (h *ProfileHandler) UpdateEmergencyContacts(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	var req UpdateEmergencyContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	contacts := make([]model.EmergencyContact, len(req.Contacts))
+	for i, c := range req.Contacts {
+		contacts[i] = model.EmergencyContact{
+			Name:     strings.TrimSpace(c.Name),
+			Relation: strings.TrimSpace(c.Relation),
+			Phone:    strings.TrimSpace(c.Phone),
+		}
+	}
+
+	if err := h.profileRepo.ReplaceEmergencyContacts(r.Context(), student.ID, contacts); err != nil {
+		response.InternalError(w, "failed to save emergency contacts")
+		return
+	}
+
+	response.OK(w, contacts)
+}
+
+// GET /api/v1/students/:id/documents
+func (h *ProfileHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	documents, err := h.profileRepo.GetDocuments(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get documents")
+		return
+	}
+
+	response.OK(w, documents)
+}
+
+// POST /api/v1/students/:id/documents
+// Takes a multipart "file" (PDF, JPEG, PNG or WebP, up to 10 MB) and its
+// "kind"
+func (h *ProfileHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	// Room for the multipart envelope around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.BadRequest(w, "file is larger than 10 MB")
+			return
+		}
+		response.BadRequest(w, "file is required")
+		return
+	}
+	defer file.Close()
+
+	kind := r.FormValue("kind")
+	switch model.DocumentKind(kind) {
+	case model.DocumentMedicalCertificate, model.DocumentWaiver, model.DocumentConsent,
+		model.DocumentIdentity, model.DocumentOther:
+	case "":
+		kind = string(model.DocumentOther)
+	default:
+		response.UnprocessableEntity(w, "kind must be one of: medical_certificate waiver consent id_document other")
+		return
+	}
+
+	if header.Size > maxDocumentSize {
+		response.BadRequest(w, "file is larger than 10 MB")
+		return
+	}
+
+	// Trust the content, not the name or the browser
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		response.BadRequest(w, "failed to read file")
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !documentTypes[contentType] {
+		response.UnprocessableEntity(w, "file must be a PDF, JPEG, PNG or WebP")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		response.InternalError(w, "failed to read file")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	document := &model.StudentDocument{
+		ID:          uuid.New(),
+		StudentID:   student.ID,
+		Kind:        kind,
+		Filename:    documentFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		UploadedBy:  &userID,
+	}
+	document.StorageKey = "students/" + student.ID.String() + "/" + document.ID.String()
+
+	if err := h.storage.Put(r.Context(), document.StorageKey, file, document.Size, contentType); err != nil {
+		h.logger.Error("failed to store document", slog.String("key", document.StorageKey), slog.String("error", err.Error()))
+		response.InternalError(w, "failed to store document")
+		return
+	}
+
+	if err := h.profileRepo.CreateDocument(r.Context(), document); err != nil {
+		h.deleteFile(r, document.StorageKey)
+		response.InternalError(w, "failed to save document")
+		return
+	}
+
+	response.Created(w, document)
+}
+
+// GET /api/v1/documents/:id
+// Sends the file itself
+func (h *ProfileHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	document, ok := h.loadDocument(w, r)
+	if !ok {
+		return
+	}
+
+	file, err := h.storage.Get(r.Context(), document.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			response.NotFound(w, "document file not found")
+			return
+		}
+		h.logger.Error("failed to read document", slog.String("key", document.StorageKey), slog.String("error", err.Error()))
+		response.InternalError(w, "failed to read document")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(document.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// DELETE /api/v1/documents/:id
+func (h *ProfileHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	document, ok := h.loadDocument(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.profileRepo.DeleteDocument(r.Context(), document.ID); err != nil {
+		response.InternalError(w, "failed to delete document")
+		return
+	}
+	h.deleteFile(r, document.StorageKey)
+
+	response.NoContent(w)
+}
+
+// GET /api/v1/students/:id/consents
+func (h *ProfileHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileRead)
+	if !ok {
+		return
+	}
+
+	consents, err := h.profileRepo.GetConsents(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get consents")
+		return
+	}
+
+	response.OK(w, consents)
+}
+
+// POST /api/v1/students/:id/consents
+func (h *ProfileHandler) CreateConsent(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.loadStudent(w, r, chi.URLParam(r, "id"), profileManage)
+	if !ok {
+		return
+	}
+
+	var req CreateConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	consent := &model.StudentConsent{
+		StudentID:  student.ID,
+		Kind:       req.Kind,
+		SignedBy:   strings.TrimSpace(req.SignedBy),
+		SignedAt:   time.Now(),
+		RecordedBy: &userID,
+	}
+	if req.SignedAt != nil {
+		if req.SignedAt.After(time.Now()) {
+			response.UnprocessableEntity(w, "signed_at must not be in the future")
+			return
+		}
+		consent.SignedAt = *req.SignedAt
+	}
+
+	if consent.DocumentID, ok = h.studentDocument(w, r, student.ID, req.DocumentID); !ok {
+		return
+	}
+
+	if err := h.profileRepo.CreateConsent(r.Context(), consent); err != nil {
+		response.InternalError(w, "failed to save consent")
+		return
+	}
+
+	response.Created(w, consent)
+}
+
+// POST /api/v1/consents/:id/revoke
+func (h *ProfileHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid consent id")
+		return
+	}
+
+	consent, err := h.profileRepo.GetConsent(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "consent not found")
+			return
+		}
+		response.InternalError(w, "failed to get consent")
+		return
+	}
+
+	if _, ok := h.loadStudent(w, r, consent.StudentID.String(), profileManage); !ok {
+		return
+	}
+
+	if consent.RevokedAt != nil {
+		response.Conflict(w, "consent is already revoked")
+		return
+	}
+
+	now := time.Now()
+	if err := h.profileRepo.RevokeConsent(r.Context(), id, now); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Conflict(w, "consent is already revoked")
+			return
+		}
+		response.InternalError(w, "failed to revoke consent")
+		return
+	}
+	consent.RevokedAt = &now
+
+	response.OK(w, consent)
+}
+
+// loadStudent fetches a student, writing the error response and returning
+// false unless the caller has at least the given access to their profile
+func (h *ProfileHandler) loadStudent(w http.ResponseWriter, r *http.Request, idStr string, need profileAccess) (*model.Student, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid student id")
+		return nil, false
+	}
+
+	student, err := h.studentRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get student")
+		return nil, false
+	}
+
+	access, err := h.access(r, student)
+	if err != nil {
+		response.InternalError(w, "failed to verify access")
+		return nil, false
+	}
+	if access < need {
+		response.Forbidden(w, "you don't have permission to access this student's profile")
+		return nil, false
+	}
+	return student, true
+}
+
+func (h *ProfileHandler) access(r *http.Request, student *model.Student) (profileAccess, error) {
+	userID := middleware.GetUserID(r.Context())
+
+	club, err := h.clubRepo.GetByID(r.Context(), student.ClubID)
+	if err != nil {
+		return 0, err
+	}
+	if club.OwnerUserID == userID {
+		return profileManage, nil
+	}
+
+	coaches, err := h.profileRepo.CoachesStudent(r.Context(), userID, student.ID)
+	if err != nil || !coaches {
+		return 0, err
+	}
+	return profileRead, nil
+}
+
+// loadDocument fetches the document in the URL for the club owner
+func (h *ProfileHandler) loadDocument(w http.ResponseWriter, r *http.Request) (*model.StudentDocument, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid document id")
+		return nil, false
+	}
+
+	document, err := h.profileRepo.GetDocument(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "document not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get document")
+		return nil, false
+	}
+
+	if _, ok := h.loadStudent(w, r, document.StudentID.String(), profileManage); !ok {
+		return nil, false
+	}
+	return document, true
+}
+
+// studentDocument resolves an optional document reference, which must be
+// one of the student's documents
+func (h *ProfileHandler) studentDocument(w http.ResponseWriter, r *http.Request, studentID uuid.UUID, idStr string) (*uuid.UUID, bool) {
+	if idStr == "" {
+		return nil, true
+	}
+
+	id, _ := uuid.Parse(idStr)
+	document, err := h.profileRepo.GetDocument(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && document.StudentID != studentID) {
+		response.UnprocessableEntity(w, "document_id is not a document of this student")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(w, "failed to get document")
+		return nil, false
+	}
+	return &id, true
+}
+
+// deleteFile removes a stored file. A failure only leaves an orphaned
+// file behind, so it is logged rather than reported.
+func (h *ProfileHandler) deleteFile(r *http.Request, key string) {
+	if err := h.storage.Delete(r.Context(), key); err != nil {
+		h.logger.Error("failed to delete document file", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
+
+// documentFilename keeps the base name of an uploaded file
+func documentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "document"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[len(runes)-200:])
+	}
+	return name
+}
@@ -0,0 +1,802 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+// defaultMaxScore is the top score of a skill created without one
+const defaultMaxScore = 10
+
+// ProgressHandler manages the levels and skills of groups, coach
+// assessments, grading exams and the progress of students. The club owner
+// and the group's coach manage a group's progress; coaches of a student's
+// groups see their progress.
+type ProgressHandler struct {
+	progressRepo *repository.ProgressRepository
+	studentRepo  *repository.StudentRepository
+	groupRepo    *repository.GroupRepository
+	clubRepo     *repository.ClubRepository
+	profileRepo  *repository.ProfileRepository
+	validator    *validator.Validator
+}
+
+func NewProgressHandler(
+	progressRepo *repository.ProgressRepository,
+	studentRepo *repository.StudentRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	profileRepo *repository.ProfileRepository,
+	validator *validator.Validator,
+) *ProgressHandler {
+	return &ProgressHandler{
+		progressRepo: progressRepo,
+		studentRepo:  studentRepo,
+		groupRepo:    groupRepo,
+		clubRepo:     clubRepo,
+		profileRepo:  profileRepo,
+		validator:    validator,
+	}
+}
+
+// ==================== Levels ====================
+
+// GET /api/v1/groups/:group_id/levels
+func (h *ProgressHandler) ListLevels(w http.ResponseWriter, r *http.Request) {
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	levels, err := h.progressRepo.GetLevels(r.Context(), group.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get levels")
+		return
+	}
+
+	response.OK(w, levels)
+}
+
+// POST /api/v1/groups/:group_id/levels
+func (h *ProgressHandler) CreateLevel(w http.ResponseWriter, r *http.Request) {
+	var req ProgressLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	level := &model.ProgressLevel{
+		GroupID:     group.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Position:    req.Position,
+		Description: req.Description,
+	}
+
+	if err := h.progressRepo.CreateLevel(r.Context(), level); err != nil {
+		if repository.IsDuplicateProgressName(err) {
+			response.Conflict(w, "the group already has a level with this name")
+			return
+		}
+		response.InternalError(w, "failed to create level")
+		return
+	}
+
+	response.Created(w, level)
+}
+
+// PUT /api/v1/levels/:id
+func (h *ProgressHandler) UpdateLevel(w http.ResponseWriter, r *http.Request) {
+	var req ProgressLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	level, _, ok := h.loadLevel(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	level.Name = strings.TrimSpace(req.Name)
+	level.Description = req.Description
+	if req.Position > 0 {
+		level.Position = req.Position
+	}
+
+	if err := h.progressRepo.UpdateLevel(r.Context(), level); err != nil {
+		if repository.IsDuplicateProgressName(err) {
+			response.Conflict(w, "the group already has a level with this name")
+			return
+		}
+		response.InternalError(w, "failed to update level")
+		return
+	}
+
+	response.OK(w, level)
+}
+
+// DELETE /api/v1/levels/:id
+// Also removes the grading results for the level and the awards of it
+func (h *ProgressHandler) DeleteLevel(w http.ResponseWriter, r *http.Request) {
+	level, _, ok := h.loadLevel(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	if err := h.progressRepo.DeleteLevel(r.Context(), level.ID); err != nil {
+		response.InternalError(w, "failed to delete level")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Skills ====================
+
+// GET /api/v1/groups/:group_id/skills
+func (h *ProgressHandler) ListSkills(w http.ResponseWriter, r *http.Request) {
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	skills, err := h.progressRepo.GetSkills(r.Context(), group.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get skills")
+		return
+	}
+
+	response.OK(w, skills)
+}
+
+// POST /api/v1/groups/:group_id/skills
+func (h *ProgressHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
+	var req SkillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	skill := &model.Skill{
+		GroupID:     group.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		MaxScore:    req.MaxScore,
+	}
+	if skill.MaxScore == 0 {
+		skill.MaxScore = defaultMaxScore
+	}
+
+	if err := h.progressRepo.CreateSkill(r.Context(), skill); err != nil {
+		if repository.IsDuplicateProgressName(err) {
+			response.Conflict(w, "the group already has a skill with this name")
+			return
+		}
+		response.InternalError(w, "failed to create skill")
+		return
+	}
+
+	response.Created(w, skill)
+}
+
+// PUT /api/v1/skills/:id
+// Earlier scores are kept as they were given, also above a lowered
+// max_score
+func (h *ProgressHandler) UpdateSkill(w http.ResponseWriter, r *http.Request) {
+	var req SkillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	skill, _, ok := h.loadSkill(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	skill.Name = strings.TrimSpace(req.Name)
+	skill.Description = req.Description
+	if req.MaxScore > 0 {
+		skill.MaxScore = req.MaxScore
+	}
+
+	if err := h.progressRepo.UpdateSkill(r.Context(), skill); err != nil {
+		if repository.IsDuplicateProgressName(err) {
+			response.Conflict(w, "the group already has a skill with this name")
+			return
+		}
+		response.InternalError(w, "failed to update skill")
+		return
+	}
+
+	response.OK(w, skill)
+}
+
+// DELETE /api/v1/skills/:id
+// Also removes the assessments of the skill
+func (h *ProgressHandler) DeleteSkill(w http.ResponseWriter, r *http.Request) {
+	skill, _, ok := h.loadSkill(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	if err := h.progressRepo.DeleteSkill(r.Context(), skill.ID); err != nil {
+		response.InternalError(w, "failed to delete skill")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Assessments ====================
+
+// POST /api/v1/students/:id/assessments
+func (h *ProgressHandler) CreateAssessment(w http.ResponseWriter, r *http.Request) {
+	var req CreateAssessmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	student, ok := h.getStudent(w, r)
+	if !ok {
+		return
+	}
+
+	skill, club, ok := h.loadSkill(w, r, req.SkillID)
+	if !ok {
+		return
+	}
+	if club.ID != student.ClubID {
+		response.UnprocessableEntity(w, "skill_id is not a skill of the student's club")
+		return
+	}
+
+	if req.Score > skill.MaxScore {
+		response.UnprocessableEntity(w, fmt.Sprintf("score must be at most %d", skill.MaxScore))
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	assessment := &model.SkillAssessment{
+		StudentID:  student.ID,
+		SkillID:    skill.ID,
+		Score:      req.Score,
+		Notes:      req.Notes,
+		AssessedOn: progressDay(req.AssessedOn, club),
+		AssessedBy: &userID,
+	}
+
+	if err := h.progressRepo.CreateAssessment(r.Context(), assessment); err != nil {
+		response.InternalError(w, "failed to create assessment")
+		return
+	}
+
+	response.Created(w, assessment)
+}
+
+// DELETE /api/v1/assessments/:id
+func (h *ProgressHandler) DeleteAssessment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid assessment id")
+		return
+	}
+
+	assessment, err := h.progressRepo.GetAssessment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "assessment not found")
+			return
+		}
+		response.InternalError(w, "failed to get assessment")
+		return
+	}
+
+	if _, _, ok := h.loadSkill(w, r, assessment.SkillID.String()); !ok {
+		return
+	}
+
+	if err := h.progressRepo.DeleteAssessment(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to delete assessment")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Gradings ====================
+
+// GET /api/v1/groups/:group_id/gradings
+func (h *ProgressHandler) ListGradings(w http.ResponseWriter, r *http.Request) {
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	gradings, err := h.progressRepo.GetGradings(r.Context(), group.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get gradings")
+		return
+	}
+
+	response.OK(w, gradings)
+}
+
+// POST /api/v1/groups/:group_id/gradings
+func (h *ProgressHandler) CreateGrading(w http.ResponseWriter, r *http.Request) {
+	var req CreateGradingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	group, _, ok := h.loadGroup(w, r, chi.URLParam(r, "group_id"))
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	grading := &model.Grading{
+		GroupID:   group.ID,
+		Title:     strings.TrimSpace(req.Title),
+		Notes:     req.Notes,
+		CreatedBy: &userID,
+	}
+	grading.HeldOn, _ = time.Parse("2006-01-02", req.HeldOn)
+
+	if err := h.progressRepo.CreateGrading(r.Context(), grading); err != nil {
+		response.InternalError(w, "failed to create grading")
+		return
+	}
+
+	response.Created(w, grading)
+}
+
+// GET /api/v1/gradings/:id
+func (h *ProgressHandler) GetGrading(w http.ResponseWriter, r *http.Request) {
+	grading, ok := h.loadGrading(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, grading)
+}
+
+// PUT /api/v1/gradings/:id/results
+// Replaces every result of the grading. Passing awards the level tried
+// for on the day of the grading.
+func (h *ProgressHandler) SaveResults(w http.ResponseWriter, r *http.Request) {
+	var req GradingResultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	grading, ok := h.loadGrading(w, r)
+	if !ok {
+		return
+	}
+	group, err := h.groupRepo.GetByID(r.Context(), grading.GroupID)
+	if err != nil {
+		response.InternalError(w, "failed to get group")
+		return
+	}
+
+	levels, err := h.progressRepo.GetLevels(r.Context(), grading.GroupID)
+	if err != nil {
+		response.InternalError(w, "failed to get levels")
+		return
+	}
+	groupLevels := make(map[uuid.UUID]bool, len(levels))
+	for _, l := range levels {
+		groupLevels[l.ID] = true
+	}
+
+	results := make([]model.GradingResult, 0, len(req.Results))
+	studentIDs := make([]uuid.UUID, 0, len(req.Results))
+	seen := make(map[uuid.UUID]bool, len(req.Results))
+	for _, item := range req.Results {
+		studentID, _ := uuid.Parse(item.StudentID)
+		levelID, _ := uuid.Parse(item.LevelID)
+		if seen[studentID] {
+			response.UnprocessableEntity(w, fmt.Sprintf("student %s is listed more than once", studentID))
+			return
+		}
+		seen[studentID] = true
+		if !groupLevels[levelID] {
+			response.UnprocessableEntity(w, fmt.Sprintf("level %s is not a level of this group", levelID))
+			return
+		}
+
+		results = append(results, model.GradingResult{
+			GradingID: grading.ID,
+			StudentID: studentID,
+			LevelID:   levelID,
+			Passed:    item.Passed,
+			Notes:     item.Notes,
+		})
+		studentIDs = append(studentIDs, studentID)
+	}
+
+	missing, err := h.progressRepo.MissingStudents(r.Context(), group.ClubID, studentIDs)
+	if err != nil {
+		response.InternalError(w, "failed to verify students")
+		return
+	}
+	if len(missing) > 0 {
+		response.UnprocessableEntity(w, fmt.Sprintf("student %s is not a student of this club", missing[0]))
+		return
+	}
+
+	if err := h.progressRepo.SaveResults(r.Context(), grading, results, middleware.GetUserID(r.Context())); err != nil {
+		response.InternalError(w, "failed to save grading results")
+		return
+	}
+
+	grading, err = h.progressRepo.GetGrading(r.Context(), grading.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get grading")
+		return
+	}
+
+	response.OK(w, grading)
+}
+
+// DELETE /api/v1/gradings/:id
+// Also takes back the levels the grading awarded
+func (h *ProgressHandler) DeleteGrading(w http.ResponseWriter, r *http.Request) {
+	grading, ok := h.loadGrading(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.progressRepo.DeleteGrading(r.Context(), grading.ID); err != nil {
+		response.InternalError(w, "failed to delete grading")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Progress of students ====================
+
+// StudentProgressResponse is a student's current levels, latest skill
+// scores and the timeline of their progress
+type StudentProgressResponse struct {
+	StudentID uuid.UUID             `json:"student_id"`
+	Levels    []model.StudentLevel  `json:"levels"`
+	Skills    []model.SkillScore    `json:"skills"`
+	Timeline  []model.ProgressEvent `json:"timeline"`
+}
+
+// GET /api/v1/students/:id/progress
+func (h *ProgressHandler) GetStudentProgress(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.getStudent(w, r)
+	if !ok {
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), student.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify access")
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID {
+		coaches, err := h.profileRepo.CoachesStudent(r.Context(), userID, student.ID)
+		if err != nil {
+			response.InternalError(w, "failed to verify access")
+			return
+		}
+		if !coaches {
+			response.Forbidden(w, "you don't have permission to view this student's progress")
+			return
+		}
+	}
+
+	levels, err := h.progressRepo.GetStudentLevels(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get levels")
+		return
+	}
+
+	skills, err := h.progressRepo.GetSkillScores(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get skill scores")
+		return
+	}
+
+	timeline, err := h.progressRepo.GetTimeline(r.Context(), student.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get progress timeline")
+		return
+	}
+
+	response.OK(w, StudentProgressResponse{
+		StudentID: student.ID,
+		Levels:    levels,
+		Skills:    skills,
+		Timeline:  timeline,
+	})
+}
+
+// POST /api/v1/students/:id/levels
+// Gives the student a level without a grading, e.g. one earned elsewhere
+func (h *ProgressHandler) AwardLevel(w http.ResponseWriter, r *http.Request) {
+	var req AwardLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	student, ok := h.getStudent(w, r)
+	if !ok {
+		return
+	}
+
+	level, club, ok := h.loadLevel(w, r, req.LevelID)
+	if !ok {
+		return
+	}
+	if club.ID != student.ClubID {
+		response.UnprocessableEntity(w, "level_id is not a level of the student's club")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	award := &model.LevelAward{
+		StudentID: student.ID,
+		LevelID:   level.ID,
+		AwardedOn: progressDay(req.AwardedOn, club),
+		Notes:     req.Notes,
+		AwardedBy: &userID,
+	}
+
+	if err := h.progressRepo.CreateAward(r.Context(), award); err != nil {
+		response.InternalError(w, "failed to award level")
+		return
+	}
+
+	response.Created(w, award)
+}
+
+// DELETE /api/v1/level-awards/:id
+// Levels awarded by a grading change with its results instead
+func (h *ProgressHandler) DeleteAward(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid level award id")
+		return
+	}
+
+	award, err := h.progressRepo.GetAward(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "level award not found")
+			return
+		}
+		response.InternalError(w, "failed to get level award")
+		return
+	}
+
+	if _, _, ok := h.loadLevel(w, r, award.LevelID.String()); !ok {
+		return
+	}
+
+	if award.GradingID != nil {
+		response.Conflict(w, "the level was awarded by a grading; change its results instead")
+		return
+	}
+
+	if err := h.progressRepo.DeleteAward(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to delete level award")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ==================== Helpers ====================
+
+// loadGroup fetches a group, writing the error response and returning
+// false unless the caller owns its club or coaches it
+func (h *ProgressHandler) loadGroup(w http.ResponseWriter, r *http.Request, idStr string) (*model.Group, *model.Club, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid group_id")
+		return nil, nil, false
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get group")
+		return nil, nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return nil, nil, false
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
+		response.Forbidden(w, "you don't have permission to manage progress in this group")
+		return nil, nil, false
+	}
+	return group, club, true
+}
+
+// loadLevel fetches a level with its club for the owner or coach of its
+// group
+func (h *ProgressHandler) loadLevel(w http.ResponseWriter, r *http.Request, idStr string) (*model.ProgressLevel, *model.Club, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid level id")
+		return nil, nil, false
+	}
+
+	level, err := h.progressRepo.GetLevel(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "level not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get level")
+		return nil, nil, false
+	}
+
+	_, club, ok := h.loadGroup(w, r, level.GroupID.String())
+	if !ok {
+		return nil, nil, false
+	}
+	return level, club, true
+}
+
+// loadSkill fetches a skill with its club for the owner or coach of its
+// group
+func (h *ProgressHandler) loadSkill(w http.ResponseWriter, r *http.Request, idStr string) (*model.Skill, *model.Club, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid skill id")
+		return nil, nil, false
+	}
+
+	skill, err := h.progressRepo.GetSkill(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "skill not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get skill")
+		return nil, nil, false
+	}
+
+	_, club, ok := h.loadGroup(w, r, skill.GroupID.String())
+	if !ok {
+		return nil, nil, false
+	}
+	return skill, club, true
+}
+
+// loadGrading fetches the grading in the URL with its results for the
+// owner or coach of its group
+func (h *ProgressHandler) loadGrading(w http.ResponseWriter, r *http.Request) (*model.Grading, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid grading id")
+		return nil, false
+	}
+
+	grading, err := h.progressRepo.GetGrading(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "grading not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get grading")
+		return nil, false
+	}
+
+	if _, _, ok := h.loadGroup(w, r, grading.GroupID.String()); !ok {
+		return nil, false
+	}
+	return grading, true
+}
+
+// getStudent fetches the student in the URL. Access is checked against the
+// group of the skill or level recorded for them.
+func (h *ProgressHandler) getStudent(w http.ResponseWriter, r *http.Request) (*model.Student, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid student id")
+		return nil, false
+	}
+
+	student, err := h.studentRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get student")
+		return nil, false
+	}
+	return student, true
+}
+
+// progressDay parses a date of the request, defaulting to the club's today
+func progressDay(s string, club *model.Club) time.Time {
+	if day, err := time.Parse("2006-01-02", s); err == nil {
+		return day
+	}
+	now := time.Now().In(club.Location())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
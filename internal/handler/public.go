@@ -7,32 +7,37 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
 	"github.com/neo/trainer-plus/pkg/response"
 )
 
 type PublicHandler struct {
-	clubRepo    *repository.ClubRepository
-	groupRepo   *repository.GroupRepository
-	sessionRepo *repository.SessionRepository
+	clubRepo     *repository.ClubRepository
+	groupRepo    *repository.GroupRepository
+	sessionRepo  *repository.SessionRepository
+	locationRepo *repository.LocationRepository
 }
 
 func NewPublicHandler(
 	clubRepo *repository.ClubRepository,
 	groupRepo *repository.GroupRepository,
 	sessionRepo *repository.SessionRepository,
+	locationRepo *repository.LocationRepository,
 ) *PublicHandler {
 	return &PublicHandler{
-		clubRepo:    clubRepo,
-		groupRepo:   groupRepo,
-		sessionRepo: sessionRepo,
+		clubRepo:     clubRepo,
+		groupRepo:    groupRepo,
+		sessionRepo:  sessionRepo,
+		locationRepo: locationRepo,
 	}
 }
 
 // PublicScheduleResponse represents the public schedule data
 type PublicScheduleResponse struct {
-	Club     PublicClubInfo     `json:"club"`
-	Groups   []PublicGroupInfo  `json:"groups"`
-	Sessions []PublicSessionInfo `json:"sessions"`
+	Club      PublicClubInfo       `json:"club"`
+	Groups    []PublicGroupInfo    `json:"groups"`
+	Sessions  []PublicSessionInfo  `json:"sessions"`
+	Locations []PublicLocationInfo `json:"locations"`
 }
 
 type PublicClubInfo struct {
@@ -41,6 +46,7 @@ type PublicClubInfo struct {
 	Address  string    `json:"address,omitempty"`
 	Phone    string    `json:"phone,omitempty"`
 	Currency string    `json:"currency"`
+	Timezone string    `json:"timezone"`
 }
 
 type PublicGroupInfo struct {
@@ -52,13 +58,23 @@ type PublicGroupInfo struct {
 	Description string    `json:"description,omitempty"`
 }
 
+type PublicLocationInfo struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Address  string    `json:"address,omitempty"`
+	Capacity int       `json:"capacity,omitempty"`
+}
+
 type PublicSessionInfo struct {
-	ID              uuid.UUID `json:"id"`
-	GroupID         uuid.UUID `json:"group_id"`
-	GroupTitle      string    `json:"group_title"`
-	StartAt         time.Time `json:"start_at"`
-	DurationMinutes int       `json:"duration_minutes"`
-	Location        string    `json:"location,omitempty"`
+	ID              uuid.UUID  `json:"id"`
+	GroupID         uuid.UUID  `json:"group_id"`
+	GroupTitle      string     `json:"group_title"`
+	StartAt         time.Time  `json:"start_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Location        string     `json:"location,omitempty"`
+	LocationID      *uuid.UUID `json:"location_id,omitempty"`
+	Status          string     `json:"status"`
+	CancelReason    string     `json:"cancel_reason,omitempty"`
 }
 
 // GET /public/club/:id/schedule
@@ -72,31 +88,42 @@ func (h *PublicHandler) Schedule(w http.ResponseWriter, r *http.Request) {
 
 	// Get club
 	club, err := h.clubRepo.GetByID(r.Context(), clubID)
-	if err != nil {
+	if err != nil || club.ArchivedAt != nil {
 		response.NotFound(w, "club not found")
 		return
 	}
 
 	// Get groups
-	groups, err := h.groupRepo.GetByClub(r.Context(), clubID)
+	groups, err := h.groupRepo.GetByClub(r.Context(), clubID, repository.ActiveOnly, repository.AttributeFilter{})
 	if err != nil {
 		response.InternalError(w, "failed to get groups")
 		return
 	}
 
-	// Parse date range from query (default: next 30 days)
+	// Parse date range from query (default: next 30 days).
+	// Dates are calendar days in the club's time zone.
+	loc := club.Location()
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
 
 	var from, to time.Time
 	if fromStr != "" {
-		from, _ = time.Parse("2006-01-02", fromStr)
+		from, err = schedule.ParseDate(fromStr, loc)
+		if err != nil {
+			response.BadRequest(w, "invalid from date format, use YYYY-MM-DD")
+			return
+		}
 	} else {
 		from = time.Now()
 	}
 
 	if toStr != "" {
-		to, _ = time.Parse("2006-01-02", toStr)
+		to, err = schedule.ParseDate(toStr, loc)
+		if err != nil {
+			response.BadRequest(w, "invalid to date format, use YYYY-MM-DD")
+			return
+		}
+		to = schedule.EndOfDay(to, loc)
 	} else {
 		to = time.Now().AddDate(0, 0, 30)
 	}
@@ -123,16 +150,42 @@ func (h *PublicHandler) Schedule(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Build session response
+	// Build session response (start times rendered with the club's offset)
 	publicSessions := make([]PublicSessionInfo, len(sessions))
 	for i, s := range sessions {
 		publicSessions[i] = PublicSessionInfo{
 			ID:              s.ID,
 			GroupID:         s.GroupID,
 			GroupTitle:      groupMap[s.GroupID],
-			StartAt:         s.StartAt,
+			StartAt:         s.StartAt.In(loc),
 			DurationMinutes: s.DurationMinutes,
 			Location:        s.Location,
+			LocationID:      s.LocationID,
+			Status:          s.Status,
+		}
+		// Cancelled sessions stay listed so parents see why
+		if s.CancelReason != nil {
+			publicSessions[i].CancelReason = *s.CancelReason
+		}
+	}
+
+	locations, err := h.locationRepo.GetByClub(r.Context(), clubID)
+	if err != nil {
+		response.InternalError(w, "failed to get locations")
+		return
+	}
+
+	publicLocations := make([]PublicLocationInfo, len(locations))
+	for i, l := range locations {
+		publicLocations[i] = PublicLocationInfo{
+			ID:   l.ID,
+			Name: l.Name,
+		}
+		if l.Address != nil {
+			publicLocations[i].Address = *l.Address
+		}
+		if l.Capacity != nil {
+			publicLocations[i].Capacity = *l.Capacity
 		}
 	}
 
@@ -143,9 +196,11 @@ func (h *PublicHandler) Schedule(w http.ResponseWriter, r *http.Request) {
 			Address:  club.Address,
 			Phone:    club.Phone,
 			Currency: club.Currency,
+			Timezone: loc.String(),
 		},
-		Groups:   publicGroups,
-		Sessions: publicSessions,
+		Groups:    publicGroups,
+		Sessions:  publicSessions,
+		Locations: publicLocations,
 	}
 
 	response.OK(w, resp)
@@ -161,13 +216,13 @@ func (h *PublicHandler) Groups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check club exists
-	_, err = h.clubRepo.GetByID(r.Context(), clubID)
-	if err != nil {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil || club.ArchivedAt != nil {
 		response.NotFound(w, "club not found")
 		return
 	}
 
-	groups, err := h.groupRepo.GetByClub(r.Context(), clubID)
+	groups, err := h.groupRepo.GetByClub(r.Context(), clubID, repository.ActiveOnly, repository.AttributeFilter{})
 	if err != nil {
 		response.InternalError(w, "failed to get groups")
 		return
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type ReminderHandler struct {
+	reminderRepo *repository.ReminderRepository
+	clubRepo     *repository.ClubRepository
+	validator    *validator.Validator
+}
+
+func NewReminderHandler(reminderRepo *repository.ReminderRepository, clubRepo *repository.ClubRepository, v *validator.Validator) *ReminderHandler {
+	return &ReminderHandler{
+		reminderRepo: reminderRepo,
+		clubRepo:     clubRepo,
+		validator:    v,
+	}
+}
+
+// ReminderConfig is a club's reminder settings with one rule per kind
+type ReminderConfig struct {
+	QuietHoursStart *string              `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string              `json:"quiet_hours_end,omitempty"`
+	Channels        []string             `json:"channels"`
+	Rules           []model.ReminderRule `json:"rules"`
+}
+
+// GET /api/v1/clubs/:club_id/reminders
+// Kinds the club has not configured are listed disabled with the default
+// threshold.
+func (h *ReminderHandler) Get(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	config, err := h.load(r, clubID)
+	if err != nil {
+		response.InternalError(w, "failed to get reminders")
+		return
+	}
+
+	response.OK(w, config)
+}
+
+// PUT /api/v1/clubs/:club_id/reminders
+// Replaces the settings and rules; rules left out are removed.
+func (h *ReminderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	var req UpdateRemindersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	if (req.QuietHoursStart == nil) != (req.QuietHoursEnd == nil) {
+		response.UnprocessableEntity(w, "quiet_hours_start and quiet_hours_end must be set together")
+		return
+	}
+
+	if !h.verifyOwner(w, r, clubID) {
+		return
+	}
+
+	settings := &model.ReminderSettings{
+		ClubID:          clubID,
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		Channels:        req.Channels,
+	}
+
+	rules := make([]model.ReminderRule, 0, len(req.Rules))
+	seen := make(map[string]bool, len(req.Rules))
+	for _, rr := range req.Rules {
+		if seen[rr.Kind] {
+			response.UnprocessableEntity(w, "duplicate rule kind "+rr.Kind)
+			return
+		}
+		seen[rr.Kind] = true
+
+		threshold := model.ReminderKind(rr.Kind).DefaultThreshold()
+		if rr.Threshold != nil {
+			threshold = *rr.Threshold
+		}
+		rules = append(rules, model.ReminderRule{
+			Kind:      rr.Kind,
+			Enabled:   rr.Enabled,
+			Threshold: threshold,
+			Channels:  rr.Channels,
+		})
+	}
+
+	if err := h.reminderRepo.Save(r.Context(), settings, rules); err != nil {
+		response.InternalError(w, "failed to save reminders")
+		return
+	}
+
+	config, err := h.load(r, clubID)
+	if err != nil {
+		response.InternalError(w, "failed to get reminders")
+		return
+	}
+
+	response.OK(w, config)
+}
+
+func (h *ReminderHandler) load(r *http.Request, clubID uuid.UUID) (*ReminderConfig, error) {
+	config := &ReminderConfig{Channels: []string{}}
+
+	settings, err := h.reminderRepo.GetSettings(r.Context(), clubID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		config.QuietHoursStart = settings.QuietHoursStart
+		config.QuietHoursEnd = settings.QuietHoursEnd
+		config.Channels = settings.Channels
+	}
+
+	rules, err := h.reminderRepo.GetRules(r.Context(), clubID)
+	if err != nil {
+		return nil, err
+	}
+	byKind := make(map[string]model.ReminderRule, len(rules))
+	for _, rule := range rules {
+		byKind[rule.Kind] = rule
+	}
+
+	for _, kind := range model.ReminderKinds {
+		rule, ok := byKind[string(kind)]
+		if !ok {
+			rule = model.ReminderRule{
+				ClubID:    clubID,
+				Kind:      string(kind),
+				Threshold: kind.DefaultThreshold(),
+				Channels:  []string{},
+			}
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return config, nil
+}
+
+// verifyOwner writes the error response and returns false unless the
+// caller owns the club
+func (h *ReminderHandler) verifyOwner(w http.ResponseWriter, r *http.Request, clubID uuid.UUID) bool {
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return false
+		}
+		response.InternalError(w, "failed to verify club")
+		return false
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to manage this club's reminders")
+		return false
+	}
+	return true
+}
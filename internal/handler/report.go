@@ -1,17 +1,23 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
 	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
 	"github.com/neo/trainer-plus/pkg/response"
 )
 
+var errForbidden = errors.New("forbidden")
+
 type ReportHandler struct {
 	reportRepo *repository.ReportRepository
 	clubRepo   *repository.ClubRepository
@@ -26,78 +32,157 @@ func NewReportHandler(reportRepo *repository.ReportRepository, clubRepo *reposit
 
 // GET /api/v1/clubs/:club_id/reports/finance
 func (h *ReportHandler) Finance(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
-	from, to := h.parseDateRange(r)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
 
-	report, err := h.reportRepo.GetFinanceReport(r.Context(), clubID, from, to)
+	from, to := parseDateRange(r, club.Location())
+
+	report, err := h.reportRepo.GetFinanceReport(r.Context(), club.ID, from, to)
 	if err != nil {
 		response.InternalError(w, "failed to generate finance report")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "finance", Title: "report.finance", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportFinance(d, report)
+		})
+		return
+	}
+
 	response.OK(w, report)
 }
 
 // GET /api/v1/clubs/:club_id/reports/occupancy
 func (h *ReportHandler) Occupancy(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
-	from, to := h.parseDateRange(r)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to := parseDateRange(r, club.Location())
 
-	report, err := h.reportRepo.GetOccupancyReport(r.Context(), clubID, from, to)
+	report, err := h.reportRepo.GetOccupancyReport(r.Context(), club.ID, from, to)
 	if err != nil {
 		response.InternalError(w, "failed to generate occupancy report")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "occupancy", Title: "report.occupancy", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportOccupancy(d, report)
+		})
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// GET /api/v1/clubs/:club_id/reports/utilisation
+func (h *ReportHandler) Utilisation(w http.ResponseWriter, r *http.Request) {
+	club, err := h.parseAndVerifyClubAccess(w, r)
+	if err != nil {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to := parseDateRange(r, club.Location())
+
+	report, err := h.reportRepo.GetUtilisationReport(r.Context(), club.ID, from, to)
+	if err != nil {
+		response.InternalError(w, "failed to generate utilisation report")
+		return
+	}
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "utilisation", Title: "report.utilisation", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportUtilisation(d, report)
+		})
+		return
+	}
+
 	response.OK(w, report)
 }
 
 // GET /api/v1/clubs/:club_id/reports/mrr
 func (h *ReportHandler) MRR(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
-	// Parse month (default: current month)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse month (default: current month in the club's time zone)
+	loc := club.Location()
 	monthStr := r.URL.Query().Get("month")
 	var month time.Time
 	if monthStr != "" {
-		month, err = time.Parse("2006-01", monthStr)
+		month, err = time.ParseInLocation("2006-01", monthStr, loc)
 		if err != nil {
 			response.BadRequest(w, "invalid month format, use YYYY-MM")
 			return
 		}
 	} else {
-		now := time.Now()
-		month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		month = schedule.StartOfMonth(time.Now(), loc)
 	}
 
-	report, err := h.reportRepo.GetMRRReport(r.Context(), clubID, month)
+	report, err := h.reportRepo.GetMRRReport(r.Context(), club.ID, month)
 	if err != nil {
 		response.InternalError(w, "failed to generate MRR report")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "mrr", Title: "report.mrr", Subtitle: report.Month}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportMRR(d, report)
+		})
+		return
+	}
+
 	response.OK(w, report)
 }
 
 // GET /api/v1/clubs/:club_id/reports/students
 func (h *ReportHandler) Students(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
-	from, to := h.parseDateRange(r)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to := parseDateRange(r, club.Location())
 
 	// Parse limit for top students
 	limit := 10
@@ -107,22 +192,36 @@ func (h *ReportHandler) Students(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	report, err := h.reportRepo.GetStudentsReport(r.Context(), clubID, from, to, limit)
+	report, err := h.reportRepo.GetStudentsReport(r.Context(), club.ID, from, to, limit)
 	if err != nil {
 		response.InternalError(w, "failed to generate students report")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "students", Title: "report.students", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportStudentsReport(d, report)
+		})
+		return
+	}
+
 	response.OK(w, report)
 }
 
 // GET /api/v1/clubs/:club_id/reports/debt
 func (h *ReportHandler) Debt(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
 	// Parse days threshold (default: 7 days)
 	days := 7
 	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
@@ -131,77 +230,269 @@ func (h *ReportHandler) Debt(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	report, err := h.reportRepo.GetDebtReport(r.Context(), clubID, days)
+	report, err := h.reportRepo.GetDebtReport(r.Context(), club.ID, days)
 	if err != nil {
 		response.InternalError(w, "failed to generate debt report")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "debt", Title: "report.debt", Subtitle: locale.Date(time.Now())}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportDebt(d, report)
+		})
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// GET /api/v1/clubs/:club_id/reports/trials
+func (h *ReportHandler) Trials(w http.ResponseWriter, r *http.Request) {
+	club, err := h.parseAndVerifyClubAccess(w, r)
+	if err != nil {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to := parseDateRange(r, club.Location())
+
+	report, err := h.reportRepo.GetTrialReport(r.Context(), club.ID, from, to)
+	if err != nil {
+		response.InternalError(w, "failed to generate trial report")
+		return
+	}
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "trials", Title: "report.trials", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportTrials(d, report)
+		})
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// GET /api/v1/clubs/:club_id/reports/levels
+func (h *ReportHandler) Levels(w http.ResponseWriter, r *http.Request) {
+	club, err := h.parseAndVerifyClubAccess(w, r)
+	if err != nil {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportRepo.GetLevelReport(r.Context(), club.ID)
+	if err != nil {
+		response.InternalError(w, "failed to generate level report")
+		return
+	}
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "levels", Title: "report.levels", Subtitle: locale.Date(time.Now())}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportLevels(d, report)
+		})
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// GET /api/v1/clubs/:club_id/reports/retention?from=2024-01&to=2024-06&days=14
+func (h *ReportHandler) Retention(w http.ResponseWriter, r *http.Request) {
+	club, err := h.parseAndVerifyClubAccess(w, r)
+	if err != nil {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseMonthRange(w, r, club.Location())
+	if !ok {
+		return
+	}
+
+	// Parse renewal window (default: repository.DefaultRenewalDays)
+	days := repository.DefaultRenewalDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	report, err := h.reportRepo.GetRetentionReport(r.Context(), club.ID, from, to, days)
+	if err != nil {
+		response.InternalError(w, "failed to generate retention report")
+		return
+	}
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "retention", Title: "report.retention", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportRetention(d, report)
+		})
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// GET /api/v1/clubs/:club_id/reports/revenue?from=2024-01&to=2024-06
+func (h *ReportHandler) Revenue(w http.ResponseWriter, r *http.Request) {
+	club, err := h.parseAndVerifyClubAccess(w, r)
+	if err != nil {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseMonthRange(w, r, club.Location())
+	if !ok {
+		return
+	}
+
+	report, err := h.reportRepo.GetRevenueReport(r.Context(), club.ID, from, to)
+	if err != nil {
+		response.InternalError(w, "failed to generate revenue report")
+		return
+	}
+
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "revenue", Title: "report.revenue", Subtitle: locale.Period(from, to)}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportRevenue(d, report)
+		})
+		return
+	}
+
 	response.OK(w, report)
 }
 
 // GET /api/v1/clubs/:club_id/dashboard
 func (h *ReportHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
-	clubID, err := h.parseAndVerifyClubAccess(w, r)
+	club, err := h.parseAndVerifyClubAccess(w, r)
 	if err != nil {
 		return
 	}
 
-	stats, err := h.reportRepo.GetDashboardStats(r.Context(), clubID)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.reportRepo.GetDashboardStats(r.Context(), club.ID, club.Location())
 	if err != nil {
 		response.InternalError(w, "failed to get dashboard stats")
 		return
 	}
 
+	if format != export.FormatJSON {
+		locale := export.LocaleFor(r, club)
+		info := export.Info{Name: "dashboard", Title: "report.dashboard", Subtitle: locale.Date(time.Now())}
+		sendExport(w, format, locale, info, func(d *export.Document) error {
+			return exportDashboard(d, stats)
+		})
+		return
+	}
+
 	response.OK(w, stats)
 }
 
 // Helper: parse club_id and verify access
-func (h *ReportHandler) parseAndVerifyClubAccess(w http.ResponseWriter, r *http.Request) (uuid.UUID, error) {
+func (h *ReportHandler) parseAndVerifyClubAccess(w http.ResponseWriter, r *http.Request) (*model.Club, error) {
 	clubIDStr := chi.URLParam(r, "club_id")
 	clubID, err := uuid.Parse(clubIDStr)
 	if err != nil {
 		response.BadRequest(w, "invalid club_id")
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	// Verify club exists and user has access
 	club, err := h.clubRepo.GetByID(r.Context(), clubID)
 	if err != nil {
 		response.NotFound(w, "club not found")
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	userID := middleware.GetUserID(r.Context())
 	if club.OwnerUserID != userID {
 		response.Forbidden(w, "you don't have access to this club's reports")
-		return uuid.Nil, err
+		return nil, errForbidden
 	}
 
-	return clubID, nil
+	return club, nil
 }
 
-// Helper: parse date range from query params
-func (h *ReportHandler) parseDateRange(r *http.Request) (from, to time.Time) {
+// Helper: parse date range from query params.
+// Dates are calendar days in the club's time zone.
+func parseDateRange(r *http.Request, loc *time.Location) (from, to time.Time) {
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
 
 	// Default: last 30 days
-	to = time.Now().UTC()
+	to = time.Now().In(loc)
 	from = to.AddDate(0, 0, -30)
 
 	if fromStr != "" {
-		if t, err := time.Parse("2006-01-02", fromStr); err == nil {
+		if t, err := schedule.ParseDate(fromStr, loc); err == nil {
 			from = t
 		}
 	}
 
 	if toStr != "" {
-		if t, err := time.Parse("2006-01-02", toStr); err == nil {
-			// End of day
-			to = t.Add(24*time.Hour - time.Second)
+		if t, err := schedule.ParseDate(toStr, loc); err == nil {
+			to = schedule.EndOfDay(t, loc)
 		}
 	}
 
 	return from, to
 }
+
+// Helper: parse a range of months from the from and to query params
+// (YYYY-MM), the last 6 months by default. Returns the start of the first
+// month and the end of the last in the club's time zone; on failure the
+// response has already been written.
+func parseMonthRange(w http.ResponseWriter, r *http.Request, loc *time.Location) (from, to time.Time, ok bool) {
+	var err error
+	to = schedule.StartOfMonth(time.Now(), loc)
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = time.ParseInLocation("2006-01", toStr, loc); err != nil {
+			response.BadRequest(w, "invalid to month, use YYYY-MM")
+			return from, to, false
+		}
+	}
+
+	from = to.AddDate(0, -5, 0)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = time.ParseInLocation("2006-01", fromStr, loc); err != nil {
+			response.BadRequest(w, "invalid from month, use YYYY-MM")
+			return from, to, false
+		}
+	}
+
+	if from.After(to) {
+		response.BadRequest(w, "from must not be after to")
+		return from, to, false
+	}
+	return from, to.AddDate(0, 1, 0).Add(-time.Second), true
+}
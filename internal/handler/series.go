@@ -0,0 +1,386 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
+	"github.com/neo/trainer-plus/internal/service"
+	"github.com/neo/trainer-plus/internal/validator"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type SeriesHandler struct {
+	seriesRepo      *repository.SeriesRepository
+	groupRepo       *repository.GroupRepository
+	clubRepo        *repository.ClubRepository
+	locationRepo    *repository.LocationRepository
+	scheduleService *service.ScheduleService
+	validator       *validator.Validator
+}
+
+func NewSeriesHandler(
+	seriesRepo *repository.SeriesRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	locationRepo *repository.LocationRepository,
+	scheduleService *service.ScheduleService,
+	validator *validator.Validator,
+) *SeriesHandler {
+	return &SeriesHandler{
+		seriesRepo:      seriesRepo,
+		groupRepo:       groupRepo,
+		clubRepo:        clubRepo,
+		locationRepo:    locationRepo,
+		scheduleService: scheduleService,
+		validator:       validator,
+	}
+}
+
+// POST /api/v1/groups/:group_id/series[?force=true]
+func (h *SeriesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "group_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid group_id")
+		return
+	}
+
+	var req CreateSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), groupID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to verify group")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	hasPermission, err := h.checkPermission(r, group, userID)
+	if err != nil || !hasPermission {
+		response.Forbidden(w, "you don't have permission to manage this group's schedule")
+		return
+	}
+
+	// Series dates are plain calendar dates
+	startsOn, err := schedule.ParseDate(req.StartsOn, time.UTC)
+	if err != nil {
+		response.BadRequest(w, "invalid starts_on format, use YYYY-MM-DD")
+		return
+	}
+
+	series := &model.ScheduleSeries{
+		GroupID:         groupID,
+		RRule:           req.RRule,
+		StartsOn:        startsOn,
+		StartTime:       req.StartTime,
+		DurationMinutes: req.DurationMinutes,
+	}
+
+	location, err := locationFor(r.Context(), h.locationRepo, group, req.LocationID, req.Location)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+	series.Location, series.LocationID = locationFields(location, req.Location)
+
+	if req.EndsOn != "" {
+		endsOn, err := schedule.ParseDate(req.EndsOn, time.UTC)
+		if err != nil {
+			response.BadRequest(w, "invalid ends_on format, use YYYY-MM-DD")
+			return
+		}
+		if endsOn.Before(startsOn) {
+			response.BadRequest(w, "ends_on must not be before starts_on")
+			return
+		}
+		series.EndsOn = &endsOn
+	}
+
+	if series.ExDates, err = parseExDates(req.ExDates); err != nil {
+		response.BadRequest(w, "invalid exdates format, use YYYY-MM-DD")
+		return
+	}
+
+	conflicts, err := h.scheduleService.SeriesConflicts(r.Context(), series, time.Time{})
+	if err != nil {
+		h.writeScheduleError(w, err, "failed to check schedule conflicts")
+		return
+	}
+	if len(conflicts) > 0 && !forceRequested(r) {
+		writeConflicts(w, conflicts)
+		return
+	}
+
+	created, err := h.scheduleService.CreateSeries(r.Context(), series, time.Time{})
+	if err != nil {
+		h.writeScheduleError(w, err, "failed to create series")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{
+		"series":        series,
+		"created_count": created,
+		"conflicts":     nonNilConflicts(conflicts),
+	})
+}
+
+// GET /api/v1/groups/:group_id/series
+func (h *SeriesHandler) ListByGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "group_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid group_id")
+		return
+	}
+
+	if _, err := h.groupRepo.GetByID(r.Context(), groupID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to verify group")
+		return
+	}
+
+	series, err := h.seriesRepo.GetByGroup(r.Context(), groupID)
+	if err != nil {
+		response.InternalError(w, "failed to get series")
+		return
+	}
+
+	response.OK(w, series)
+}
+
+// GET /api/v1/series/:id
+func (h *SeriesHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid series id")
+		return
+	}
+
+	series, err := h.seriesRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "series not found")
+			return
+		}
+		response.InternalError(w, "failed to get series")
+		return
+	}
+
+	response.OK(w, series)
+}
+
+// PUT /api/v1/series/:id[?force=true]
+// Changes apply to the whole series from today on; use
+// PUT /sessions/:id?scope=following to change it from a given occurrence.
+func (h *SeriesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid series id")
+		return
+	}
+
+	var req UpdateSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	series, ok := h.loadForWrite(w, r, id)
+	if !ok {
+		return
+	}
+
+	if req.RRule != nil {
+		series.RRule = *req.RRule
+	}
+	if req.EndsOn != nil {
+		if *req.EndsOn == "" {
+			series.EndsOn = nil
+		} else {
+			endsOn, err := schedule.ParseDate(*req.EndsOn, time.UTC)
+			if err != nil {
+				response.BadRequest(w, "invalid ends_on format, use YYYY-MM-DD")
+				return
+			}
+			if endsOn.Before(series.StartsOn) {
+				response.BadRequest(w, "ends_on must not be before starts_on")
+				return
+			}
+			series.EndsOn = &endsOn
+		}
+	}
+	if req.StartTime != nil {
+		series.StartTime = *req.StartTime
+	}
+	if req.DurationMinutes != nil {
+		series.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Location != nil {
+		series.Location = *req.Location
+	}
+	if req.LocationID != nil {
+		group, err := h.groupRepo.GetByID(r.Context(), series.GroupID)
+		if err != nil {
+			response.InternalError(w, "failed to verify group")
+			return
+		}
+		location, err := resolveLocation(r.Context(), h.locationRepo, group.ClubID, *req.LocationID)
+		if err != nil {
+			writeLocationError(w, err)
+			return
+		}
+		series.LocationID = nil
+		if location != nil {
+			series.LocationID = &location.ID
+			if req.Location == nil {
+				series.Location = location.Name
+			}
+		}
+	}
+	if req.ExDates != nil {
+		if series.ExDates, err = parseExDates(req.ExDates); err != nil {
+			response.BadRequest(w, "invalid exdates format, use YYYY-MM-DD")
+			return
+		}
+	}
+
+	conflicts, err := h.scheduleService.SeriesConflicts(r.Context(), series, time.Time{})
+	if err != nil {
+		h.writeScheduleError(w, err, "failed to check schedule conflicts")
+		return
+	}
+	if len(conflicts) > 0 && !forceRequested(r) {
+		writeConflicts(w, conflicts)
+		return
+	}
+
+	created, err := h.scheduleService.UpdateSeries(r.Context(), series)
+	if err != nil {
+		h.writeScheduleError(w, err, "failed to update series")
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"series":        series,
+		"created_count": created,
+		"conflicts":     nonNilConflicts(conflicts),
+	})
+}
+
+// DELETE /api/v1/series/:id
+// Future sessions without attendance are removed; the rest are kept as
+// standalone sessions.
+func (h *SeriesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid series id")
+		return
+	}
+
+	series, ok := h.loadForWrite(w, r, id)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduleService.DeleteSeries(r.Context(), series); err != nil {
+		h.writeScheduleError(w, err, "failed to delete series")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// loadForWrite fetches a series and checks the caller may change it.
+// On failure the response has already been written.
+func (h *SeriesHandler) loadForWrite(w http.ResponseWriter, r *http.Request, id uuid.UUID) (*model.ScheduleSeries, bool) {
+	series, err := h.seriesRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "series not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get series")
+		return nil, false
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), series.GroupID)
+	if err != nil {
+		response.InternalError(w, "failed to verify group")
+		return nil, false
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	hasPermission, err := h.checkPermission(r, group, userID)
+	if err != nil || !hasPermission {
+		response.Forbidden(w, "you don't have permission to manage this group's schedule")
+		return nil, false
+	}
+
+	return series, true
+}
+
+func (h *SeriesHandler) writeScheduleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, schedule.ErrInvalidRule), errors.Is(err, service.ErrInvalidStartTime):
+		response.UnprocessableEntity(w, err.Error())
+	case errors.Is(err, service.ErrSeriesDateChange):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		response.NotFound(w, "series not found")
+	default:
+		response.InternalError(w, fallback)
+	}
+}
+
+func (h *SeriesHandler) checkPermission(r *http.Request, group *model.Group, userID uuid.UUID) (bool, error) {
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		return false, err
+	}
+	if club.OwnerUserID == userID {
+		return true, nil
+	}
+	if group.CoachUserID != nil && *group.CoachUserID == userID {
+		return true, nil
+	}
+	return false, nil
+}
+
+// parseExDates validates and normalises excluded occurrence dates
+func parseExDates(values []string) ([]string, error) {
+	dates := make([]string, 0, len(values))
+	for _, v := range values {
+		d, err := schedule.ParseDate(v, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, d.Format(schedule.DateLayout))
+	}
+	return dates, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,32 +12,40 @@ import (
 	"github.com/neo/trainer-plus/internal/middleware"
 	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
+	"github.com/neo/trainer-plus/internal/service"
 	"github.com/neo/trainer-plus/internal/validator"
 	"github.com/neo/trainer-plus/pkg/response"
 )
 
 type SessionHandler struct {
-	sessionRepo *repository.SessionRepository
-	groupRepo   *repository.GroupRepository
-	clubRepo    *repository.ClubRepository
-	validator   *validator.Validator
+	sessionRepo     *repository.SessionRepository
+	groupRepo       *repository.GroupRepository
+	clubRepo        *repository.ClubRepository
+	locationRepo    *repository.LocationRepository
+	scheduleService *service.ScheduleService
+	validator       *validator.Validator
 }
 
 func NewSessionHandler(
 	sessionRepo *repository.SessionRepository,
 	groupRepo *repository.GroupRepository,
 	clubRepo *repository.ClubRepository,
+	locationRepo *repository.LocationRepository,
+	scheduleService *service.ScheduleService,
 	validator *validator.Validator,
 ) *SessionHandler {
 	return &SessionHandler{
-		sessionRepo: sessionRepo,
-		groupRepo:   groupRepo,
-		clubRepo:    clubRepo,
-		validator:   validator,
+		sessionRepo:     sessionRepo,
+		groupRepo:       groupRepo,
+		clubRepo:        clubRepo,
+		locationRepo:    locationRepo,
+		scheduleService: scheduleService,
+		validator:       validator,
 	}
 }
 
-// POST /api/v1/groups/:group_id/sessions
+// POST /api/v1/groups/:group_id/sessions[?force=true]
 func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 	groupIDStr := chi.URLParam(r, "group_id")
 	groupID, err := uuid.Parse(groupIDStr)
@@ -75,10 +84,22 @@ func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse start time
-	startAt, err := time.Parse(time.RFC3339, req.StartAt)
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	// Parse start time (offset-less values are club local time)
+	startAt, err := parseSessionStart(req.StartAt, club.Location())
+	if err != nil {
+		response.BadRequest(w, "invalid start_at format, use RFC3339 or YYYY-MM-DDTHH:MM")
+		return
+	}
+
+	location, err := locationFor(r.Context(), h.locationRepo, group, req.LocationID, req.Location)
 	if err != nil {
-		response.BadRequest(w, "invalid start_at format, use RFC3339")
+		writeLocationError(w, err)
 		return
 	}
 
@@ -86,7 +107,17 @@ func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		GroupID:         groupID,
 		StartAt:         startAt,
 		DurationMinutes: req.DurationMinutes,
-		Location:        req.Location,
+	}
+	session.Location, session.LocationID = locationFields(location, req.Location)
+
+	conflicts, err := h.scheduleService.CheckConflicts(r.Context(), group, []model.Session{*session})
+	if err != nil {
+		response.InternalError(w, "failed to check schedule conflicts")
+		return
+	}
+	if len(conflicts) > 0 && !forceRequested(r) {
+		writeConflicts(w, conflicts)
+		return
 	}
 
 	if err := h.sessionRepo.Create(r.Context(), session); err != nil {
@@ -94,10 +125,13 @@ func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Created(w, session)
+	response.Created(w, struct {
+		*model.Session
+		Conflicts []schedule.Conflict `json:"conflicts,omitempty"`
+	}{session, conflicts})
 }
 
-// POST /api/v1/groups/:group_id/sessions/recurring
+// POST /api/v1/groups/:group_id/sessions/recurring[?force=true]
 func (h *SessionHandler) CreateRecurring(w http.ResponseWriter, r *http.Request) {
 	groupIDStr := chi.URLParam(r, "group_id")
 	groupID, err := uuid.Parse(groupIDStr)
@@ -135,14 +169,21 @@ func (h *SessionHandler) CreateRecurring(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse dates
-	fromDate, err := time.Parse("2006-01-02", req.FromDate)
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+	loc := club.Location()
+
+	// Parse dates (calendar dates in the club's time zone)
+	fromDate, err := schedule.ParseDate(req.FromDate, loc)
 	if err != nil {
 		response.BadRequest(w, "invalid from_date format, use YYYY-MM-DD")
 		return
 	}
 
-	toDate, err := time.Parse("2006-01-02", req.ToDate)
+	toDate, err := schedule.ParseDate(req.ToDate, loc)
 	if err != nil {
 		response.BadRequest(w, "invalid to_date format, use YYYY-MM-DD")
 		return
@@ -160,53 +201,65 @@ func (h *SessionHandler) CreateRecurring(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Generate sessions
-	var sessions []model.Session
-	weekdayMap := make(map[int]bool)
+	weekdays := make([]time.Weekday, 0, len(req.Weekdays))
 	for _, wd := range req.Weekdays {
 		if wd < 0 || wd > 6 {
 			response.BadRequest(w, "weekdays must be 0-6 (Sunday=0, Monday=1, etc.)")
 			return
 		}
-		weekdayMap[wd] = true
-	}
-
-	// Iterate through date range
-	for d := fromDate; !d.After(toDate); d = d.AddDate(0, 0, 1) {
-		weekday := int(d.Weekday())
-		if weekdayMap[weekday] {
-			startAt := time.Date(
-				d.Year(), d.Month(), d.Day(),
-				startTimeParts.Hour(), startTimeParts.Minute(), 0, 0,
-				time.Local,
-			)
-			sessions = append(sessions, model.Session{
-				GroupID:         groupID,
-				StartAt:         startAt,
-				DurationMinutes: req.DurationMinutes,
-				Location:        req.Location,
-			})
-		}
+		weekdays = append(weekdays, time.Weekday(wd))
 	}
 
-	if len(sessions) == 0 {
+	// Limit to reasonable number. Dates are counted at the same local
+	// wall-clock time, so DST transitions don't shift the class hour.
+	count := len(schedule.Weekly(fromDate, toDate, weekdays, startTimeParts.Hour(), startTimeParts.Minute(), loc))
+	if count == 0 {
 		response.BadRequest(w, "no sessions generated for the given parameters")
 		return
 	}
-
-	// Limit to reasonable number
-	if len(sessions) > 365 {
+	if count > 365 {
 		response.BadRequest(w, "too many sessions, maximum 365 at once")
 		return
 	}
 
-	if err := h.sessionRepo.CreateBatch(r.Context(), sessions); err != nil {
+	location, err := locationFor(r.Context(), h.locationRepo, group, req.LocationID, req.Location)
+	if err != nil {
+		writeLocationError(w, err)
+		return
+	}
+
+	// Persist the rule as a series so it can be edited later
+	startsOn, endsOn := schedule.DateOf(fromDate), schedule.DateOf(toDate)
+	series := &model.ScheduleSeries{
+		GroupID:         groupID,
+		RRule:           schedule.WeeklyRule(weekdays).String(),
+		StartsOn:        startsOn,
+		EndsOn:          &endsOn,
+		StartTime:       startTimeParts.Format("15:04"),
+		DurationMinutes: req.DurationMinutes,
+	}
+	series.Location, series.LocationID = locationFields(location, req.Location)
+
+	conflicts, err := h.scheduleService.SeriesConflicts(r.Context(), series, endsOn)
+	if err != nil {
+		response.InternalError(w, "failed to check schedule conflicts")
+		return
+	}
+	if len(conflicts) > 0 && !forceRequested(r) {
+		writeConflicts(w, conflicts)
+		return
+	}
+
+	created, err := h.scheduleService.CreateSeries(r.Context(), series, endsOn)
+	if err != nil {
 		response.InternalError(w, "failed to create sessions")
 		return
 	}
 
 	response.Created(w, map[string]interface{}{
-		"created_count": len(sessions),
+		"created_count": created,
+		"series_id":     series.ID,
+		"conflicts":     nonNilConflicts(conflicts),
 		"message":       "recurring sessions created successfully",
 	})
 }
@@ -220,13 +273,30 @@ func (h *SessionHandler) ListByGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse date range from query params
+	group, err := h.groupRepo.GetByID(r.Context(), groupID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "group not found")
+			return
+		}
+		response.InternalError(w, "failed to verify group")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+	loc := club.Location()
+
+	// Parse date range from query params (dates are in the club's time zone)
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
 
 	var from, to time.Time
 	if fromStr != "" {
-		from, err = time.Parse("2006-01-02", fromStr)
+		from, err = schedule.ParseDate(fromStr, loc)
 		if err != nil {
 			response.BadRequest(w, "invalid from date format, use YYYY-MM-DD")
 			return
@@ -236,11 +306,12 @@ func (h *SessionHandler) ListByGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if toStr != "" {
-		to, err = time.Parse("2006-01-02", toStr)
+		to, err = schedule.ParseDate(toStr, loc)
 		if err != nil {
 			response.BadRequest(w, "invalid to date format, use YYYY-MM-DD")
 			return
 		}
+		to = schedule.EndOfDay(to, loc)
 	} else {
 		to = time.Now().AddDate(0, 1, 0) // Default: next month
 	}
@@ -276,7 +347,7 @@ func (h *SessionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, session)
 }
 
-// PUT /api/v1/sessions/:id
+// PUT /api/v1/sessions/:id?scope=this|following|all[&force=true]
 func (h *SessionHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -316,28 +387,79 @@ func (h *SessionHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse and apply updates
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	scope := model.EditScope(r.URL.Query().Get("scope"))
+	switch scope {
+	case "", model.EditThis, model.EditFollowing, model.EditAll:
+	default:
+		response.BadRequest(w, "scope must be one of: this, following, all")
+		return
+	}
+
+	// Parse updates
+	var changes service.SessionChanges
 	if req.StartAt != "" {
-		startAt, err := time.Parse(time.RFC3339, req.StartAt)
+		startAt, err := parseSessionStart(req.StartAt, club.Location())
 		if err != nil {
 			response.BadRequest(w, "invalid start_at format")
 			return
 		}
-		session.StartAt = startAt
+		changes.StartAt = &startAt
 	}
 	if req.DurationMinutes > 0 {
-		session.DurationMinutes = req.DurationMinutes
+		changes.DurationMinutes = &req.DurationMinutes
 	}
 	if req.Location != "" {
-		session.Location = req.Location
+		changes.Location = &req.Location
+	}
+	if err := h.applyLocationChange(r, group.ClubID, req.LocationID, &changes); err != nil {
+		writeLocationError(w, err)
+		return
+	}
+
+	var conflicts []schedule.Conflict
+	if !session.IsCancelled() {
+		conflicts, err = h.scheduleService.SessionConflicts(r.Context(), group, session, changes, scope)
+		if err != nil {
+			writeSessionUpdateError(w, err, "failed to check schedule conflicts")
+			return
+		}
+		if len(conflicts) > 0 && !forceRequested(r) {
+			writeConflicts(w, conflicts)
+			return
+		}
 	}
 
-	if err := h.sessionRepo.Update(r.Context(), session); err != nil {
-		response.InternalError(w, "failed to update session")
+	session, err = h.scheduleService.UpdateSession(r.Context(), session, changes, scope)
+	if err != nil {
+		writeSessionUpdateError(w, err, "failed to update session")
 		return
 	}
 
-	response.OK(w, session)
+	response.OK(w, struct {
+		*model.Session
+		Conflicts []schedule.Conflict `json:"conflicts,omitempty"`
+	}{session, conflicts})
+}
+
+// writeSessionUpdateError maps schedule service errors of a session edit to
+// responses
+func writeSessionUpdateError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrSeriesDateChange):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, service.ErrSessionCancelled):
+		response.Conflict(w, "cancelled sessions cannot be changed")
+	case errors.Is(err, schedule.ErrInvalidRule):
+		response.UnprocessableEntity(w, err.Error())
+	default:
+		response.InternalError(w, fallback)
+	}
 }
 
 // DELETE /api/v1/sessions/:id
@@ -372,14 +494,226 @@ func (h *SessionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.sessionRepo.Delete(r.Context(), id); err != nil {
-		response.InternalError(w, "failed to delete session")
+	// Series occurrences are excluded so they are not generated again
+	if err := h.scheduleService.DeleteSession(r.Context(), session); err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionAttended):
+			response.Conflict(w, "session has attendance, cancel it with POST /api/v1/sessions/"+id.String()+"/cancel instead")
+		case errors.Is(err, repository.ErrNotFound):
+			response.NotFound(w, "session not found")
+		default:
+			response.InternalError(w, "failed to delete session")
+		}
 		return
 	}
 
 	response.NoContent(w)
 }
 
+// POST /api/v1/sessions/:id/cancel
+func (h *SessionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	var req CancelSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	session, _, ok := h.loadForWrite(w, r, "you don't have permission to cancel this session")
+	if !ok {
+		return
+	}
+
+	session, err := h.scheduleService.CancelSession(r.Context(), session, req.Reason)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionCancelled) {
+			response.Conflict(w, "session is already cancelled")
+			return
+		}
+		response.InternalError(w, "failed to cancel session")
+		return
+	}
+
+	response.OK(w, session)
+}
+
+// POST /api/v1/sessions/:id/reschedule[?force=true]
+// Moves a single session to another time; the session keeps its ID and
+// its participants are notified.
+func (h *SessionHandler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	var req RescheduleSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.UnprocessableEntity(w, err.Error())
+		return
+	}
+
+	session, club, ok := h.loadForWrite(w, r, "you don't have permission to reschedule this session")
+	if !ok {
+		return
+	}
+
+	startAt, err := parseSessionStart(req.StartAt, club.Location())
+	if err != nil {
+		response.BadRequest(w, "invalid start_at format, use RFC3339 or YYYY-MM-DDTHH:MM")
+		return
+	}
+
+	changes := service.SessionChanges{StartAt: &startAt}
+	if req.DurationMinutes > 0 {
+		changes.DurationMinutes = &req.DurationMinutes
+	}
+	if req.Location != "" {
+		changes.Location = &req.Location
+	}
+	if err := h.applyLocationChange(r, club.ID, req.LocationID, &changes); err != nil {
+		writeLocationError(w, err)
+		return
+	}
+
+	if !session.IsCancelled() && !forceRequested(r) {
+		group, err := h.groupRepo.GetByID(r.Context(), session.GroupID)
+		if err != nil {
+			response.InternalError(w, "failed to verify group")
+			return
+		}
+		conflicts, err := h.scheduleService.SessionConflicts(r.Context(), group, session, changes, model.EditThis)
+		if err != nil {
+			response.InternalError(w, "failed to check schedule conflicts")
+			return
+		}
+		if len(conflicts) > 0 {
+			writeConflicts(w, conflicts)
+			return
+		}
+	}
+
+	session, err = h.scheduleService.UpdateSession(r.Context(), session, changes, model.EditThis)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionCancelled) {
+			response.Conflict(w, "cancelled sessions cannot be rescheduled")
+			return
+		}
+		response.InternalError(w, "failed to reschedule session")
+		return
+	}
+
+	response.OK(w, session)
+}
+
+// GET /api/v1/clubs/:club_id/conflicts?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *SessionHandler) ClubConflicts(w http.ResponseWriter, r *http.Request) {
+	clubID, err := uuid.Parse(chi.URLParam(r, "club_id"))
+	if err != nil {
+		response.BadRequest(w, "invalid club_id")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to get club")
+		return
+	}
+
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have access to this club")
+		return
+	}
+
+	// Default: the next 30 days in the club's time zone
+	loc := club.Location()
+	from := schedule.StartOfDay(time.Now(), loc)
+	to := from.AddDate(0, 0, 30)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = schedule.ParseDate(v, loc); err != nil {
+			response.BadRequest(w, "invalid from date format, use YYYY-MM-DD")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = schedule.ParseDate(v, loc); err != nil {
+			response.BadRequest(w, "invalid to date format, use YYYY-MM-DD")
+			return
+		}
+		to = schedule.EndOfDay(to, loc)
+	}
+
+	conflicts, err := h.scheduleService.AuditConflicts(r.Context(), clubID, from, to)
+	if err != nil {
+		response.InternalError(w, "failed to check schedule conflicts")
+		return
+	}
+
+	response.OK(w, conflicts)
+}
+
+// applyLocationChange resolves a requested location_id into session changes.
+// The location's name is used as the label unless one was given.
+func (h *SessionHandler) applyLocationChange(r *http.Request, clubID uuid.UUID, locationID string, changes *service.SessionChanges) error {
+	location, err := resolveLocation(r.Context(), h.locationRepo, clubID, locationID)
+	if err != nil || location == nil {
+		return err
+	}
+
+	changes.LocationID = &location.ID
+	if changes.Location == nil {
+		changes.Location = &location.Name
+	}
+	return nil
+}
+
+// loadForWrite fetches the session from the URL and checks the caller may
+// change it. On failure the response has already been written.
+func (h *SessionHandler) loadForWrite(w http.ResponseWriter, r *http.Request, forbidden string) (*model.Session, *model.Club, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid session id")
+		return nil, nil, false
+	}
+
+	session, err := h.sessionRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "session not found")
+			return nil, nil, false
+		}
+		response.InternalError(w, "failed to get session")
+		return nil, nil, false
+	}
+
+	group, err := h.groupRepo.GetByID(r.Context(), session.GroupID)
+	if err != nil {
+		response.InternalError(w, "failed to verify group")
+		return nil, nil, false
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return nil, nil, false
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
+		response.Forbidden(w, forbidden)
+		return nil, nil, false
+	}
+
+	return session, club, true
+}
+
 func (h *SessionHandler) checkPermission(r *http.Request, clubID uuid.UUID, coachID *uuid.UUID, userID uuid.UUID) (bool, error) {
 	club, err := h.clubRepo.GetByID(r.Context(), clubID)
 	if err != nil {
@@ -393,3 +727,34 @@ func (h *SessionHandler) checkPermission(r *http.Request, clubID uuid.UUID, coac
 	}
 	return false, nil
 }
+
+// forceRequested reports whether the client asked to save despite
+// scheduling conflicts
+func forceRequested(r *http.Request) bool {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	return force
+}
+
+// writeConflicts rejects a schedule change that double-books a coach or a
+// location, listing the clashes so the client can offer ?force=true
+func writeConflicts(w http.ResponseWriter, conflicts []schedule.Conflict) {
+	response.ErrorWithData(w, http.StatusConflict, "SCHEDULE_CONFLICT",
+		"the schedule overlaps with other sessions of the same coach or location",
+		map[string]interface{}{"conflicts": conflicts})
+}
+
+func nonNilConflicts(conflicts []schedule.Conflict) []schedule.Conflict {
+	if conflicts == nil {
+		return []schedule.Conflict{}
+	}
+	return conflicts
+}
+
+// parseSessionStart accepts an RFC3339 timestamp, or a local date-time
+// without offset which is interpreted in the club's time zone
+func parseSessionStart(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04", value, loc)
+}
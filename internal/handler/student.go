@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
 	"github.com/neo/trainer-plus/internal/middleware"
 	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
@@ -18,18 +21,23 @@ import (
 type StudentHandler struct {
 	studentRepo *repository.StudentRepository
 	clubRepo    *repository.ClubRepository
+	fieldRepo   *repository.CustomFieldRepository
 	validator   *validator.Validator
+	attributes  attributeReader
 }
 
 func NewStudentHandler(
 	studentRepo *repository.StudentRepository,
 	clubRepo *repository.ClubRepository,
+	fieldRepo *repository.CustomFieldRepository,
 	validator *validator.Validator,
 ) *StudentHandler {
 	return &StudentHandler{
 		studentRepo: studentRepo,
 		clubRepo:    clubRepo,
+		fieldRepo:   fieldRepo,
 		validator:   validator,
+		attributes:  attributeReader{fieldRepo: fieldRepo, validator: validator},
 	}
 }
 
@@ -69,10 +77,17 @@ func (h *StudentHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags, customFields, ok := h.attributes.read(w, r, clubID, model.CustomFieldStudent, req.Tags, req.CustomFields)
+	if !ok {
+		return
+	}
+
 	student := &model.Student{
-		ClubID: clubID,
-		Name:   req.Name,
-		Notes:  req.Notes,
+		ClubID:       clubID,
+		Name:         req.Name,
+		Notes:        req.Notes,
+		Tags:         tags,
+		CustomFields: model.CustomValues{}.Apply(customFields),
 	}
 
 	// Parse birth date if provided
@@ -88,9 +103,12 @@ func (h *StudentHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse parent contact if provided
 	if req.ParentContact != nil {
 		student.ParentContact = &model.ParentContact{
-			Name:  req.ParentContact.Name,
-			Phone: req.ParentContact.Phone,
-			Email: req.ParentContact.Email,
+			Name:           req.ParentContact.Name,
+			Phone:          req.ParentContact.Phone,
+			Email:          req.ParentContact.Email,
+			Language:       req.ParentContact.Language,
+			Channels:       req.ParentContact.Channels,
+			TelegramChatID: req.ParentContact.TelegramChatID,
 		}
 	}
 
@@ -133,16 +151,35 @@ func (h *StudentHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	archived, ok := parseArchived(w, r)
+	if !ok {
+		return
+	}
+
+	attrs, ok := h.attributes.filter(w, r, clubID, model.CustomFieldStudent)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		h.export(w, r, clubID, archived, attrs, format)
+		return
+	}
+
 	pagination := parsePagination(r)
 
-	students, err := h.studentRepo.GetByClub(r.Context(), clubID, pagination.GetLimit(), pagination.GetOffset())
+	students, err := h.studentRepo.GetByClub(r.Context(), clubID, archived, attrs, pagination.GetLimit(), pagination.GetOffset())
 	if err != nil {
 		response.InternalError(w, "failed to get students")
 		return
 	}
 
 	// Get total count for pagination
-	total, err := h.studentRepo.CountByClub(r.Context(), clubID)
+	total, err := h.studentRepo.CountByClub(r.Context(), clubID, archived, attrs)
 	if err != nil {
 		response.InternalError(w, "failed to count students")
 		return
@@ -156,7 +193,53 @@ func (h *StudentHandler) ListByClub(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /api/v1/clubs/:club_id/students/search
+// export streams every student of the club, ignoring pagination. Tags and
+// each of the club's custom fields get a column.
+func (h *StudentHandler) export(w http.ResponseWriter, r *http.Request, clubID uuid.UUID, archived repository.ArchiveFilter, attrs repository.AttributeFilter, format export.Format) {
+	club := exportClub(w, r, h.clubRepo, clubID)
+	if club == nil {
+		return
+	}
+
+	fields, err := h.fieldRepo.GetByClub(r.Context(), clubID, model.CustomFieldStudent)
+	if err != nil {
+		response.InternalError(w, "failed to get custom fields")
+		return
+	}
+
+	locale := export.LocaleFor(r, club)
+	info := export.Info{Name: "students", Title: "list.students"}
+	sendExport(w, format, locale, info, func(d *export.Document) error {
+		columns := []export.Column{
+			{Key: "name"},
+			{Key: "birth_date", Kind: export.Day},
+			{Key: "parent_name"},
+			{Key: "parent_phone"},
+			{Key: "parent_email"},
+			{Key: "notes"},
+			{Key: "tags"},
+		}
+		columns = append(columns, customFieldColumns(fields)...)
+		columns = append(columns, export.Column{Key: "created_at", Kind: export.Date})
+		if err := d.Table("", columns...); err != nil {
+			return err
+		}
+
+		return h.studentRepo.EachByClub(r.Context(), clubID, archived, attrs, func(s *model.Student) error {
+			var parent model.ParentContact
+			if s.ParentContact != nil {
+				parent = *s.ParentContact
+			}
+			row := []interface{}{s.Name, s.BirthDate, parent.Name, parent.Phone, parent.Email, s.Notes, strings.Join(s.Tags, ", ")}
+			for _, f := range fields {
+				row = append(row, s.CustomFields[f.Key])
+			}
+			return d.Row(append(row, s.CreatedAt)...)
+		})
+	})
+}
+
+// GET /api/v1/clubs/:club_id/students/search?q=&group_id=&subscription_status=&debt=&birth_year_from=&birth_year_to=&tag=&field.<key>=&archived=&limit=20&cursor=
 func (h *StudentHandler) Search(w http.ResponseWriter, r *http.Request) {
 	clubIDStr := chi.URLParam(r, "club_id")
 	clubID, err := uuid.Parse(clubIDStr)
@@ -165,19 +248,113 @@ func (h *StudentHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		response.BadRequest(w, "search query (q) is required")
+	// Results include guardian contacts, so only the owner may search
+	club, err := h.clubRepo.GetByID(r.Context(), clubID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "club not found")
+			return
+		}
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+	if club.OwnerUserID != middleware.GetUserID(r.Context()) {
+		response.Forbidden(w, "you don't have permission to search this club's students")
 		return
 	}
 
-	students, err := h.studentRepo.Search(r.Context(), clubID, query)
+	search, ok := parseStudentSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if search.Attributes, ok = h.attributes.filter(w, r, clubID, model.CustomFieldStudent); !ok {
+		return
+	}
+
+	students, next, err := h.studentRepo.Search(r.Context(), clubID, search)
 	if err != nil {
 		response.InternalError(w, "failed to search students")
 		return
 	}
 
-	response.OK(w, students)
+	meta := &response.Meta{PerPage: search.Limit}
+	if next != nil {
+		meta.NextCursor = next.Encode()
+	}
+	response.WithMeta(w, http.StatusOK, students, meta)
+}
+
+// parseStudentSearch reads the search from the query string, writing the
+// error response and returning false if a parameter is malformed
+func parseStudentSearch(w http.ResponseWriter, r *http.Request) (repository.StudentSearch, bool) {
+	q := r.URL.Query()
+	search := repository.StudentSearch{Query: q.Get("q"), Limit: 20}
+
+	if groupIDStr := q.Get("group_id"); groupIDStr != "" {
+		groupID, err := uuid.Parse(groupIDStr)
+		if err != nil {
+			response.BadRequest(w, "invalid group_id")
+			return search, false
+		}
+		search.GroupID = &groupID
+	}
+
+	switch status := model.SubscriptionStatus(q.Get("subscription_status")); status {
+	case "", model.SubscriptionPending, model.SubscriptionActive, model.SubscriptionUsed, model.SubscriptionExpired, model.SubscriptionCancelled:
+		search.SubscriptionStatus = string(status)
+	default:
+		response.BadRequest(w, "invalid subscription_status")
+		return search, false
+	}
+
+	if debtStr := q.Get("debt"); debtStr != "" {
+		debt, err := strconv.ParseBool(debtStr)
+		if err != nil {
+			response.BadRequest(w, "debt must be true or false")
+			return search, false
+		}
+		search.Debt = &debt
+	}
+
+	for _, year := range []struct {
+		param string
+		value *int
+	}{{"birth_year_from", &search.BirthYearFrom}, {"birth_year_to", &search.BirthYearTo}} {
+		yearStr := q.Get(year.param)
+		if yearStr == "" {
+			continue
+		}
+		y, err := strconv.Atoi(yearStr)
+		if err != nil || y < 1900 || y > 9999 {
+			response.BadRequest(w, "invalid "+year.param)
+			return search, false
+		}
+		*year.value = y
+	}
+
+	archived, ok := parseArchived(w, r)
+	if !ok {
+		return search, false
+	}
+	search.Archived = archived
+
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			search.Limit = l
+		}
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		after, err := repository.DecodeStudentCursor(cursor)
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return search, false
+		}
+		search.After = after
+	}
+
+	return search, true
 }
 
 // PUT /api/v1/students/:id
@@ -240,12 +417,24 @@ func (h *StudentHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	if req.ParentContact != nil {
 		student.ParentContact = &model.ParentContact{
-			Name:  req.ParentContact.Name,
-			Phone: req.ParentContact.Phone,
-			Email: req.ParentContact.Email,
+			Name:           req.ParentContact.Name,
+			Phone:          req.ParentContact.Phone,
+			Email:          req.ParentContact.Email,
+			Language:       req.ParentContact.Language,
+			Channels:       req.ParentContact.Channels,
+			TelegramChatID: req.ParentContact.TelegramChatID,
 		}
 	}
 
+	tags, customFields, ok := h.attributes.read(w, r, student.ClubID, model.CustomFieldStudent, req.Tags, req.CustomFields)
+	if !ok {
+		return
+	}
+	if tags != nil {
+		student.Tags = tags
+	}
+	student.CustomFields = student.CustomFields.Apply(customFields)
+
 	if err := h.studentRepo.Update(r.Context(), student); err != nil {
 		response.InternalError(w, "failed to update student")
 		return
@@ -255,6 +444,7 @@ func (h *StudentHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE /api/v1/students/:id
+// Archives the student; only an admin can purge them
 func (h *StudentHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -285,10 +475,56 @@ func (h *StudentHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.studentRepo.Delete(r.Context(), id); err != nil {
+	if err := h.studentRepo.Archive(r.Context(), id); err != nil {
 		response.InternalError(w, "failed to delete student")
 		return
 	}
 
 	response.NoContent(w)
 }
+
+// POST /api/v1/students/:id/restore
+func (h *StudentHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "invalid student id")
+		return
+	}
+
+	student, err := h.studentRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "student not found")
+			return
+		}
+		response.InternalError(w, "failed to get student")
+		return
+	}
+
+	club, err := h.clubRepo.GetByID(r.Context(), student.ClubID)
+	if err != nil {
+		response.InternalError(w, "failed to verify club")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if club.OwnerUserID != userID {
+		response.Forbidden(w, "you don't have permission to restore this student")
+		return
+	}
+
+	// Nothing is left to restore of an erased student
+	if student.ErasedAt != nil {
+		response.Conflict(w, "an erased student cannot be restored")
+		return
+	}
+
+	if err := h.studentRepo.Restore(r.Context(), id); err != nil {
+		response.InternalError(w, "failed to restore student")
+		return
+	}
+	student.ArchivedAt = nil
+
+	response.OK(w, student)
+}
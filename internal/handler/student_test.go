@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/handler"
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+)
+
+// clubDriver serves one club owned by clubOwner and records the other
+// queries it is asked, which return no rows
+type clubDriver struct{}
+
+var (
+	clubOwner      = uuid.New()
+	clubQueriesMu  sync.Mutex
+	clubQueries    []string
+	registerClubDB sync.Once
+)
+
+func (clubDriver) Open(string) (driver.Conn, error) { return clubConn{}, nil }
+
+type clubConn struct{}
+
+func (clubConn) Prepare(query string) (driver.Stmt, error) { return clubStmt{query}, nil }
+func (clubConn) Close() error                              { return nil }
+func (clubConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type clubStmt struct{ query string }
+
+func (s clubStmt) Close() error  { return nil }
+func (s clubStmt) NumInput() int { return -1 }
+
+func (s clubStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s clubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "FROM clubs") {
+		return &clubRows{row: []driver.Value{args[0], clubOwner.String()}}, nil
+	}
+	clubQueriesMu.Lock()
+	clubQueries = append(clubQueries, s.query)
+	clubQueriesMu.Unlock()
+	return &clubRows{}, nil
+}
+
+type clubRows struct{ row []driver.Value }
+
+func (r *clubRows) Columns() []string {
+	if r.row == nil {
+		return nil
+	}
+	return []string{"id", "owner_user_id"}
+}
+
+func (r *clubRows) Close() error { return nil }
+
+func (r *clubRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
+
+func newStudentHandler(t *testing.T) *handler.StudentHandler {
+	t.Helper()
+	registerClubDB.Do(func() { sql.Register("club", clubDriver{}) })
+	db, err := sqlx.Open("club", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return handler.NewStudentHandler(
+		repository.NewStudentRepository(db),
+		repository.NewClubRepository(db),
+		repository.NewCustomFieldRepository(db),
+		validator.New(),
+	)
+}
+
+func TestStudentHandler_Search_Permission(t *testing.T) {
+	h := newStudentHandler(t)
+	clubID := uuid.New()
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		want     int
+		searched bool
+	}{
+		{"foreign user", uuid.New(), http.StatusForbidden, false},
+		{"owner", clubOwner, http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubQueriesMu.Lock()
+			clubQueries = nil
+			clubQueriesMu.Unlock()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clubs/"+clubID.String()+"/students/search?q=%2B7701", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("club_id", clubID.String())
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, middleware.UserIDKey, tt.userID)
+			rec := httptest.NewRecorder()
+
+			h.Search(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			clubQueriesMu.Lock()
+			searched := len(clubQueries) > 0
+			clubQueriesMu.Unlock()
+			if searched != tt.searched {
+				t.Errorf("students searched = %v, want %v", searched, tt.searched)
+			}
+		})
+	}
+}
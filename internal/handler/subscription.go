@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/export"
 	"github.com/neo/trainer-plus/internal/middleware"
 	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/repository"
@@ -92,6 +93,11 @@ func (h *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if student.ArchivedAt != nil || group.ArchivedAt != nil {
+		response.UnprocessableEntity(w, "student or group is archived")
+		return
+	}
+
 	// Check permission - user must be owner of the club
 	userID := middleware.GetUserID(r.Context())
 	club, err := h.clubRepo.GetByID(r.Context(), group.ClubID)
@@ -133,6 +139,7 @@ func (h *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		StartsAt:          startsAt,
 		ExpiresAt:         expiresAt,
 		Status:            string(model.SubscriptionActive), // Direct creation = active
+		Kind:              string(model.SubscriptionPackage),
 	}
 
 	if err := h.subRepo.Create(r.Context(), sub); err != nil {
@@ -194,6 +201,15 @@ func (h *SubscriptionHandler) ListByClub(w http.ResponseWriter, r *http.Request)
 
 	status := r.URL.Query().Get("status")
 
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		h.export(w, r, clubID, status, format)
+		return
+	}
+
 	subs, err := h.subRepo.GetByClubWithDetails(r.Context(), clubID, status)
 	if err != nil {
 		response.InternalError(w, "failed to get subscriptions")
@@ -203,6 +219,39 @@ func (h *SubscriptionHandler) ListByClub(w http.ResponseWriter, r *http.Request)
 	response.OK(w, subs)
 }
 
+// export streams the club's subscriptions with the status, if given
+func (h *SubscriptionHandler) export(w http.ResponseWriter, r *http.Request, clubID uuid.UUID, status string, format export.Format) {
+	club := exportClub(w, r, h.clubRepo, clubID)
+	if club == nil {
+		return
+	}
+
+	locale := export.LocaleFor(r, club)
+	info := export.Info{Name: "subscriptions", Title: "list.subscriptions"}
+	sendExport(w, format, locale, info, func(d *export.Document) error {
+		err := d.Table("",
+			export.Column{Key: "student"},
+			export.Column{Key: "group"},
+			export.Column{Key: "subscription_kind", Kind: export.Label},
+			export.Column{Key: "subscription_status", Kind: export.Label},
+			export.Column{Key: "total_sessions", Kind: export.Int},
+			export.Column{Key: "remaining_sessions", Kind: export.Int},
+			export.Column{Key: "price", Kind: export.Money},
+			export.Column{Key: "starts_at", Kind: export.Date},
+			export.Column{Key: "expires_at", Kind: export.Date},
+			export.Column{Key: "created_at", Kind: export.Date},
+		)
+		if err != nil {
+			return err
+		}
+
+		return h.subRepo.EachByClubWithDetails(r.Context(), clubID, status, func(s *repository.SubscriptionWithDetails) error {
+			return d.Row(s.StudentName, s.GroupTitle, s.Kind, s.Status, s.TotalSessions, s.RemainingSessions,
+				s.Price, s.StartsAt, s.ExpiresAt, s.CreatedAt)
+		})
+	})
+}
+
 // PUT /api/v1/subscriptions/:id/cancel
 func (h *SubscriptionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
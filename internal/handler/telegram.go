@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/middleware"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/telegram"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+type TelegramHandler struct {
+	telegramRepo *repository.TelegramRepository
+	bot          *telegram.Bot // nil when the bot is not configured
+	secret       string
+	botUsername  string
+	logger       *slog.Logger
+}
+
+func NewTelegramHandler(
+	telegramRepo *repository.TelegramRepository,
+	bot *telegram.Bot,
+	secret string,
+	botUsername string,
+	logger *slog.Logger,
+) *TelegramHandler {
+	return &TelegramHandler{
+		telegramRepo: telegramRepo,
+		bot:          bot,
+		secret:       secret,
+		botUsername:  botUsername,
+		logger:       logger,
+	}
+}
+
+// LinkCodeResponse is a one-time code for linking the bot
+type LinkCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Deep link that opens the bot and sends the code
+	URL string `json:"url,omitempty"`
+}
+
+// POST /api/v1/telegram/link-code
+// The code is sent to the bot as "/start CODE" and works once.
+func (h *TelegramHandler) CreateLinkCode(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	code, err := telegram.NewLinkCode()
+	if err != nil {
+		response.InternalError(w, "failed to create link code")
+		return
+	}
+
+	expiresAt := time.Now().Add(telegram.LinkCodeTTL)
+	if err := h.telegramRepo.CreateLinkCode(r.Context(), userID, telegram.HashLinkCode(code), expiresAt); err != nil {
+		response.InternalError(w, "failed to create link code")
+		return
+	}
+
+	resp := LinkCodeResponse{Code: code, ExpiresAt: expiresAt}
+	if h.botUsername != "" {
+		resp.URL = "https://t.me/" + h.botUsername + "?start=" + code
+	}
+	response.Created(w, resp)
+}
+
+// GET /api/v1/telegram/link
+func (h *TelegramHandler) GetLink(w http.ResponseWriter, r *http.Request) {
+	account, err := h.telegramRepo.GetByUser(r.Context(), middleware.GetUserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "telegram is not linked")
+			return
+		}
+		response.InternalError(w, "failed to get telegram link")
+		return
+	}
+
+	response.OK(w, account)
+}
+
+// DELETE /api/v1/telegram/link
+func (h *TelegramHandler) Unlink(w http.ResponseWriter, r *http.Request) {
+	if err := h.telegramRepo.Unlink(r.Context(), middleware.GetUserID(r.Context())); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "telegram is not linked")
+			return
+		}
+		response.InternalError(w, "failed to unlink telegram")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// POST /api/v1/webhooks/telegram
+// Telegram retries updates that are not answered with 200, so failures
+// are logged and still acknowledged. Updates are only accepted with the
+// configured secret token; without one anybody could act as a coach.
+func (h *TelegramHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if h.bot == nil || h.secret == "" {
+		response.NotFound(w, "telegram webhook is not enabled")
+		return
+	}
+
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(h.secret)) != 1 {
+		response.Unauthorized(w, "invalid secret token")
+		return
+	}
+
+	var update telegram.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		response.BadRequest(w, "invalid update")
+		return
+	}
+
+	if err := h.bot.HandleUpdate(r.Context(), update); err != nil {
+		h.logger.Error("failed to handle telegram update",
+			slog.Int64("update_id", update.UpdateID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
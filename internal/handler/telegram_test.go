@@ -0,0 +1,51 @@
+package handler_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neo/trainer-plus/internal/handler"
+	"github.com/neo/trainer-plus/internal/telegram"
+)
+
+func TestTelegramWebhook_RequiresSecret(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bot := telegram.NewBot(telegram.NewClient("http://telegram.invalid", "token"), nil, nil, nil, nil, nil, nil, logger)
+
+	// A forged callback that would mark attendance as the coach
+	update := `{"update_id":1,"callback_query":{"id":"1","from":{"id":42},"data":"mark"}}`
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   int
+	}{
+		{"no secret configured", "", "", http.StatusNotFound},
+		{"no secret configured, any header", "", "guess", http.StatusNotFound},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"wrong header", "s3cret", "guess", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewTelegramHandler(nil, bot, tt.secret, "trainer_bot", logger)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/telegram", strings.NewReader(update))
+			if tt.header != "" {
+				req.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			h.Webhook(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
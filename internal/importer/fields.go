@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fields a column can be mapped to
+const (
+	FieldStudentName       = "student_name"
+	FieldBirthDate         = "birth_date"
+	FieldNotes             = "notes"
+	FieldParentName        = "parent_name"
+	FieldParentPhone       = "parent_phone"
+	FieldParentEmail       = "parent_email"
+	FieldTags              = "tags"
+	FieldGroup             = "group"
+	FieldTotalSessions     = "total_sessions"
+	FieldRemainingSessions = "remaining_sessions"
+	FieldPrice             = "price"
+	FieldPaid              = "paid"
+	FieldStartsAt          = "starts_at"
+	FieldExpiresAt         = "expires_at"
+)
+
+// CustomFieldPrefix maps a column to a club's custom field of students,
+// e.g. "custom.belt"
+const CustomFieldPrefix = "custom."
+
+// Fields lists every field in the order they are suggested
+var Fields = []string{
+	FieldStudentName, FieldBirthDate, FieldNotes,
+	FieldParentName, FieldParentPhone, FieldParentEmail, FieldTags,
+	FieldGroup, FieldTotalSessions, FieldRemainingSessions, FieldPrice, FieldPaid, FieldStartsAt, FieldExpiresAt,
+}
+
+// synonyms are the normalized header names recognized for each field
+var synonyms = map[string][]string{
+	FieldStudentName:       {"student name", "student", "name", "full name", "фио", "фио ученика", "ученик", "имя", "спортсмен", "оқушы"},
+	FieldBirthDate:         {"birth date", "birthday", "date of birth", "dob", "дата рождения", "др", "туған күні"},
+	FieldNotes:             {"notes", "note", "comment", "comments", "примечание", "примечания", "комментарий", "заметки"},
+	FieldParentName:        {"parent name", "parent", "guardian", "родитель", "фио родителя", "законный представитель", "ата-ана"},
+	FieldParentPhone:       {"parent phone", "phone", "guardian phone", "телефон", "телефон родителя", "контактный телефон", "телефон нөмірі"},
+	FieldParentEmail:       {"parent email", "email", "e-mail", "guardian email", "почта", "эл. почта", "электронная почта"},
+	FieldTags:              {"tags", "tag", "labels", "теги", "метки", "тегтер"},
+	FieldGroup:             {"group", "группа", "топ"},
+	FieldTotalSessions:     {"total sessions", "sessions", "всего занятий", "занятий", "количество занятий"},
+	FieldRemainingSessions: {"remaining sessions", "remaining", "sessions left", "остаток", "осталось занятий", "остаток занятий"},
+	FieldPrice:             {"price", "amount", "стоимость", "цена", "сумма", "бағасы"},
+	FieldPaid:              {"paid", "оплачен", "оплачено", "оплата", "төленді"},
+	FieldStartsAt:          {"starts at", "start", "start date", "начало", "дата начала"},
+	FieldExpiresAt:         {"expires at", "expires", "end date", "valid until", "окончание", "дата окончания", "действует до"},
+}
+
+// Mapping maps field names to headers of the uploaded file
+type Mapping map[string]string
+
+// SuggestMapping maps the headers it recognizes. Each header is used for
+// at most one field.
+func SuggestMapping(headers []string) Mapping {
+	byName := make(map[string]string, len(headers))
+	for _, h := range headers {
+		if n := normalizeHeader(h); byName[n] == "" {
+			byName[n] = h
+		}
+	}
+
+	m := Mapping{}
+	used := map[string]bool{}
+	for _, field := range Fields {
+		for _, s := range synonyms[field] {
+			if h, ok := byName[s]; ok && !used[h] {
+				m[field] = h
+				used[h] = true
+				break
+			}
+		}
+	}
+	return m
+}
+
+// SuggestCustomFields maps the headers still unused that read as the label
+// or key of a custom field. Labels are keyed by the field key.
+func (m Mapping) SuggestCustomFields(headers []string, labels map[string]string) {
+	used := make(map[string]bool, len(m))
+	for _, h := range m {
+		used[h] = true
+	}
+
+	for key, label := range labels {
+		for _, h := range headers {
+			n := normalizeHeader(h)
+			if !used[h] && (n == normalizeHeader(label) || n == normalizeHeader(key)) {
+				m[CustomFieldPrefix+key] = h
+				used[h] = true
+				break
+			}
+		}
+	}
+}
+
+// CustomFields returns the keys of the custom fields mapped to a column
+func (m Mapping) CustomFields() []string {
+	var keys []string
+	for field, header := range m {
+		if key, ok := strings.CutPrefix(field, CustomFieldPrefix); ok && header != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.ReplaceAll(h, "_", " "))
+	h = strings.TrimRight(strings.TrimSpace(h), ":*")
+	return strings.Join(strings.Fields(h), " ")
+}
+
+// Validate checks that the mapping names known fields and existing
+// headers, that the student name is mapped and, when subscriptions are
+// imported, that the group and remaining sessions are mapped too. Custom
+// fields are not checked against the club's; see CustomFields.
+func (m Mapping) Validate(headers []string, withSubscriptions bool) error {
+	known := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		known[h] = true
+	}
+
+	for field, header := range m {
+		_, ok := synonyms[field]
+		if key, custom := strings.CutPrefix(field, CustomFieldPrefix); custom && key != "" {
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if header != "" && !known[header] {
+			return fmt.Errorf("%s: no column %q in the file", field, header)
+		}
+	}
+
+	if m[FieldStudentName] == "" {
+		return fmt.Errorf("%s must be mapped", FieldStudentName)
+	}
+	if withSubscriptions {
+		if m[FieldGroup] == "" || m[FieldRemainingSessions] == "" {
+			return fmt.Errorf("%s and %s must be mapped to import subscriptions", FieldGroup, FieldRemainingSessions)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/importer"
+)
+
+func TestParse_CSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		headers []string
+		rows    []importer.Row
+	}{
+		{
+			name:    "comma",
+			data:    []byte("Name,Phone\nAsel,+7 701 000 00 00\n"),
+			headers: []string{"Name", "Phone"},
+			rows:    []importer.Row{{Number: 2, Cells: []string{"Asel", "+7 701 000 00 00"}}},
+		},
+		{
+			name:    "semicolon with BOM and blank lines",
+			data:    []byte("\xef\xbb\xbfФИО;Группа\n\n Иванов Иван ; Младшая \n;\n"),
+			headers: []string{"ФИО", "Группа"},
+			rows:    []importer.Row{{Number: 3, Cells: []string{"Иванов Иван", "Младшая"}}},
+		},
+		{
+			name:    "tab with quoted comma",
+			data:    []byte("name\tnotes\n\"Li\"\t\"likes a, b\"\n"),
+			headers: []string{"name", "notes"},
+			rows:    []importer.Row{{Number: 2, Cells: []string{"Li", "likes a, b"}}},
+		},
+		{
+			name:    "windows-1251",
+			data:    []byte("\xd4\xc8\xce\n\xc8\xe2\xe0\xed \xb8\n"), // ФИО / Иван ё
+			headers: []string{"ФИО"},
+			rows:    []importer.Row{{Number: 2, Cells: []string{"Иван ё"}}},
+		},
+		{
+			name:    "empty and repeated headers",
+			data:    []byte("name,,name\na,b,c\n"),
+			headers: []string{"name", "Column B", "name (2)"},
+			rows:    []importer.Row{{Number: 2, Cells: []string{"a", "b", "c"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := importer.Parse("students.csv", tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(table.Headers, tt.headers) {
+				t.Errorf("Headers = %q, want %q", table.Headers, tt.headers)
+			}
+			if !reflect.DeepEqual(table.Rows, tt.rows) {
+				t.Errorf("Rows = %q, want %q", table.Rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := importer.Parse("students.xls", []byte("data")); !errors.Is(err, importer.ErrUnsupportedFormat) {
+		t.Errorf("xls: error = %v, want ErrUnsupportedFormat", err)
+	}
+	if _, err := importer.Parse("students.csv", []byte("name,phone\n\n")); !errors.Is(err, importer.ErrEmpty) {
+		t.Errorf("header only: error = %v, want ErrEmpty", err)
+	}
+
+	big := "name\n" + strings.Repeat("x\n", importer.MaxRows+1)
+	if _, err := importer.Parse("students.csv", []byte(big)); !errors.Is(err, importer.ErrTooManyRows) {
+		t.Errorf("too many rows: error = %v, want ErrTooManyRows", err)
+	}
+}
+
+func TestParse_XLSX(t *testing.T) {
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Students" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>ФИО</t></si><si><t>Дата рождения</t></si><si><r><t>Иванов </t></r><r><t>Иван</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>7</v></c><c r="B3"><v>42005</v></c></row>
+			<row r="4"><c r="A4" t="inlineStr"><is><t>Петров</t></is></c></row>
+		</sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := importer.Parse("students.xlsx", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantHeaders := []string{"ФИО", "Дата рождения"}
+	if !reflect.DeepEqual(table.Headers, wantHeaders) {
+		t.Errorf("Headers = %q, want %q", table.Headers, wantHeaders)
+	}
+	wantRows := []importer.Row{
+		{Number: 3, Cells: []string{"Иванов Иван", "42005", "7"}},
+		{Number: 4, Cells: []string{"Петров"}},
+	}
+	if !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Errorf("Rows = %q, want %q", table.Rows, wantRows)
+	}
+}
+
+func TestSuggestMapping(t *testing.T) {
+	headers := []string{"ФИО ученика", "Дата_рождения", "Телефон родителя:", "E-mail", "Группа", "Остаток", "Unknown"}
+
+	got := importer.SuggestMapping(headers)
+	want := importer.Mapping{
+		importer.FieldStudentName:       "ФИО ученика",
+		importer.FieldBirthDate:         "Дата_рождения",
+		importer.FieldParentPhone:       "Телефон родителя:",
+		importer.FieldParentEmail:       "E-mail",
+		importer.FieldGroup:             "Группа",
+		importer.FieldRemainingSessions: "Остаток",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestMapping() = %v, want %v", got, want)
+	}
+}
+
+func TestMapping_SuggestCustomFields(t *testing.T) {
+	headers := []string{"ФИО", "Пояс", "Weight", "Телефон"}
+
+	m := importer.SuggestMapping(headers)
+	m.SuggestCustomFields(headers, map[string]string{"belt": "Пояс", "weight": "Вес", "phone": "Телефон"})
+
+	want := importer.Mapping{
+		importer.FieldStudentName: "ФИО",
+		importer.FieldParentPhone: "Телефон",
+		"custom.belt":             "Пояс",
+		"custom.weight":           "Weight",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("mapping = %v, want %v", m, want)
+	}
+}
+
+func TestMapping_Validate(t *testing.T) {
+	headers := []string{"Name", "Group", "Left"}
+
+	tests := []struct {
+		name    string
+		mapping importer.Mapping
+		subs    bool
+		wantErr bool
+	}{
+		{"name only", importer.Mapping{"student_name": "Name"}, false, false},
+		{"name missing", importer.Mapping{"group": "Group"}, false, true},
+		{"unknown field", importer.Mapping{"student_name": "Name", "shoe_size": "Left"}, false, true},
+		{"unknown header", importer.Mapping{"student_name": "Nom"}, false, true},
+		{"custom field", importer.Mapping{"student_name": "Name", "custom.belt": "Left"}, false, false},
+		{"custom field without key", importer.Mapping{"student_name": "Name", "custom.": "Left"}, false, true},
+		{"subscriptions need group", importer.Mapping{"student_name": "Name", "remaining_sessions": "Left"}, true, true},
+		{"subscriptions", importer.Mapping{"student_name": "Name", "group": "Group", "remaining_sessions": "Left"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mapping.Validate(headers, tt.subs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapping_Record(t *testing.T) {
+	headers := []string{"name", "born", "phone", "email", "group", "total", "left", "price", "paid", "from", "to"}
+	mapping := importer.Mapping{
+		importer.FieldStudentName:       "name",
+		importer.FieldBirthDate:         "born",
+		importer.FieldParentPhone:       "phone",
+		importer.FieldParentEmail:       "email",
+		importer.FieldGroup:             "group",
+		importer.FieldTotalSessions:     "total",
+		importer.FieldRemainingSessions: "left",
+		importer.FieldPrice:             "price",
+		importer.FieldPaid:              "paid",
+		importer.FieldStartsAt:          "from",
+		importer.FieldExpiresAt:         "to",
+	}
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("valid", func(t *testing.T) {
+		cells := []string{" Иванов  Иван ", "05.04.2015", "8 (701) 123-45-67", "p@example.com", "Младшая", "8", "3", "15 000", "нет", "2026-02-01", "2026-03-31"}
+		rec, errs := mapping.Record(headers, cells, true, now)
+		if len(errs) != 0 {
+			t.Fatalf("errors = %q", errs)
+		}
+		if rec.StudentName != "Иванов Иван" || rec.Group != "Младшая" {
+			t.Errorf("name/group = %q/%q", rec.StudentName, rec.Group)
+		}
+		if rec.BirthDate == nil || !rec.BirthDate.Equal(time.Date(2015, 4, 5, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("BirthDate = %v", rec.BirthDate)
+		}
+		if rec.TotalSessions != 8 || rec.RemainingSessions != 3 || rec.Paid {
+			t.Errorf("sessions = %d/%d, paid = %v", rec.RemainingSessions, rec.TotalSessions, rec.Paid)
+		}
+		if rec.Price == nil || *rec.Price != 15000 {
+			t.Errorf("Price = %v", rec.Price)
+		}
+	})
+
+	t.Run("without subscriptions", func(t *testing.T) {
+		cells := []string{"Li", "", "", "", "Младшая", "x"}
+		rec, errs := mapping.Record(headers, cells, false, now)
+		if len(errs) != 0 || rec.HasSubscription() {
+			t.Errorf("errors = %q, HasSubscription = %v", errs, rec.HasSubscription())
+		}
+	})
+
+	t.Run("total defaults to remaining", func(t *testing.T) {
+		rec, errs := mapping.Record(headers, []string{"Li", "", "", "", "A", "", "4"}, true, now)
+		if len(errs) != 0 || rec.TotalSessions != 4 || !rec.Paid {
+			t.Errorf("errors = %q, total = %d, paid = %v", errs, rec.TotalSessions, rec.Paid)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cells := []string{"", "2030-01-01", "123", "not-an-email", "A", "2", "5", "abc", "maybe", "2026-02-01", "2026-01-01"}
+		_, errs := mapping.Record(headers, cells, true, now)
+		want := []string{
+			"student_name: is required",
+			"birth_date: is in the future",
+			`parent_phone: "123" is not a phone number`,
+			`parent_email: "not-an-email" is not an email address`,
+			"total_sessions: is less than the remaining sessions",
+			`price: "abc" is not an amount`,
+			`paid: "maybe" is not yes or no`,
+			"expires_at: is before starts_at",
+		}
+		if !reflect.DeepEqual(errs, want) {
+			t.Errorf("errors = %q\nwant %q", errs, want)
+		}
+	})
+
+	t.Run("tags and custom fields", func(t *testing.T) {
+		headers := []string{"name", "tags", "belt", "weight"}
+		mapping := importer.Mapping{
+			importer.FieldStudentName:             "name",
+			importer.FieldTags:                    "tags",
+			importer.CustomFieldPrefix + "belt":   "belt",
+			importer.CustomFieldPrefix + "weight": "weight",
+		}
+		rec, errs := mapping.Record(headers, []string{"Li", "beginner; team, ,", " yellow ", ""}, false, now)
+		if len(errs) != 0 {
+			t.Fatalf("errors = %q", errs)
+		}
+		if want := []string{"beginner", "team"}; !reflect.DeepEqual(rec.Tags, want) {
+			t.Errorf("Tags = %q, want %q", rec.Tags, want)
+		}
+		if want := map[string]string{"belt": "yellow"}; !reflect.DeepEqual(rec.CustomFields, want) {
+			t.Errorf("CustomFields = %q, want %q", rec.CustomFields, want)
+		}
+	})
+
+	t.Run("missing remaining sessions", func(t *testing.T) {
+		_, errs := mapping.Record(headers, []string{"Li", "", "", "", "A"}, true, now)
+		if len(errs) != 1 || !strings.HasPrefix(errs[0], "remaining_sessions:") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2015-01-02", "02.01.2015", "2.1.2015", "02/01/2015", "2015-01-02 00:00:00", "42006"} {
+		got, err := importer.ParseDate(s)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"tomorrow", "31.02.2015", "12", "2015-13-01"} {
+		if _, err := importer.ParseDate(s); err == nil {
+			t.Errorf("ParseDate(%q) error = nil", s)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]float64{
+		"15000":       15000,
+		"15 000":      15000,
+		"15 000,50 ₸": 15000.5,
+		"15,000":      15000,
+		"15,000.25":   15000.25,
+		"15.000,25":   15000.25,
+		"1,5":         1.5,
+		"$12.345":     12.35,
+	}
+	for s, want := range tests {
+		got, err := importer.ParseAmount(s)
+		if err != nil || got != want {
+			t.Errorf("ParseAmount(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "free", "-100"} {
+		if _, err := importer.ParseAmount(s); err == nil {
+			t.Errorf("ParseAmount(%q) error = nil", s)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"+7 (701) 123-45-67": "77011234567",
+		"8 701 123 45 67":    "77011234567",
+		"7011234567":         "77011234567",
+		"+44 20 7946 0958":   "442079460958",
+		"":                   "",
+	}
+	for in, want := range tests {
+		if got := importer.NormalizePhone(in); got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	born := time.Date(2015, 4, 5, 0, 0, 0, 0, time.UTC)
+	other := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	existing := uuid.New()
+
+	m := importer.NewMatcher()
+	m.Add(importer.Person{StudentID: existing, Name: "Ёлкин Артём", BirthDate: &born, Phone: "+7 701 123 45 67"})
+
+	tests := []struct {
+		name   string
+		person importer.Person
+		want   bool
+	}{
+		{"same name and birth date", importer.Person{Name: "елкин  артем", BirthDate: &born}, true},
+		{"same name and phone", importer.Person{Name: "Ёлкин Артём", Phone: "87011234567"}, true},
+		{"same name only", importer.Person{Name: "Ёлкин Артём"}, false},
+		{"same name, other birth date", importer.Person{Name: "Ёлкин Артём", BirthDate: &other}, false},
+		{"other name, same phone", importer.Person{Name: "Ёлкина Анна", Phone: "87011234567"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Find(tt.person)
+			if ok != tt.want {
+				t.Fatalf("Find() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && got.StudentID != existing {
+				t.Errorf("Find() = %v, want %v", got.StudentID, existing)
+			}
+		})
+	}
+}
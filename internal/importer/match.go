@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Person is what duplicates are detected by
+type Person struct {
+	// Existing student, or zero for a row of the file
+	StudentID uuid.UUID
+	// Row number for a row of the file
+	Row       int
+	Name      string
+	BirthDate *time.Time
+	Phone     string
+}
+
+// Matcher finds people with the same name and the same birth date or
+// parent phone. A shared name alone is not a duplicate: siblings and
+// namesakes are common.
+type Matcher struct {
+	byName map[string][]Person
+}
+
+func NewMatcher() *Matcher {
+	return &Matcher{byName: map[string][]Person{}}
+}
+
+func (m *Matcher) Add(p Person) {
+	key := NormalizeName(p.Name)
+	m.byName[key] = append(m.byName[key], p)
+}
+
+// Find returns the first person added that matches p
+func (m *Matcher) Find(p Person) (Person, bool) {
+	phone := NormalizePhone(p.Phone)
+	for _, other := range m.byName[NormalizeName(p.Name)] {
+		if p.BirthDate != nil && other.BirthDate != nil && sameDay(*p.BirthDate, *other.BirthDate) {
+			return other, true
+		}
+		if phone != "" && phone == NormalizePhone(other.Phone) {
+			return other, true
+		}
+	}
+	return Person{}, false
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,208 @@
+// Package importer turns uploaded CSV and XLSX files of students into
+// validated records: it parses the file, maps its columns to fields and
+// finds rows matching existing students.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neo/trainer-plus/pkg/xlsx"
+)
+
+// Upload limits
+const (
+	MaxFileSize = 10 << 20
+	MaxRows     = 10000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format, upload .csv or .xlsx")
+	ErrEmpty             = errors.New("file has no rows below the header")
+	ErrTooManyRows       = fmt.Errorf("file has more than %d rows", MaxRows)
+)
+
+// Table is the content of an uploaded file
+type Table struct {
+	// Unique, non-empty column names from the header row
+	Headers []string
+	Rows    []Row
+}
+
+// Row is a non-blank row below the header
+type Row struct {
+	// Line of the row in the file, counting the header as 1 when it is
+	// the first line
+	Number int
+	Cells  []string
+}
+
+// Parse reads a CSV or XLSX file. The first non-blank row is the header;
+// blank rows are dropped and cells are trimmed.
+func Parse(filename string, data []byte) (*Table, error) {
+	var rows [][]string
+	var err error
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".xlsx" || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		rows, err = xlsx.ReadRows(bytes.NewReader(data), int64(len(data)))
+	case ext == ".csv" || ext == ".txt":
+		rows, err = readCSV(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newTable(rows)
+}
+
+func newTable(rows [][]string) (*Table, error) {
+	t := &Table{}
+	header := -1
+	for i, cells := range rows {
+		for j := range cells {
+			cells[j] = strings.TrimSpace(cells[j])
+		}
+		if isBlank(cells) {
+			continue
+		}
+		if header < 0 {
+			header = i
+			t.Headers = uniqueHeaders(cells)
+			continue
+		}
+		if len(t.Rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		t.Rows = append(t.Rows, Row{Number: i + 1, Cells: cells})
+	}
+
+	if len(t.Rows) == 0 {
+		return nil, ErrEmpty
+	}
+	return t, nil
+}
+
+func isBlank(cells []string) bool {
+	for _, c := range cells {
+		if c != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueHeaders names empty headers after their column and numbers
+// repeated ones, so every column can be mapped by name
+func uniqueHeaders(cells []string) []string {
+	headers := make([]string, len(cells))
+	seen := make(map[string]int, len(cells))
+	for i, h := range cells {
+		if h == "" {
+			h = "Column " + xlsx.ColumnName(i)
+		}
+		seen[h]++
+		if n := seen[h]; n > 1 {
+			h = fmt.Sprintf("%s (%d)", h, n)
+		}
+		headers[i] = h
+	}
+	return headers
+}
+
+// readCSV reads comma, semicolon or tab separated values. Excel saves CSV
+// with a byte order mark, or in Windows-1251 on Russian systems.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		data = decodeWindows1251(data)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = detectDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows, nil
+			}
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+		// The header row plus MaxRows rows, with some room for blank ones
+		if len(rows) > 2*MaxRows {
+			return nil, ErrTooManyRows
+		}
+		// Keep rows at the index of their line; the reader skips blank
+		// lines and quoted values may span several
+		line, _ := r.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, record)
+	}
+}
+
+// detectDelimiter picks the separator used most on the first line outside
+// quotes
+func detectDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+
+	counts := map[rune]int{}
+	quoted := false
+	for _, c := range string(line) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ';' || c == '\t'):
+			counts[c]++
+		}
+	}
+
+	best := ','
+	for _, d := range []rune{';', '\t'} {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+// windows1251 maps bytes 0x80-0xBF; 0xC0-0xFF are А-я in order
+var windows1251 = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', utf8.RuneError, '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+func decodeWindows1251(data []byte) []byte {
+	var b strings.Builder
+	b.Grow(len(data) * 2)
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xC0:
+			b.WriteRune(windows1251[c-0x80])
+		default:
+			b.WriteRune(rune(c-0xC0) + 'А')
+		}
+	}
+	return []byte(b.String())
+}
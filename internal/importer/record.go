@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Record is a row read through a mapping
+type Record struct {
+	StudentName string
+	BirthDate   *time.Time
+	Notes       string
+	ParentName  string
+	ParentPhone string
+	ParentEmail string
+	Tags        []string
+	// CustomFields holds the non-empty cells of custom field columns by
+	// field key, as written
+	CustomFields map[string]string
+
+	// Subscription; Group is empty for rows without one
+	Group             string
+	TotalSessions     int
+	RemainingSessions int
+	Price             *float64 // nil: the group price
+	Paid              bool
+	StartsAt          *time.Time
+	ExpiresAt         *time.Time
+}
+
+// HasSubscription reports whether the row carries a subscription
+func (r *Record) HasSubscription() bool {
+	return r.Group != ""
+}
+
+// Record reads the cells of a row. It returns every problem found, each
+// prefixed with the field name, and a record that is only usable when
+// there are none. Subscription fields are read when withSubscriptions is
+// set. Dates in the future are rejected for birth dates only.
+func (m Mapping) Record(headers []string, cells []string, withSubscriptions bool, now time.Time) (Record, []string) {
+	col := make(map[string]int, len(headers))
+	for i, h := range headers {
+		col[h] = i
+	}
+	value := func(field string) string {
+		i, ok := col[m[field]]
+		if !ok || i >= len(cells) {
+			return ""
+		}
+		return strings.TrimSpace(cells[i])
+	}
+
+	var rec Record
+	var errs []string
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	rec.StudentName = strings.Join(strings.Fields(value(FieldStudentName)), " ")
+	switch n := utf8.RuneCountInString(rec.StudentName); {
+	case n == 0:
+		fail(FieldStudentName, "is required")
+	case n > 255:
+		fail(FieldStudentName, "is longer than 255 characters")
+	}
+
+	if v := value(FieldBirthDate); v != "" {
+		if d, err := ParseDate(v); err != nil {
+			fail(FieldBirthDate, "%v", err)
+		} else if d.After(now) {
+			fail(FieldBirthDate, "is in the future")
+		} else {
+			rec.BirthDate = &d
+		}
+	}
+
+	rec.Notes = value(FieldNotes)
+	rec.ParentName = strings.Join(strings.Fields(value(FieldParentName)), " ")
+
+	rec.ParentPhone = value(FieldParentPhone)
+	if rec.ParentPhone != "" {
+		if n := len(NormalizePhone(rec.ParentPhone)); n < 10 || n > 15 {
+			fail(FieldParentPhone, "%q is not a phone number", rec.ParentPhone)
+		}
+	}
+
+	rec.ParentEmail = value(FieldParentEmail)
+	if rec.ParentEmail != "" {
+		if addr, err := mail.ParseAddress(rec.ParentEmail); err != nil || addr.Address != rec.ParentEmail {
+			fail(FieldParentEmail, "%q is not an email address", rec.ParentEmail)
+		}
+	}
+
+	if v := value(FieldTags); v != "" {
+		for _, tag := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				rec.Tags = append(rec.Tags, tag)
+			}
+		}
+	}
+
+	for _, key := range m.CustomFields() {
+		if v := value(CustomFieldPrefix + key); v != "" {
+			if rec.CustomFields == nil {
+				rec.CustomFields = map[string]string{}
+			}
+			rec.CustomFields[key] = v
+		}
+	}
+
+	if !withSubscriptions {
+		return rec, errs
+	}
+
+	rec.Group = strings.Join(strings.Fields(value(FieldGroup)), " ")
+	if rec.Group == "" {
+		return rec, errs
+	}
+
+	remaining, ok := parseCount(value(FieldRemainingSessions))
+	switch {
+	case value(FieldRemainingSessions) == "":
+		fail(FieldRemainingSessions, "is required for a subscription")
+	case !ok:
+		fail(FieldRemainingSessions, "%q is not a number of sessions", value(FieldRemainingSessions))
+	default:
+		rec.RemainingSessions = remaining
+	}
+
+	rec.TotalSessions = rec.RemainingSessions
+	if v := value(FieldTotalSessions); v != "" {
+		if total, ok := parseCount(v); !ok || total == 0 {
+			fail(FieldTotalSessions, "%q is not a number of sessions", v)
+		} else if total < rec.RemainingSessions {
+			fail(FieldTotalSessions, "is less than the remaining sessions")
+		} else {
+			rec.TotalSessions = total
+		}
+	}
+	if rec.TotalSessions == 0 && ok {
+		fail(FieldTotalSessions, "is required when no sessions remain")
+	}
+
+	if v := value(FieldPrice); v != "" {
+		if price, err := ParseAmount(v); err != nil {
+			fail(FieldPrice, "%v", err)
+		} else {
+			rec.Price = &price
+		}
+	}
+
+	rec.Paid = true
+	if v := value(FieldPaid); v != "" {
+		if paid, err := ParseBool(v); err != nil {
+			fail(FieldPaid, "%v", err)
+		} else {
+			rec.Paid = paid
+		}
+	}
+
+	if v := value(FieldStartsAt); v != "" {
+		if d, err := ParseDate(v); err != nil {
+			fail(FieldStartsAt, "%v", err)
+		} else {
+			rec.StartsAt = &d
+		}
+	}
+	if v := value(FieldExpiresAt); v != "" {
+		if d, err := ParseDate(v); err != nil {
+			fail(FieldExpiresAt, "%v", err)
+		} else if rec.StartsAt != nil && d.Before(*rec.StartsAt) {
+			fail(FieldExpiresAt, "is before starts_at")
+		} else {
+			rec.ExpiresAt = &d
+		}
+	}
+
+	return rec, errs
+}
+
+func parseCount(s string) (int, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 || f != math.Trunc(f) || f > 10000 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// dateLayouts are tried in order; day-first as written in ru and kk locales
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02.01.2006",
+	"2.1.2006",
+	"02.01.06",
+	"02/01/2006",
+	"2/1/2006",
+}
+
+// excelEpoch is day zero of Excel serial dates (1900 date system)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseDate reads a date in one of the common layouts or as an Excel
+// serial day, which is how XLSX stores date cells
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	// Serial days between 1910 and 2100; time of day is dropped
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 3654 && f < 73051 {
+		return excelEpoch.AddDate(0, 0, int(f)), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date, use YYYY-MM-DD or DD.MM.YYYY", s)
+}
+
+// ParseAmount reads a non-negative amount written with spaces or commas
+// between thousands, a comma or dot as decimal separator and an optional
+// currency sign
+func ParseAmount(s string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == ',' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+
+	dot := strings.LastIndex(cleaned, ".")
+	comma := strings.LastIndex(cleaned, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		// The last separator is the decimal one
+		if comma > dot {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		} else {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		}
+	case comma >= 0:
+		// 1,5 is a decimal; 15,000 separates thousands
+		if strings.Count(cleaned, ",") == 1 && len(cleaned)-comma-1 <= 2 {
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		} else {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		}
+	}
+
+	f, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%q is not an amount", s)
+	}
+	return math.Round(f*100) / 100, nil
+}
+
+var boolValues = map[string]bool{
+	"yes": true, "y": true, "true": true, "1": true, "+": true, "да": true, "д": true, "оплачен": true, "оплачено": true, "иә": true,
+	"no": false, "n": false, "false": false, "0": false, "-": false, "нет": false, "н": false, "не оплачен": false, "жоқ": false,
+}
+
+// ParseBool reads yes/no answers in English, Russian and Kazakh
+func ParseBool(s string) (bool, error) {
+	b, ok := boolValues[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return false, fmt.Errorf("%q is not yes or no", s)
+	}
+	return b, nil
+}
+
+// NormalizePhone keeps the digits of a phone number, writing the local
+// 8 prefix of Kazakhstan and Russia as the country code 7
+func NormalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+
+	switch {
+	case len(digits) == 11 && digits[0] == '8':
+		return "7" + digits[1:]
+	case len(digits) == 10 && digits[0] != '0':
+		return "7" + digits
+	}
+	return digits
+}
+
+// NormalizeName makes names comparable: case, repeated spaces and ё are
+// ignored
+func NormalizeName(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.ReplaceAll(s, "ё", "е")
+}
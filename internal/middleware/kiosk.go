@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/pkg/response"
+)
+
+const KioskDeviceKey contextKey = "kiosk_device"
+
+// DeviceTokenHeader carries the kiosk device token
+const DeviceTokenHeader = "X-Device-Token"
+
+// DeviceLookup resolves a device token to an active kiosk device
+type DeviceLookup func(ctx context.Context, token string) (*model.KioskDevice, error)
+
+// KioskAuth authenticates check-in kiosks by their device token instead of
+// a user session
+func KioskAuth(lookup DeviceLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(DeviceTokenHeader)
+			if token == "" {
+				response.Unauthorized(w, "missing device token")
+				return
+			}
+
+			device, err := lookup(r.Context(), token)
+			if err != nil {
+				response.Unauthorized(w, "invalid or revoked device token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), KioskDeviceKey, device)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func GetKioskDevice(ctx context.Context) *model.KioskDevice {
+	if device, ok := ctx.Value(KioskDeviceKey).(*model.KioskDevice); ok {
+		return device
+	}
+	return nil
+}
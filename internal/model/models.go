@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,7 +39,38 @@ type Club struct {
 	Address     string    `db:"address" json:"address,omitempty"`
 	Phone       string    `db:"phone" json:"phone,omitempty"`
 	Currency    string    `db:"currency" json:"currency"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	Timezone    string    `db:"timezone" json:"timezone"`
+	// MakeupValidDays is how long an excused absence can be made up;
+	// 0 disables makeup credits
+	MakeupValidDays int `db:"makeup_valid_days" json:"makeup_valid_days"`
+	// TrialVisitsPerGroup is how many free trials a student may take in
+	// each group; 0 disables trials
+	TrialVisitsPerGroup int       `db:"trial_visits_per_group" json:"trial_visits_per_group"`
+	CreatedAt           time.Time `db:"created_at" json:"created_at"`
+	// ArchivedAt is set while the club is archived
+	ArchivedAt *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+}
+
+// DefaultTimezone is used for clubs created before timezones were stored
+const DefaultTimezone = "UTC"
+
+// DefaultMakeupValidDays is the makeup credit lifetime of new clubs
+const DefaultMakeupValidDays = 30
+
+// DefaultTrialVisitsPerGroup is the trial allowance of new clubs
+const DefaultTrialVisitsPerGroup = 1
+
+// Location returns the club's IANA time zone, falling back to UTC
+// when the stored name is empty or unknown to the tz database.
+func (c *Club) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 type Group struct {
@@ -49,18 +82,137 @@ type Group struct {
 	Price       float64    `db:"price" json:"price"`
 	Description string     `db:"description" json:"description,omitempty"`
 	CoachUserID *uuid.UUID `db:"coach_user_id" json:"coach_user_id,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LocationID  *uuid.UUID `db:"location_id" json:"location_id,omitempty"`
+	// DropInPrice is charged for a single visit; nil means no drop-ins
+	DropInPrice  *float64     `db:"drop_in_price" json:"drop_in_price,omitempty"`
+	Tags         []string     `db:"-" json:"tags"`
+	CustomFields CustomValues `db:"-" json:"custom_fields"`
+	CreatedAt    time.Time    `db:"created_at" json:"created_at"`
+	ArchivedAt   *time.Time   `db:"archived_at" json:"archived_at,omitempty"`
+}
+
+// Location is a hall or other venue of a club
+type Location struct {
+	ID           uuid.UUID    `db:"id" json:"id"`
+	ClubID       uuid.UUID    `db:"club_id" json:"club_id"`
+	Name         string       `db:"name" json:"name"`
+	Address      *string      `db:"address" json:"address,omitempty"`
+	Capacity     *int         `db:"capacity" json:"capacity,omitempty"`
+	OpeningHours OpeningHours `db:"-" json:"opening_hours"`
+	Resources    []string     `db:"-" json:"resources"` // e.g. "mats", "ring"
+	CreatedAt    time.Time    `db:"created_at" json:"created_at"`
+}
+
+// OpeningHours maps a weekday code ("mon" ... "sun") to the hours the
+// location is open that day. Missing days are closed.
+type OpeningHours map[string]DayHours
+
+type DayHours struct {
+	Open  string `json:"open"`  // "08:00"
+	Close string `json:"close"` // "22:00"
+}
+
+// OpeningWeekdays lists the valid OpeningHours keys in week order
+var OpeningWeekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// Validate checks weekday keys and that every day closes after it opens
+func (h OpeningHours) Validate() error {
+	for day, hours := range h {
+		if !slices.Contains(OpeningWeekdays, day) {
+			return fmt.Errorf("unknown weekday %q, use mon..sun", day)
+		}
+		if _, err := hours.Duration(); err != nil {
+			return fmt.Errorf("%s: %w", day, err)
+		}
+	}
+	return nil
+}
+
+// WeeklyHours returns how many hours per week the location is open
+func (h OpeningHours) WeeklyHours() float64 {
+	var total time.Duration
+	for _, hours := range h {
+		if d, err := hours.Duration(); err == nil {
+			total += d
+		}
+	}
+	return total.Hours()
+}
+
+// Duration returns how long the location is open that day. Close may be
+// "24:00" for midnight.
+func (d DayHours) Duration() (time.Duration, error) {
+	open, err := time.Parse("15:04", d.Open)
+	if err != nil {
+		return 0, fmt.Errorf("open must be HH:MM")
+	}
+	var close time.Time
+	if d.Close == "24:00" {
+		close = time.Date(0, 1, 2, 0, 0, 0, 0, time.UTC)
+	} else if close, err = time.Parse("15:04", d.Close); err != nil {
+		return 0, fmt.Errorf("close must be HH:MM")
+	}
+	if !close.After(open) {
+		return 0, fmt.Errorf("close must be after open")
+	}
+	return close.Sub(open), nil
 }
 
 type Session struct {
-	ID              uuid.UUID `db:"id" json:"id"`
-	GroupID         uuid.UUID `db:"group_id" json:"group_id"`
-	StartAt         time.Time `db:"start_at" json:"start_at"`
-	DurationMinutes int       `db:"duration_minutes" json:"duration_minutes"`
-	Location        string    `db:"location" json:"location,omitempty"`
-	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	ID              uuid.UUID  `db:"id" json:"id"`
+	GroupID         uuid.UUID  `db:"group_id" json:"group_id"`
+	StartAt         time.Time  `db:"start_at" json:"start_at"`
+	DurationMinutes int        `db:"duration_minutes" json:"duration_minutes"`
+	Location        string     `db:"location" json:"location,omitempty"`
+	LocationID      *uuid.UUID `db:"location_id" json:"location_id,omitempty"`
+	SeriesID        *uuid.UUID `db:"series_id" json:"series_id,omitempty"`
+	OccurrenceDate  *time.Time `db:"occurrence_date" json:"occurrence_date,omitempty"`
+	IsException     bool       `db:"is_exception" json:"is_exception,omitempty"`
+	Status          string     `db:"status" json:"status"`
+	CancelReason    *string    `db:"cancel_reason" json:"cancel_reason,omitempty"`
+	CancelledAt     *time.Time `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	RescheduledFrom *time.Time `db:"rescheduled_from" json:"rescheduled_from,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
 }
 
+type SessionStatus string
+
+const (
+	SessionScheduled SessionStatus = "scheduled"
+	SessionCancelled SessionStatus = "cancelled"
+)
+
+// IsCancelled reports whether the session was called off
+func (s *Session) IsCancelled() bool {
+	return s.Status == string(SessionCancelled)
+}
+
+// ScheduleSeries is a persisted recurring schedule for a group. Dates and
+// StartTime are in the club's time zone; sessions are materialised from it.
+type ScheduleSeries struct {
+	ID                uuid.UUID  `db:"id" json:"id"`
+	GroupID           uuid.UUID  `db:"group_id" json:"group_id"`
+	RRule             string     `db:"rrule" json:"rrule"`
+	StartsOn          time.Time  `db:"starts_on" json:"starts_on"`
+	EndsOn            *time.Time `db:"ends_on" json:"ends_on,omitempty"`
+	StartTime         string     `db:"start_time" json:"start_time"` // "18:00"
+	DurationMinutes   int        `db:"duration_minutes" json:"duration_minutes"`
+	Location          string     `db:"location" json:"location,omitempty"`
+	LocationID        *uuid.UUID `db:"location_id" json:"location_id,omitempty"`
+	ExDates           []string   `db:"-" json:"exdates,omitempty"` // "2006-01-02"
+	MaterializedUntil *time.Time `db:"materialized_until" json:"materialized_until,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+}
+
+// EditScope selects which occurrences of a series an edit applies to
+type EditScope string
+
+const (
+	EditThis      EditScope = "this"
+	EditFollowing EditScope = "following"
+	EditAll       EditScope = "all"
+)
+
 type Student struct {
 	ID            uuid.UUID      `db:"id" json:"id"`
 	ClubID        uuid.UUID      `db:"club_id" json:"club_id"`
@@ -68,13 +220,24 @@ type Student struct {
 	BirthDate     *time.Time     `db:"birth_date" json:"birth_date,omitempty"`
 	ParentContact *ParentContact `db:"parent_contact" json:"parent_contact,omitempty"`
 	Notes         string         `db:"notes" json:"notes,omitempty"`
+	Tags          []string       `db:"-" json:"tags"`
+	CustomFields  CustomValues   `db:"-" json:"custom_fields"`
 	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	ArchivedAt    *time.Time     `db:"archived_at" json:"archived_at,omitempty"`
+	// ErasedAt is set once the student's personal data has been erased
+	ErasedAt *time.Time `db:"erased_at" json:"erased_at,omitempty"`
 }
 
 type ParentContact struct {
 	Name  string `json:"name,omitempty"`
 	Phone string `json:"phone,omitempty"`
 	Email string `json:"email,omitempty"`
+
+	// Notification preferences. Without channels the guardian is notified
+	// by email, or by SMS when there is no email.
+	Language       string   `json:"language,omitempty"` // ru, kk or en
+	Channels       []string `json:"channels,omitempty"` // email, sms, telegram
+	TelegramChatID string   `json:"telegram_chat_id,omitempty"`
 }
 
 type Subscription struct {
@@ -87,6 +250,7 @@ type Subscription struct {
 	StartsAt          *time.Time `db:"starts_at" json:"starts_at,omitempty"`
 	ExpiresAt         *time.Time `db:"expires_at" json:"expires_at,omitempty"`
 	Status            string     `db:"status" json:"status"`
+	Kind              string     `db:"kind" json:"kind"`
 	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
 }
 
@@ -100,12 +264,23 @@ const (
 	SubscriptionCancelled SubscriptionStatus = "cancelled"
 )
 
+type SubscriptionKind string
+
+const (
+	SubscriptionPackage SubscriptionKind = "package"
+	// SubscriptionDropIn is the one-off charge of a drop-in visit
+	SubscriptionDropIn SubscriptionKind = "drop_in"
+)
+
 type Attendance struct {
 	ID             uuid.UUID  `db:"id" json:"id"`
 	SessionID      uuid.UUID  `db:"session_id" json:"session_id"`
 	StudentID      uuid.UUID  `db:"student_id" json:"student_id"`
 	SubscriptionID *uuid.UUID `db:"subscription_id" json:"subscription_id,omitempty"`
+	MakeupCreditID *uuid.UUID `db:"makeup_credit_id" json:"makeup_credit_id,omitempty"`
+	KioskDeviceID  *uuid.UUID `db:"kiosk_device_id" json:"kiosk_device_id,omitempty"`
 	Status         string     `db:"status" json:"status"`
+	Kind           string     `db:"kind" json:"kind"`
 	NotedBy        uuid.UUID  `db:"noted_by" json:"noted_by,omitempty"`
 	NotedAt        time.Time  `db:"noted_at" json:"noted_at"`
 }
@@ -118,6 +293,41 @@ const (
 	AttendanceExcused AttendanceStatus = "excused"
 )
 
+// AttendanceKind says how a visit is paid for
+type AttendanceKind string
+
+const (
+	AttendanceRegular AttendanceKind = "regular"
+	AttendanceTrial   AttendanceKind = "trial"
+	AttendanceDropIn  AttendanceKind = "drop_in"
+)
+
+// KioskDevice is a check-in terminal of a club. Without a location it
+// accepts check-ins for any session of the club.
+type KioskDevice struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	ClubID     uuid.UUID  `db:"club_id" json:"club_id"`
+	LocationID *uuid.UUID `db:"location_id" json:"location_id,omitempty"`
+	Name       string     `db:"name" json:"name"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	CreatedBy  uuid.UUID  `db:"created_by" json:"created_by"`
+	LastSeenAt *time.Time `db:"last_seen_at" json:"last_seen_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// MakeupCredit is earned by an excused absence and lets the student attend
+// one session of the same or an allowed group before it expires
+type MakeupCredit struct {
+	ID                 uuid.UUID  `db:"id" json:"id"`
+	StudentID          uuid.UUID  `db:"student_id" json:"student_id"`
+	SourceGroupID      uuid.UUID  `db:"source_group_id" json:"source_group_id"`
+	SourceAttendanceID uuid.UUID  `db:"source_attendance_id" json:"source_attendance_id"`
+	ExpiresAt          time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt             *time.Time `db:"used_at" json:"used_at,omitempty"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+}
+
 type Payment struct {
 	ID                uuid.UUID              `db:"id" json:"id"`
 	SubscriptionID    uuid.UUID              `db:"subscription_id" json:"subscription_id"`
@@ -147,3 +357,563 @@ const (
 	PaymentCash   PaymentMethod = "cash"
 	PaymentManual PaymentMethod = "manual"
 )
+
+// Notification is a queued message to one recipient over one channel
+type Notification struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	ClubID        uuid.UUID  `db:"club_id" json:"club_id"`
+	StudentID     *uuid.UUID `db:"student_id" json:"student_id,omitempty"`
+	Event         string     `db:"event" json:"event"`
+	Channel       string     `db:"channel" json:"channel"`
+	Recipient     string     `db:"recipient" json:"recipient"`
+	Language      string     `db:"language" json:"language"`
+	Subject       string     `db:"subject" json:"subject,omitempty"`
+	Body          string     `db:"body" json:"body"`
+	DedupeKey     *string    `db:"dedupe_key" json:"-"`
+	Status        string     `db:"status" json:"status"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	LastError     *string    `db:"last_error" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	SentAt        *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"
+)
+
+// ReminderSettings are a club's delivery settings for reminders
+type ReminderSettings struct {
+	ClubID uuid.UUID `db:"club_id" json:"club_id"`
+	// Local "HH:MM"; may wrap midnight, e.g. 22:00-08:00
+	QuietHoursStart *string   `db:"quiet_hours_start" json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string   `db:"quiet_hours_end" json:"quiet_hours_end,omitempty"`
+	Channels        []string  `db:"-" json:"channels"` // empty: guardian's preference
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ReminderRule enables one kind of reminder for a club
+type ReminderRule struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	ClubID    uuid.UUID `db:"club_id" json:"club_id"`
+	Kind      string    `db:"kind" json:"kind"`
+	Enabled   bool      `db:"enabled" json:"enabled"`
+	Threshold int       `db:"threshold" json:"threshold"`
+	Channels  []string  `db:"-" json:"channels"` // empty: club channels
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ReminderKind is what a reminder rule watches. The unit of the rule's
+// threshold depends on the kind.
+type ReminderKind string
+
+const (
+	ReminderLowBalance      ReminderKind = "low_balance"         // remaining sessions
+	ReminderExpiring        ReminderKind = "expiring"            // days before expiry
+	ReminderUpcomingSession ReminderKind = "upcoming_session"    // hours before start
+	ReminderUnpaidDebt      ReminderKind = "unpaid_debt"         // days pending
+	ReminderMedical         ReminderKind = "medical_certificate" // days before the certificate expires
+)
+
+// ReminderKinds lists every reminder kind
+var ReminderKinds = []ReminderKind{ReminderLowBalance, ReminderExpiring, ReminderUpcomingSession, ReminderUnpaidDebt, ReminderMedical}
+
+// DefaultThreshold is the threshold of a rule the club has not configured
+func (k ReminderKind) DefaultThreshold() int {
+	switch k {
+	case ReminderLowBalance:
+		return 2
+	case ReminderExpiring:
+		return 5
+	case ReminderUpcomingSession:
+		return 2
+	case ReminderUnpaidDebt:
+		return 7
+	case ReminderMedical:
+		return 14
+	default:
+		return 1
+	}
+}
+
+// TelegramAccount links a user to the Telegram chat of the coach bot
+type TelegramAccount struct {
+	UserID   uuid.UUID `db:"user_id" json:"user_id"`
+	ChatID   int64     `db:"chat_id" json:"chat_id"`
+	Username *string   `db:"username" json:"username,omitempty"`
+	LinkedAt time.Time `db:"linked_at" json:"linked_at"`
+}
+
+// Import is an uploaded file of students being validated and committed
+// by the import worker
+type Import struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	ClubID    uuid.UUID  `db:"club_id" json:"club_id"`
+	CreatedBy *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	Filename  string     `db:"filename" json:"filename"`
+	Headers   []string   `db:"-" json:"headers"`
+	// Field name -> header of the uploaded file
+	Mapping              map[string]string `db:"-" json:"mapping"`
+	Options              ImportOptions     `db:"-" json:"options"`
+	Status               string            `db:"status" json:"status"`
+	TotalRows            int               `db:"total_rows" json:"total_rows"`
+	ProcessedRows        int               `db:"processed_rows" json:"processed_rows"`
+	ValidRows            int               `db:"valid_rows" json:"valid_rows"`
+	InvalidRows          int               `db:"invalid_rows" json:"invalid_rows"`
+	DuplicateRows        int               `db:"duplicate_rows" json:"duplicate_rows"`
+	CreatedStudents      int               `db:"created_students" json:"created_students"`
+	CreatedSubscriptions int               `db:"created_subscriptions" json:"created_subscriptions"`
+	Error                *string           `db:"error" json:"error,omitempty"`
+	LockedUntil          *time.Time        `db:"locked_until" json:"-"`
+	CreatedAt            time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time         `db:"updated_at" json:"updated_at"`
+	CompletedAt          *time.Time        `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// ImportOptions control how an import is committed
+type ImportOptions struct {
+	// Create a subscription for rows with a group
+	CreateSubscriptions bool `json:"create_subscriptions"`
+	// What to do with rows matching an existing student
+	OnDuplicate string `json:"on_duplicate"`
+}
+
+type ImportStatus string
+
+const (
+	ImportUploaded   ImportStatus = "uploaded"
+	ImportValidating ImportStatus = "validating"
+	ImportValidated  ImportStatus = "validated"
+	ImportCommitting ImportStatus = "committing"
+	ImportCompleted  ImportStatus = "completed"
+	ImportFailed     ImportStatus = "failed"
+)
+
+// DuplicateAction is what an import does with a row matching an existing
+// student
+type DuplicateAction string
+
+const (
+	DuplicateSkip     DuplicateAction = "skip"     // leave the row out
+	DuplicateCreate   DuplicateAction = "create"   // create another student
+	DuplicateExisting DuplicateAction = "existing" // add the subscription to the existing student
+)
+
+// ImportRow is one row of an uploaded file and the outcome of its last
+// validation or commit
+type ImportRow struct {
+	ImportID       uuid.UUID  `db:"import_id" json:"-"`
+	RowNumber      int        `db:"row_number" json:"row_number"`
+	Data           []string   `db:"-" json:"data"`
+	Status         string     `db:"status" json:"status"`
+	Errors         []string   `db:"-" json:"errors"`
+	DuplicateOf    *uuid.UUID `db:"duplicate_of" json:"duplicate_of,omitempty"`
+	StudentID      *uuid.UUID `db:"student_id" json:"student_id,omitempty"`
+	SubscriptionID *uuid.UUID `db:"subscription_id" json:"subscription_id,omitempty"`
+}
+
+type ImportRowStatus string
+
+const (
+	ImportRowPending   ImportRowStatus = "pending"
+	ImportRowValid     ImportRowStatus = "valid"
+	ImportRowInvalid   ImportRowStatus = "invalid"
+	ImportRowDuplicate ImportRowStatus = "duplicate"
+	ImportRowCreated   ImportRowStatus = "created" // a new student
+	ImportRowLinked    ImportRowStatus = "linked"  // subscription for an existing student
+	ImportRowSkipped   ImportRowStatus = "skipped"
+)
+
+// StudentMerge records a duplicate student merged into another one
+type StudentMerge struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	ClubID     uuid.UUID  `db:"club_id" json:"club_id"`
+	SurvivorID *uuid.UUID `db:"survivor_id" json:"survivor_id,omitempty"`
+	// The deleted duplicate as it was before the merge
+	MergedStudentID    uuid.UUID  `db:"merged_student_id" json:"merged_student_id"`
+	MergedStudent      Student    `db:"-" json:"merged_student"`
+	MovedSubscriptions int        `db:"moved_subscriptions" json:"moved_subscriptions"`
+	MovedAttendances   int        `db:"moved_attendances" json:"moved_attendances"`
+	MovedPayments      int        `db:"moved_payments" json:"moved_payments"`
+	MovedMakeupCredits int        `db:"moved_makeup_credits" json:"moved_makeup_credits"`
+	RemovedAttendances int        `db:"removed_attendances" json:"removed_attendances"`
+	MergedBy           *uuid.UUID `db:"merged_by" json:"merged_by,omitempty"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+}
+
+// StudentMedical holds the medical details of a student
+type StudentMedical struct {
+	StudentID   uuid.UUID  `db:"student_id" json:"student_id"`
+	BloodType   string     `db:"blood_type" json:"blood_type,omitempty"`
+	Allergies   string     `db:"allergies" json:"allergies,omitempty"`
+	Conditions  string     `db:"conditions" json:"conditions,omitempty"`
+	Medications string     `db:"medications" json:"medications,omitempty"`
+	Notes       string     `db:"notes" json:"notes,omitempty"`
+	UpdatedBy   *uuid.UUID `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// MedicalCertificate admits a student to training until ExpiresOn, a
+// calendar date
+type MedicalCertificate struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	StudentID  uuid.UUID  `db:"student_id" json:"student_id"`
+	IssuedOn   *time.Time `db:"issued_on" json:"issued_on,omitempty"`
+	ExpiresOn  time.Time  `db:"expires_on" json:"expires_on"`
+	Issuer     string     `db:"issuer" json:"issuer,omitempty"`
+	Notes      string     `db:"notes" json:"notes,omitempty"`
+	DocumentID *uuid.UUID `db:"document_id" json:"document_id,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// EmergencyContact is someone to call when something happens at training
+type EmergencyContact struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	StudentID uuid.UUID `db:"student_id" json:"student_id"`
+	Position  int       `db:"position" json:"position"`
+	Name      string    `db:"name" json:"name"`
+	Relation  string    `db:"relation" json:"relation,omitempty"`
+	Phone     string    `db:"phone" json:"phone"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// StudentDocument is a file attached to a student. The content lives in
+// file storage under StorageKey.
+type StudentDocument struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	StudentID   uuid.UUID  `db:"student_id" json:"student_id"`
+	Kind        string     `db:"kind" json:"kind"`
+	Filename    string     `db:"filename" json:"filename"`
+	ContentType string     `db:"content_type" json:"content_type"`
+	Size        int64      `db:"size" json:"size"`
+	StorageKey  string     `db:"storage_key" json:"-"`
+	UploadedBy  *uuid.UUID `db:"uploaded_by" json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+type DocumentKind string
+
+const (
+	DocumentMedicalCertificate DocumentKind = "medical_certificate"
+	DocumentWaiver             DocumentKind = "waiver"
+	DocumentConsent            DocumentKind = "consent"
+	DocumentIdentity           DocumentKind = "id_document"
+	DocumentOther              DocumentKind = "other"
+)
+
+// StudentConsent is a waiver or consent signed by the guardian
+type StudentConsent struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	StudentID  uuid.UUID  `db:"student_id" json:"student_id"`
+	Kind       string     `db:"kind" json:"kind"`
+	SignedBy   string     `db:"signed_by" json:"signed_by"`
+	SignedAt   time.Time  `db:"signed_at" json:"signed_at"`
+	DocumentID *uuid.UUID `db:"document_id" json:"document_id,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	RecordedBy *uuid.UUID `db:"recorded_by" json:"recorded_by,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+type ConsentKind string
+
+const (
+	ConsentWaiver         ConsentKind = "waiver"          // liability waiver
+	ConsentPhoto          ConsentKind = "photo"           // photos and video may be published
+	ConsentDataProcessing ConsentKind = "data_processing" // personal data processing
+)
+
+// Purge records a student, group or club deleted for good by an admin
+type Purge struct {
+	ID       uuid.UUID `db:"id" json:"id"`
+	Entity   string    `db:"entity" json:"entity"`
+	EntityID uuid.UUID `db:"entity_id" json:"entity_id"`
+	ClubID   uuid.UUID `db:"club_id" json:"club_id"`
+	// Subscriptions of a purged student stay for the books, without it
+	KeptSubscriptions int        `db:"kept_subscriptions" json:"kept_subscriptions"`
+	PurgedBy          *uuid.UUID `db:"purged_by" json:"purged_by,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+}
+
+type PurgeEntity string
+
+const (
+	PurgeStudent PurgeEntity = "student"
+	PurgeGroup   PurgeEntity = "group"
+	PurgeClub    PurgeEntity = "club"
+)
+
+// DataRequest logs a guardian's request to export or erase the personal
+// data held about a student or about all of the guardian's children
+type DataRequest struct {
+	ID          uuid.UUID   `db:"id" json:"id"`
+	ClubID      uuid.UUID   `db:"club_id" json:"club_id"`
+	Kind        string      `db:"kind" json:"kind"`
+	Subject     string      `db:"subject" json:"subject"`
+	StudentIDs  []uuid.UUID `db:"-" json:"student_ids"`
+	RequestedBy *uuid.UUID  `db:"requested_by" json:"requested_by,omitempty"`
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+}
+
+type DataRequestKind string
+
+const (
+	DataExport  DataRequestKind = "export"
+	DataErasure DataRequestKind = "erasure"
+)
+
+type DataSubject string
+
+const (
+	SubjectStudent  DataSubject = "student"
+	SubjectGuardian DataSubject = "guardian"
+)
+
+// CustomField is a field a club defines for its students or groups, e.g.
+// belt colour or shirt size
+type CustomField struct {
+	ID     uuid.UUID `db:"id" json:"id"`
+	ClubID uuid.UUID `db:"club_id" json:"club_id"`
+	Entity string    `db:"entity" json:"entity"`
+	// Key names the field in custom_fields, filters and imports
+	Key   string `db:"key" json:"key"`
+	Label string `db:"label" json:"label"`
+	Type  string `db:"type" json:"type"`
+	// Options are the allowed values of an enum field
+	Options   []string  `db:"-" json:"options,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type CustomFieldEntity string
+
+const (
+	CustomFieldStudent CustomFieldEntity = "student"
+	CustomFieldGroup   CustomFieldEntity = "group"
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldDate   CustomFieldType = "date" // "2006-01-02"
+	CustomFieldEnum   CustomFieldType = "enum"
+)
+
+// CustomValues maps custom field keys to values: strings for text, date
+// and enum fields, float64 for numbers
+type CustomValues map[string]interface{}
+
+// Apply returns the values with the changes made: nil removes a field
+func (v CustomValues) Apply(changes CustomValues) CustomValues {
+	result := make(CustomValues, len(v)+len(changes))
+	for key, value := range v {
+		result[key] = value
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(result, key)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// ProgressLevel is a level a group's students advance through, e.g. a belt.
+// Position 1 is the lowest.
+type ProgressLevel struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	GroupID     uuid.UUID `db:"group_id" json:"group_id"`
+	Name        string    `db:"name" json:"name"`
+	Position    int       `db:"position" json:"position"`
+	Description string    `db:"description" json:"description,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Skill is something coaches assess in a group, scored from 0 to MaxScore
+type Skill struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	GroupID     uuid.UUID `db:"group_id" json:"group_id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description,omitempty"`
+	MaxScore    int       `db:"max_score" json:"max_score"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// SkillAssessment is a coach's score of a student's skill
+type SkillAssessment struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	StudentID  uuid.UUID  `db:"student_id" json:"student_id"`
+	SkillID    uuid.UUID  `db:"skill_id" json:"skill_id"`
+	Score      int        `db:"score" json:"score"`
+	Notes      string     `db:"notes" json:"notes,omitempty"`
+	AssessedOn time.Time  `db:"assessed_on" json:"assessed_on"`
+	AssessedBy *uuid.UUID `db:"assessed_by" json:"assessed_by,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Grading is a grading exam held in a group
+type Grading struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	GroupID   uuid.UUID       `db:"group_id" json:"group_id"`
+	Title     string          `db:"title" json:"title"`
+	HeldOn    time.Time       `db:"held_on" json:"held_on"`
+	Notes     string          `db:"notes" json:"notes,omitempty"`
+	CreatedBy *uuid.UUID      `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	Results   []GradingResult `db:"-" json:"results,omitempty"`
+}
+
+// GradingResult is the level a student tried for at a grading and whether
+// they passed. Passing awards the level.
+type GradingResult struct {
+	GradingID   uuid.UUID `db:"grading_id" json:"grading_id"`
+	StudentID   uuid.UUID `db:"student_id" json:"student_id"`
+	StudentName string    `db:"student_name" json:"student_name"`
+	LevelID     uuid.UUID `db:"level_id" json:"level_id"`
+	LevelName   string    `db:"level_name" json:"level_name"`
+	Passed      bool      `db:"passed" json:"passed"`
+	Notes       string    `db:"notes" json:"notes,omitempty"`
+}
+
+// LevelAward gives a student a level, by a passed grading or directly. A
+// student's level in a group is the one awarded last.
+type LevelAward struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	StudentID uuid.UUID  `db:"student_id" json:"student_id"`
+	LevelID   uuid.UUID  `db:"level_id" json:"level_id"`
+	AwardedOn time.Time  `db:"awarded_on" json:"awarded_on"`
+	GradingID *uuid.UUID `db:"grading_id" json:"grading_id,omitempty"`
+	Notes     string     `db:"notes" json:"notes,omitempty"`
+	AwardedBy *uuid.UUID `db:"awarded_by" json:"awarded_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// StudentLevel is a student's current level in a group
+type StudentLevel struct {
+	GroupID    uuid.UUID `db:"group_id" json:"group_id"`
+	GroupTitle string    `db:"group_title" json:"group_title"`
+	LevelID    uuid.UUID `db:"level_id" json:"level_id"`
+	LevelName  string    `db:"level_name" json:"level_name"`
+	Position   int       `db:"position" json:"position"`
+	AwardedOn  time.Time `db:"awarded_on" json:"awarded_on"`
+}
+
+// SkillScore is a student's latest score of a skill
+type SkillScore struct {
+	SkillID    uuid.UUID `db:"skill_id" json:"skill_id"`
+	SkillName  string    `db:"skill_name" json:"skill_name"`
+	GroupID    uuid.UUID `db:"group_id" json:"group_id"`
+	Score      int       `db:"score" json:"score"`
+	MaxScore   int       `db:"max_score" json:"max_score"`
+	AssessedOn time.Time `db:"assessed_on" json:"assessed_on"`
+}
+
+// ProgressEvent is an entry of a student's progress timeline. Title is the
+// skill assessed, the grading or the level awarded; a grading also has the
+// level tried for and the result.
+type ProgressEvent struct {
+	Kind       string    `db:"kind" json:"kind"`
+	ID         uuid.UUID `db:"id" json:"id"`
+	Date       time.Time `db:"date" json:"date"`
+	GroupID    uuid.UUID `db:"group_id" json:"group_id"`
+	GroupTitle string    `db:"group_title" json:"group_title"`
+	Title      string    `db:"title" json:"title"`
+	LevelName  string    `db:"level_name" json:"level_name,omitempty"`
+	Score      *int      `db:"score" json:"score,omitempty"`
+	MaxScore   *int      `db:"max_score" json:"max_score,omitempty"`
+	Passed     *bool     `db:"passed" json:"passed,omitempty"`
+	Notes      string    `db:"notes" json:"notes,omitempty"`
+}
+
+type ProgressEventKind string
+
+const (
+	ProgressAssessment ProgressEventKind = "assessment"
+	ProgressGrading    ProgressEventKind = "grading"
+	ProgressAward      ProgressEventKind = "level"
+)
+
+// PayrollRule is how a coach is paid for a group. Without a group it
+// covers every group of the coach in the club that has no rule of its
+// own. Rate is money per session or attendee, or a percentage of the
+// group's payments.
+type PayrollRule struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	ClubID      uuid.UUID  `db:"club_id" json:"club_id"`
+	CoachUserID uuid.UUID  `db:"coach_user_id" json:"coach_user_id"`
+	GroupID     *uuid.UUID `db:"group_id" json:"group_id,omitempty"`
+	Kind        string     `db:"kind" json:"kind"`
+	Rate        float64    `db:"rate" json:"rate"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type PayrollRuleKind string
+
+const (
+	PayrollPerSession   PayrollRuleKind = "per_session"
+	PayrollPerAttendee  PayrollRuleKind = "per_attendee"
+	PayrollRevenueShare PayrollRuleKind = "revenue_share"
+)
+
+// PayrollRun is the payroll of a club for the days from PeriodStart to
+// PeriodEnd. Items and Coaches are filled in when a single run is fetched.
+type PayrollRun struct {
+	ID           uuid.UUID           `db:"id" json:"id"`
+	ClubID       uuid.UUID           `db:"club_id" json:"club_id"`
+	PeriodStart  time.Time           `db:"period_start" json:"period_start"`
+	PeriodEnd    time.Time           `db:"period_end" json:"period_end"`
+	Status       string              `db:"status" json:"status"`
+	Total        float64             `db:"total" json:"total"`
+	CreatedBy    *uuid.UUID          `db:"created_by" json:"created_by,omitempty"`
+	CalculatedAt time.Time           `db:"calculated_at" json:"calculated_at"`
+	ApprovedBy   *uuid.UUID          `db:"approved_by" json:"approved_by,omitempty"`
+	ApprovedAt   *time.Time          `db:"approved_at" json:"approved_at,omitempty"`
+	CreatedAt    time.Time           `db:"created_at" json:"created_at"`
+	Coaches      []PayrollCoachTotal `db:"-" json:"coaches,omitempty"`
+	Items        []PayrollItem       `db:"-" json:"items,omitempty"`
+}
+
+type PayrollRunStatus string
+
+const (
+	PayrollDraft PayrollRunStatus = "draft"
+	// An approved run is locked
+	PayrollApproved PayrollRunStatus = "approved"
+)
+
+// PayrollItem is a line of a run: a session paid per session or per
+// attendee, or a payment shared with the coach. Quantity is 1 session, the
+// attendees or the amount paid.
+type PayrollItem struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	RunID       uuid.UUID  `db:"run_id" json:"run_id"`
+	CoachUserID *uuid.UUID `db:"coach_user_id" json:"coach_user_id,omitempty"`
+	CoachName   string     `db:"coach_name" json:"coach_name"`
+	GroupID     *uuid.UUID `db:"group_id" json:"group_id,omitempty"`
+	GroupTitle  string     `db:"group_title" json:"group_title"`
+	RuleID      *uuid.UUID `db:"rule_id" json:"rule_id,omitempty"`
+	SessionID   *uuid.UUID `db:"session_id" json:"session_id,omitempty"`
+	PaymentID   *uuid.UUID `db:"payment_id" json:"payment_id,omitempty"`
+	Kind        string     `db:"kind" json:"kind"`
+	Rate        float64    `db:"rate" json:"rate"`
+	Quantity    float64    `db:"quantity" json:"quantity"`
+	Amount      float64    `db:"amount" json:"amount"`
+	OccurredAt  time.Time  `db:"occurred_at" json:"occurred_at"`
+}
+
+// PayrollCoachTotal sums a coach's lines of a run
+type PayrollCoachTotal struct {
+	CoachUserID *uuid.UUID `json:"coach_user_id,omitempty"`
+	CoachName   string     `json:"coach_name"`
+	Sessions    int        `json:"sessions"`
+	Attendees   int        `json:"attendees"`
+	Revenue     float64    `json:"revenue"`
+	Amount      float64    `json:"amount"`
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// FakeDriver keeps messages in memory and logs them instead of sending.
+// It stands in for channels that are not configured and in tests.
+type FakeDriver struct {
+	channel Channel
+	logger  *slog.Logger
+
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func NewFakeDriver(channel Channel, logger *slog.Logger) *FakeDriver {
+	return &FakeDriver{channel: channel, logger: logger}
+}
+
+func (d *FakeDriver) Send(ctx context.Context, msg Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+	d.sent = append(d.sent, msg)
+
+	if d.logger != nil {
+		d.logger.InfoContext(ctx, "notification not sent (fake driver)",
+			slog.String("channel", string(d.channel)),
+			slog.String("to", msg.To),
+			slog.String("subject", msg.Subject),
+		)
+	}
+	return nil
+}
+
+// Sent returns the messages accepted so far
+func (d *FakeDriver) Sent() []Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Message(nil), d.sent...)
+}
+
+// FailWith makes the following sends return err; nil restores success
+func (d *FakeDriver) FailWith(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+)
+
+var (
+	_ service.SessionNotifier      = (*Notifier)(nil)
+	_ service.PaymentNotifier      = (*Notifier)(nil)
+	_ service.SubscriptionNotifier = (*Notifier)(nil)
+)
+
+// Notifier turns domain events into queued notifications for each
+// guardian on each of their channels, and for coaches who linked the
+// Telegram bot. Delivery happens later in the Dispatcher.
+type Notifier struct {
+	repo         *repository.NotificationRepository
+	telegramRepo *repository.TelegramRepository
+}
+
+func NewNotifier(repo *repository.NotificationRepository, telegramRepo *repository.TelegramRepository) *Notifier {
+	return &Notifier{repo: repo, telegramRepo: telegramRepo}
+}
+
+func (n *Notifier) PaymentSucceeded(ctx context.Context, notice service.PaymentNotice) error {
+	amount := formatAmount(notice.Payment.Amount, notice.Payment.Currency)
+	data := func(Language) Data {
+		return Data{
+			ClubName:          notice.Club.Name,
+			GroupTitle:        notice.Group.Title,
+			StudentName:       notice.Student.Name,
+			Amount:            amount,
+			RemainingSessions: notice.Subscription.RemainingSessions,
+		}
+	}
+
+	key := fmt.Sprintf("%s:%s", EventPaymentSucceeded, notice.Payment.ID)
+	if err := n.enqueue(ctx, notice.Club, notice.Student, EventPaymentSucceeded, key, data); err != nil {
+		return err
+	}
+
+	// Tell the coach too when they use the bot
+	if notice.Group.CoachUserID == nil {
+		return nil
+	}
+	account, err := n.telegramRepo.GetByUser(ctx, *notice.Group.CoachUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	coach := []Recipient{{Channel: ChannelTelegram, Address: strconv.FormatInt(account.ChatID, 10), Language: DefaultLanguage}}
+	key = fmt.Sprintf("%s:%s", EventCoachPaymentReceived, notice.Payment.ID)
+	_, err = n.enqueueTo(ctx, notice.Club.ID, notice.Student.ID, coach, EventCoachPaymentReceived, key, time.Time{}, data)
+	return err
+}
+
+func (n *Notifier) SubscriptionExpired(ctx context.Context, notice service.SubscriptionNotice) error {
+	key := fmt.Sprintf("%s:%s", EventSubscriptionExpired, notice.Subscription.ID)
+
+	return n.enqueue(ctx, notice.Club, notice.Student, EventSubscriptionExpired, key, func(lang Language) Data {
+		data := Data{
+			ClubName:          notice.Club.Name,
+			GroupTitle:        notice.Group.Title,
+			StudentName:       notice.Student.Name,
+			RemainingSessions: notice.Subscription.RemainingSessions,
+		}
+		if notice.Subscription.ExpiresAt != nil {
+			data.ExpiresAt = FormatDate(notice.Subscription.ExpiresAt.In(notice.Club.Location()), lang)
+		}
+		return data
+	})
+}
+
+func (n *Notifier) SessionCancelled(ctx context.Context, notice service.SessionNotice) error {
+	key := fmt.Sprintf("%s:%s", EventSessionCancelled, notice.Session.ID)
+	return n.enqueueSession(ctx, notice, EventSessionCancelled, key)
+}
+
+// SessionRescheduled notifies once per new start time, so a session moved
+// twice is announced twice
+func (n *Notifier) SessionRescheduled(ctx context.Context, notice service.SessionNotice) error {
+	key := fmt.Sprintf("%s:%s:%d", EventSessionRescheduled, notice.Session.ID, notice.Session.StartAt.Unix())
+	return n.enqueueSession(ctx, notice, EventSessionRescheduled, key)
+}
+
+func (n *Notifier) enqueueSession(ctx context.Context, notice service.SessionNotice, event Event, key string) error {
+	loc := notice.Club.Location()
+	for i := range notice.Students {
+		student := &notice.Students[i]
+		err := n.enqueue(ctx, notice.Club, student, event, key, func(lang Language) Data {
+			data := Data{
+				ClubName:    notice.Club.Name,
+				GroupTitle:  notice.Group.Title,
+				StudentName: student.Name,
+				StartAt:     FormatTime(notice.Session.StartAt.In(loc), lang),
+				Reason:      notice.Reason,
+			}
+			if notice.PreviousStartAt != nil {
+				data.PreviousStartAt = FormatTime(notice.PreviousStartAt.In(loc), lang)
+			}
+			return data
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue renders the event for every channel of the student's guardian
+func (n *Notifier) enqueue(ctx context.Context, club *model.Club, student *model.Student, event Event, key string, data func(Language) Data) error {
+	_, err := n.enqueueTo(ctx, club.ID, student.ID, Recipients(student.ParentContact), event, key, time.Time{}, data)
+	return err
+}
+
+// enqueueTo queues the event for each recipient, due at due or now when
+// it is zero, and returns how many notifications were new. The dedupe key
+// is made per recipient so a retried trigger never queues the same
+// message twice.
+func (n *Notifier) enqueueTo(ctx context.Context, clubID, studentID uuid.UUID, recipients []Recipient, event Event, key string, due time.Time, data func(Language) Data) (int, error) {
+	queued := 0
+	for _, r := range recipients {
+		subject, body, err := Render(event, r.Language, data(r.Language))
+		if err != nil {
+			return queued, err
+		}
+
+		dedupeKey := fmt.Sprintf("%s:%s:%s:%s", key, studentID, r.Channel, r.Address)
+		notification := &model.Notification{
+			ClubID:        clubID,
+			StudentID:     &studentID,
+			Event:         string(event),
+			Channel:       string(r.Channel),
+			Recipient:     r.Address,
+			Language:      string(r.Language),
+			Subject:       subject,
+			Body:          body,
+			DedupeKey:     &dedupeKey,
+			NextAttemptAt: due,
+		}
+		if err := n.repo.Enqueue(ctx, notification); err != nil {
+			return queued, err
+		}
+		if notification.ID != uuid.Nil {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+func formatAmount(amount float64, currency string) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64) + " " + currency
+}
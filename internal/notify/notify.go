@@ -0,0 +1,48 @@
+// Package notify renders messages for guardians in their language, keeps
+// them in a persistent queue and delivers them by email, SMS or Telegram.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// Channel is a delivery channel
+type Channel string
+
+const (
+	ChannelEmail    Channel = "email"
+	ChannelSMS      Channel = "sms"
+	ChannelTelegram Channel = "telegram"
+)
+
+// Message is a rendered notification addressed for one channel
+type Message struct {
+	To      string // email address, phone number or Telegram chat id
+	Subject string
+	Body    string
+}
+
+// Driver delivers messages over one channel
+type Driver interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a delivery failure that retrying cannot fix, such as a
+// rejected address
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
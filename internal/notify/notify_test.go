@@ -0,0 +1,344 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/notify"
+)
+
+func TestRender_AllEventsAndLanguages(t *testing.T) {
+	events := []notify.Event{
+		notify.EventPaymentSucceeded,
+		notify.EventSubscriptionExpired,
+		notify.EventSessionCancelled,
+		notify.EventSessionRescheduled,
+		notify.EventReminderLowBalance,
+		notify.EventReminderExpiring,
+		notify.EventReminderUpcomingSession,
+		notify.EventReminderUnpaidDebt,
+		notify.EventCoachPaymentReceived,
+	}
+	langs := []notify.Language{notify.LangRU, notify.LangKK, notify.LangEN}
+	data := notify.Data{
+		ClubName:          "Arena",
+		GroupTitle:        "Juniors",
+		StudentName:       "Aruzhan",
+		StartAt:           "01.03.2026 18:00",
+		Amount:            "15000 KZT",
+		ExpiresAt:         "01.04.2026",
+		Since:             "01.02.2026",
+		RemainingSessions: 8,
+	}
+
+	for _, event := range events {
+		for _, lang := range langs {
+			t.Run(string(event)+"/"+string(lang), func(t *testing.T) {
+				subject, body, err := notify.Render(event, lang, data)
+				if err != nil {
+					t.Fatalf("Render: %v", err)
+				}
+				if subject == "" || body == "" {
+					t.Fatalf("empty message: %q / %q", subject, body)
+				}
+				if !strings.Contains(subject+body, "Juniors") {
+					t.Errorf("group title missing from %q / %q", subject, body)
+				}
+				if strings.Contains(body, "<no value>") {
+					t.Errorf("unfilled field in %q", body)
+				}
+			})
+		}
+	}
+}
+
+// A certificate belongs to the student rather than a group
+func TestRender_MedicalCertificate(t *testing.T) {
+	for _, lang := range []notify.Language{notify.LangRU, notify.LangKK, notify.LangEN} {
+		subject, body, err := notify.Render(notify.EventReminderMedicalCertificate, lang, notify.Data{
+			ClubName:    "Arena",
+			StudentName: "Aruzhan",
+			ExpiresAt:   "01.04.2026",
+		})
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		if !strings.Contains(subject, "Arena") || !strings.Contains(body, "Aruzhan") || !strings.Contains(body, "01.04.2026") {
+			t.Errorf("%s: unexpected message %q / %q", lang, subject, body)
+		}
+	}
+}
+
+func TestRender_OptionalParts(t *testing.T) {
+	_, body, err := notify.Render(notify.EventSessionCancelled, notify.LangEN, notify.Data{GroupTitle: "Juniors", StartAt: "Mar 1"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(body, "Reason") {
+		t.Errorf("expected no reason sentence, got %q", body)
+	}
+
+	_, body, _ = notify.Render(notify.EventSessionCancelled, notify.LangEN, notify.Data{GroupTitle: "Juniors", Reason: "coach is ill"})
+	if !strings.Contains(body, "Reason: coach is ill.") {
+		t.Errorf("expected reason, got %q", body)
+	}
+}
+
+func TestRender_UnknownEvent(t *testing.T) {
+	if _, _, err := notify.Render("nope", notify.LangRU, notify.Data{}); err == nil {
+		t.Error("expected an error for an unknown event")
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want notify.Language
+	}{
+		{"", notify.LangRU},
+		{"ru", notify.LangRU},
+		{"KZ", notify.LangKK},
+		{"kk", notify.LangKK},
+		{" en ", notify.LangEN},
+		{"de", notify.DefaultLanguage},
+	}
+
+	for _, tt := range tests {
+		if got := notify.ParseLanguage(tt.in); got != tt.want {
+			t.Errorf("ParseLanguage(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name    string
+		contact *model.ParentContact
+		want    []notify.Recipient
+	}{
+		{"no contact", nil, nil},
+		{
+			"defaults to email",
+			&model.ParentContact{Email: "a@example.com", Phone: "+77010000000"},
+			[]notify.Recipient{{Channel: notify.ChannelEmail, Address: "a@example.com", Language: notify.LangRU}},
+		},
+		{
+			"falls back to sms",
+			&model.ParentContact{Phone: "+77010000000", Language: "kz"},
+			[]notify.Recipient{{Channel: notify.ChannelSMS, Address: "+77010000000", Language: notify.LangKK}},
+		},
+		{
+			"preferred channels in order",
+			&model.ParentContact{
+				Email:          "a@example.com",
+				Phone:          "+77010000000",
+				TelegramChatID: "12345",
+				Language:       "en",
+				Channels:       []string{"telegram", "sms", "telegram"},
+			},
+			[]notify.Recipient{
+				{Channel: notify.ChannelTelegram, Address: "12345", Language: notify.LangEN},
+				{Channel: notify.ChannelSMS, Address: "+77010000000", Language: notify.LangEN},
+			},
+		},
+		{
+			"skips channels without address",
+			&model.ParentContact{Email: "a@example.com", Channels: []string{"telegram", "email"}},
+			[]notify.Recipient{{Channel: notify.ChannelEmail, Address: "a@example.com", Language: notify.LangRU}},
+		},
+		{"nothing on file", &model.ParentContact{Name: "Mom"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notify.Recipients(tt.contact); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecipientsVia(t *testing.T) {
+	contact := &model.ParentContact{Email: "a@example.com", Phone: "+77010000000"}
+	withPrefs := &model.ParentContact{
+		Email:          "a@example.com",
+		TelegramChatID: "12345",
+		Channels:       []string{"telegram", "email"},
+	}
+
+	tests := []struct {
+		name    string
+		contact *model.ParentContact
+		allowed []string
+		want    []notify.Recipient
+	}{
+		{
+			"no limit",
+			contact, nil,
+			[]notify.Recipient{{Channel: notify.ChannelEmail, Address: "a@example.com", Language: notify.LangRU}},
+		},
+		{
+			"first allowed channel with an address",
+			contact, []string{"telegram", "sms", "email"},
+			[]notify.Recipient{{Channel: notify.ChannelSMS, Address: "+77010000000", Language: notify.LangRU}},
+		},
+		{
+			"guardian preferences filtered",
+			withPrefs, []string{"email", "sms"},
+			[]notify.Recipient{{Channel: notify.ChannelEmail, Address: "a@example.com", Language: notify.LangRU}},
+		},
+		{"no preferred channel allowed", withPrefs, []string{"sms"}, nil},
+		{"no address on an allowed channel", contact, []string{"telegram"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notify.RecipientsVia(tt.contact, tt.allowed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQuietHours_Until(t *testing.T) {
+	almaty, _ := time.LoadLocation("Asia/Almaty")
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 3, day, hour, min, 0, 0, almaty)
+	}
+
+	tests := []struct {
+		name  string
+		quiet notify.QuietHours
+		t     time.Time
+		want  time.Time
+	}{
+		{"not configured", notify.QuietHours{}, at(1, 23, 0), at(1, 23, 0)},
+		{"equal bounds", notify.QuietHours{Start: "22:00", End: "22:00"}, at(1, 22, 30), at(1, 22, 30)},
+		{"outside overnight", notify.QuietHours{Start: "22:00", End: "08:00"}, at(1, 12, 0), at(1, 12, 0)},
+		{"late evening", notify.QuietHours{Start: "22:00", End: "08:00"}, at(1, 23, 15), at(2, 8, 0)},
+		{"at start", notify.QuietHours{Start: "22:00", End: "08:00"}, at(1, 22, 0), at(2, 8, 0)},
+		{"early morning", notify.QuietHours{Start: "22:00", End: "08:00"}, at(2, 6, 45), at(2, 8, 0)},
+		{"at end", notify.QuietHours{Start: "22:00", End: "08:00"}, at(2, 8, 0), at(2, 8, 0)},
+		{"daytime window", notify.QuietHours{Start: "13:00", End: "15:30"}, at(1, 14, 0), at(1, 15, 30)},
+		{"after daytime window", notify.QuietHours{Start: "13:00", End: "15:30"}, at(1, 16, 0), at(1, 16, 0)},
+		{"invalid", notify.QuietHours{Start: "late", End: "08:00"}, at(1, 23, 0), at(1, 23, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.Until(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Until(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 4 * time.Minute},
+		{3, 16 * time.Minute},
+		{4, 64 * time.Minute},
+		{5, 256 * time.Minute},
+		{6, 6 * time.Hour},
+		{20, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := notify.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestSMSGatewayDriver(t *testing.T) {
+	var got map[string]string
+	var auth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	driver := notify.NewSMSGatewayDriver(srv.URL, "token", "Club")
+	msg := notify.Message{To: "+77010000000", Subject: "ignored", Body: "hello"}
+
+	if err := driver.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("expected bearer token, got %q", auth)
+	}
+	want := map[string]string{"to": "+77010000000", "text": "hello", "sender": "Club"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected payload %v, got %v", want, got)
+	}
+
+	status = http.StatusBadRequest
+	if err := driver.Send(context.Background(), msg); !notify.IsPermanent(err) {
+		t.Errorf("expected permanent error for 400, got %v", err)
+	}
+
+	status = http.StatusTooManyRequests
+	if err := driver.Send(context.Background(), msg); err == nil || notify.IsPermanent(err) {
+		t.Errorf("expected retryable error for 429, got %v", err)
+	}
+
+	status = http.StatusBadGateway
+	if err := driver.Send(context.Background(), msg); err == nil || notify.IsPermanent(err) {
+		t.Errorf("expected retryable error for 502, got %v", err)
+	}
+}
+
+func TestTelegramDriver(t *testing.T) {
+	var path string
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	driver := notify.NewTelegramDriver(srv.URL+"/", "123:abc")
+	err := driver.Send(context.Background(), notify.Message{To: "42", Subject: "Hi", Body: "there"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if path != "/bot123:abc/sendMessage" {
+		t.Errorf("unexpected path %q", path)
+	}
+	if got["chat_id"] != "42" || got["text"] != "Hi\n\nthere" {
+		t.Errorf("unexpected payload %v", got)
+	}
+}
+
+func TestFakeDriver(t *testing.T) {
+	driver := notify.NewFakeDriver(notify.ChannelEmail, nil)
+	msg := notify.Message{To: "a@example.com", Body: "hello"}
+
+	if err := driver.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	driver.FailWith(notify.Permanent(context.DeadlineExceeded))
+	if err := driver.Send(context.Background(), msg); !notify.IsPermanent(err) {
+		t.Errorf("expected the configured error, got %v", err)
+	}
+
+	if sent := driver.Sent(); len(sent) != 1 || sent[0] != msg {
+		t.Errorf("expected one recorded message, got %+v", sent)
+	}
+}
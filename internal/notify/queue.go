@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+const (
+	// MaxAttempts is how often delivery is tried before giving up
+	MaxAttempts = 5
+	// dispatchBatch bounds the notifications claimed per run
+	dispatchBatch = 50
+
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 6 * time.Hour
+)
+
+// Backoff is the wait after the given failed attempt: 1m, 4m, 16m, ...
+// capped at six hours
+func Backoff(attempt int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 4
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Dispatcher delivers queued notifications through the channel drivers
+type Dispatcher struct {
+	repo    *repository.NotificationRepository
+	drivers map[Channel]Driver
+	logger  *slog.Logger
+}
+
+func NewDispatcher(repo *repository.NotificationRepository, drivers map[Channel]Driver, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:    repo,
+		drivers: drivers,
+		logger:  logger,
+	}
+}
+
+// DispatchDue sends the notifications that are due and returns how many
+// went out. Failed sends are retried with Backoff until MaxAttempts, or
+// given up at once when the driver reports a permanent error.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	due, err := d.repo.ClaimDue(ctx, dispatchBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range due {
+		n := &due[i]
+		err := d.deliver(ctx, n)
+		if err == nil {
+			if err := d.repo.MarkSent(ctx, n.ID); err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		d.logger.Warn("notification delivery failed",
+			slog.String("notification_id", n.ID.String()),
+			slog.String("channel", n.Channel),
+			slog.Int("attempt", n.Attempts),
+			slog.String("error", err.Error()),
+		)
+		if IsPermanent(err) || n.Attempts >= MaxAttempts {
+			err = d.repo.MarkFailed(ctx, n.ID, err.Error())
+		} else {
+			err = d.repo.MarkRetry(ctx, n.ID, err.Error(), time.Now().Add(Backoff(n.Attempts)))
+		}
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, n *model.Notification) error {
+	driver, ok := d.drivers[Channel(n.Channel)]
+	if !ok {
+		return Permanent(fmt.Errorf("no driver for channel %q", n.Channel))
+	}
+	return driver.Send(ctx, Message{To: n.Recipient, Subject: n.Subject, Body: n.Body})
+}
+
+// Run calls DispatchDue every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := d.DispatchDue(ctx)
+		if err != nil {
+			d.logger.Error("failed to dispatch notifications", slog.String("error", err.Error()))
+		} else if sent > 0 {
+			d.logger.Info("sent notifications", slog.Int("sent", sent))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
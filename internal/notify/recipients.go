@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"slices"
+
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// Recipient is one address of a guardian on one channel
+type Recipient struct {
+	Channel  Channel
+	Address  string
+	Language Language
+}
+
+// Recipients resolves a guardian's channel preferences to addresses.
+// Without preferences the guardian gets email, or SMS when no email is on
+// file. Channels without an address are skipped.
+func Recipients(contact *model.ParentContact) []Recipient {
+	if contact == nil {
+		return nil
+	}
+	lang := ParseLanguage(contact.Language)
+
+	channels := make([]Channel, 0, len(contact.Channels))
+	for _, c := range contact.Channels {
+		channels = append(channels, Channel(c))
+	}
+	if len(channels) == 0 {
+		if contact.Email != "" {
+			channels = []Channel{ChannelEmail}
+		} else {
+			channels = []Channel{ChannelSMS}
+		}
+	}
+
+	var recipients []Recipient
+	seen := make(map[Channel]bool, len(channels))
+	for _, channel := range channels {
+		if seen[channel] {
+			continue
+		}
+		seen[channel] = true
+
+		address := addressOf(contact, channel)
+		if address == "" {
+			continue
+		}
+		recipients = append(recipients, Recipient{Channel: channel, Address: address, Language: lang})
+	}
+	return recipients
+}
+
+// RecipientsVia is Recipients limited to the channels a club allows, e.g.
+// for reminders. A guardian without preferences gets the first allowed
+// channel that has an address. No allowed channels means no limit.
+func RecipientsVia(contact *model.ParentContact, allowed []string) []Recipient {
+	if contact == nil || len(allowed) == 0 {
+		return Recipients(contact)
+	}
+
+	if len(contact.Channels) > 0 {
+		var recipients []Recipient
+		for _, r := range Recipients(contact) {
+			if slices.Contains(allowed, string(r.Channel)) {
+				recipients = append(recipients, r)
+			}
+		}
+		return recipients
+	}
+
+	for _, c := range allowed {
+		if address := addressOf(contact, Channel(c)); address != "" {
+			return []Recipient{{Channel: Channel(c), Address: address, Language: ParseLanguage(contact.Language)}}
+		}
+	}
+	return nil
+}
+
+func addressOf(contact *model.ParentContact, channel Channel) string {
+	switch channel {
+	case ChannelEmail:
+		return contact.Email
+	case ChannelSMS:
+		return contact.Phone
+	case ChannelTelegram:
+		return contact.TelegramChatID
+	default:
+		return ""
+	}
+}
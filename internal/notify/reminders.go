@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+// QuietHours is a daily local period in which reminders are held back.
+// Start after End wraps midnight, e.g. 22:00-08:00. Unset or equal
+// bounds mean no quiet hours.
+type QuietHours struct {
+	Start string // "HH:MM"
+	End   string
+}
+
+// Until returns when a message due at t may go out: t itself, or the end
+// of the quiet period t falls in. t must be in the club's time zone.
+func (q QuietHours) Until(t time.Time) time.Time {
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return t
+	}
+	end, err := time.Parse("15:04", q.End)
+	if err != nil || start.Equal(end) {
+		return t
+	}
+
+	clock := t.Hour()*60 + t.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	endOn := func(days int) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day()+days, end.Hour(), end.Minute(), 0, 0, t.Location())
+	}
+
+	switch {
+	case from < to && clock >= from && clock < to:
+		return endOn(0)
+	case from > to && clock >= from:
+		return endOn(1)
+	case from > to && clock < to:
+		return endOn(0)
+	default:
+		return t
+	}
+}
+
+// Reminders queues the reminders of the clubs' reminder rules. Each rule
+// reminds about the same thing once, using the notification dedupe keys as
+// send history:
+//   - low_balance once per subscription
+//   - expiring once per subscription and expiry date
+//   - upcoming_session once per session start
+//   - unpaid_debt again every threshold days while the debt is open
+//   - medical_certificate once per certificate and expiry date
+type Reminders struct {
+	repo     *repository.ReminderRepository
+	clubRepo *repository.ClubRepository
+	notifier *Notifier
+	logger   *slog.Logger
+}
+
+func NewReminders(repo *repository.ReminderRepository, clubRepo *repository.ClubRepository, notifier *Notifier, logger *slog.Logger) *Reminders {
+	return &Reminders{
+		repo:     repo,
+		clubRepo: clubRepo,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// clubReminders is what a run needs to know about a club
+type clubReminders struct {
+	club     *model.Club
+	quiet    QuietHours
+	channels []string
+}
+
+// RunDue queues the reminders the enabled rules call for at now and
+// returns how many notifications are new. A reminder that fails to queue
+// is logged and skipped.
+func (r *Reminders) RunDue(ctx context.Context, now time.Time) (int, error) {
+	enabled, err := r.repo.GetEnabledRules(ctx)
+	if err != nil || len(enabled) == 0 {
+		return 0, err
+	}
+
+	rules := make(map[uuid.UUID]map[model.ReminderKind]*model.ReminderRule)
+	for i := range enabled {
+		rule := &enabled[i]
+		if rules[rule.ClubID] == nil {
+			rules[rule.ClubID] = make(map[model.ReminderKind]*model.ReminderRule)
+		}
+		rules[rule.ClubID][model.ReminderKind(rule.Kind)] = rule
+	}
+
+	clubs := make(map[uuid.UUID]*clubReminders)
+	queued := 0
+	for _, kind := range model.ReminderKinds {
+		candidates, err := r.candidates(ctx, kind, now)
+		if err != nil {
+			return queued, err
+		}
+
+		for i := range candidates {
+			c := &candidates[i]
+			rule := rules[c.ClubID][kind]
+			if rule == nil {
+				// Enabled after the rules were loaded; next run
+				continue
+			}
+
+			club, err := r.club(ctx, clubs, c.ClubID)
+			if err == nil {
+				var n int
+				n, err = r.remind(ctx, club, rule, c, now)
+				queued += n
+			}
+			if err != nil {
+				r.logger.Error("failed to queue reminder",
+					slog.String("kind", string(kind)),
+					slog.String("student_id", c.StudentID.String()),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+	return queued, nil
+}
+
+func (r *Reminders) candidates(ctx context.Context, kind model.ReminderKind, now time.Time) ([]repository.ReminderCandidate, error) {
+	switch kind {
+	case model.ReminderLowBalance:
+		return r.repo.LowBalanceCandidates(ctx, now)
+	case model.ReminderExpiring:
+		return r.repo.ExpiringCandidates(ctx, now)
+	case model.ReminderUpcomingSession:
+		return r.repo.UpcomingSessionCandidates(ctx, now)
+	case model.ReminderUnpaidDebt:
+		return r.repo.UnpaidDebtCandidates(ctx, now)
+	case model.ReminderMedical:
+		return r.repo.MedicalCertificateCandidates(ctx, now)
+	default:
+		return nil, fmt.Errorf("notify: unknown reminder kind %q", kind)
+	}
+}
+
+// club loads a club and its reminder settings once per run
+func (r *Reminders) club(ctx context.Context, cache map[uuid.UUID]*clubReminders, clubID uuid.UUID) (*clubReminders, error) {
+	if c, ok := cache[clubID]; ok {
+		return c, nil
+	}
+
+	club, err := r.clubRepo.GetByID(ctx, clubID)
+	if err != nil {
+		return nil, err
+	}
+	c := &clubReminders{club: club}
+
+	settings, err := r.repo.GetSettings(ctx, clubID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		if settings.QuietHoursStart != nil && settings.QuietHoursEnd != nil {
+			c.quiet = QuietHours{Start: *settings.QuietHoursStart, End: *settings.QuietHoursEnd}
+		}
+		c.channels = settings.Channels
+	}
+
+	cache[clubID] = c
+	return c, nil
+}
+
+func (r *Reminders) remind(ctx context.Context, club *clubReminders, rule *model.ReminderRule, c *repository.ReminderCandidate, now time.Time) (int, error) {
+	loc := club.club.Location()
+	due := club.quiet.Until(now.In(loc))
+
+	var event Event
+	var key string
+	switch model.ReminderKind(rule.Kind) {
+	case model.ReminderLowBalance:
+		event = EventReminderLowBalance
+		key = fmt.Sprintf("%s:%s", event, *c.SubscriptionID)
+	case model.ReminderExpiring:
+		event = EventReminderExpiring
+		key = fmt.Sprintf("%s:%s:%s", event, *c.SubscriptionID, c.ExpiresAt.In(loc).Format("2006-01-02"))
+	case model.ReminderUpcomingSession:
+		// Held past the start by quiet hours, the reminder is pointless
+		if !due.Before(*c.StartAt) {
+			return 0, nil
+		}
+		event = EventReminderUpcomingSession
+		key = fmt.Sprintf("%s:%s:%d", event, *c.SessionID, c.StartAt.Unix())
+	case model.ReminderUnpaidDebt:
+		event = EventReminderUnpaidDebt
+		period := int(now.Sub(*c.Since).Hours()/24) / rule.Threshold
+		key = fmt.Sprintf("%s:%s:%d", event, *c.SubscriptionID, period)
+	case model.ReminderMedical:
+		event = EventReminderMedicalCertificate
+		// A calendar date, not an instant: keep the day in the club's zone
+		day := time.Date(c.ExpiresAt.Year(), c.ExpiresAt.Month(), c.ExpiresAt.Day(), 0, 0, 0, 0, loc)
+		c.ExpiresAt = &day
+		key = fmt.Sprintf("%s:%s:%s", event, *c.CertificateID, day.Format("2006-01-02"))
+	default:
+		return 0, fmt.Errorf("notify: unknown reminder kind %q", rule.Kind)
+	}
+
+	channels := rule.Channels
+	if len(channels) == 0 {
+		channels = club.channels
+	}
+
+	return r.notifier.enqueueTo(ctx, c.ClubID, c.StudentID, RecipientsVia(c.ParentContact, channels), event, key, due, func(lang Language) Data {
+		data := Data{
+			ClubName:          club.club.Name,
+			GroupTitle:        c.GroupTitle,
+			StudentName:       c.StudentName,
+			RemainingSessions: c.RemainingSessions,
+		}
+		if c.ExpiresAt != nil {
+			data.ExpiresAt = FormatDate(c.ExpiresAt.In(loc), lang)
+		}
+		if c.StartAt != nil {
+			data.StartAt = FormatTime(c.StartAt.In(loc), lang)
+		}
+		if c.Since != nil {
+			data.Since = FormatDate(c.Since.In(loc), lang)
+		}
+		if c.Amount > 0 {
+			data.Amount = formatAmount(c.Amount, club.club.Currency)
+		}
+		return data
+	})
+}
+
+// Run calls RunDue every interval until ctx is done
+func (r *Reminders) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		queued, err := r.RunDue(ctx, time.Now())
+		if err != nil {
+			r.logger.Error("failed to run reminders", slog.String("error", err.Error()))
+		} else if queued > 0 {
+			r.logger.Info("queued reminders", slog.Int("queued", queued))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// SMSGatewayDriver sends text messages through a generic HTTP gateway. It
+// posts {"to", "text", "sender"} as JSON with the token as a bearer
+// credential, which most aggregators accept or can be proxied to.
+type SMSGatewayDriver struct {
+	url    string
+	token  string
+	sender string
+	client *http.Client
+}
+
+func NewSMSGatewayDriver(url, token, sender string) *SMSGatewayDriver {
+	return &SMSGatewayDriver{
+		url:    url,
+		token:  token,
+		sender: sender,
+		client: &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+// Send delivers the body only; SMS has no subject
+func (d *SMSGatewayDriver) Send(ctx context.Context, msg Message) error {
+	header := http.Header{}
+	if d.token != "" {
+		header.Set("Authorization", "Bearer "+d.token)
+	}
+
+	return postJSON(ctx, d.client, d.url, header, map[string]string{
+		"to":     msg.To,
+		"text":   msg.Body,
+		"sender": d.sender,
+	})
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// SMTPDriver sends plain-text email through an SMTP server
+type SMTPDriver struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPDriver authenticates with PLAIN auth when user is set. The
+// server must offer STARTTLS for that, which net/smtp negotiates.
+func NewSMTPDriver(host, port, user, password, from string) *SMTPDriver {
+	d := &SMTPDriver{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if user != "" {
+		d.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return d
+}
+
+func (d *SMTPDriver) Send(ctx context.Context, msg Message) error {
+	err := smtp.SendMail(d.addr, d.auth, d.from, []string{msg.To}, buildEmail(d.from, msg))
+
+	// 5xx replies such as an unknown mailbox will not change on retry
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+// buildEmail renders a UTF-8 text/plain message with a base64 body
+func buildEmail(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// DefaultTelegramAPI is the Telegram Bot API endpoint
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// TelegramDriver sends messages to chats through the Telegram Bot API.
+// The guardian must have started a chat with the bot for the chat id to
+// be usable.
+type TelegramDriver struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewTelegramDriver(baseURL, token string) *TelegramDriver {
+	if baseURL == "" {
+		baseURL = DefaultTelegramAPI
+	}
+	return &TelegramDriver{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+func (d *TelegramDriver) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + msg.Body
+	}
+
+	// A blocked bot or unknown chat answers 400/403, which postJSON
+	// reports as permanent
+	return postJSON(ctx, d.client, d.baseURL+"/bot"+d.token+"/sendMessage", nil, map[string]string{
+		"chat_id": msg.To,
+		"text":    text,
+	})
+}
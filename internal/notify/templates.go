@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Event is what a notification is about
+type Event string
+
+const (
+	EventPaymentSucceeded    Event = "payment_succeeded"
+	EventSubscriptionExpired Event = "subscription_expired"
+	EventSessionCancelled    Event = "session_cancelled"
+	EventSessionRescheduled  Event = "session_rescheduled"
+
+	// Reminders sent by the rules in Reminders
+	EventReminderLowBalance         Event = "reminder_low_balance"
+	EventReminderExpiring           Event = "reminder_expiring"
+	EventReminderUpcomingSession    Event = "reminder_upcoming_session"
+	EventReminderUnpaidDebt         Event = "reminder_unpaid_debt"
+	EventReminderMedicalCertificate Event = "reminder_medical_certificate"
+
+	// Sent to the group's coach through the Telegram bot
+	EventCoachPaymentReceived Event = "coach_payment_received"
+)
+
+// Language of a message
+type Language string
+
+const (
+	LangRU Language = "ru"
+	LangKK Language = "kk" // Kazakh
+	LangEN Language = "en"
+)
+
+// DefaultLanguage is used when the guardian has not chosen one
+const DefaultLanguage = LangRU
+
+// ParseLanguage maps a stored preference to a supported language. "kz" is
+// accepted for Kazakh; anything unknown falls back to DefaultLanguage.
+func ParseLanguage(s string) Language {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ru":
+		return LangRU
+	case "kk", "kz":
+		return LangKK
+	case "en":
+		return LangEN
+	default:
+		return DefaultLanguage
+	}
+}
+
+// Data fills the message templates. Times are already formatted for the
+// recipient.
+type Data struct {
+	ClubName          string
+	GroupTitle        string
+	StudentName       string
+	StartAt           string
+	PreviousStartAt   string
+	Reason            string
+	Amount            string
+	ExpiresAt         string
+	Since             string
+	RemainingSessions int
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[Event]map[Language]messageTemplate{
+	EventPaymentSucceeded: {
+		LangRU: parse(
+			"Оплата получена — {{.ClubName}}",
+			"Здравствуйте! Оплата {{.Amount}} за абонемент {{.StudentName}} в группу «{{.GroupTitle}}» получена.{{if .RemainingSessions}} Занятий в абонементе: {{.RemainingSessions}}.{{end}} Спасибо!"),
+		LangKK: parse(
+			"Төлем қабылданды — {{.ClubName}}",
+			"Сәлеметсіз бе! {{.StudentName}} үшін «{{.GroupTitle}}» тобына абонемент төлемі {{.Amount}} қабылданды.{{if .RemainingSessions}} Абонементтегі сабақ саны: {{.RemainingSessions}}.{{end}} Рахмет!"),
+		LangEN: parse(
+			"Payment received — {{.ClubName}}",
+			"Hello! We have received {{.Amount}} for {{.StudentName}}'s subscription to {{.GroupTitle}}.{{if .RemainingSessions}} Sessions included: {{.RemainingSessions}}.{{end}} Thank you!"),
+	},
+	EventSubscriptionExpired: {
+		LangRU: parse(
+			"Абонемент закончился — {{.ClubName}}",
+			"Абонемент {{.StudentName}} в группу «{{.GroupTitle}}» истёк {{.ExpiresAt}}.{{if .RemainingSessions}} Неиспользованных занятий: {{.RemainingSessions}}.{{end}} Чтобы продолжить занятия, продлите абонемент."),
+		LangKK: parse(
+			"Абонемент мерзімі аяқталды — {{.ClubName}}",
+			"{{.StudentName}} үшін «{{.GroupTitle}}» тобына абонементтің мерзімі {{.ExpiresAt}} аяқталды.{{if .RemainingSessions}} Пайдаланылмаған сабақтар: {{.RemainingSessions}}.{{end}} Сабақты жалғастыру үшін абонементті ұзартыңыз."),
+		LangEN: parse(
+			"Subscription expired — {{.ClubName}}",
+			"{{.StudentName}}'s subscription to {{.GroupTitle}} expired on {{.ExpiresAt}}.{{if .RemainingSessions}} Unused sessions: {{.RemainingSessions}}.{{end}} Renew it to keep training."),
+	},
+	EventSessionCancelled: {
+		LangRU: parse(
+			"Занятие отменено — {{.GroupTitle}}",
+			"Занятие группы «{{.GroupTitle}}» {{.StartAt}} отменено.{{if .Reason}} Причина: {{.Reason}}.{{end}} Занятие не списывается с абонемента."),
+		LangKK: parse(
+			"Сабақ болмайды — {{.GroupTitle}}",
+			"«{{.GroupTitle}}» тобының {{.StartAt}} сабағы болмайды.{{if .Reason}} Себебі: {{.Reason}}.{{end}} Сабақ абонементтен шегерілмейді."),
+		LangEN: parse(
+			"Session cancelled — {{.GroupTitle}}",
+			"The {{.GroupTitle}} session on {{.StartAt}} is cancelled.{{if .Reason}} Reason: {{.Reason}}.{{end}} It is not deducted from the subscription."),
+	},
+	EventSessionRescheduled: {
+		LangRU: parse(
+			"Занятие перенесено — {{.GroupTitle}}",
+			"Занятие группы «{{.GroupTitle}}»{{if .PreviousStartAt}} {{.PreviousStartAt}}{{end}} перенесено на {{.StartAt}}."),
+		LangKK: parse(
+			"Сабақ ауыстырылды — {{.GroupTitle}}",
+			"«{{.GroupTitle}}» тобының{{if .PreviousStartAt}} {{.PreviousStartAt}}{{end}} сабағы {{.StartAt}} уақытына ауыстырылды."),
+		LangEN: parse(
+			"Session moved — {{.GroupTitle}}",
+			"The {{.GroupTitle}} session{{if .PreviousStartAt}} on {{.PreviousStartAt}}{{end}} has moved to {{.StartAt}}."),
+	},
+	EventReminderLowBalance: {
+		LangRU: parse(
+			"Заканчивается абонемент — {{.GroupTitle}}",
+			"В абонементе {{.StudentName}} в группу «{{.GroupTitle}}» осталось занятий: {{.RemainingSessions}}. Продлите абонемент заранее, чтобы не пропустить тренировки."),
+		LangKK: parse(
+			"Абонемент таусылып барады — {{.GroupTitle}}",
+			"{{.StudentName}} үшін «{{.GroupTitle}}» тобына абонементте қалған сабақ саны: {{.RemainingSessions}}. Жаттығуды жібермеу үшін абонементті алдын ала ұзартыңыз."),
+		LangEN: parse(
+			"Subscription running low — {{.GroupTitle}}",
+			"{{.StudentName}}'s subscription to {{.GroupTitle}} has {{.RemainingSessions}} session(s) left. Renew it in advance so no training is missed."),
+	},
+	EventReminderExpiring: {
+		LangRU: parse(
+			"Абонемент скоро истекает — {{.GroupTitle}}",
+			"Абонемент {{.StudentName}} в группу «{{.GroupTitle}}» действует до {{.ExpiresAt}}.{{if .RemainingSessions}} Осталось занятий: {{.RemainingSessions}}.{{end}}"),
+		LangKK: parse(
+			"Абонемент мерзімі жақында бітеді — {{.GroupTitle}}",
+			"{{.StudentName}} үшін «{{.GroupTitle}}» тобына абонемент {{.ExpiresAt}} дейін жарамды.{{if .RemainingSessions}} Қалған сабақтар: {{.RemainingSessions}}.{{end}}"),
+		LangEN: parse(
+			"Subscription expires soon — {{.GroupTitle}}",
+			"{{.StudentName}}'s subscription to {{.GroupTitle}} is valid until {{.ExpiresAt}}.{{if .RemainingSessions}} Sessions left: {{.RemainingSessions}}.{{end}}"),
+	},
+	EventReminderUpcomingSession: {
+		LangRU: parse(
+			"Напоминание о занятии — {{.GroupTitle}}",
+			"{{.StudentName}}, ждём вас на занятии группы «{{.GroupTitle}}» {{.StartAt}}."),
+		LangKK: parse(
+			"Сабақ туралы еске салу — {{.GroupTitle}}",
+			"{{.StudentName}}, сізді «{{.GroupTitle}}» тобының {{.StartAt}} сабағында күтеміз."),
+		LangEN: parse(
+			"Session reminder — {{.GroupTitle}}",
+			"{{.StudentName}}, see you at the {{.GroupTitle}} session on {{.StartAt}}."),
+	},
+	EventReminderUnpaidDebt: {
+		LangRU: parse(
+			"Ожидает оплаты — {{.ClubName}}",
+			"Абонемент {{.StudentName}} в группу «{{.GroupTitle}}» на сумму {{.Amount}}{{if .Since}} от {{.Since}}{{end}} ещё не оплачен. Пожалуйста, оплатите его."),
+		LangKK: parse(
+			"Төлем күтілуде — {{.ClubName}}",
+			"{{.StudentName}} үшін «{{.GroupTitle}}» тобына{{if .Since}} {{.Since}} берілген{{end}} {{.Amount}} сомасындағы абонемент әлі төленбеген. Төлеуіңізді сұраймыз."),
+		LangEN: parse(
+			"Payment due — {{.ClubName}}",
+			"{{.StudentName}}'s subscription to {{.GroupTitle}} for {{.Amount}}{{if .Since}} from {{.Since}}{{end}} is still unpaid. Please settle it."),
+	},
+	EventReminderMedicalCertificate: {
+		LangRU: parse(
+			"Истекает медицинская справка — {{.ClubName}}",
+			"Медицинская справка {{.StudentName}} действует до {{.ExpiresAt}}. Без действующей справки тренер не сможет допустить к занятиям — пожалуйста, принесите новую."),
+		LangKK: parse(
+			"Медициналық анықтаманың мерзімі бітеді — {{.ClubName}}",
+			"{{.StudentName}} медициналық анықтамасы {{.ExpiresAt}} дейін жарамды. Жарамды анықтамасыз жаттықтырушы сабаққа жібере алмайды — жаңасын әкеліңіз."),
+		LangEN: parse(
+			"Medical certificate expiring — {{.ClubName}}",
+			"{{.StudentName}}'s medical certificate is valid until {{.ExpiresAt}}. Without a valid certificate the coach cannot admit them to training, so please bring a new one."),
+	},
+	EventCoachPaymentReceived: {
+		LangRU: parse(
+			"Новая оплата — {{.GroupTitle}}",
+			"{{.StudentName}} оплатил(а) {{.Amount}} за абонемент в группу «{{.GroupTitle}}».{{if .RemainingSessions}} Занятий: {{.RemainingSessions}}.{{end}}"),
+		LangKK: parse(
+			"Жаңа төлем — {{.GroupTitle}}",
+			"{{.StudentName}} «{{.GroupTitle}}» тобына абонемент үшін {{.Amount}} төледі.{{if .RemainingSessions}} Сабақ саны: {{.RemainingSessions}}.{{end}}"),
+		LangEN: parse(
+			"New payment — {{.GroupTitle}}",
+			"{{.StudentName}} paid {{.Amount}} for a subscription to {{.GroupTitle}}.{{if .RemainingSessions}} Sessions: {{.RemainingSessions}}.{{end}}"),
+	},
+}
+
+func parse(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// Render fills the template of an event in the given language, falling
+// back to DefaultLanguage when there is no translation
+func Render(event Event, lang Language, data Data) (subject, body string, err error) {
+	byLang, ok := templates[event]
+	if !ok {
+		return "", "", fmt.Errorf("notify: no template for event %q", event)
+	}
+	tmpl, ok := byLang[lang]
+	if !ok {
+		tmpl = byLang[DefaultLanguage]
+	}
+
+	var sb, bb strings.Builder
+	if err := tmpl.subject.Execute(&sb, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&bb, data); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
+
+// FormatTime formats t the way people write dates in lang
+func FormatTime(t time.Time, lang Language) string {
+	if lang == LangEN {
+		return t.Format("Jan 2, 2006 15:04")
+	}
+	return t.Format("02.01.2006 15:04")
+}
+
+// FormatDate is FormatTime without the time of day
+func FormatDate(t time.Time, lang Language) string {
+	if lang == LangEN {
+		return t.Format("Jan 2, 2006")
+	}
+	return t.Format("02.01.2006")
+}
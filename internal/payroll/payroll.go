@@ -0,0 +1,185 @@
+// Package payroll works out what coaches earn from the rules their club
+// set: per session they taught, per attendee or a share of what was paid
+// for their groups.
+package payroll
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// Session is a session held in a group, taught by the group's coach
+type Session struct {
+	ID          uuid.UUID
+	GroupID     uuid.UUID
+	GroupTitle  string
+	CoachUserID uuid.UUID
+	CoachName   string
+	StartAt     time.Time
+	// Attendees is how many students were present
+	Attendees int
+}
+
+// Payment is a payment for a subscription to a group with a coach
+type Payment struct {
+	ID          uuid.UUID
+	GroupID     uuid.UUID
+	GroupTitle  string
+	CoachUserID uuid.UUID
+	CoachName   string
+	PaidAt      time.Time
+	Amount      float64
+}
+
+type ruleKey struct {
+	coachID, groupID uuid.UUID
+}
+
+// Rules finds the rule a coach is paid by for a group
+type Rules struct {
+	byGroup map[ruleKey]model.PayrollRule
+	byCoach map[uuid.UUID]model.PayrollRule
+}
+
+func NewRules(rules []model.PayrollRule) Rules {
+	r := Rules{byGroup: map[ruleKey]model.PayrollRule{}, byCoach: map[uuid.UUID]model.PayrollRule{}}
+	for _, rule := range rules {
+		if rule.GroupID != nil {
+			r.byGroup[ruleKey{rule.CoachUserID, *rule.GroupID}] = rule
+		} else {
+			r.byCoach[rule.CoachUserID] = rule
+		}
+	}
+	return r
+}
+
+// For returns the coach's rule for the group, else their rule without a
+// group
+func (r Rules) For(coachID, groupID uuid.UUID) (model.PayrollRule, bool) {
+	if rule, ok := r.byGroup[ruleKey{coachID, groupID}]; ok {
+		return rule, true
+	}
+	rule, ok := r.byCoach[coachID]
+	return rule, ok
+}
+
+// Items computes the lines of a run, by coach and then in time order.
+// Under a per session or per attendee rule each session makes a line,
+// leaving out sessions nobody attended for the latter; under a revenue
+// share each payment does. Coaches without a rule for the group get
+// nothing for it.
+func Items(rules Rules, sessions []Session, payments []Payment) []model.PayrollItem {
+	items := []model.PayrollItem{}
+
+	for _, s := range sessions {
+		rule, ok := rules.For(s.CoachUserID, s.GroupID)
+		if !ok {
+			continue
+		}
+
+		var quantity float64
+		switch model.PayrollRuleKind(rule.Kind) {
+		case model.PayrollPerSession:
+			quantity = 1
+		case model.PayrollPerAttendee:
+			if s.Attendees == 0 {
+				continue
+			}
+			quantity = float64(s.Attendees)
+		default:
+			continue
+		}
+
+		item := newItem(rule, s.CoachUserID, s.CoachName, s.GroupID, s.GroupTitle, s.StartAt)
+		item.SessionID = &s.ID
+		item.Quantity = quantity
+		item.Amount = roundMoney(rule.Rate * quantity)
+		items = append(items, item)
+	}
+
+	for _, p := range payments {
+		rule, ok := rules.For(p.CoachUserID, p.GroupID)
+		if !ok || model.PayrollRuleKind(rule.Kind) != model.PayrollRevenueShare {
+			continue
+		}
+
+		item := newItem(rule, p.CoachUserID, p.CoachName, p.GroupID, p.GroupTitle, p.PaidAt)
+		item.PaymentID = &p.ID
+		item.Quantity = p.Amount
+		item.Amount = roundMoney(p.Amount * rule.Rate / 100)
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.CoachName != b.CoachName {
+			return a.CoachName < b.CoachName
+		}
+		if *a.CoachUserID != *b.CoachUserID {
+			return a.CoachUserID.String() < b.CoachUserID.String()
+		}
+		return a.OccurredAt.Before(b.OccurredAt)
+	})
+	return items
+}
+
+func newItem(rule model.PayrollRule, coachID uuid.UUID, coachName string, groupID uuid.UUID, groupTitle string, at time.Time) model.PayrollItem {
+	return model.PayrollItem{
+		CoachUserID: &coachID,
+		CoachName:   coachName,
+		GroupID:     &groupID,
+		GroupTitle:  groupTitle,
+		RuleID:      &rule.ID,
+		Kind:        rule.Kind,
+		Rate:        rule.Rate,
+		OccurredAt:  at,
+	}
+}
+
+type coachKey struct {
+	id   uuid.UUID
+	name string
+}
+
+// Totals sums the items per coach, in the order the coaches first appear,
+// and overall
+func Totals(items []model.PayrollItem) ([]model.PayrollCoachTotal, float64) {
+	coaches := []model.PayrollCoachTotal{}
+	byCoach := map[coachKey]int{}
+	var total float64
+
+	for _, item := range items {
+		key := coachKey{name: item.CoachName}
+		if item.CoachUserID != nil {
+			key.id = *item.CoachUserID
+		}
+		i, ok := byCoach[key]
+		if !ok {
+			i = len(coaches)
+			byCoach[key] = i
+			coaches = append(coaches, model.PayrollCoachTotal{CoachUserID: item.CoachUserID, CoachName: item.CoachName})
+		}
+
+		c := &coaches[i]
+		switch model.PayrollRuleKind(item.Kind) {
+		case model.PayrollPerSession:
+			c.Sessions++
+		case model.PayrollPerAttendee:
+			c.Sessions++
+			c.Attendees += int(item.Quantity)
+		case model.PayrollRevenueShare:
+			c.Revenue = roundMoney(c.Revenue + item.Quantity)
+		}
+		c.Amount = roundMoney(c.Amount + item.Amount)
+		total = roundMoney(total + item.Amount)
+	}
+	return coaches, total
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,108 @@
+package payroll_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/payroll"
+)
+
+func TestRules_For(t *testing.T) {
+	coach, other := uuid.New(), uuid.New()
+	group, special := uuid.New(), uuid.New()
+
+	fallback := model.PayrollRule{ID: uuid.New(), CoachUserID: coach, Kind: string(model.PayrollPerSession), Rate: 5000}
+	own := model.PayrollRule{ID: uuid.New(), CoachUserID: coach, GroupID: &special, Kind: string(model.PayrollRevenueShare), Rate: 30}
+	rules := payroll.NewRules([]model.PayrollRule{own, fallback})
+
+	if rule, ok := rules.For(coach, special); !ok || rule.ID != own.ID {
+		t.Errorf("expected the group's own rule, got %+v, %v", rule, ok)
+	}
+	if rule, ok := rules.For(coach, group); !ok || rule.ID != fallback.ID {
+		t.Errorf("expected the coach's rule without a group, got %+v, %v", rule, ok)
+	}
+	if _, ok := rules.For(other, special); ok {
+		t.Error("expected no rule for another coach")
+	}
+}
+
+func TestItems(t *testing.T) {
+	anna, boris := uuid.New(), uuid.New()
+	judo, karate, chess := uuid.New(), uuid.New(), uuid.New()
+	day := time.Date(2026, 9, 1, 18, 0, 0, 0, time.UTC)
+
+	rules := payroll.NewRules([]model.PayrollRule{
+		{ID: uuid.New(), CoachUserID: anna, GroupID: &judo, Kind: string(model.PayrollPerAttendee), Rate: 700},
+		{ID: uuid.New(), CoachUserID: anna, Kind: string(model.PayrollPerSession), Rate: 5000},
+		{ID: uuid.New(), CoachUserID: boris, Kind: string(model.PayrollRevenueShare), Rate: 33.3},
+	})
+
+	sessions := []payroll.Session{
+		{ID: uuid.New(), GroupID: judo, GroupTitle: "Judo", CoachUserID: anna, CoachName: "Anna", StartAt: day.Add(48 * time.Hour), Attendees: 12},
+		{ID: uuid.New(), GroupID: judo, GroupTitle: "Judo", CoachUserID: anna, CoachName: "Anna", StartAt: day.Add(72 * time.Hour)},
+		{ID: uuid.New(), GroupID: karate, GroupTitle: "Karate", CoachUserID: anna, CoachName: "Anna", StartAt: day, Attendees: 3},
+		// Boris shares revenue: sessions don't count
+		{ID: uuid.New(), GroupID: chess, GroupTitle: "Chess", CoachUserID: boris, CoachName: "Boris", StartAt: day, Attendees: 8},
+	}
+	payments := []payroll.Payment{
+		{ID: uuid.New(), GroupID: chess, GroupTitle: "Chess", CoachUserID: boris, CoachName: "Boris", PaidAt: day, Amount: 25000},
+		// Anna is paid per session for karate
+		{ID: uuid.New(), GroupID: karate, GroupTitle: "Karate", CoachUserID: anna, CoachName: "Anna", PaidAt: day, Amount: 18000},
+	}
+
+	items := payroll.Items(rules, sessions, payments)
+
+	expected := []struct {
+		coach, group, kind string
+		quantity, amount   float64
+	}{
+		{"Anna", "Karate", "per_session", 1, 5000},
+		{"Anna", "Judo", "per_attendee", 12, 8400},
+		{"Boris", "Chess", "revenue_share", 25000, 8325},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %+v", len(expected), items)
+	}
+	for i, e := range expected {
+		item := items[i]
+		if item.CoachName != e.coach || item.GroupTitle != e.group || item.Kind != e.kind ||
+			item.Quantity != e.quantity || item.Amount != e.amount {
+			t.Errorf("item %d = %s/%s/%s %.2f x %.2f = %.2f, expected %+v",
+				i, item.CoachName, item.GroupTitle, item.Kind, item.Quantity, item.Rate, item.Amount, e)
+		}
+		if item.RuleID == nil {
+			t.Errorf("item %d has no rule", i)
+		}
+	}
+	if items[0].SessionID == nil || *items[0].SessionID != sessions[2].ID {
+		t.Errorf("expected the karate session on the first line, got %v", items[0].SessionID)
+	}
+	if items[2].PaymentID == nil || *items[2].PaymentID != payments[0].ID {
+		t.Errorf("expected the chess payment on the last line, got %v", items[2].PaymentID)
+	}
+
+	coaches, total := payroll.Totals(items)
+	if total != 21725 {
+		t.Errorf("expected a total of 21725, got %.2f", total)
+	}
+	if len(coaches) != 2 {
+		t.Fatalf("expected 2 coaches, got %+v", coaches)
+	}
+	if c := coaches[0]; c.CoachName != "Anna" || c.Sessions != 2 || c.Attendees != 12 || c.Amount != 13400 {
+		t.Errorf("unexpected total for Anna: %+v", c)
+	}
+	if c := coaches[1]; c.CoachName != "Boris" || c.Sessions != 0 || c.Revenue != 25000 || c.Amount != 8325 {
+		t.Errorf("unexpected total for Boris: %+v", c)
+	}
+}
+
+func TestItems_NoRules(t *testing.T) {
+	session := payroll.Session{ID: uuid.New(), GroupID: uuid.New(), CoachUserID: uuid.New(), StartAt: time.Now(), Attendees: 5}
+
+	items := payroll.Items(payroll.NewRules(nil), []payroll.Session{session}, nil)
+	if len(items) != 0 {
+		t.Errorf("expected no items, got %+v", items)
+	}
+}
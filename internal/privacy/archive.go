@@ -0,0 +1,185 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+// WriteArchive writes the dossier as a ZIP:
+//
+//	data.json          everything, including the students' profiles
+//	students.csv       the students with their parent contact
+//	subscriptions.csv
+//	attendances.csv
+//	payments.csv
+//	documents/         the uploaded files, as <document id>_<filename>
+//
+// Documents whose file is missing from the storage are left out.
+func WriteArchive(ctx context.Context, w io.Writer, d *Dossier, files storage.Storage) error {
+	zw := zip.NewWriter(w)
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(zw, "data.json", d.GeneratedAt, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	for _, table := range tables(d) {
+		if err := writeFile(zw, table.name, d.GeneratedAt, table.writeCSV); err != nil {
+			return err
+		}
+	}
+
+	for _, st := range d.Students {
+		for _, doc := range st.Documents {
+			name := "documents/" + doc.ID.String() + "_" + safeFilename(doc.Filename)
+			if err := copyDocument(ctx, zw, name, doc.CreatedAt, doc.StorageKey, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, modified time.Time, fn func(w io.Writer) error) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	return fn(w)
+}
+
+func copyDocument(ctx context.Context, zw *zip.Writer, name string, modified time.Time, key string, files storage.Storage) error {
+	body, err := files.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("privacy: read document %s: %w", key, err)
+	}
+	defer body.Close()
+
+	return writeFile(zw, name, modified, func(w io.Writer) error {
+		_, err := io.Copy(w, body)
+		return err
+	})
+}
+
+// safeFilename keeps a stored filename from creating folders in the ZIP
+func safeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// ==================== CSV tables ====================
+
+type table struct {
+	name    string
+	headers []string
+	rows    [][]string
+}
+
+func (t table) writeCSV(w io.Writer) error {
+	// The BOM makes Excel read the file as UTF-8
+	io.WriteString(w, "\ufeff")
+
+	cw := csv.NewWriter(w)
+	cw.Write(t.headers)
+	return cw.WriteAll(t.rows)
+}
+
+func tables(d *Dossier) []table {
+	students := table{
+		name:    "students.csv",
+		headers: []string{"id", "name", "birth_date", "parent_name", "parent_phone", "parent_email", "notes", "created_at", "archived_at", "erased_at"},
+	}
+	for _, sd := range d.Students {
+		st := sd.Student
+		var parentName, parentPhone, parentEmail string
+		if st.ParentContact != nil {
+			parentName, parentPhone, parentEmail = st.ParentContact.Name, st.ParentContact.Phone, st.ParentContact.Email
+		}
+		students.rows = append(students.rows, []string{
+			st.ID.String(), st.Name, date(st.BirthDate), parentName, parentPhone, parentEmail, st.Notes,
+			timestamp(&st.CreatedAt), timestamp(st.ArchivedAt), timestamp(st.ErasedAt),
+		})
+	}
+
+	subscriptions := table{
+		name:    "subscriptions.csv",
+		headers: []string{"id", "student_id", "group", "kind", "status", "total_sessions", "remaining_sessions", "price", "starts_at", "expires_at", "created_at"},
+	}
+	for _, sub := range d.Subscriptions {
+		subscriptions.rows = append(subscriptions.rows, []string{
+			sub.ID.String(), sub.StudentID.String(), sub.GroupTitle, sub.Kind, sub.Status,
+			strconv.Itoa(sub.TotalSessions), strconv.Itoa(sub.RemainingSessions), money(sub.Price),
+			timestamp(sub.StartsAt), timestamp(sub.ExpiresAt), timestamp(&sub.CreatedAt),
+		})
+	}
+
+	attendances := table{
+		name:    "attendances.csv",
+		headers: []string{"id", "student_id", "group", "session_start_at", "status", "kind", "subscription_id", "noted_at"},
+	}
+	for _, a := range d.Attendances {
+		var subscriptionID string
+		if a.SubscriptionID != nil {
+			subscriptionID = a.SubscriptionID.String()
+		}
+		attendances.rows = append(attendances.rows, []string{
+			a.ID.String(), a.StudentID.String(), a.GroupTitle, timestamp(&a.SessionStartAt),
+			a.Status, a.Kind, subscriptionID, timestamp(&a.NotedAt),
+		})
+	}
+
+	payments := table{
+		name:    "payments.csv",
+		headers: []string{"id", "student_id", "subscription_id", "amount", "currency", "method", "status", "paid_at", "created_at"},
+	}
+	for _, p := range d.Payments {
+		payments.rows = append(payments.rows, []string{
+			p.ID.String(), p.StudentID.String(), p.SubscriptionID.String(), money(p.Amount), p.Currency,
+			p.Method, p.Status, timestamp(p.PaidAt), timestamp(&p.CreatedAt),
+		})
+	}
+
+	return []table{students, subscriptions, attendances, payments}
+}
+
+func date(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func timestamp(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,82 @@
+// Package privacy answers guardians' requests about the personal data held
+// on their children: it finds a guardian's students by the contacts they
+// gave and packs everything recorded about them into a ZIP of JSON and CSV
+// files.
+package privacy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/importer"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+// minPhoneDigits keeps a partial number from matching other families
+const minPhoneDigits = 7
+
+// ErasedName replaces the name of an erased student
+const ErasedName = "Erased student"
+
+// Guardian is who a request came from, known by the contacts they gave
+// the club
+type Guardian struct {
+	Email string
+	Phone string
+}
+
+// Valid reports whether the guardian has a contact students can be matched
+// on
+func (g Guardian) Valid() bool {
+	return normalizeEmail(g.Email) != "" || len(importer.NormalizePhone(g.Phone)) >= minPhoneDigits
+}
+
+// Match returns the students whose parent contact has the guardian's email
+// or phone. Emails are compared ignoring case; phones by their digits, with
+// a leading 8 read as the country code 7.
+func (g Guardian) Match(students []model.Student) []model.Student {
+	email := normalizeEmail(g.Email)
+	phone := importer.NormalizePhone(g.Phone)
+	if len(phone) < minPhoneDigits {
+		phone = ""
+	}
+
+	matched := []model.Student{}
+	for _, st := range students {
+		if st.ParentContact == nil {
+			continue
+		}
+		if (email != "" && normalizeEmail(st.ParentContact.Email) == email) ||
+			(phone != "" && importer.NormalizePhone(st.ParentContact.Phone) == phone) {
+			matched = append(matched, st)
+		}
+	}
+	return matched
+}
+
+// Dossier is everything held about the students of a request
+type Dossier struct {
+	Request     model.DataRequest `json:"request"`
+	Club        string            `json:"club"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Students    []StudentData     `json:"students"`
+
+	Subscriptions []repository.StudentSubscription `json:"subscriptions"`
+	Attendances   []repository.StudentAttendance   `json:"attendances"`
+	Payments      []repository.StudentPayment      `json:"payments"`
+}
+
+// StudentData is a student with their profile
+type StudentData struct {
+	Student             model.Student              `json:"student"`
+	Medical             *model.StudentMedical      `json:"medical,omitempty"`
+	MedicalCertificates []model.MedicalCertificate `json:"medical_certificates"`
+	EmergencyContacts   []model.EmergencyContact   `json:"emergency_contacts"`
+	Consents            []model.StudentConsent     `json:"consents"`
+	Documents           []model.StudentDocument    `json:"documents"`
+}
+
+func normalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,171 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/privacy"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+func student(name, phone, email string) model.Student {
+	return model.Student{
+		ID:            uuid.New(),
+		Name:          name,
+		ParentContact: &model.ParentContact{Name: "Parent", Phone: phone, Email: email},
+	}
+}
+
+func TestGuardian_Match(t *testing.T) {
+	first := student("Иванов Иван", "8 (701) 111-22-33", "")
+	sibling := student("Иванова Инна", "+7 701 111 22 33", "Ivanova@Example.com")
+	other := student("Петров Пётр", "+77012223344", "petrov@example.com")
+	noContact := model.Student{ID: uuid.New(), Name: "Ким Алина"}
+	students := []model.Student{first, sibling, other, noContact}
+
+	tests := []struct {
+		name     string
+		guardian privacy.Guardian
+		expected []uuid.UUID
+	}{
+		{"by phone in any format", privacy.Guardian{Phone: "87011112233"}, []uuid.UUID{first.ID, sibling.ID}},
+		{"by email ignoring case", privacy.Guardian{Email: " ivanova@example.com"}, []uuid.UUID{sibling.ID}},
+		{"by either", privacy.Guardian{Email: "petrov@example.com", Phone: "+7 701 111 22 33"}, []uuid.UUID{first.ID, sibling.ID, other.ID}},
+		{"partial phone matches nothing", privacy.Guardian{Phone: "2233"}, nil},
+		{"unknown guardian", privacy.Guardian{Email: "nobody@example.com"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := tt.guardian.Match(students)
+			if len(matched) != len(tt.expected) {
+				t.Fatalf("expected %d students, got %d", len(tt.expected), len(matched))
+			}
+			for i, st := range matched {
+				if st.ID != tt.expected[i] {
+					t.Errorf("student %d: expected %s, got %s (%s)", i, tt.expected[i], st.ID, st.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestGuardian_Valid(t *testing.T) {
+	tests := []struct {
+		guardian privacy.Guardian
+		expected bool
+	}{
+		{privacy.Guardian{Email: "a@example.com"}, true},
+		{privacy.Guardian{Phone: "+7 701 111 22 33"}, true},
+		{privacy.Guardian{Phone: "12-34"}, false},
+		{privacy.Guardian{Email: "  "}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.guardian.Valid(); got != tt.expected {
+			t.Errorf("%+v: expected %v, got %v", tt.guardian, tt.expected, got)
+		}
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	ctx := context.Background()
+	files := storage.NewLocal(t.TempDir())
+
+	st := student("Иванов Иван", "+77011112233", "ivanov@example.com")
+	birth := time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC)
+	st.BirthDate = &birth
+
+	stored := model.StudentDocument{ID: uuid.New(), StudentID: st.ID, Filename: "../waiver.pdf", StorageKey: "students/" + st.ID.String() + "/waiver"}
+	missing := model.StudentDocument{ID: uuid.New(), StudentID: st.ID, Filename: "lost.pdf", StorageKey: "students/" + st.ID.String() + "/lost"}
+	if err := files.Put(ctx, stored.StorageKey, strings.NewReader("%PDF-1.4"), 8, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := repository.StudentSubscription{
+		Subscription: model.Subscription{ID: uuid.New(), StudentID: st.ID, TotalSessions: 8, RemainingSessions: 5, Price: 20000, Status: "active", Kind: "package"},
+		GroupTitle:   "Дзюдо",
+	}
+	dossier := &privacy.Dossier{
+		Club:          "Test Club",
+		GeneratedAt:   time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Students:      []privacy.StudentData{{Student: st, Documents: []model.StudentDocument{stored, missing}}},
+		Subscriptions: []repository.StudentSubscription{sub},
+		Payments: []repository.StudentPayment{{
+			Payment:   model.Payment{ID: uuid.New(), SubscriptionID: sub.ID, Amount: 20000, Currency: "KZT", Method: "cash", Status: "succeeded"},
+			StudentID: st.ID,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := privacy.WriteArchive(ctx, &buf, dossier, files); err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a ZIP: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+
+	expected := []string{"data.json", "students.csv", "subscriptions.csv", "attendances.csv", "payments.csv", "documents/" + stored.ID.String() + "_waiver.pdf"}
+	if len(contents) != len(expected) {
+		t.Errorf("expected %d files, got %v", len(expected), keys(contents))
+	}
+	for _, name := range expected {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("missing %s, got %v", name, keys(contents))
+		}
+	}
+
+	var decoded privacy.Dossier
+	if err := json.Unmarshal([]byte(contents["data.json"]), &decoded); err != nil {
+		t.Fatalf("data.json: %v", err)
+	}
+	if len(decoded.Students) != 1 || decoded.Students[0].Student.ParentContact.Email != "ivanov@example.com" {
+		t.Errorf("data.json lacks the student with the parent contact: %+v", decoded.Students)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(contents["students.csv"], "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatalf("students.csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and 1 row, got %v", records)
+	}
+	row := records[1]
+	if row[1] != "Иванов Иван" || row[2] != "2015-03-04" || row[4] != "+77011112233" || row[5] != "ivanov@example.com" {
+		t.Errorf("unexpected student row %v", row)
+	}
+
+	if !strings.Contains(contents["subscriptions.csv"], "Дзюдо") || !strings.Contains(contents["payments.csv"], "20000.00") {
+		t.Errorf("subscriptions or payments missing:\n%s\n%s", contents["subscriptions.csv"], contents["payments.csv"])
+	}
+}
+
+func keys(m map[string]string) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
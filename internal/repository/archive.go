@@ -0,0 +1,28 @@
+package repository
+
+// ArchiveFilter selects records of a list by whether they are archived
+type ArchiveFilter string
+
+const (
+	ActiveOnly   ArchiveFilter = ""
+	ArchivedOnly ArchiveFilter = "archived"
+	AnyArchived  ArchiveFilter = "all"
+)
+
+// condition returns the SQL condition of the filter on the archived_at
+// column of the table with the given alias ("" for none)
+func (f ArchiveFilter) condition(alias string) string {
+	column := "archived_at"
+	if alias != "" {
+		column = alias + ".archived_at"
+	}
+
+	switch f {
+	case ArchivedOnly:
+		return column + " IS NOT NULL"
+	case AnyArchived:
+		return "TRUE"
+	default:
+		return column + " IS NULL"
+	}
+}
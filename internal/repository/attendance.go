@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/neo/trainer-plus/internal/model"
 )
 
@@ -36,15 +37,18 @@ func (r *AttendanceRepository) Create(ctx context.Context, att *model.Attendance
 // CreateInTx creates attendance within a transaction
 func (r *AttendanceRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, att *model.Attendance) error {
 	query := `
-		INSERT INTO attendances (session_id, student_id, subscription_id, status, noted_by)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO attendances (session_id, student_id, subscription_id, makeup_credit_id, kiosk_device_id, status, kind, noted_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, noted_at`
 
 	return tx.QueryRowxContext(ctx, query,
 		att.SessionID,
 		att.StudentID,
 		att.SubscriptionID,
+		att.MakeupCreditID,
+		att.KioskDeviceID,
 		att.Status,
+		att.Kind,
 		att.NotedBy,
 	).Scan(&att.ID, &att.NotedAt)
 }
@@ -98,6 +102,110 @@ func (r *AttendanceRepository) Update(ctx context.Context, att *model.Attendance
 	return nil
 }
 
+// UpdateInTx updates attendance status and the credit it consumed
+// within a transaction
+func (r *AttendanceRepository) UpdateInTx(ctx context.Context, tx *sqlx.Tx, att *model.Attendance) error {
+	query := `
+		UPDATE attendances 
+		SET status = $2, noted_by = $3, subscription_id = $4, makeup_credit_id = $5, noted_at = now()
+		WHERE id = $1
+		RETURNING noted_at`
+
+	err := tx.QueryRowxContext(ctx, query,
+		att.ID,
+		att.Status,
+		att.NotedBy,
+		att.SubscriptionID,
+		att.MakeupCreditID,
+	).Scan(&att.NotedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteInTx removes attendance within a transaction
+func (r *AttendanceRepository) DeleteInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM attendances WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByIDForUpdate locks and returns one attendance
+// Must be called within a transaction
+func (r *AttendanceRepository) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*model.Attendance, error) {
+	var att model.Attendance
+	err := tx.GetContext(ctx, &att, `SELECT * FROM attendances WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &att, nil
+}
+
+// GetBySessionForUpdate locks and returns the attendance of a session,
+// keyed by student
+// Must be called within a transaction
+func (r *AttendanceRepository) GetBySessionForUpdate(ctx context.Context, tx *sqlx.Tx, sessionID uuid.UUID) (map[uuid.UUID]*model.Attendance, error) {
+	var rows []model.Attendance
+	query := `SELECT * FROM attendances WHERE session_id = $1 FOR UPDATE`
+
+	if err := tx.SelectContext(ctx, &rows, query, sessionID); err != nil {
+		return nil, err
+	}
+
+	byStudent := make(map[uuid.UUID]*model.Attendance, len(rows))
+	for i := range rows {
+		byStudent[rows[i].StudentID] = &rows[i]
+	}
+	return byStudent, nil
+}
+
+// GetSharedSessionsForUpdate locks and returns the attendance of a student
+// at sessions another student attended as well
+// Must be called within a transaction
+func (r *AttendanceRepository) GetSharedSessionsForUpdate(ctx context.Context, tx *sqlx.Tx, studentID, otherID uuid.UUID) ([]model.Attendance, error) {
+	var rows []model.Attendance
+	query := `
+		SELECT * FROM attendances
+		WHERE student_id = $1
+		  AND session_id IN (SELECT session_id FROM attendances WHERE student_id = $2)
+		FOR UPDATE`
+
+	if err := tx.SelectContext(ctx, &rows, query, studentID, otherID); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// CountTrialsInTx counts the trial visits a student attended in a group,
+// not counting the attendance being changed
+// Must be called within a transaction
+func (r *AttendanceRepository) CountTrialsInTx(ctx context.Context, tx *sqlx.Tx, studentID, groupID uuid.UUID, excludeID uuid.UUID) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM attendances a
+		JOIN sessions s ON s.id = a.session_id
+		WHERE a.student_id = $1
+		  AND s.group_id = $2
+		  AND a.kind = 'trial'
+		  AND a.status = 'present'
+		  AND a.id <> $3`
+
+	err := tx.GetContext(ctx, &count, query, studentID, groupID, excludeID)
+	return count, err
+}
+
 func (r *AttendanceRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM attendances WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -112,6 +220,13 @@ func (r *AttendanceRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// IsDuplicateAttendance reports whether err is the one-attendance-per-student
+// unique violation of a session
+func IsDuplicateAttendance(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "attendances_session_id_student_id_key"
+}
+
 // Exists checks if attendance already exists for session+student
 func (r *AttendanceRepository) Exists(ctx context.Context, sessionID, studentID uuid.UUID) (bool, error) {
 	var exists bool
@@ -153,7 +268,7 @@ func (r *AttendanceRepository) GetStatsByGroup(ctx context.Context, groupID uuid
 	var stats AttendanceStats
 	query := `
 		SELECT 
-			(SELECT COUNT(*) FROM sessions WHERE group_id = $1) as total_sessions,
+			(SELECT COUNT(*) FROM sessions WHERE group_id = $1 AND status = 'scheduled') as total_sessions,
 			COUNT(*) as total_attendance,
 			COUNT(CASE WHEN a.status = 'present' THEN 1 END) as present_count,
 			COUNT(CASE WHEN a.status = 'absent' THEN 1 END) as absent_count,
@@ -0,0 +1,173 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+// argCheckDriver fails every statement whose highest $N placeholder does
+// not match the number of arguments bound to it, as Postgres would. Queries
+// return one row for their RETURNING columns: a fresh id for *id columns
+// and the current time for the rest.
+type argCheckDriver struct{}
+
+func (argCheckDriver) Open(string) (driver.Conn, error) { return argCheckConn{}, nil }
+
+type argCheckConn struct{}
+
+func (argCheckConn) Prepare(query string) (driver.Stmt, error) { return argCheckStmt{query}, nil }
+func (argCheckConn) Close() error                              { return nil }
+func (argCheckConn) Begin() (driver.Tx, error)                 { return argCheckTx{}, nil }
+
+type argCheckTx struct{}
+
+func (argCheckTx) Commit() error   { return nil }
+func (argCheckTx) Rollback() error { return nil }
+
+type argCheckStmt struct{ query string }
+
+var (
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+	returning   = regexp.MustCompile(`(?s)RETURNING\s+(.+)$`)
+)
+
+func (s argCheckStmt) check(args []driver.Value) error {
+	highest := 0
+	for _, m := range placeholder.FindAllStringSubmatch(s.query, -1) {
+		if n, _ := strconv.Atoi(m[1]); n > highest {
+			highest = n
+		}
+	}
+	if highest != len(args) {
+		return fmt.Errorf("query has %d placeholders but got %d args", highest, len(args))
+	}
+	return nil
+}
+
+func (s argCheckStmt) Close() error  { return nil }
+func (s argCheckStmt) NumInput() int { return -1 }
+
+func (s argCheckStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.check(args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s argCheckStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.check(args); err != nil {
+		return nil, err
+	}
+	rows := &argCheckRows{}
+	if m := returning.FindStringSubmatch(s.query); m != nil {
+		for _, col := range strings.Split(m[1], ",") {
+			rows.columns = append(rows.columns, strings.TrimSpace(col))
+		}
+	}
+	return rows, nil
+}
+
+type argCheckRows struct {
+	columns []string
+	done    bool
+}
+
+func (r *argCheckRows) Columns() []string { return r.columns }
+func (r *argCheckRows) Close() error      { return nil }
+
+func (r *argCheckRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	for i, col := range r.columns {
+		if strings.HasSuffix(col, "id") {
+			dest[i] = uuid.New().String()
+		} else {
+			dest[i] = time.Now()
+		}
+	}
+	return nil
+}
+
+var registerArgCheck sync.Once
+
+func newArgCheckDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	registerArgCheck.Do(func() { sql.Register("argcheck", argCheckDriver{}) })
+	db, err := sqlx.Open("argcheck", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAttendanceRepository_InsertArgs(t *testing.T) {
+	repo := repository.NewAttendanceRepository(newArgCheckDB(t))
+	ctx := context.Background()
+
+	newAttendance := func() *model.Attendance {
+		return &model.Attendance{
+			SessionID: uuid.New(),
+			StudentID: uuid.New(),
+			Status:    string(model.AttendancePresent),
+			Kind:      string(model.AttendanceDropIn),
+			NotedBy:   uuid.New(),
+		}
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		att := newAttendance()
+		if err := repo.Create(ctx, att); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if att.ID == uuid.Nil {
+			t.Fatal("Create did not scan the new id")
+		}
+	})
+
+	t.Run("CreateInTx", func(t *testing.T) {
+		tx, err := repo.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("BeginTx: %v", err)
+		}
+		defer tx.Rollback()
+
+		att := newAttendance()
+		if err := repo.CreateInTx(ctx, tx, att); err != nil {
+			t.Fatalf("CreateInTx: %v", err)
+		}
+		if att.ID == uuid.Nil {
+			t.Fatal("CreateInTx did not scan the new id")
+		}
+	})
+
+	t.Run("UpdateInTx", func(t *testing.T) {
+		tx, err := repo.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("BeginTx: %v", err)
+		}
+		defer tx.Rollback()
+
+		att := newAttendance()
+		att.ID = uuid.New()
+		if err := repo.UpdateInTx(ctx, tx, att); err != nil {
+			t.Fatalf("UpdateInTx: %v", err)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// AttributeFilter selects students or groups by tags and custom fields.
+// Every tag and every field value must match.
+type AttributeFilter struct {
+	Tags   []string
+	Fields model.CustomValues
+}
+
+// condition returns the SQL condition of the filter on the table with the
+// given alias ("" for none), appending its arguments to args
+func (f AttributeFilter) condition(alias string, args *[]interface{}) string {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	var conditions []string
+	if len(f.Tags) > 0 {
+		*args = append(*args, pq.StringArray(f.Tags))
+		conditions = append(conditions, prefix+"tags @> $"+strconv.Itoa(len(*args))+"::text[]")
+	}
+	if len(f.Fields) > 0 {
+		*args = append(*args, customValuesJSON(f.Fields))
+		conditions = append(conditions, prefix+"custom_fields @> $"+strconv.Itoa(len(*args))+"::jsonb")
+	}
+
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func customValuesJSON(v model.CustomValues) []byte {
+	if v == nil {
+		v = model.CustomValues{}
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func customValuesFromJSON(data []byte) model.CustomValues {
+	v := model.CustomValues{}
+	if data != nil {
+		json.Unmarshal(data, &v)
+	}
+	return v
+}
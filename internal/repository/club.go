@@ -20,8 +20,8 @@ func NewClubRepository(db *sqlx.DB) *ClubRepository {
 
 func (r *ClubRepository) Create(ctx context.Context, club *model.Club) error {
 	query := `
-		INSERT INTO clubs (owner_user_id, name, address, phone, currency)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO clubs (owner_user_id, name, address, phone, currency, timezone, makeup_valid_days, trial_visits_per_group)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at`
 
 	return r.db.QueryRowxContext(ctx, query,
@@ -30,6 +30,9 @@ func (r *ClubRepository) Create(ctx context.Context, club *model.Club) error {
 		club.Address,
 		club.Phone,
 		club.Currency,
+		club.Timezone,
+		club.MakeupValidDays,
+		club.TrialVisitsPerGroup,
 	).Scan(&club.ID, &club.CreatedAt)
 }
 
@@ -44,9 +47,9 @@ func (r *ClubRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Club
 	return &club, err
 }
 
-func (r *ClubRepository) GetByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Club, error) {
+func (r *ClubRepository) GetByOwner(ctx context.Context, ownerID uuid.UUID, archived ArchiveFilter) ([]model.Club, error) {
 	var clubs []model.Club
-	query := `SELECT * FROM clubs WHERE owner_user_id = $1 ORDER BY created_at DESC`
+	query := `SELECT * FROM clubs WHERE owner_user_id = $1 AND ` + archived.condition("") + ` ORDER BY created_at DESC`
 
 	err := r.db.SelectContext(ctx, &clubs, query, ownerID)
 	return clubs, err
@@ -55,7 +58,8 @@ func (r *ClubRepository) GetByOwner(ctx context.Context, ownerID uuid.UUID) ([]m
 func (r *ClubRepository) Update(ctx context.Context, club *model.Club) error {
 	query := `
 		UPDATE clubs 
-		SET name = $2, address = $3, phone = $4, currency = $5
+		SET name = $2, address = $3, phone = $4, currency = $5, timezone = $6,
+		    makeup_valid_days = $7, trial_visits_per_group = $8
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -64,6 +68,9 @@ func (r *ClubRepository) Update(ctx context.Context, club *model.Club) error {
 		club.Address,
 		club.Phone,
 		club.Currency,
+		club.Timezone,
+		club.MakeupValidDays,
+		club.TrialVisitsPerGroup,
 	)
 	if err != nil {
 		return err
@@ -76,8 +83,25 @@ func (r *ClubRepository) Update(ctx context.Context, club *model.Club) error {
 	return nil
 }
 
-func (r *ClubRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM clubs WHERE id = $1`
+// Archive hides a club from its owner's list, its public pages and
+// reminders; everything recorded for it is kept. Archiving an archived club
+// is a no-op.
+func (r *ClubRepository) Archive(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE clubs SET archived_at = COALESCE(archived_at, now()) WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *ClubRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE clubs SET archived_at = NULL WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type CustomFieldRepository struct {
+	db *sqlx.DB
+}
+
+func NewCustomFieldRepository(db *sqlx.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+// customFieldTables maps the entity of a field to the table holding its
+// values
+var customFieldTables = map[string]string{
+	string(model.CustomFieldStudent): "students",
+	string(model.CustomFieldGroup):   "groups",
+}
+
+func (r *CustomFieldRepository) Create(ctx context.Context, field *model.CustomField) error {
+	query := `
+		INSERT INTO custom_fields (club_id, entity, key, label, type, options)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		field.ClubID,
+		field.Entity,
+		field.Key,
+		field.Label,
+		field.Type,
+		pq.StringArray(nonNilStrings(field.Options)),
+	).Scan(&field.ID, &field.CreatedAt)
+}
+
+func (r *CustomFieldRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.CustomField, error) {
+	var field customFieldDB
+	query := `SELECT * FROM custom_fields WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &field, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return field.toModel(), nil
+}
+
+// GetByClub returns a club's fields of the entity, or of both entities
+// when it is empty, in the order they were added
+func (r *CustomFieldRepository) GetByClub(ctx context.Context, clubID uuid.UUID, entity model.CustomFieldEntity) ([]model.CustomField, error) {
+	var rows []customFieldDB
+	query := `
+		SELECT * FROM custom_fields
+		WHERE club_id = $1 AND ($2 = '' OR entity = $2)
+		ORDER BY entity, created_at, key`
+
+	if err := r.db.SelectContext(ctx, &rows, query, clubID, string(entity)); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.CustomField, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result, nil
+}
+
+// Update changes the label and options of a field. Values of an enum field
+// that are no longer among its options are removed.
+func (r *CustomFieldRepository) Update(ctx context.Context, field *model.CustomField) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE custom_fields SET label = $2, options = $3 WHERE id = $1`
+	result, err := tx.ExecContext(ctx, query, field.ID, field.Label, pq.StringArray(nonNilStrings(field.Options)))
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+
+	if field.Type == string(model.CustomFieldEnum) {
+		clear := `
+			UPDATE ` + customFieldTables[field.Entity] + `
+			SET custom_fields = custom_fields - $2
+			WHERE club_id = $1 AND NOT (custom_fields->>$2 = ANY($3::text[]))`
+		if _, err := tx.ExecContext(ctx, clear, field.ClubID, field.Key, pq.StringArray(nonNilStrings(field.Options))); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a field and its values
+func (r *CustomFieldRepository) Delete(ctx context.Context, field *model.CustomField) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM custom_fields WHERE id = $1`, field.ID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+
+	clear := `
+		UPDATE ` + customFieldTables[field.Entity] + `
+		SET custom_fields = custom_fields - $2
+		WHERE club_id = $1 AND custom_fields ? $2`
+	if _, err := tx.ExecContext(ctx, clear, field.ClubID, field.Key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsDuplicateFieldKey reports whether err is the per-club unique field key
+// violation
+func IsDuplicateFieldKey(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "custom_fields_club_entity_key"
+}
+
+// Helper struct for DB scanning with TEXT[]
+type customFieldDB struct {
+	model.CustomField
+	OptionsRaw pq.StringArray `db:"options"`
+}
+
+func (f *customFieldDB) toModel() *model.CustomField {
+	f.CustomField.Options = nonNilStrings(f.OptionsRaw)
+	return &f.CustomField
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/neo/trainer-plus/internal/model"
 )
 
@@ -21,8 +22,9 @@ func NewGroupRepository(db *sqlx.DB) *GroupRepository {
 
 func (r *GroupRepository) Create(ctx context.Context, group *model.Group) error {
 	query := `
-		INSERT INTO groups (club_id, title, sport, capacity, price, description, coach_user_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO groups (club_id, title, sport, capacity, price, description, coach_user_id, location_id, drop_in_price,
+		                    tags, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at`
 
 	return r.db.QueryRowxContext(ctx, query,
@@ -33,40 +35,56 @@ func (r *GroupRepository) Create(ctx context.Context, group *model.Group) error
 		group.Price,
 		group.Description,
 		group.CoachUserID,
+		group.LocationID,
+		group.DropInPrice,
+		pq.StringArray(nonNilStrings(group.Tags)),
+		customValuesJSON(group.CustomFields),
 	).Scan(&group.ID, &group.CreatedAt)
 }
 
 func (r *GroupRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Group, error) {
-	var group model.Group
+	var group groupDB
 	query := `SELECT * FROM groups WHERE id = $1`
 
 	err := r.db.GetContext(ctx, &group, query, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
-	return &group, err
+	if err != nil {
+		return nil, err
+	}
+	return group.toModel(), nil
 }
 
-func (r *GroupRepository) GetByClub(ctx context.Context, clubID uuid.UUID) ([]model.Group, error) {
-	var groups []model.Group
-	query := `SELECT * FROM groups WHERE club_id = $1 ORDER BY title`
+func (r *GroupRepository) GetByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) ([]model.Group, error) {
+	var rows []groupDB
+	args := []interface{}{clubID}
+	query := `
+		SELECT * FROM groups
+		WHERE club_id = $1 AND ` + archived.condition("") + ` AND ` + attrs.condition("", &args) + `
+		ORDER BY title`
 
-	err := r.db.SelectContext(ctx, &groups, query, clubID)
-	return groups, err
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return groupModels(rows), nil
 }
 
 func (r *GroupRepository) GetByCoach(ctx context.Context, coachID uuid.UUID) ([]model.Group, error) {
-	var groups []model.Group
-	query := `SELECT * FROM groups WHERE coach_user_id = $1 ORDER BY title`
+	var rows []groupDB
+	query := `SELECT * FROM groups WHERE coach_user_id = $1 AND archived_at IS NULL ORDER BY title`
 
-	err := r.db.SelectContext(ctx, &groups, query, coachID)
-	return groups, err
+	if err := r.db.SelectContext(ctx, &rows, query, coachID); err != nil {
+		return nil, err
+	}
+	return groupModels(rows), nil
 }
 
 func (r *GroupRepository) Update(ctx context.Context, group *model.Group) error {
 	query := `
 		UPDATE groups 
-		SET title = $2, sport = $3, capacity = $4, price = $5, description = $6, coach_user_id = $7
+		SET title = $2, sport = $3, capacity = $4, price = $5, description = $6, coach_user_id = $7,
+		    location_id = $8, drop_in_price = $9, tags = $10, custom_fields = $11
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -77,6 +95,10 @@ func (r *GroupRepository) Update(ctx context.Context, group *model.Group) error
 		group.Price,
 		group.Description,
 		group.CoachUserID,
+		group.LocationID,
+		group.DropInPrice,
+		pq.StringArray(nonNilStrings(group.Tags)),
+		customValuesJSON(group.CustomFields),
 	)
 	if err != nil {
 		return err
@@ -89,8 +111,25 @@ func (r *GroupRepository) Update(ctx context.Context, group *model.Group) error
 	return nil
 }
 
-func (r *GroupRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM groups WHERE id = $1`
+// Archive hides a group from lists, the public schedule, coaches and
+// reminders; its sessions and subscriptions are kept. Archiving an archived
+// group is a no-op.
+func (r *GroupRepository) Archive(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE groups SET archived_at = COALESCE(archived_at, now()) WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE groups SET archived_at = NULL WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
@@ -110,17 +149,56 @@ type GroupWithStats struct {
 	SessionsCount int `db:"sessions_count" json:"sessions_count"`
 }
 
-func (r *GroupRepository) GetByClubWithStats(ctx context.Context, clubID uuid.UUID) ([]GroupWithStats, error) {
-	var groups []GroupWithStats
+func (r *GroupRepository) GetByClubWithStats(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) ([]GroupWithStats, error) {
+	var rows []groupWithStatsDB
+	args := []interface{}{clubID, time.Now()}
 	query := `
 		SELECT 
 			g.*,
 			(SELECT COUNT(DISTINCT s.student_id) FROM subscriptions s WHERE s.group_id = g.id AND s.status = 'active') as student_count,
-			(SELECT COUNT(*) FROM sessions ses WHERE ses.group_id = g.id AND ses.start_at > $2) as sessions_count
+			(SELECT COUNT(*) FROM sessions ses WHERE ses.group_id = g.id AND ses.start_at > $2 AND ses.status = 'scheduled') as sessions_count
 		FROM groups g
-		WHERE g.club_id = $1
+		WHERE g.club_id = $1 AND ` + archived.condition("g") + ` AND ` + attrs.condition("g", &args) + `
 		ORDER BY g.title`
 
-	err := r.db.SelectContext(ctx, &groups, query, clubID, time.Now())
-	return groups, err
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	groups := make([]GroupWithStats, len(rows))
+	for i := range rows {
+		groups[i] = GroupWithStats{
+			Group:         *rows[i].toModel(),
+			StudentCount:  rows[i].StudentCount,
+			SessionsCount: rows[i].SessionsCount,
+		}
+	}
+	return groups, nil
+}
+
+// Helper structs for DB scanning with JSONB and TEXT[]
+type groupDB struct {
+	model.Group
+	TagsRaw         pq.StringArray `db:"tags"`
+	CustomFieldsRaw []byte         `db:"custom_fields"`
+}
+
+type groupWithStatsDB struct {
+	groupDB
+	StudentCount  int `db:"student_count"`
+	SessionsCount int `db:"sessions_count"`
+}
+
+func (g *groupDB) toModel() *model.Group {
+	g.Group.Tags = nonNilStrings(g.TagsRaw)
+	g.Group.CustomFields = customValuesFromJSON(g.CustomFieldsRaw)
+	return &g.Group
+}
+
+func groupModels(rows []groupDB) []model.Group {
+	result := make([]model.Group, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result
 }
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyTTL is how long a stored response is replayed for its key
+const IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key
+type IdempotencyRecord struct {
+	Endpoint    string `db:"endpoint"`
+	RequestHash string `db:"request_hash"`
+	StatusCode  int    `db:"status_code"`
+	Response    []byte `db:"response"`
+}
+
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Get returns the completed record for a key that has not expired
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*IdempotencyRecord, error) {
+	var rec IdempotencyRecord
+	query := `
+		SELECT endpoint, request_hash, status_code, response FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND status_code IS NOT NULL AND created_at > $3`
+
+	err := r.db.GetContext(ctx, &rec, query, userID, key, time.Now().Add(-IdempotencyTTL))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &rec, err
+}
+
+// ReserveInTx claims a key for the request being processed in tx. It
+// returns false if the key is already taken; a concurrent request with the
+// same key waits here until the first one commits or rolls back.
+// Must be called within a transaction
+func (r *IdempotencyRepository) ReserveInTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, key, endpoint, requestHash string) (bool, error) {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND created_at <= $3`,
+		userID, key, time.Now().Add(-IdempotencyTTL)); err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, endpoint, request_hash)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, userID, key, endpoint, requestHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// CompleteInTx stores the response of a reserved key
+// Must be called within a transaction
+func (r *IdempotencyRepository) CompleteInTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, key string, statusCode int, response interface{}) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = $3, response = $4 WHERE user_id = $1 AND key = $2`,
+		userID, key, statusCode, body)
+	return err
+}
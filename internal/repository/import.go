@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// ImportLease is how long a claimed import stays hidden from other workers.
+// Progress updates extend it.
+const ImportLease = 5 * time.Minute
+
+// importBatch is how many rows are written per statement
+const importBatch = 1000
+
+type ImportRepository struct {
+	db *sqlx.DB
+}
+
+func NewImportRepository(db *sqlx.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+// Create stores an uploaded file and its rows
+func (r *ImportRepository) Create(ctx context.Context, imp *model.Import, rows []model.ImportRow) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mappingJSON, _ := json.Marshal(imp.Mapping)
+	optionsJSON, _ := json.Marshal(imp.Options)
+
+	query := `
+		INSERT INTO imports (club_id, created_by, filename, headers, mapping, options, total_rows)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, status, created_at, updated_at`
+
+	err = tx.QueryRowxContext(ctx, query,
+		imp.ClubID,
+		imp.CreatedBy,
+		imp.Filename,
+		pq.StringArray(nonNilStrings(imp.Headers)),
+		mappingJSON,
+		optionsJSON,
+		len(rows),
+	).Scan(&imp.ID, &imp.Status, &imp.CreatedAt, &imp.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	imp.TotalRows = len(rows)
+
+	type rowData struct {
+		RowNumber int      `json:"row_number"`
+		Data      []string `json:"data"`
+	}
+	for start := 0; start < len(rows); start += importBatch {
+		end := min(start+importBatch, len(rows))
+		batch := make([]rowData, 0, end-start)
+		for _, row := range rows[start:end] {
+			batch = append(batch, rowData{RowNumber: row.RowNumber, Data: nonNilStrings(row.Data)})
+		}
+		batchJSON, _ := json.Marshal(batch)
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO import_rows (import_id, row_number, data)
+			SELECT $1, x.row_number, x.data
+			FROM jsonb_to_recordset($2::jsonb) AS x(row_number INT, data JSONB)`,
+			imp.ID, batchJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ImportRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Import, error) {
+	var imp importDB
+	err := r.db.GetContext(ctx, &imp, `SELECT * FROM imports WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return imp.toModel(), nil
+}
+
+func (r *ImportRepository) GetByClub(ctx context.Context, clubID uuid.UUID, limit int) ([]model.Import, error) {
+	var rows []importDB
+	query := `
+		SELECT * FROM imports
+		WHERE club_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &rows, query, clubID, limit); err != nil {
+		return nil, err
+	}
+
+	imports := make([]model.Import, len(rows))
+	for i := range rows {
+		imports[i] = *rows[i].toModel()
+	}
+	return imports, nil
+}
+
+// GetRows returns rows of an import in file order, only those with the
+// given status when it is not empty
+func (r *ImportRepository) GetRows(ctx context.Context, importID uuid.UUID, status string, limit, offset int) ([]model.ImportRow, error) {
+	query := `
+		SELECT * FROM import_rows
+		WHERE import_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY row_number
+		LIMIT $3 OFFSET $4`
+
+	return r.selectRows(ctx, query, importID, status, limit, offset)
+}
+
+// CountRows counts the rows of an import, only those with the given
+// status when it is not empty
+func (r *ImportRepository) CountRows(ctx context.Context, importID uuid.UUID, status string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM import_rows WHERE import_id = $1 AND ($2 = '' OR status = $2)`
+	err := r.db.GetContext(ctx, &count, query, importID, status)
+	return count, err
+}
+
+// GetAllRows returns every row of an import in file order
+func (r *ImportRepository) GetAllRows(ctx context.Context, importID uuid.UUID) ([]model.ImportRow, error) {
+	return r.selectRows(ctx, `SELECT * FROM import_rows WHERE import_id = $1 ORDER BY row_number`, importID)
+}
+
+func (r *ImportRepository) selectRows(ctx context.Context, query string, args ...any) ([]model.ImportRow, error) {
+	var rows []importRowDB
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.ImportRow, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result, nil
+}
+
+// Queue hands an import to the worker with the given status, mapping and
+// options. It returns ErrNotFound unless the import is in one of the from
+// statuses, so two requests cannot queue it twice.
+func (r *ImportRepository) Queue(ctx context.Context, imp *model.Import, status model.ImportStatus, from ...model.ImportStatus) error {
+	mappingJSON, _ := json.Marshal(imp.Mapping)
+	optionsJSON, _ := json.Marshal(imp.Options)
+
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+
+	query := `
+		UPDATE imports
+		SET status = $2, mapping = $3, options = $4, processed_rows = 0,
+		    error = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND status = ANY($5)
+		RETURNING status, processed_rows, updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		imp.ID,
+		status,
+		mappingJSON,
+		optionsJSON,
+		pq.StringArray(fromStatuses),
+	).Scan(&imp.Status, &imp.ProcessedRows, &imp.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	imp.Error = nil
+	return err
+}
+
+// ClaimNext leases the oldest import waiting for validation or commit.
+// It returns ErrNotFound when there is none; concurrent workers never
+// claim the same import.
+func (r *ImportRepository) ClaimNext(ctx context.Context) (*model.Import, error) {
+	var imp importDB
+	query := `
+		UPDATE imports
+		SET locked_until = now() + $1 * interval '1 second'
+		WHERE id = (
+			SELECT id FROM imports
+			WHERE status IN ('validating', 'committing')
+			  AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY updated_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	err := r.db.GetContext(ctx, &imp, query, ImportLease.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return imp.toModel(), nil
+}
+
+// SetProgress records how many rows the worker has processed and extends
+// its lease
+func (r *ImportRepository) SetProgress(ctx context.Context, id uuid.UUID, processed int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE imports
+		SET processed_rows = $2, locked_until = now() + $3 * interval '1 second'
+		WHERE id = $1`,
+		id, processed, ImportLease.Seconds())
+	return err
+}
+
+// SaveResults stores the outcome of validating rows
+func (r *ImportRepository) SaveResults(ctx context.Context, importID uuid.UUID, rows []model.ImportRow) error {
+	return saveImportResults(ctx, r.db, importID, rows)
+}
+
+// SaveResultsInTx stores the outcome of committing rows
+// Must be called within a transaction
+func (r *ImportRepository) SaveResultsInTx(ctx context.Context, tx *sqlx.Tx, importID uuid.UUID, rows []model.ImportRow) error {
+	return saveImportResults(ctx, tx, importID, rows)
+}
+
+func saveImportResults(ctx context.Context, exec sqlx.ExecerContext, importID uuid.UUID, rows []model.ImportRow) error {
+	type rowResult struct {
+		RowNumber      int        `json:"row_number"`
+		Status         string     `json:"status"`
+		Errors         []string   `json:"errors"`
+		DuplicateOf    *uuid.UUID `json:"duplicate_of"`
+		StudentID      *uuid.UUID `json:"student_id"`
+		SubscriptionID *uuid.UUID `json:"subscription_id"`
+	}
+
+	for start := 0; start < len(rows); start += importBatch {
+		end := min(start+importBatch, len(rows))
+		batch := make([]rowResult, 0, end-start)
+		for _, row := range rows[start:end] {
+			batch = append(batch, rowResult{
+				RowNumber:      row.RowNumber,
+				Status:         row.Status,
+				Errors:         nonNilStrings(row.Errors),
+				DuplicateOf:    row.DuplicateOf,
+				StudentID:      row.StudentID,
+				SubscriptionID: row.SubscriptionID,
+			})
+		}
+		batchJSON, _ := json.Marshal(batch)
+
+		_, err := exec.ExecContext(ctx, `
+			UPDATE import_rows r
+			SET status = x.status, errors = x.errors, duplicate_of = x.duplicate_of,
+			    student_id = x.student_id, subscription_id = x.subscription_id
+			FROM jsonb_to_recordset($2::jsonb) AS x(row_number INT, status TEXT, errors JSONB, duplicate_of UUID, student_id UUID, subscription_id UUID)
+			WHERE r.import_id = $1 AND r.row_number = x.row_number`,
+			importID, batchJSON)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FinishValidation records the counts of a validated import and releases
+// its lease
+func (r *ImportRepository) FinishValidation(ctx context.Context, imp *model.Import) error {
+	query := `
+		UPDATE imports
+		SET status = 'validated', processed_rows = total_rows, valid_rows = $2, invalid_rows = $3,
+		    duplicate_rows = $4, locked_until = NULL, updated_at = now()
+		WHERE id = $1
+		RETURNING status, processed_rows, updated_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		imp.ID,
+		imp.ValidRows,
+		imp.InvalidRows,
+		imp.DuplicateRows,
+	).Scan(&imp.Status, &imp.ProcessedRows, &imp.UpdatedAt)
+}
+
+// CompleteInTx records what a committed import created
+// Must be called within a transaction
+func (r *ImportRepository) CompleteInTx(ctx context.Context, tx *sqlx.Tx, imp *model.Import) error {
+	query := `
+		UPDATE imports
+		SET status = 'completed', processed_rows = total_rows, created_students = $2,
+		    created_subscriptions = $3, locked_until = NULL, updated_at = now(), completed_at = now()
+		WHERE id = $1
+		RETURNING status, processed_rows, updated_at, completed_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		imp.ID,
+		imp.CreatedStudents,
+		imp.CreatedSubscriptions,
+	).Scan(&imp.Status, &imp.ProcessedRows, &imp.UpdatedAt, &imp.CompletedAt)
+}
+
+// Fail stops an import with an error; it can be validated again
+func (r *ImportRepository) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE imports
+		SET status = 'failed', error = $2, locked_until = NULL, updated_at = now()
+		WHERE id = $1`,
+		id, message)
+	return err
+}
+
+// BeginTx starts the transaction an import is committed in
+func (r *ImportRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+type importDB struct {
+	model.Import
+	HeadersRaw pq.StringArray `db:"headers"`
+	MappingRaw []byte         `db:"mapping"`
+	OptionsRaw []byte         `db:"options"`
+}
+
+func (i *importDB) toModel() *model.Import {
+	i.Import.Headers = nonNilStrings(i.HeadersRaw)
+	i.Import.Mapping = map[string]string{}
+	json.Unmarshal(i.MappingRaw, &i.Import.Mapping)
+	json.Unmarshal(i.OptionsRaw, &i.Import.Options)
+	return &i.Import
+}
+
+type importRowDB struct {
+	model.ImportRow
+	DataRaw   []byte `db:"data"`
+	ErrorsRaw []byte `db:"errors"`
+}
+
+func (r *importRowDB) toModel() *model.ImportRow {
+	json.Unmarshal(r.DataRaw, &r.ImportRow.Data)
+	json.Unmarshal(r.ErrorsRaw, &r.ImportRow.Errors)
+	r.ImportRow.Data = nonNilStrings(r.ImportRow.Data)
+	r.ImportRow.Errors = nonNilStrings(r.ImportRow.Errors)
+	return &r.ImportRow
+}
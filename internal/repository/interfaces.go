@@ -12,9 +12,10 @@ import (
 type ClubRepositoryInterface interface {
 	Create(ctx context.Context, club *model.Club) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Club, error)
-	GetByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Club, error)
+	GetByOwner(ctx context.Context, ownerID uuid.UUID, archived ArchiveFilter) ([]model.Club, error)
 	Update(ctx context.Context, club *model.Club) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Archive(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
 	IsOwner(ctx context.Context, clubID, userID uuid.UUID) (bool, error)
 }
 
@@ -22,11 +23,12 @@ type ClubRepositoryInterface interface {
 type GroupRepositoryInterface interface {
 	Create(ctx context.Context, group *model.Group) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Group, error)
-	GetByClub(ctx context.Context, clubID uuid.UUID) ([]model.Group, error)
+	GetByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) ([]model.Group, error)
 	GetByCoach(ctx context.Context, coachID uuid.UUID) ([]model.Group, error)
-	GetByClubWithStats(ctx context.Context, clubID uuid.UUID) ([]GroupWithStats, error)
+	GetByClubWithStats(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) ([]GroupWithStats, error)
 	Update(ctx context.Context, group *model.Group) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Archive(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
 }
 
 // SessionRepositoryInterface defines the contract for session repository
@@ -46,11 +48,12 @@ type SessionRepositoryInterface interface {
 type StudentRepositoryInterface interface {
 	Create(ctx context.Context, student *model.Student) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Student, error)
-	GetByClub(ctx context.Context, clubID uuid.UUID, limit, offset int) ([]model.Student, error)
-	CountByClub(ctx context.Context, clubID uuid.UUID) (int, error)
-	Search(ctx context.Context, clubID uuid.UUID, searchTerm string) ([]model.Student, error)
+	GetByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter, limit, offset int) ([]model.Student, error)
+	CountByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) (int, error)
+	Search(ctx context.Context, clubID uuid.UUID, search StudentSearch) ([]model.Student, *StudentCursor, error)
 	Update(ctx context.Context, student *model.Student) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Archive(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
 }
 
 // UserRepositoryInterface defines the contract for user repository
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type KioskRepository struct {
+	db *sqlx.DB
+}
+
+func NewKioskRepository(db *sqlx.DB) *KioskRepository {
+	return &KioskRepository{db: db}
+}
+
+func (r *KioskRepository) Create(ctx context.Context, device *model.KioskDevice) error {
+	query := `
+		INSERT INTO kiosk_devices (club_id, location_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		device.ClubID,
+		device.LocationID,
+		device.Name,
+		device.TokenHash,
+		device.CreatedBy,
+	).Scan(&device.ID, &device.CreatedAt)
+}
+
+func (r *KioskRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.KioskDevice, error) {
+	var device model.KioskDevice
+	query := `SELECT * FROM kiosk_devices WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &device, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &device, err
+}
+
+func (r *KioskRepository) GetByClub(ctx context.Context, clubID uuid.UUID) ([]model.KioskDevice, error) {
+	var devices []model.KioskDevice
+	query := `SELECT * FROM kiosk_devices WHERE club_id = $1 ORDER BY revoked_at NULLS FIRST, name`
+
+	err := r.db.SelectContext(ctx, &devices, query, clubID)
+	return devices, err
+}
+
+// Authenticate returns the active device with the given token hash and
+// records that it was seen
+func (r *KioskRepository) Authenticate(ctx context.Context, tokenHash string) (*model.KioskDevice, error) {
+	var device model.KioskDevice
+	query := `
+		UPDATE kiosk_devices SET last_seen_at = now()
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		RETURNING *`
+
+	err := r.db.GetContext(ctx, &device, query, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &device, err
+}
+
+// Revoke disables a device; its token stops working immediately
+func (r *KioskRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE kiosk_devices SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
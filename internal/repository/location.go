@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type LocationRepository struct {
+	db *sqlx.DB
+}
+
+func NewLocationRepository(db *sqlx.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+func (r *LocationRepository) Create(ctx context.Context, location *model.Location) error {
+	openingHoursJSON, err := json.Marshal(openingHoursOrEmpty(location.OpeningHours))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO locations (club_id, name, address, capacity, opening_hours, resources)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		location.ClubID,
+		location.Name,
+		location.Address,
+		location.Capacity,
+		openingHoursJSON,
+		pq.StringArray(nonNilStrings(location.Resources)),
+	).Scan(&location.ID, &location.CreatedAt)
+}
+
+func (r *LocationRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Location, error) {
+	var location locationDB
+	query := `SELECT * FROM locations WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &location, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return location.toModel(), nil
+}
+
+func (r *LocationRepository) GetByClub(ctx context.Context, clubID uuid.UUID) ([]model.Location, error) {
+	var rows []locationDB
+	query := `SELECT * FROM locations WHERE club_id = $1 ORDER BY name`
+
+	if err := r.db.SelectContext(ctx, &rows, query, clubID); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.Location, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result, nil
+}
+
+func (r *LocationRepository) Update(ctx context.Context, location *model.Location) error {
+	openingHoursJSON, err := json.Marshal(openingHoursOrEmpty(location.OpeningHours))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE locations
+		SET name = $2, address = $3, capacity = $4, opening_hours = $5, resources = $6
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		location.ID,
+		location.Name,
+		location.Address,
+		location.Capacity,
+		openingHoursJSON,
+		pq.StringArray(nonNilStrings(location.Resources)),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a location. Groups, series and sessions keep their
+// free-text location and lose the reference.
+func (r *LocationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM locations WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsDuplicateName reports whether err is the per-club unique name violation
+func IsDuplicateName(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_locations_club_name"
+}
+
+// Helper struct for DB scanning with JSONB and TEXT[]
+type locationDB struct {
+	model.Location
+	OpeningHoursRaw []byte         `db:"opening_hours"`
+	ResourcesRaw    pq.StringArray `db:"resources"`
+}
+
+func (l *locationDB) toModel() *model.Location {
+	l.Location.OpeningHours = model.OpeningHours{}
+	if l.OpeningHoursRaw != nil {
+		json.Unmarshal(l.OpeningHoursRaw, &l.Location.OpeningHours)
+	}
+	l.Location.Resources = nonNilStrings(l.ResourcesRaw)
+	return &l.Location
+}
+
+func openingHoursOrEmpty(h model.OpeningHours) model.OpeningHours {
+	if h == nil {
+		return model.OpeningHours{}
+	}
+	return h
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type MakeupRepository struct {
+	db *sqlx.DB
+}
+
+func NewMakeupRepository(db *sqlx.DB) *MakeupRepository {
+	return &MakeupRepository{db: db}
+}
+
+// CreateInTx grants a credit for an excused attendance. An attendance earns
+// at most one credit; granting again is a no-op.
+// Must be called within a transaction
+func (r *MakeupRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, credit *model.MakeupCredit) error {
+	query := `
+		INSERT INTO makeup_credits (student_id, source_group_id, source_attendance_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source_attendance_id) DO NOTHING
+		RETURNING id, created_at`
+
+	err := tx.QueryRowxContext(ctx, query,
+		credit.StudentID,
+		credit.SourceGroupID,
+		credit.SourceAttendanceID,
+		credit.ExpiresAt,
+	).Scan(&credit.ID, &credit.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// FindUsableForAttendance finds and locks the soonest-expiring unused credit
+// the student can redeem in the given group at sessionTime
+// Must be called within a transaction
+func (r *MakeupRepository) FindUsableForAttendance(ctx context.Context, tx *sqlx.Tx, studentID, groupID uuid.UUID, sessionTime time.Time) (*model.MakeupCredit, error) {
+	var credit model.MakeupCredit
+	query := `
+		SELECT c.* FROM makeup_credits c
+		WHERE c.student_id = $1
+		  AND c.used_at IS NULL
+		  AND c.expires_at >= $3
+		  AND (c.source_group_id = $2 OR EXISTS (
+		      SELECT 1 FROM makeup_allowed_groups m
+		      WHERE m.group_id = c.source_group_id AND m.allowed_group_id = $2))
+		ORDER BY c.expires_at ASC
+		LIMIT 1
+		FOR UPDATE OF c`
+
+	err := tx.GetContext(ctx, &credit, query, studentID, groupID, sessionTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &credit, err
+}
+
+// RedeemInTx marks a credit as used
+// Must be called within a transaction
+func (r *MakeupRepository) RedeemInTx(ctx context.Context, tx *sqlx.Tx, creditID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE makeup_credits SET used_at = now() WHERE id = $1 AND used_at IS NULL`, creditID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RestoreInTx makes a redeemed credit usable again
+// Must be called within a transaction
+func (r *MakeupRepository) RestoreInTx(ctx context.Context, tx *sqlx.Tx, creditID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `UPDATE makeup_credits SET used_at = NULL WHERE id = $1`, creditID)
+	return err
+}
+
+// RevokeUnusedInTx removes the credit an attendance earned if it has not
+// been redeemed yet, e.g. when the absence is no longer excused. It reports
+// whether the credit had already been redeemed and was left in place.
+// Must be called within a transaction
+func (r *MakeupRepository) RevokeUnusedInTx(ctx context.Context, tx *sqlx.Tx, sourceAttendanceID uuid.UUID) (bool, error) {
+	var redeemed bool
+	query := `SELECT EXISTS (SELECT 1 FROM makeup_credits WHERE source_attendance_id = $1 AND used_at IS NOT NULL)`
+	if err := tx.GetContext(ctx, &redeemed, query, sourceAttendanceID); err != nil {
+		return false, err
+	}
+	if redeemed {
+		return true, nil
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM makeup_credits WHERE source_attendance_id = $1 AND used_at IS NULL`, sourceAttendanceID)
+	return false, err
+}
+
+// RevertSessionInTx undoes makeup bookkeeping for a cancelled session:
+// credits redeemed by its attendances become usable again and unused
+// credits earned by excused absences from it are removed
+// Must be called within a transaction
+func (r *MakeupRepository) RevertSessionInTx(ctx context.Context, tx *sqlx.Tx, sessionID uuid.UUID) error {
+	restore := `
+		UPDATE makeup_credits c
+		SET used_at = NULL
+		FROM attendances a
+		WHERE a.session_id = $1 AND a.makeup_credit_id = c.id`
+
+	if _, err := tx.ExecContext(ctx, restore, sessionID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE attendances SET makeup_credit_id = NULL WHERE session_id = $1 AND makeup_credit_id IS NOT NULL`,
+		sessionID); err != nil {
+		return err
+	}
+
+	revoke := `
+		DELETE FROM makeup_credits c
+		USING attendances a
+		WHERE a.session_id = $1 AND c.source_attendance_id = a.id AND c.used_at IS NULL`
+
+	_, err := tx.ExecContext(ctx, revoke, sessionID)
+	return err
+}
+
+// GetByStudent lists a student's credits, usable ones first
+func (r *MakeupRepository) GetByStudent(ctx context.Context, studentID uuid.UUID) ([]model.MakeupCredit, error) {
+	var credits []model.MakeupCredit
+	query := `
+		SELECT * FROM makeup_credits
+		WHERE student_id = $1
+		ORDER BY (used_at IS NULL AND expires_at >= now()) DESC, expires_at ASC`
+
+	err := r.db.SelectContext(ctx, &credits, query, studentID)
+	return credits, err
+}
+
+// GetAllowedGroups lists the groups where absences of groupID may be made up
+// besides the group itself
+func (r *MakeupRepository) GetAllowedGroups(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	query := `SELECT allowed_group_id FROM makeup_allowed_groups WHERE group_id = $1 ORDER BY allowed_group_id`
+
+	err := r.db.SelectContext(ctx, &ids, query, groupID)
+	return ids, err
+}
+
+// SetAllowedGroups replaces the groups where absences of groupID may be
+// made up. Only groups of the same club are stored.
+func (r *MakeupRepository) SetAllowedGroups(ctx context.Context, groupID uuid.UUID, allowed []uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM makeup_allowed_groups WHERE group_id = $1`, groupID); err != nil {
+		return err
+	}
+
+	insert := `
+		INSERT INTO makeup_allowed_groups (group_id, allowed_group_id)
+		SELECT $1, g.id FROM groups g
+		WHERE g.id = $2 AND g.id <> $1
+		  AND g.club_id = (SELECT club_id FROM groups WHERE id = $1)
+		ON CONFLICT DO NOTHING`
+
+	for _, id := range allowed {
+		if _, err := tx.ExecContext(ctx, insert, groupID, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type StudentMergeRepository struct {
+	db *sqlx.DB
+}
+
+func NewStudentMergeRepository(db *sqlx.DB) *StudentMergeRepository {
+	return &StudentMergeRepository{db: db}
+}
+
+// MoveStudentInTx hands everything recorded for one student over to another
+// and counts what was moved into merge. Payments belong to subscriptions, so
+// they follow them and are only counted; profile records move uncounted.
+// Attendance at sessions both students attended must be removed first.
+// Must be called within a transaction
+func (r *StudentMergeRepository) MoveStudentInTx(ctx context.Context, tx *sqlx.Tx, from, to uuid.UUID, merge *model.StudentMerge) error {
+	err := tx.GetContext(ctx, &merge.MovedPayments, `
+		SELECT COUNT(*) FROM payments p
+		JOIN subscriptions s ON s.id = p.subscription_id
+		WHERE s.student_id = $1`, from)
+	if err != nil {
+		return err
+	}
+
+	moves := []struct {
+		query string
+		count *int
+	}{
+		{`UPDATE subscriptions SET student_id = $2 WHERE student_id = $1`, &merge.MovedSubscriptions},
+		{`UPDATE attendances SET student_id = $2 WHERE student_id = $1`, &merge.MovedAttendances},
+		{`UPDATE makeup_credits SET student_id = $2 WHERE student_id = $1`, &merge.MovedMakeupCredits},
+		{`UPDATE notifications SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE import_rows SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE import_rows SET duplicate_of = $2 WHERE duplicate_of = $1`, nil},
+		{`UPDATE medical_certificates SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE student_documents SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE student_consents SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE skill_assessments SET student_id = $2 WHERE student_id = $1`, nil},
+		{`UPDATE level_awards SET student_id = $2 WHERE student_id = $1`, nil},
+		// Where both took the same grading the survivor's result stands
+		{`UPDATE grading_results res SET student_id = $2
+		  WHERE student_id = $1
+		    AND NOT EXISTS (SELECT 1 FROM grading_results WHERE grading_id = res.grading_id AND student_id = $2)`, nil},
+		// Contacts of the duplicate go after the survivor's own
+		{`UPDATE emergency_contacts SET student_id = $2, position = position + 1000 WHERE student_id = $1`, nil},
+		// Medical details are kept only if the survivor has none
+		{`UPDATE student_medical SET student_id = $2
+		  WHERE student_id = $1 AND NOT EXISTS (SELECT 1 FROM student_medical WHERE student_id = $2)`, nil},
+	}
+	for _, m := range moves {
+		result, err := tx.ExecContext(ctx, m.query, from, to)
+		if err != nil {
+			return err
+		}
+		if m.count != nil {
+			rows, _ := result.RowsAffected()
+			*m.count = int(rows)
+		}
+	}
+	return nil
+}
+
+// CreateInTx records a merge
+// Must be called within a transaction
+func (r *StudentMergeRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, merge *model.StudentMerge) error {
+	studentJSON, _ := json.Marshal(merge.MergedStudent)
+
+	query := `
+		INSERT INTO student_merges (
+			club_id, survivor_id, merged_student_id, merged_student,
+			moved_subscriptions, moved_attendances, moved_payments, moved_makeup_credits,
+			removed_attendances, merged_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		merge.ClubID,
+		merge.SurvivorID,
+		merge.MergedStudentID,
+		studentJSON,
+		merge.MovedSubscriptions,
+		merge.MovedAttendances,
+		merge.MovedPayments,
+		merge.MovedMakeupCredits,
+		merge.RemovedAttendances,
+		merge.MergedBy,
+	).Scan(&merge.ID, &merge.CreatedAt)
+}
+
+// GetByClub returns the latest merges of a club, newest first
+func (r *StudentMergeRepository) GetByClub(ctx context.Context, clubID uuid.UUID, limit int) ([]model.StudentMerge, error) {
+	var merges []studentMergeDB
+	query := `
+		SELECT * FROM student_merges
+		WHERE club_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &merges, query, clubID, limit); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.StudentMerge, len(merges))
+	for i := range merges {
+		result[i] = *merges[i].toModel()
+	}
+	return result, nil
+}
+
+// BeginTx starts a new transaction
+func (r *StudentMergeRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Helper struct for DB scanning with JSONB
+type studentMergeDB struct {
+	model.StudentMerge
+	MergedStudentRaw []byte `db:"merged_student"`
+}
+
+func (m *studentMergeDB) toModel() *model.StudentMerge {
+	json.Unmarshal(m.MergedStudentRaw, &m.StudentMerge.MergedStudent)
+	return &m.StudentMerge
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// NotificationLease is how long a claimed notification stays hidden from
+// other dispatchers before it is retried
+const NotificationLease = 5 * time.Minute
+
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Enqueue adds a notification to the delivery queue, due at
+// n.NextAttemptAt or now when that is zero. A notification whose dedupe
+// key is already queued is skipped; n.ID stays zero then.
+func (r *NotificationRepository) Enqueue(ctx context.Context, n *model.Notification) error {
+	var due *time.Time
+	if !n.NextAttemptAt.IsZero() {
+		due = &n.NextAttemptAt
+	}
+
+	query := `
+		INSERT INTO notifications (club_id, student_id, event, channel, recipient, language, subject, body, dedupe_key, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, now()))
+		ON CONFLICT (dedupe_key) DO NOTHING
+		RETURNING id, status, next_attempt_at, created_at`
+
+	rows, err := r.db.QueryxContext(ctx, query,
+		n.ClubID,
+		n.StudentID,
+		n.Event,
+		n.Channel,
+		n.Recipient,
+		n.Language,
+		n.Subject,
+		n.Body,
+		n.DedupeKey,
+		due,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&n.ID, &n.Status, &n.NextAttemptAt, &n.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ClaimDue takes up to limit pending notifications that are due and leases
+// them for NotificationLease, counting the attempt. Concurrent
+// dispatchers never claim the same row.
+func (r *NotificationRepository) ClaimDue(ctx context.Context, limit int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	query := `
+		UPDATE notifications
+		SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	err := r.db.SelectContext(ctx, &notifications, query, limit, NotificationLease.Seconds())
+	return notifications, err
+}
+
+func (r *NotificationRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'sent', sent_at = now(), last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *NotificationRepository) MarkRetry(ctx context.Context, id uuid.UUID, lastError string, next time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, lastError, next)
+	return err
+}
+
+// MarkFailed gives up on a notification
+func (r *NotificationRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'failed', last_error = $2 WHERE id = $1`, id, lastError)
+	return err
+}
+
+// GetByClub lists a club's most recent notifications, optionally filtered
+// by status
+func (r *NotificationRepository) GetByClub(ctx context.Context, clubID uuid.UUID, status string, limit int) ([]model.Notification, error) {
+	notifications := []model.Notification{}
+	query := `
+		SELECT * FROM notifications
+		WHERE club_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`
+
+	err := r.db.SelectContext(ctx, &notifications, query, clubID, status, limit)
+	return notifications, err
+}
+
+// Retry puts a failed notification back in the queue
+func (r *NotificationRepository) Retry(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'pending', attempts = 0, next_attempt_at = now() WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Notification, error) {
+	var n model.Notification
+	err := r.db.GetContext(ctx, &n, `SELECT * FROM notifications WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &n, err
+}
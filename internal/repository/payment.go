@@ -162,6 +162,54 @@ func (r *PaymentRepository) GetByClub(ctx context.Context, clubID uuid.UUID, fro
 	return result, nil
 }
 
+// PaymentWithDetails includes the student and group paid for
+type PaymentWithDetails struct {
+	model.Payment
+	StudentName string `json:"student_name"`
+	GroupTitle  string `json:"group_title"`
+}
+
+// EachByClubWithDetails calls fn for the payments GetByClub returns,
+// reading them from a cursor
+func (r *PaymentRepository) EachByClubWithDetails(ctx context.Context, clubID uuid.UUID, from, to time.Time, status string, fn func(*PaymentWithDetails) error) error {
+	query := `
+		SELECT p.*, COALESCE(st.name, '') as student_name, g.title as group_title
+		FROM payments p
+		JOIN subscriptions s ON p.subscription_id = s.id
+		LEFT JOIN students st ON s.student_id = st.id
+		JOIN groups g ON s.group_id = g.id
+		WHERE g.club_id = $1 AND p.created_at BETWEEN $2 AND $3`
+
+	args := []interface{}{clubID, from, to}
+	if status != "" {
+		query += ` AND p.status = $4`
+		args = append(args, status)
+	}
+	query += ` ORDER BY p.created_at DESC`
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p paymentWithDetailsDB
+		if err := rows.StructScan(&p); err != nil {
+			return err
+		}
+		payment := PaymentWithDetails{
+			Payment:     *p.toModel(),
+			StudentName: p.StudentName,
+			GroupTitle:  p.GroupTitle,
+		}
+		if err := fn(&payment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // PaymentStats for reports
 type PaymentStats struct {
 	TotalAmount    float64 `db:"total_amount" json:"total_amount"`
@@ -201,6 +249,12 @@ type paymentDB struct {
 	CreatedAt         time.Time  `db:"created_at"`
 }
 
+type paymentWithDetailsDB struct {
+	paymentDB
+	StudentName string `db:"student_name"`
+	GroupTitle  string `db:"group_title"`
+}
+
 func (p *paymentDB) toModel() *model.Payment {
 	payment := &model.Payment{
 		ID:                p.ID,
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// PayrollRepository keeps the rules coaches are paid by and the payroll
+// runs calculated from them
+type PayrollRepository struct {
+	db *sqlx.DB
+}
+
+func NewPayrollRepository(db *sqlx.DB) *PayrollRepository {
+	return &PayrollRepository{db: db}
+}
+
+// ==================== Rules ====================
+
+func (r *PayrollRepository) GetRules(ctx context.Context, clubID uuid.UUID) ([]model.PayrollRule, error) {
+	rules := []model.PayrollRule{}
+	query := `SELECT * FROM payroll_rules WHERE club_id = $1 ORDER BY coach_user_id, group_id NULLS FIRST`
+
+	err := r.db.SelectContext(ctx, &rules, query, clubID)
+	return rules, err
+}
+
+func (r *PayrollRepository) GetRule(ctx context.Context, id uuid.UUID) (*model.PayrollRule, error) {
+	var rule model.PayrollRule
+	err := r.db.GetContext(ctx, &rule, `SELECT * FROM payroll_rules WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *PayrollRepository) CreateRule(ctx context.Context, rule *model.PayrollRule) error {
+	query := `
+		INSERT INTO payroll_rules (club_id, coach_user_id, group_id, kind, rate)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		rule.ClubID,
+		rule.CoachUserID,
+		rule.GroupID,
+		rule.Kind,
+		rule.Rate,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+// UpdateRule changes how the coach is paid; runs already calculated keep
+// the rate they were calculated with
+func (r *PayrollRepository) UpdateRule(ctx context.Context, rule *model.PayrollRule) error {
+	query := `
+		UPDATE payroll_rules SET kind = $2, rate = $3, updated_at = now()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query, rule.ID, rule.Kind, rule.Rate).Scan(&rule.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *PayrollRepository) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM payroll_rules WHERE id = $1`, id)
+}
+
+// IsDuplicatePayrollRule reports whether err is the violation of one rule
+// per coach and group, or per coach without a group
+func IsDuplicatePayrollRule(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "payroll_rules_coach_group"
+}
+
+// ==================== Runs ====================
+
+func (r *PayrollRepository) GetRuns(ctx context.Context, clubID uuid.UUID) ([]model.PayrollRun, error) {
+	runs := []model.PayrollRun{}
+	query := `SELECT * FROM payroll_runs WHERE club_id = $1 ORDER BY period_start DESC, created_at DESC`
+
+	err := r.db.SelectContext(ctx, &runs, query, clubID)
+	return runs, err
+}
+
+func (r *PayrollRepository) GetRun(ctx context.Context, id uuid.UUID) (*model.PayrollRun, error) {
+	var run model.PayrollRun
+	err := r.db.GetContext(ctx, &run, `SELECT * FROM payroll_runs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetItems returns the lines of a run by coach and then in time order
+func (r *PayrollRepository) GetItems(ctx context.Context, runID uuid.UUID) ([]model.PayrollItem, error) {
+	items := []model.PayrollItem{}
+	query := `
+		SELECT * FROM payroll_items
+		WHERE run_id = $1
+		ORDER BY coach_name, coach_user_id, occurred_at, group_title`
+
+	err := r.db.SelectContext(ctx, &items, query, runID)
+	return items, err
+}
+
+// DeleteRun removes a draft run; ErrNotFound covers approved ones too
+func (r *PayrollRepository) DeleteRun(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM payroll_runs WHERE id = $1 AND status = 'draft'`, id)
+}
+
+// BeginTx starts a new transaction
+func (r *PayrollRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// CreateRunInTx adds a draft run without items
+// Must be called within a transaction
+func (r *PayrollRepository) CreateRunInTx(ctx context.Context, tx *sqlx.Tx, run *model.PayrollRun) error {
+	query := `
+		INSERT INTO payroll_runs (club_id, period_start, period_end, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, total, calculated_at, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		run.ClubID,
+		run.PeriodStart,
+		run.PeriodEnd,
+		run.CreatedBy,
+	).Scan(&run.ID, &run.Status, &run.Total, &run.CalculatedAt, &run.CreatedAt)
+}
+
+// LockRunInTx fetches a run and locks it until the transaction ends
+// Must be called within a transaction
+func (r *PayrollRepository) LockRunInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*model.PayrollRun, error) {
+	var run model.PayrollRun
+	err := tx.GetContext(ctx, &run, `SELECT * FROM payroll_runs WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ReplaceItemsInTx sets the lines and total of a run
+// Must be called within a transaction
+func (r *PayrollRepository) ReplaceItemsInTx(ctx context.Context, tx *sqlx.Tx, run *model.PayrollRun, items []model.PayrollItem, total float64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payroll_items WHERE run_id = $1`, run.ID); err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		item.RunID = run.ID
+		query := `
+			INSERT INTO payroll_items (run_id, coach_user_id, coach_name, group_id, group_title, rule_id,
+			                           session_id, payment_id, kind, rate, quantity, amount, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING id`
+		err := tx.QueryRowxContext(ctx, query,
+			item.RunID, item.CoachUserID, item.CoachName, item.GroupID, item.GroupTitle, item.RuleID,
+			item.SessionID, item.PaymentID, item.Kind, item.Rate, item.Quantity, item.Amount, item.OccurredAt,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE payroll_runs SET total = $2, calculated_at = now() WHERE id = $1 RETURNING calculated_at`
+	if err := tx.QueryRowxContext(ctx, query, run.ID, total).Scan(&run.CalculatedAt); err != nil {
+		return err
+	}
+	run.Total = total
+	return nil
+}
+
+// LockApprovalsInTx serialises approvals of the club's runs until the
+// transaction ends, so two overlapping runs cannot both pass the overlap
+// check. It locks the club row without blocking inserts that reference it.
+// Must be called within a transaction
+func (r *PayrollRepository) LockApprovalsInTx(ctx context.Context, tx *sqlx.Tx, clubID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.GetContext(ctx, &id, `SELECT id FROM clubs WHERE id = $1 FOR NO KEY UPDATE`, clubID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// OverlapsApprovedInTx reports whether another approved run of the club
+// covers any day of the run's period
+// Must be called within a transaction
+func (r *PayrollRepository) OverlapsApprovedInTx(ctx context.Context, tx *sqlx.Tx, run *model.PayrollRun) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM payroll_runs
+			WHERE club_id = $1
+			  AND id <> $2
+			  AND status = 'approved'
+			  AND period_start <= $4
+			  AND period_end >= $3
+		)`
+
+	var overlaps bool
+	err := tx.GetContext(ctx, &overlaps, query, run.ClubID, run.ID, run.PeriodStart, run.PeriodEnd)
+	return overlaps, err
+}
+
+// ApproveInTx locks a run against changes
+// Must be called within a transaction
+func (r *PayrollRepository) ApproveInTx(ctx context.Context, tx *sqlx.Tx, run *model.PayrollRun, by uuid.UUID) error {
+	query := `
+		UPDATE payroll_runs SET status = 'approved', approved_by = $2, approved_at = now()
+		WHERE id = $1
+		RETURNING status, approved_by, approved_at`
+
+	return tx.QueryRowxContext(ctx, query, run.ID, by).Scan(&run.Status, &run.ApprovedBy, &run.ApprovedAt)
+}
+
+// ==================== What a run is calculated from ====================
+
+// PayrollSession is a session held in a club group that has a coach
+type PayrollSession struct {
+	ID          uuid.UUID `db:"id"`
+	GroupID     uuid.UUID `db:"group_id"`
+	GroupTitle  string    `db:"group_title"`
+	CoachUserID uuid.UUID `db:"coach_user_id"`
+	CoachName   string    `db:"coach_name"`
+	StartAt     time.Time `db:"start_at"`
+	Attendees   int       `db:"attendees"`
+}
+
+// PayrollPayment is a payment for a subscription to a club group that has
+// a coach
+type PayrollPayment struct {
+	ID          uuid.UUID `db:"id"`
+	GroupID     uuid.UUID `db:"group_id"`
+	GroupTitle  string    `db:"group_title"`
+	CoachUserID uuid.UUID `db:"coach_user_id"`
+	CoachName   string    `db:"coach_name"`
+	PaidAt      time.Time `db:"paid_at"`
+	Amount      float64   `db:"amount"`
+}
+
+// PayrollSessionsInTx lists the sessions not cancelled that started
+// between from and to, with the students present. A session counts for
+// its group's coach.
+// Must be called within a transaction
+func (r *PayrollRepository) PayrollSessionsInTx(ctx context.Context, tx *sqlx.Tx, clubID uuid.UUID, from, to time.Time) ([]PayrollSession, error) {
+	sessions := []PayrollSession{}
+	query := `
+		SELECT
+			s.id,
+			g.id as group_id,
+			g.title as group_title,
+			g.coach_user_id,
+			COALESCE(NULLIF(u.name, ''), u.email) as coach_name,
+			s.start_at,
+			(SELECT COUNT(*) FROM attendances a
+			 WHERE a.session_id = s.id AND a.status = 'present') as attendees
+		FROM sessions s
+		JOIN groups g ON g.id = s.group_id
+		JOIN users u ON u.id = g.coach_user_id
+		WHERE g.club_id = $1
+		  AND s.status <> 'cancelled'
+		  AND s.start_at BETWEEN $2 AND $3
+		ORDER BY s.start_at`
+
+	err := tx.SelectContext(ctx, &sessions, query, clubID, from, to)
+	return sessions, err
+}
+
+// PayrollPaymentsInTx lists the succeeded payments paid between from and
+// to. A payment counts for its group's coach.
+// Must be called within a transaction
+func (r *PayrollRepository) PayrollPaymentsInTx(ctx context.Context, tx *sqlx.Tx, clubID uuid.UUID, from, to time.Time) ([]PayrollPayment, error) {
+	payments := []PayrollPayment{}
+	query := `
+		SELECT
+			p.id,
+			g.id as group_id,
+			g.title as group_title,
+			g.coach_user_id,
+			COALESCE(NULLIF(u.name, ''), u.email) as coach_name,
+			p.paid_at,
+			p.amount
+		FROM payments p
+		JOIN subscriptions sub ON sub.id = p.subscription_id
+		JOIN groups g ON g.id = sub.group_id
+		JOIN users u ON u.id = g.coach_user_id
+		WHERE g.club_id = $1
+		  AND p.status = 'succeeded'
+		  AND p.paid_at BETWEEN $2 AND $3
+		ORDER BY p.paid_at`
+
+	err := tx.SelectContext(ctx, &payments, query, clubID, from, to)
+	return payments, err
+}
+
+// execOne runs a statement on one row, returning ErrNotFound if it
+// touched none
+func (r *PayrollRepository) execOne(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// PrivacyRepository gathers the personal data held about students for a
+// guardian's export, erases it and logs both kinds of request
+type PrivacyRepository struct {
+	db *sqlx.DB
+}
+
+func NewPrivacyRepository(db *sqlx.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// StudentSubscription is a subscription with the group it is for
+type StudentSubscription struct {
+	model.Subscription
+	GroupTitle string `db:"group_title" json:"group_title"`
+}
+
+// StudentAttendance is an attendance mark with its session
+type StudentAttendance struct {
+	model.Attendance
+	GroupTitle     string    `db:"group_title" json:"group_title"`
+	SessionStartAt time.Time `db:"session_start_at" json:"session_start_at"`
+}
+
+// StudentPayment is a payment with the student whose subscription it paid
+type StudentPayment struct {
+	model.Payment
+	StudentID uuid.UUID `json:"student_id"`
+}
+
+// GetSubscriptions returns the subscriptions of the students, oldest first
+func (r *PrivacyRepository) GetSubscriptions(ctx context.Context, studentIDs []uuid.UUID) ([]StudentSubscription, error) {
+	subs := []StudentSubscription{}
+	query := `
+		SELECT sub.*, g.title AS group_title
+		FROM subscriptions sub
+		JOIN groups g ON g.id = sub.group_id
+		WHERE sub.student_id = ANY($1::uuid[])
+		ORDER BY sub.created_at`
+
+	err := r.db.SelectContext(ctx, &subs, query, uuidArray(studentIDs))
+	return subs, err
+}
+
+// GetAttendances returns the attendance marks of the students in session
+// order
+func (r *PrivacyRepository) GetAttendances(ctx context.Context, studentIDs []uuid.UUID) ([]StudentAttendance, error) {
+	attendances := []StudentAttendance{}
+	query := `
+		SELECT a.*, g.title AS group_title, s.start_at AS session_start_at
+		FROM attendances a
+		JOIN sessions s ON s.id = a.session_id
+		JOIN groups g ON g.id = s.group_id
+		WHERE a.student_id = ANY($1::uuid[])
+		ORDER BY s.start_at`
+
+	err := r.db.SelectContext(ctx, &attendances, query, uuidArray(studentIDs))
+	return attendances, err
+}
+
+// GetPayments returns the payments for the students' subscriptions, oldest
+// first
+func (r *PrivacyRepository) GetPayments(ctx context.Context, studentIDs []uuid.UUID) ([]StudentPayment, error) {
+	var rows []studentPaymentDB
+	query := `
+		SELECT p.*, sub.student_id
+		FROM payments p
+		JOIN subscriptions sub ON sub.id = p.subscription_id
+		WHERE sub.student_id = ANY($1::uuid[])
+		ORDER BY p.created_at`
+
+	if err := r.db.SelectContext(ctx, &rows, query, uuidArray(studentIDs)); err != nil {
+		return nil, err
+	}
+
+	payments := make([]StudentPayment, len(rows))
+	for i, p := range rows {
+		payments[i] = StudentPayment{Payment: *p.toModel(), StudentID: p.StudentID}
+	}
+	return payments, nil
+}
+
+// EraseStudentInTx anonymises a student and removes or blanks everything
+// personal recorded about them: contacts, notes, tags and custom fields,
+// medical details, emergency contacts, documents, who signed their
+// consents, the messages sent about them, their imported rows, the copy
+// kept by a merge, the names in their payments and coaches' notes on their
+// progress. Subscriptions, payments, attendance and levels stay, so
+// reports and the books still add up. The student is archived as well.
+//
+// It returns the storage keys of the removed documents, whose files the
+// caller deletes once the transaction is committed.
+// Must be called within a transaction
+func (r *PrivacyRepository) EraseStudentInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, name string) ([]string, error) {
+	var keys []string
+	err := tx.SelectContext(ctx, &keys, `SELECT storage_key FROM student_documents WHERE student_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE students
+		SET name = $2, birth_date = NULL, parent_contact = NULL, notes = '', tags = '{}', custom_fields = '{}',
+		    archived_at = COALESCE(archived_at, now()),
+		    erased_at = COALESCE(erased_at, now())
+		WHERE id = $1`
+
+	result, err := tx.ExecContext(ctx, query, id, name)
+	if err != nil {
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	statements := []string{
+		`DELETE FROM student_medical WHERE student_id = $1`,
+		`DELETE FROM medical_certificates WHERE student_id = $1`,
+		`DELETE FROM emergency_contacts WHERE student_id = $1`,
+		`DELETE FROM student_documents WHERE student_id = $1`,
+		// The consents stay as a record of what was agreed, without the
+		// name of who signed them
+		`UPDATE student_consents SET signed_by = '' WHERE student_id = $1`,
+		// Queued messages would go to the erased contacts
+		`DELETE FROM notifications WHERE student_id = $1 AND status = 'pending'`,
+		`UPDATE notifications SET recipient = '', subject = '', body = '', last_error = NULL WHERE student_id = $1`,
+		`UPDATE import_rows SET data = '[]' WHERE student_id = $1 OR duplicate_of = $1`,
+		// Scores, results and levels stay; what coaches wrote about the
+		// student goes
+		`UPDATE skill_assessments SET notes = '' WHERE student_id = $1`,
+		`UPDATE grading_results SET notes = '' WHERE student_id = $1`,
+		`UPDATE level_awards SET notes = '' WHERE student_id = $1`,
+		`UPDATE student_merges SET merged_student = jsonb_build_object('id', merged_student_id) WHERE survivor_id = $1`,
+		`UPDATE payments SET provider_metadata = provider_metadata - 'student_name' - 'notes'
+		 WHERE provider_metadata IS NOT NULL
+		   AND subscription_id IN (SELECT id FROM subscriptions WHERE student_id = $1)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, id); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// CreateRequest logs a data request
+func (r *PrivacyRepository) CreateRequest(ctx context.Context, req *model.DataRequest) error {
+	query := `
+		INSERT INTO data_requests (club_id, kind, subject, student_ids, requested_by)
+		VALUES ($1, $2, $3, $4::uuid[], $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		req.ClubID,
+		req.Kind,
+		req.Subject,
+		uuidArray(req.StudentIDs),
+		req.RequestedBy,
+	).Scan(&req.ID, &req.CreatedAt)
+}
+
+// CreateRequestInTx logs a data request within a transaction
+// Must be called within a transaction
+func (r *PrivacyRepository) CreateRequestInTx(ctx context.Context, tx *sqlx.Tx, req *model.DataRequest) error {
+	query := `
+		INSERT INTO data_requests (club_id, kind, subject, student_ids, requested_by)
+		VALUES ($1, $2, $3, $4::uuid[], $5)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		req.ClubID,
+		req.Kind,
+		req.Subject,
+		uuidArray(req.StudentIDs),
+		req.RequestedBy,
+	).Scan(&req.ID, &req.CreatedAt)
+}
+
+// GetRequestsByClub returns the latest data requests of a club, newest
+// first
+func (r *PrivacyRepository) GetRequestsByClub(ctx context.Context, clubID uuid.UUID, limit int) ([]model.DataRequest, error) {
+	var rows []dataRequestDB
+	query := `SELECT * FROM data_requests WHERE club_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &rows, query, clubID, limit); err != nil {
+		return nil, err
+	}
+
+	requests := make([]model.DataRequest, len(rows))
+	for i := range rows {
+		requests[i] = *rows[i].toModel()
+	}
+	return requests, nil
+}
+
+// BeginTx starts a new transaction
+func (r *PrivacyRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	array := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		array[i] = id.String()
+	}
+	return array
+}
+
+type studentPaymentDB struct {
+	paymentDB
+	StudentID uuid.UUID `db:"student_id"`
+}
+
+// Helper struct for DB scanning with UUID[]
+type dataRequestDB struct {
+	model.DataRequest
+	StudentIDsRaw pq.StringArray `db:"student_ids"`
+}
+
+func (d *dataRequestDB) toModel() *model.DataRequest {
+	d.DataRequest.StudentIDs = make([]uuid.UUID, 0, len(d.StudentIDsRaw))
+	for _, id := range d.StudentIDsRaw {
+		if parsed, err := uuid.Parse(id); err == nil {
+			d.DataRequest.StudentIDs = append(d.DataRequest.StudentIDs, parsed)
+		}
+	}
+	return &d.DataRequest
+}
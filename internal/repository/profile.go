@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// ProfileRepository stores the sensitive parts of a student's profile:
+// medical details and certificates, emergency contacts, documents and
+// consents. They are kept apart from students so that listing students
+// never exposes them.
+type ProfileRepository struct {
+	db *sqlx.DB
+}
+
+func NewProfileRepository(db *sqlx.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// CoachesStudent reports whether the user coaches a group the student has
+// an active or pending subscription to
+func (r *ProfileRepository) CoachesStudent(ctx context.Context, userID, studentID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM subscriptions sub
+			JOIN groups g ON g.id = sub.group_id
+			WHERE sub.student_id = $1
+			  AND g.coach_user_id = $2
+			  AND sub.status IN ('active', 'pending')
+		)`
+
+	err := r.db.GetContext(ctx, &exists, query, studentID, userID)
+	return exists, err
+}
+
+// ==================== Medical ====================
+
+// GetMedical returns ErrNotFound for a student without medical details
+func (r *ProfileRepository) GetMedical(ctx context.Context, studentID uuid.UUID) (*model.StudentMedical, error) {
+	var m model.StudentMedical
+	err := r.db.GetContext(ctx, &m, `SELECT * FROM student_medical WHERE student_id = $1`, studentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SaveMedical creates or replaces a student's medical details
+func (r *ProfileRepository) SaveMedical(ctx context.Context, m *model.StudentMedical) error {
+	query := `
+		INSERT INTO student_medical (student_id, blood_type, allergies, conditions, medications, notes, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (student_id) DO UPDATE
+		SET blood_type = EXCLUDED.blood_type,
+		    allergies = EXCLUDED.allergies,
+		    conditions = EXCLUDED.conditions,
+		    medications = EXCLUDED.medications,
+		    notes = EXCLUDED.notes,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+		RETURNING updated_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		m.StudentID,
+		m.BloodType,
+		m.Allergies,
+		m.Conditions,
+		m.Medications,
+		m.Notes,
+		m.UpdatedBy,
+	).Scan(&m.UpdatedAt)
+}
+
+// GetCertificates returns a student's medical certificates, latest expiry
+// first
+func (r *ProfileRepository) GetCertificates(ctx context.Context, studentID uuid.UUID) ([]model.MedicalCertificate, error) {
+	certificates := []model.MedicalCertificate{}
+	query := `SELECT * FROM medical_certificates WHERE student_id = $1 ORDER BY expires_on DESC, created_at DESC`
+
+	err := r.db.SelectContext(ctx, &certificates, query, studentID)
+	return certificates, err
+}
+
+func (r *ProfileRepository) GetCertificate(ctx context.Context, id uuid.UUID) (*model.MedicalCertificate, error) {
+	var c model.MedicalCertificate
+	err := r.db.GetContext(ctx, &c, `SELECT * FROM medical_certificates WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ProfileRepository) CreateCertificate(ctx context.Context, c *model.MedicalCertificate) error {
+	query := `
+		INSERT INTO medical_certificates (student_id, issued_on, expires_on, issuer, notes, document_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		c.StudentID,
+		c.IssuedOn,
+		c.ExpiresOn,
+		c.Issuer,
+		c.Notes,
+		c.DocumentID,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *ProfileRepository) DeleteCertificate(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM medical_certificates WHERE id = $1`, id)
+}
+
+// CertificateAlert is a student whose latest medical certificate expires
+// soon or has expired
+type CertificateAlert struct {
+	StudentID     uuid.UUID `db:"student_id" json:"student_id"`
+	StudentName   string    `db:"student_name" json:"student_name"`
+	CertificateID uuid.UUID `db:"certificate_id" json:"certificate_id"`
+	ExpiresOn     time.Time `db:"expires_on" json:"expires_on"`
+	Expired       bool      `db:"expired" json:"expired"`
+}
+
+// GetCertificateAlerts returns the students of a club whose latest
+// certificate expires on or before until, soonest first. today is the
+// club's current calendar date.
+func (r *ProfileRepository) GetCertificateAlerts(ctx context.Context, clubID uuid.UUID, today, until time.Time) ([]CertificateAlert, error) {
+	alerts := []CertificateAlert{}
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (st.id)
+			       st.id AS student_id, st.name AS student_name,
+			       mc.id AS certificate_id, mc.expires_on, mc.expires_on < $2 AS expired
+			FROM medical_certificates mc
+			JOIN students st ON st.id = mc.student_id
+			WHERE st.club_id = $1
+			ORDER BY st.id, mc.expires_on DESC
+		) latest
+		WHERE expires_on <= $3
+		ORDER BY expires_on, student_name`
+
+	err := r.db.SelectContext(ctx, &alerts, query, clubID, today, until)
+	return alerts, err
+}
+
+// ==================== Emergency contacts ====================
+
+func (r *ProfileRepository) GetEmergencyContacts(ctx context.Context, studentID uuid.UUID) ([]model.EmergencyContact, error) {
+	contacts := []model.EmergencyContact{}
+	query := `SELECT * FROM emergency_contacts WHERE student_id = $1 ORDER BY position`
+
+	err := r.db.SelectContext(ctx, &contacts, query, studentID)
+	return contacts, err
+}
+
+// ReplaceEmergencyContacts replaces a student's contacts with the given
+// ones, in that order
+func (r *ProfileRepository) ReplaceEmergencyContacts(ctx context.Context, studentID uuid.UUID, contacts []model.EmergencyContact) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM emergency_contacts WHERE student_id = $1`, studentID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO emergency_contacts (student_id, position, name, relation, phone)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	for i := range contacts {
+		c := &contacts[i]
+		c.StudentID = studentID
+		c.Position = i
+		err := tx.QueryRowxContext(ctx, query, c.StudentID, c.Position, c.Name, c.Relation, c.Phone).
+			Scan(&c.ID, &c.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ==================== Documents ====================
+
+// CreateDocument records an uploaded file. The ID is chosen by the caller,
+// who stores the file under a key derived from it first.
+func (r *ProfileRepository) CreateDocument(ctx context.Context, d *model.StudentDocument) error {
+	query := `
+		INSERT INTO student_documents (id, student_id, kind, filename, content_type, size, storage_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		d.ID,
+		d.StudentID,
+		d.Kind,
+		d.Filename,
+		d.ContentType,
+		d.Size,
+		d.StorageKey,
+		d.UploadedBy,
+	).Scan(&d.CreatedAt)
+}
+
+func (r *ProfileRepository) GetDocument(ctx context.Context, id uuid.UUID) (*model.StudentDocument, error) {
+	var d model.StudentDocument
+	err := r.db.GetContext(ctx, &d, `SELECT * FROM student_documents WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetDocuments returns a student's documents, newest first
+func (r *ProfileRepository) GetDocuments(ctx context.Context, studentID uuid.UUID) ([]model.StudentDocument, error) {
+	documents := []model.StudentDocument{}
+	query := `SELECT * FROM student_documents WHERE student_id = $1 ORDER BY created_at DESC`
+
+	err := r.db.SelectContext(ctx, &documents, query, studentID)
+	return documents, err
+}
+
+// DeleteDocument removes the record of a document; certificates and
+// consents referring to it lose the reference
+func (r *ProfileRepository) DeleteDocument(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM student_documents WHERE id = $1`, id)
+}
+
+// ==================== Consents ====================
+
+// GetConsents returns a student's consents, revoked ones included, newest
+// first
+func (r *ProfileRepository) GetConsents(ctx context.Context, studentID uuid.UUID) ([]model.StudentConsent, error) {
+	consents := []model.StudentConsent{}
+	query := `SELECT * FROM student_consents WHERE student_id = $1 ORDER BY signed_at DESC`
+
+	err := r.db.SelectContext(ctx, &consents, query, studentID)
+	return consents, err
+}
+
+func (r *ProfileRepository) GetConsent(ctx context.Context, id uuid.UUID) (*model.StudentConsent, error) {
+	var c model.StudentConsent
+	err := r.db.GetContext(ctx, &c, `SELECT * FROM student_consents WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ProfileRepository) CreateConsent(ctx context.Context, c *model.StudentConsent) error {
+	query := `
+		INSERT INTO student_consents (student_id, kind, signed_by, signed_at, document_id, recorded_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		c.StudentID,
+		c.Kind,
+		c.SignedBy,
+		c.SignedAt,
+		c.DocumentID,
+		c.RecordedBy,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// RevokeConsent marks a consent revoked. Returns ErrNotFound if it does
+// not exist or is already revoked.
+func (r *ProfileRepository) RevokeConsent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.execOne(ctx, `UPDATE student_consents SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, at)
+}
+
+// execOne runs a statement on one row, returning ErrNotFound if it
+// touched none
+func (r *ProfileRepository) execOne(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
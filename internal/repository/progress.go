@@ -0,0 +1,404 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// ProgressRepository keeps the levels and skills of groups and the
+// progress of students through them: assessments, gradings and awarded
+// levels
+type ProgressRepository struct {
+	db *sqlx.DB
+}
+
+func NewProgressRepository(db *sqlx.DB) *ProgressRepository {
+	return &ProgressRepository{db: db}
+}
+
+// ==================== Levels ====================
+
+func (r *ProgressRepository) GetLevels(ctx context.Context, groupID uuid.UUID) ([]model.ProgressLevel, error) {
+	levels := []model.ProgressLevel{}
+	query := `SELECT * FROM progress_levels WHERE group_id = $1 ORDER BY position, name`
+
+	err := r.db.SelectContext(ctx, &levels, query, groupID)
+	return levels, err
+}
+
+func (r *ProgressRepository) GetLevel(ctx context.Context, id uuid.UUID) (*model.ProgressLevel, error) {
+	var l model.ProgressLevel
+	err := r.db.GetContext(ctx, &l, `SELECT * FROM progress_levels WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// CreateLevel adds a level; without a position it goes above the group's
+// highest
+func (r *ProgressRepository) CreateLevel(ctx context.Context, l *model.ProgressLevel) error {
+	query := `
+		INSERT INTO progress_levels (group_id, name, position, description)
+		VALUES ($1, $2,
+		        CASE WHEN $3 > 0 THEN $3
+		             ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM progress_levels WHERE group_id = $1) END,
+		        $4)
+		RETURNING id, position, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		l.GroupID,
+		l.Name,
+		l.Position,
+		l.Description,
+	).Scan(&l.ID, &l.Position, &l.CreatedAt)
+}
+
+func (r *ProgressRepository) UpdateLevel(ctx context.Context, l *model.ProgressLevel) error {
+	query := `UPDATE progress_levels SET name = $2, position = $3, description = $4 WHERE id = $1`
+	return r.execOne(ctx, query, l.ID, l.Name, l.Position, l.Description)
+}
+
+// DeleteLevel removes a level with the grading results and awards of it
+func (r *ProgressRepository) DeleteLevel(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM progress_levels WHERE id = $1`, id)
+}
+
+// ==================== Skills ====================
+
+func (r *ProgressRepository) GetSkills(ctx context.Context, groupID uuid.UUID) ([]model.Skill, error) {
+	skills := []model.Skill{}
+	query := `SELECT * FROM skills WHERE group_id = $1 ORDER BY name`
+
+	err := r.db.SelectContext(ctx, &skills, query, groupID)
+	return skills, err
+}
+
+func (r *ProgressRepository) GetSkill(ctx context.Context, id uuid.UUID) (*model.Skill, error) {
+	var s model.Skill
+	err := r.db.GetContext(ctx, &s, `SELECT * FROM skills WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *ProgressRepository) CreateSkill(ctx context.Context, s *model.Skill) error {
+	query := `
+		INSERT INTO skills (group_id, name, description, max_score)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		s.GroupID,
+		s.Name,
+		s.Description,
+		s.MaxScore,
+	).Scan(&s.ID, &s.CreatedAt)
+}
+
+func (r *ProgressRepository) UpdateSkill(ctx context.Context, s *model.Skill) error {
+	query := `UPDATE skills SET name = $2, description = $3, max_score = $4 WHERE id = $1`
+	return r.execOne(ctx, query, s.ID, s.Name, s.Description, s.MaxScore)
+}
+
+// DeleteSkill removes a skill with its assessments
+func (r *ProgressRepository) DeleteSkill(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM skills WHERE id = $1`, id)
+}
+
+// ==================== Assessments ====================
+
+func (r *ProgressRepository) GetAssessment(ctx context.Context, id uuid.UUID) (*model.SkillAssessment, error) {
+	var a model.SkillAssessment
+	err := r.db.GetContext(ctx, &a, `SELECT * FROM skill_assessments WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *ProgressRepository) CreateAssessment(ctx context.Context, a *model.SkillAssessment) error {
+	query := `
+		INSERT INTO skill_assessments (student_id, skill_id, score, notes, assessed_on, assessed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		a.StudentID,
+		a.SkillID,
+		a.Score,
+		a.Notes,
+		a.AssessedOn,
+		a.AssessedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *ProgressRepository) DeleteAssessment(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM skill_assessments WHERE id = $1`, id)
+}
+
+// ==================== Gradings ====================
+
+// GetGradings returns a group's gradings, latest first, without results
+func (r *ProgressRepository) GetGradings(ctx context.Context, groupID uuid.UUID) ([]model.Grading, error) {
+	gradings := []model.Grading{}
+	query := `SELECT * FROM gradings WHERE group_id = $1 ORDER BY held_on DESC, created_at DESC`
+
+	err := r.db.SelectContext(ctx, &gradings, query, groupID)
+	return gradings, err
+}
+
+// GetGrading returns a grading with its results
+func (r *ProgressRepository) GetGrading(ctx context.Context, id uuid.UUID) (*model.Grading, error) {
+	var g model.Grading
+	err := r.db.GetContext(ctx, &g, `SELECT * FROM gradings WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.Results = []model.GradingResult{}
+	query := `
+		SELECT res.grading_id, res.student_id, st.name as student_name,
+		       res.level_id, l.name as level_name, res.passed, res.notes
+		FROM grading_results res
+		JOIN students st ON st.id = res.student_id
+		JOIN progress_levels l ON l.id = res.level_id
+		WHERE res.grading_id = $1
+		ORDER BY st.name`
+
+	if err := r.db.SelectContext(ctx, &g.Results, query, id); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *ProgressRepository) CreateGrading(ctx context.Context, g *model.Grading) error {
+	query := `
+		INSERT INTO gradings (group_id, title, held_on, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		g.GroupID,
+		g.Title,
+		g.HeldOn,
+		g.Notes,
+		g.CreatedBy,
+	).Scan(&g.ID, &g.CreatedAt)
+}
+
+// DeleteGrading removes a grading with its results and the levels it
+// awarded
+func (r *ProgressRepository) DeleteGrading(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM gradings WHERE id = $1`, id)
+}
+
+// SaveResults replaces the results of a grading. Each passed result awards
+// its level on the day of the grading, replacing the awards of the
+// previous results.
+func (r *ProgressRepository) SaveResults(ctx context.Context, grading *model.Grading, results []model.GradingResult, by uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM level_awards WHERE grading_id = $1`, grading.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM grading_results WHERE grading_id = $1`, grading.ID); err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		query := `
+			INSERT INTO grading_results (grading_id, student_id, level_id, passed, notes)
+			VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.ExecContext(ctx, query, grading.ID, res.StudentID, res.LevelID, res.Passed, res.Notes); err != nil {
+			return err
+		}
+		if !res.Passed {
+			continue
+		}
+
+		award := `
+			INSERT INTO level_awards (student_id, level_id, awarded_on, grading_id, awarded_by)
+			VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.ExecContext(ctx, award, res.StudentID, res.LevelID, grading.HeldOn, grading.ID, by); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ==================== Levels of students ====================
+
+func (r *ProgressRepository) GetAward(ctx context.Context, id uuid.UUID) (*model.LevelAward, error) {
+	var a model.LevelAward
+	err := r.db.GetContext(ctx, &a, `SELECT * FROM level_awards WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAward gives a student a level directly, e.g. one earned before
+// joining the club
+func (r *ProgressRepository) CreateAward(ctx context.Context, a *model.LevelAward) error {
+	query := `
+		INSERT INTO level_awards (student_id, level_id, awarded_on, notes, awarded_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowxContext(ctx, query,
+		a.StudentID,
+		a.LevelID,
+		a.AwardedOn,
+		a.Notes,
+		a.AwardedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+// DeleteAward takes back a level given directly. Levels awarded by a
+// grading change with its results.
+func (r *ProgressRepository) DeleteAward(ctx context.Context, id uuid.UUID) error {
+	return r.execOne(ctx, `DELETE FROM level_awards WHERE id = $1 AND grading_id IS NULL`, id)
+}
+
+// GetStudentLevels returns a student's current level in each group they
+// were awarded one in: the latest award, the highest level on the same day
+func (r *ProgressRepository) GetStudentLevels(ctx context.Context, studentID uuid.UUID) ([]model.StudentLevel, error) {
+	levels := []model.StudentLevel{}
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (l.group_id)
+				l.group_id, g.title as group_title, l.id as level_id, l.name as level_name,
+				l.position, la.awarded_on
+			FROM level_awards la
+			JOIN progress_levels l ON l.id = la.level_id
+			JOIN groups g ON g.id = l.group_id
+			WHERE la.student_id = $1
+			ORDER BY l.group_id, la.awarded_on DESC, l.position DESC, la.created_at DESC
+		) current
+		ORDER BY group_title`
+
+	err := r.db.SelectContext(ctx, &levels, query, studentID)
+	return levels, err
+}
+
+// GetSkillScores returns a student's latest score of each skill assessed
+func (r *ProgressRepository) GetSkillScores(ctx context.Context, studentID uuid.UUID) ([]model.SkillScore, error) {
+	scores := []model.SkillScore{}
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (a.skill_id)
+				a.skill_id, s.name as skill_name, s.group_id, a.score, s.max_score, a.assessed_on
+			FROM skill_assessments a
+			JOIN skills s ON s.id = a.skill_id
+			WHERE a.student_id = $1
+			ORDER BY a.skill_id, a.assessed_on DESC, a.created_at DESC
+		) latest
+		ORDER BY group_id, skill_name`
+
+	err := r.db.SelectContext(ctx, &scores, query, studentID)
+	return scores, err
+}
+
+// GetTimeline returns a student's assessments, grading results and awarded
+// levels, latest first
+func (r *ProgressRepository) GetTimeline(ctx context.Context, studentID uuid.UUID) ([]model.ProgressEvent, error) {
+	events := []model.ProgressEvent{}
+	query := `
+		SELECT kind, id, date, group_id, group_title, title, level_name, score, max_score, passed, notes
+		FROM (
+			SELECT 'assessment' as kind, a.id, a.assessed_on as date, g.id as group_id, g.title as group_title,
+			       s.name as title, '' as level_name, a.score, s.max_score, NULL::boolean as passed, a.notes,
+			       a.created_at
+			FROM skill_assessments a
+			JOIN skills s ON s.id = a.skill_id
+			JOIN groups g ON g.id = s.group_id
+			WHERE a.student_id = $1
+
+			UNION ALL
+
+			SELECT 'grading', gr.id, gr.held_on, g.id, g.title,
+			       gr.title, l.name, NULL::int, NULL::int, res.passed, res.notes,
+			       gr.created_at
+			FROM grading_results res
+			JOIN gradings gr ON gr.id = res.grading_id
+			JOIN progress_levels l ON l.id = res.level_id
+			JOIN groups g ON g.id = gr.group_id
+			WHERE res.student_id = $1
+
+			UNION ALL
+
+			SELECT 'level', la.id, la.awarded_on, g.id, g.title,
+			       l.name, '', NULL::int, NULL::int, NULL::boolean, la.notes,
+			       la.created_at
+			FROM level_awards la
+			JOIN progress_levels l ON l.id = la.level_id
+			JOIN groups g ON g.id = l.group_id
+			WHERE la.student_id = $1
+		) events
+		ORDER BY date DESC, created_at DESC`
+
+	err := r.db.SelectContext(ctx, &events, query, studentID)
+	return events, err
+}
+
+// MissingStudents returns the ids that are not students of the club
+func (r *ProgressRepository) MissingStudents(ctx context.Context, clubID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var missing []uuid.UUID
+	query := `
+		SELECT wanted.id::uuid FROM unnest($2::text[]) AS wanted(id)
+		WHERE NOT EXISTS (SELECT 1 FROM students st WHERE st.id = wanted.id::uuid AND st.club_id = $1)`
+
+	err := r.db.SelectContext(ctx, &missing, query, clubID, uuidArray(ids))
+	return missing, err
+}
+
+// IsDuplicateProgressName reports whether err is the per-group unique name
+// violation of a level or skill
+func IsDuplicateProgressName(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" &&
+		(pqErr.Constraint == "progress_levels_group_name" || pqErr.Constraint == "skills_group_name")
+}
+
+// execOne runs a statement on one row, returning ErrNotFound if it
+// touched none
+func (r *ProgressRepository) execOne(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// PurgeRepository deletes archived students, groups and clubs for good
+type PurgeRepository struct {
+	db *sqlx.DB
+}
+
+func NewPurgeRepository(db *sqlx.DB) *PurgeRepository {
+	return &PurgeRepository{db: db}
+}
+
+// purgeTables maps what can be purged to its table and the column holding
+// its club
+var purgeTables = map[model.PurgeEntity]struct{ table, clubColumn string }{
+	model.PurgeStudent: {"students", "club_id"},
+	model.PurgeGroup:   {"groups", "club_id"},
+	model.PurgeClub:    {"clubs", "id"},
+}
+
+// LockInTx locks a student, group or club and returns its club and when it
+// was archived (nil if it is not)
+// Must be called within a transaction
+func (r *PurgeRepository) LockInTx(ctx context.Context, tx *sqlx.Tx, entity model.PurgeEntity, id uuid.UUID) (uuid.UUID, *time.Time, error) {
+	t, ok := purgeTables[entity]
+	if !ok {
+		return uuid.Nil, nil, fmt.Errorf("repository: cannot purge %q", entity)
+	}
+
+	var row struct {
+		ClubID     uuid.UUID  `db:"club_id"`
+		ArchivedAt *time.Time `db:"archived_at"`
+	}
+	query := `SELECT ` + t.clubColumn + ` AS club_id, archived_at FROM ` + t.table + ` WHERE id = $1 FOR UPDATE`
+
+	err := tx.GetContext(ctx, &row, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil, ErrNotFound
+	}
+	return row.ClubID, row.ArchivedAt, err
+}
+
+// CountSubscriptionsInTx counts the subscriptions of a student, of a group
+// or of the groups of a club
+// Must be called within a transaction
+func (r *PurgeRepository) CountSubscriptionsInTx(ctx context.Context, tx *sqlx.Tx, entity model.PurgeEntity, id uuid.UUID) (int, error) {
+	var query string
+	switch entity {
+	case model.PurgeStudent:
+		query = `SELECT COUNT(*) FROM subscriptions WHERE student_id = $1`
+	case model.PurgeGroup:
+		query = `SELECT COUNT(*) FROM subscriptions WHERE group_id = $1`
+	case model.PurgeClub:
+		query = `
+			SELECT COUNT(*) FROM subscriptions sub
+			JOIN groups g ON g.id = sub.group_id
+			WHERE g.club_id = $1`
+	default:
+		return 0, fmt.Errorf("repository: cannot purge %q", entity)
+	}
+
+	var count int
+	err := tx.GetContext(ctx, &count, query, id)
+	return count, err
+}
+
+// DocumentKeysInTx returns the storage keys of the documents that go with
+// a student or club, so their files can be removed after the purge
+// Must be called within a transaction
+func (r *PurgeRepository) DocumentKeysInTx(ctx context.Context, tx *sqlx.Tx, entity model.PurgeEntity, id uuid.UUID) ([]string, error) {
+	var query string
+	switch entity {
+	case model.PurgeStudent:
+		query = `SELECT storage_key FROM student_documents WHERE student_id = $1`
+	case model.PurgeClub:
+		query = `
+			SELECT d.storage_key FROM student_documents d
+			JOIN students st ON st.id = d.student_id
+			WHERE st.club_id = $1`
+	default:
+		return nil, nil
+	}
+
+	var keys []string
+	err := tx.SelectContext(ctx, &keys, query, id)
+	return keys, err
+}
+
+// DeleteInTx deletes a student, group or club with everything that
+// cascades from it
+// Must be called within a transaction
+func (r *PurgeRepository) DeleteInTx(ctx context.Context, tx *sqlx.Tx, entity model.PurgeEntity, id uuid.UUID) error {
+	t, ok := purgeTables[entity]
+	if !ok {
+		return fmt.Errorf("repository: cannot purge %q", entity)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM `+t.table+` WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateInTx records a purge
+// Must be called within a transaction
+func (r *PurgeRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, purge *model.Purge) error {
+	query := `
+		INSERT INTO purges (entity, entity_id, club_id, kept_subscriptions, purged_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		purge.Entity,
+		purge.EntityID,
+		purge.ClubID,
+		purge.KeptSubscriptions,
+		purge.PurgedBy,
+	).Scan(&purge.ID, &purge.CreatedAt)
+}
+
+// GetRecent returns the latest purges, newest first
+func (r *PurgeRepository) GetRecent(ctx context.Context, limit int) ([]model.Purge, error) {
+	purges := []model.Purge{}
+	query := `SELECT * FROM purges ORDER BY created_at DESC LIMIT $1`
+
+	err := r.db.SelectContext(ctx, &purges, query, limit)
+	return purges, err
+}
+
+// BeginTx starts a new transaction
+func (r *PurgeRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type ReminderRepository struct {
+	db *sqlx.DB
+}
+
+func NewReminderRepository(db *sqlx.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// GetSettings returns ErrNotFound for a club that has not saved settings
+func (r *ReminderRepository) GetSettings(ctx context.Context, clubID uuid.UUID) (*model.ReminderSettings, error) {
+	var s reminderSettingsDB
+	err := r.db.GetContext(ctx, &s, `SELECT * FROM reminder_settings WHERE club_id = $1`, clubID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.toModel(), nil
+}
+
+func (r *ReminderRepository) GetRules(ctx context.Context, clubID uuid.UUID) ([]model.ReminderRule, error) {
+	return r.selectRules(ctx, `SELECT * FROM reminder_rules WHERE club_id = $1 ORDER BY kind`, clubID)
+}
+
+// GetEnabledRules returns the enabled rules of all clubs
+func (r *ReminderRepository) GetEnabledRules(ctx context.Context) ([]model.ReminderRule, error) {
+	return r.selectRules(ctx, `SELECT * FROM reminder_rules WHERE enabled ORDER BY club_id, kind`)
+}
+
+func (r *ReminderRepository) selectRules(ctx context.Context, query string, args ...any) ([]model.ReminderRule, error) {
+	var rows []reminderRuleDB
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	rules := make([]model.ReminderRule, len(rows))
+	for i := range rows {
+		rules[i] = *rows[i].toModel()
+	}
+	return rules, nil
+}
+
+// Save replaces a club's reminder settings and rules. Rules of kinds not
+// listed are removed.
+func (r *ReminderRepository) Save(ctx context.Context, settings *model.ReminderSettings, rules []model.ReminderRule) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reminder_settings (club_id, quiet_hours_start, quiet_hours_end, channels)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (club_id) DO UPDATE
+		SET quiet_hours_start = EXCLUDED.quiet_hours_start,
+		    quiet_hours_end = EXCLUDED.quiet_hours_end,
+		    channels = EXCLUDED.channels,
+		    updated_at = now()
+		RETURNING updated_at`
+
+	err = tx.QueryRowxContext(ctx, query,
+		settings.ClubID,
+		settings.QuietHoursStart,
+		settings.QuietHoursEnd,
+		pq.StringArray(nonNilStrings(settings.Channels)),
+	).Scan(&settings.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	kinds := make([]string, len(rules))
+	for i := range rules {
+		kinds[i] = rules[i].Kind
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM reminder_rules WHERE club_id = $1 AND NOT (kind = ANY($2))`,
+		settings.ClubID, pq.StringArray(kinds)); err != nil {
+		return err
+	}
+
+	upsert := `
+		INSERT INTO reminder_rules (club_id, kind, enabled, threshold, channels)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (club_id, kind) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    threshold = EXCLUDED.threshold,
+		    channels = EXCLUDED.channels,
+		    updated_at = now()
+		RETURNING id, created_at, updated_at`
+
+	for i := range rules {
+		rule := &rules[i]
+		rule.ClubID = settings.ClubID
+		err := tx.QueryRowxContext(ctx, upsert,
+			rule.ClubID,
+			rule.Kind,
+			rule.Enabled,
+			rule.Threshold,
+			pq.StringArray(nonNilStrings(rule.Channels)),
+		).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReminderCandidate is a student a reminder rule currently applies to.
+// Only the fields of the rule's kind are set.
+type ReminderCandidate struct {
+	ClubID            uuid.UUID            `db:"club_id"`
+	StudentID         uuid.UUID            `db:"student_id"`
+	StudentName       string               `db:"student_name"`
+	ParentContactRaw  []byte               `db:"parent_contact"`
+	ParentContact     *model.ParentContact `db:"-"`
+	GroupTitle        string               `db:"group_title"`
+	SubscriptionID    *uuid.UUID           `db:"subscription_id"`
+	RemainingSessions int                  `db:"remaining_sessions"`
+	ExpiresAt         *time.Time           `db:"expires_at"`
+	SessionID         *uuid.UUID           `db:"session_id"`
+	StartAt           *time.Time           `db:"start_at"`
+	Amount            float64              `db:"amount"`
+	Since             *time.Time           `db:"since"`
+	CertificateID     *uuid.UUID           `db:"certificate_id"`
+}
+
+// reminderRenewed excludes subscriptions the student has already renewed
+// in the same group, paid or not
+const reminderRenewed = `
+	NOT EXISTS (
+		SELECT 1 FROM subscriptions nxt
+		WHERE nxt.student_id = sub.student_id AND nxt.group_id = sub.group_id
+		  AND nxt.id <> sub.id AND nxt.status IN ('pending', 'active')
+		  AND nxt.created_at > sub.created_at
+	)`
+
+// LowBalanceCandidates lists active package subscriptions with at most
+// threshold sessions left
+func (r *ReminderRepository) LowBalanceCandidates(ctx context.Context, now time.Time) ([]ReminderCandidate, error) {
+	query := `
+		SELECT g.club_id, st.id AS student_id, st.name AS student_name, st.parent_contact,
+		       g.title AS group_title, sub.id AS subscription_id, sub.remaining_sessions, sub.expires_at
+		FROM subscriptions sub
+		JOIN groups g ON g.id = sub.group_id AND g.archived_at IS NULL
+		JOIN clubs c ON c.id = g.club_id AND c.archived_at IS NULL
+		JOIN students st ON st.id = sub.student_id AND st.archived_at IS NULL
+		JOIN reminder_rules rr ON rr.club_id = g.club_id AND rr.kind = 'low_balance' AND rr.enabled
+		WHERE sub.status = 'active' AND sub.kind = 'package'
+		  AND sub.remaining_sessions <= rr.threshold
+		  AND (sub.expires_at IS NULL OR sub.expires_at > $1)
+		  AND ` + reminderRenewed
+
+	return r.selectCandidates(ctx, query, now)
+}
+
+// ExpiringCandidates lists active package subscriptions with sessions left
+// that expire within threshold days
+func (r *ReminderRepository) ExpiringCandidates(ctx context.Context, now time.Time) ([]ReminderCandidate, error) {
+	query := `
+		SELECT g.club_id, st.id AS student_id, st.name AS student_name, st.parent_contact,
+		       g.title AS group_title, sub.id AS subscription_id, sub.remaining_sessions, sub.expires_at
+		FROM subscriptions sub
+		JOIN groups g ON g.id = sub.group_id AND g.archived_at IS NULL
+		JOIN clubs c ON c.id = g.club_id AND c.archived_at IS NULL
+		JOIN students st ON st.id = sub.student_id AND st.archived_at IS NULL
+		JOIN reminder_rules rr ON rr.club_id = g.club_id AND rr.kind = 'expiring' AND rr.enabled
+		WHERE sub.status = 'active' AND sub.kind = 'package'
+		  AND sub.remaining_sessions > 0
+		  AND sub.expires_at > $1
+		  AND sub.expires_at <= $1 + rr.threshold * interval '1 day'
+		  AND ` + reminderRenewed
+
+	return r.selectCandidates(ctx, query, now)
+}
+
+// UpcomingSessionCandidates lists the students expected in sessions that
+// start within threshold hours: those with an active subscription of the
+// group valid at the start, as on the session roster
+func (r *ReminderRepository) UpcomingSessionCandidates(ctx context.Context, now time.Time) ([]ReminderCandidate, error) {
+	query := `
+		SELECT DISTINCT ON (s.id, st.id)
+		       g.club_id, st.id AS student_id, st.name AS student_name, st.parent_contact,
+		       g.title AS group_title, sub.id AS subscription_id, sub.remaining_sessions,
+		       s.id AS session_id, s.start_at
+		FROM sessions s
+		JOIN groups g ON g.id = s.group_id AND g.archived_at IS NULL
+		JOIN clubs c ON c.id = g.club_id AND c.archived_at IS NULL
+		JOIN reminder_rules rr ON rr.club_id = g.club_id AND rr.kind = 'upcoming_session' AND rr.enabled
+		JOIN subscriptions sub ON sub.group_id = s.group_id
+		 AND sub.status = 'active'
+		 AND sub.remaining_sessions > 0
+		 AND (sub.starts_at IS NULL OR sub.starts_at <= s.start_at)
+		 AND (sub.expires_at IS NULL OR sub.expires_at >= s.start_at)
+		JOIN students st ON st.id = sub.student_id AND st.archived_at IS NULL
+		WHERE s.status = 'scheduled'
+		  AND s.start_at > $1
+		  AND s.start_at <= $1 + rr.threshold * interval '1 hour'
+		ORDER BY s.id, st.id, sub.starts_at ASC NULLS LAST`
+
+	return r.selectCandidates(ctx, query, now)
+}
+
+// UnpaidDebtCandidates lists subscriptions that have been pending for at
+// least threshold days, the same debts GetDebtReport shows
+func (r *ReminderRepository) UnpaidDebtCandidates(ctx context.Context, now time.Time) ([]ReminderCandidate, error) {
+	query := `
+		SELECT g.club_id, st.id AS student_id, st.name AS student_name, st.parent_contact,
+		       g.title AS group_title, sub.id AS subscription_id, sub.remaining_sessions,
+		       sub.price AS amount, sub.created_at AS since
+		FROM subscriptions sub
+		JOIN groups g ON g.id = sub.group_id AND g.archived_at IS NULL
+		JOIN clubs c ON c.id = g.club_id AND c.archived_at IS NULL
+		JOIN students st ON st.id = sub.student_id AND st.archived_at IS NULL
+		JOIN reminder_rules rr ON rr.club_id = g.club_id AND rr.kind = 'unpaid_debt' AND rr.enabled
+		WHERE sub.status = 'pending'
+		  AND sub.created_at <= $1 - rr.threshold * interval '1 day'`
+
+	return r.selectCandidates(ctx, query, now)
+}
+
+// MedicalCertificateCandidates lists students whose latest medical
+// certificate expires within threshold days of the club's current date,
+// the same certificates GetCertificateAlerts shows
+func (r *ReminderRepository) MedicalCertificateCandidates(ctx context.Context, now time.Time) ([]ReminderCandidate, error) {
+	query := `
+		SELECT club_id, student_id, student_name, parent_contact, certificate_id, expires_at
+		FROM (
+			SELECT DISTINCT ON (st.id)
+			       st.club_id, st.id AS student_id, st.name AS student_name, st.parent_contact,
+			       mc.id AS certificate_id, mc.expires_on AS expires_at,
+			       ($1 AT TIME ZONE c.timezone)::date AS today, rr.threshold
+			FROM medical_certificates mc
+			JOIN students st ON st.id = mc.student_id AND st.archived_at IS NULL
+			JOIN clubs c ON c.id = st.club_id AND c.archived_at IS NULL
+			JOIN reminder_rules rr ON rr.club_id = st.club_id AND rr.kind = 'medical_certificate' AND rr.enabled
+			ORDER BY st.id, mc.expires_on DESC
+		) latest
+		WHERE expires_at >= today
+		  AND expires_at <= today + threshold`
+
+	return r.selectCandidates(ctx, query, now)
+}
+
+func (r *ReminderRepository) selectCandidates(ctx context.Context, query string, now time.Time) ([]ReminderCandidate, error) {
+	var candidates []ReminderCandidate
+	if err := r.db.SelectContext(ctx, &candidates, query, now); err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		if c.ParentContactRaw != nil {
+			var pc model.ParentContact
+			json.Unmarshal(c.ParentContactRaw, &pc)
+			c.ParentContact = &pc
+		}
+	}
+	return candidates, nil
+}
+
+// Helper structs for DB scanning with TEXT[]
+type reminderSettingsDB struct {
+	model.ReminderSettings
+	ChannelsRaw pq.StringArray `db:"channels"`
+}
+
+func (s *reminderSettingsDB) toModel() *model.ReminderSettings {
+	s.ReminderSettings.Channels = nonNilStrings(s.ChannelsRaw)
+	return &s.ReminderSettings
+}
+
+type reminderRuleDB struct {
+	model.ReminderRule
+	ChannelsRaw pq.StringArray `db:"channels"`
+}
+
+func (r *reminderRuleDB) toModel() *model.ReminderRule {
+	r.ReminderRule.Channels = nonNilStrings(r.ChannelsRaw)
+	return &r.ReminderRule
+}
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
 )
 
 type ReportRepository struct {
@@ -47,6 +51,10 @@ type DailyRevenue struct {
 	Count  int     `db:"count" json:"count"`
 }
 
+// GetFinanceReport summarises payments between from and to. Daily revenue
+// is bucketed by calendar day in from's location (the club's time zone).
+// Revenue here is cash as it is paid; see GetRevenueReport for the revenue
+// earned.
 func (r *ReportRepository) GetFinanceReport(ctx context.Context, clubID uuid.UUID, from, to time.Time) (*FinanceReport, error) {
 	report := &FinanceReport{}
 
@@ -120,7 +128,7 @@ func (r *ReportRepository) GetFinanceReport(ctx context.Context, clubID uuid.UUI
 	// Daily revenue
 	dailyQuery := `
 		SELECT 
-			TO_CHAR(p.paid_at, 'YYYY-MM-DD') as date,
+			TO_CHAR(p.paid_at AT TIME ZONE $4, 'YYYY-MM-DD') as date,
 			SUM(p.amount) as amount,
 			COUNT(*) as count
 		FROM payments p
@@ -129,10 +137,10 @@ func (r *ReportRepository) GetFinanceReport(ctx context.Context, clubID uuid.UUI
 		WHERE g.club_id = $1 
 		  AND p.status = 'succeeded'
 		  AND p.paid_at BETWEEN $2 AND $3
-		GROUP BY TO_CHAR(p.paid_at, 'YYYY-MM-DD')
+		GROUP BY 1
 		ORDER BY date`
 
-	if err := r.db.SelectContext(ctx, &report.DailyRevenue, dailyQuery, clubID, from, to); err != nil {
+	if err := r.db.SelectContext(ctx, &report.DailyRevenue, dailyQuery, clubID, from, to, from.Location().String()); err != nil {
 		return nil, err
 	}
 
@@ -168,6 +176,7 @@ func (r *ReportRepository) GetOccupancyReport(ctx context.Context, clubID uuid.U
 			FROM sessions s
 			LEFT JOIN attendances a ON a.session_id = s.id
 			WHERE s.start_at BETWEEN $2 AND $3
+			  AND s.status = 'scheduled'
 			GROUP BY s.group_id, s.id
 		)
 		SELECT 
@@ -205,21 +214,107 @@ func (r *ReportRepository) GetOccupancyReport(ctx context.Context, clubID uuid.U
 	return report, nil
 }
 
+// UtilisationReport shows how much of each location's opening hours is booked
+type UtilisationReport struct {
+	Weeks           float64               `json:"weeks"`
+	UnassignedHours float64               `json:"unassigned_hours"`
+	Locations       []LocationUtilisation `json:"locations"`
+}
+
+type LocationUtilisation struct {
+	LocationID         uuid.UUID `db:"location_id" json:"location_id"`
+	Name               string    `db:"name" json:"name"`
+	Capacity           *int      `db:"capacity" json:"capacity,omitempty"`
+	SessionCount       int       `db:"session_count" json:"session_count"`
+	BookedHours        float64   `db:"booked_hours" json:"booked_hours"`
+	BookedHoursPerWeek float64   `json:"booked_hours_per_week"`
+	OpenHoursPerWeek   float64   `json:"open_hours_per_week"`
+	UtilisationRate    float64   `json:"utilisation_rate"`
+	OpeningHoursRaw    []byte    `db:"opening_hours" json:"-"`
+}
+
+// GetUtilisationReport sums scheduled session time per location between
+// from and to and compares the weekly average with the opening hours
+func (r *ReportRepository) GetUtilisationReport(ctx context.Context, clubID uuid.UUID, from, to time.Time) (*UtilisationReport, error) {
+	report := &UtilisationReport{
+		Weeks:     to.Sub(from).Hours() / (24 * 7),
+		Locations: []LocationUtilisation{},
+	}
+
+	query := `
+		SELECT
+			l.id as location_id,
+			l.name,
+			l.capacity,
+			l.opening_hours,
+			COUNT(s.id) as session_count,
+			COALESCE(SUM(s.duration_minutes), 0)::float / 60 as booked_hours
+		FROM locations l
+		LEFT JOIN sessions s ON s.location_id = l.id
+			AND s.start_at BETWEEN $2 AND $3
+			AND s.status = 'scheduled'
+		WHERE l.club_id = $1
+		GROUP BY l.id, l.name, l.capacity, l.opening_hours
+		ORDER BY booked_hours DESC, l.name`
+
+	if err := r.db.SelectContext(ctx, &report.Locations, query, clubID, from, to); err != nil {
+		return nil, err
+	}
+
+	unassignedQuery := `
+		SELECT COALESCE(SUM(s.duration_minutes), 0)::float / 60
+		FROM sessions s
+		JOIN groups g ON g.id = s.group_id
+		WHERE g.club_id = $1
+		  AND s.location_id IS NULL
+		  AND s.start_at BETWEEN $2 AND $3
+		  AND s.status = 'scheduled'`
+
+	if err := r.db.GetContext(ctx, &report.UnassignedHours, unassignedQuery, clubID, from, to); err != nil {
+		return nil, err
+	}
+
+	for i := range report.Locations {
+		lu := &report.Locations[i]
+
+		var hours model.OpeningHours
+		if lu.OpeningHoursRaw != nil {
+			json.Unmarshal(lu.OpeningHoursRaw, &hours)
+		}
+		lu.OpenHoursPerWeek = hours.WeeklyHours()
+
+		if report.Weeks > 0 {
+			lu.BookedHoursPerWeek = lu.BookedHours / report.Weeks
+		}
+		if lu.OpenHoursPerWeek > 0 {
+			lu.UtilisationRate = lu.BookedHoursPerWeek / lu.OpenHoursPerWeek * 100
+		}
+	}
+
+	return report, nil
+}
+
 // MRRReport represents monthly recurring revenue
 type MRRReport struct {
 	Month           string  `json:"month"`
 	Revenue         float64 `json:"revenue"`
 	NewSubscriptions int    `json:"new_subscriptions"`
 	ChurnedSubscriptions int `json:"churned_subscriptions"`
+	ChurnedStudents     int `json:"churned_students"`
 	ActiveSubscriptions int `json:"active_subscriptions"`
 }
 
+// GetMRRReport computes the report for the calendar month containing month,
+// with boundaries taken in month's location (the club's time zone).
+// Churned subscriptions are those that ended in the month without the
+// student renewing in the club within DefaultRenewalDays; see
+// GetRetentionReport.
 func (r *ReportRepository) GetMRRReport(ctx context.Context, clubID uuid.UUID, month time.Time) (*MRRReport, error) {
 	report := &MRRReport{
 		Month: month.Format("2006-01"),
 	}
 
-	startOfMonth := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	startOfMonth := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
 	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Second)
 
 	// Revenue for month
@@ -248,18 +343,24 @@ func (r *ReportRepository) GetMRRReport(ctx context.Context, clubID uuid.UUID, m
 		return nil, err
 	}
 
-	// Churned (cancelled or expired) this month
+	// Ended this month and not renewed
 	churnedQuery := `
-		SELECT COUNT(*)
-		FROM subscriptions s
-		JOIN groups g ON s.group_id = g.id
-		WHERE g.club_id = $1 
-		  AND s.status IN ('cancelled', 'expired', 'used')
-		  AND s.expires_at BETWEEN $2 AND $3`
-
-	if err := r.db.GetContext(ctx, &report.ChurnedSubscriptions, churnedQuery, clubID, startOfMonth, endOfMonth); err != nil {
+		WITH ` + subscriptionSpells + `
+		SELECT COUNT(*) as subscriptions, COUNT(DISTINCT sp.student_id) as students
+		FROM spells sp
+		WHERE sp.ended_at BETWEEN $2 AND $3
+		  AND sp.ended_at + make_interval(days => $4) <= now()
+		  AND NOT ` + renewed("TRUE", "$4")
+
+	var churned struct {
+		Subscriptions int `db:"subscriptions"`
+		Students      int `db:"students"`
+	}
+	if err := r.db.GetContext(ctx, &churned, churnedQuery, clubID, startOfMonth, endOfMonth, DefaultRenewalDays); err != nil {
 		return nil, err
 	}
+	report.ChurnedSubscriptions = churned.Subscriptions
+	report.ChurnedStudents = churned.Students
 
 	// Active subscriptions at end of month
 	activeQuery := `
@@ -375,6 +476,7 @@ type DebtorInfo struct {
 	ParentEmail   string     `db:"parent_email" json:"parent_email,omitempty"`
 	SubscriptionID uuid.UUID `db:"subscription_id" json:"subscription_id"`
 	GroupTitle    string     `db:"group_title" json:"group_title"`
+	Kind          string     `db:"kind" json:"kind"` // package or drop_in
 	Amount        float64    `db:"amount" json:"amount"`
 	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
 	DaysOverdue   int        `db:"days_overdue" json:"days_overdue"`
@@ -393,6 +495,7 @@ func (r *ReportRepository) GetDebtReport(ctx context.Context, clubID uuid.UUID,
 			COALESCE(st.parent_contact->>'email', '') as parent_email,
 			s.id as subscription_id,
 			g.title as group_title,
+			s.kind,
 			s.price as amount,
 			s.created_at,
 			EXTRACT(DAY FROM NOW() - s.created_at)::int as days_overdue
@@ -416,6 +519,749 @@ func (r *ReportRepository) GetDebtReport(ctx context.Context, clubID uuid.UUID,
 	return report, nil
 }
 
+// TrialReport shows how trial students convert into paying ones and what
+// drop-in visits brought in
+type TrialReport struct {
+	TrialStudents     int         `json:"trial_students"`
+	ConvertedStudents int         `json:"converted_students"`
+	ConversionRate    float64     `json:"conversion_rate"`
+	DropInVisits      int         `json:"drop_in_visits"`
+	DropInPaid        float64     `json:"drop_in_paid"`
+	DropInOutstanding float64     `json:"drop_in_outstanding"`
+	Leads             []TrialLead `json:"leads"`
+}
+
+// TrialLead is a student who took a trial; not yet converted ones are the
+// leads to follow up
+type TrialLead struct {
+	StudentID    uuid.UUID  `db:"student_id" json:"student_id"`
+	StudentName  string     `db:"student_name" json:"student_name"`
+	ParentPhone  string     `db:"parent_phone" json:"parent_phone,omitempty"`
+	ParentEmail  string     `db:"parent_email" json:"parent_email,omitempty"`
+	GroupTitle   string     `db:"group_title" json:"group_title"`
+	FirstTrialAt time.Time  `db:"first_trial_at" json:"first_trial_at"`
+	TrialCount   int        `db:"trial_count" json:"trial_count"`
+	ConvertedAt  *time.Time `db:"converted_at" json:"converted_at,omitempty"`
+}
+
+// GetTrialReport covers students whose first trial fell between from and
+// to. A student converts by getting a package subscription that is paid
+// for (not pending or cancelled) after the first trial.
+func (r *ReportRepository) GetTrialReport(ctx context.Context, clubID uuid.UUID, from, to time.Time) (*TrialReport, error) {
+	report := &TrialReport{Leads: []TrialLead{}}
+
+	leadsQuery := `
+		WITH trials AS (
+			SELECT
+				a.student_id,
+				MIN(s.start_at) as first_trial_at,
+				COUNT(*) as trial_count,
+				(ARRAY_AGG(g.title ORDER BY s.start_at))[1] as group_title
+			FROM attendances a
+			JOIN sessions s ON a.session_id = s.id
+			JOIN groups g ON s.group_id = g.id
+			WHERE g.club_id = $1
+			  AND a.kind = 'trial'
+			  AND a.status = 'present'
+			GROUP BY a.student_id
+			HAVING MIN(s.start_at) BETWEEN $2 AND $3
+		)
+		SELECT
+			t.student_id,
+			st.name as student_name,
+			COALESCE(st.parent_contact->>'phone', '') as parent_phone,
+			COALESCE(st.parent_contact->>'email', '') as parent_email,
+			t.group_title,
+			t.first_trial_at,
+			t.trial_count,
+			(SELECT MIN(sub.created_at) FROM subscriptions sub
+			 WHERE sub.student_id = t.student_id
+			   AND sub.kind = 'package'
+			   AND sub.status NOT IN ('pending', 'cancelled')
+			   AND sub.created_at >= t.first_trial_at) as converted_at
+		FROM trials t
+		JOIN students st ON t.student_id = st.id
+		ORDER BY t.first_trial_at DESC`
+
+	if err := r.db.SelectContext(ctx, &report.Leads, leadsQuery, clubID, from, to); err != nil {
+		return nil, err
+	}
+
+	report.TrialStudents = len(report.Leads)
+	for _, l := range report.Leads {
+		if l.ConvertedAt != nil {
+			report.ConvertedStudents++
+		}
+	}
+	if report.TrialStudents > 0 {
+		report.ConversionRate = float64(report.ConvertedStudents) / float64(report.TrialStudents) * 100
+	}
+
+	dropInQuery := `
+		SELECT
+			COUNT(*) as visits,
+			COALESCE(SUM(CASE WHEN s.status NOT IN ('pending', 'cancelled') THEN s.price ELSE 0 END), 0) as paid,
+			COALESCE(SUM(CASE WHEN s.status = 'pending' THEN s.price ELSE 0 END), 0) as outstanding
+		FROM subscriptions s
+		JOIN groups g ON s.group_id = g.id
+		WHERE g.club_id = $1
+		  AND s.kind = 'drop_in'
+		  AND s.status <> 'cancelled'
+		  AND s.starts_at BETWEEN $2 AND $3`
+
+	var dropIns struct {
+		Visits      int     `db:"visits"`
+		Paid        float64 `db:"paid"`
+		Outstanding float64 `db:"outstanding"`
+	}
+	if err := r.db.GetContext(ctx, &dropIns, dropInQuery, clubID, from, to); err != nil {
+		return nil, err
+	}
+	report.DropInVisits = dropIns.Visits
+	report.DropInPaid = dropIns.Paid
+	report.DropInOutstanding = dropIns.Outstanding
+
+	return report, nil
+}
+
+// LevelReport shows how the students of each group with levels are spread
+// over them. Students count in a group while they have an active or
+// pending subscription to it.
+type LevelReport struct {
+	Groups []GroupLevels `json:"groups"`
+}
+
+type GroupLevels struct {
+	GroupID      uuid.UUID    `json:"group_id"`
+	GroupTitle   string       `json:"group_title"`
+	Students     int          `json:"students"`
+	WithoutLevel int          `json:"without_level"`
+	Levels       []LevelCount `json:"levels"`
+}
+
+type LevelCount struct {
+	LevelID  uuid.UUID `db:"level_id" json:"level_id"`
+	Name     string    `db:"name" json:"name"`
+	Position int       `db:"position" json:"position"`
+	Students int       `db:"students" json:"students"`
+	// Share is the percentage of the group's students at the level
+	Share float64 `db:"-" json:"share"`
+}
+
+// GetLevelReport counts the students of each active group at their current
+// level, the one awarded last
+func (r *ReportRepository) GetLevelReport(ctx context.Context, clubID uuid.UUID) (*LevelReport, error) {
+	report := &LevelReport{Groups: []GroupLevels{}}
+
+	const members = `
+		members AS (
+			SELECT DISTINCT sub.group_id, sub.student_id
+			FROM subscriptions sub
+			JOIN groups g ON g.id = sub.group_id
+			JOIN students st ON st.id = sub.student_id
+			WHERE g.club_id = $1
+			  AND g.archived_at IS NULL
+			  AND st.archived_at IS NULL
+			  AND sub.status IN ('active', 'pending')
+		),
+		current AS (
+			SELECT DISTINCT ON (la.student_id, l.group_id) la.student_id, l.group_id, l.id as level_id
+			FROM level_awards la
+			JOIN progress_levels l ON l.id = la.level_id
+			JOIN groups g ON g.id = l.group_id
+			WHERE g.club_id = $1
+			ORDER BY la.student_id, l.group_id, la.awarded_on DESC, l.position DESC, la.created_at DESC
+		)`
+
+	levelsQuery := `
+		WITH ` + members + `
+		SELECT
+			g.id as group_id,
+			g.title as group_title,
+			l.id as level_id,
+			l.name,
+			l.position,
+			COUNT(m.student_id) as students
+		FROM groups g
+		JOIN progress_levels l ON l.group_id = g.id
+		LEFT JOIN current c ON c.level_id = l.id
+		LEFT JOIN members m ON m.group_id = g.id AND m.student_id = c.student_id
+		WHERE g.club_id = $1 AND g.archived_at IS NULL
+		GROUP BY g.id, g.title, l.id, l.name, l.position
+		ORDER BY g.title, g.id, l.position, l.name`
+
+	var levels []struct {
+		GroupID    uuid.UUID `db:"group_id"`
+		GroupTitle string    `db:"group_title"`
+		LevelCount
+	}
+	if err := r.db.SelectContext(ctx, &levels, levelsQuery, clubID); err != nil {
+		return nil, err
+	}
+
+	totalsQuery := `
+		WITH ` + members + `
+		SELECT
+			m.group_id,
+			COUNT(*) as students,
+			COUNT(*) FILTER (WHERE c.level_id IS NULL) as without_level
+		FROM members m
+		LEFT JOIN current c ON c.group_id = m.group_id AND c.student_id = m.student_id
+		GROUP BY m.group_id`
+
+	var totals []struct {
+		GroupID      uuid.UUID `db:"group_id"`
+		Students     int       `db:"students"`
+		WithoutLevel int       `db:"without_level"`
+	}
+	if err := r.db.SelectContext(ctx, &totals, totalsQuery, clubID); err != nil {
+		return nil, err
+	}
+	byGroup := make(map[uuid.UUID]int, len(totals))
+	for i, t := range totals {
+		byGroup[t.GroupID] = i
+	}
+
+	for _, l := range levels {
+		n := len(report.Groups)
+		if n == 0 || report.Groups[n-1].GroupID != l.GroupID {
+			group := GroupLevels{GroupID: l.GroupID, GroupTitle: l.GroupTitle}
+			if i, ok := byGroup[l.GroupID]; ok {
+				group.Students, group.WithoutLevel = totals[i].Students, totals[i].WithoutLevel
+			}
+			report.Groups = append(report.Groups, group)
+			n++
+		}
+
+		group := &report.Groups[n-1]
+		if group.Students > 0 {
+			l.Share = float64(l.Students) / float64(group.Students) * 100
+		}
+		group.Levels = append(group.Levels, l.LevelCount)
+	}
+
+	return report, nil
+}
+
+// DefaultRenewalDays is how many days after a subscription ends a new one
+// still counts as renewing it
+const DefaultRenewalDays = 14
+
+// At-risk students visited at least atRiskMinVisits times in the
+// atRiskWeeks before the last atRiskWeeks, and at most half as often since
+const (
+	atRiskWeeks     = 4
+	atRiskMinVisits = 2
+)
+
+// subscriptionSpells lists the package subscriptions of club $1 that ran,
+// i.e. were neither pending nor cancelled, with when they started and
+// ended. A used up subscription ends at its last visit and an expired one
+// at its expiry; any other ends at its expiry, if it has one.
+const subscriptionSpells = `
+		spells AS (
+			SELECT
+				sub.id,
+				sub.student_id,
+				sub.group_id,
+				g.coach_user_id,
+				COALESCE(sub.starts_at, sub.created_at) as started_at,
+				CASE
+					WHEN sub.status = 'used' THEN COALESCE(
+						(SELECT MAX(se.start_at) FROM attendances a
+						 JOIN sessions se ON se.id = a.session_id
+						 WHERE a.subscription_id = sub.id),
+						sub.expires_at, sub.starts_at, sub.created_at)
+					WHEN sub.status = 'expired' THEN COALESCE(sub.expires_at, sub.starts_at, sub.created_at)
+					ELSE sub.expires_at
+				END as ended_at
+			FROM subscriptions sub
+			JOIN groups g ON g.id = sub.group_id
+			WHERE g.club_id = $1
+			  AND sub.kind = 'package'
+			  AND sub.status NOT IN ('pending', 'cancelled')
+		)`
+
+// renewed is the condition that spell sp was renewed: another spell of
+// the student, among those scope allows, started within days after sp
+// ended and either started after sp or outlasted it
+func renewed(scope, days string) string {
+	return `EXISTS (
+				SELECT 1 FROM spells n
+				WHERE n.student_id = sp.student_id
+				  AND n.id <> sp.id
+				  AND ` + scope + `
+				  AND n.started_at <= sp.ended_at + make_interval(days => ` + days + `)
+				  AND (n.started_at > sp.started_at OR n.ended_at IS NULL OR n.ended_at > sp.ended_at)
+			)`
+}
+
+// RetentionReport follows students from their first subscription to the
+// club and shows who did not renew
+type RetentionReport struct {
+	RenewalDays     int               `json:"renewal_days"`
+	EndedStudents   int               `json:"ended_students"`
+	ChurnedStudents int               `json:"churned_students"`
+	ChurnRate       float64           `json:"churn_rate"`
+	Cohorts         []RetentionCohort `json:"cohorts"`
+	Groups          []GroupChurn      `json:"groups"`
+	Coaches         []CoachChurn      `json:"coaches"`
+	AtRisk          []AtRiskStudent   `json:"at_risk"`
+}
+
+// RetentionCohort is the students whose first subscription started in
+// Month. Retention[i] is the percentage of them subscribed at some point
+// of the i-th month after it, up to the current month.
+type RetentionCohort struct {
+	Month     string    `json:"month"`
+	Students  int       `json:"students"`
+	Retention []float64 `json:"retention"`
+}
+
+// GroupChurn counts the students whose subscription to the group ended
+// and those of them who did not renew it
+type GroupChurn struct {
+	GroupID    uuid.UUID `json:"group_id"`
+	GroupTitle string    `json:"group_title"`
+	Ended      int       `json:"ended"`
+	Churned    int       `json:"churned"`
+	ChurnRate  float64   `json:"churn_rate"`
+}
+
+// CoachChurn is GroupChurn over all groups of a coach: a student moving to
+// another group of the coach is not churned
+type CoachChurn struct {
+	CoachUserID uuid.UUID `json:"coach_user_id"`
+	CoachName   string    `json:"coach_name"`
+	Ended       int       `json:"ended"`
+	Churned     int       `json:"churned"`
+	ChurnRate   float64   `json:"churn_rate"`
+}
+
+// AtRiskStudent is a subscribed student whose visits dropped
+type AtRiskStudent struct {
+	StudentID      uuid.UUID `db:"student_id" json:"student_id"`
+	StudentName    string    `db:"student_name" json:"student_name"`
+	ParentPhone    string    `db:"parent_phone" json:"parent_phone,omitempty"`
+	ParentEmail    string    `db:"parent_email" json:"parent_email,omitempty"`
+	GroupTitle     string    `db:"group_title" json:"group_title"`
+	PreviousVisits int       `db:"previous_visits" json:"previous_visits"`
+	RecentVisits   int       `db:"recent_visits" json:"recent_visits"`
+	LastVisitAt    time.Time `db:"last_visit_at" json:"last_visit_at"`
+}
+
+// churnCount accumulates the students whose subscriptions ended, keeping
+// whether each churned
+type churnCount map[uuid.UUID]bool
+
+func (c churnCount) add(studentID uuid.UUID, churned bool) {
+	c[studentID] = c[studentID] || churned
+}
+
+func (c churnCount) totals() (ended, churned int, rate float64) {
+	for _, ch := range c {
+		if ch {
+			churned++
+		}
+	}
+	ended = len(c)
+	if ended > 0 {
+		rate = float64(churned) / float64(ended) * 100
+	}
+	return ended, churned, rate
+}
+
+// GetRetentionReport builds cohorts of the students whose first
+// subscription started between from and to, by month in from's location
+// (the club's time zone). Churn covers the subscriptions that ended between
+// from and to: a student churns unless they renew within renewalDays, in
+// the same group, among the coach's groups or anywhere in the club for the
+// totals. Subscriptions ended too recently to tell are left out.
+func (r *ReportRepository) GetRetentionReport(ctx context.Context, clubID uuid.UUID, from, to time.Time, renewalDays int) (*RetentionReport, error) {
+	report := &RetentionReport{
+		RenewalDays: renewalDays,
+		Cohorts:     []RetentionCohort{},
+		Groups:      []GroupChurn{},
+		Coaches:     []CoachChurn{},
+		AtRisk:      []AtRiskStudent{},
+	}
+
+	cohortsQuery := `
+		WITH ` + subscriptionSpells + `,
+		cohorts AS (
+			SELECT student_id, date_trunc('month', MIN(started_at) AT TIME ZONE $4) as cohort
+			FROM spells
+			GROUP BY student_id
+			HAVING MIN(started_at) BETWEEN $2 AND $3
+		),
+		months AS (
+			SELECT
+				c.student_id,
+				c.cohort,
+				m.month_start,
+				EXISTS (
+					SELECT 1 FROM spells sp
+					WHERE sp.student_id = c.student_id
+					  AND sp.started_at < (m.month_start + interval '1 month') AT TIME ZONE $4
+					  AND (sp.ended_at IS NULL OR sp.ended_at >= m.month_start AT TIME ZONE $4)
+				) as active
+			FROM cohorts c
+			CROSS JOIN generate_series(c.cohort, date_trunc('month', now() AT TIME ZONE $4), interval '1 month') as m(month_start)
+		)
+		SELECT
+			TO_CHAR(cohort, 'YYYY-MM') as month,
+			((EXTRACT(YEAR FROM month_start) - EXTRACT(YEAR FROM cohort)) * 12
+				+ EXTRACT(MONTH FROM month_start) - EXTRACT(MONTH FROM cohort))::int as month_offset,
+			COUNT(*) as students,
+			COUNT(*) FILTER (WHERE active) as active
+		FROM months
+		GROUP BY 1, 2
+		ORDER BY 1, 2`
+
+	var cohorts []struct {
+		Month       string `db:"month"`
+		MonthOffset int    `db:"month_offset"`
+		Students    int    `db:"students"`
+		Active      int    `db:"active"`
+	}
+	if err := r.db.SelectContext(ctx, &cohorts, cohortsQuery, clubID, from, to, from.Location().String()); err != nil {
+		return nil, err
+	}
+	for _, c := range cohorts {
+		n := len(report.Cohorts)
+		if n == 0 || report.Cohorts[n-1].Month != c.Month {
+			report.Cohorts = append(report.Cohorts, RetentionCohort{Month: c.Month, Students: c.Students})
+			n++
+		}
+		cohort := &report.Cohorts[n-1]
+		cohort.Retention = append(cohort.Retention, float64(c.Active)/float64(c.Students)*100)
+	}
+
+	churnQuery := `
+		WITH ` + subscriptionSpells + `
+		SELECT
+			sp.student_id,
+			sp.group_id,
+			g.title as group_title,
+			g.coach_user_id,
+			COALESCE(NULLIF(u.name, ''), u.email, '') as coach_name,
+			` + renewed("n.group_id = sp.group_id", "$4") + ` as renewed_group,
+			` + renewed("n.coach_user_id = sp.coach_user_id", "$4") + ` as renewed_coach,
+			` + renewed("TRUE", "$4") + ` as renewed_club
+		FROM spells sp
+		JOIN groups g ON g.id = sp.group_id
+		LEFT JOIN users u ON u.id = g.coach_user_id
+		WHERE sp.ended_at BETWEEN $2 AND $3
+		  AND sp.ended_at + make_interval(days => $4) <= now()
+		ORDER BY g.title, g.id`
+
+	var ended []struct {
+		StudentID    uuid.UUID  `db:"student_id"`
+		GroupID      uuid.UUID  `db:"group_id"`
+		GroupTitle   string     `db:"group_title"`
+		CoachUserID  *uuid.UUID `db:"coach_user_id"`
+		CoachName    string     `db:"coach_name"`
+		RenewedGroup bool       `db:"renewed_group"`
+		RenewedCoach bool       `db:"renewed_coach"`
+		RenewedClub  bool       `db:"renewed_club"`
+	}
+	if err := r.db.SelectContext(ctx, &ended, churnQuery, clubID, from, to, renewalDays); err != nil {
+		return nil, err
+	}
+
+	club := churnCount{}
+	var groupIDs, coachIDs []uuid.UUID
+	groups, coaches := map[uuid.UUID]churnCount{}, map[uuid.UUID]churnCount{}
+	groupTitles, coachNames := map[uuid.UUID]string{}, map[uuid.UUID]string{}
+	for _, e := range ended {
+		club.add(e.StudentID, !e.RenewedClub)
+
+		if groups[e.GroupID] == nil {
+			groups[e.GroupID] = churnCount{}
+			groupIDs = append(groupIDs, e.GroupID)
+			groupTitles[e.GroupID] = e.GroupTitle
+		}
+		groups[e.GroupID].add(e.StudentID, !e.RenewedGroup)
+
+		if e.CoachUserID == nil {
+			continue
+		}
+		if coaches[*e.CoachUserID] == nil {
+			coaches[*e.CoachUserID] = churnCount{}
+			coachIDs = append(coachIDs, *e.CoachUserID)
+			coachNames[*e.CoachUserID] = e.CoachName
+		}
+		coaches[*e.CoachUserID].add(e.StudentID, !e.RenewedCoach)
+	}
+
+	report.EndedStudents, report.ChurnedStudents, report.ChurnRate = club.totals()
+	for _, id := range groupIDs {
+		g := GroupChurn{GroupID: id, GroupTitle: groupTitles[id]}
+		g.Ended, g.Churned, g.ChurnRate = groups[id].totals()
+		report.Groups = append(report.Groups, g)
+	}
+	for _, id := range coachIDs {
+		c := CoachChurn{CoachUserID: id, CoachName: coachNames[id]}
+		c.Ended, c.Churned, c.ChurnRate = coaches[id].totals()
+		report.Coaches = append(report.Coaches, c)
+	}
+	sort.SliceStable(report.Coaches, func(i, j int) bool {
+		return report.Coaches[i].CoachName < report.Coaches[j].CoachName
+	})
+
+	atRiskQuery := `
+		WITH ` + subscriptionSpells + `,
+		current AS (
+			SELECT sp.student_id, string_agg(DISTINCT g.title, ', ') as group_title
+			FROM spells sp
+			JOIN groups g ON g.id = sp.group_id
+			WHERE g.archived_at IS NULL
+			  AND sp.started_at <= now()
+			  AND (sp.ended_at IS NULL OR sp.ended_at >= now())
+			GROUP BY sp.student_id
+		),
+		visits AS (
+			SELECT
+				a.student_id,
+				COUNT(*) FILTER (WHERE se.start_at < now() - make_interval(weeks => $2)) as previous_visits,
+				COUNT(*) FILTER (WHERE se.start_at >= now() - make_interval(weeks => $2)) as recent_visits,
+				MAX(se.start_at) as last_visit_at
+			FROM attendances a
+			JOIN sessions se ON se.id = a.session_id
+			JOIN groups g ON g.id = se.group_id
+			WHERE g.club_id = $1
+			  AND a.status = 'present'
+			  AND se.start_at BETWEEN now() - make_interval(weeks => $2) * 2 AND now()
+			GROUP BY a.student_id
+		)
+		SELECT
+			c.student_id,
+			st.name as student_name,
+			COALESCE(st.parent_contact->>'phone', '') as parent_phone,
+			COALESCE(st.parent_contact->>'email', '') as parent_email,
+			c.group_title,
+			v.previous_visits,
+			v.recent_visits,
+			v.last_visit_at
+		FROM current c
+		JOIN students st ON st.id = c.student_id
+		JOIN visits v ON v.student_id = c.student_id
+		WHERE st.archived_at IS NULL
+		  AND v.previous_visits >= $3
+		  AND v.recent_visits * 2 <= v.previous_visits
+		ORDER BY v.previous_visits - v.recent_visits DESC, st.name`
+
+	if err := r.db.SelectContext(ctx, &report.AtRisk, atRiskQuery, clubID, atRiskWeeks, atRiskMinVisits); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// RevenueReport sets the cash a club received against the revenue it
+// earned. Deferred revenue is the cash received for sessions not yet
+// recognised: what the club owes its students.
+type RevenueReport struct {
+	OpeningDeferred float64        `json:"opening_deferred"`
+	CashReceived    float64        `json:"cash_received"`
+	Recognised      float64        `json:"recognised"`
+	ClosingDeferred float64        `json:"closing_deferred"`
+	Months          []RevenueMonth `json:"months"`
+	Groups          []GroupRevenue `json:"groups"`
+}
+
+// RevenueMonth is a month of a club or group; Deferred is the balance at
+// its end
+type RevenueMonth struct {
+	Month        string  `json:"month"`
+	CashReceived float64 `json:"cash_received"`
+	Recognised   float64 `json:"recognised"`
+	Deferred     float64 `json:"deferred"`
+}
+
+type GroupRevenue struct {
+	GroupID         uuid.UUID      `json:"group_id"`
+	GroupTitle      string         `json:"group_title"`
+	OpeningDeferred float64        `json:"opening_deferred"`
+	CashReceived    float64        `json:"cash_received"`
+	Recognised      float64        `json:"recognised"`
+	ClosingDeferred float64        `json:"closing_deferred"`
+	Months          []RevenueMonth `json:"months"`
+}
+
+// revenueTotals adds up months into a report line, starting from the
+// balance deferred before them
+type revenueTotals struct {
+	opening, cash, recognised float64
+	months                    map[string]*RevenueMonth
+}
+
+func (t *revenueTotals) add(month string, cash, recognised float64) {
+	if month == "" {
+		t.opening += cash - recognised
+		return
+	}
+	m := t.months[month]
+	m.CashReceived += cash
+	m.Recognised += recognised
+	t.cash += cash
+	t.recognised += recognised
+}
+
+// list returns the months in order with their closing balances
+func (t *revenueTotals) list(months []string) []RevenueMonth {
+	list := make([]RevenueMonth, len(months))
+	deferred := t.opening
+	for i, month := range months {
+		m := t.months[month]
+		deferred += m.CashReceived - m.Recognised
+		list[i] = RevenueMonth{
+			Month:        month,
+			CashReceived: roundMoney(m.CashReceived),
+			Recognised:   roundMoney(m.Recognised),
+			Deferred:     roundMoney(deferred),
+		}
+	}
+	return list
+}
+
+func newRevenueTotals(months []string) *revenueTotals {
+	t := &revenueTotals{months: make(map[string]*RevenueMonth, len(months))}
+	for _, month := range months {
+		t.months[month] = &RevenueMonth{Month: month}
+	}
+	return t
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// GetRevenueReport covers the months from from to to, in from's location
+// (the club's time zone). Cash is the succeeded payments by the time they
+// were paid; refunded ones are left out. A subscription's paid amount is
+// recognised in equal parts per session charged to it, and what its unused
+// sessions are worth once it expires or, for imported ones, once it is
+// used up. Nothing is recognised before the subscription is first paid.
+func (r *ReportRepository) GetRevenueReport(ctx context.Context, clubID uuid.UUID, from, to time.Time) (*RevenueReport, error) {
+	report := &RevenueReport{Months: []RevenueMonth{}, Groups: []GroupRevenue{}}
+
+	query := `
+		WITH paid AS (
+			SELECT
+				s.id,
+				s.group_id,
+				s.total_sessions,
+				s.status,
+				s.expires_at,
+				SUM(p.amount) as amount,
+				SUM(p.amount) / NULLIF(s.total_sessions, 0) as per_session,
+				MIN(p.paid_at) as paid_at
+			FROM subscriptions s
+			JOIN groups g ON g.id = s.group_id
+			JOIN payments p ON p.subscription_id = s.id
+			WHERE g.club_id = $1
+			  AND p.status = 'succeeded'
+			  AND p.paid_at IS NOT NULL
+			GROUP BY s.id
+		),
+		charged AS (
+			SELECT
+				a.subscription_id,
+				se.start_at,
+				ROW_NUMBER() OVER (PARTITION BY a.subscription_id ORDER BY se.start_at, a.id) as n
+			FROM attendances a
+			JOIN sessions se ON se.id = a.session_id
+			WHERE a.subscription_id IN (SELECT id FROM paid)
+			  AND se.start_at <= now()
+		),
+		events AS (
+			SELECT p.group_id, pay.paid_at as at, pay.amount as cash, 0 as recognised
+			FROM payments pay
+			JOIN paid p ON p.id = pay.subscription_id
+			WHERE pay.status = 'succeeded'
+			  AND pay.paid_at IS NOT NULL
+
+			UNION ALL
+
+			SELECT p.group_id, GREATEST(c.start_at, p.paid_at), 0, p.per_session
+			FROM charged c
+			JOIN paid p ON p.id = c.subscription_id
+			WHERE c.n <= p.total_sessions
+
+			UNION ALL
+
+			SELECT p.group_id, GREATEST(u.ended_at, p.paid_at), 0,
+				p.amount - COALESCE(p.per_session * LEAST(u.sessions, p.total_sessions), 0)
+			FROM paid p
+			CROSS JOIN LATERAL (
+				SELECT
+					COUNT(c.n) as sessions,
+					CASE
+						WHEN p.expires_at <= now() THEN p.expires_at
+						WHEN p.status = 'used' THEN COALESCE(MAX(c.start_at), p.paid_at)
+					END as ended_at
+				FROM charged c
+				WHERE c.subscription_id = p.id
+			) u
+			WHERE u.ended_at IS NOT NULL
+		)
+		SELECT
+			e.group_id,
+			g.title as group_title,
+			CASE WHEN e.at < $2 THEN '' ELSE TO_CHAR(e.at AT TIME ZONE $4, 'YYYY-MM') END as month,
+			SUM(e.cash) as cash,
+			SUM(e.recognised) as recognised
+		FROM events e
+		JOIN groups g ON g.id = e.group_id
+		WHERE e.at <= $3
+		GROUP BY 1, 2, 3
+		ORDER BY g.title, e.group_id, 3`
+
+	var rows []struct {
+		GroupID    uuid.UUID `db:"group_id"`
+		GroupTitle string    `db:"group_title"`
+		Month      string    `db:"month"`
+		Cash       float64   `db:"cash"`
+		Recognised float64   `db:"recognised"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, clubID, from, to, from.Location().String()); err != nil {
+		return nil, err
+	}
+
+	var months []string
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format("2006-01"))
+	}
+
+	club := newRevenueTotals(months)
+	var groups []*revenueTotals
+	for i, row := range rows {
+		if i == 0 || rows[i-1].GroupID != row.GroupID {
+			report.Groups = append(report.Groups, GroupRevenue{GroupID: row.GroupID, GroupTitle: row.GroupTitle})
+			groups = append(groups, newRevenueTotals(months))
+		}
+		groups[len(groups)-1].add(row.Month, row.Cash, row.Recognised)
+		club.add(row.Month, row.Cash, row.Recognised)
+	}
+
+	for i, t := range groups {
+		g := &report.Groups[i]
+		g.Months = t.list(months)
+		g.OpeningDeferred = roundMoney(t.opening)
+		g.CashReceived = roundMoney(t.cash)
+		g.Recognised = roundMoney(t.recognised)
+		g.ClosingDeferred = roundMoney(t.opening + t.cash - t.recognised)
+	}
+	report.Months = club.list(months)
+	report.OpeningDeferred = roundMoney(club.opening)
+	report.CashReceived = roundMoney(club.cash)
+	report.Recognised = roundMoney(club.recognised)
+	report.ClosingDeferred = roundMoney(club.opening + club.cash - club.recognised)
+
+	return report, nil
+}
+
 // DashboardStats for quick overview
 type DashboardStats struct {
 	TotalStudents       int     `json:"total_students"`
@@ -426,9 +1272,15 @@ type DashboardStats struct {
 	PendingPayments     int     `json:"pending_payments"`
 }
 
-func (r *ReportRepository) GetDashboardStats(ctx context.Context, clubID uuid.UUID) (*DashboardStats, error) {
+// GetDashboardStats evaluates "today" and "this month" in loc
+func (r *ReportRepository) GetDashboardStats(ctx context.Context, clubID uuid.UUID, loc *time.Location) (*DashboardStats, error) {
 	stats := &DashboardStats{}
 
+	now := time.Now().In(loc)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	startOfTomorrow := startOfDay.AddDate(0, 0, 1)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
 	// Total students
 	r.db.GetContext(ctx, &stats.TotalStudents, 
 		`SELECT COUNT(*) FROM students WHERE club_id = $1`, clubID)
@@ -443,17 +1295,17 @@ func (r *ReportRepository) GetDashboardStats(ctx context.Context, clubID uuid.UU
 	r.db.GetContext(ctx, &stats.UpcomingSessions,
 		`SELECT COUNT(*) FROM sessions s 
 		 JOIN groups g ON s.group_id = g.id 
-		 WHERE g.club_id = $1 AND s.start_at BETWEEN NOW() AND NOW() + INTERVAL '7 days'`, clubID)
+		 WHERE g.club_id = $1 AND s.status = 'scheduled'
+		   AND s.start_at BETWEEN NOW() AND NOW() + INTERVAL '7 days'`, clubID)
 
 	// Today's sessions
 	r.db.GetContext(ctx, &stats.TodaySessions,
 		`SELECT COUNT(*) FROM sessions s 
 		 JOIN groups g ON s.group_id = g.id 
-		 WHERE g.club_id = $1 AND DATE(s.start_at) = CURRENT_DATE`, clubID)
+		 WHERE g.club_id = $1 AND s.status = 'scheduled' AND s.start_at >= $2 AND s.start_at < $3`,
+		clubID, startOfDay, startOfTomorrow)
 
 	// This month revenue
-	startOfMonth := time.Now().UTC().Truncate(24 * time.Hour)
-	startOfMonth = time.Date(startOfMonth.Year(), startOfMonth.Month(), 1, 0, 0, 0, 0, time.UTC)
 	r.db.GetContext(ctx, &stats.MonthRevenue,
 		`SELECT COALESCE(SUM(p.amount), 0) FROM payments p
 		 JOIN subscriptions s ON p.subscription_id = s.id
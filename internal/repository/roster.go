@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// Where a roster student comes from
+const (
+	RosterSourceSubscription = "subscription"
+	RosterSourceMakeup       = "makeup"
+	RosterSourceTrial        = "trial"
+	RosterSourceDropIn       = "drop_in"
+	RosterSourceWalkIn       = "walk_in"
+)
+
+// Roster warnings
+const (
+	RosterWarnLastSession  = "last_session"
+	RosterWarnExpiresSoon  = "expires_soon"
+	RosterWarnDebt         = "debt"
+	rosterExpiryWarnWindow = 7 * 24 * time.Hour
+)
+
+// RosterEntry is one student expected in or marked for a session
+type RosterEntry struct {
+	StudentID         uuid.UUID  `db:"student_id" json:"student_id"`
+	StudentName       string     `db:"student_name" json:"student_name"`
+	Source            string     `db:"-" json:"source"`
+	Expected          bool       `db:"expected" json:"expected"`
+	AttendanceID      *uuid.UUID `db:"attendance_id" json:"attendance_id,omitempty"`
+	Mark              *string    `db:"mark" json:"mark"`
+	Kind              *string    `db:"kind" json:"-"`
+	MakeupCreditID    *uuid.UUID `db:"makeup_credit_id" json:"makeup_credit_id,omitempty"`
+	SubscriptionID    *uuid.UUID `db:"subscription_id" json:"subscription_id,omitempty"`
+	RemainingSessions *int       `db:"remaining_sessions" json:"remaining_sessions,omitempty"`
+	ExpiresAt         *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	PendingAmount     float64    `db:"pending_amount" json:"pending_amount"`
+	HasDebt           bool       `db:"has_debt" json:"has_debt"`
+	Warnings          []string   `db:"-" json:"warnings"`
+}
+
+// GetRoster lists everyone expected in a session (active subscriptions of
+// the group valid at its start) together with everyone already marked,
+// such as makeups and walk-ins, with their current mark. Archived students
+// are no longer expected but keep the marks they already have.
+func (r *AttendanceRepository) GetRoster(ctx context.Context, session *model.Session) ([]RosterEntry, error) {
+	var entries []RosterEntry
+	query := `
+		WITH expected AS (
+			SELECT DISTINCT ON (sub.student_id) sub.student_id, sub.id AS subscription_id
+			FROM subscriptions sub
+			JOIN students st ON st.id = sub.student_id
+			WHERE sub.group_id = $2
+			  AND st.archived_at IS NULL
+			  AND sub.status = 'active'
+			  AND sub.remaining_sessions > 0
+			  AND (sub.starts_at IS NULL OR sub.starts_at <= $3)
+			  AND (sub.expires_at IS NULL OR sub.expires_at >= $3)
+			ORDER BY sub.student_id, sub.starts_at ASC NULLS LAST
+		),
+		marked AS (
+			SELECT * FROM attendances WHERE session_id = $1
+		),
+		roster AS (
+			SELECT
+				COALESCE(e.student_id, a.student_id) AS student_id,
+				e.student_id IS NOT NULL AS expected,
+				a.id AS attendance_id,
+				a.status AS mark,
+				a.kind,
+				a.makeup_credit_id,
+				CASE WHEN a.id IS NOT NULL THEN a.subscription_id ELSE e.subscription_id END AS subscription_id
+			FROM expected e
+			FULL OUTER JOIN marked a ON a.student_id = e.student_id
+		)
+		SELECT
+			r.student_id,
+			st.name AS student_name,
+			r.expected,
+			r.attendance_id,
+			r.mark,
+			r.kind,
+			r.makeup_credit_id,
+			r.subscription_id,
+			sub.remaining_sessions,
+			sub.expires_at,
+			COALESCE(debt.amount, 0) AS pending_amount,
+			debt.amount IS NOT NULL AS has_debt
+		FROM roster r
+		JOIN students st ON st.id = r.student_id
+		LEFT JOIN subscriptions sub ON sub.id = r.subscription_id
+		LEFT JOIN LATERAL (
+			SELECT SUM(p.price)::float AS amount FROM subscriptions p
+			WHERE p.student_id = r.student_id AND p.status = 'pending'
+		) debt ON true
+		ORDER BY st.name`
+
+	if err := r.db.SelectContext(ctx, &entries, query, session.ID, session.GroupID, session.StartAt); err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].annotate(session.StartAt)
+	}
+	return entries, nil
+}
+
+func (e *RosterEntry) annotate(startAt time.Time) {
+	switch {
+	case e.Kind != nil && *e.Kind == string(model.AttendanceTrial):
+		e.Source = RosterSourceTrial
+	case e.Kind != nil && *e.Kind == string(model.AttendanceDropIn):
+		e.Source = RosterSourceDropIn
+	case e.MakeupCreditID != nil:
+		e.Source = RosterSourceMakeup
+	case e.SubscriptionID != nil:
+		e.Source = RosterSourceSubscription
+	default:
+		e.Source = RosterSourceWalkIn
+	}
+
+	e.Warnings = []string{}
+	if e.Expected && e.RemainingSessions != nil && *e.RemainingSessions <= 1 {
+		e.Warnings = append(e.Warnings, RosterWarnLastSession)
+	}
+	if e.Expected && e.ExpiresAt != nil && e.ExpiresAt.Sub(startAt) <= rosterExpiryWarnWindow {
+		e.Warnings = append(e.Warnings, RosterWarnExpiresSoon)
+	}
+	if e.HasDebt {
+		e.Warnings = append(e.Warnings, RosterWarnDebt)
+	}
+}
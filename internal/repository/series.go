@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type SeriesRepository struct {
+	db *sqlx.DB
+}
+
+func NewSeriesRepository(db *sqlx.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+// CreateInTx creates a series within a transaction
+func (r *SeriesRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, series *model.ScheduleSeries) error {
+	query := `
+		INSERT INTO schedule_series (group_id, rrule, starts_on, ends_on, start_time, duration_minutes, location, location_id, exdates)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		series.GroupID,
+		series.RRule,
+		series.StartsOn,
+		series.EndsOn,
+		series.StartTime,
+		series.DurationMinutes,
+		series.Location,
+		series.LocationID,
+		pq.StringArray(nonNilStrings(series.ExDates)),
+	).Scan(&series.ID, &series.CreatedAt)
+}
+
+func (r *SeriesRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ScheduleSeries, error) {
+	var series seriesDB
+	query := `SELECT * FROM schedule_series WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &series, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return series.toModel(), nil
+}
+
+func (r *SeriesRepository) GetByGroup(ctx context.Context, groupID uuid.UUID) ([]model.ScheduleSeries, error) {
+	var rows []seriesDB
+	query := `SELECT * FROM schedule_series WHERE group_id = $1 ORDER BY starts_on`
+
+	if err := r.db.SelectContext(ctx, &rows, query, groupID); err != nil {
+		return nil, err
+	}
+	return seriesToModels(rows), nil
+}
+
+// GetDueForMaterialization returns open-ended or unfinished series whose
+// materialised horizon is behind the given date
+func (r *SeriesRepository) GetDueForMaterialization(ctx context.Context, horizon time.Time) ([]model.ScheduleSeries, error) {
+	var rows []seriesDB
+	query := `
+		SELECT * FROM schedule_series
+		WHERE (materialized_until IS NULL OR materialized_until < $1)
+		  AND (ends_on IS NULL OR materialized_until IS NULL OR materialized_until < ends_on)
+		ORDER BY created_at`
+
+	if err := r.db.SelectContext(ctx, &rows, query, horizon); err != nil {
+		return nil, err
+	}
+	return seriesToModels(rows), nil
+}
+
+// UpdateInTx updates series fields within a transaction
+func (r *SeriesRepository) UpdateInTx(ctx context.Context, tx *sqlx.Tx, series *model.ScheduleSeries) error {
+	query := `
+		UPDATE schedule_series
+		SET rrule = $2, starts_on = $3, ends_on = $4, start_time = $5,
+		    duration_minutes = $6, location = $7, location_id = $8, exdates = $9, materialized_until = $10
+		WHERE id = $1`
+
+	result, err := tx.ExecContext(ctx, query,
+		series.ID,
+		series.RRule,
+		series.StartsOn,
+		series.EndsOn,
+		series.StartTime,
+		series.DurationMinutes,
+		series.Location,
+		series.LocationID,
+		pq.StringArray(nonNilStrings(series.ExDates)),
+		series.MaterializedUntil,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetMaterializedUntilInTx records how far sessions have been generated
+func (r *SeriesRepository) SetMaterializedUntilInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, until time.Time) error {
+	query := `UPDATE schedule_series SET materialized_until = $2 WHERE id = $1`
+	_, err := tx.ExecContext(ctx, query, id, until)
+	return err
+}
+
+// AddExDateInTx excludes a single occurrence date from the series
+func (r *SeriesRepository) AddExDateInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, date time.Time) error {
+	query := `
+		UPDATE schedule_series
+		SET exdates = array_append(exdates, $2::date)
+		WHERE id = $1 AND NOT ($2::date = ANY(exdates))`
+	_, err := tx.ExecContext(ctx, query, id, date.Format("2006-01-02"))
+	return err
+}
+
+// DeleteInTx deletes a series within a transaction. Its remaining sessions
+// are detached (series_id is set to NULL by the foreign key).
+func (r *SeriesRepository) DeleteInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	query := `DELETE FROM schedule_series WHERE id = $1`
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// BeginTx starts a new transaction
+func (r *SeriesRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Helper struct for DB scanning with DATE[]
+type seriesDB struct {
+	model.ScheduleSeries
+	ExDatesRaw pq.StringArray `db:"exdates"`
+}
+
+func (s *seriesDB) toModel() *model.ScheduleSeries {
+	s.ScheduleSeries.ExDates = make([]string, len(s.ExDatesRaw))
+	for i, d := range s.ExDatesRaw {
+		// DATE values come back as "2006-01-02" or full timestamps depending on the driver
+		if len(d) > 10 {
+			d = d[:10]
+		}
+		s.ScheduleSeries.ExDates[i] = d
+	}
+	return &s.ScheduleSeries
+}
+
+func seriesToModels(rows []seriesDB) []model.ScheduleSeries {
+	result := make([]model.ScheduleSeries, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
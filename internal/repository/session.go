@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/neo/trainer-plus/internal/model"
 )
 
@@ -21,27 +22,50 @@ func NewSessionRepository(db *sqlx.DB) *SessionRepository {
 
 func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
 	query := `
-		INSERT INTO sessions (group_id, start_at, duration_minutes, location)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at`
+		INSERT INTO sessions (group_id, start_at, duration_minutes, location, location_id, series_id, occurrence_date, is_exception)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, status, created_at`
 
 	return r.db.QueryRowxContext(ctx, query,
 		session.GroupID,
 		session.StartAt,
 		session.DurationMinutes,
 		session.Location,
-	).Scan(&session.ID, &session.CreatedAt)
+		session.LocationID,
+		session.SeriesID,
+		session.OccurrenceDate,
+		session.IsException,
+	).Scan(&session.ID, &session.Status, &session.CreatedAt)
 }
 
 func (r *SessionRepository) CreateBatch(ctx context.Context, sessions []model.Session) error {
 	query := `
-		INSERT INTO sessions (group_id, start_at, duration_minutes, location)
-		VALUES (:group_id, :start_at, :duration_minutes, :location)`
+		INSERT INTO sessions (group_id, start_at, duration_minutes, location, location_id, series_id, occurrence_date, is_exception)
+		VALUES (:group_id, :start_at, :duration_minutes, :location, :location_id, :series_id, :occurrence_date, :is_exception)`
 
 	_, err := r.db.NamedExecContext(ctx, query, sessions)
 	return err
 }
 
+// InsertOccurrencesInTx inserts materialised series sessions, skipping
+// occurrences that already exist (including edited or attended ones)
+func (r *SessionRepository) InsertOccurrencesInTx(ctx context.Context, tx *sqlx.Tx, sessions []model.Session) (int64, error) {
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO sessions (group_id, start_at, duration_minutes, location, location_id, series_id, occurrence_date, is_exception)
+		VALUES (:group_id, :start_at, :duration_minutes, :location, :location_id, :series_id, :occurrence_date, :is_exception)
+		ON CONFLICT (series_id, occurrence_date) WHERE series_id IS NOT NULL DO NOTHING`
+
+	result, err := tx.NamedExecContext(ctx, query, sessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *SessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Session, error) {
 	var session model.Session
 	query := `SELECT * FROM sessions WHERE id = $1`
@@ -68,7 +92,7 @@ func (r *SessionRepository) GetUpcoming(ctx context.Context, groupID uuid.UUID,
 	var sessions []model.Session
 	query := `
 		SELECT * FROM sessions 
-		WHERE group_id = $1 AND start_at > $2 
+		WHERE group_id = $1 AND start_at > $2 AND status = 'scheduled'
 		ORDER BY start_at
 		LIMIT $3`
 
@@ -76,6 +100,25 @@ func (r *SessionRepository) GetUpcoming(ctx context.Context, groupID uuid.UUID,
 	return sessions, err
 }
 
+// GetCheckInCandidates returns the scheduled sessions of a club running at
+// the given time, or starting within early after it. With a location only
+// sessions held there (directly or through the group's default) are returned.
+func (r *SessionRepository) GetCheckInCandidates(ctx context.Context, clubID uuid.UUID, locationID *uuid.UUID, at time.Time, early time.Duration) ([]model.Session, error) {
+	var sessions []model.Session
+	query := `
+		SELECT s.* FROM sessions s
+		JOIN groups g ON s.group_id = g.id
+		WHERE g.club_id = $1
+		  AND s.status = 'scheduled'
+		  AND ($2::uuid IS NULL OR s.location_id = $2 OR (s.location_id IS NULL AND g.location_id = $2))
+		  AND s.start_at <= $3::timestamptz + make_interval(secs => $4)
+		  AND s.start_at + make_interval(mins => s.duration_minutes) >= $3
+		ORDER BY s.start_at`
+
+	err := r.db.SelectContext(ctx, &sessions, query, clubID, locationID, at, early.Seconds())
+	return sessions, err
+}
+
 func (r *SessionRepository) GetByClubDateRange(ctx context.Context, clubID uuid.UUID, from, to time.Time) ([]model.Session, error) {
 	var sessions []model.Session
 	query := `
@@ -91,7 +134,8 @@ func (r *SessionRepository) GetByClubDateRange(ctx context.Context, clubID uuid.
 func (r *SessionRepository) Update(ctx context.Context, session *model.Session) error {
 	query := `
 		UPDATE sessions 
-		SET start_at = $2, duration_minutes = $3, location = $4
+		SET start_at = $2, duration_minutes = $3, location = $4, location_id = $5,
+		    is_exception = $6, rescheduled_from = $7
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -99,6 +143,9 @@ func (r *SessionRepository) Update(ctx context.Context, session *model.Session)
 		session.StartAt,
 		session.DurationMinutes,
 		session.Location,
+		session.LocationID,
+		session.IsException,
+		session.RescheduledFrom,
 	)
 	if err != nil {
 		return err
@@ -125,6 +172,28 @@ func (r *SessionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// CancelInTx marks a scheduled session as cancelled, keeping the row and its
+// attendance for history. Series occurrences become exceptions so that
+// regenerating the series does not bring them back.
+func (r *SessionRepository) CancelInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE sessions
+		SET status = 'cancelled', cancel_reason = NULLIF($2, ''), cancelled_at = now(),
+		    is_exception = (series_id IS NOT NULL)
+		WHERE id = $1 AND status = 'scheduled'`
+
+	result, err := tx.ExecContext(ctx, query, id, reason)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (r *SessionRepository) DeleteFutureSessions(ctx context.Context, groupID uuid.UUID, after time.Time) (int64, error) {
 	query := `DELETE FROM sessions WHERE group_id = $1 AND start_at > $2`
 	result, err := r.db.ExecContext(ctx, query, groupID, after)
@@ -133,3 +202,140 @@ func (r *SessionRepository) DeleteFutureSessions(ctx context.Context, groupID uu
 	}
 	return result.RowsAffected()
 }
+
+// HasAttendanceInTx locks a session and reports whether anyone is marked
+// for it. No attendance can be added until the transaction ends.
+// Must be called within a transaction
+func (r *SessionRepository) HasAttendanceInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (bool, error) {
+	var locked uuid.UUID
+	err := tx.GetContext(ctx, &locked, `SELECT id FROM sessions WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM attendances WHERE session_id = $1)`, id)
+	return exists, err
+}
+
+// DeleteInTx deletes a session within a transaction
+func (r *SessionRepository) DeleteInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteStaleOccurrencesInTx removes generated sessions of a series from the
+// given occurrence date on whose date is not in keep. Edited occurrences and
+// sessions that already have attendance are never removed.
+func (r *SessionRepository) DeleteStaleOccurrencesInTx(ctx context.Context, tx *sqlx.Tx, seriesID uuid.UUID, from time.Time, keep []string) (int64, error) {
+	query := `
+		DELETE FROM sessions s
+		WHERE s.series_id = $1
+		  AND s.occurrence_date >= $2
+		  AND NOT (s.occurrence_date = ANY($3::date[]))
+		  AND NOT s.is_exception
+		  AND NOT EXISTS (SELECT 1 FROM attendances a WHERE a.session_id = s.id)`
+
+	result, err := tx.ExecContext(ctx, query, seriesID, from, pq.StringArray(keep))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RescheduleOccurrencesInTx moves generated sessions of a series from the
+// given occurrence date on to a new local start time, duration and location.
+// The wall-clock time is resolved in tz by Postgres, so it is DST-safe.
+// Edited occurrences and sessions with attendance are left untouched.
+func (r *SessionRepository) RescheduleOccurrencesInTx(ctx context.Context, tx *sqlx.Tx, seriesID uuid.UUID, from time.Time, startTime string, durationMinutes int, location string, locationID *uuid.UUID, tz string) (int64, error) {
+	query := `
+		UPDATE sessions s
+		SET start_at = (s.occurrence_date + $3::time) AT TIME ZONE $4,
+		    duration_minutes = $5,
+		    location = $6,
+		    location_id = $7
+		WHERE s.series_id = $1
+		  AND s.occurrence_date >= $2
+		  AND NOT s.is_exception
+		  AND NOT EXISTS (SELECT 1 FROM attendances a WHERE a.session_id = s.id)`
+
+	result, err := tx.ExecContext(ctx, query, seriesID, from, startTime, tz, durationMinutes, location, locationID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReassignSeriesInTx moves the remaining occurrences of a series from the
+// given date on to another series (used when splitting a series)
+func (r *SessionRepository) ReassignSeriesInTx(ctx context.Context, tx *sqlx.Tx, fromSeriesID, toSeriesID uuid.UUID, from time.Time) error {
+	query := `UPDATE sessions SET series_id = $2 WHERE series_id = $1 AND occurrence_date >= $3`
+	_, err := tx.ExecContext(ctx, query, fromSeriesID, toSeriesID, from)
+	return err
+}
+
+// SessionSlot is a scheduled session with the group data needed to detect
+// coach and location double-booking
+type SessionSlot struct {
+	SessionID       uuid.UUID  `db:"session_id"`
+	GroupID         uuid.UUID  `db:"group_id"`
+	ClubID          uuid.UUID  `db:"club_id"`
+	GroupTitle      string     `db:"group_title"`
+	CoachUserID     *uuid.UUID `db:"coach_user_id"`
+	Location        string     `db:"location"`
+	LocationID      *uuid.UUID `db:"location_id"`
+	SeriesID        *uuid.UUID `db:"series_id"`
+	StartAt         time.Time  `db:"start_at"`
+	DurationMinutes int        `db:"duration_minutes"`
+}
+
+// GetSlots returns scheduled sessions overlapping [from, to) that belong to
+// the club or are coached by one of the given coaches (who may also work
+// for other clubs). Cancelled sessions are ignored. Sessions without a
+// location get their group's default.
+func (r *SessionRepository) GetSlots(ctx context.Context, clubID uuid.UUID, coachIDs []uuid.UUID, from, to time.Time) ([]SessionSlot, error) {
+	coaches := make([]string, len(coachIDs))
+	for i, id := range coachIDs {
+		coaches[i] = id.String()
+	}
+
+	var slots []SessionSlot
+	query := `
+		SELECT
+			s.id as session_id,
+			s.group_id,
+			g.club_id,
+			g.title as group_title,
+			g.coach_user_id,
+			COALESCE(s.location, '') as location,
+			COALESCE(s.location_id, g.location_id) as location_id,
+			s.series_id,
+			s.start_at,
+			s.duration_minutes
+		FROM sessions s
+		JOIN groups g ON s.group_id = g.id
+		WHERE (g.club_id = $1 OR g.coach_user_id = ANY($2::uuid[]))
+		  AND s.status = 'scheduled'
+		  AND s.start_at < $4
+		  AND s.start_at + s.duration_minutes * INTERVAL '1 minute' > $3
+		ORDER BY s.start_at`
+
+	err := r.db.SelectContext(ctx, &slots, query, clubID, pq.StringArray(coaches), from, to)
+	return slots, err
+}
+
+// BeginTx starts a new transaction
+func (r *SessionRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
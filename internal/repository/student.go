@@ -3,11 +3,18 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/neo/trainer-plus/internal/importer"
 	"github.com/neo/trainer-plus/internal/model"
 )
 
@@ -23,8 +30,8 @@ func (r *StudentRepository) Create(ctx context.Context, student *model.Student)
 	parentContactJSON, _ := json.Marshal(student.ParentContact)
 
 	query := `
-		INSERT INTO students (club_id, name, birth_date, parent_contact, notes)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO students (club_id, name, birth_date, parent_contact, notes, tags, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at`
 
 	return r.db.QueryRowxContext(ctx, query,
@@ -33,6 +40,29 @@ func (r *StudentRepository) Create(ctx context.Context, student *model.Student)
 		student.BirthDate,
 		parentContactJSON,
 		student.Notes,
+		pq.StringArray(nonNilStrings(student.Tags)),
+		customValuesJSON(student.CustomFields),
+	).Scan(&student.ID, &student.CreatedAt)
+}
+
+// CreateInTx creates a student within a transaction
+// Must be called within a transaction
+func (r *StudentRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, student *model.Student) error {
+	parentContactJSON, _ := json.Marshal(student.ParentContact)
+
+	query := `
+		INSERT INTO students (club_id, name, birth_date, parent_contact, notes, tags, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		student.ClubID,
+		student.Name,
+		student.BirthDate,
+		parentContactJSON,
+		student.Notes,
+		pq.StringArray(nonNilStrings(student.Tags)),
+		customValuesJSON(student.CustomFields),
 	).Scan(&student.ID, &student.CreatedAt)
 }
 
@@ -50,15 +80,16 @@ func (r *StudentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.S
 	return student.toModel(), nil
 }
 
-func (r *StudentRepository) GetByClub(ctx context.Context, clubID uuid.UUID, limit, offset int) ([]model.Student, error) {
+func (r *StudentRepository) GetByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter, limit, offset int) ([]model.Student, error) {
 	var students []studentDB
+	args := []interface{}{clubID, limit, offset}
 	query := `
 		SELECT * FROM students 
-		WHERE club_id = $1 
+		WHERE club_id = $1 AND ` + archived.condition("") + ` AND ` + attrs.condition("", &args) + `
 		ORDER BY name
 		LIMIT $2 OFFSET $3`
 
-	err := r.db.SelectContext(ctx, &students, query, clubID, limit, offset)
+	err := r.db.SelectContext(ctx, &students, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,22 +101,221 @@ func (r *StudentRepository) GetByClub(ctx context.Context, clubID uuid.UUID, lim
 	return result, nil
 }
 
-func (r *StudentRepository) CountByClub(ctx context.Context, clubID uuid.UUID) (int, error) {
+// GetAllByClub returns every student of a club, e.g. to find duplicates
+func (r *StudentRepository) GetAllByClub(ctx context.Context, clubID uuid.UUID) ([]model.Student, error) {
+	var students []studentDB
+	query := `SELECT * FROM students WHERE club_id = $1 ORDER BY name`
+
+	if err := r.db.SelectContext(ctx, &students, query, clubID); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.Student, len(students))
+	for i, s := range students {
+		result[i] = *s.toModel()
+	}
+	return result, nil
+}
+
+// EachByClub calls fn for every student of a club in name order, reading
+// them from a cursor so exports of large clubs are not held in memory
+func (r *StudentRepository) EachByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter, fn func(*model.Student) error) error {
+	args := []interface{}{clubID}
+	query := `
+		SELECT * FROM students
+		WHERE club_id = $1 AND ` + archived.condition("") + ` AND ` + attrs.condition("", &args) + `
+		ORDER BY name`
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s studentDB
+		if err := rows.StructScan(&s); err != nil {
+			return err
+		}
+		if err := fn(s.toModel()); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *StudentRepository) CountByClub(ctx context.Context, clubID uuid.UUID, archived ArchiveFilter, attrs AttributeFilter) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM students WHERE club_id = $1`
-	err := r.db.GetContext(ctx, &count, query, clubID)
+	args := []interface{}{clubID}
+	query := `SELECT COUNT(*) FROM students WHERE club_id = $1 AND ` + archived.condition("") + ` AND ` + attrs.condition("", &args)
+	err := r.db.GetContext(ctx, &count, query, args...)
 	return count, err
 }
 
-func (r *StudentRepository) Search(ctx context.Context, clubID uuid.UUID, searchTerm string) ([]model.Student, error) {
+// StudentSearch is a search of a club's students. Every field is
+// optional; without a query students come in name order.
+type StudentSearch struct {
+	// Query is matched against the student's and guardian's names and the
+	// guardian's email, in either alphabet and with typos, and against the
+	// guardian's phone when it is a number
+	Query string
+	// GroupID and SubscriptionStatus keep students with a subscription in
+	// the group, in that status, or both
+	GroupID            *uuid.UUID
+	SubscriptionStatus string
+	// Debt keeps students with (true) or without (false) an unpaid
+	// subscription
+	Debt          *bool
+	BirthYearFrom int
+	BirthYearTo   int
+	Archived      ArchiveFilter
+	Attributes    AttributeFilter
+	Limit         int
+	// After continues a search from the last student of the previous page
+	After *StudentCursor
+}
+
+// StudentCursor is the position of a student in search results: best
+// matches first, then by name
+type StudentCursor struct {
+	Rank float32   `json:"r"`
+	Name string    `json:"n"`
+	ID   uuid.UUID `json:"i"`
+}
+
+// Encode turns the cursor into an opaque string for the client
+func (c StudentCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeStudentCursor reads a cursor made by Encode
+func DecodeStudentCursor(s string) (*StudentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c StudentCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// minPhoneQueryDigits is how many digits a query needs to be looked up
+// among phones
+const minPhoneQueryDigits = 4
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search returns a page of the students matching the search and the cursor
+// of the next page, nil on the last one. Matches are ranked by how alike
+// they are to the query, exact substrings and phone matches first.
+func (r *StudentRepository) Search(ctx context.Context, clubID uuid.UUID, search StudentSearch) ([]model.Student, *StudentCursor, error) {
+	args := []interface{}{clubID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"st.club_id = $1", search.Archived.condition("st"), search.Attributes.condition("st", &args)}
+	rank := "0"
+
+	if query := strings.TrimSpace(search.Query); query != "" {
+		text := "student_search_text(st.name, st.parent_contact)"
+		q := "search_fold(" + arg(query) + ")"
+		like := "'%' || search_fold(" + arg(likeEscaper.Replace(query)) + ") || '%'"
+
+		matches := []string{q + " <% " + text, text + " LIKE " + like}
+		ranks := []string{"word_similarity(" + q + ", " + text + ")", "CASE WHEN " + text + " LIKE " + like + " THEN 1 ELSE 0 END"}
+
+		if digits := importer.NormalizePhone(query); len(digits) >= minPhoneQueryDigits && !strings.ContainsFunc(query, unicode.IsLetter) {
+			phone := "search_phone(st.parent_contact->>'phone') LIKE " + arg("%"+digits+"%")
+			matches = append(matches, phone)
+			ranks = append(ranks, "CASE WHEN "+phone+" THEN 1 ELSE 0 END")
+		}
+
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+		rank = "GREATEST(" + strings.Join(ranks, ", ") + ")"
+	}
+
+	if search.GroupID != nil || search.SubscriptionStatus != "" {
+		subscription := "SELECT 1 FROM subscriptions sub WHERE sub.student_id = st.id"
+		if search.GroupID != nil {
+			subscription += " AND sub.group_id = " + arg(*search.GroupID)
+		}
+		if search.SubscriptionStatus != "" {
+			subscription += " AND sub.status = " + arg(search.SubscriptionStatus)
+		}
+		conditions = append(conditions, "EXISTS ("+subscription+")")
+	}
+
+	if search.Debt != nil {
+		debt := "EXISTS (SELECT 1 FROM subscriptions sub WHERE sub.student_id = st.id AND sub.status = 'pending')"
+		if !*search.Debt {
+			debt = "NOT " + debt
+		}
+		conditions = append(conditions, debt)
+	}
+
+	if search.BirthYearFrom > 0 {
+		conditions = append(conditions, "st.birth_date >= "+arg(time.Date(search.BirthYearFrom, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}
+	if search.BirthYearTo > 0 {
+		conditions = append(conditions, "st.birth_date < "+arg(time.Date(search.BirthYearTo+1, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}
+
+	after := "TRUE"
+	if c := search.After; c != nil {
+		rankArg, nameArg, idArg := arg(c.Rank), arg(c.Name), arg(c.ID)
+		after = "(rank < " + rankArg + "::real OR (rank = " + rankArg + "::real AND (name, id) > (" + nameArg + ", " + idArg + "::uuid)))"
+	}
+
+	query := `
+		WITH matches AS (
+			SELECT st.*, (` + rank + `)::real AS rank
+			FROM students st
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		)
+		SELECT * FROM matches
+		WHERE ` + after + `
+		ORDER BY rank DESC, name, id
+		LIMIT ` + arg(search.Limit+1)
+
+	var rows []studentSearchDB
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, nil, err
+	}
+
+	var next *StudentCursor
+	if len(rows) > search.Limit {
+		rows = rows[:search.Limit]
+		last := rows[len(rows)-1]
+		next = &StudentCursor{Rank: last.Rank, Name: last.Name, ID: last.ID}
+	}
+
+	result := make([]model.Student, len(rows))
+	for i := range rows {
+		result[i] = *rows[i].toModel()
+	}
+	return result, next, nil
+}
+
+// GetWithActiveSubscription returns students holding an active subscription
+// in the group that is valid at the given time
+func (r *StudentRepository) GetWithActiveSubscription(ctx context.Context, groupID uuid.UUID, at time.Time) ([]model.Student, error) {
 	var students []studentDB
 	query := `
-		SELECT * FROM students 
-		WHERE club_id = $1 AND name ILIKE $2
-		ORDER BY name
-		LIMIT 50`
+		SELECT DISTINCT st.* FROM students st
+		JOIN subscriptions sub ON sub.student_id = st.id
+		WHERE sub.group_id = $1
+		  AND sub.status = 'active'
+		  AND st.archived_at IS NULL
+		  AND (sub.starts_at IS NULL OR sub.starts_at <= $2)
+		  AND (sub.expires_at IS NULL OR sub.expires_at >= $2)
+		ORDER BY st.name`
 
-	err := r.db.SelectContext(ctx, &students, query, clubID, "%"+searchTerm+"%")
+	err := r.db.SelectContext(ctx, &students, query, groupID, at)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +332,7 @@ func (r *StudentRepository) Update(ctx context.Context, student *model.Student)
 
 	query := `
 		UPDATE students 
-		SET name = $2, birth_date = $3, parent_contact = $4, notes = $5
+		SET name = $2, birth_date = $3, parent_contact = $4, notes = $5, tags = $6, custom_fields = $7
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -111,6 +341,8 @@ func (r *StudentRepository) Update(ctx context.Context, student *model.Student)
 		student.BirthDate,
 		parentContactJSON,
 		student.Notes,
+		pq.StringArray(nonNilStrings(student.Tags)),
+		customValuesJSON(student.CustomFields),
 	)
 	if err != nil {
 		return err
@@ -123,8 +355,24 @@ func (r *StudentRepository) Update(ctx context.Context, student *model.Student)
 	return nil
 }
 
-func (r *StudentRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM students WHERE id = $1`
+// Archive hides a student from lists, rosters and reminders; everything
+// recorded for them is kept. Archiving an archived student is a no-op.
+func (r *StudentRepository) Archive(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE students SET archived_at = COALESCE(archived_at, now()) WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *StudentRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE students SET archived_at = NULL WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
@@ -137,10 +385,78 @@ func (r *StudentRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// GetForUpdateInTx locks and returns a student
+// Must be called within a transaction
+func (r *StudentRepository) GetForUpdateInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*model.Student, error) {
+	var student studentDB
+	query := `SELECT * FROM students WHERE id = $1 FOR UPDATE`
+
+	err := tx.GetContext(ctx, &student, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return student.toModel(), nil
+}
+
+// UpdateInTx updates a student within a transaction
+// Must be called within a transaction
+func (r *StudentRepository) UpdateInTx(ctx context.Context, tx *sqlx.Tx, student *model.Student) error {
+	parentContactJSON, _ := json.Marshal(student.ParentContact)
+
+	query := `
+		UPDATE students 
+		SET name = $2, birth_date = $3, parent_contact = $4, notes = $5, tags = $6, custom_fields = $7
+		WHERE id = $1`
+
+	result, err := tx.ExecContext(ctx, query,
+		student.ID,
+		student.Name,
+		student.BirthDate,
+		parentContactJSON,
+		student.Notes,
+		pq.StringArray(nonNilStrings(student.Tags)),
+		customValuesJSON(student.CustomFields),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteInTx deletes a student within a transaction
+// Must be called within a transaction
+func (r *StudentRepository) DeleteInTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM students WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // Helper struct for DB scanning with JSONB
 type studentDB struct {
 	model.Student
-	ParentContactRaw []byte `db:"parent_contact"`
+	ParentContactRaw []byte         `db:"parent_contact"`
+	TagsRaw          pq.StringArray `db:"tags"`
+	CustomFieldsRaw  []byte         `db:"custom_fields"`
+}
+
+type studentSearchDB struct {
+	studentDB
+	Rank float32 `db:"rank"`
 }
 
 func (s *studentDB) toModel() *model.Student {
@@ -149,5 +465,7 @@ func (s *studentDB) toModel() *model.Student {
 		json.Unmarshal(s.ParentContactRaw, &pc)
 		s.Student.ParentContact = &pc
 	}
+	s.Student.Tags = nonNilStrings(s.TagsRaw)
+	s.Student.CustomFields = customValuesFromJSON(s.CustomFieldsRaw)
 	return &s.Student
 }
@@ -21,8 +21,8 @@ func NewSubscriptionRepository(db *sqlx.DB) *SubscriptionRepository {
 
 func (r *SubscriptionRepository) Create(ctx context.Context, sub *model.Subscription) error {
 	query := `
-		INSERT INTO subscriptions (student_id, group_id, total_sessions, remaining_sessions, price, starts_at, expires_at, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO subscriptions (student_id, group_id, total_sessions, remaining_sessions, price, starts_at, expires_at, status, kind)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at`
 
 	return r.db.QueryRowxContext(ctx, query,
@@ -34,6 +34,28 @@ func (r *SubscriptionRepository) Create(ctx context.Context, sub *model.Subscrip
 		sub.StartsAt,
 		sub.ExpiresAt,
 		sub.Status,
+		sub.Kind,
+	).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+// CreateInTx creates a subscription within a transaction
+// Must be called within a transaction
+func (r *SubscriptionRepository) CreateInTx(ctx context.Context, tx *sqlx.Tx, sub *model.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (student_id, group_id, total_sessions, remaining_sessions, price, starts_at, expires_at, status, kind)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	return tx.QueryRowxContext(ctx, query,
+		sub.StudentID,
+		sub.GroupID,
+		sub.TotalSessions,
+		sub.RemainingSessions,
+		sub.Price,
+		sub.StartsAt,
+		sub.ExpiresAt,
+		sub.Status,
+		sub.Kind,
 	).Scan(&sub.ID, &sub.CreatedAt)
 }
 
@@ -152,6 +174,114 @@ func (r *SubscriptionRepository) DecrementRemainingSessions(ctx context.Context,
 	return nil
 }
 
+// RefundInTx gives one session back to a subscription, reactivating it if
+// it was used up
+// Must be called within a transaction
+func (r *SubscriptionRepository) RefundInTx(ctx context.Context, tx *sqlx.Tx, subID uuid.UUID) error {
+	query := `
+		UPDATE subscriptions
+		SET remaining_sessions = remaining_sessions + 1,
+		    status = CASE WHEN status = 'used' THEN 'active' ELSE status END
+		WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, subID)
+	return err
+}
+
+// CreateDropInChargeInTx records the one-off charge of a drop-in visit as
+// a used single-session subscription awaiting payment, priced at the
+// group's drop-in price. Returns ErrNotFound if the group takes no drop-ins.
+// Must be called within a transaction
+func (r *SubscriptionRepository) CreateDropInChargeInTx(ctx context.Context, tx *sqlx.Tx, studentID, groupID uuid.UUID, visitAt time.Time) (*model.Subscription, error) {
+	var sub model.Subscription
+	query := `
+		INSERT INTO subscriptions (student_id, group_id, total_sessions, remaining_sessions, price, starts_at, expires_at, status, kind)
+		SELECT $1, g.id, 1, 0, g.drop_in_price, $3, $3, 'pending', 'drop_in'
+		FROM groups g
+		WHERE g.id = $2 AND g.drop_in_price IS NOT NULL
+		RETURNING *`
+
+	err := tx.GetContext(ctx, &sub, query, studentID, groupID, visitAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &sub, err
+}
+
+// CancelPendingInTx cancels a subscription that has not been paid yet and
+// reports whether it did
+// Must be called within a transaction
+func (r *SubscriptionRepository) CancelPendingInTx(ctx context.Context, tx *sqlx.Tx, subID uuid.UUID) (bool, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE subscriptions SET status = 'cancelled' WHERE id = $1 AND status = 'pending'`, subID)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ReopenDropInInTx turns a paid drop-in charge back into a session to use
+// later: it becomes active with one session left, valid from now at least
+// until validUntil. The charge itself expires on its visit, so it could
+// not be found again otherwise.
+// Must be called within a transaction
+func (r *SubscriptionRepository) ReopenDropInInTx(ctx context.Context, tx *sqlx.Tx, subID uuid.UUID, validUntil time.Time) error {
+	query := `
+		UPDATE subscriptions
+		SET remaining_sessions = remaining_sessions + 1,
+		    status = 'active',
+		    starts_at = LEAST(COALESCE(starts_at, now()), now()),
+		    expires_at = GREATEST(COALESCE(expires_at, $2), $2)
+		WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, subID, validUntil)
+	return err
+}
+
+// RefundSessionInTx gives back the session credit taken by every attendance
+// of the given session and unlinks those attendances from their
+// subscriptions. Used subscriptions become active again, unpaid drop-in
+// charges are cancelled and paid ones reopened as in ReopenDropInInTx.
+// Must be called within a transaction
+func (r *SubscriptionRepository) RefundSessionInTx(ctx context.Context, tx *sqlx.Tx, sessionID uuid.UUID, dropInValidUntil time.Time) (int64, error) {
+	cancelDropIns := `
+		UPDATE subscriptions sub
+		SET status = 'cancelled'
+		FROM attendances a
+		WHERE a.session_id = $1 AND a.subscription_id = sub.id
+		  AND sub.kind = 'drop_in' AND sub.status = 'pending'`
+
+	if _, err := tx.ExecContext(ctx, cancelDropIns, sessionID); err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE subscriptions sub
+		SET remaining_sessions = sub.remaining_sessions + 1,
+		    status = CASE WHEN sub.status = 'used' THEN 'active' ELSE sub.status END,
+		    starts_at = CASE WHEN sub.kind = 'drop_in'
+		        THEN LEAST(COALESCE(sub.starts_at, now()), now()) ELSE sub.starts_at END,
+		    expires_at = CASE WHEN sub.kind = 'drop_in'
+		        THEN GREATEST(COALESCE(sub.expires_at, $2), $2) ELSE sub.expires_at END
+		FROM attendances a
+		WHERE a.session_id = $1 AND a.subscription_id = sub.id
+		  AND sub.status <> 'cancelled'`
+
+	result, err := tx.ExecContext(ctx, query, sessionID, dropInValidUntil)
+	if err != nil {
+		return 0, err
+	}
+	refunded, _ := result.RowsAffected()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE attendances SET subscription_id = NULL WHERE session_id = $1 AND subscription_id IS NOT NULL`,
+		sessionID); err != nil {
+		return 0, err
+	}
+	return refunded, nil
+}
+
 func (r *SubscriptionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	query := `UPDATE subscriptions SET status = $2 WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id, status)
@@ -165,10 +295,27 @@ func (r *SubscriptionRepository) UpdateStatus(ctx context.Context, id uuid.UUID,
 	return nil
 }
 
+// ExpireDue marks active subscriptions whose expiry date has passed as
+// expired and returns them
+func (r *SubscriptionRepository) ExpireDue(ctx context.Context, now time.Time) ([]model.Subscription, error) {
+	var subs []model.Subscription
+	query := `
+		UPDATE subscriptions
+		SET status = 'expired'
+		WHERE status = 'active' AND expires_at < $1
+		RETURNING *`
+
+	err := r.db.SelectContext(ctx, &subs, query, now)
+	return subs, err
+}
+
+// Activate marks a paid subscription active. One with no sessions left,
+// such as a drop-in charge, becomes used.
 func (r *SubscriptionRepository) Activate(ctx context.Context, id uuid.UUID, startsAt, expiresAt *time.Time) error {
 	query := `
 		UPDATE subscriptions 
-		SET status = 'active', starts_at = COALESCE($2, starts_at, now()), expires_at = $3
+		SET status = CASE WHEN remaining_sessions > 0 THEN 'active' ELSE 'used' END,
+		    starts_at = COALESCE($2, starts_at, now()), expires_at = $3
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, id, startsAt, expiresAt)
@@ -214,9 +361,9 @@ type SubscriptionWithDetails struct {
 func (r *SubscriptionRepository) GetByClubWithDetails(ctx context.Context, clubID uuid.UUID, status string) ([]SubscriptionWithDetails, error) {
 	var subs []SubscriptionWithDetails
 	query := `
-		SELECT s.*, st.name as student_name, g.title as group_title
+		SELECT s.*, COALESCE(st.name, '') as student_name, g.title as group_title
 		FROM subscriptions s
-		JOIN students st ON s.student_id = st.id
+		LEFT JOIN students st ON s.student_id = st.id
 		JOIN groups g ON s.group_id = g.id
 		WHERE g.club_id = $1`
 
@@ -230,3 +377,38 @@ func (r *SubscriptionRepository) GetByClubWithDetails(ctx context.Context, clubI
 	err := r.db.SelectContext(ctx, &subs, query, args...)
 	return subs, err
 }
+
+// EachByClubWithDetails calls fn for the subscriptions GetByClubWithDetails
+// returns, reading them from a cursor
+func (r *SubscriptionRepository) EachByClubWithDetails(ctx context.Context, clubID uuid.UUID, status string, fn func(*SubscriptionWithDetails) error) error {
+	query := `
+		SELECT s.*, COALESCE(st.name, '') as student_name, g.title as group_title
+		FROM subscriptions s
+		LEFT JOIN students st ON s.student_id = st.id
+		JOIN groups g ON s.group_id = g.id
+		WHERE g.club_id = $1`
+
+	args := []interface{}{clubID}
+	if status != "" {
+		query += ` AND s.status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY s.created_at DESC`
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sub SubscriptionWithDetails
+		if err := rows.StructScan(&sub); err != nil {
+			return err
+		}
+		if err := fn(&sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+type TelegramRepository struct {
+	db *sqlx.DB
+}
+
+func NewTelegramRepository(db *sqlx.DB) *TelegramRepository {
+	return &TelegramRepository{db: db}
+}
+
+// CreateLinkCode stores the hash of a new link code for userID and drops
+// the user's earlier codes
+func (r *TelegramRepository) CreateLinkCode(ctx context.Context, userID uuid.UUID, codeHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telegram_link_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO telegram_link_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, codeHash, userID, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Link redeems a link code and links chatID to its user, replacing any
+// earlier link of the user or the chat. It returns ErrNotFound for an
+// unknown, used or expired code.
+func (r *TelegramRepository) Link(ctx context.Context, codeHash string, chatID int64, username *string) (*model.TelegramAccount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.GetContext(ctx, &userID,
+		`DELETE FROM telegram_link_codes WHERE code_hash = $1 AND expires_at > now() RETURNING user_id`, codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telegram_accounts WHERE chat_id = $1 AND user_id <> $2`, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	account := &model.TelegramAccount{UserID: userID, ChatID: chatID, Username: username}
+	query := `
+		INSERT INTO telegram_accounts (user_id, chat_id, username)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET chat_id = EXCLUDED.chat_id, username = EXCLUDED.username, linked_at = now()
+		RETURNING linked_at`
+
+	if err := tx.QueryRowxContext(ctx, query, userID, chatID, username).Scan(&account.LinkedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (r *TelegramRepository) GetByChatID(ctx context.Context, chatID int64) (*model.TelegramAccount, error) {
+	var account model.TelegramAccount
+	err := r.db.GetContext(ctx, &account, `SELECT * FROM telegram_accounts WHERE chat_id = $1`, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &account, err
+}
+
+func (r *TelegramRepository) GetByUser(ctx context.Context, userID uuid.UUID) (*model.TelegramAccount, error) {
+	var account model.TelegramAccount
+	err := r.db.GetContext(ctx, &account, `SELECT * FROM telegram_accounts WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &account, err
+}
+
+func (r *TelegramRepository) Unlink(ctx context.Context, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM telegram_accounts WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
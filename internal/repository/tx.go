@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// WithSavepoint runs fn inside a savepoint of tx. If fn fails, everything
+// it did is undone and tx stays usable for the next statement, instead of
+// Postgres aborting the whole transaction.
+func WithSavepoint(ctx context.Context, tx *sqlx.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT item"); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT item; RELEASE SAVEPOINT item"); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT item")
+	return err
+}
+
+// IsForeignKeyViolation reports whether err references a missing row,
+// e.g. an unknown student
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
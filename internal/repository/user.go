@@ -13,6 +13,7 @@ import (
 var (
 	ErrNotFound      = errors.New("record not found")
 	ErrAlreadyExists = errors.New("record already exists")
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
 type UserRepository struct {
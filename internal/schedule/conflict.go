@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConflictKind says which resource is double-booked
+type ConflictKind string
+
+const (
+	ConflictCoach    ConflictKind = "coach"
+	ConflictLocation ConflictKind = "location"
+)
+
+// Slot is a block of scheduled time held by a group's coach and location.
+// SessionID is zero for sessions that are not stored yet.
+type Slot struct {
+	SessionID       uuid.UUID  `json:"session_id,omitempty"`
+	GroupID         uuid.UUID  `json:"group_id"`
+	ClubID          uuid.UUID  `json:"-"`
+	GroupTitle      string     `json:"group_title,omitempty"`
+	CoachUserID     *uuid.UUID `json:"coach_user_id,omitempty"`
+	Location        string     `json:"location,omitempty"`
+	LocationID      *uuid.UUID `json:"location_id,omitempty"`
+	StartAt         time.Time  `json:"start_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+// EndAt returns the time the slot ends
+func (s Slot) EndAt() time.Time {
+	return s.StartAt.Add(time.Duration(s.DurationMinutes) * time.Minute)
+}
+
+// Overlaps reports whether two slots share any time. Back-to-back slots
+// (one ends exactly when the other starts) do not overlap.
+func (s Slot) Overlaps(o Slot) bool {
+	return s.StartAt.Before(o.EndAt()) && o.StartAt.Before(s.EndAt())
+}
+
+// Conflict is a pair of overlapping slots that share a coach or a location
+type Conflict struct {
+	Kind ConflictKind `json:"kind"`
+	Slot Slot         `json:"slot"`
+	With Slot         `json:"with"`
+}
+
+// FindConflicts checks candidate slots against existing ones. A slot never
+// conflicts with itself, so an edited session can be checked against a
+// schedule that still contains it.
+func FindConflicts(candidates, existing []Slot) []Conflict {
+	sorted := sortedByStart(existing)
+
+	var conflicts []Conflict
+	for _, c := range candidates {
+		// Existing slots starting at or after c ends cannot overlap it
+		end := sort.Search(len(sorted), func(i int) bool {
+			return !sorted[i].StartAt.Before(c.EndAt())
+		})
+		for _, e := range sorted[:end] {
+			if c.SessionID != uuid.Nil && c.SessionID == e.SessionID {
+				continue
+			}
+			conflicts = append(conflicts, conflictsBetween(c, e)...)
+		}
+	}
+	return conflicts
+}
+
+// FindOverlaps reports every conflicting pair within one schedule
+func FindOverlaps(slots []Slot) []Conflict {
+	sorted := sortedByStart(slots)
+
+	var conflicts []Conflict
+	for i, a := range sorted {
+		for _, b := range sorted[i+1:] {
+			if !b.StartAt.Before(a.EndAt()) {
+				break
+			}
+			conflicts = append(conflicts, conflictsBetween(a, b)...)
+		}
+	}
+	return conflicts
+}
+
+func conflictsBetween(a, b Slot) []Conflict {
+	if !a.Overlaps(b) {
+		return nil
+	}
+
+	var conflicts []Conflict
+	if a.CoachUserID != nil && b.CoachUserID != nil && *a.CoachUserID == *b.CoachUserID {
+		conflicts = append(conflicts, Conflict{Kind: ConflictCoach, Slot: a, With: b})
+	}
+	if sameLocation(a, b) {
+		conflicts = append(conflicts, Conflict{Kind: ConflictLocation, Slot: a, With: b})
+	}
+	return conflicts
+}
+
+// EffectiveLocationID is the hall a session is held in: its own location,
+// or the group's default when it has none
+func EffectiveLocationID(session, group *uuid.UUID) *uuid.UUID {
+	if session != nil {
+		return session
+	}
+	return group
+}
+
+// sameLocation compares location references when both slots have one and
+// falls back to the free-text name. Names are per club; equal names in
+// different clubs are different halls.
+func sameLocation(a, b Slot) bool {
+	if a.LocationID != nil && b.LocationID != nil {
+		return *a.LocationID == *b.LocationID
+	}
+	la, lb := normalizeLocation(a.Location), normalizeLocation(b.Location)
+	return la != "" && la == lb && a.ClubID == b.ClubID
+}
+
+func sortedByStart(slots []Slot) []Slot {
+	sorted := append([]Slot(nil), slots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartAt.Before(sorted[j].StartAt) })
+	return sorted
+}
+
+// normalizeLocation makes free-text locations comparable
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.Join(strings.Fields(location), " "))
+}
@@ -0,0 +1,123 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+func slotAt(hour, minute, duration int, coach *uuid.UUID, location string) schedule.Slot {
+	return schedule.Slot{
+		SessionID:       uuid.New(),
+		GroupID:         uuid.New(),
+		CoachUserID:     coach,
+		Location:        location,
+		StartAt:         time.Date(2025, 12, 1, hour, minute, 0, 0, time.UTC),
+		DurationMinutes: duration,
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	coach := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name      string
+		candidate schedule.Slot
+		existing  schedule.Slot
+		want      []schedule.ConflictKind
+	}{
+		{"same coach overlapping", slotAt(18, 0, 60, &coach, "Hall A"), slotAt(18, 30, 60, &coach, "Hall B"), []schedule.ConflictKind{schedule.ConflictCoach}},
+		{"same hall overlapping", slotAt(18, 0, 60, &coach, "Hall A"), slotAt(18, 30, 60, &other, " hall  a "), []schedule.ConflictKind{schedule.ConflictLocation}},
+		{"coach and hall", slotAt(18, 0, 90, &coach, "Hall A"), slotAt(19, 0, 60, &coach, "Hall A"), []schedule.ConflictKind{schedule.ConflictCoach, schedule.ConflictLocation}},
+		{"back to back", slotAt(18, 0, 60, &coach, "Hall A"), slotAt(19, 0, 60, &coach, "Hall A"), nil},
+		{"different coach and hall", slotAt(18, 0, 60, &coach, "Hall A"), slotAt(18, 0, 60, &other, "Hall B"), nil},
+		{"no coach no hall", slotAt(18, 0, 60, nil, ""), slotAt(18, 0, 60, nil, ""), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := schedule.FindConflicts([]schedule.Slot{tt.candidate}, []schedule.Slot{tt.existing})
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("expected %d conflicts, got %d: %+v", len(tt.want), len(conflicts), conflicts)
+			}
+			for i, kind := range tt.want {
+				if conflicts[i].Kind != kind {
+					t.Errorf("conflict %d: expected %s, got %s", i, kind, conflicts[i].Kind)
+				}
+			}
+		})
+	}
+}
+
+func TestFindConflicts_SkipsSameSession(t *testing.T) {
+	coach := uuid.New()
+	existing := slotAt(18, 0, 60, &coach, "Hall A")
+	moved := existing
+	moved.StartAt = moved.StartAt.Add(30 * time.Minute)
+
+	if conflicts := schedule.FindConflicts([]schedule.Slot{moved}, []schedule.Slot{existing}); len(conflicts) != 0 {
+		t.Errorf("expected a session not to conflict with itself, got %+v", conflicts)
+	}
+}
+
+func TestFindConflicts_LocationReferences(t *testing.T) {
+	hallA, hallB := uuid.New(), uuid.New()
+
+	a := slotAt(18, 0, 60, nil, "Main hall")
+	a.LocationID = &hallA
+	b := slotAt(18, 30, 60, nil, "Main hall")
+	b.LocationID = &hallB
+
+	// Same label, different halls
+	if conflicts := schedule.FindConflicts([]schedule.Slot{a}, []schedule.Slot{b}); len(conflicts) != 0 {
+		t.Errorf("expected no conflict between different halls, got %+v", conflicts)
+	}
+
+	b.LocationID = &hallA
+	b.Location = "renamed hall"
+	if conflicts := schedule.FindConflicts([]schedule.Slot{a}, []schedule.Slot{b}); len(conflicts) != 1 {
+		t.Errorf("expected a conflict for the same hall, got %+v", conflicts)
+	}
+}
+
+func TestFindConflicts_GroupLocation(t *testing.T) {
+	groupHall, ownHall := uuid.New(), uuid.New()
+
+	// Neither session names a hall; both are held in their groups' default
+	a := slotAt(18, 0, 60, nil, "")
+	a.LocationID = schedule.EffectiveLocationID(nil, &groupHall)
+	b := slotAt(18, 30, 60, nil, "")
+	b.LocationID = schedule.EffectiveLocationID(nil, &groupHall)
+
+	conflicts := schedule.FindConflicts([]schedule.Slot{a}, []schedule.Slot{b})
+	if len(conflicts) != 1 || conflicts[0].Kind != schedule.ConflictLocation {
+		t.Fatalf("expected a location conflict in the group's hall, got %+v", conflicts)
+	}
+
+	// A session's own hall wins over the group's
+	b.LocationID = schedule.EffectiveLocationID(&ownHall, &groupHall)
+	if conflicts := schedule.FindConflicts([]schedule.Slot{a}, []schedule.Slot{b}); len(conflicts) != 0 {
+		t.Errorf("expected no conflict with a session moved to another hall, got %+v", conflicts)
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	coach := uuid.New()
+	slots := []schedule.Slot{
+		slotAt(20, 0, 60, &coach, "Hall B"),
+		slotAt(18, 0, 60, &coach, "Hall A"),
+		slotAt(18, 30, 60, nil, "Hall A"),
+		slotAt(19, 0, 60, nil, "Hall C"),
+	}
+
+	conflicts := schedule.FindOverlaps(slots)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d: %+v", len(conflicts), conflicts)
+	}
+	if conflicts[0].Kind != schedule.ConflictLocation {
+		t.Errorf("expected location conflict, got %s", conflicts[0].Kind)
+	}
+}
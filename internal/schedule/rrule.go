@@ -0,0 +1,332 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned for RRULE strings outside the supported subset
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Frequency is the RRULE FREQ part
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+)
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month
+// (1 = first, -1 = last) and is only meaningful for MONTHLY rules;
+// zero means every such weekday.
+type WeekdayNum struct {
+	Day time.Weekday
+	N   int
+}
+
+// Rule is the supported subset of an RFC 5545 RRULE:
+// FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY, BYMONTHDAY, COUNT, UNTIL.
+// Occurrences are calendar dates; the time of day lives on the series.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time // inclusive calendar date
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR".
+// A leading "RRULE:" prefix is accepted.
+func ParseRule(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(val)); f {
+			case FreqDaily, FreqWeekly, FreqMonthly:
+				rule.Freq = f
+			default:
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRule)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive integer", ErrInvalidRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			if len(val) < 8 {
+				return nil, fmt.Errorf("%w: UNTIL must be YYYYMMDD", ErrInvalidRule)
+			}
+			// Only the date matters; a trailing THHMMSSZ is ignored
+			until, err := time.Parse("20060102", val[:8])
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be YYYYMMDD", ErrInvalidRule)
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%w: invalid BYMONTHDAY %q", ErrInvalidRule, d)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, fmt.Errorf("%w: only WKST=MO is supported", ErrInvalidRule)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	if rule.Freq != FreqMonthly {
+		for _, wd := range rule.ByDay {
+			if wd.N != 0 {
+				return nil, fmt.Errorf("%w: BYDAY ordinals are only valid with FREQ=MONTHLY", ErrInvalidRule)
+			}
+		}
+		if len(rule.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("%w: BYMONTHDAY is only valid with FREQ=MONTHLY", ErrInvalidRule)
+		}
+	}
+	return rule, nil
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRule, code)
+	}
+	day, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRule, code)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("%w: invalid BYDAY ordinal %q", ErrInvalidRule, code)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// String renders the rule back to RRULE syntax
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			codes[i] = weekdayNames[wd.Day]
+			if wd.N != 0 {
+				codes[i] = strconv.Itoa(wd.N) + codes[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// WeeklyRule builds a FREQ=WEEKLY rule for the given weekdays
+func WeeklyRule(weekdays []time.Weekday) *Rule {
+	sorted := append([]time.Weekday(nil), weekdays...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rule := &Rule{Freq: FreqWeekly, Interval: 1}
+	for _, wd := range sorted {
+		rule.ByDay = append(rule.ByDay, WeekdayNum{Day: wd})
+	}
+	return rule
+}
+
+// Dates returns the occurrence dates of a series starting on start, limited
+// to the window [from, to]. Only calendar dates are compared; the results are
+// midnight UTC values carrying the year, month and day.
+//
+// COUNT is applied from start, so occurrences before from still consume it.
+func (r *Rule) Dates(start, from, to time.Time) []time.Time {
+	start = dateOnly(start)
+	from = dateOnly(from)
+	to = dateOnly(to)
+	if r.Until != nil && r.Until.Before(to) {
+		to = dateOnly(*r.Until)
+	}
+
+	var dates []time.Time
+	seen := 0
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if !r.matches(start, d) {
+			continue
+		}
+		seen++
+		if r.Count > 0 && seen > r.Count {
+			break
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+func (r *Rule) matches(start, d time.Time) bool {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case FreqDaily:
+		days := int(d.Sub(start).Hours() / 24)
+		if days%interval != 0 {
+			return false
+		}
+		return len(r.ByDay) == 0 || r.hasWeekday(d.Weekday())
+
+	case FreqWeekly:
+		weeks := int(weekStart(d).Sub(weekStart(start)).Hours() / 24 / 7)
+		if weeks%interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return d.Weekday() == start.Weekday()
+		}
+		return r.hasWeekday(d.Weekday())
+
+	case FreqMonthly:
+		months := (d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())
+		if months%interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return d.Day() == start.Day()
+		}
+		if len(r.ByMonthDay) > 0 && !matchesMonthDay(r.ByMonthDay, d) {
+			return false
+		}
+		return len(r.ByDay) == 0 || matchesMonthWeekday(r.ByDay, d)
+	}
+	return false
+}
+
+func (r *Rule) hasWeekday(wd time.Weekday) bool {
+	for _, bd := range r.ByDay {
+		if bd.Day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMonthDay(days []int, d time.Time) bool {
+	last := daysInMonth(d)
+	for _, md := range days {
+		if md > 0 && md == d.Day() {
+			return true
+		}
+		if md < 0 && last+md+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMonthWeekday(days []WeekdayNum, d time.Time) bool {
+	nth := (d.Day()-1)/7 + 1
+	nthFromEnd := -((daysInMonth(d)-d.Day())/7 + 1)
+	for _, wd := range days {
+		if wd.Day != d.Weekday() {
+			continue
+		}
+		if wd.N == 0 || wd.N == nth || wd.N == nthFromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func daysInMonth(d time.Time) int {
+	return time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// weekStart returns the Monday of d's week (WKST=MO)
+func weekStart(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// At combines a calendar date with a wall-clock time in loc
+func At(date time.Time, hour, minute int, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc)
+}
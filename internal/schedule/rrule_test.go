@@ -0,0 +1,140 @@
+package schedule_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func formatDates(dates []time.Time) []string {
+	out := make([]string, len(dates))
+	for i, d := range dates {
+		out[i] = d.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestParseRule_RoundTrip(t *testing.T) {
+	tests := []string{
+		"FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"FREQ=DAILY;INTERVAL=2;COUNT=10",
+		"FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20261231",
+		"FREQ=MONTHLY;BYMONTHDAY=1,15",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			rule, err := schedule.ParseRule(in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rule.String(); got != in {
+				t.Errorf("expected %q, got %q", in, got)
+			}
+		})
+	}
+}
+
+func TestParseRule_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260101",
+		"FREQ=WEEKLY;BYSETPOS=1",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			if _, err := schedule.ParseRule(in); !errors.Is(err, schedule.ErrInvalidRule) {
+				t.Errorf("expected ErrInvalidRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRule_Dates(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		from  time.Time
+		to    time.Time
+		want  []string
+	}{
+		{
+			name:  "weekly by day",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE",
+			start: date(2025, 12, 1),
+			from:  date(2025, 12, 1),
+			to:    date(2025, 12, 10),
+			want:  []string{"2025-12-01", "2025-12-03", "2025-12-08", "2025-12-10"},
+		},
+		{
+			name:  "biweekly",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			start: date(2025, 12, 1),
+			from:  date(2025, 12, 1),
+			to:    date(2025, 12, 31),
+			want:  []string{"2025-12-02", "2025-12-16", "2025-12-30"},
+		},
+		{
+			name:  "count consumed before window",
+			rule:  "FREQ=DAILY;COUNT=5",
+			start: date(2026, 1, 1),
+			from:  date(2026, 1, 4),
+			to:    date(2026, 1, 31),
+			want:  []string{"2026-01-04", "2026-01-05"},
+		},
+		{
+			name:  "until inclusive",
+			rule:  "FREQ=DAILY;INTERVAL=3;UNTIL=20260107",
+			start: date(2026, 1, 1),
+			from:  date(2026, 1, 1),
+			to:    date(2026, 12, 31),
+			want:  []string{"2026-01-01", "2026-01-04", "2026-01-07"},
+		},
+		{
+			name:  "last friday of month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: date(2026, 1, 1),
+			from:  date(2026, 1, 1),
+			to:    date(2026, 3, 31),
+			want:  []string{"2026-01-30", "2026-02-27", "2026-03-27"},
+		},
+		{
+			name:  "last day of month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2026, 1, 1),
+			from:  date(2026, 1, 1),
+			to:    date(2026, 3, 31),
+			want:  []string{"2026-01-31", "2026-02-28", "2026-03-31"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := schedule.ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := formatDates(rule.Dates(tt.start, tt.from, tt.to))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package schedule expands recurring session patterns into concrete start
+// times. All expansion happens in the club's time zone so that a class at
+// 18:00 stays at 18:00 local time across DST transitions.
+package schedule
+
+import "time"
+
+// DateLayout is the calendar date format accepted by the API
+const DateLayout = "2006-01-02"
+
+// Weekly returns the start times of sessions held on the given weekdays
+// between from and to (inclusive calendar dates) at hour:minute wall-clock
+// time in loc. Only the year, month and day of from and to are used.
+//
+// Wall-clock times that do not exist in loc (skipped by a DST jump) are
+// normalised forward by time.Date.
+func Weekly(from, to time.Time, weekdays []time.Weekday, hour, minute int, loc *time.Location) []time.Time {
+	var starts []time.Time
+	for _, d := range WeeklyRule(weekdays).Dates(from, from, to) {
+		starts = append(starts, At(d, hour, minute, loc))
+	}
+	return starts
+}
+
+// StartOfDay returns local midnight of t's calendar day in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// StartOfMonth returns local midnight of the first day of t's month in loc
+func StartOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+}
+
+// EndOfDay returns the last second of the calendar day that starts at
+// midnight of t in loc. Days are 23 or 25 hours long around DST changes,
+// so this is computed from the next midnight rather than by adding 24h.
+func EndOfDay(t time.Time, loc *time.Location) time.Time {
+	return StartOfDay(t, loc).AddDate(0, 0, 1).Add(-time.Second)
+}
+
+// DateOf returns the calendar date of t (in t's own location) as a
+// midnight UTC value
+func DateOf(t time.Time) time.Time {
+	return dateOnly(t)
+}
+
+// Today returns the current calendar date in loc as a midnight UTC value,
+// the representation used for occurrence dates
+func Today(loc *time.Location) time.Time {
+	return dateOnly(time.Now().In(loc))
+}
+
+// ParseDate parses a YYYY-MM-DD date as local midnight in loc
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, value, loc)
+}
@@ -0,0 +1,87 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("tz database missing %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestWeekly_UsesClubTimezone(t *testing.T) {
+	almaty := mustLoad(t, "Asia/Almaty")
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC) // Monday
+	to := time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC)
+
+	starts := schedule.Weekly(from, to, []time.Weekday{time.Monday, time.Wednesday}, 18, 0, almaty)
+
+	if len(starts) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(starts))
+	}
+	for _, s := range starts {
+		local := s.In(almaty)
+		if local.Hour() != 18 || local.Minute() != 0 {
+			t.Errorf("expected 18:00 local, got %s", local.Format("15:04"))
+		}
+	}
+	if got := starts[0].UTC().Hour(); got == 18 {
+		t.Errorf("expected UTC hour to differ from local 18:00, got %d", got)
+	}
+}
+
+func TestWeekly_DSTSafe(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	// DST starts on Sunday 2025-03-30 in Europe/Berlin
+	from := time.Date(2025, 3, 24, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 7, 0, 0, 0, 0, time.UTC)
+
+	starts := schedule.Weekly(from, to, []time.Weekday{time.Monday}, 18, 30, berlin)
+
+	if len(starts) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(starts))
+	}
+	for _, s := range starts {
+		local := s.In(berlin)
+		if local.Hour() != 18 || local.Minute() != 30 {
+			t.Errorf("expected 18:30 local on %s, got %s", local.Format("2006-01-02"), local.Format("15:04"))
+		}
+	}
+	if starts[0].UTC().Hour() == starts[1].UTC().Hour() {
+		t.Errorf("expected UTC offset to change across DST, both at %d UTC", starts[0].UTC().Hour())
+	}
+}
+
+func TestEndOfDay_ShortDay(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	day := time.Date(2025, 3, 30, 12, 0, 0, 0, berlin)
+
+	start := schedule.StartOfDay(day, berlin)
+	end := schedule.EndOfDay(day, berlin)
+
+	if got := end.Sub(start); got != 23*time.Hour-time.Second {
+		t.Errorf("expected 23h day, got %s", got)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	almaty := mustLoad(t, "Asia/Almaty")
+	d, err := schedule.ParseDate("2026-01-15", almaty)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Location() != almaty || d.Hour() != 0 {
+		t.Errorf("expected local midnight in Asia/Almaty, got %s", d)
+	}
+
+	if _, err := schedule.ParseDate("15.01.2026", almaty); err == nil {
+		t.Error("expected error for invalid date format")
+	}
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+// Outcomes of one student in a bulk or sheet request
+const (
+	ItemCreated   = "created"
+	ItemUpdated   = "updated"
+	ItemUnchanged = "unchanged"
+	ItemRemoved   = "removed"
+	ItemFailed    = "failed"
+)
+
+var (
+	ErrDuplicateStudent = errors.New("student listed more than once")
+	ErrAlreadyMarked    = errors.New("attendance already marked for this student and session")
+	ErrNoCredit         = errors.New("no makeup credit or active subscription found for this student and group")
+	ErrTrialLimit       = errors.New("student has used up the trial visits for this group")
+	ErrNoDropIn         = errors.New("group does not take drop-in visits")
+	ErrMakeupUsed       = errors.New("the makeup credit earned by this excused absence has already been used")
+)
+
+// AttendanceItem is one student's line in a bulk request or attendance sheet
+type AttendanceItem struct {
+	StudentID uuid.UUID
+	Status    string
+	Kind      string // only used when the student is not marked yet
+}
+
+// AttendanceItemResult reports what happened to one student
+type AttendanceItemResult struct {
+	StudentID  uuid.UUID         `json:"student_id"`
+	Result     string            `json:"result"`
+	Error      string            `json:"error,omitempty"`
+	Attendance *model.Attendance `json:"attendance,omitempty"`
+}
+
+// AttendanceService records attendance together with its bookkeeping:
+// subscription sessions, makeup credits used and earned, trial allowances
+// and drop-in charges
+type AttendanceService struct {
+	attendanceRepo *repository.AttendanceRepository
+	subRepo        *repository.SubscriptionRepository
+	makeupRepo     *repository.MakeupRepository
+}
+
+func NewAttendanceService(
+	attendanceRepo *repository.AttendanceRepository,
+	subRepo *repository.SubscriptionRepository,
+	makeupRepo *repository.MakeupRepository,
+) *AttendanceService {
+	return &AttendanceService{
+		attendanceRepo: attendanceRepo,
+		subRepo:        subRepo,
+		makeupRepo:     makeupRepo,
+	}
+}
+
+// Mark records attendance in its own transaction. See MarkInTx.
+func (s *AttendanceService) Mark(ctx context.Context, session *model.Session, club *model.Club, attendance *model.Attendance) error {
+	tx, err := s.attendanceRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.MarkInTx(ctx, tx, session, club, attendance); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkInTx records attendance for a session of the club. A present student
+// on a regular visit pays with a makeup credit usable in the session's
+// group if there is one, else with a subscription session (ErrNoCredit
+// when neither exists). A trial is free while the student has trials left
+// in the group (ErrTrialLimit); a drop-in creates a one-off charge
+// (ErrNoDropIn if the group takes none). An excused absence on a regular
+// visit still takes a session from an active subscription, if the student
+// has one, and then earns a makeup credit.
+// Returns ErrAlreadyMarked if the student already has attendance for the session.
+func (s *AttendanceService) MarkInTx(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, attendance *model.Attendance) error {
+	if attendance.Kind == "" {
+		attendance.Kind = string(model.AttendanceRegular)
+	}
+
+	switch attendance.Status {
+	case string(model.AttendancePresent):
+		if err := s.charge(ctx, tx, session, club, attendance); err != nil {
+			return err
+		}
+	case string(model.AttendanceExcused):
+		if err := s.chargeAbsence(ctx, tx, session, attendance); err != nil {
+			return err
+		}
+	}
+
+	if err := s.attendanceRepo.CreateInTx(ctx, tx, attendance); err != nil {
+		if repository.IsDuplicateAttendance(err) {
+			return ErrAlreadyMarked
+		}
+		return err
+	}
+
+	if attendance.Status == string(model.AttendanceExcused) {
+		return s.grantMakeup(ctx, tx, session, club, attendance)
+	}
+	return nil
+}
+
+// UpdateStatus changes the status of recorded attendance in its own
+// transaction. The row is read again under a lock, so concurrent changes
+// reconcile one after the other; attendance is updated to the result and
+// its NotedBy is kept. See SetStatusInTx.
+func (s *AttendanceService) UpdateStatus(ctx context.Context, session *model.Session, club *model.Club, attendance *model.Attendance, status string) error {
+	tx, err := s.attendanceRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := s.attendanceRepo.GetByIDForUpdate(ctx, tx, attendance.ID)
+	if err != nil {
+		return err
+	}
+	locked.NotedBy = attendance.NotedBy
+
+	if err := s.SetStatusInTx(ctx, tx, session, club, locked, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*attendance = *locked
+	return nil
+}
+
+// SetStatusInTx changes the status of recorded attendance and reconciles
+// its credits: whatever the old status took is given back and the new
+// status is charged as in MarkInTx (ErrNoCredit if 'present' finds
+// nothing to pay with), and the makeup credit earned follows the excused
+// status. An excused absence whose makeup credit was already used cannot
+// change (ErrMakeupUsed).
+func (s *AttendanceService) SetStatusInTx(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, attendance *model.Attendance, status string) error {
+	previous := attendance.Status
+	if status == previous {
+		return nil
+	}
+
+	// Drop the credit this absence earned first so it cannot pay for itself.
+	// Once used, the absence's charge paid for that makeup and stays.
+	excused := string(model.AttendanceExcused)
+	if previous == excused {
+		redeemed, err := s.makeupRepo.RevokeUnusedInTx(ctx, tx, attendance.ID)
+		if err != nil {
+			return err
+		}
+		if redeemed {
+			return ErrMakeupUsed
+		}
+	}
+
+	if err := s.refund(ctx, tx, attendance); err != nil {
+		return err
+	}
+	switch status {
+	case string(model.AttendancePresent):
+		if err := s.charge(ctx, tx, session, club, attendance); err != nil {
+			return err
+		}
+	case excused:
+		if err := s.chargeAbsence(ctx, tx, session, attendance); err != nil {
+			return err
+		}
+	}
+
+	attendance.Status = status
+	if err := s.attendanceRepo.UpdateInTx(ctx, tx, attendance); err != nil {
+		return err
+	}
+
+	if status == excused {
+		return s.grantMakeup(ctx, tx, session, club, attendance)
+	}
+	return nil
+}
+
+// Remove deletes attendance in its own transaction, reading the row again
+// under a lock. See RemoveInTx.
+func (s *AttendanceService) Remove(ctx context.Context, attendanceID uuid.UUID) error {
+	tx, err := s.attendanceRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	attendance, err := s.attendanceRepo.GetByIDForUpdate(ctx, tx, attendanceID)
+	if err != nil {
+		return err
+	}
+	if err := s.RemoveInTx(ctx, tx, attendance); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveInTx deletes attendance and gives back the credit it consumed.
+// An unused makeup credit it earned goes with it; if the credit was used,
+// the absence's subscription session paid for that makeup and is kept.
+func (s *AttendanceService) RemoveInTx(ctx context.Context, tx *sqlx.Tx, attendance *model.Attendance) error {
+	redeemed, err := s.makeupRepo.RevokeUnusedInTx(ctx, tx, attendance.ID)
+	if err != nil {
+		return err
+	}
+	if redeemed {
+		attendance.SubscriptionID = nil
+	}
+	if err := s.refund(ctx, tx, attendance); err != nil {
+		return err
+	}
+	return s.attendanceRepo.DeleteInTx(ctx, tx, attendance.ID)
+}
+
+// BulkMarkInTx records attendance for several students of a session. Each
+// student runs in its own savepoint, so one failure leaves the others (and
+// the transaction) intact. Students already marked are reported as failed.
+func (s *AttendanceService) BulkMarkInTx(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, notedBy uuid.UUID, items []AttendanceItem) ([]AttendanceItemResult, error) {
+	existing, err := s.attendanceRepo.GetBySessionForUpdate(ctx, tx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AttendanceItemResult, len(items))
+	seen := make(map[uuid.UUID]bool, len(items))
+	for i, item := range items {
+		results[i].StudentID = item.StudentID
+		switch {
+		case seen[item.StudentID]:
+			results[i].fail(ErrDuplicateStudent)
+			continue
+		case existing[item.StudentID] != nil:
+			results[i].fail(ErrAlreadyMarked)
+			continue
+		}
+		seen[item.StudentID] = true
+
+		attendance := &model.Attendance{
+			SessionID: session.ID,
+			StudentID: item.StudentID,
+			Status:    item.Status,
+			Kind:      item.Kind,
+			NotedBy:   notedBy,
+		}
+		err := repository.WithSavepoint(ctx, tx, func() error {
+			return s.MarkInTx(ctx, tx, session, club, attendance)
+		})
+		if err != nil {
+			if !isItemError(err) {
+				return nil, err
+			}
+			results[i].fail(err)
+			continue
+		}
+		results[i].Result = ItemCreated
+		results[i].Attendance = attendance
+	}
+	return results, nil
+}
+
+// ApplySheetInTx makes the session's attendance match a full attendance
+// sheet: new students are marked, changed statuses are updated with their
+// credits reconciled, and students left off the sheet are removed with
+// their credits given back. Each student runs in its own savepoint.
+func (s *AttendanceService) ApplySheetInTx(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, notedBy uuid.UUID, items []AttendanceItem) ([]AttendanceItemResult, error) {
+	existing, err := s.attendanceRepo.GetBySessionForUpdate(ctx, tx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AttendanceItemResult, 0, len(items))
+	seen := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		result := AttendanceItemResult{StudentID: item.StudentID}
+		if seen[item.StudentID] {
+			result.fail(ErrDuplicateStudent)
+			results = append(results, result)
+			continue
+		}
+		seen[item.StudentID] = true
+
+		attendance := existing[item.StudentID]
+		if attendance != nil && attendance.Status == item.Status {
+			result.Result = ItemUnchanged
+			result.Attendance = attendance
+			results = append(results, result)
+			continue
+		}
+
+		outcome := ItemUpdated
+		err := repository.WithSavepoint(ctx, tx, func() error {
+			if attendance == nil {
+				outcome = ItemCreated
+				attendance = &model.Attendance{
+					SessionID: session.ID,
+					StudentID: item.StudentID,
+					Status:    item.Status,
+					Kind:      item.Kind,
+					NotedBy:   notedBy,
+				}
+				return s.MarkInTx(ctx, tx, session, club, attendance)
+			}
+			// Work on a copy so a rolled-back item leaves no stale state
+			updated := *attendance
+			updated.NotedBy = notedBy
+			if err := s.SetStatusInTx(ctx, tx, session, club, &updated, item.Status); err != nil {
+				return err
+			}
+			attendance = &updated
+			return nil
+		})
+		if err != nil {
+			if !isItemError(err) {
+				return nil, err
+			}
+			result.fail(err)
+			results = append(results, result)
+			continue
+		}
+		result.Result = outcome
+		result.Attendance = attendance
+		results = append(results, result)
+	}
+
+	var dropped []*model.Attendance
+	for studentID, attendance := range existing {
+		if !seen[studentID] {
+			dropped = append(dropped, attendance)
+		}
+	}
+	sort.Slice(dropped, func(i, j int) bool {
+		return dropped[i].StudentID.String() < dropped[j].StudentID.String()
+	})
+
+	for _, attendance := range dropped {
+		if err := s.RemoveInTx(ctx, tx, attendance); err != nil {
+			return nil, err
+		}
+		results = append(results, AttendanceItemResult{
+			StudentID: attendance.StudentID,
+			Result:    ItemRemoved,
+		})
+	}
+	return results, nil
+}
+
+func (r *AttendanceItemResult) fail(err error) {
+	r.Result = ItemFailed
+	r.Error = err.Error()
+	if repository.IsForeignKeyViolation(err) {
+		r.Error = "student not found"
+	}
+}
+
+// isItemError reports whether err concerns a single student rather than
+// the request as a whole
+func isItemError(err error) bool {
+	return errors.Is(err, ErrNoCredit) ||
+		errors.Is(err, ErrTrialLimit) ||
+		errors.Is(err, ErrNoDropIn) ||
+		errors.Is(err, ErrAlreadyMarked) ||
+		errors.Is(err, ErrMakeupUsed) ||
+		repository.IsForeignKeyViolation(err)
+}
+
+// refund gives back the subscription session or makeup credit an
+// attendance consumed and unlinks it. An unpaid drop-in charge is
+// cancelled; a paid one becomes a session in the group to use later.
+func (s *AttendanceService) refund(ctx context.Context, tx *sqlx.Tx, attendance *model.Attendance) error {
+	if attendance.SubscriptionID != nil {
+		if err := s.refundSubscription(ctx, tx, attendance); err != nil {
+			return err
+		}
+		attendance.SubscriptionID = nil
+	}
+	if attendance.MakeupCreditID != nil {
+		if err := s.makeupRepo.RestoreInTx(ctx, tx, *attendance.MakeupCreditID); err != nil {
+			return err
+		}
+		attendance.MakeupCreditID = nil
+	}
+	return nil
+}
+
+// refundSubscription gives back the session an attendance took from its
+// subscription
+func (s *AttendanceService) refundSubscription(ctx context.Context, tx *sqlx.Tx, attendance *model.Attendance) error {
+	subID := *attendance.SubscriptionID
+	if attendance.Kind != string(model.AttendanceDropIn) {
+		return s.subRepo.RefundInTx(ctx, tx, subID)
+	}
+
+	cancelled, err := s.subRepo.CancelPendingInTx(ctx, tx, subID)
+	if err != nil || cancelled {
+		return err
+	}
+	return s.subRepo.ReopenDropInInTx(ctx, tx, subID, dropInValidUntil())
+}
+
+// dropInValidUntil is how long a paid drop-in that was given back stays
+// usable: as long as a subscription paid today
+func dropInValidUntil() time.Time {
+	return time.Now().AddDate(0, 3, 0)
+}
+
+func (s *AttendanceService) charge(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, attendance *model.Attendance) error {
+	switch model.AttendanceKind(attendance.Kind) {
+	case model.AttendanceTrial:
+		return s.checkTrialAllowance(ctx, tx, session, club, attendance)
+	case model.AttendanceDropIn:
+		return s.chargeDropIn(ctx, tx, session, attendance)
+	}
+
+	credit, err := s.makeupRepo.FindUsableForAttendance(ctx, tx, attendance.StudentID, session.GroupID, session.StartAt)
+	if err == nil {
+		if err := s.makeupRepo.RedeemInTx(ctx, tx, credit.ID); err != nil {
+			return err
+		}
+		attendance.MakeupCreditID = &credit.ID
+		return nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	sub, err := s.subRepo.FindActiveForAttendance(ctx, tx, attendance.StudentID, session.GroupID, session.StartAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNoCredit
+		}
+		return err
+	}
+	if err := s.subRepo.DecrementRemainingSessions(ctx, tx, sub.ID); err != nil {
+		return err
+	}
+	attendance.SubscriptionID = &sub.ID
+	return nil
+}
+
+// checkTrialAllowance lets a trial through while the student has trials
+// left in the session's group
+func (s *AttendanceService) checkTrialAllowance(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, attendance *model.Attendance) error {
+	used, err := s.attendanceRepo.CountTrialsInTx(ctx, tx, attendance.StudentID, session.GroupID, attendance.ID)
+	if err != nil {
+		return err
+	}
+	if used >= club.TrialVisitsPerGroup {
+		return ErrTrialLimit
+	}
+	return nil
+}
+
+// chargeDropIn bills a drop-in visit; the charge shows up as debt until paid
+func (s *AttendanceService) chargeDropIn(ctx context.Context, tx *sqlx.Tx, session *model.Session, attendance *model.Attendance) error {
+	sub, err := s.subRepo.CreateDropInChargeInTx(ctx, tx, attendance.StudentID, session.GroupID, session.StartAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNoDropIn
+		}
+		return err
+	}
+	attendance.SubscriptionID = &sub.ID
+	return nil
+}
+
+// chargeAbsence takes a subscription session for an excused absence on a
+// regular visit: the place was held for the student. Without an active
+// subscription the absence is recorded free of charge.
+func (s *AttendanceService) chargeAbsence(ctx context.Context, tx *sqlx.Tx, session *model.Session, attendance *model.Attendance) error {
+	if attendance.Kind != string(model.AttendanceRegular) {
+		return nil
+	}
+
+	sub, err := s.subRepo.FindActiveForAttendance(ctx, tx, attendance.StudentID, session.GroupID, session.StartAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := s.subRepo.DecrementRemainingSessions(ctx, tx, sub.ID); err != nil {
+		return err
+	}
+	attendance.SubscriptionID = &sub.ID
+	return nil
+}
+
+// grantMakeup gives an excused student a makeup credit when the club offers
+// makeups and the missed session was paid from a subscription
+func (s *AttendanceService) grantMakeup(ctx context.Context, tx *sqlx.Tx, session *model.Session, club *model.Club, attendance *model.Attendance) error {
+	if club.MakeupValidDays <= 0 || attendance.SubscriptionID == nil {
+		return nil
+	}
+
+	credit := &model.MakeupCredit{
+		StudentID:          attendance.StudentID,
+		SourceGroupID:      session.GroupID,
+		SourceAttendanceID: attendance.ID,
+		ExpiresAt:          session.StartAt.AddDate(0, 0, club.MakeupValidDays),
+	}
+	return s.makeupRepo.CreateInTx(ctx, tx, credit)
+}
@@ -0,0 +1,215 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+)
+
+// scriptDB is a database whose queries are answered by a function and
+// whose statements are recorded in order
+type scriptDB struct {
+	mu         sync.Mutex
+	statements []string
+	answer     func(query string) (columns []string, row []driver.Value)
+}
+
+func (db *scriptDB) ran(fragment string) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, s := range db.statements {
+		if strings.Contains(s, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *scriptDB) record(query string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.statements = append(db.statements, strings.Join(strings.Fields(query), " "))
+}
+
+var (
+	scripts        sync.Map // DSN -> *scriptDB
+	registerScript sync.Once
+)
+
+type scriptDriver struct{}
+
+func (scriptDriver) Open(dsn string) (driver.Conn, error) {
+	db, _ := scripts.Load(dsn)
+	return scriptConn{db.(*scriptDB)}, nil
+}
+
+type scriptConn struct{ db *scriptDB }
+
+func (c scriptConn) Prepare(query string) (driver.Stmt, error) { return scriptStmt{c.db, query}, nil }
+func (c scriptConn) Close() error                              { return nil }
+func (c scriptConn) Begin() (driver.Tx, error)                 { return scriptTx{}, nil }
+
+type scriptTx struct{}
+
+func (scriptTx) Commit() error   { return nil }
+func (scriptTx) Rollback() error { return nil }
+
+type scriptStmt struct {
+	db    *scriptDB
+	query string
+}
+
+func (s scriptStmt) Close() error  { return nil }
+func (s scriptStmt) NumInput() int { return -1 }
+
+func (s scriptStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s scriptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query)
+	columns, row := s.db.answer(s.query)
+	return &scriptRows{columns: columns, row: row}, nil
+}
+
+type scriptRows struct {
+	columns []string
+	row     []driver.Value
+	done    bool
+}
+
+func (r *scriptRows) Columns() []string { return r.columns }
+func (r *scriptRows) Close() error      { return nil }
+
+func (r *scriptRows) Next(dest []driver.Value) error {
+	if r.done || r.row == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func newScriptDB(t *testing.T, answer func(query string) ([]string, []driver.Value)) (*scriptDB, *sqlx.DB) {
+	t.Helper()
+	registerScript.Do(func() { sql.Register("script", scriptDriver{}) })
+
+	script := &scriptDB{answer: answer}
+	dsn := t.Name()
+	scripts.Store(dsn, script)
+	t.Cleanup(func() { scripts.Delete(dsn) })
+
+	db, err := sqlx.Open("script", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return script, db
+}
+
+// excusedAbsence answers queries for an excused, charged absence whose
+// makeup credit has or has not been redeemed
+func excusedAbsence(att *model.Attendance, redeemed bool) func(string) ([]string, []driver.Value) {
+	return func(query string) ([]string, []driver.Value) {
+		switch {
+		case strings.Contains(query, "FROM attendances WHERE id = $1 FOR UPDATE"):
+			return []string{"id", "session_id", "student_id", "subscription_id", "status", "kind", "noted_by", "noted_at"},
+				[]driver.Value{att.ID.String(), att.SessionID.String(), att.StudentID.String(), att.SubscriptionID.String(),
+					att.Status, att.Kind, att.NotedBy.String(), time.Now()}
+		case strings.Contains(query, "used_at IS NOT NULL"):
+			return []string{"exists"}, []driver.Value{redeemed}
+		case strings.Contains(query, "RETURNING noted_at"):
+			return []string{"noted_at"}, []driver.Value{time.Now()}
+		}
+		return nil, nil
+	}
+}
+
+func newAttendanceService(db *sqlx.DB) *service.AttendanceService {
+	return service.NewAttendanceService(
+		repository.NewAttendanceRepository(db),
+		repository.NewSubscriptionRepository(db),
+		repository.NewMakeupRepository(db),
+	)
+}
+
+func chargedExcused() *model.Attendance {
+	subID := uuid.New()
+	return &model.Attendance{
+		ID:             uuid.New(),
+		SessionID:      uuid.New(),
+		StudentID:      uuid.New(),
+		SubscriptionID: &subID,
+		Status:         string(model.AttendanceExcused),
+		Kind:           string(model.AttendanceRegular),
+		NotedBy:        uuid.New(),
+	}
+}
+
+func TestAttendanceService_UpdateStatus_UsedMakeup(t *testing.T) {
+	ctx := context.Background()
+	session := &model.Session{ID: uuid.New(), GroupID: uuid.New(), StartAt: time.Now()}
+	club := &model.Club{MakeupValidDays: 30}
+
+	t.Run("unused credit", func(t *testing.T) {
+		att := chargedExcused()
+		script, db := newScriptDB(t, excusedAbsence(att, false))
+
+		if err := newAttendanceService(db).UpdateStatus(ctx, session, club, att, string(model.AttendanceAbsent)); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+		if !script.ran("DELETE FROM makeup_credits") {
+			t.Error("expected the unused credit to be revoked")
+		}
+		if !script.ran("UPDATE subscriptions SET remaining_sessions = remaining_sessions + 1") {
+			t.Error("expected the absence's session to be given back")
+		}
+		if att.Status != string(model.AttendanceAbsent) || att.SubscriptionID != nil {
+			t.Errorf("attendance = %s on %v, want absent and uncharged", att.Status, att.SubscriptionID)
+		}
+	})
+
+	t.Run("redeemed credit", func(t *testing.T) {
+		att := chargedExcused()
+		script, db := newScriptDB(t, excusedAbsence(att, true))
+
+		err := newAttendanceService(db).UpdateStatus(ctx, session, club, att, string(model.AttendanceAbsent))
+		if !errors.Is(err, service.ErrMakeupUsed) {
+			t.Fatalf("expected ErrMakeupUsed, got %v", err)
+		}
+		if script.ran("UPDATE subscriptions") {
+			t.Error("expected the session paying for the used makeup not to be given back")
+		}
+		if script.ran("UPDATE attendances") {
+			t.Error("expected the attendance to stay excused")
+		}
+	})
+}
+
+func TestAttendanceService_Remove_UsedMakeup(t *testing.T) {
+	att := chargedExcused()
+	script, db := newScriptDB(t, excusedAbsence(att, true))
+
+	if err := newAttendanceService(db).Remove(context.Background(), att.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if script.ran("UPDATE subscriptions") {
+		t.Error("expected the session paying for the used makeup not to be given back")
+	}
+	if !script.ran("DELETE FROM attendances") {
+		t.Error("expected the attendance to be deleted")
+	}
+}
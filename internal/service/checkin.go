@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/checkin"
+)
+
+const (
+	// CheckInEarly is how long before the start students may check in
+	CheckInEarly = 30 * time.Minute
+	// MaxOfflineScanAge bounds how old a scan uploaded by a kiosk may be
+	MaxOfflineScanAge = 7 * 24 * time.Hour
+	// maxClockSkew tolerates kiosk clocks running slightly ahead
+	maxClockSkew = 5 * time.Minute
+)
+
+var (
+	ErrUnknownStudent  = errors.New("student not found in this club")
+	ErrNoSessionNow    = errors.New("no session to check in to at this time")
+	ErrInvalidScanTime = errors.New("scan time is in the future or too old")
+)
+
+// CheckInResult describes the outcome of one scan. A repeated scan for a
+// session the student is already marked for is reported, not treated as
+// an error.
+type CheckInResult struct {
+	StudentID        uuid.UUID         `json:"student_id"`
+	StudentName      string            `json:"student_name"`
+	SessionID        uuid.UUID         `json:"session_id"`
+	StartAt          time.Time         `json:"start_at"`
+	AlreadyCheckedIn bool              `json:"already_checked_in"`
+	Attendance       *model.Attendance `json:"attendance,omitempty"`
+}
+
+// Scan is a QR scan recorded by a kiosk, possibly while offline
+type Scan struct {
+	ID        string // client-side reference echoed back
+	Token     string
+	ScannedAt time.Time // zero means now
+}
+
+type ScanResult struct {
+	ID     string         `json:"id,omitempty"`
+	Result *CheckInResult `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// CheckInService marks students present from kiosk QR scans
+type CheckInService struct {
+	sessionRepo       *repository.SessionRepository
+	studentRepo       *repository.StudentRepository
+	clubRepo          *repository.ClubRepository
+	attendanceRepo    *repository.AttendanceRepository
+	attendanceService *AttendanceService
+	signer            *checkin.Signer
+}
+
+func NewCheckInService(
+	sessionRepo *repository.SessionRepository,
+	studentRepo *repository.StudentRepository,
+	clubRepo *repository.ClubRepository,
+	attendanceRepo *repository.AttendanceRepository,
+	attendanceService *AttendanceService,
+	signer *checkin.Signer,
+) *CheckInService {
+	return &CheckInService{
+		sessionRepo:       sessionRepo,
+		studentRepo:       studentRepo,
+		clubRepo:          clubRepo,
+		attendanceRepo:    attendanceRepo,
+		attendanceService: attendanceService,
+		signer:            signer,
+	}
+}
+
+// StudentToken returns the personal QR token of a student
+func (s *CheckInService) StudentToken(studentID uuid.UUID) string {
+	return s.signer.Sign(studentID)
+}
+
+// CheckIn resolves a scanned token to the session running at the device's
+// location at the given time and marks the student present through the
+// same path as manual marking.
+func (s *CheckInService) CheckIn(ctx context.Context, device *model.KioskDevice, token string, at time.Time) (*CheckInResult, error) {
+	studentID, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	student, err := s.studentRepo.GetByID(ctx, studentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUnknownStudent
+		}
+		return nil, err
+	}
+	if student.ClubID != device.ClubID || student.ArchivedAt != nil {
+		return nil, ErrUnknownStudent
+	}
+
+	club, err := s.clubRepo.GetByID(ctx, device.ClubID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.GetCheckInCandidates(ctx, device.ClubID, device.LocationID, at, CheckInEarly)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNoSessionNow
+	}
+
+	// A repeated scan is answered with the earlier check-in
+	for i := range sessions {
+		exists, err := s.attendanceRepo.Exists(ctx, sessions[i].ID, studentID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return checkInResult(student, &sessions[i], nil), nil
+		}
+	}
+
+	// Several groups can share a hall; check in to the first session the
+	// student can pay for
+	lastErr := ErrNoCredit
+	for i := range sessions {
+		session := &sessions[i]
+		attendance := &model.Attendance{
+			SessionID:     session.ID,
+			StudentID:     studentID,
+			KioskDeviceID: &device.ID,
+			Status:        string(model.AttendancePresent),
+			NotedBy:       device.CreatedBy,
+		}
+
+		err := s.attendanceService.Mark(ctx, session, club, attendance)
+		switch {
+		case err == nil:
+			return checkInResult(student, session, attendance), nil
+		case errors.Is(err, ErrAlreadyMarked):
+			// Concurrent scan of the same code
+			return checkInResult(student, session, nil), nil
+		case errors.Is(err, ErrNoCredit):
+			lastErr = err
+		default:
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// CheckInBatch replays scans a kiosk collected while offline, oldest
+// first. Each scan is checked in on its own so one failure does not block
+// the rest.
+func (s *CheckInService) CheckInBatch(ctx context.Context, device *model.KioskDevice, scans []Scan) []ScanResult {
+	now := time.Now()
+	order := make([]int, len(scans))
+	for i := range order {
+		order[i] = i
+		if scans[i].ScannedAt.IsZero() {
+			scans[i].ScannedAt = now
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scans[order[a]].ScannedAt.Before(scans[order[b]].ScannedAt)
+	})
+
+	results := make([]ScanResult, len(scans))
+	for _, i := range order {
+		scan := scans[i]
+		results[i].ID = scan.ID
+
+		if err := ValidateScanTime(scan.ScannedAt, now); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		result, err := s.CheckIn(ctx, device, scan.Token, scan.ScannedAt)
+		if err != nil {
+			results[i].Error = checkInErrorMessage(err)
+			continue
+		}
+		results[i].Result = result
+	}
+	return results
+}
+
+// ValidateScanTime rejects scans from the future or older than MaxOfflineScanAge
+func ValidateScanTime(scannedAt, now time.Time) error {
+	if scannedAt.After(now.Add(maxClockSkew)) || scannedAt.Before(now.Add(-MaxOfflineScanAge)) {
+		return ErrInvalidScanTime
+	}
+	return nil
+}
+
+func checkInResult(student *model.Student, session *model.Session, attendance *model.Attendance) *CheckInResult {
+	return &CheckInResult{
+		StudentID:        student.ID,
+		StudentName:      student.Name,
+		SessionID:        session.ID,
+		StartAt:          session.StartAt,
+		AlreadyCheckedIn: attendance == nil,
+		Attendance:       attendance,
+	}
+}
+
+// checkInErrorMessage keeps internal errors out of kiosk responses
+func checkInErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, checkin.ErrInvalidToken),
+		errors.Is(err, ErrUnknownStudent),
+		errors.Is(err, ErrNoSessionNow),
+		errors.Is(err, ErrNoCredit):
+		return err.Error()
+	default:
+		return "check-in failed"
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+// CheckConflicts reports where the given sessions of a group would
+// double-book its coach or location against the stored schedule or against
+// each other. Stored sessions passed in (non-zero ID) are not compared with
+// themselves.
+func (s *ScheduleService) CheckConflicts(ctx context.Context, group *model.Group, sessions []model.Session) ([]schedule.Conflict, error) {
+	return s.checkConflicts(ctx, group, sessions, nil)
+}
+
+// checkConflicts is CheckConflicts ignoring the stored sessions for which
+// replaced returns true
+func (s *ScheduleService) checkConflicts(ctx context.Context, group *model.Group, sessions []model.Session, replaced func(repository.SessionSlot) bool) ([]schedule.Conflict, error) {
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]schedule.Slot, len(sessions))
+	from, to := sessions[0].StartAt, sessions[0].StartAt
+	for i, session := range sessions {
+		candidates[i] = schedule.Slot{
+			SessionID:       session.ID,
+			GroupID:         group.ID,
+			ClubID:          group.ClubID,
+			GroupTitle:      group.Title,
+			CoachUserID:     group.CoachUserID,
+			Location:        session.Location,
+			LocationID:      schedule.EffectiveLocationID(session.LocationID, group.LocationID),
+			StartAt:         session.StartAt,
+			DurationMinutes: session.DurationMinutes,
+		}
+		if session.StartAt.Before(from) {
+			from = session.StartAt
+		}
+		if end := candidates[i].EndAt(); end.After(to) {
+			to = end
+		}
+	}
+
+	var coaches []uuid.UUID
+	if group.CoachUserID != nil {
+		coaches = append(coaches, *group.CoachUserID)
+	}
+
+	existing, err := s.sessionRepo.GetSlots(ctx, group.ClubID, coaches, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if replaced != nil {
+		kept := existing[:0]
+		for _, slot := range existing {
+			if !replaced(slot) {
+				kept = append(kept, slot)
+			}
+		}
+		existing = kept
+	}
+
+	conflicts := schedule.FindConflicts(candidates, toSlots(existing))
+	return append(conflicts, schedule.FindOverlaps(candidates)...), nil
+}
+
+// SessionConflicts checks an edit of a stored session for conflicts. With
+// scope following or all the edited series is checked instead.
+func (s *ScheduleService) SessionConflicts(ctx context.Context, group *model.Group, session *model.Session, changes SessionChanges, scope model.EditScope) ([]schedule.Conflict, error) {
+	if session.SeriesID == nil || scope == "" || scope == model.EditThis {
+		edited := *session
+		changes.ApplyTo(&edited)
+		return s.CheckConflicts(ctx, group, []model.Session{edited})
+	}
+
+	_, edited, err := s.seriesEdit(ctx, session, changes, scope)
+	if err != nil {
+		return nil, err
+	}
+	return s.SeriesConflicts(ctx, edited, time.Time{})
+}
+
+// SeriesConflicts previews the sessions a series would have through the
+// given date (or the rolling horizon when through is zero) and checks them
+// for conflicts. For a stored series only occurrences from today on are
+// checked, and its own sessions in that range are ignored as they are
+// regenerated by the edit.
+func (s *ScheduleService) SeriesConflicts(ctx context.Context, series *model.ScheduleSeries, through time.Time) ([]schedule.Conflict, error) {
+	preview := *series
+	rule, err := s.ValidateSeries(&preview)
+	if err != nil {
+		return nil, err
+	}
+	startTime, err := time.Parse("15:04", preview.StartTime)
+	if err != nil {
+		return nil, ErrInvalidStartTime
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, series.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	club, err := s.clubRepo.GetByID(ctx, group.ClubID)
+	if err != nil {
+		return nil, err
+	}
+	loc := club.Location()
+
+	if through.IsZero() {
+		through = time.Now().In(loc).Add(s.horizon)
+	}
+	if preview.EndsOn != nil && preview.EndsOn.Before(through) {
+		through = *preview.EndsOn
+	}
+
+	from := preview.StartsOn
+	var replaced func(repository.SessionSlot) bool
+	if series.ID != uuid.Nil {
+		if today := schedule.Today(loc); today.After(from) {
+			from = today
+		}
+		fromDate := from.Format(schedule.DateLayout)
+		replaced = func(slot repository.SessionSlot) bool {
+			return slot.SeriesID != nil && *slot.SeriesID == series.ID &&
+				slot.StartAt.In(loc).Format(schedule.DateLayout) >= fromDate
+		}
+	}
+
+	sessions := seriesSessions(&preview, rule, startTime, loc, from, through)
+	return s.checkConflicts(ctx, group, sessions, replaced)
+}
+
+// AuditConflicts lists every double-booking in a club's schedule between
+// from and to, including clashes with other clubs its coaches work for
+func (s *ScheduleService) AuditConflicts(ctx context.Context, clubID uuid.UUID, from, to time.Time) ([]schedule.Conflict, error) {
+	groups, err := s.groupRepo.GetByClub(ctx, clubID, repository.ActiveOnly, repository.AttributeFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	clubGroups := make(map[uuid.UUID]bool, len(groups))
+	var coaches []uuid.UUID
+	for _, g := range groups {
+		clubGroups[g.ID] = true
+		if g.CoachUserID != nil {
+			coaches = append(coaches, *g.CoachUserID)
+		}
+	}
+
+	slots, err := s.sessionRepo.GetSlots(ctx, clubID, coaches, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only report clashes that involve this club's sessions
+	conflicts := []schedule.Conflict{}
+	for _, c := range schedule.FindOverlaps(toSlots(slots)) {
+		if clubGroups[c.Slot.GroupID] || clubGroups[c.With.GroupID] {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts, nil
+}
+
+func toSlots(rows []repository.SessionSlot) []schedule.Slot {
+	slots := make([]schedule.Slot, len(rows))
+	for i, r := range rows {
+		slots[i] = schedule.Slot{
+			SessionID:       r.SessionID,
+			GroupID:         r.GroupID,
+			ClubID:          r.ClubID,
+			GroupTitle:      r.GroupTitle,
+			CoachUserID:     r.CoachUserID,
+			Location:        r.Location,
+			LocationID:      r.LocationID,
+			StartAt:         r.StartAt,
+			DurationMinutes: r.DurationMinutes,
+		}
+	}
+	return slots
+}
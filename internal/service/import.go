@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/importer"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/validator"
+)
+
+// importProgressEvery is how often, in rows, the import worker reports
+// progress
+const importProgressEvery = 250
+
+// ImportService validates and commits uploaded student imports. Both run
+// in the background: requests only queue the import and clients poll its
+// progress.
+type ImportService struct {
+	importRepo  *repository.ImportRepository
+	studentRepo *repository.StudentRepository
+	subRepo     *repository.SubscriptionRepository
+	groupRepo   *repository.GroupRepository
+	fieldRepo   *repository.CustomFieldRepository
+	validator   *validator.Validator
+	logger      *slog.Logger
+}
+
+func NewImportService(
+	importRepo *repository.ImportRepository,
+	studentRepo *repository.StudentRepository,
+	subRepo *repository.SubscriptionRepository,
+	groupRepo *repository.GroupRepository,
+	fieldRepo *repository.CustomFieldRepository,
+	validator *validator.Validator,
+	logger *slog.Logger,
+) *ImportService {
+	return &ImportService{
+		importRepo:  importRepo,
+		studentRepo: studentRepo,
+		subRepo:     subRepo,
+		groupRepo:   groupRepo,
+		fieldRepo:   fieldRepo,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// Run processes queued imports until ctx is done, checking for new ones
+// every interval
+func (s *ImportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := s.RunNext(ctx)
+		if err != nil {
+			s.logger.Error("failed to process import", slog.String("error", err.Error()))
+		}
+		if processed && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext validates or commits the next queued import. It reports whether
+// there was one; an import that cannot be processed is marked failed.
+func (s *ImportService) RunNext(ctx context.Context) (bool, error) {
+	imp, err := s.importRepo.ClaimNext(ctx)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch model.ImportStatus(imp.Status) {
+	case model.ImportValidating:
+		err = s.validate(ctx, imp)
+	case model.ImportCommitting:
+		err = s.commit(ctx, imp)
+	}
+	if err != nil {
+		if failErr := s.importRepo.Fail(ctx, imp.ID, "import failed, validate it again"); failErr != nil {
+			return true, failErr
+		}
+		return true, fmt.Errorf("import %s: %w", imp.ID, err)
+	}
+
+	s.logger.Info("processed import",
+		slog.String("import_id", imp.ID.String()),
+		slog.String("status", imp.Status),
+	)
+	return true, nil
+}
+
+// checkedRow is a row with the record read from it
+type checkedRow struct {
+	model.ImportRow
+	record importer.Record
+	group  *model.Group
+	tags   []string
+	custom model.CustomValues
+}
+
+// check reads every row through the import's mapping and flags invalid
+// rows, rows repeating an earlier row and rows matching existing students
+func (s *ImportService) check(ctx context.Context, imp *model.Import) ([]checkedRow, error) {
+	rows, err := s.importRepo.GetAllRows(ctx, imp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	students, err := s.studentRepo.GetAllByClub(ctx, imp.ClubID)
+	if err != nil {
+		return nil, err
+	}
+	existing := importer.NewMatcher()
+	for _, st := range students {
+		p := importer.Person{StudentID: st.ID, Name: st.Name, BirthDate: st.BirthDate}
+		if st.ParentContact != nil {
+			p.Phone = st.ParentContact.Phone
+		}
+		existing.Add(p)
+	}
+
+	groups, err := s.groupRepo.GetByClub(ctx, imp.ClubID, repository.ActiveOnly, repository.AttributeFilter{})
+	if err != nil {
+		return nil, err
+	}
+	groupsByTitle := make(map[string]*model.Group, len(groups))
+	for i := range groups {
+		groupsByTitle[importer.NormalizeName(groups[i].Title)] = &groups[i]
+	}
+
+	fields, err := s.fieldRepo.GetByClub(ctx, imp.ClubID, model.CustomFieldStudent)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := importer.Mapping(imp.Mapping)
+	seen := importer.NewMatcher()
+	now := time.Now()
+
+	checked := make([]checkedRow, len(rows))
+	for i, row := range rows {
+		c := checkedRow{ImportRow: row}
+		c.DuplicateOf, c.StudentID, c.SubscriptionID = nil, nil, nil
+
+		var errs []string
+		c.record, errs = mapping.Record(imp.Headers, row.Data, imp.Options.CreateSubscriptions, now)
+		if c.record.HasSubscription() {
+			if c.group = groupsByTitle[importer.NormalizeName(c.record.Group)]; c.group == nil {
+				errs = append(errs, fmt.Sprintf("%s: no group %q in the club", importer.FieldGroup, c.record.Group))
+			}
+		}
+		if c.tags, err = s.validator.Tags(c.record.Tags); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", importer.FieldTags, err))
+		}
+		if len(c.record.CustomFields) > 0 {
+			if c.custom, err = s.validator.CustomFields(fields, importedCustomValues(fields, c.record.CustomFields)); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		person := importer.Person{
+			Row:       row.RowNumber,
+			Name:      c.record.StudentName,
+			BirthDate: c.record.BirthDate,
+			Phone:     c.record.ParentPhone,
+		}
+		if len(errs) == 0 {
+			if earlier, ok := seen.Find(person); ok {
+				errs = append(errs, fmt.Sprintf("same student as row %d", earlier.Row))
+			} else {
+				seen.Add(person)
+			}
+		}
+
+		switch {
+		case len(errs) > 0:
+			c.Status = string(model.ImportRowInvalid)
+		default:
+			c.Status = string(model.ImportRowValid)
+			if match, ok := existing.Find(person); ok {
+				c.Status = string(model.ImportRowDuplicate)
+				c.DuplicateOf = &match.StudentID
+			}
+		}
+		c.Errors = errs
+		checked[i] = c
+
+		if (i+1)%importProgressEvery == 0 {
+			if err := s.importRepo.SetProgress(ctx, imp.ID, i+1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return checked, nil
+}
+
+// validate is the dry run: it stores each row's problems and duplicate
+// and counts them, without creating anything
+func (s *ImportService) validate(ctx context.Context, imp *model.Import) error {
+	checked, err := s.check(ctx, imp)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]model.ImportRow, len(checked))
+	imp.ValidRows, imp.InvalidRows, imp.DuplicateRows = 0, 0, 0
+	for i, c := range checked {
+		rows[i] = c.ImportRow
+		switch model.ImportRowStatus(c.Status) {
+		case model.ImportRowValid:
+			imp.ValidRows++
+		case model.ImportRowInvalid:
+			imp.InvalidRows++
+		case model.ImportRowDuplicate:
+			imp.DuplicateRows++
+		}
+	}
+
+	if err := s.importRepo.SaveResults(ctx, imp.ID, rows); err != nil {
+		return err
+	}
+	return s.importRepo.FinishValidation(ctx, imp)
+}
+
+// commit checks the rows again, since students may have been added since
+// the dry run, and creates the students and subscriptions of valid rows in
+// one transaction. Invalid rows are left out; duplicates are handled as
+// the import's options say.
+func (s *ImportService) commit(ctx context.Context, imp *model.Import) error {
+	checked, err := s.check(ctx, imp)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.importRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	onDuplicate := model.DuplicateAction(imp.Options.OnDuplicate)
+	imp.CreatedStudents, imp.CreatedSubscriptions = 0, 0
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	rows := make([]model.ImportRow, len(checked))
+	for i := range checked {
+		c := &checked[i]
+
+		var studentID uuid.UUID
+		switch {
+		case c.Status == string(model.ImportRowInvalid):
+		case c.Status == string(model.ImportRowDuplicate) && onDuplicate == model.DuplicateExisting:
+			studentID = *c.DuplicateOf
+			c.Status = string(model.ImportRowSkipped)
+			if c.group != nil {
+				c.Status = string(model.ImportRowLinked)
+			}
+		case c.Status == string(model.ImportRowDuplicate) && onDuplicate != model.DuplicateCreate:
+			c.Status = string(model.ImportRowSkipped)
+		default:
+			student := newImportedStudent(imp.ClubID, &c.record)
+			student.Tags, student.CustomFields = c.tags, c.custom
+			if err := s.studentRepo.CreateInTx(ctx, tx, student); err != nil {
+				return err
+			}
+			studentID = student.ID
+			c.Status = string(model.ImportRowCreated)
+			imp.CreatedStudents++
+		}
+
+		if studentID != uuid.Nil {
+			c.StudentID = &studentID
+		}
+		if studentID != uuid.Nil && c.group != nil {
+			sub := newImportedSubscription(studentID, c.group, &c.record, today)
+			if err := s.subRepo.CreateInTx(ctx, tx, sub); err != nil {
+				return err
+			}
+			c.SubscriptionID = &sub.ID
+			imp.CreatedSubscriptions++
+		}
+		rows[i] = c.ImportRow
+
+		if (i+1)%importProgressEvery == 0 {
+			if err := s.importRepo.SetProgress(ctx, imp.ID, i+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.importRepo.SaveResultsInTx(ctx, tx, imp.ID, rows); err != nil {
+		return err
+	}
+	if err := s.importRepo.CompleteInTx(ctx, tx, imp); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func newImportedStudent(clubID uuid.UUID, rec *importer.Record) *model.Student {
+	student := &model.Student{
+		ClubID:    clubID,
+		Name:      rec.StudentName,
+		BirthDate: rec.BirthDate,
+		Notes:     rec.Notes,
+	}
+	if rec.ParentName != "" || rec.ParentPhone != "" || rec.ParentEmail != "" {
+		student.ParentContact = &model.ParentContact{
+			Name:  rec.ParentName,
+			Phone: rec.ParentPhone,
+			Email: rec.ParentEmail,
+		}
+	}
+	return student
+}
+
+// importedCustomValues reads the custom field cells of a row for the
+// validator. Dates are accepted in the same layouts as the other columns.
+func importedCustomValues(fields []model.CustomField, cells map[string]string) map[string]interface{} {
+	types := make(map[string]string, len(fields))
+	for _, f := range fields {
+		types[f.Key] = f.Type
+	}
+
+	values := make(map[string]interface{}, len(cells))
+	for key, cell := range cells {
+		values[key] = cell
+		if types[key] == string(model.CustomFieldDate) {
+			if d, err := importer.ParseDate(cell); err == nil {
+				values[key] = d.Format("2006-01-02")
+			}
+		}
+	}
+	return values
+}
+
+// newImportedSubscription carries over a subscription bought before the
+// club moved here. Unpaid ones stay pending so they show up as debt.
+func newImportedSubscription(studentID uuid.UUID, group *model.Group, rec *importer.Record, today time.Time) *model.Subscription {
+	price := group.Price
+	if rec.Price != nil {
+		price = *rec.Price
+	}
+
+	status := model.SubscriptionActive
+	switch {
+	case !rec.Paid:
+		status = model.SubscriptionPending
+	case rec.RemainingSessions == 0:
+		status = model.SubscriptionUsed
+	case rec.ExpiresAt != nil && rec.ExpiresAt.Before(today):
+		status = model.SubscriptionExpired
+	}
+
+	return &model.Subscription{
+		StudentID:         studentID,
+		GroupID:           group.ID,
+		TotalSessions:     rec.TotalSessions,
+		RemainingSessions: rec.RemainingSessions,
+		Price:             price,
+		StartsAt:          rec.StartsAt,
+		ExpiresAt:         rec.ExpiresAt,
+		Status:            string(status),
+		Kind:              string(model.SubscriptionPackage),
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/dedupe"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+var (
+	ErrSameStudent    = errors.New("cannot merge a student into itself")
+	ErrDifferentClubs = errors.New("students belong to different clubs")
+)
+
+// MergeService finds duplicate students and merges them
+type MergeService struct {
+	mergeRepo      *repository.StudentMergeRepository
+	studentRepo    *repository.StudentRepository
+	attendanceRepo *repository.AttendanceRepository
+	attendance     *AttendanceService
+}
+
+func NewMergeService(
+	mergeRepo *repository.StudentMergeRepository,
+	studentRepo *repository.StudentRepository,
+	attendanceRepo *repository.AttendanceRepository,
+	attendance *AttendanceService,
+) *MergeService {
+	return &MergeService{
+		mergeRepo:      mergeRepo,
+		studentRepo:    studentRepo,
+		attendanceRepo: attendanceRepo,
+		attendance:     attendance,
+	}
+}
+
+// FindDuplicates returns the likely duplicate students of a club
+func (s *MergeService) FindDuplicates(ctx context.Context, clubID uuid.UUID) ([]dedupe.Pair, map[uuid.UUID]model.Student, error) {
+	students, err := s.studentRepo.GetAllByClub(ctx, clubID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]model.Student, len(students))
+	candidates := make([]dedupe.Student, len(students))
+	for i, st := range students {
+		byID[st.ID] = st
+		candidates[i] = dedupe.Student{ID: st.ID, Name: st.Name, BirthDate: st.BirthDate, CreatedAt: st.CreatedAt}
+		if st.ParentContact != nil {
+			candidates[i].Phone = st.ParentContact.Phone
+			candidates[i].Email = st.ParentContact.Email
+		}
+	}
+	return dedupe.Find(candidates), byID, nil
+}
+
+// Merge moves the subscriptions (with their payments), attendance and
+// makeup credits of the duplicate to the survivor and deletes the
+// duplicate, in one transaction. Where both attended the same session the
+// survivor's attendance is kept and the duplicate's is undone, giving back
+// what it was charged. Blank details of the survivor are filled in from
+// the duplicate. The merge is recorded with a copy of the deleted student.
+func (s *MergeService) Merge(ctx context.Context, survivorID, duplicateID, mergedBy uuid.UUID) (*model.StudentMerge, error) {
+	if survivorID == duplicateID {
+		return nil, ErrSameStudent
+	}
+
+	tx, err := s.mergeRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock in a fixed order so two merges of the same pair cannot deadlock
+	first, second := survivorID, duplicateID
+	if second.String() < first.String() {
+		first, second = second, first
+	}
+	locked := make(map[uuid.UUID]*model.Student, 2)
+	for _, id := range []uuid.UUID{first, second} {
+		st, err := s.studentRepo.GetForUpdateInTx(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		locked[id] = st
+	}
+	survivor, duplicate := locked[survivorID], locked[duplicateID]
+	if survivor.ClubID != duplicate.ClubID {
+		return nil, ErrDifferentClubs
+	}
+
+	merge := &model.StudentMerge{
+		ClubID:          survivor.ClubID,
+		SurvivorID:      &survivor.ID,
+		MergedStudentID: duplicate.ID,
+		MergedStudent:   *duplicate,
+		MergedBy:        &mergedBy,
+	}
+
+	shared, err := s.attendanceRepo.GetSharedSessionsForUpdate(ctx, tx, duplicate.ID, survivor.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		if err := s.attendance.RemoveInTx(ctx, tx, &shared[i]); err != nil {
+			return nil, err
+		}
+	}
+	merge.RemovedAttendances = len(shared)
+
+	if err := s.mergeRepo.MoveStudentInTx(ctx, tx, duplicate.ID, survivor.ID, merge); err != nil {
+		return nil, err
+	}
+
+	fillBlanks(survivor, duplicate)
+	if err := s.studentRepo.UpdateInTx(ctx, tx, survivor); err != nil {
+		return nil, err
+	}
+	if err := s.studentRepo.DeleteInTx(ctx, tx, duplicate.ID); err != nil {
+		return nil, err
+	}
+	if err := s.mergeRepo.CreateInTx(ctx, tx, merge); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// fillBlanks copies what the survivor is missing from the duplicate. Notes
+// and tags of both are kept.
+func fillBlanks(survivor, duplicate *model.Student) {
+	if survivor.BirthDate == nil {
+		survivor.BirthDate = duplicate.BirthDate
+	}
+
+	if duplicate.Notes != "" && duplicate.Notes != survivor.Notes {
+		if survivor.Notes == "" {
+			survivor.Notes = duplicate.Notes
+		} else {
+			survivor.Notes += "\n" + duplicate.Notes
+		}
+	}
+
+	for _, tag := range duplicate.Tags {
+		if !slices.Contains(survivor.Tags, tag) {
+			survivor.Tags = append(survivor.Tags, tag)
+		}
+	}
+	survivor.CustomFields = duplicate.CustomFields.Apply(survivor.CustomFields)
+
+	from := duplicate.ParentContact
+	if from == nil {
+		return
+	}
+	if survivor.ParentContact == nil {
+		contact := *from
+		survivor.ParentContact = &contact
+		return
+	}
+
+	to := survivor.ParentContact
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&to.Name, from.Name},
+		{&to.Phone, from.Phone},
+		{&to.Email, from.Email},
+		{&to.Language, from.Language},
+		{&to.TelegramChatID, from.TelegramChatID},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	if len(to.Channels) == 0 {
+		to.Channels = from.Channels
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// SessionNotice describes a change to a session for its participants
+type SessionNotice struct {
+	Club            *model.Club
+	Group           *model.Group
+	Session         *model.Session
+	Students        []model.Student
+	Reason          string
+	PreviousStartAt *time.Time // set for reschedules
+}
+
+// SessionNotifier tells participants about cancelled or moved sessions
+type SessionNotifier interface {
+	SessionCancelled(ctx context.Context, notice SessionNotice) error
+	SessionRescheduled(ctx context.Context, notice SessionNotice) error
+}
+
+// PaymentNotice describes a payment received for a subscription
+type PaymentNotice struct {
+	Club         *model.Club
+	Group        *model.Group
+	Student      *model.Student
+	Subscription *model.Subscription
+	Payment      *model.Payment
+}
+
+// PaymentNotifier sends receipts for successful payments
+type PaymentNotifier interface {
+	PaymentSucceeded(ctx context.Context, notice PaymentNotice) error
+}
+
+// SubscriptionNotice describes a change to a student's subscription
+type SubscriptionNotice struct {
+	Club         *model.Club
+	Group        *model.Group
+	Student      *model.Student
+	Subscription *model.Subscription
+}
+
+// SubscriptionNotifier tells guardians their subscription has run out
+type SubscriptionNotifier interface {
+	SubscriptionExpired(ctx context.Context, notice SubscriptionNotice) error
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/payroll"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+var (
+	ErrPayrollApproved = errors.New("payroll run is approved and can no longer change")
+	ErrPayrollOverlaps = errors.New("the period overlaps an approved payroll run")
+)
+
+// PayrollService calculates what coaches earn for a period from the
+// sessions they taught, who attended and what was paid for their groups
+type PayrollService struct {
+	payrollRepo *repository.PayrollRepository
+}
+
+func NewPayrollService(payrollRepo *repository.PayrollRepository) *PayrollService {
+	return &PayrollService{payrollRepo: payrollRepo}
+}
+
+// PayrollPeriod returns when the days of a run start and end in the club's
+// time zone
+func PayrollPeriod(run *model.PayrollRun, loc *time.Location) (from, to time.Time) {
+	from = time.Date(run.PeriodStart.Year(), run.PeriodStart.Month(), run.PeriodStart.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(run.PeriodEnd.Year(), run.PeriodEnd.Month(), run.PeriodEnd.Day(), 0, 0, 0, 0, loc)
+	return from, schedule.EndOfDay(end, loc)
+}
+
+// Create calculates a draft run of the club for the days from start to end
+func (s *PayrollService) Create(ctx context.Context, club *model.Club, start, end time.Time, by uuid.UUID) (*model.PayrollRun, error) {
+	tx, err := s.payrollRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run := &model.PayrollRun{ClubID: club.ID, PeriodStart: start, PeriodEnd: end, CreatedBy: &by}
+	if err := s.payrollRepo.CreateRunInTx(ctx, tx, run); err != nil {
+		return nil, err
+	}
+	if err := s.calculate(ctx, tx, club, run); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Recalculate replaces the lines of a draft run, e.g. after attendance was
+// corrected or a rule changed
+func (s *PayrollService) Recalculate(ctx context.Context, club *model.Club, runID uuid.UUID) (*model.PayrollRun, error) {
+	tx, err := s.payrollRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := s.payrollRepo.LockRunInTx(ctx, tx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == string(model.PayrollApproved) {
+		return nil, ErrPayrollApproved
+	}
+	if err := s.calculate(ctx, tx, club, run); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Approve locks a draft run. Approvals of a club are serialised and
+// approved runs cannot overlap, so no session or payment is paid for twice.
+func (s *PayrollService) Approve(ctx context.Context, runID, by uuid.UUID) (*model.PayrollRun, error) {
+	tx, err := s.payrollRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := s.payrollRepo.LockRunInTx(ctx, tx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == string(model.PayrollApproved) {
+		return nil, ErrPayrollApproved
+	}
+
+	// Another approval of the club waits here until this one commits, and
+	// then sees this run as approved
+	if err := s.payrollRepo.LockApprovalsInTx(ctx, tx, run.ClubID); err != nil {
+		return nil, err
+	}
+	overlaps, err := s.payrollRepo.OverlapsApprovedInTx(ctx, tx, run)
+	if err != nil {
+		return nil, err
+	}
+	if overlaps {
+		return nil, ErrPayrollOverlaps
+	}
+
+	if err := s.payrollRepo.ApproveInTx(ctx, tx, run, by); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// calculate works out the lines of a run with the club's current rules
+func (s *PayrollService) calculate(ctx context.Context, tx *sqlx.Tx, club *model.Club, run *model.PayrollRun) error {
+	rules, err := s.payrollRepo.GetRules(ctx, club.ID)
+	if err != nil {
+		return err
+	}
+
+	from, to := PayrollPeriod(run, club.Location())
+	sessionRows, err := s.payrollRepo.PayrollSessionsInTx(ctx, tx, club.ID, from, to)
+	if err != nil {
+		return err
+	}
+	paymentRows, err := s.payrollRepo.PayrollPaymentsInTx(ctx, tx, club.ID, from, to)
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]payroll.Session, len(sessionRows))
+	for i, row := range sessionRows {
+		sessions[i] = payroll.Session{
+			ID:          row.ID,
+			GroupID:     row.GroupID,
+			GroupTitle:  row.GroupTitle,
+			CoachUserID: row.CoachUserID,
+			CoachName:   row.CoachName,
+			StartAt:     row.StartAt,
+			Attendees:   row.Attendees,
+		}
+	}
+	payments := make([]payroll.Payment, len(paymentRows))
+	for i, row := range paymentRows {
+		payments[i] = payroll.Payment{
+			ID:          row.ID,
+			GroupID:     row.GroupID,
+			GroupTitle:  row.GroupTitle,
+			CoachUserID: row.CoachUserID,
+			CoachName:   row.CoachName,
+			PaidAt:      row.PaidAt,
+			Amount:      row.Amount,
+		}
+	}
+
+	items := payroll.Items(payroll.NewRules(rules), sessions, payments)
+	coaches, total := payroll.Totals(items)
+	if err := s.payrollRepo.ReplaceItemsInTx(ctx, tx, run, items, total); err != nil {
+		return err
+	}
+	run.Items = items
+	run.Coaches = coaches
+	return nil
+}
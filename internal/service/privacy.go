@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/privacy"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+var ErrNoPersonalData = errors.New("no students match the request")
+
+// DataSubject is who a personal data request is about: one student, or
+// every student of a club whose parent contact is the guardian's
+type DataSubject struct {
+	ClubID   uuid.UUID
+	Kind     model.DataSubject
+	Students []model.Student
+}
+
+// PrivacyService exports and erases the personal data of students on their
+// guardians' request. Every request is logged, including those that match
+// no students.
+type PrivacyService struct {
+	privacyRepo *repository.PrivacyRepository
+	studentRepo *repository.StudentRepository
+	profileRepo *repository.ProfileRepository
+	storage     storage.Storage
+	logger      *slog.Logger
+}
+
+func NewPrivacyService(
+	privacyRepo *repository.PrivacyRepository,
+	studentRepo *repository.StudentRepository,
+	profileRepo *repository.ProfileRepository,
+	storage storage.Storage,
+	logger *slog.Logger,
+) *PrivacyService {
+	return &PrivacyService{
+		privacyRepo: privacyRepo,
+		studentRepo: studentRepo,
+		profileRepo: profileRepo,
+		storage:     storage,
+		logger:      logger,
+	}
+}
+
+// StudentSubject returns the subject of a request about one student
+func (s *PrivacyService) StudentSubject(ctx context.Context, studentID uuid.UUID) (*DataSubject, error) {
+	student, err := s.studentRepo.GetByID(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &DataSubject{
+		ClubID:   student.ClubID,
+		Kind:     model.SubjectStudent,
+		Students: []model.Student{*student},
+	}, nil
+}
+
+// GuardianSubject returns the subject of a guardian's request: the club's
+// students, archived ones included, whose parent contact has the
+// guardian's email or phone. It may have no students.
+func (s *PrivacyService) GuardianSubject(ctx context.Context, clubID uuid.UUID, guardian privacy.Guardian) (*DataSubject, error) {
+	students, err := s.studentRepo.GetAllByClub(ctx, clubID)
+	if err != nil {
+		return nil, err
+	}
+	return &DataSubject{
+		ClubID:   clubID,
+		Kind:     model.SubjectGuardian,
+		Students: guardian.Match(students),
+	}, nil
+}
+
+// Export logs the request and gathers everything held about the subject's
+// students: their rows with the parent contact, profiles, subscriptions,
+// attendance and payments
+func (s *PrivacyService) Export(ctx context.Context, club *model.Club, subject *DataSubject, requestedBy uuid.UUID) (*privacy.Dossier, error) {
+	req := newDataRequest(model.DataExport, subject, requestedBy)
+	if err := s.privacyRepo.CreateRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	s.logRequest(req)
+
+	if len(req.StudentIDs) == 0 {
+		return nil, ErrNoPersonalData
+	}
+
+	dossier := &privacy.Dossier{
+		Request:     *req,
+		Club:        club.Name,
+		GeneratedAt: time.Now(),
+		Students:    make([]privacy.StudentData, len(subject.Students)),
+	}
+	for i, st := range subject.Students {
+		data, err := s.studentData(ctx, st)
+		if err != nil {
+			return nil, err
+		}
+		dossier.Students[i] = *data
+	}
+
+	var err error
+	if dossier.Subscriptions, err = s.privacyRepo.GetSubscriptions(ctx, req.StudentIDs); err != nil {
+		return nil, err
+	}
+	if dossier.Attendances, err = s.privacyRepo.GetAttendances(ctx, req.StudentIDs); err != nil {
+		return nil, err
+	}
+	if dossier.Payments, err = s.privacyRepo.GetPayments(ctx, req.StudentIDs); err != nil {
+		return nil, err
+	}
+	return dossier, nil
+}
+
+func (s *PrivacyService) studentData(ctx context.Context, student model.Student) (*privacy.StudentData, error) {
+	data := &privacy.StudentData{Student: student}
+
+	medical, err := s.profileRepo.GetMedical(ctx, student.ID)
+	switch {
+	case err == nil:
+		data.Medical = medical
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	if data.MedicalCertificates, err = s.profileRepo.GetCertificates(ctx, student.ID); err != nil {
+		return nil, err
+	}
+	if data.EmergencyContacts, err = s.profileRepo.GetEmergencyContacts(ctx, student.ID); err != nil {
+		return nil, err
+	}
+	if data.Consents, err = s.profileRepo.GetConsents(ctx, student.ID); err != nil {
+		return nil, err
+	}
+	if data.Documents, err = s.profileRepo.GetDocuments(ctx, student.ID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Erase anonymises the subject's students in one transaction, logging the
+// request with it. Their subscriptions, payments and attendance are kept
+// for accounting, without personal details. Document files are removed
+// once the erasure is committed.
+func (s *PrivacyService) Erase(ctx context.Context, subject *DataSubject, requestedBy uuid.UUID) (*model.DataRequest, error) {
+	req := newDataRequest(model.DataErasure, subject, requestedBy)
+	if len(req.StudentIDs) == 0 {
+		if err := s.privacyRepo.CreateRequest(ctx, req); err != nil {
+			return nil, err
+		}
+		s.logRequest(req)
+		return nil, ErrNoPersonalData
+	}
+
+	tx, err := s.privacyRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var keys []string
+	for _, id := range req.StudentIDs {
+		studentKeys, err := s.privacyRepo.EraseStudentInTx(ctx, tx, id, privacy.ErasedName)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, studentKeys...)
+	}
+
+	if err := s.privacyRepo.CreateRequestInTx(ctx, tx, req); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.logRequest(req)
+
+	deleteFiles(ctx, s.storage, s.logger, keys)
+	return req, nil
+}
+
+func newDataRequest(kind model.DataRequestKind, subject *DataSubject, requestedBy uuid.UUID) *model.DataRequest {
+	ids := make([]uuid.UUID, len(subject.Students))
+	for i, st := range subject.Students {
+		ids[i] = st.ID
+	}
+	return &model.DataRequest{
+		ClubID:      subject.ClubID,
+		Kind:        string(kind),
+		Subject:     string(subject.Kind),
+		StudentIDs:  ids,
+		RequestedBy: &requestedBy,
+	}
+}
+
+func (s *PrivacyService) logRequest(req *model.DataRequest) {
+	s.logger.Info("personal data request",
+		slog.String("id", req.ID.String()),
+		slog.String("kind", req.Kind),
+		slog.String("subject", req.Subject),
+		slog.String("club_id", req.ClubID.String()),
+		slog.Int("students", len(req.StudentIDs)),
+	)
+}
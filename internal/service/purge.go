@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/pkg/storage"
+)
+
+var (
+	ErrNotArchived      = errors.New("only archived records can be purged")
+	ErrFinancialHistory = errors.New("subscriptions and payments refer to it and must be kept")
+)
+
+// PurgeService deletes archived students, groups and clubs for good while
+// keeping the financial records
+type PurgeService struct {
+	purgeRepo *repository.PurgeRepository
+	storage   storage.Storage
+	logger    *slog.Logger
+}
+
+func NewPurgeService(purgeRepo *repository.PurgeRepository, storage storage.Storage, logger *slog.Logger) *PurgeService {
+	return &PurgeService{
+		purgeRepo: purgeRepo,
+		storage:   storage,
+		logger:    logger,
+	}
+}
+
+// Purge deletes an archived student, group or club with everything
+// recorded for it except money:
+//   - a student's subscriptions, and their payments, stay without the
+//     student
+//   - a group or club that has subscriptions cannot be purged, as the
+//     subscriptions need it to be counted in reports; it stays archived
+//
+// Document files are removed once the purge is committed. The purge is
+// recorded, without personal data.
+func (s *PurgeService) Purge(ctx context.Context, entity model.PurgeEntity, id, purgedBy uuid.UUID) (*model.Purge, error) {
+	tx, err := s.purgeRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	clubID, archivedAt, err := s.purgeRepo.LockInTx(ctx, tx, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if archivedAt == nil {
+		return nil, ErrNotArchived
+	}
+
+	subscriptions, err := s.purgeRepo.CountSubscriptionsInTx(ctx, tx, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscriptions > 0 && entity != model.PurgeStudent {
+		return nil, ErrFinancialHistory
+	}
+
+	keys, err := s.purgeRepo.DocumentKeysInTx(ctx, tx, entity, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.purgeRepo.DeleteInTx(ctx, tx, entity, id); err != nil {
+		return nil, err
+	}
+
+	purge := &model.Purge{
+		Entity:            string(entity),
+		EntityID:          id,
+		ClubID:            clubID,
+		KeptSubscriptions: subscriptions,
+		PurgedBy:          &purgedBy,
+	}
+	if err := s.purgeRepo.CreateInTx(ctx, tx, purge); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	deleteFiles(ctx, s.storage, s.logger, keys)
+	return purge, nil
+}
+
+// deleteFiles removes the stored documents of rows that are already gone.
+// A file left behind is only logged: the rows cannot be brought back.
+func deleteFiles(ctx context.Context, storage storage.Storage, logger *slog.Logger, keys []string) {
+	for _, key := range keys {
+		if err := storage.Delete(ctx, key); err != nil {
+			logger.Error("failed to delete document", slog.String("key", key), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,669 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/schedule"
+)
+
+var (
+	ErrInvalidStartTime = errors.New("start_time must be HH:MM")
+	ErrSeriesDateChange = errors.New("moving an occurrence to another day is only allowed with scope=this")
+	ErrSessionCancelled = errors.New("session is cancelled")
+	// ErrSessionAttended is returned when deleting a session with marks,
+	// which would lose the sessions and credits they used; cancel it instead
+	ErrSessionAttended = errors.New("session has attendance")
+)
+
+// DefaultScheduleHorizon is how far ahead series sessions are materialised
+const DefaultScheduleHorizon = 56 * 24 * time.Hour
+
+// SessionChanges holds the optional fields of a session edit
+type SessionChanges struct {
+	StartAt         *time.Time
+	DurationMinutes *int
+	Location        *string
+	LocationID      *uuid.UUID
+}
+
+// ScheduleService keeps persisted schedule series and their materialised
+// sessions in sync
+type ScheduleService struct {
+	seriesRepo  *repository.SeriesRepository
+	sessionRepo *repository.SessionRepository
+	groupRepo   *repository.GroupRepository
+	clubRepo    *repository.ClubRepository
+	studentRepo *repository.StudentRepository
+	subRepo     *repository.SubscriptionRepository
+	makeupRepo  *repository.MakeupRepository
+	notifier    SessionNotifier
+	horizon     time.Duration
+	logger      *slog.Logger
+}
+
+func NewScheduleService(
+	seriesRepo *repository.SeriesRepository,
+	sessionRepo *repository.SessionRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	studentRepo *repository.StudentRepository,
+	subRepo *repository.SubscriptionRepository,
+	makeupRepo *repository.MakeupRepository,
+	notifier SessionNotifier,
+	logger *slog.Logger,
+) *ScheduleService {
+	return &ScheduleService{
+		seriesRepo:  seriesRepo,
+		sessionRepo: sessionRepo,
+		groupRepo:   groupRepo,
+		clubRepo:    clubRepo,
+		studentRepo: studentRepo,
+		subRepo:     subRepo,
+		makeupRepo:  makeupRepo,
+		notifier:    notifier,
+		horizon:     DefaultScheduleHorizon,
+		logger:      logger,
+	}
+}
+
+// ValidateSeries checks the rule and start time of a series
+func (s *ScheduleService) ValidateSeries(series *model.ScheduleSeries) (*schedule.Rule, error) {
+	rule, err := schedule.ParseRule(series.RRule)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("15:04", series.StartTime); err != nil {
+		return nil, ErrInvalidStartTime
+	}
+	series.RRule = rule.String()
+	return rule, nil
+}
+
+// CreateSeries stores a new series and materialises its sessions through
+// the given date, or through the rolling horizon when through is zero.
+func (s *ScheduleService) CreateSeries(ctx context.Context, series *model.ScheduleSeries, through time.Time) (int64, error) {
+	rule, err := s.ValidateSeries(series)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := s.groupLocation(ctx, series.GroupID)
+	if err != nil {
+		return 0, err
+	}
+	if through.IsZero() {
+		through = time.Now().In(loc).Add(s.horizon)
+	}
+
+	tx, err := s.seriesRepo.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := s.seriesRepo.CreateInTx(ctx, tx, series); err != nil {
+		return 0, err
+	}
+
+	created, err := s.syncInTx(ctx, tx, series, rule, loc, series.StartsOn, through)
+	if err != nil {
+		return 0, err
+	}
+
+	return created, tx.Commit()
+}
+
+// UpdateSeries applies an edit to the whole series. Occurrences from today
+// on are moved, removed or added to match the new rule; past sessions,
+// edited occurrences and sessions with attendance are kept as they are.
+func (s *ScheduleService) UpdateSeries(ctx context.Context, series *model.ScheduleSeries) (int64, error) {
+	rule, err := s.ValidateSeries(series)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := s.groupLocation(ctx, series.GroupID)
+	if err != nil {
+		return 0, err
+	}
+
+	from := schedule.Today(loc)
+	if series.StartsOn.After(from) {
+		from = series.StartsOn
+	}
+
+	tx, err := s.seriesRepo.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := s.seriesRepo.UpdateInTx(ctx, tx, series); err != nil {
+		return 0, err
+	}
+
+	created, err := s.syncInTx(ctx, tx, series, rule, loc, from, s.resyncThrough(series, loc))
+	if err != nil {
+		return 0, err
+	}
+
+	return created, tx.Commit()
+}
+
+// SplitSeries implements "this and following": the series is ended the day
+// before next.StartsOn and next continues it from that date with its own
+// fields. Remaining occurrences are handed over to next.
+func (s *ScheduleService) SplitSeries(ctx context.Context, series, next *model.ScheduleSeries) (int64, error) {
+	if !next.StartsOn.After(series.StartsOn) {
+		// Splitting at the first occurrence is an edit of the whole series
+		id, createdAt, materialized := series.ID, series.CreatedAt, series.MaterializedUntil
+		*series = *next
+		series.ID, series.CreatedAt, series.MaterializedUntil = id, createdAt, materialized
+		return s.UpdateSeries(ctx, series)
+	}
+
+	oldRule, err := schedule.ParseRule(series.RRule)
+	if err != nil {
+		return 0, err
+	}
+	rule, err := s.ValidateSeries(next)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := s.groupLocation(ctx, series.GroupID)
+	if err != nil {
+		return 0, err
+	}
+
+	// COUNT is relative to the series start; carry over only what is left
+	if oldRule.Count > 0 && rule.Count == oldRule.Count {
+		used := len(oldRule.Dates(series.StartsOn, series.StartsOn, next.StartsOn.AddDate(0, 0, -1)))
+		if used >= rule.Count {
+			return 0, fmt.Errorf("%w: no occurrences left after %s", schedule.ErrInvalidRule, next.StartsOn.Format(schedule.DateLayout))
+		}
+		rule.Count -= used
+		next.RRule = rule.String()
+	}
+
+	through := s.resyncThrough(series, loc)
+	endsOn := next.StartsOn.AddDate(0, 0, -1)
+	series.EndsOn = &endsOn
+	if series.MaterializedUntil != nil && series.MaterializedUntil.After(endsOn) {
+		series.MaterializedUntil = &endsOn
+	}
+	next.GroupID = series.GroupID
+
+	tx, err := s.seriesRepo.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := s.seriesRepo.UpdateInTx(ctx, tx, series); err != nil {
+		return 0, err
+	}
+	if err := s.seriesRepo.CreateInTx(ctx, tx, next); err != nil {
+		return 0, err
+	}
+	if err := s.sessionRepo.ReassignSeriesInTx(ctx, tx, series.ID, next.ID, next.StartsOn); err != nil {
+		return 0, err
+	}
+
+	created, err := s.syncInTx(ctx, tx, next, rule, loc, next.StartsOn, through)
+	if err != nil {
+		return 0, err
+	}
+
+	return created, tx.Commit()
+}
+
+// DeleteSeries removes future generated sessions and the series itself.
+// Past sessions, edited occurrences and attended sessions stay and are
+// detached from the series.
+func (s *ScheduleService) DeleteSeries(ctx context.Context, series *model.ScheduleSeries) error {
+	loc, err := s.groupLocation(ctx, series.GroupID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.seriesRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.sessionRepo.DeleteStaleOccurrencesInTx(ctx, tx, series.ID, schedule.Today(loc), []string{}); err != nil {
+		return err
+	}
+	if err := s.seriesRepo.DeleteInTx(ctx, tx, series.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateSession edits a session. For sessions generated from a series the
+// scope decides whether only this occurrence, this and the following ones,
+// or the whole series change.
+//
+// Moving a single session keeps its ID and notifies its participants.
+func (s *ScheduleService) UpdateSession(ctx context.Context, session *model.Session, changes SessionChanges, scope model.EditScope) (*model.Session, error) {
+	if session.IsCancelled() {
+		return nil, ErrSessionCancelled
+	}
+
+	if session.SeriesID == nil || scope == "" || scope == model.EditThis {
+		previous := session.StartAt
+		moved := changes.StartAt != nil && !changes.StartAt.Equal(previous)
+
+		changes.ApplyTo(session)
+		if moved && session.RescheduledFrom == nil {
+			// Keep the originally planned time for history
+			session.RescheduledFrom = &previous
+		}
+		if session.SeriesID != nil {
+			// Keep the edit when the series is regenerated
+			session.IsException = true
+		}
+		if err := s.sessionRepo.Update(ctx, session); err != nil {
+			return nil, err
+		}
+
+		if moved && (previous.After(time.Now()) || session.StartAt.After(time.Now())) {
+			s.notify(ctx, session, func(notice SessionNotice) error {
+				notice.PreviousStartAt = &previous
+				return s.notifier.SessionRescheduled(ctx, notice)
+			})
+		}
+		return session, nil
+	}
+
+	series, edited, err := s.seriesEdit(ctx, session, changes, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope == model.EditAll {
+		*series = *edited
+		_, err = s.UpdateSeries(ctx, series)
+	} else {
+		_, err = s.SplitSeries(ctx, series, edited)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.IsException {
+		return s.sessionRepo.GetByID(ctx, session.ID)
+	}
+
+	// Regenerating the series skips exceptions, so the edited occurrence
+	// takes the change itself
+	updated, err := s.sessionRepo.GetByID(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	changes.ApplyTo(updated)
+	if err := s.sessionRepo.Update(ctx, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// seriesEdit loads the series of an occurrence and returns it together with
+// a copy carrying the change. With EditFollowing the copy starts on the
+// occurrence date.
+func (s *ScheduleService) seriesEdit(ctx context.Context, session *model.Session, changes SessionChanges, scope model.EditScope) (*model.ScheduleSeries, *model.ScheduleSeries, error) {
+	if scope != model.EditAll && scope != model.EditFollowing {
+		return nil, nil, fmt.Errorf("unknown scope %q", scope)
+	}
+
+	series, err := s.seriesRepo.GetByID(ctx, *session.SeriesID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := s.groupLocation(ctx, series.GroupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	occurrence := session.StartAt.In(loc)
+	if session.OccurrenceDate != nil {
+		occurrence = *session.OccurrenceDate
+	}
+
+	edited := *series
+	if changes.StartAt != nil {
+		local := changes.StartAt.In(loc)
+		if local.Format(schedule.DateLayout) != occurrence.Format(schedule.DateLayout) {
+			return nil, nil, ErrSeriesDateChange
+		}
+		edited.StartTime = local.Format("15:04")
+	}
+	if changes.DurationMinutes != nil {
+		edited.DurationMinutes = *changes.DurationMinutes
+	}
+	if changes.Location != nil {
+		edited.Location = *changes.Location
+	}
+	if changes.LocationID != nil {
+		edited.LocationID = changes.LocationID
+	}
+	if scope == model.EditFollowing {
+		edited.StartsOn = time.Date(occurrence.Year(), occurrence.Month(), occurrence.Day(), 0, 0, 0, 0, time.UTC)
+		edited.MaterializedUntil = nil
+	}
+	return series, &edited, nil
+}
+
+// CancelSession calls a session off while keeping it for history. Credits
+// already taken from subscriptions for it are returned, and students with an
+// active subscription in the group are notified about upcoming sessions.
+func (s *ScheduleService) CancelSession(ctx context.Context, session *model.Session, reason string) (*model.Session, error) {
+	if session.IsCancelled() {
+		return nil, ErrSessionCancelled
+	}
+
+	tx, err := s.sessionRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.subRepo.RefundSessionInTx(ctx, tx, session.ID, dropInValidUntil()); err != nil {
+		return nil, err
+	}
+	if err := s.makeupRepo.RevertSessionInTx(ctx, tx, session.ID); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.CancelInTx(ctx, tx, session.ID, reason); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// Cancelled concurrently
+			return nil, ErrSessionCancelled
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.sessionRepo.GetByID(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cancelled.StartAt.After(time.Now()) {
+		s.notify(ctx, cancelled, func(notice SessionNotice) error {
+			notice.Reason = reason
+			return s.notifier.SessionCancelled(ctx, notice)
+		})
+	}
+	return cancelled, nil
+}
+
+// DeleteSession deletes a single session that nobody is marked for.
+// Occurrences of a series are recorded as exceptions so they are not
+// generated again.
+func (s *ScheduleService) DeleteSession(ctx context.Context, session *model.Session) error {
+	tx, err := s.sessionRepo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Marks cascade with the session, taking charged sessions and makeup
+	// credits with them, so like series sync only unattended ones go
+	attended, err := s.sessionRepo.HasAttendanceInTx(ctx, tx, session.ID)
+	if err != nil {
+		return err
+	}
+	if attended {
+		return ErrSessionAttended
+	}
+
+	if session.SeriesID != nil && session.OccurrenceDate != nil {
+		if err := s.seriesRepo.AddExDateInTx(ctx, tx, *session.SeriesID, *session.OccurrenceDate); err != nil {
+			return err
+		}
+	}
+	if err := s.sessionRepo.DeleteInTx(ctx, tx, session.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MaterializeDue extends every series to the rolling horizon
+func (s *ScheduleService) MaterializeDue(ctx context.Context) (int64, error) {
+	due, err := s.seriesRepo.GetDueForMaterialization(ctx, time.Now().Add(s.horizon))
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for i := range due {
+		series := &due[i]
+		rule, err := schedule.ParseRule(series.RRule)
+		if err != nil {
+			s.logMaterializeError(series, err)
+			continue
+		}
+		loc, err := s.groupLocation(ctx, series.GroupID)
+		if err != nil {
+			s.logMaterializeError(series, err)
+			continue
+		}
+
+		from := series.StartsOn
+		if series.MaterializedUntil != nil {
+			from = series.MaterializedUntil.AddDate(0, 0, 1)
+		}
+
+		created, err := s.materialize(ctx, series, rule, loc, from)
+		if err != nil {
+			s.logMaterializeError(series, err)
+			continue
+		}
+		total += created
+	}
+	return total, nil
+}
+
+// materialize extends one series from the given day to the horizon
+func (s *ScheduleService) materialize(ctx context.Context, series *model.ScheduleSeries, rule *schedule.Rule, loc *time.Location, from time.Time) (int64, error) {
+	tx, err := s.seriesRepo.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	created, err := s.syncInTx(ctx, tx, series, rule, loc, from, time.Now().In(loc).Add(s.horizon))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// logMaterializeError reports a series that is skipped by MaterializeDue;
+// the others are still extended
+func (s *ScheduleService) logMaterializeError(series *model.ScheduleSeries, err error) {
+	s.logger.Error("failed to materialize schedule series",
+		slog.String("series_id", series.ID.String()),
+		slog.String("error", err.Error()),
+	)
+}
+
+// RunMaterializer calls MaterializeDue every interval until ctx is done
+func (s *ScheduleService) RunMaterializer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := s.MaterializeDue(ctx)
+		if err != nil {
+			s.logger.Error("failed to materialize schedule series", slog.String("error", err.Error()))
+		} else if created > 0 {
+			s.logger.Info("materialized series sessions", slog.Int64("created", created))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncInTx makes the series' sessions in [from, through] match the rule:
+// stale occurrences are removed, existing ones moved to the series' time,
+// and missing ones inserted.
+func (s *ScheduleService) syncInTx(ctx context.Context, tx *sqlx.Tx, series *model.ScheduleSeries, rule *schedule.Rule, loc *time.Location, from, through time.Time) (int64, error) {
+	startTime, err := time.Parse("15:04", series.StartTime)
+	if err != nil {
+		return 0, ErrInvalidStartTime
+	}
+	if series.EndsOn != nil && series.EndsOn.Before(through) {
+		through = *series.EndsOn
+	}
+
+	sessions := seriesSessions(series, rule, startTime, loc, from, through)
+	keep := make([]string, len(sessions))
+	for i, session := range sessions {
+		keep[i] = session.OccurrenceDate.Format(schedule.DateLayout)
+	}
+
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	if _, err := s.sessionRepo.DeleteStaleOccurrencesInTx(ctx, tx, series.ID, fromDate, keep); err != nil {
+		return 0, err
+	}
+	if _, err := s.sessionRepo.RescheduleOccurrencesInTx(ctx, tx, series.ID, fromDate, series.StartTime, series.DurationMinutes, series.Location, series.LocationID, loc.String()); err != nil {
+		return 0, err
+	}
+	created, err := s.sessionRepo.InsertOccurrencesInTx(ctx, tx, sessions)
+	if err != nil {
+		return 0, err
+	}
+
+	until := time.Date(through.Year(), through.Month(), through.Day(), 0, 0, 0, 0, time.UTC)
+	if series.MaterializedUntil == nil || until.After(*series.MaterializedUntil) {
+		if err := s.seriesRepo.SetMaterializedUntilInTx(ctx, tx, series.ID, until); err != nil {
+			return 0, err
+		}
+		series.MaterializedUntil = &until
+	}
+	return created, nil
+}
+
+// seriesSessions builds the sessions of a series in [from, through],
+// skipping excluded dates
+func seriesSessions(series *model.ScheduleSeries, rule *schedule.Rule, startTime time.Time, loc *time.Location, from, through time.Time) []model.Session {
+	excluded := make(map[string]bool, len(series.ExDates))
+	for _, d := range series.ExDates {
+		excluded[d] = true
+	}
+
+	var sessions []model.Session
+	for _, d := range rule.Dates(series.StartsOn, from, through) {
+		if excluded[d.Format(schedule.DateLayout)] {
+			continue
+		}
+
+		occurrence := d
+		sessions = append(sessions, model.Session{
+			GroupID:         series.GroupID,
+			StartAt:         schedule.At(d, startTime.Hour(), startTime.Minute(), loc),
+			DurationMinutes: series.DurationMinutes,
+			Location:        series.Location,
+			LocationID:      series.LocationID,
+			SeriesID:        &series.ID,
+			OccurrenceDate:  &occurrence,
+		})
+	}
+	return sessions
+}
+
+// resyncThrough is the last date to regenerate on an edit: at least the
+// rolling horizon, and never less than what was already materialised
+func (s *ScheduleService) resyncThrough(series *model.ScheduleSeries, loc *time.Location) time.Time {
+	through := time.Now().In(loc).Add(s.horizon)
+	if series.MaterializedUntil != nil && series.MaterializedUntil.After(through) {
+		through = *series.MaterializedUntil
+	}
+	return through
+}
+
+// notify sends a session notice to the students expected in it. Delivery
+// problems are logged and never fail the schedule change itself.
+func (s *ScheduleService) notify(ctx context.Context, session *model.Session, send func(SessionNotice) error) {
+	if s.notifier == nil {
+		return
+	}
+
+	notice, err := s.sessionNotice(ctx, session)
+	if err == nil {
+		err = send(notice)
+	}
+	if err != nil {
+		s.logger.Error("failed to notify session participants",
+			slog.String("session_id", session.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *ScheduleService) sessionNotice(ctx context.Context, session *model.Session) (SessionNotice, error) {
+	group, err := s.groupRepo.GetByID(ctx, session.GroupID)
+	if err != nil {
+		return SessionNotice{}, err
+	}
+	club, err := s.clubRepo.GetByID(ctx, group.ClubID)
+	if err != nil {
+		return SessionNotice{}, err
+	}
+	students, err := s.studentRepo.GetWithActiveSubscription(ctx, group.ID, session.StartAt)
+	if err != nil {
+		return SessionNotice{}, err
+	}
+	return SessionNotice{Club: club, Group: group, Session: session, Students: students}, nil
+}
+
+func (s *ScheduleService) groupLocation(ctx context.Context, groupID uuid.UUID) (*time.Location, error) {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	club, err := s.clubRepo.GetByID(ctx, group.ClubID)
+	if err != nil {
+		return nil, err
+	}
+	return club.Location(), nil
+}
+
+// ApplyTo copies the set fields onto session
+func (changes SessionChanges) ApplyTo(session *model.Session) {
+	if changes.StartAt != nil {
+		session.StartAt = *changes.StartAt
+	}
+	if changes.DurationMinutes != nil {
+		session.DurationMinutes = *changes.DurationMinutes
+	}
+	if changes.Location != nil {
+		session.Location = *changes.Location
+	}
+	if changes.LocationID != nil {
+		session.LocationID = changes.LocationID
+	}
+}
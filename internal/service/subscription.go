@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/neo/trainer-plus/internal/repository"
+)
+
+// SubscriptionService runs the subscription lifecycle jobs
+type SubscriptionService struct {
+	subRepo     *repository.SubscriptionRepository
+	studentRepo *repository.StudentRepository
+	groupRepo   *repository.GroupRepository
+	clubRepo    *repository.ClubRepository
+	notifier    SubscriptionNotifier
+}
+
+func NewSubscriptionService(
+	subRepo *repository.SubscriptionRepository,
+	studentRepo *repository.StudentRepository,
+	groupRepo *repository.GroupRepository,
+	clubRepo *repository.ClubRepository,
+	notifier SubscriptionNotifier,
+) *SubscriptionService {
+	return &SubscriptionService{
+		subRepo:     subRepo,
+		studentRepo: studentRepo,
+		groupRepo:   groupRepo,
+		clubRepo:    clubRepo,
+		notifier:    notifier,
+	}
+}
+
+// ExpireDue expires active subscriptions past their expiry date and tells
+// the guardians. Delivery problems are logged and do not stop the rest.
+func (s *SubscriptionService) ExpireDue(ctx context.Context, now time.Time) (int, error) {
+	expired, err := s.subRepo.ExpireDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.notifier == nil {
+		return len(expired), nil
+	}
+
+	for i := range expired {
+		sub := &expired[i]
+		notice := SubscriptionNotice{Subscription: sub}
+
+		notice.Student, err = s.studentRepo.GetByID(ctx, sub.StudentID)
+		if err == nil {
+			notice.Group, err = s.groupRepo.GetByID(ctx, sub.GroupID)
+		}
+		if err == nil {
+			notice.Club, err = s.clubRepo.GetByID(ctx, notice.Group.ClubID)
+		}
+		if err == nil {
+			err = s.notifier.SubscriptionExpired(ctx, notice)
+		}
+		if err != nil {
+			slog.Default().Error("failed to notify about expired subscription",
+				slog.String("subscription_id", sub.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return len(expired), nil
+}
+
+// RunExpirer calls ExpireDue every interval until ctx is done
+func (s *SubscriptionService) RunExpirer(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.ExpireDue(ctx, time.Now())
+		if err != nil {
+			logger.Error("failed to expire subscriptions", slog.String("error", err.Error()))
+		} else if expired > 0 {
+			logger.Info("expired subscriptions", slog.Int("expired", expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
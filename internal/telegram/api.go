@@ -0,0 +1,193 @@
+// Package telegram is the coach bot: coaches link their account with a
+// one-time code, see today's sessions and mark attendance from Telegram.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAPI is the Telegram Bot API endpoint
+const DefaultAPI = "https://api.telegram.org"
+
+// requestTimeout bounds one API call other than long polling
+const requestTimeout = 10 * time.Second
+
+// Update is an incoming event; only the fields the bot uses are decoded
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text,omitempty"`
+}
+
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// CallbackQuery is a press of an inline button
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// APIError is an error answer of the Bot API
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// Client calls the Telegram Bot API
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPI
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		// Deadlines come from the request contexts; long polls hold the
+		// connection open
+		client: &http.Client{},
+	}
+}
+
+// GetUpdates long-polls for updates starting at offset
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestTimeout)
+	defer cancel()
+
+	var updates []Update
+	err := c.do(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string, markup *InlineKeyboardMarkup) error {
+	payload := map[string]interface{}{"chat_id": chatID, "text": text}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return c.call(ctx, "sendMessage", payload)
+}
+
+// EditMessageText replaces the text and buttons of a message. Editing a
+// message to what it already shows is not an error.
+func (c *Client) EditMessageText(ctx context.Context, chatID, messageID int64, text string, markup *InlineKeyboardMarkup) error {
+	payload := map[string]interface{}{"chat_id": chatID, "message_id": messageID, "text": text}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+
+	err := c.call(ctx, "editMessageText", payload)
+	if apiErr, ok := err.(*APIError); ok && strings.Contains(apiErr.Description, "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+// AnswerCallbackQuery stops the button spinner, showing text as a toast
+// when it is not empty
+func (c *Client) AnswerCallbackQuery(ctx context.Context, id, text string) error {
+	return c.call(ctx, "answerCallbackQuery", map[string]interface{}{"callback_query_id": id, "text": text})
+}
+
+// SetWebhook makes Telegram post updates to url with secret in the
+// X-Telegram-Bot-Api-Secret-Token header
+func (c *Client) SetWebhook(ctx context.Context, url, secret string) error {
+	payload := map[string]interface{}{
+		"url":             url,
+		"allowed_updates": []string{"message", "callback_query"},
+	}
+	if secret != "" {
+		payload["secret_token"] = secret
+	}
+	return c.call(ctx, "setWebhook", payload)
+}
+
+// DeleteWebhook switches the bot back to getUpdates
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", map[string]interface{}{})
+}
+
+func (c *Client) call(ctx context.Context, method string, payload interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return c.do(ctx, method, payload, nil)
+}
+
+func (c *Client) do(ctx context.Context, method string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var answer struct {
+		OK          bool            `json:"ok"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return fmt.Errorf("telegram: %s: %s", method, resp.Status)
+	}
+	if !answer.OK {
+		return &APIError{Code: answer.ErrorCode, Description: answer.Description}
+	}
+	if result != nil {
+		return json.Unmarshal(answer.Result, result)
+	}
+	return nil
+}
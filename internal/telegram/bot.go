@@ -0,0 +1,450 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+	"github.com/neo/trainer-plus/internal/repository"
+	"github.com/neo/trainer-plus/internal/service"
+)
+
+// pollTimeout is how long one getUpdates call waits for updates
+const pollTimeout = 30 * time.Second
+
+var errForbidden = errors.New("not the coach of this group")
+
+const (
+	textLinkHelp = "Чтобы подключить бота, получите код в приложении Тренер+ (профиль → Telegram) и отправьте его сюда: /start КОД"
+	textHelp     = "/today — занятия на сегодня\n/unlink — отключить бота"
+	textNotFound = "Занятие не найдено"
+)
+
+// Bot answers coaches' messages and button presses. It is fed updates by
+// the webhook handler or by Poll.
+type Bot struct {
+	client            *Client
+	telegramRepo      *repository.TelegramRepository
+	groupRepo         *repository.GroupRepository
+	sessionRepo       *repository.SessionRepository
+	clubRepo          *repository.ClubRepository
+	attendanceRepo    *repository.AttendanceRepository
+	attendanceService *service.AttendanceService
+	logger            *slog.Logger
+}
+
+func NewBot(
+	client *Client,
+	telegramRepo *repository.TelegramRepository,
+	groupRepo *repository.GroupRepository,
+	sessionRepo *repository.SessionRepository,
+	clubRepo *repository.ClubRepository,
+	attendanceRepo *repository.AttendanceRepository,
+	attendanceService *service.AttendanceService,
+	logger *slog.Logger,
+) *Bot {
+	return &Bot{
+		client:            client,
+		telegramRepo:      telegramRepo,
+		groupRepo:         groupRepo,
+		sessionRepo:       sessionRepo,
+		clubRepo:          clubRepo,
+		attendanceRepo:    attendanceRepo,
+		attendanceService: attendanceService,
+		logger:            logger,
+	}
+}
+
+// HandleUpdate processes one update. Errors are infrastructure failures;
+// problems of the user's making are answered in the chat instead.
+func (b *Bot) HandleUpdate(ctx context.Context, u Update) error {
+	switch {
+	case u.CallbackQuery != nil:
+		return b.handleCallback(ctx, u.CallbackQuery)
+	case u.Message != nil:
+		return b.handleMessage(ctx, u.Message)
+	default:
+		return nil
+	}
+}
+
+// Poll receives updates by long polling until ctx is done. It is meant
+// for local development, where Telegram cannot reach the webhook.
+func (b *Bot) Poll(ctx context.Context) {
+	if err := b.client.DeleteWebhook(ctx); err != nil {
+		b.logger.Error("failed to delete telegram webhook", slog.String("error", err.Error()))
+	}
+
+	var offset int64
+	for {
+		updates, err := b.client.GetUpdates(ctx, offset, pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.logger.Error("failed to get telegram updates", slog.String("error", err.Error()))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if err := b.HandleUpdate(ctx, u); err != nil {
+				b.logger.Error("failed to handle telegram update",
+					slog.Int64("update_id", u.UpdateID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+func (b *Bot) handleMessage(ctx context.Context, msg *Message) error {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 {
+		return nil
+	}
+	// Commands may be addressed as /today@SomeBot in groups
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	chatID := msg.Chat.ID
+
+	if command == "/start" || command == "/link" {
+		if len(fields) > 1 {
+			return b.link(ctx, msg, fields[1])
+		}
+	}
+
+	account, err := b.telegramRepo.GetByChatID(ctx, chatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return b.client.SendMessage(ctx, chatID, textLinkHelp, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "/today":
+		text, markup, err := b.todayView(ctx, account.UserID)
+		if err != nil {
+			return err
+		}
+		return b.client.SendMessage(ctx, chatID, text, markup)
+
+	case "/unlink":
+		if err := b.telegramRepo.Unlink(ctx, account.UserID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return b.client.SendMessage(ctx, chatID, "Бот отключён от аккаунта. "+textLinkHelp, nil)
+
+	default:
+		return b.client.SendMessage(ctx, chatID, textHelp, nil)
+	}
+}
+
+func (b *Bot) link(ctx context.Context, msg *Message, code string) error {
+	var username *string
+	if msg.From != nil && msg.From.Username != "" {
+		username = &msg.From.Username
+	}
+
+	_, err := b.telegramRepo.Link(ctx, HashLinkCode(code), msg.Chat.ID, username)
+	if errors.Is(err, repository.ErrNotFound) {
+		return b.client.SendMessage(ctx, msg.Chat.ID, "Код не подошёл или истёк. Получите новый код в приложении.", nil)
+	}
+	if err != nil {
+		return err
+	}
+	return b.client.SendMessage(ctx, msg.Chat.ID, "Аккаунт подключён.\n\n"+textHelp, nil)
+}
+
+func (b *Bot) handleCallback(ctx context.Context, q *CallbackQuery) error {
+	if q.Message == nil {
+		return b.client.AnswerCallbackQuery(ctx, q.ID, "")
+	}
+	chatID, messageID := q.Message.Chat.ID, q.Message.MessageID
+
+	account, err := b.telegramRepo.GetByChatID(ctx, chatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return b.client.AnswerCallbackQuery(ctx, q.ID, "Бот не подключён к аккаунту")
+	}
+	if err != nil {
+		return err
+	}
+
+	cb, err := ParseCallback(q.Data)
+	if err != nil {
+		return b.client.AnswerCallbackQuery(ctx, q.ID, "Кнопка устарела, отправьте /today")
+	}
+
+	var toast string
+	if cb.Action == ActionMark {
+		toast, err = b.mark(ctx, account.UserID, cb)
+		if err != nil {
+			return err
+		}
+	}
+
+	var text string
+	var markup *InlineKeyboardMarkup
+	if cb.Action == ActionToday {
+		text, markup, err = b.todayView(ctx, account.UserID)
+	} else {
+		text, markup, err = b.rosterView(ctx, account.UserID, cb.SessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := b.client.EditMessageText(ctx, chatID, messageID, text, markup); err != nil {
+		return err
+	}
+	return b.client.AnswerCallbackQuery(ctx, q.ID, toast)
+}
+
+// todaySession is a session of one of the coach's groups
+type todaySession struct {
+	session model.Session
+	group   *model.Group
+	club    *model.Club
+}
+
+// todayView lists today's sessions of the coach's groups, today being the
+// day in each club's time zone
+func (b *Bot) todayView(ctx context.Context, userID uuid.UUID) (string, *InlineKeyboardMarkup, error) {
+	groups, err := b.groupRepo.GetByCoach(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(groups) == 0 {
+		return "У вас нет групп, где вы тренер.", nil, nil
+	}
+
+	now := time.Now()
+	clubs := make(map[uuid.UUID]*model.Club)
+	var today []todaySession
+	for i := range groups {
+		group := &groups[i]
+		club, ok := clubs[group.ClubID]
+		if !ok {
+			if club, err = b.clubRepo.GetByID(ctx, group.ClubID); err != nil {
+				return "", nil, err
+			}
+			clubs[group.ClubID] = club
+		}
+
+		local := now.In(club.Location())
+		dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+		sessions, err := b.sessionRepo.GetByGroup(ctx, group.ID, dayStart, dayStart.AddDate(0, 0, 1).Add(-time.Nanosecond))
+		if err != nil {
+			return "", nil, err
+		}
+		for _, s := range sessions {
+			today = append(today, todaySession{session: s, group: group, club: club})
+		}
+	}
+
+	if len(today) == 0 {
+		return "Сегодня занятий нет.", nil, nil
+	}
+	sort.Slice(today, func(i, j int) bool {
+		return today[i].session.StartAt.Before(today[j].session.StartAt)
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Занятия на сегодня:\n")
+	markup := &InlineKeyboardMarkup{}
+	for _, t := range today {
+		label := t.session.StartAt.In(t.club.Location()).Format("15:04") + " " + t.group.Title
+		if t.session.IsCancelled() {
+			sb.WriteString("\n" + label + " — отменено")
+			continue
+		}
+		sb.WriteString("\n" + label)
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []InlineKeyboardButton{{
+			Text:         label,
+			CallbackData: Callback{Action: ActionSession, SessionID: t.session.ID}.String(),
+		}})
+	}
+	return sb.String(), markup, nil
+}
+
+// rosterView shows a session's students with their mark and balance and a
+// row of mark buttons for each
+func (b *Bot) rosterView(ctx context.Context, userID, sessionID uuid.UUID) (string, *InlineKeyboardMarkup, error) {
+	back := []InlineKeyboardButton{{Text: "« Сегодня", CallbackData: Callback{Action: ActionToday}.String()}}
+
+	session, group, club, err := b.sessionFor(ctx, userID, sessionID)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, errForbidden) {
+		return textNotFound, &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{back}}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s — %s", group.Title, session.StartAt.In(club.Location()).Format("02.01 15:04"))
+	if session.IsCancelled() {
+		sb.WriteString("\n\nЗанятие отменено.")
+		return sb.String(), &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{back}}, nil
+	}
+
+	entries, err := b.attendanceRepo.GetRoster(ctx, session)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(entries) == 0 {
+		sb.WriteString("\n\nНет учеников с действующим абонементом.")
+	}
+
+	markup := &InlineKeyboardMarkup{}
+	for _, e := range entries {
+		sb.WriteString("\n" + markIcon(e.Mark) + " " + e.StudentName + " — " + balance(e, club))
+
+		cb := Callback{Action: ActionMark, SessionID: session.ID, StudentID: e.StudentID}
+		row := make([]InlineKeyboardButton, 0, 3)
+		for _, status := range []model.AttendanceStatus{model.AttendancePresent, model.AttendanceAbsent, model.AttendanceExcused} {
+			cb.Status = string(status)
+			text := markIcon(&cb.Status)
+			if status == model.AttendancePresent {
+				text += " " + e.StudentName
+			}
+			row = append(row, InlineKeyboardButton{Text: text, CallbackData: cb.String()})
+		}
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, back)
+	return sb.String(), markup, nil
+}
+
+// mark records a student's attendance the way the attendance API does and
+// returns the text to show the coach
+func (b *Bot) mark(ctx context.Context, userID uuid.UUID, cb Callback) (string, error) {
+	session, _, club, err := b.sessionFor(ctx, userID, cb.SessionID)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, errForbidden) {
+		return textNotFound, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Cancelled sessions never consume subscription sessions
+	if session.IsCancelled() {
+		return "Занятие отменено", nil
+	}
+
+	marked, err := b.attendanceRepo.GetBySession(ctx, session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var existing *model.Attendance
+	for i := range marked {
+		if marked[i].StudentID == cb.StudentID {
+			existing = &marked[i]
+			break
+		}
+	}
+
+	if existing == nil {
+		attendance := &model.Attendance{
+			SessionID: session.ID,
+			StudentID: cb.StudentID,
+			Status:    cb.Status,
+			NotedBy:   userID,
+		}
+		err = b.attendanceService.Mark(ctx, session, club, attendance)
+	} else if existing.Status != cb.Status {
+		err = b.attendanceService.UpdateStatus(ctx, session, club, existing, cb.Status)
+	}
+
+	switch {
+	case err == nil:
+		return "Отмечено", nil
+	case errors.Is(err, service.ErrNoCredit):
+		return "Нет действующего абонемента или отработки", nil
+	case errors.Is(err, service.ErrAlreadyMarked):
+		return "Уже отмечено", nil
+	case errors.Is(err, service.ErrTrialLimit), errors.Is(err, service.ErrNoDropIn), errors.Is(err, service.ErrMakeupUsed):
+		return "Не удалось отметить: " + err.Error(), nil
+	default:
+		return "", err
+	}
+}
+
+// sessionFor loads a session the user may mark: as the group's coach or
+// the club owner, the same rule as the attendance API
+func (b *Bot) sessionFor(ctx context.Context, userID, sessionID uuid.UUID) (*model.Session, *model.Group, *model.Club, error) {
+	session, err := b.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	group, err := b.groupRepo.GetByID(ctx, session.GroupID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	club, err := b.clubRepo.GetByID(ctx, group.ClubID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if club.OwnerUserID != userID && (group.CoachUserID == nil || *group.CoachUserID != userID) {
+		return nil, nil, nil, errForbidden
+	}
+	return session, group, club, nil
+}
+
+func markIcon(status *string) string {
+	if status == nil {
+		return "▫️"
+	}
+	switch model.AttendanceStatus(*status) {
+	case model.AttendancePresent:
+		return "✅"
+	case model.AttendanceAbsent:
+		return "❌"
+	case model.AttendanceExcused:
+		return "🤒"
+	default:
+		return "▫️"
+	}
+}
+
+// balance describes what a roster student trains on
+func balance(e repository.RosterEntry, club *model.Club) string {
+	var parts []string
+	switch {
+	case e.RemainingSessions != nil:
+		parts = append(parts, "осталось "+strconv.Itoa(*e.RemainingSessions))
+	case e.Source == repository.RosterSourceMakeup:
+		parts = append(parts, "отработка")
+	case e.Source == repository.RosterSourceTrial:
+		parts = append(parts, "пробное")
+	case e.Source == repository.RosterSourceDropIn:
+		parts = append(parts, "разовое")
+	default:
+		parts = append(parts, "без абонемента")
+	}
+
+	for _, w := range e.Warnings {
+		switch w {
+		case repository.RosterWarnLastSession:
+			parts = append(parts, "⚠️ последнее занятие")
+		case repository.RosterWarnExpiresSoon:
+			parts = append(parts, "⚠️ абонемент истекает")
+		case repository.RosterWarnDebt:
+			parts = append(parts, "💳 долг "+strconv.FormatFloat(e.PendingAmount, 'f', -1, 64)+" "+club.Currency)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+// LinkCodeTTL is how long a link code can be redeemed
+const LinkCodeTTL = 10 * time.Minute
+
+// linkCodeAlphabet leaves out characters that are easy to confuse
+const linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const linkCodeLength = 8
+
+// NewLinkCode generates a one-time code that links a Telegram chat to the
+// user it was issued to. Only its hash is stored.
+func NewLinkCode() (string, error) {
+	b := make([]byte, linkCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = linkCodeAlphabet[int(b[i])%len(linkCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// HashLinkCode returns the stored form of a link code. Codes are not case
+// sensitive.
+func HashLinkCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// Callback actions
+const (
+	ActionToday   = "t" // list today's sessions
+	ActionSession = "s" // show a session roster
+	ActionMark    = "m" // mark a student
+)
+
+// Attendance statuses as sent in callback data
+var markStatuses = map[byte]string{
+	'p': string(model.AttendancePresent),
+	'a': string(model.AttendanceAbsent),
+	'e': string(model.AttendanceExcused),
+}
+
+// ErrBadCallback is returned for callback data the bot did not produce
+var ErrBadCallback = errors.New("telegram: malformed callback data")
+
+// Callback is what an inline button asks for. Telegram limits callback
+// data to 64 bytes, so ids are encoded as unpadded base64.
+type Callback struct {
+	Action    string
+	SessionID uuid.UUID
+	StudentID uuid.UUID
+	Status    string // for ActionMark
+}
+
+func (c Callback) String() string {
+	switch c.Action {
+	case ActionSession:
+		return ActionSession + ":" + encodeID(c.SessionID)
+	case ActionMark:
+		return ActionMark + ":" + encodeID(c.SessionID) + encodeID(c.StudentID) + ":" + c.Status[:1]
+	default:
+		return ActionToday
+	}
+}
+
+// ParseCallback decodes callback data made by Callback.String
+func ParseCallback(data string) (Callback, error) {
+	action, rest, _ := strings.Cut(data, ":")
+	switch action {
+	case ActionToday:
+		return Callback{Action: ActionToday}, nil
+
+	case ActionSession:
+		id, err := decodeID(rest)
+		if err != nil {
+			return Callback{}, ErrBadCallback
+		}
+		return Callback{Action: ActionSession, SessionID: id}, nil
+
+	case ActionMark:
+		ids, status, _ := strings.Cut(rest, ":")
+		if len(ids) != 2*encodedIDLen || len(status) != 1 {
+			return Callback{}, ErrBadCallback
+		}
+		sessionID, err := decodeID(ids[:encodedIDLen])
+		if err != nil {
+			return Callback{}, ErrBadCallback
+		}
+		studentID, err := decodeID(ids[encodedIDLen:])
+		if err != nil {
+			return Callback{}, ErrBadCallback
+		}
+		s, ok := markStatuses[status[0]]
+		if !ok {
+			return Callback{}, ErrBadCallback
+		}
+		return Callback{Action: ActionMark, SessionID: sessionID, StudentID: studentID, Status: s}, nil
+
+	default:
+		return Callback{}, ErrBadCallback
+	}
+}
+
+var encodedIDLen = base64.RawURLEncoding.EncodedLen(16)
+
+func encodeID(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func decodeID(s string) (uuid.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(b)
+}
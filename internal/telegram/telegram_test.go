@@ -0,0 +1,156 @@
+package telegram_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/internal/telegram"
+)
+
+func TestCallback_RoundTrip(t *testing.T) {
+	sessionID, studentID := uuid.New(), uuid.New()
+
+	tests := []telegram.Callback{
+		{Action: telegram.ActionToday},
+		{Action: telegram.ActionSession, SessionID: sessionID},
+		{Action: telegram.ActionMark, SessionID: sessionID, StudentID: studentID, Status: "present"},
+		{Action: telegram.ActionMark, SessionID: sessionID, StudentID: studentID, Status: "absent"},
+		{Action: telegram.ActionMark, SessionID: sessionID, StudentID: studentID, Status: "excused"},
+	}
+
+	for _, want := range tests {
+		data := want.String()
+		if len(data) > 64 {
+			t.Errorf("callback data %q is longer than 64 bytes", data)
+		}
+		got, err := telegram.ParseCallback(data)
+		if err != nil {
+			t.Fatalf("ParseCallback(%q): %v", data, err)
+		}
+		if got != want {
+			t.Errorf("ParseCallback(%q) = %+v, want %+v", data, got, want)
+		}
+	}
+}
+
+func TestParseCallback_Malformed(t *testing.T) {
+	valid := telegram.Callback{Action: telegram.ActionMark, SessionID: uuid.New(), StudentID: uuid.New(), Status: "present"}.String()
+
+	tests := []string{
+		"",
+		"x:abc",
+		"s:not-base64!",
+		"s:AAAA",
+		"m:short:p",
+		valid[:len(valid)-1] + "z",
+		strings.TrimSuffix(valid, ":p"),
+	}
+
+	for _, data := range tests {
+		if _, err := telegram.ParseCallback(data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestLinkCode(t *testing.T) {
+	code, err := telegram.NewLinkCode()
+	if err != nil {
+		t.Fatalf("NewLinkCode: %v", err)
+	}
+	if len(code) != 8 || strings.ToUpper(code) != code || strings.ContainsAny(code, "01IO") {
+		t.Errorf("unexpected code %q", code)
+	}
+
+	other, _ := telegram.NewLinkCode()
+	if other == code {
+		t.Error("expected distinct codes")
+	}
+
+	if telegram.HashLinkCode(code) != telegram.HashLinkCode(" "+strings.ToLower(code)+" ") {
+		t.Error("expected hashes to ignore case and spaces")
+	}
+	if telegram.HashLinkCode(code) == telegram.HashLinkCode(other) {
+		t.Error("expected distinct hashes")
+	}
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	var path string
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+
+	client := telegram.NewClient(srv.URL, "123:abc")
+	markup := &telegram.InlineKeyboardMarkup{InlineKeyboard: [][]telegram.InlineKeyboardButton{
+		{{Text: "18:00 Juniors", CallbackData: "t"}},
+	}}
+	if err := client.SendMessage(context.Background(), 42, "hello", markup); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	if path != "/bot123:abc/sendMessage" {
+		t.Errorf("unexpected path %q", path)
+	}
+	if got["chat_id"] != float64(42) || got["text"] != "hello" {
+		t.Errorf("unexpected payload %v", got)
+	}
+	keyboard := got["reply_markup"].(map[string]interface{})["inline_keyboard"].([]interface{})
+	button := keyboard[0].([]interface{})[0].(map[string]interface{})
+	if button["text"] != "18:00 Juniors" || button["callback_data"] != "t" {
+		t.Errorf("unexpected keyboard %v", keyboard)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	description := "Bad Request: message is not modified"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": description})
+	}))
+	defer srv.Close()
+
+	client := telegram.NewClient(srv.URL, "token")
+	if err := client.EditMessageText(context.Background(), 1, 2, "same", nil); err != nil {
+		t.Errorf("expected unchanged edits to succeed, got %v", err)
+	}
+
+	description = "Forbidden: bot was blocked by the user"
+	err := client.SendMessage(context.Background(), 1, "hi", nil)
+	apiErr, ok := err.(*telegram.APIError)
+	if !ok || apiErr.Code != 400 || apiErr.Description != description {
+		t.Errorf("expected an APIError, got %v", err)
+	}
+}
+
+func TestClient_GetUpdates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["offset"] != float64(7) {
+			t.Errorf("unexpected offset %v", req["offset"])
+		}
+		w.Write([]byte(`{"ok":true,"result":[
+			{"update_id":7,"message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"/today"}},
+			{"update_id":8,"callback_query":{"id":"q","from":{"id":42},"data":"t"}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	updates, err := telegram.NewClient(srv.URL, "token").GetUpdates(context.Background(), 7, 0)
+	if err != nil {
+		t.Fatalf("GetUpdates: %v", err)
+	}
+	if len(updates) != 2 || updates[0].Message.Text != "/today" || updates[1].CallbackQuery.Data != "t" {
+		t.Errorf("unexpected updates %+v", updates)
+	}
+}
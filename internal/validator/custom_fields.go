@@ -0,0 +1,153 @@
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/neo/trainer-plus/internal/model"
+)
+
+const (
+	maxTags              = 20
+	maxTagLength         = 50
+	maxCustomTextLength  = 500
+	customFieldDateStyle = "2006-01-02"
+)
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// validateFieldKey checks a custom field key: lowercase Latin letters,
+// digits and underscores, starting with a letter
+func validateFieldKey(fl validator.FieldLevel) bool {
+	return fieldKeyPattern.MatchString(fl.Field().String())
+}
+
+// Tags normalises free-form tags: trimmed, lowercase and without repeats,
+// in the order given
+func (v *Validator) Tags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("tags must have at most %d items", maxTags)
+	}
+
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		switch {
+		case tag == "":
+			return nil, fmt.Errorf("tags must not be empty")
+		case utf8.RuneCountInString(tag) > maxTagLength:
+			return nil, fmt.Errorf("tag %q must be at most %d characters", tag, maxTagLength)
+		case seen[tag]:
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// CustomFields checks values against the fields a club defined and returns
+// them normalised: numbers as float64, also when written as strings, dates
+// as YYYY-MM-DD and enum values spelled as the option. A null or empty
+// value is kept as nil, which removes the field on update.
+func (v *Validator) CustomFields(fields []model.CustomField, values map[string]interface{}) (model.CustomValues, error) {
+	byKey := make(map[string]*model.CustomField, len(fields))
+	for i := range fields {
+		byKey[fields[i].Key] = &fields[i]
+	}
+
+	result := make(model.CustomValues, len(values))
+	var errMessages []string
+	// In key order, so that the messages read the same every time
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		value := values[key]
+		field, ok := byKey[key]
+		if !ok {
+			errMessages = append(errMessages, fmt.Sprintf("custom_fields.%s is not a field of this club", key))
+			continue
+		}
+		normalized, err := customValue(field, value)
+		if err != nil {
+			errMessages = append(errMessages, fmt.Sprintf("custom_fields.%s %v", key, err))
+			continue
+		}
+		result[key] = normalized
+	}
+
+	if len(errMessages) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errMessages, "; "))
+	}
+	return result, nil
+}
+
+func customValue(field *model.CustomField, value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		value = strings.TrimSpace(s)
+		if value == "" {
+			return nil, nil
+		}
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch model.CustomFieldType(field.Type) {
+	case model.CustomFieldNumber:
+		var f float64
+		switch n := value.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(strings.Replace(n, ",", ".", 1), 64); err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+		default:
+			return nil, fmt.Errorf("must be a number")
+		}
+		// ParseFloat accepts "NaN" and "Inf", which JSON cannot carry back
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return f, nil
+
+	case model.CustomFieldDate:
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(customFieldDateStyle, s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+
+	case model.CustomFieldEnum:
+		if s, ok := value.(string); ok {
+			for _, option := range field.Options {
+				if strings.EqualFold(option, s) {
+					return option, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("must be one of: %s", strings.Join(field.Options, ", "))
+
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be text")
+		}
+		if utf8.RuneCountInString(s) > maxCustomTextLength {
+			return nil, fmt.Errorf("must be at most %d characters", maxCustomTextLength)
+		}
+		return s, nil
+	}
+}
@@ -16,6 +16,7 @@ func New() *Validator {
 	
 	// Register custom validations if needed
 	v.RegisterValidation("currency", validateCurrency)
+	v.RegisterValidation("field_key", validateFieldKey)
 	
 	return &Validator{validate: v}
 }
@@ -57,6 +58,12 @@ func formatError(fe validator.FieldError) string {
 		return fmt.Sprintf("%s must be one of: %s", field, fe.Param())
 	case "currency":
 		return fmt.Sprintf("%s must be a valid currency (KZT, USD, EUR, RUB)", field)
+	case "timezone":
+		return fmt.Sprintf("%s must be a valid IANA time zone (e.g. Asia/Almaty)", field)
+	case "required_if":
+		return fmt.Sprintf("%s is required", field)
+	case "field_key":
+		return fmt.Sprintf("%s must be lowercase latin letters, digits and underscores, starting with a letter", field)
 	default:
 		return fmt.Sprintf("%s is invalid", field)
 	}
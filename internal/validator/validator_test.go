@@ -1,8 +1,12 @@
 package validator_test
 
 import (
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/neo/trainer-plus/internal/model"
 	"github.com/neo/trainer-plus/internal/validator"
 )
 
@@ -53,6 +57,20 @@ func TestValidator_Struct(t *testing.T) {
 			}{C1: "KZT", C2: "USD", C3: "EUR", C4: "RUB"},
 			wantErr: false,
 		},
+		{
+			name: "valid timezone",
+			input: struct {
+				TZ string `validate:"required,timezone"`
+			}{TZ: "Asia/Almaty"},
+			wantErr: false,
+		},
+		{
+			name: "invalid timezone",
+			input: struct {
+				TZ string `validate:"required,timezone"`
+			}{TZ: "Mars/Olympus"},
+			wantErr: true,
+		},
 		{
 			name: "valid email",
 			input: struct {
@@ -120,3 +138,86 @@ func TestValidator_Struct(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_Tags(t *testing.T) {
+	v := validator.New()
+
+	got, err := v.Tags([]string{" Competition  Team ", "beginner", "BEGINNER"})
+	if err != nil {
+		t.Fatalf("Tags() error = %v", err)
+	}
+	want := []string{"competition team", "beginner"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %q, want %q", got, want)
+	}
+
+	if _, err := v.Tags([]string{"ok", "  "}); err == nil {
+		t.Error("Tags() with a blank tag: want error")
+	}
+	if _, err := v.Tags(make([]string, 21)); err == nil {
+		t.Error("Tags() with 21 tags: want error")
+	}
+	if _, err := v.Tags([]string{strings.Repeat("a", 51)}); err == nil {
+		t.Error("Tags() with a long tag: want error")
+	}
+}
+
+func TestValidator_CustomFields(t *testing.T) {
+	v := validator.New()
+	fields := []model.CustomField{
+		{Key: "belt", Type: string(model.CustomFieldEnum), Options: []string{"White", "Yellow"}},
+		{Key: "weight", Type: string(model.CustomFieldNumber)},
+		{Key: "graded_on", Type: string(model.CustomFieldDate)},
+		{Key: "school", Type: string(model.CustomFieldText)},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		got, err := v.CustomFields(fields, map[string]interface{}{
+			"belt":      "yellow",
+			"weight":    "32,5",
+			"graded_on": "2026-05-20",
+			"school":    " No. 12 ",
+		})
+		if err != nil {
+			t.Fatalf("CustomFields() error = %v", err)
+		}
+		want := model.CustomValues{"belt": "Yellow", "weight": 32.5, "graded_on": "2026-05-20", "school": "No. 12"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("CustomFields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("removal", func(t *testing.T) {
+		got, err := v.CustomFields(fields, map[string]interface{}{"belt": nil, "school": ""})
+		if err != nil {
+			t.Fatalf("CustomFields() error = %v", err)
+		}
+		want := model.CustomValues{"belt": nil, "school": nil}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("CustomFields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := v.CustomFields(fields, map[string]interface{}{
+			"belt":      "black",
+			"weight":    "heavy",
+			"graded_on": "20.05.2026",
+			"shoe_size": 38,
+		})
+		want := "custom_fields.belt must be one of: White, Yellow; " +
+			"custom_fields.graded_on must be a date in YYYY-MM-DD format; " +
+			"custom_fields.shoe_size is not a field of this club; " +
+			"custom_fields.weight must be a number"
+		if err == nil || err.Error() != want {
+			t.Errorf("CustomFields() error = %v\nwant %s", err, want)
+		}
+	})
+	t.Run("non-finite numbers", func(t *testing.T) {
+		for _, weight := range []interface{}{"NaN", "inf", "-Infinity", "1e400", math.NaN(), math.Inf(1)} {
+			if _, err := v.CustomFields(fields, map[string]interface{}{"weight": weight}); err == nil {
+				t.Errorf("CustomFields(weight: %v) expected an error", weight)
+			}
+		}
+	})
+}
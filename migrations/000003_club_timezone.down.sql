@@ -0,0 +1 @@
+ALTER TABLE clubs DROP COLUMN IF EXISTS timezone;
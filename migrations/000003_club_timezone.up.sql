@@ -0,0 +1,3 @@
+-- Club time zone (IANA name). Sessions, "today" and month boundaries in
+-- reports are evaluated in this zone instead of the server's.
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
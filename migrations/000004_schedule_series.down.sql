@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_sessions_series_occurrence;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS is_exception;
+ALTER TABLE sessions DROP COLUMN IF EXISTS occurrence_date;
+ALTER TABLE sessions DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS schedule_series;
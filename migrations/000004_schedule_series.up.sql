@@ -0,0 +1,25 @@
+-- Persisted recurring schedules. A series stores the rule; sessions are
+-- materialised from it on a rolling horizon and keep a link back to it.
+CREATE TABLE schedule_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    rrule TEXT NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE,
+    start_time TEXT NOT NULL,
+    duration_minutes INT NOT NULL DEFAULT 60,
+    location TEXT,
+    exdates DATE[] NOT NULL DEFAULT '{}',
+    materialized_until DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_schedule_series_group ON schedule_series(group_id);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES schedule_series(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS is_exception BOOLEAN NOT NULL DEFAULT false;
+
+-- One materialised session per series occurrence
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_series_occurrence
+ON sessions(series_id, occurrence_date) WHERE series_id IS NOT NULL;
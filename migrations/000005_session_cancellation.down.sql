@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_sessions_group_start_active;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_status_check;
+ALTER TABLE sessions DROP COLUMN IF EXISTS rescheduled_from;
+ALTER TABLE sessions DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS cancel_reason;
+ALTER TABLE sessions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,11 @@
+-- Cancelled sessions are kept for history instead of being deleted
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'scheduled';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS rescheduled_from TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_status_check;
+ALTER TABLE sessions ADD CONSTRAINT sessions_status_check CHECK (status IN ('scheduled', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS idx_sessions_group_start_active
+ON sessions(group_id, start_at) WHERE status = 'scheduled';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sessions_location_start;
+ALTER TABLE schedule_series DROP COLUMN IF EXISTS location_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS location_id;
+ALTER TABLE groups DROP COLUMN IF EXISTS location_id;
+DROP TABLE IF EXISTS locations;
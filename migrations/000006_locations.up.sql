@@ -0,0 +1,21 @@
+-- Halls and other venues of a club
+CREATE TABLE locations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID REFERENCES clubs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    address TEXT,
+    capacity INT,
+    opening_hours JSONB NOT NULL DEFAULT '{}',
+    resources TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_locations_club ON locations(club_id);
+CREATE UNIQUE INDEX idx_locations_club_name ON locations(club_id, lower(name));
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id) ON DELETE SET NULL;
+ALTER TABLE schedule_series ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_location_start
+ON sessions(location_id, start_at) WHERE location_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS makeup_allowed_groups;
+ALTER TABLE attendances DROP COLUMN IF EXISTS makeup_credit_id;
+DROP TABLE IF EXISTS makeup_credits;
+ALTER TABLE clubs DROP COLUMN IF EXISTS makeup_valid_days;
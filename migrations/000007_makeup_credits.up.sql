@@ -0,0 +1,25 @@
+-- How long a makeup credit from an excused absence stays valid (0 disables makeups)
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS makeup_valid_days INT NOT NULL DEFAULT 30;
+
+-- Makeup credits earned by excused absences
+CREATE TABLE makeup_credits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID REFERENCES students(id) ON DELETE CASCADE,
+    source_group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    source_attendance_id UUID UNIQUE REFERENCES attendances(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_makeup_credits_student ON makeup_credits(student_id, expires_at) WHERE used_at IS NULL;
+
+-- Attendance that redeemed a makeup credit instead of a subscription session
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS makeup_credit_id UUID REFERENCES makeup_credits(id) ON DELETE SET NULL;
+
+-- Other groups where absences of group_id may be made up
+CREATE TABLE makeup_allowed_groups (
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    allowed_group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, allowed_group_id)
+);
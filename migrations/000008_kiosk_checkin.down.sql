@@ -0,0 +1,2 @@
+ALTER TABLE attendances DROP COLUMN IF EXISTS kiosk_device_id;
+DROP TABLE IF EXISTS kiosk_devices;
@@ -0,0 +1,17 @@
+-- Kiosk devices for self check-in, scoped to a club and optionally a location
+CREATE TABLE kiosk_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID REFERENCES clubs(id) ON DELETE CASCADE,
+    location_id UUID REFERENCES locations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_by UUID REFERENCES users(id),
+    last_seen_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_kiosk_devices_club ON kiosk_devices(club_id);
+
+-- Device that recorded a self check-in
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS kiosk_device_id UUID REFERENCES kiosk_devices(id) ON DELETE SET NULL;
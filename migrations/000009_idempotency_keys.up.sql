@@ -0,0 +1,13 @@
+-- Responses of requests sent with an Idempotency-Key, replayed on retry
+CREATE TABLE idempotency_keys (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT,
+    response JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS kind;
+DROP INDEX IF EXISTS idx_attendances_trials;
+ALTER TABLE attendances DROP COLUMN IF EXISTS kind;
+ALTER TABLE groups DROP COLUMN IF EXISTS drop_in_price;
+ALTER TABLE clubs DROP COLUMN IF EXISTS trial_visits_per_group;
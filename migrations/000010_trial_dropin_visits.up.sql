@@ -0,0 +1,16 @@
+-- Free trial visits a student may take in each group (0 disables trials)
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS trial_visits_per_group INT NOT NULL DEFAULT 1;
+
+-- Price of a single paid visit; NULL means the group takes no drop-ins
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS drop_in_price DECIMAL(10,2);
+
+-- Regular visits are paid by a subscription or makeup credit, trials are
+-- free and drop-ins create a one-off charge
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'regular'
+    CHECK (kind IN ('regular', 'trial', 'drop_in'));
+
+CREATE INDEX idx_attendances_trials ON attendances(student_id) WHERE kind = 'trial';
+
+-- A drop-in charge is a single-visit subscription awaiting payment
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'package'
+    CHECK (kind IN ('package', 'drop_in'));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reminder_rules;
+DROP TABLE IF EXISTS reminder_settings;
@@ -0,0 +1,24 @@
+-- Per-club reminder delivery settings
+CREATE TABLE reminder_settings (
+    club_id UUID PRIMARY KEY REFERENCES clubs(id) ON DELETE CASCADE,
+    -- Local "HH:MM"; reminders falling inside are held until the end
+    quiet_hours_start VARCHAR(5),
+    quiet_hours_end VARCHAR(5),
+    -- Channels reminders may use; empty means the guardian's preference
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+-- Reminder rules; the meaning of threshold depends on kind
+CREATE TABLE reminder_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID REFERENCES clubs(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('low_balance', 'expiring', 'upcoming_session', 'unpaid_debt')),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    threshold INT NOT NULL CHECK (threshold > 0),
+    -- Overrides the club channels when not empty
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    UNIQUE (club_id, kind)
+);
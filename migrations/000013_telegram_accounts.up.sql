@@ -0,0 +1,17 @@
+-- Telegram chats linked to user accounts, used by the coach bot
+CREATE TABLE telegram_accounts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    chat_id BIGINT NOT NULL UNIQUE,
+    username TEXT,
+    linked_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+-- One-time codes that link a chat to a user; only the hash is stored
+CREATE TABLE telegram_link_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_telegram_link_codes_user ON telegram_link_codes(user_id);
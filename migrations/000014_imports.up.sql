@@ -0,0 +1,42 @@
+-- Uploaded student imports; validating and committing rows are the job queue
+CREATE TABLE imports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename TEXT NOT NULL,
+    headers TEXT[] NOT NULL DEFAULT '{}',
+    -- Field name -> header of the uploaded file
+    mapping JSONB NOT NULL DEFAULT '{}',
+    options JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'uploaded'
+        CHECK (status IN ('uploaded', 'validating', 'validated', 'committing', 'completed', 'failed')),
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    valid_rows INT NOT NULL DEFAULT 0,
+    invalid_rows INT NOT NULL DEFAULT 0,
+    duplicate_rows INT NOT NULL DEFAULT 0,
+    created_students INT NOT NULL DEFAULT 0,
+    created_subscriptions INT NOT NULL DEFAULT 0,
+    error TEXT,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_imports_club ON imports(club_id, created_at DESC);
+CREATE INDEX idx_imports_queue ON imports(updated_at) WHERE status IN ('validating', 'committing');
+
+-- Raw cells of each uploaded row and the outcome of its last validation
+CREATE TABLE import_rows (
+    import_id UUID NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    data JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'valid', 'invalid', 'duplicate', 'created', 'linked', 'skipped')),
+    errors JSONB NOT NULL DEFAULT '[]',
+    duplicate_of UUID REFERENCES students(id) ON DELETE SET NULL,
+    student_id UUID REFERENCES students(id) ON DELETE SET NULL,
+    subscription_id UUID REFERENCES subscriptions(id) ON DELETE SET NULL,
+    PRIMARY KEY (import_id, row_number)
+);
@@ -0,0 +1,19 @@
+-- Audit trail of merged duplicate students. The merged student is deleted,
+-- so its row is kept here as it was before the merge.
+CREATE TABLE student_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    survivor_id UUID REFERENCES students(id) ON DELETE SET NULL,
+    merged_student_id UUID NOT NULL,
+    merged_student JSONB NOT NULL,
+    moved_subscriptions INT NOT NULL DEFAULT 0,
+    moved_attendances INT NOT NULL DEFAULT 0,
+    moved_payments INT NOT NULL DEFAULT 0,
+    moved_makeup_credits INT NOT NULL DEFAULT 0,
+    -- Visits of both students to the same session: the duplicate's are undone
+    removed_attendances INT NOT NULL DEFAULT 0,
+    merged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_student_merges_club ON student_merges(club_id, created_at DESC);
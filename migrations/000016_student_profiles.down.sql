@@ -0,0 +1,10 @@
+DELETE FROM reminder_rules WHERE kind = 'medical_certificate';
+ALTER TABLE reminder_rules DROP CONSTRAINT reminder_rules_kind_check;
+ALTER TABLE reminder_rules ADD CONSTRAINT reminder_rules_kind_check
+    CHECK (kind IN ('low_balance', 'expiring', 'upcoming_session', 'unpaid_debt'));
+
+DROP TABLE IF EXISTS student_consents;
+DROP TABLE IF EXISTS emergency_contacts;
+DROP TABLE IF EXISTS medical_certificates;
+DROP TABLE IF EXISTS student_documents;
+DROP TABLE IF EXISTS student_medical;
@@ -0,0 +1,75 @@
+-- Medical details of a student; one row per student
+CREATE TABLE student_medical (
+    student_id UUID PRIMARY KEY REFERENCES students(id) ON DELETE CASCADE,
+    blood_type VARCHAR(10) NOT NULL DEFAULT '',
+    allergies TEXT NOT NULL DEFAULT '',
+    conditions TEXT NOT NULL DEFAULT '',
+    medications TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+-- Files attached to a student: certificate scans, signed waivers and so on
+CREATE TABLE student_documents (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL
+        CHECK (kind IN ('medical_certificate', 'waiver', 'consent', 'id_document', 'other')),
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_student_documents_student ON student_documents(student_id, created_at DESC);
+
+-- Medical certificates admitting a student to training until expires_on
+CREATE TABLE medical_certificates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    issued_on DATE,
+    expires_on DATE NOT NULL,
+    issuer TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    document_id UUID REFERENCES student_documents(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_medical_certificates_student ON medical_certificates(student_id, expires_on DESC);
+CREATE INDEX idx_medical_certificates_expires ON medical_certificates(expires_on);
+
+-- Whom to call when something happens at training, in order
+CREATE TABLE emergency_contacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    relation TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_emergency_contacts_student ON emergency_contacts(student_id, position);
+
+-- Waivers and consents signed by the guardian. Revoking keeps the record.
+CREATE TABLE student_consents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('waiver', 'photo', 'data_processing')),
+    signed_by TEXT NOT NULL,
+    signed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    document_id UUID REFERENCES student_documents(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_student_consents_student ON student_consents(student_id, kind);
+
+-- Reminder of expiring medical certificates, threshold in days
+ALTER TABLE reminder_rules DROP CONSTRAINT reminder_rules_kind_check;
+ALTER TABLE reminder_rules ADD CONSTRAINT reminder_rules_kind_check
+    CHECK (kind IN ('low_balance', 'expiring', 'upcoming_session', 'unpaid_debt', 'medical_certificate'));
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS purges;
+
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_student_id_fkey;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_student_id_fkey
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_students_club_active;
+DROP INDEX IF EXISTS idx_groups_club_active;
+DROP INDEX IF EXISTS idx_clubs_owner_active;
+
+ALTER TABLE students DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE groups DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE clubs DROP COLUMN IF EXISTS archived_at;
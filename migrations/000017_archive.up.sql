@@ -0,0 +1,28 @@
+-- Students, groups and clubs are archived instead of deleted. Archived
+-- records are hidden from lists and can be restored.
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE students ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_clubs_owner_active ON clubs(owner_user_id) WHERE archived_at IS NULL;
+CREATE INDEX idx_groups_club_active ON groups(club_id) WHERE archived_at IS NULL;
+CREATE INDEX idx_students_club_active ON students(club_id) WHERE archived_at IS NULL;
+
+-- Purging a student keeps their subscriptions, and with them the payments,
+-- for the books
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_student_id_fkey;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_student_id_fkey
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE SET NULL;
+
+-- Audit trail of purges. Only ids are kept: the purged data is gone.
+CREATE TABLE purges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('student', 'group', 'club')),
+    entity_id UUID NOT NULL,
+    club_id UUID NOT NULL,
+    kept_subscriptions INT NOT NULL DEFAULT 0,
+    purged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_purges_club ON purges(club_id, created_at DESC);
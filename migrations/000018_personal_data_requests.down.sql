@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS data_requests;
+
+ALTER TABLE students DROP COLUMN IF EXISTS erased_at;
@@ -0,0 +1,17 @@
+-- Erased students keep their row, anonymised, so that subscriptions and
+-- payments still add up in reports
+ALTER TABLE students ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP WITH TIME ZONE;
+
+-- Log of guardians' requests to export or erase personal data. Only the
+-- ids of the students concerned are kept, never the guardian's contacts.
+CREATE TABLE data_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('export', 'erasure')),
+    subject VARCHAR(20) NOT NULL CHECK (subject IN ('student', 'guardian')),
+    student_ids UUID[] NOT NULL DEFAULT '{}',
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_data_requests_club ON data_requests(club_id, created_at DESC);
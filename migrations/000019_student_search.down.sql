@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_students_club_birth_date;
+DROP INDEX IF EXISTS idx_students_search_phone;
+DROP INDEX IF EXISTS idx_students_search_text;
+
+DROP FUNCTION IF EXISTS student_search_text(TEXT, JSONB);
+DROP FUNCTION IF EXISTS search_phone(TEXT);
+DROP FUNCTION IF EXISTS search_fold(TEXT);
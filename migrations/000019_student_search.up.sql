@@ -0,0 +1,48 @@
+-- Student search: by the student's or guardian's name and email with typos
+-- and in either alphabet, and by the guardian's phone digits
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- search_fold brings text to lowercase Latin so that Cyrillic, Kazakh and
+-- Latin spellings meet: "Алёна" and "Alena" both become "alena". Upper
+-- case Cyrillic is folded explicitly as lower() only handles ASCII under
+-- the C locale.
+CREATE OR REPLACE FUNCTION search_fold(s TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT translate(
+        replace(replace(replace(replace(replace(
+        replace(replace(replace(replace(replace(
+            lower(translate(coalesce(s, ''),
+                'АБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯӘҒҚҢӨҰҮҺІ',
+                'абвгдеёжзийклмнопрстуфхцчшщъыьэюяәғқңөұүһі')),
+            'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'),
+            'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'), 'ъ', ''), 'ь', ''),
+        'абвгдеёзийклмнопрстуфыэәғқңөұүһі',
+        'abvgdeeziiklmnoprstufyeagknouuhi')
+$$;
+
+-- search_phone keeps the digits of a phone, writing the local 8 prefix of
+-- Kazakhstan and Russia as the country code 7, like the importer does
+CREATE OR REPLACE FUNCTION search_phone(s TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT CASE
+        WHEN d ~ '^8[0-9]{10}$' THEN '7' || substr(d, 2)
+        WHEN d ~ '^[1-9][0-9]{9}$' THEN '7' || d
+        ELSE d
+    END
+    FROM (SELECT regexp_replace(coalesce(s, ''), '[^0-9]', '', 'g') AS d) digits
+$$;
+
+-- What a student is found by, apart from the phone
+CREATE OR REPLACE FUNCTION student_search_text(name TEXT, parent_contact JSONB) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT search_fold(
+        coalesce(name, '') || ' ' ||
+        coalesce(parent_contact->>'name', '') || ' ' ||
+        coalesce(parent_contact->>'email', ''))
+$$;
+
+CREATE INDEX idx_students_search_text ON students
+    USING gin (student_search_text(name, parent_contact) gin_trgm_ops);
+CREATE INDEX idx_students_search_phone ON students
+    USING gin (search_phone(parent_contact->>'phone') gin_trgm_ops);
+CREATE INDEX idx_students_club_birth_date ON students(club_id, birth_date);
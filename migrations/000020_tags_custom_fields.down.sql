@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_groups_custom_fields;
+DROP INDEX IF EXISTS idx_groups_tags;
+DROP INDEX IF EXISTS idx_students_custom_fields;
+DROP INDEX IF EXISTS idx_students_tags;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS custom_fields, DROP COLUMN IF EXISTS tags;
+ALTER TABLE students DROP COLUMN IF EXISTS custom_fields, DROP COLUMN IF EXISTS tags;
+
+DROP TABLE IF EXISTS custom_fields;
@@ -0,0 +1,25 @@
+-- Club-defined fields of students and groups, e.g. belt colour or school
+CREATE TABLE custom_fields (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('student', 'group')),
+    key VARCHAR(50) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('text', 'number', 'date', 'enum')),
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT custom_fields_club_entity_key UNIQUE (club_id, entity, key)
+);
+
+-- Values are keyed by custom_fields.key; tags are free-form and lowercase
+ALTER TABLE students
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_students_tags ON students USING gin (tags);
+CREATE INDEX idx_students_custom_fields ON students USING gin (custom_fields jsonb_path_ops);
+CREATE INDEX idx_groups_tags ON groups USING gin (tags);
+CREATE INDEX idx_groups_custom_fields ON groups USING gin (custom_fields jsonb_path_ops);
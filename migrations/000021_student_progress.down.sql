@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS level_awards;
+DROP TABLE IF EXISTS grading_results;
+DROP TABLE IF EXISTS gradings;
+DROP TABLE IF EXISTS skill_assessments;
+DROP TABLE IF EXISTS skills;
+DROP TABLE IF EXISTS progress_levels;
@@ -0,0 +1,80 @@
+-- Levels a group's students advance through, e.g. belts or grades;
+-- position 1 is the lowest
+CREATE TABLE progress_levels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT progress_levels_group_name UNIQUE (group_id, name)
+);
+
+CREATE INDEX idx_progress_levels_group ON progress_levels(group_id, position);
+
+-- Skills coaches assess in a group, scored from 0 to max_score
+CREATE TABLE skills (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    max_score INT NOT NULL DEFAULT 10 CHECK (max_score > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT skills_group_name UNIQUE (group_id, name)
+);
+
+CREATE TABLE skill_assessments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    skill_id UUID NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    score INT NOT NULL CHECK (score >= 0),
+    notes TEXT NOT NULL DEFAULT '',
+    assessed_on DATE NOT NULL,
+    assessed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_skill_assessments_student ON skill_assessments(student_id, assessed_on DESC);
+CREATE INDEX idx_skill_assessments_skill ON skill_assessments(skill_id);
+
+-- Grading exams held in a group
+CREATE TABLE gradings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    held_on DATE NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_gradings_group ON gradings(group_id, held_on DESC);
+
+-- The level each examinee tried for and whether they passed
+CREATE TABLE grading_results (
+    grading_id UUID NOT NULL REFERENCES gradings(id) ON DELETE CASCADE,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    level_id UUID NOT NULL REFERENCES progress_levels(id) ON DELETE CASCADE,
+    passed BOOLEAN NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (grading_id, student_id)
+);
+
+CREATE INDEX idx_grading_results_student ON grading_results(student_id);
+
+-- Levels awarded to students, by passing a grading or directly. A
+-- student's level in a group is the one awarded last.
+CREATE TABLE level_awards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    level_id UUID NOT NULL REFERENCES progress_levels(id) ON DELETE CASCADE,
+    awarded_on DATE NOT NULL,
+    grading_id UUID REFERENCES gradings(id) ON DELETE CASCADE,
+    notes TEXT NOT NULL DEFAULT '',
+    awarded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_level_awards_student ON level_awards(student_id, awarded_on DESC);
+CREATE INDEX idx_level_awards_level ON level_awards(level_id);
+CREATE INDEX idx_level_awards_grading ON level_awards(grading_id) WHERE grading_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payroll_items;
+DROP TABLE IF EXISTS payroll_runs;
+DROP TABLE IF EXISTS payroll_rules;
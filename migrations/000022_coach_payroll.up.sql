@@ -0,0 +1,56 @@
+-- How coaches are paid: per session, per attendee or a percentage of the
+-- payments for the group. A rule without a group covers every group of
+-- the coach in the club that has no rule of its own.
+CREATE TABLE payroll_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    coach_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('per_session', 'per_attendee', 'revenue_share')),
+    rate NUMERIC(10,2) NOT NULL CHECK (rate >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE UNIQUE INDEX payroll_rules_coach_group
+    ON payroll_rules(club_id, coach_user_id, COALESCE(group_id, '00000000-0000-0000-0000-000000000000'));
+
+-- Payroll of a club for the days from period_start to period_end. A draft
+-- can be recalculated; an approved run is locked.
+CREATE TABLE payroll_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    club_id UUID NOT NULL REFERENCES clubs(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'approved')),
+    total NUMERIC(12,2) NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    calculated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    approved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    approved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CHECK (period_end >= period_start)
+);
+
+CREATE INDEX idx_payroll_runs_club ON payroll_runs(club_id, period_start DESC);
+
+-- Lines of a run: a session paid per session or per attendee, or a payment
+-- shared with the coach. Names are kept as they were when calculated.
+CREATE TABLE payroll_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id UUID NOT NULL REFERENCES payroll_runs(id) ON DELETE CASCADE,
+    coach_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    coach_name TEXT NOT NULL,
+    group_id UUID REFERENCES groups(id) ON DELETE SET NULL,
+    group_title TEXT NOT NULL,
+    rule_id UUID REFERENCES payroll_rules(id) ON DELETE SET NULL,
+    session_id UUID REFERENCES sessions(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    kind VARCHAR(20) NOT NULL,
+    rate NUMERIC(10,2) NOT NULL,
+    quantity NUMERIC(12,2) NOT NULL,
+    amount NUMERIC(12,2) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_payroll_items_run ON payroll_items(run_id, coach_name, occurred_at);
@@ -0,0 +1,81 @@
+// Package checkin issues the credentials used for self check-in: signed
+// personal QR tokens for students and opaque kiosk device tokens.
+package checkin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidToken = errors.New("invalid check-in token")
+
+// tokenPrefix versions the QR token format
+const tokenPrefix = "tp1"
+
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Sign returns the student's QR token: "tp1.<student id>.<mac>", both parts
+// base64url so the code stays small enough for a low-density QR
+func (s *Signer) Sign(studentID uuid.UUID) string {
+	id := base64.RawURLEncoding.EncodeToString(studentID[:])
+	return tokenPrefix + "." + id + "." + base64.RawURLEncoding.EncodeToString(s.mac(studentID))
+}
+
+// Verify checks the signature and returns the student ID
+func (s *Signer) Verify(token string) (uuid.UUID, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] != tokenPrefix {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	studentID, err := uuid.FromBytes(raw)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, s.mac(studentID)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return studentID, nil
+}
+
+func (s *Signer) mac(studentID uuid.UUID) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte("student-checkin:"))
+	h.Write(studentID[:])
+	return h.Sum(nil)[:16]
+}
+
+// NewDeviceToken generates a random kiosk device token. Only its hash is
+// stored; the token itself is shown once when the device is registered.
+func NewDeviceToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "kd_" + hex.EncodeToString(b), nil
+}
+
+// HashDeviceToken returns the stored form of a device token
+func HashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
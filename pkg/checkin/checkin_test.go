@@ -0,0 +1,65 @@
+package checkin_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neo/trainer-plus/pkg/checkin"
+)
+
+func TestSigner_RoundTrip(t *testing.T) {
+	signer := checkin.NewSigner("secret")
+	studentID := uuid.New()
+
+	got, err := signer.Verify(signer.Sign(studentID))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != studentID {
+		t.Errorf("expected %s, got %s", studentID, got)
+	}
+}
+
+func TestSigner_Rejects(t *testing.T) {
+	signer := checkin.NewSigner("secret")
+	token := signer.Sign(uuid.New())
+	parts := strings.Split(token, ".")
+	otherID := strings.Split(signer.Sign(uuid.New()), ".")[1]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"wrong prefix", "tp0." + parts[1] + "." + parts[2]},
+		{"swapped student", parts[0] + "." + otherID + "." + parts[2]},
+		{"other secret", checkin.NewSigner("other").Sign(uuid.New())},
+		{"garbage", "tp1.!!.!!"},
+		{"missing mac", parts[0] + "." + parts[1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.token); !errors.Is(err, checkin.ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeviceToken(t *testing.T) {
+	a, err := checkin.NewDeviceToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := checkin.NewDeviceToken()
+
+	if a == b {
+		t.Error("expected distinct tokens")
+	}
+	if checkin.HashDeviceToken(a) != checkin.HashDeviceToken(a) || checkin.HashDeviceToken(a) == checkin.HashDeviceToken(b) {
+		t.Error("expected a stable, distinct hash per token")
+	}
+}
@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The documents use the standard Helvetica fonts, which viewers provide,
+// so no font is embedded. Codes 32-126 are ASCII as in WinAnsiEncoding;
+// codes from 128 are re-mapped to Cyrillic, the Kazakh letters and a few
+// symbols. Other characters are printed as '?'.
+
+// glyph is an extra character of the encoding and its width in 1/1000 em
+type glyph struct {
+	r     rune
+	name  string
+	width int
+}
+
+var extraGlyphs = buildExtraGlyphs()
+
+func buildExtraGlyphs() []glyph {
+	// А-Я and а-я, widths in alphabet order
+	upper := []int{667, 656, 667, 542, 677, 667, 923, 604, 719, 719, 583, 656, 833, 722, 778, 719,
+		667, 722, 611, 635, 760, 667, 740, 667, 917, 938, 792, 885, 656, 719, 1010, 722}
+	lower := []int{556, 573, 531, 365, 583, 556, 669, 458, 559, 559, 438, 583, 688, 552, 556, 542,
+		556, 500, 458, 500, 823, 500, 573, 521, 802, 823, 625, 719, 521, 510, 750, 542}
+
+	var glyphs []glyph
+	for i, w := range upper {
+		glyphs = append(glyphs, glyph{r: 'А' + rune(i), width: w})
+	}
+	for i, w := range lower {
+		glyphs = append(glyphs, glyph{r: 'а' + rune(i), width: w})
+	}
+
+	others := []glyph{
+		{r: 'Ё', width: 667}, {r: 'ё', width: 556},
+		// Kazakh
+		{r: 'Ә', width: 667}, {r: 'ә', width: 556},
+		{r: 'Ғ', width: 542}, {r: 'ғ', width: 365},
+		{r: 'Қ', width: 583}, {r: 'қ', width: 438},
+		{r: 'Ң', width: 722}, {r: 'ң', width: 552},
+		{r: 'Ө', width: 778}, {r: 'ө', width: 556},
+		{r: 'Ұ', width: 635}, {r: 'ұ', width: 500},
+		{r: 'Ү', width: 635}, {r: 'ү', width: 500},
+		{r: 'Һ', width: 667}, {r: 'һ', width: 556},
+		{r: 'І', width: 278}, {r: 'і', width: 222},
+		// Symbols
+		{r: '№', width: 1073},
+		{r: '₸', width: 556},
+		{r: '₽', width: 556},
+		{r: '€', name: "Euro", width: 556},
+		{r: '—', name: "emdash", width: 1000},
+		{r: '–', name: "endash", width: 556},
+		{r: '«', name: "guillemotleft", width: 556},
+		{r: '»', name: "guillemotright", width: 556},
+		{r: '…', name: "ellipsis", width: 1000},
+		{r: '•', name: "bullet", width: 350},
+		{r: '°', name: "degree", width: 400},
+	}
+	glyphs = append(glyphs, others...)
+
+	for i := range glyphs {
+		if glyphs[i].name == "" {
+			glyphs[i].name = fmt.Sprintf("uni%04X", glyphs[i].r)
+		}
+	}
+	return glyphs
+}
+
+// firstExtra is the code of the first extra glyph
+const firstExtra = 128
+
+// asciiWidths are the Helvetica widths of codes 32-126
+var asciiWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space-/
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0-?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @-O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P-_
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // `-o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p-~
+}
+
+// boldFactor approximates Helvetica-Bold from the regular widths
+const boldFactor = 1.05
+
+var codes = buildCodes()
+
+func buildCodes() map[rune]byte {
+	m := make(map[rune]byte, len(extraGlyphs))
+	for i, g := range extraGlyphs {
+		m[g.r] = byte(firstExtra + i)
+	}
+	return m
+}
+
+// encode maps text to codes of the font
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r <= 126:
+			b = append(b, byte(r))
+		case r == '\t' || r == '\u00a0' || r == '\u202f':
+			b = append(b, ' ')
+		default:
+			if c, ok := codes[r]; ok {
+				b = append(b, c)
+			} else if r >= 32 {
+				b = append(b, '?')
+			}
+		}
+	}
+	return b
+}
+
+func codeWidth(c byte) int {
+	switch {
+	case c >= 32 && c <= 126:
+		return asciiWidths[c-32]
+	case c >= firstExtra && int(c-firstExtra) < len(extraGlyphs):
+		return extraGlyphs[c-firstExtra].width
+	default:
+		return 556
+	}
+}
+
+// textWidth is the width of s in points at size
+func textWidth(s string, size float64, bold bool) float64 {
+	total := 0
+	for _, c := range encode(s) {
+		total += codeWidth(c)
+	}
+	w := float64(total) * size / 1000
+	if bold {
+		w *= boldFactor
+	}
+	return w
+}
+
+// fit shortens s with an ellipsis until it fits width
+func fit(s string, width, size float64, bold bool) string {
+	if textWidth(s, size, bold) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 {
+		r = r[:len(r)-1]
+		candidate := strings.TrimRight(string(r), " ") + "…"
+		if textWidth(candidate, size, bold) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// fontObject is the font dictionary of Helvetica or Helvetica-Bold with
+// the encoding above
+func fontObject(base string, bold bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /FirstChar 32 /LastChar %d /Widths [", base, firstExtra+len(extraGlyphs)-1)
+	for c := 32; c < firstExtra+len(extraGlyphs); c++ {
+		w := 0
+		if c <= 126 || c >= firstExtra {
+			w = codeWidth(byte(c))
+		}
+		if bold {
+			w = int(float64(w)*boldFactor + 0.5)
+		}
+		fmt.Fprintf(&b, "%d ", w)
+	}
+	b.WriteString("] /Encoding << /Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [")
+	fmt.Fprintf(&b, "%d", firstExtra)
+	for _, g := range extraGlyphs {
+		b.WriteString(" /" + g.name)
+	}
+	b.WriteString("] >> >>")
+	return b.String()
+}
@@ -0,0 +1,337 @@
+// Package pdf writes printable table documents: a title, notes and tables
+// flowing over A4 pages. Pages are written as soon as they are full, so
+// long tables are streamed rather than built in memory.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Page geometry in points
+const (
+	a4Width  = 595.28
+	a4Height = 841.89
+	margin   = 36.0
+
+	titleSize  = 14.0
+	textSize   = 9.0
+	cellSize   = 8.5
+	rowHeight  = 15.0
+	cellIndent = 3.0
+)
+
+// Fixed object numbers; pages follow
+const (
+	objCatalog = 1
+	objPages   = 2
+	objFont    = 3
+	objBold    = 4
+	firstPage  = 5
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Column of a table. Widths are relative and spread over the page width.
+type Column struct {
+	Header string
+	Width  float64
+	Align  Align
+}
+
+type Options struct {
+	Landscape bool
+	// Footer is printed at the bottom left of every page
+	Footer string
+}
+
+// ErrClosed is returned when writing to a closed document
+var ErrClosed = errors.New("pdf: document is closed")
+
+// Document is a PDF being written to w
+type Document struct {
+	w       *countingWriter
+	opts    Options
+	width   float64
+	height  float64
+	offsets map[int]int64
+	pages   []int
+	nextObj int
+
+	page    *bytes.Buffer // content of the current page
+	y       float64       // top of the next line
+	columns []Column
+	widths  []float64 // in points
+	closed  bool
+}
+
+func New(w io.Writer, opts Options) *Document {
+	d := &Document{
+		w:       &countingWriter{w: w},
+		opts:    opts,
+		width:   a4Width,
+		height:  a4Height,
+		offsets: map[int]int64{},
+		nextObj: firstPage,
+	}
+	if opts.Landscape {
+		d.width, d.height = a4Height, a4Width
+	}
+
+	// The binary comment marks the file as binary for transfer tools
+	d.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	d.object(objFont, fontObject("Helvetica", false))
+	d.object(objBold, fontObject("Helvetica-Bold", true))
+	return d
+}
+
+// Title writes a large bold line
+func (d *Document) Title(s string) error {
+	return d.line(s, titleSize, true, titleSize*1.6)
+}
+
+// Text writes a line of plain text, e.g. the period of a report
+func (d *Document) Text(s string) error {
+	return d.line(s, textSize, false, textSize*1.7)
+}
+
+func (d *Document) line(s string, size float64, bold bool, height float64) error {
+	if d.closed {
+		return ErrClosed
+	}
+	d.columns = nil
+	if err := d.ensureSpace(height); err != nil {
+		return err
+	}
+	d.y -= height
+	d.text(margin, d.y+height*0.3, fit(s, d.width-2*margin, size, bold), size, bold)
+	return d.w.err
+}
+
+// Table starts a table; its header is repeated on every page it spans
+func (d *Document) Table(columns []Column) error {
+	if d.closed {
+		return ErrClosed
+	}
+
+	total := 0.0
+	for _, c := range columns {
+		total += c.Width
+	}
+	available := d.width - 2*margin
+	d.widths = make([]float64, len(columns))
+	for i, c := range columns {
+		d.widths[i] = available / float64(len(columns))
+		if total > 0 {
+			d.widths[i] = available * c.Width / total
+		}
+	}
+	d.columns = columns
+
+	// Some room between tables, and keep the header with the first row
+	if d.page != nil && d.y < d.height-margin {
+		d.y -= rowHeight / 2
+	}
+	if err := d.ensureSpace(2 * rowHeight); err != nil {
+		return err
+	}
+	d.header()
+	return d.w.err
+}
+
+// Row writes a row of the current table. Cells too wide for their column
+// are shortened with an ellipsis.
+func (d *Document) Row(cells ...string) error {
+	if d.closed {
+		return ErrClosed
+	}
+	if d.columns == nil {
+		return errors.New("pdf: no table started")
+	}
+
+	if d.y-rowHeight < d.bottom() {
+		if err := d.newPage(); err != nil {
+			return err
+		}
+		d.header()
+	}
+	d.y -= rowHeight
+	d.cells(cells, false)
+	fmt.Fprintf(d.page, "0.85 G 0.4 w %s %s m %s %s l S 0 G\n", num(margin), num(d.y), num(d.width-margin), num(d.y))
+	return d.w.err
+}
+
+func (d *Document) header() {
+	d.y -= rowHeight
+	fmt.Fprintf(d.page, "0.92 g %s %s %s %s re f 0 g\n", num(margin), num(d.y), num(d.width-2*margin), num(rowHeight))
+	headers := make([]string, len(d.columns))
+	for i, c := range d.columns {
+		headers[i] = c.Header
+	}
+	d.cells(headers, true)
+}
+
+func (d *Document) cells(cells []string, bold bool) {
+	x := margin
+	baseline := d.y + (rowHeight-cellSize)/2 + 1
+	for i, width := range d.widths {
+		if i < len(cells) && cells[i] != "" {
+			s := fit(cells[i], width-2*cellIndent, cellSize, bold)
+			tx := x + cellIndent
+			if d.columns[i].Align == AlignRight {
+				tx = x + width - cellIndent - textWidth(s, cellSize, bold)
+			}
+			d.text(tx, baseline, s, cellSize, bold)
+		}
+		x += width
+	}
+}
+
+func (d *Document) text(x, y float64, s string, size float64, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %s Tf %s %s Td ", font, num(size), num(x), num(y))
+	writeString(d.page, encode(s))
+	d.page.WriteString(" Tj ET\n")
+}
+
+func (d *Document) bottom() float64 {
+	return margin + 14 // room for the footer
+}
+
+// ensureSpace starts a page unless height fits on the current one
+func (d *Document) ensureSpace(height float64) error {
+	if d.page != nil && d.y-height >= d.bottom() {
+		return nil
+	}
+	return d.newPage()
+}
+
+func (d *Document) newPage() error {
+	if err := d.finishPage(); err != nil {
+		return err
+	}
+	d.page = &bytes.Buffer{}
+	d.y = d.height - margin
+	return nil
+}
+
+// finishPage writes the footer and the current page
+func (d *Document) finishPage() error {
+	if d.page == nil {
+		return nil
+	}
+
+	n := strconv.Itoa(len(d.pages) + 1)
+	if d.opts.Footer != "" {
+		d.text(margin, margin, fit(d.opts.Footer, d.width/2, 7, false), 7, false)
+	}
+	d.text(d.width-margin-textWidth(n, 7, false), margin, n, 7, false)
+
+	content, page := d.nextObj, d.nextObj+1
+	d.nextObj += 2
+
+	d.stream(content, d.page.Bytes())
+	d.object(page, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		objPages, num(d.width), num(d.height), objFont, objBold, content))
+	d.pages = append(d.pages, page)
+	d.page = nil
+	return d.w.err
+}
+
+// Close writes the last page and the document trailer. It does not close
+// the underlying writer.
+func (d *Document) Close() error {
+	if d.closed {
+		return nil
+	}
+	if d.page == nil && len(d.pages) == 0 {
+		d.newPage()
+	}
+	if err := d.finishPage(); err != nil {
+		return err
+	}
+	d.closed = true
+
+	var kids bytes.Buffer
+	for _, p := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", p)
+	}
+	d.object(objPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(d.pages)))
+	d.object(objCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", objPages))
+
+	xref := d.w.n
+	size := d.nextObj
+	d.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", size))
+	for i := 1; i < size; i++ {
+		d.write(fmt.Sprintf("%010d 00000 n \n", d.offsets[i]))
+	}
+	d.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, objCatalog, xref))
+	return d.w.err
+}
+
+func (d *Document) object(n int, body string) {
+	d.offsets[n] = d.w.n
+	d.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", n, body))
+}
+
+func (d *Document) stream(n int, content []byte) {
+	d.offsets[n] = d.w.n
+	d.write(fmt.Sprintf("%d 0 obj\n<< /Length %d >>\nstream\n", n, len(content)))
+	d.w.Write(content)
+	d.write("\nendstream\nendobj\n")
+}
+
+func (d *Document) write(s string) {
+	io.WriteString(d.w, s)
+}
+
+// writeString writes a PDF string literal, escaping delimiters and bytes
+// outside ASCII
+func writeString(b *bytes.Buffer, s []byte) {
+	b.WriteByte('(')
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// countingWriter tracks the offsets of objects and keeps the first error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
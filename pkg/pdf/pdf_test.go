@@ -0,0 +1,145 @@
+package pdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/neo/trainer-plus/pkg/pdf"
+)
+
+var (
+	startxrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	sizeRe      = regexp.MustCompile(`/Size (\d+)`)
+	countRe     = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+	streamRe    = regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n`)
+)
+
+// checkStructure verifies the xref table against the object offsets and
+// the stream lengths against their data, and returns the page count
+func checkStructure(t *testing.T, doc []byte) int {
+	t.Helper()
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing header: %q", doc[:min(len(doc), 16)])
+	}
+
+	m := startxrefRe.FindSubmatch(doc)
+	if m == nil {
+		t.Fatalf("missing startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(doc[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	size, _ := strconv.Atoi(string(sizeRe.FindSubmatch(doc)[1]))
+	lines := strings.Split(string(doc[xref:]), "\n")
+	if lines[1] != fmt.Sprintf("0 %d", size) {
+		t.Fatalf("xref subsection %q, want 0 %d", lines[1], size)
+	}
+	for n := 1; n < size; n++ {
+		entry := lines[2+n]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d is malformed: %q", n, entry)
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil {
+			t.Fatalf("xref entry %d: %v", n, err)
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", n); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("xref offset %d of object %d points at %q", offset, n, doc[offset:min(len(doc), offset+12)])
+		}
+	}
+
+	for _, loc := range streamRe.FindAllSubmatchIndex(doc, -1) {
+		length, _ := strconv.Atoi(string(doc[loc[2]:loc[3]]))
+		if end := loc[1] + length; !bytes.HasPrefix(doc[end:], []byte("\nendstream\n")) {
+			t.Errorf("stream at %d does not end after its /Length %d", loc[0], length)
+		}
+	}
+
+	count := countRe.FindSubmatch(doc)
+	if count == nil {
+		t.Fatalf("missing page tree")
+	}
+	pages, _ := strconv.Atoi(string(count[1]))
+	return pages
+}
+
+func TestDocument_Structure(t *testing.T) {
+	var buf bytes.Buffer
+	d := pdf.New(&buf, pdf.Options{Footer: "Trainer Plus (demo)"})
+	if err := d.Title("Посещаемость"); err != nil {
+		t.Fatalf("Title: %v", err)
+	}
+	if err := d.Text(`01.03.2024 \ 31.03.2024`); err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	if err := d.Table([]pdf.Column{{Header: "Student", Width: 3}, {Header: "Visits", Width: 1, Align: pdf.AlignRight}}); err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	if err := d.Row("Әсел (Асель)", "12"); err != nil {
+		t.Fatalf("Row: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if pages := checkStructure(t, buf.Bytes()); pages != 1 {
+		t.Errorf("pages = %d, want 1", pages)
+	}
+}
+
+func TestDocument_TableSpansPages(t *testing.T) {
+	var buf bytes.Buffer
+	d := pdf.New(&buf, pdf.Options{Landscape: true})
+	if err := d.Table([]pdf.Column{{Header: "#"}, {Header: "Name"}}); err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		if err := d.Row(strconv.Itoa(i+1), "student"); err != nil {
+			t.Fatalf("Row: %v", err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	doc := buf.Bytes()
+	pages := checkStructure(t, doc)
+	if pages < 2 {
+		t.Fatalf("pages = %d, want the table to span several", pages)
+	}
+	// The header is repeated on every page
+	if n := bytes.Count(doc, []byte("(Name) Tj")); n != pages {
+		t.Errorf("header printed %d times on %d pages", n, pages)
+	}
+}
+
+func TestDocument_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pdf.New(&buf, pdf.Options{}).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if pages := checkStructure(t, buf.Bytes()); pages != 1 {
+		t.Errorf("pages = %d, want 1", pages)
+	}
+}
+
+func TestDocument_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	d := pdf.New(&buf, pdf.Options{})
+	if err := d.Row("x"); err == nil {
+		t.Error("expected an error for a row outside a table")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := d.Text("x"); err != pdf.ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
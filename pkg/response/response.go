@@ -18,10 +18,11 @@ type ErrorBody struct {
 }
 
 type Meta struct {
-	Page       int `json:"page,omitempty"`
-	PerPage    int `json:"per_page,omitempty"`
-	Total      int `json:"total,omitempty"`
-	TotalPages int `json:"total_pages,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func JSON(w http.ResponseWriter, status int, data interface{}) {
@@ -64,6 +65,23 @@ func Error(w http.ResponseWriter, status int, code, message string) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// ErrorWithData writes an error together with details the client can act on
+func ErrorWithData(w http.ResponseWriter, status int, code, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := Response{
+		Success: false,
+		Data:    data,
+		Error: &ErrorBody{
+			Code:    code,
+			Message: message,
+		},
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
 // Common error responses
 func BadRequest(w http.ResponseWriter, message string) {
 	Error(w, http.StatusBadRequest, "BAD_REQUEST", message)
@@ -0,0 +1,37 @@
+// Package storage keeps uploaded files in an S3-compatible bucket or, for
+// local development, in a directory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Storage stores files by key. Keys are slash-separated paths such as
+// "students/<id>/<document id>".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns the file's content; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes a file. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the storage root
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+var errInvalidKey = errors.New("storage: invalid key")
@@ -0,0 +1,264 @@
+// Package xlsx reads and writes the cell values of simple Office Open XML
+// spreadsheets using only the standard library. Styles, formulas and
+// merged cells are not supported.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSheet is returned for a workbook without worksheets
+var ErrNoSheet = errors.New("xlsx: workbook has no worksheet")
+
+// maxColumns guards against cell references far to the right
+const maxColumns = 16384
+
+// ReadRows returns the cell values of the first worksheet, one slice per
+// row. Rows keep their position, so blank rows come back empty; cells to
+// the right of the last value are not padded. Numbers are returned as
+// stored, which for dates is the Excel serial day.
+func ReadRows(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, ErrNoSheet
+	}
+	return readSheet(f, shared)
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type workbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// firstSheetPath resolves the first sheet of the workbook through its
+// relationships, falling back to the conventional sheet1.xml
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var wb workbook
+	var rels relationships
+	if decodeFile(files["xl/workbook.xml"], &wb) != nil || decodeFile(files["xl/_rels/workbook.xml.rels"], &rels) != nil || len(wb.Sheets) == 0 {
+		if _, ok := files[fallback]; ok {
+			return fallback, nil
+		}
+		return "", ErrNoSheet
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+func decodeFile(f *zip.File, v interface{}) error {
+	if f == nil {
+		return ErrNoSheet
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// richText is the text of a shared or inline string, plain or in runs
+type richText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.R {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodeFile(f, &sst); err != nil {
+		return nil, fmt.Errorf("xlsx: shared strings: %w", err)
+	}
+
+	shared := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		shared[i] = item.String()
+	}
+	return shared, nil
+}
+
+type cell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+type row struct {
+	Num   int    `xml:"r,attr"`
+	Cells []cell `xml:"c"`
+}
+
+// readSheet streams the rows of a worksheet so large sheets are not
+// decoded into one tree
+func readSheet(f *zip.File, shared []string) ([][]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xlsx: worksheet: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var r row
+		if err := dec.DecodeElement(&r, &start); err != nil {
+			return nil, fmt.Errorf("xlsx: worksheet: %w", err)
+		}
+
+		// Rows without r= follow the previous one
+		num := r.Num
+		if num == 0 {
+			num = len(rows) + 1
+		}
+		if num > len(rows)+1000000 {
+			return nil, fmt.Errorf("xlsx: row %d out of range", num)
+		}
+		for len(rows) < num {
+			rows = append(rows, nil)
+		}
+		values, err := rowValues(r.Cells, shared)
+		if err != nil {
+			return nil, err
+		}
+		rows[num-1] = values
+	}
+}
+
+func rowValues(cells []cell, shared []string) ([]string, error) {
+	var values []string
+	for i, c := range cells {
+		col := i
+		if c.Ref != "" {
+			var err error
+			if col, err = columnIndex(c.Ref); err != nil {
+				return nil, err
+			}
+		}
+
+		var v string
+		switch c.Type {
+		case "s":
+			idx, err := strconv.Atoi(c.Value)
+			if err != nil || idx < 0 || idx >= len(shared) {
+				return nil, fmt.Errorf("xlsx: cell %s: bad shared string %q", c.Ref, c.Value)
+			}
+			v = shared[idx]
+		case "inlineStr":
+			v = c.Inline.String()
+		default:
+			v = c.Value
+		}
+		if v == "" {
+			continue
+		}
+
+		for len(values) <= col {
+			values = append(values, "")
+		}
+		values[col] = v
+	}
+	return values, nil
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to
+// a zero-based column
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 || col > maxColumns {
+		return 0, fmt.Errorf("xlsx: bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// ColumnName converts a zero-based column index to its letters
+func ColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,111 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/neo/trainer-plus/pkg/xlsx"
+)
+
+// buildWorkbook zips the given parts into a workbook
+func buildWorkbook(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func readRows(t *testing.T, r *bytes.Reader) [][]string {
+	t.Helper()
+	rows, err := xlsx.ReadRows(r, r.Size())
+	if err != nil {
+		t.Fatalf("ReadRows: %v", err)
+	}
+	return rows
+}
+
+func TestReadRows_CellTypes(t *testing.T) {
+	r := buildWorkbook(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Students" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Target="worksheets/students.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Name</t></si><si><t>Birth date</t></si><si><r><t>Aru</t></r><r><t>zhan</t></r></si></sst>`,
+		"xl/worksheets/students.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3"><v>45352</v></c><c r="D3" t="inlineStr"><is><t>&lt;note&gt;</t></is></c></row>` +
+			`<row><c t="inlineStr"><is><t>Dana</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+
+	want := [][]string{
+		{"Name", "Birth date"},
+		nil,
+		{"Aruzhan", "45352", "", "<note>"},
+		{"Dana"},
+	}
+	if got := readRows(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestReadRows_FallbackSheet(t *testing.T) {
+	r := buildWorkbook(t, map[string]string{
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="B1"><v>7</v></c></row></sheetData></worksheet>`,
+	})
+
+	want := [][]string{{"", "7"}}
+	if got := readRows(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestReadRows_Errors(t *testing.T) {
+	t.Run("no sheet", func(t *testing.T) {
+		r := buildWorkbook(t, map[string]string{"docProps/app.xml": `<Properties/>`})
+		if _, err := xlsx.ReadRows(r, r.Size()); !errors.Is(err, xlsx.ErrNoSheet) {
+			t.Errorf("expected ErrNoSheet, got %v", err)
+		}
+	})
+
+	t.Run("bad shared string", func(t *testing.T) {
+		r := buildWorkbook(t, map[string]string{
+			"xl/sharedStrings.xml":     `<sst><si><t>only</t></si></sst>`,
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>3</v></c></row></sheetData></worksheet>`,
+		})
+		if _, err := xlsx.ReadRows(r, r.Size()); err == nil {
+			t.Error("expected an error for an out of range shared string")
+		}
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		r := bytes.NewReader([]byte("name,phone\n"))
+		if _, err := xlsx.ReadRows(r, r.Size()); err == nil {
+			t.Error("expected an error for a CSV file")
+		}
+	})
+}
+
+func TestColumnName(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsx.ColumnName(col); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", col, got, want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Style is the number format and font of a cell
+type Style int
+
+const (
+	StyleDefault  Style = iota
+	StyleBold           // headers
+	StyleInteger        // 1,234
+	StyleDecimal        // 1,234.50
+	StylePercent        // 12.5% of a value that is already a percentage
+	StyleDate           // Options.DateFormat
+	StyleDateTime       // Options.DateTimeFormat
+	StyleMoney          // Options.MoneyFormat
+)
+
+// Options are the number formats of a workbook, in Excel syntax
+type Options struct {
+	MoneyFormat    string // e.g. `#,##0.00 "₸"`; default #,##0.00
+	DateFormat     string // default dd.mm.yyyy
+	DateTimeFormat string // default dd.mm.yyyy hh:mm
+}
+
+// Cell is a value and its style. Values may be strings, integers, floats,
+// bools, time.Time or nil for an empty cell. Times are written as their
+// wall clock, so convert them to the zone they should show in first.
+type Cell struct {
+	Value interface{}
+	Style Style
+}
+
+// ErrClosed is returned when writing to a closed workbook
+var ErrClosed = errors.New("xlsx: writer is closed")
+
+// maxSheetName is Excel's limit on sheet names
+const maxSheetName = 31
+
+// Writer streams a workbook: rows are compressed and written to the
+// underlying writer as they come, so large sheets are never held in
+// memory. Sheets are written one after the other.
+type Writer struct {
+	zw     *zip.Writer
+	opts   Options
+	sheet  io.Writer
+	sheets []string
+	row    int
+	closed bool
+}
+
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.MoneyFormat == "" {
+		opts.MoneyFormat = "#,##0.00"
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "dd.mm.yyyy"
+	}
+	if opts.DateTimeFormat == "" {
+		opts.DateTimeFormat = "dd.mm.yyyy hh:mm"
+	}
+	return &Writer{zw: zip.NewWriter(w), opts: opts}
+}
+
+// AddSheet finishes the current sheet and starts a new one. Widths are
+// column widths in characters; columns without one get Excel's default.
+func (w *Writer) AddSheet(name string, widths ...float64) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+
+	w.sheets = append(w.sheets, w.sheetName(name))
+	sheet, err := w.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)))
+	if err != nil {
+		return err
+	}
+	w.sheet = sheet
+	w.row = 0
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(widths) > 0 {
+		b.WriteString("<cols>")
+		for i, width := range widths {
+			if width > 0 {
+				fmt.Fprintf(&b, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, formatFloat(width))
+			}
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	_, err = io.WriteString(w.sheet, b.String())
+	return err
+}
+
+// sheetName makes name valid and unique: Excel rejects some characters,
+// long names and repeated names
+func (w *Writer) sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "Sheet"
+	}
+
+	base := truncateRunes(name, maxSheetName)
+	unique := base
+	for n := 2; w.hasSheet(unique); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		unique = truncateRunes(base, maxSheetName-len(suffix)) + suffix
+	}
+	return unique
+}
+
+func (w *Writer) hasSheet(name string) bool {
+	for _, s := range w.sheets {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// WriteRow appends a row to the current sheet
+func (w *Writer) WriteRow(cells ...Cell) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.sheet == nil {
+		return errors.New("xlsx: no sheet added")
+	}
+
+	w.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.row)
+	for i, c := range cells {
+		if err := writeCell(&b, ColumnName(i)+strconv.Itoa(w.row), c); err != nil {
+			return err
+		}
+	}
+	b.WriteString("</row>")
+
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+func writeCell(b *strings.Builder, ref string, c Cell) error {
+	style := ""
+	if c.Style != StyleDefault {
+		style = fmt.Sprintf(` s="%d"`, c.Style)
+	}
+
+	var v string
+	switch value := c.Value.(type) {
+	case nil:
+		return nil
+	case string:
+		if value == "" {
+			return nil
+		}
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, style)
+		if err := xml.EscapeText(b, []byte(value)); err != nil {
+			return err
+		}
+		b.WriteString("</t></is></c>")
+		return nil
+	case bool:
+		v = "0"
+		if value {
+			v = "1"
+		}
+		fmt.Fprintf(b, `<c r="%s" t="b"%s><v>%s</v></c>`, ref, style, v)
+		return nil
+	case int:
+		v = strconv.Itoa(value)
+	case int64:
+		v = strconv.FormatInt(value, 10)
+	case float64:
+		v = formatFloat(value)
+	case time.Time:
+		v = formatFloat(serialDate(value))
+	default:
+		return fmt.Errorf("xlsx: unsupported cell value %T", c.Value)
+	}
+
+	fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, v)
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// serialDate converts the wall clock of t to an Excel serial day
+func serialDate(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return float64(wall.Sub(excelEpoch)) / float64(24*time.Hour)
+}
+
+// excelEpoch is day zero of serial dates in the 1900 date system
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func (w *Writer) endSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(w.sheet, "</sheetData></worksheet>")
+	w.sheet = nil
+	return err
+}
+
+// Close finishes the workbook. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if len(w.sheets) == 0 {
+		if err := w.AddSheet("Sheet1"); err != nil {
+			return err
+		}
+	}
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"xl/styles.xml", w.styles()},
+		{"xl/workbook.xml", w.workbook()},
+		{"xl/_rels/workbook.xml.rels", w.workbookRels()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"[Content_Types].xml", w.contentTypes()},
+	}
+	for _, f := range files {
+		fw, err := w.zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return w.zw.Close()
+}
+
+func (w *Writer) styles() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="4">`)
+	for i, code := range []string{w.opts.MoneyFormat, `0.0"%"`, w.opts.DateFormat, w.opts.DateTimeFormat} {
+		fmt.Fprintf(&b, `<numFmt numFmtId="%d" formatCode="%s"/>`, 164+i, escapeAttr(code))
+	}
+	b.WriteString(`</numFmts>`)
+	b.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	b.WriteString(`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>`)
+	b.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	b.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+
+	// In the order of the Style constants
+	xfs := []struct{ numFmt, font int }{
+		{0, 0},   // StyleDefault
+		{0, 1},   // StyleBold
+		{3, 0},   // StyleInteger
+		{4, 0},   // StyleDecimal
+		{165, 0}, // StylePercent
+		{166, 0}, // StyleDate
+		{167, 0}, // StyleDateTime
+		{164, 0}, // StyleMoney
+	}
+	fmt.Fprintf(&b, `<cellXfs count="%d">`, len(xfs))
+	for _, xf := range xfs {
+		fmt.Fprintf(&b, `<xf numFmtId="%d" fontId="%d" fillId="0" borderId="0" xfId="0"`, xf.numFmt, xf.font)
+		if xf.numFmt != 0 {
+			b.WriteString(` applyNumberFormat="1"`)
+		}
+		if xf.font != 0 {
+			b.WriteString(` applyFont="1"`)
+		}
+		b.WriteString(`/>`)
+	}
+	b.WriteString(`</cellXfs></styleSheet>`)
+	return b.String()
+}
+
+func (w *Writer) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range w.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeAttr(name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (w *Writer) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (w *Writer) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func escapeAttr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo/trainer-plus/pkg/xlsx"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	almaty := time.FixedZone("Asia/Almaty", 5*60*60)
+
+	var buf bytes.Buffer
+	w := xlsx.NewWriter(&buf, xlsx.Options{MoneyFormat: `#,##0 "₸"`})
+	if err := w.AddSheet("Payments", 20, 0, 12); err != nil {
+		t.Fatalf("AddSheet: %v", err)
+	}
+	rows := [][]xlsx.Cell{
+		{{Value: "Student", Style: xlsx.StyleBold}, {Value: "Paid at", Style: xlsx.StyleBold}, {Value: "Amount", Style: xlsx.StyleBold}},
+		{{Value: "Aruzhan & <Co>"}, {Value: time.Date(2024, 3, 1, 12, 0, 0, 0, almaty), Style: xlsx.StyleDateTime}, {Value: 15000.5, Style: xlsx.StyleMoney}},
+		{},
+		{{Value: "  spaced  "}, {Value: nil}, {Value: int64(42)}, {Value: true}},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.AddSheet("Payments"); err != nil {
+		t.Fatalf("AddSheet: %v", err)
+	}
+	if err := w.WriteRow(xlsx.Cell{Value: "second sheet"}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	want := [][]string{
+		{"Student", "Paid at", "Amount"},
+		{"Aruzhan & <Co>", "45352.5", "15000.5"},
+		nil,
+		{"  spaced  ", "", "42", "1"},
+	}
+	if got := readRows(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+
+	workbook := readPart(t, r, "xl/workbook.xml")
+	for _, name := range []string{`name="Payments"`, `name="Payments (2)"`} {
+		if !strings.Contains(workbook, name) {
+			t.Errorf("workbook.xml is missing sheet %s: %s", name, workbook)
+		}
+	}
+	if styles := readPart(t, r, "xl/styles.xml"); !strings.Contains(styles, `formatCode="#,##0 &#34;₸&#34;"`) {
+		t.Errorf("styles.xml is missing the money format: %s", styles)
+	}
+}
+
+func TestWriter_EmptyWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	if err := xlsx.NewWriter(&buf, xlsx.Options{}).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if got := readRows(t, r); len(got) != 0 {
+		t.Errorf("expected no rows, got %q", got)
+	}
+}
+
+func TestWriter_Errors(t *testing.T) {
+	w := xlsx.NewWriter(io.Discard, xlsx.Options{})
+	if err := w.WriteRow(xlsx.Cell{Value: "x"}); err == nil {
+		t.Error("expected an error writing before AddSheet")
+	}
+	if err := w.AddSheet("Sheet"); err != nil {
+		t.Fatalf("AddSheet: %v", err)
+	}
+	if err := w.WriteRow(xlsx.Cell{Value: struct{}{}}); err == nil {
+		t.Error("expected an error for an unsupported value")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := w.WriteRow(xlsx.Cell{Value: "x"}); err != xlsx.ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func readPart(t *testing.T, r *bytes.Reader, name string) string {
+	t.Helper()
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(b)
+}